@@ -0,0 +1,110 @@
+// Package audit maintains a tamper-evident trail of namespace and permission changes.
+//
+// Unlike packages logger and evtlog (intended for debugging), each audited operation (e.g. a mount,
+// create, unlink, rename, chmod/chown, setxattr, or snapshot operation) is recorded as a single line
+// of JSON encoding a Record. Records are numbered sequentially and hash chained: each carries the
+// Hash of its predecessor (PrevHash) and its own Hash is the hex-encoded SHA-256 of its JSON encoding
+// sans the trailing Hash field. Verify() will, as a result, detect both missing and edited records...
+// even across restarts and file rotations.
+//
+// Records are appended to [Audit]LogFilePath (from which a record torn by a crash is truncated when
+// it is next opened). Once that file would exceed [Audit]MaxFileSize, it is
+// renamed to LogFilePath.<Seq> (where <Seq> is the Seq of its first Record formatted as %016X such
+// that rotated files sort in the order they were written). If [Audit]SwiftAccountName and
+// [Audit]SwiftContainerName are specified, each rotated file is then uploaded to that container as
+// an object of the same (base)name and removed locally (remaining locally should the upload fail).
+//
+// Auditing is disabled unless [Audit]Enabled is true. Changes to the [Audit] section take effect
+// when ProxyFS is signaled (e.g. via SIGHUP).
+package audit
+
+import (
+	"io"
+)
+
+// Ops recorded in Record.Op.
+const (
+	OpMount                    = "mount"
+	OpCreate                   = "create"
+	OpMkdir                    = "mkdir"
+	OpSymlink                  = "symlink"
+	OpLink                     = "link"
+	OpUnlink                   = "unlink"
+	OpRmdir                    = "rmdir"
+	OpRename                   = "rename"
+	OpSetstat                  = "setstat" // Only audited when changing Mode, UserID, or GroupID
+	OpSetXAttr                 = "setxattr"
+	OpRemoveXAttr              = "removexattr"
+	OpTrashRestore             = "trash-restore"
+	OpTrashPurge               = "trash-purge"
+	OpExpirationReap           = "expiration-reap"
+	OpSnapShotCreate           = "snapshot-create"
+	OpSnapShotDelete           = "snapshot-delete"
+	OpMiddlewareCoalesce       = "middleware-coalesce"
+	OpMiddlewareCopy           = "middleware-copy"
+	OpMiddlewareDelete         = "middleware-delete"
+	OpMiddlewareMkdir          = "middleware-mkdir"
+	OpMiddlewarePutComplete    = "middleware-put-complete"
+	OpMiddlewarePutContainer   = "middleware-put-container"
+	OpMiddlewareRestoreVersion = "middleware-restore-version"
+)
+
+// ResultOK is recorded in Record.Result for operations that succeeded.
+const ResultOK = "OK"
+
+// Record describes a single audited operation. Callers of Log() fill in Op through Attrs (as
+// applicable)... the remaining fields are filled in by Log().
+//
+// Paths are relative to the root of Volume. Operations identifying their target by directory inode
+// and basename (i.e. those issued via FUSE or SMB) record just that (in DirInodeNumber & Basename)
+// rather than the cost of walking back up to the root to reconstruct a Path.
+type Record struct {
+	Seq               uint64
+	Time              string // RFC3339Nano in UTC
+	Op                string
+	Volume            string            `json:",omitempty"`
+	UserID            uint32            // Of the caller (the root user for middleware & administrative operations)
+	GroupID           uint32            // Of the caller
+	ClientAddr        string            `json:",omitempty"` // As recorded at mount time (if known)
+	InodeNumber       uint64            `json:",omitempty"`
+	DirInodeNumber    uint64            `json:",omitempty"`
+	Basename          string            `json:",omitempty"`
+	NewDirInodeNumber uint64            `json:",omitempty"` // e.g. the destination directory of a rename
+	NewBasename       string            `json:",omitempty"` // e.g. the destination basename of a rename
+	Path              string            `json:",omitempty"`
+	NewPath           string            `json:",omitempty"` // e.g. the destination of a rename
+	Attrs             map[string]string `json:",omitempty"` // e.g. the new Mode of a chmod or the name of an XAttr
+	Result            string            // ResultOK or the error returned
+	PrevHash          string
+	Hash              string `json:",omitempty"`
+}
+
+// Enabled reports whether or not auditing is enabled... callers may use this to avoid the
+// expense of preparing a Record that Log() would simply discard.
+func Enabled() (enabled bool) {
+	enabled = isEnabled()
+	return
+}
+
+// Log appends record (with Result derived from opErr) to the audit trail if auditing is enabled.
+//
+// The record is numbered and chained immediately but written (by a dedicated goroutine) shortly
+// thereafter. Failures to write the record are logged but otherwise ignored... though the record's
+// Seq is consumed such that Verify() will report the gap.
+func Log(record *Record, opErr error) {
+	logRecord(record, opErr)
+}
+
+// Flush blocks until all records passed to Log() have been written to LogFilePath.
+func Flush() {
+	flushRecords()
+}
+
+// Verify checks that the records read from reader are well formed, properly hash chained, and
+// sequentially numbered following prevSeq & prevHash. To verify the records of a freshly enabled
+// audit trail, pass zero and "". To verify rotated files, pass each in turn the lastSeq and
+// lastHash returned for its predecessor.
+func Verify(reader io.Reader, prevSeq uint64, prevHash string) (lastSeq uint64, lastHash string, err error) {
+	lastSeq, lastHash, err = verify(reader, prevSeq, prevHash)
+	return
+}
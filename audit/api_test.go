@@ -0,0 +1,309 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/ramswift"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+	"github.com/swiftstack/ProxyFS/transitions"
+)
+
+func TestAPI(t *testing.T) {
+	var (
+		confMap                conf.ConfMap
+		confStrings            []string
+		err                    error
+		signalHandlerIsArmedWG sync.WaitGroup
+	)
+
+	testDir, err := ioutil.TempDir("", "audit_test")
+	if nil != err {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(testDir)
+
+	logFilePath := filepath.Join(testDir, "audit.log")
+
+	confStrings = []string{
+		"Logging.LogFilePath=/dev/null",
+
+		"Stats.IPAddr=localhost",
+		"Stats.UDPPort=52184",
+		"Stats.BufferLength=100",
+		"Stats.MaxLatency=1s",
+
+		"SwiftClient.NoAuthIPAddr=127.0.0.1",
+		"SwiftClient.NoAuthTCPPort=9999",
+		"SwiftClient.Timeout=10s",
+		"SwiftClient.RetryLimit=3",
+		"SwiftClient.RetryLimitObject=3",
+		"SwiftClient.RetryDelay=25ms",
+		"SwiftClient.RetryDelayObject=25ms",
+		"SwiftClient.RetryExpBackoff=1.2",
+		"SwiftClient.RetryExpBackoffObject=2.0",
+		"SwiftClient.ChunkedConnectionPoolSize=4",
+		"SwiftClient.NonChunkedConnectionPoolSize=4",
+
+		"Cluster.WhoAmI=Peer0",
+
+		"FSGlobals.VolumeGroupList=",
+		"FSGlobals.TryLockBackoffMin=100us",
+		"FSGlobals.TryLockBackoffMax=300us",
+		"FSGlobals.SymlinkMax=32",
+
+		"RamSwiftInfo.MaxAccountNameLength=256",
+		"RamSwiftInfo.MaxContainerNameLength=256",
+		"RamSwiftInfo.MaxObjectNameLength=1024",
+		"RamSwiftInfo.AccountListingLimit=10000",
+		"RamSwiftInfo.ContainerListingLimit=10000",
+
+		"Audit.Enabled=false",
+		"Audit.LogFilePath=" + logFilePath,
+	}
+
+	confMap, err = conf.MakeConfMapFromStrings(confStrings)
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings() failed: %v", err)
+	}
+
+	signalHandlerIsArmedWG.Add(1)
+	doneChan := make(chan bool, 1) // Must be buffered to avoid race
+
+	go ramswift.Daemon("/dev/null", confStrings, &signalHandlerIsArmedWG, doneChan, unix.SIGTERM)
+
+	signalHandlerIsArmedWG.Wait()
+
+	err = transitions.Up(confMap)
+	if nil != err {
+		t.Fatalf("transitions.Up() failed: %v", err)
+	}
+
+	err = swiftclient.AccountPut("AUTH_audit", map[string][]string{})
+	if nil != err {
+		t.Fatalf("swiftclient.AccountPut() failed: %v", err)
+	}
+
+	signal := func(confStrings ...string) {
+		for _, confString := range confStrings {
+			err = confMap.UpdateFromString(confString)
+			if nil != err {
+				t.Fatalf("confMap.UpdateFromString(\"%s\") failed: %v", confString, err)
+			}
+		}
+		err = transitions.Signaled(confMap)
+		if nil != err {
+			t.Fatalf("transitions.Signaled() failed: %v", err)
+		}
+	}
+
+	logRecords := func(numRecords int) {
+		for i := 0; i < numRecords; i++ {
+			Log(&Record{Op: OpCreate, Volume: "TestVolume", UserID: 1000, GroupID: 1000, ClientAddr: "127.0.0.1:12345", InodeNumber: uint64(i), Path: fmt.Sprintf("/dir/file_%d", i)}, nil)
+		}
+	}
+
+	readLog := func() (buf []byte) {
+		Flush()
+		buf, err = ioutil.ReadFile(logFilePath)
+		if nil != err {
+			t.Fatalf("ioutil.ReadFile() failed: %v", err)
+		}
+		return
+	}
+
+	// Nothing is recorded while disabled
+
+	if Enabled() {
+		t.Fatalf("Enabled() should have returned false")
+	}
+	logRecords(1)
+	_, err = os.Stat(logFilePath)
+	if !os.IsNotExist(err) {
+		t.Fatalf("LogFilePath should not exist while disabled")
+	}
+
+	// Records are appended and verifiable once enabled
+
+	signal("Audit.Enabled=true")
+
+	if !Enabled() {
+		t.Fatalf("Enabled() should have returned true")
+	}
+
+	logRecords(3)
+	Log(&Record{Op: OpUnlink, Path: "/dir/missing"}, blunder.NewError(blunder.NotFoundError, "ENOENT"))
+
+	lastSeq, lastHash, err := Verify(bytes.NewReader(readLog()), 0, "")
+	if nil != err {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+	if 4 != lastSeq {
+		t.Fatalf("Verify() returned lastSeq == %v... expected 4", lastSeq)
+	}
+
+	if !strings.Contains(string(readLog()), "\"Result\":\"ENOENT\"") {
+		t.Fatalf("Result of failed operation not recorded")
+	}
+
+	// Edits, deletions, and reorderings are detected
+
+	lines := bytes.SplitAfter(readLog(), []byte("\n"))
+
+	edited := bytes.Join([][]byte{lines[0], bytes.Replace(lines[1], []byte("file_1"), []byte("file_X"), 1), lines[2], lines[3]}, nil)
+	_, _, err = Verify(bytes.NewReader(edited), 0, "")
+	if (nil == err) || !strings.Contains(err.Error(), "Hash mismatch") {
+		t.Fatalf("Verify() of edited record should have reported Hash mismatch... got: %v", err)
+	}
+
+	deleted := bytes.Join([][]byte{lines[0], lines[2], lines[3]}, nil)
+	_, _, err = Verify(bytes.NewReader(deleted), 0, "")
+	if (nil == err) || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("Verify() with deleted record should have reported missing records... got: %v", err)
+	}
+
+	_, _, err = Verify(bytes.NewReader(lines[1]), 0, "")
+	if nil == err {
+		t.Fatalf("Verify() with truncated head should have failed")
+	}
+
+	// The chain is recovered when the log is reopened
+
+	signal("Audit.MaxFileSize=0")
+
+	logRecords(1)
+
+	lastSeq, lastHash, err = Verify(bytes.NewReader(readLog()), 0, "")
+	if nil != err {
+		t.Fatalf("Verify() after reopen failed: %v", err)
+	}
+	if 5 != lastSeq {
+		t.Fatalf("Verify() after reopen returned lastSeq == %v... expected 5", lastSeq)
+	}
+
+	// Rotated files continue the chain... and are uploaded to Swift (if configured)
+
+	signal("Audit.MaxFileSize=1024")
+
+	logRecords(10)
+	Flush()
+
+	matches, err := filepath.Glob(logFilePath + ".*")
+	if nil != err {
+		t.Fatalf("filepath.Glob() failed: %v", err)
+	}
+	if 2 > len(matches) {
+		t.Fatalf("Expected LogFilePath to have been rotated at least twice... found %v", matches)
+	}
+
+	lastSeq = 0
+	lastHash = ""
+	for i, rotatedName := range rotatedNamesOf(logFilePath, matches) {
+		buf, err := ioutil.ReadFile(filepath.Join(testDir, rotatedName))
+		if nil != err {
+			t.Fatalf("ioutil.ReadFile() failed: %v", err)
+		}
+		if (0 < i) && (1024 < len(buf)) { // The first was written before MaxFileSize was lowered
+			t.Fatalf("Rotated file %v exceeds MaxFileSize", rotatedName)
+		}
+		lastSeq, lastHash, err = Verify(bytes.NewReader(buf), lastSeq, lastHash)
+		if nil != err {
+			t.Fatalf("Verify() of rotated file %v failed: %v", rotatedName, err)
+		}
+	}
+	lastSeq, _, err = Verify(bytes.NewReader(readLog()), lastSeq, lastHash)
+	if nil != err {
+		t.Fatalf("Verify() following rotated files failed: %v", err)
+	}
+	if 15 != lastSeq {
+		t.Fatalf("Verify() following rotated files returned lastSeq == %v... expected 15", lastSeq)
+	}
+
+	signal("Audit.SwiftAccountName=AUTH_audit", "Audit.SwiftContainerName=audit")
+
+	logRecords(10)
+	Flush()
+
+	globals.uploadWG.Wait()
+
+	_, objectList, err := swiftclient.ContainerGet("AUTH_audit", "audit")
+	if nil != err {
+		t.Fatalf("swiftclient.ContainerGet() failed: %v", err)
+	}
+	uploadedNames := rotatedNamesOf(logFilePath, objectList)
+	if 0 == len(uploadedNames) {
+		t.Fatalf("Expected rotated files to have been uploaded")
+	}
+	for _, uploadedName := range uploadedNames {
+		_, err = os.Stat(filepath.Join(testDir, uploadedName))
+		if !os.IsNotExist(err) {
+			t.Fatalf("Uploaded rotated file %v should have been removed locally", uploadedName)
+		}
+	}
+
+	// With LogFilePath gone, the chain resumes from the most recently rotated (here, uploaded) file
+
+	err = os.Remove(logFilePath)
+	if nil != err {
+		t.Fatalf("os.Remove() failed: %v", err)
+	}
+
+	signal("Audit.MaxFileSize=0")
+
+	uploadedBuf, err := swiftclient.ObjectLoad("AUTH_audit", "audit", uploadedNames[len(uploadedNames)-1])
+	if nil != err {
+		t.Fatalf("swiftclient.ObjectLoad() failed: %v", err)
+	}
+	uploadedLastSeq, uploadedLastHash, _ := recoverChain(uploadedBuf)
+
+	logRecords(1)
+
+	_, _, err = Verify(bytes.NewReader(readLog()), uploadedLastSeq, uploadedLastHash)
+	if nil != err {
+		t.Fatalf("Verify() following last uploaded file failed: %v", err)
+	}
+
+	// A record torn by a crash is truncated when LogFilePath is reopened
+
+	Flush()
+
+	logFile, err := os.OpenFile(logFilePath, os.O_APPEND|os.O_WRONLY, 0600)
+	if nil != err {
+		t.Fatalf("os.OpenFile() failed: %v", err)
+	}
+	_, err = logFile.Write([]byte("{\"Seq\":"))
+	if nil != err {
+		t.Fatalf("logFile.Write() failed: %v", err)
+	}
+	err = logFile.Close()
+	if nil != err {
+		t.Fatalf("logFile.Close() failed: %v", err)
+	}
+
+	signal("Audit.MaxFileSize=0")
+
+	logRecords(1)
+
+	_, _, err = Verify(bytes.NewReader(readLog()), uploadedLastSeq, uploadedLastHash)
+	if nil != err {
+		t.Fatalf("Verify() following truncation of torn record failed: %v", err)
+	}
+
+	err = transitions.Down(confMap)
+	if nil != err {
+		t.Fatalf("transitions.Down() failed: %v", err)
+	}
+
+	unix.Kill(unix.Getpid(), unix.SIGTERM)
+	_ = <-doneChan
+}
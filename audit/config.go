@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"bufio"
+	"os"
+	"sync"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/trackedlock"
+	"github.com/swiftstack/ProxyFS/transitions"
+)
+
+const (
+	defaultMaxFileSize = uint64(64 * 1024 * 1024)
+)
+
+type settingsStruct struct {
+	enabled            bool
+	logFilePath        string
+	maxFileSize        uint64 // 0 means never rotate
+	swiftAccountName   string // "" means rotated files remain local
+	swiftContainerName string
+}
+
+// writerRequestStruct carries an encoded record (or, if flushDoneChan is non-nil, a request to be
+// signaled once all prior records have been written) to the writer.
+type writerRequestStruct struct {
+	seq           uint64
+	op            string
+	line          []byte
+	flushDoneChan chan struct{}
+}
+
+// writerStruct is owned by the goroutine (writerDaemon()) appending records to LogFilePath... such
+// that Log() callers need not wait for records to be written (nor LogFilePath to be rotated).
+type writerStruct struct {
+	settings     settingsStruct
+	requestChan  chan *writerRequestStruct
+	doneWG       sync.WaitGroup
+	file         *os.File // nil if LogFilePath could not be (re)opened
+	bufWriter    *bufio.Writer
+	fileSize     uint64
+	fileFirstSeq uint64 // Seq of the first record in file (0 if file is empty)
+}
+
+type globalsStruct struct {
+	trackedlock.Mutex
+	settings settingsStruct
+	writer   *writerStruct // nil if !settings.enabled (or LogFilePath could not be opened)
+	lastSeq  uint64
+	lastHash string
+	uploadWG sync.WaitGroup // Tracks uploads of rotated files to Swift
+}
+
+var globals globalsStruct
+
+func init() {
+	transitions.Register("audit", &globals)
+}
+
+func fetchSettings(confMap conf.ConfMap) (settings settingsStruct, err error) {
+	settings.enabled, err = confMap.FetchOptionValueBool("Audit", "Enabled")
+	if (nil != err) || !settings.enabled {
+		// Treat a missing [Audit] section as auditing disabled
+		settings = settingsStruct{}
+		err = nil
+		return
+	}
+
+	settings.logFilePath, err = confMap.FetchOptionValueString("Audit", "LogFilePath")
+	if nil != err {
+		return
+	}
+
+	settings.maxFileSize, err = confMap.FetchOptionValueUint64("Audit", "MaxFileSize")
+	if nil != err {
+		settings.maxFileSize = defaultMaxFileSize // TODO: Eventually, just return
+	}
+
+	settings.swiftAccountName, err = confMap.FetchOptionValueString("Audit", "SwiftAccountName")
+	if nil != err {
+		settings.swiftAccountName = "" // TODO: Eventually, just return
+	}
+
+	if "" != settings.swiftAccountName {
+		settings.swiftContainerName, err = confMap.FetchOptionValueString("Audit", "SwiftContainerName")
+		if nil != err {
+			return
+		}
+	}
+
+	err = nil
+	return
+}
+
+func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
+	settings, err := fetchSettings(confMap)
+	if nil != err {
+		return
+	}
+
+	globals.Lock()
+	err = openWhileLocked(settings)
+	globals.Unlock()
+
+	return
+}
+
+func (dummy *globalsStruct) VolumeGroupCreated(confMap conf.ConfMap, volumeGroupName string, activePeer string, virtualIPAddr string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) VolumeGroupMoved(confMap conf.ConfMap, volumeGroupName string, activePeer string, virtualIPAddr string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) VolumeGroupDestroyed(confMap conf.ConfMap, volumeGroupName string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) VolumeCreated(confMap conf.ConfMap, volumeName string, volumeGroupName string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) VolumeMoved(confMap conf.ConfMap, volumeName string, volumeGroupName string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) VolumeDestroyed(confMap conf.ConfMap, volumeName string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) ServeVolume(confMap conf.ConfMap, volumeName string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) UnserveVolume(confMap conf.ConfMap, volumeName string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) SignaledStart(confMap conf.ConfMap) (err error) {
+	return nil
+}
+
+func (dummy *globalsStruct) SignaledFinish(confMap conf.ConfMap) (err error) {
+	settings, err := fetchSettings(confMap)
+	if nil != err {
+		logger.ErrorfWithError(err, "audit settings unchanged")
+		err = nil
+		return
+	}
+
+	// Reopening recovers Seq & Hash from LogFilePath... so the chain continues if it is unchanged
+
+	globals.Lock()
+	closeWhileLocked()
+	err = openWhileLocked(settings)
+	globals.Unlock()
+
+	return
+}
+
+func (dummy *globalsStruct) Down(confMap conf.ConfMap) (err error) {
+	globals.Lock()
+	closeWhileLocked()
+	globals.Unlock()
+
+	globals.uploadWG.Wait()
+
+	err = nil
+	return
+}
@@ -0,0 +1,597 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+)
+
+const (
+	hashFieldPrefix = ",\"Hash\":\""
+	hashFieldSuffix = "\"}"
+
+	maxRecordSize = 1024 * 1024 // Used by verify() to bound the size of a single line
+
+	writerRequestChanDepth = 1024 // Records queued for the writer beyond this block Log() callers
+)
+
+func isEnabled() (enabled bool) {
+	globals.Lock()
+	enabled = globals.settings.enabled
+	globals.Unlock()
+	return
+}
+
+// encodeRecord returns the JSON line for record after setting record.Hash. As Hash is the last field,
+// it is simply spliced onto the encoding of the remainder of record.
+func encodeRecord(record *Record) (line []byte, err error) {
+	var (
+		hash [sha256.Size]byte
+	)
+
+	record.Hash = ""
+
+	line, err = json.Marshal(record)
+	if nil != err {
+		return
+	}
+
+	hash = sha256.Sum256(line)
+	record.Hash = hex.EncodeToString(hash[:])
+
+	line = append(line[:len(line)-1], []byte(hashFieldPrefix+record.Hash+hashFieldSuffix+"\n")...)
+
+	err = nil
+	return
+}
+
+// hashOfLine recomputes the Hash of the (newline stripped) line having the supplied recordedHash.
+func hashOfLine(line []byte, recordedHash string) (hash string, ok bool) {
+	var (
+		hashFieldSuffixLen int
+		sum                [sha256.Size]byte
+		unhashed           []byte
+	)
+
+	hashFieldSuffixLen = len(hashFieldPrefix) + len(recordedHash) + len(hashFieldSuffix)
+
+	if !bytes.HasSuffix(line, []byte(hashFieldPrefix+recordedHash+hashFieldSuffix)) {
+		ok = false
+		return
+	}
+
+	unhashed = make([]byte, 0, len(line)-hashFieldSuffixLen+1)
+	unhashed = append(unhashed, line[:len(line)-hashFieldSuffixLen]...)
+	unhashed = append(unhashed, '}')
+
+	sum = sha256.Sum256(unhashed)
+	hash = hex.EncodeToString(sum[:])
+	ok = true
+
+	return
+}
+
+func logRecord(record *Record, opErr error) {
+	var (
+		err  error
+		line []byte
+	)
+
+	globals.Lock()
+	defer globals.Unlock()
+
+	if !globals.settings.enabled {
+		return
+	}
+
+	record.Seq = globals.lastSeq + 1
+	record.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	if nil == opErr {
+		record.Result = ResultOK
+	} else {
+		record.Result = opErr.Error()
+	}
+	record.PrevHash = globals.lastHash
+
+	line, err = encodeRecord(record)
+
+	// Even should the record fail to be written, its Seq is consumed (so that the gap is detectable)
+
+	globals.lastSeq = record.Seq
+	globals.lastHash = record.Hash
+
+	if nil != err {
+		logger.ErrorfWithError(err, "audit record Seq %v (Op %v) could not be encoded", record.Seq, record.Op)
+		return
+	}
+
+	if nil == globals.writer {
+		logger.Errorf("audit record Seq %v (Op %v) lost as %v is not open", record.Seq, record.Op, globals.settings.logFilePath)
+		return
+	}
+
+	// Queueing while still locked ensures the writer receives records in Seq order
+
+	globals.writer.requestChan <- &writerRequestStruct{seq: record.Seq, op: record.Op, line: line}
+}
+
+// flushRecords blocks until the writer has written all records queued by logRecord() so far.
+func flushRecords() {
+	var (
+		flushDoneChan chan struct{}
+	)
+
+	globals.Lock()
+
+	if nil == globals.writer {
+		globals.Unlock()
+		return
+	}
+
+	flushDoneChan = make(chan struct{})
+
+	globals.writer.requestChan <- &writerRequestStruct{flushDoneChan: flushDoneChan}
+
+	globals.Unlock()
+
+	<-flushDoneChan
+}
+
+// writerDaemon appends the records it receives to LogFilePath (rotating it as necessary) until
+// requestChan is closed. Records are buffered... but flushed whenever requestChan is drained.
+func (writer *writerStruct) writerDaemon() {
+	var (
+		request *writerRequestStruct
+	)
+
+	defer writer.doneWG.Done()
+
+	for request = range writer.requestChan {
+		if nil != request.flushDoneChan {
+			writer.flush()
+			close(request.flushDoneChan)
+			continue
+		}
+
+		writer.write(request)
+
+		if 0 == len(writer.requestChan) {
+			writer.flush()
+		}
+	}
+
+	writer.close()
+}
+
+func (writer *writerStruct) write(request *writerRequestStruct) {
+	var (
+		err error
+	)
+
+	if (0 < writer.settings.maxFileSize) && (0 < writer.fileSize) && (writer.settings.maxFileSize < writer.fileSize+uint64(len(request.line))) {
+		writer.rotate()
+	}
+
+	if nil == writer.file {
+		logger.Errorf("audit record Seq %v (Op %v) lost as %v is not open", request.seq, request.op, writer.settings.logFilePath)
+		return
+	}
+
+	_, err = writer.bufWriter.Write(request.line)
+	if nil != err {
+		logger.ErrorfWithError(err, "audit record Seq %v (Op %v) could not be written to %v", request.seq, request.op, writer.settings.logFilePath)
+		writer.bufWriter.Reset(writer.file)
+		return
+	}
+
+	if 0 == writer.fileFirstSeq {
+		writer.fileFirstSeq = request.seq
+	}
+	writer.fileSize += uint64(len(request.line))
+}
+
+func (writer *writerStruct) flush() {
+	var (
+		err error
+	)
+
+	if nil == writer.file {
+		return
+	}
+
+	err = writer.bufWriter.Flush()
+	if nil != err {
+		logger.ErrorfWithError(err, "audit records could not be written to %v", writer.settings.logFilePath)
+		writer.bufWriter.Reset(writer.file)
+	}
+}
+
+func (writer *writerStruct) close() {
+	var (
+		err error
+	)
+
+	if nil == writer.file {
+		return
+	}
+
+	writer.flush()
+
+	err = writer.file.Close()
+	if nil != err {
+		logger.ErrorfWithError(err, "audit LogFilePath %v could not be closed", writer.settings.logFilePath)
+	}
+
+	writer.file = nil
+}
+
+// linesOf returns the first and last (non-empty) lines of buf.
+func linesOf(buf []byte) (firstLine []byte, lastLine []byte) {
+	var (
+		lines [][]byte
+	)
+
+	lines = bytes.Split(bytes.TrimRight(buf, "\n"), []byte("\n"))
+	if (0 == len(lines)) || (0 == len(lines[0])) {
+		return
+	}
+
+	firstLine = lines[0]
+	lastLine = lines[len(lines)-1]
+
+	return
+}
+
+// recoverChain returns the Seq & Hash of the last record in buf (or ok == false if there is none).
+func recoverChain(buf []byte) (lastSeq uint64, lastHash string, ok bool) {
+	var (
+		err      error
+		lastLine []byte
+		record   Record
+	)
+
+	_, lastLine = linesOf(buf)
+	if nil == lastLine {
+		ok = false
+		return
+	}
+
+	err = json.Unmarshal(lastLine, &record)
+	if nil != err {
+		ok = false
+		return
+	}
+
+	lastSeq = record.Seq
+	lastHash = record.Hash
+	ok = true
+
+	return
+}
+
+// rotatedNamesOf returns (in the order they were rotated) the names of rotated files found in
+// nameList matching the base name of logFilePath.
+func rotatedNamesOf(logFilePath string, nameList []string) (rotatedNames []string) {
+	var (
+		name   string
+		prefix string
+	)
+
+	prefix = filepath.Base(logFilePath) + "."
+
+	rotatedNames = make([]string, 0)
+
+	for _, name = range nameList {
+		name = filepath.Base(name)
+		if strings.HasPrefix(name, prefix) && (16 == len(name)-len(prefix)) {
+			rotatedNames = append(rotatedNames, name)
+		}
+	}
+
+	sort.Strings(rotatedNames)
+
+	return
+}
+
+// recoverChainWhileLocked establishes globals.lastSeq & globals.lastHash from the last record written
+// to LogFilePath... or, if it is empty, the most recently rotated file (be it local or in Swift).
+func recoverChainWhileLocked(buf []byte) {
+	var (
+		err               error
+		localNames        []string
+		matches           []string
+		ok                bool
+		objectList        []string
+		rotatedName       string
+		swiftRotatedNames []string
+	)
+
+	globals.lastSeq, globals.lastHash, ok = recoverChain(buf)
+	if ok {
+		return
+	}
+
+	matches, err = filepath.Glob(globals.settings.logFilePath + ".*")
+	if nil == err {
+		localNames = rotatedNamesOf(globals.settings.logFilePath, matches)
+		if 0 < len(localNames) {
+			rotatedName = localNames[len(localNames)-1]
+		}
+	}
+
+	if "" != globals.settings.swiftAccountName {
+		_, objectList, err = swiftclient.ContainerGet(globals.settings.swiftAccountName, globals.settings.swiftContainerName)
+		if nil == err {
+			swiftRotatedNames = rotatedNamesOf(globals.settings.logFilePath, objectList)
+			if (0 < len(swiftRotatedNames)) && (rotatedName < swiftRotatedNames[len(swiftRotatedNames)-1]) {
+				rotatedName = swiftRotatedNames[len(swiftRotatedNames)-1]
+				buf, err = swiftclient.ObjectLoad(globals.settings.swiftAccountName, globals.settings.swiftContainerName, rotatedName)
+				if nil == err {
+					globals.lastSeq, globals.lastHash, ok = recoverChain(buf)
+					if ok {
+						return
+					}
+				}
+				rotatedName = ""
+			}
+		}
+	}
+
+	if "" != rotatedName {
+		buf, err = ioutil.ReadFile(filepath.Join(filepath.Dir(globals.settings.logFilePath), rotatedName))
+		if nil == err {
+			globals.lastSeq, globals.lastHash, ok = recoverChain(buf)
+			if ok {
+				return
+			}
+		}
+	}
+
+	logger.Warnf("audit trail in %v starting anew", globals.settings.logFilePath)
+
+	globals.lastSeq = 0
+	globals.lastHash = ""
+}
+
+// truncateTornRecord drops the partially written (i.e. unterminated) last line, if any, left in
+// LogFilePath (as read into buf) by a crash... truncating LogFilePath to match.
+func truncateTornRecord(logFilePath string, buf []byte) (truncatedBuf []byte, err error) {
+	if (0 == len(buf)) || ('\n' == buf[len(buf)-1]) {
+		truncatedBuf = buf
+		err = nil
+		return
+	}
+
+	truncatedBuf = buf[:bytes.LastIndexByte(buf, '\n')+1]
+
+	logger.Warnf("audit LogFilePath %v ends with a torn record... truncating its last %v bytes", logFilePath, len(buf)-len(truncatedBuf))
+
+	err = os.Truncate(logFilePath, int64(len(truncatedBuf)))
+
+	return
+}
+
+func openWhileLocked(settings settingsStruct) (err error) {
+	var (
+		buf       []byte
+		firstLine []byte
+		record    Record
+		writer    *writerStruct
+	)
+
+	globals.settings = settings
+
+	if !settings.enabled {
+		err = nil
+		return
+	}
+
+	if "" != settings.swiftAccountName {
+		err = swiftclient.ContainerPut(settings.swiftAccountName, settings.swiftContainerName, map[string][]string{})
+		if nil != err {
+			logger.ErrorfWithError(err, "audit container %v/%v could not be created... rotated files will remain local", settings.swiftAccountName, settings.swiftContainerName)
+		}
+	}
+
+	buf, err = ioutil.ReadFile(settings.logFilePath)
+	if (nil != err) && !os.IsNotExist(err) {
+		err = fmt.Errorf("audit LogFilePath %v could not be read: %v", settings.logFilePath, err)
+		return
+	}
+
+	buf, err = truncateTornRecord(settings.logFilePath, buf)
+	if nil != err {
+		err = fmt.Errorf("audit LogFilePath %v could not be truncated: %v", settings.logFilePath, err)
+		return
+	}
+
+	recoverChainWhileLocked(buf)
+
+	writer = &writerStruct{
+		settings:     settings,
+		requestChan:  make(chan *writerRequestStruct, writerRequestChanDepth),
+		fileSize:     uint64(len(buf)),
+		fileFirstSeq: 0,
+	}
+
+	firstLine, _ = linesOf(buf)
+	if nil != firstLine {
+		err = json.Unmarshal(firstLine, &record)
+		if nil == err {
+			writer.fileFirstSeq = record.Seq
+		}
+	}
+
+	writer.file, err = os.OpenFile(settings.logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if nil != err {
+		err = fmt.Errorf("audit LogFilePath %v could not be opened: %v", settings.logFilePath, err)
+		return
+	}
+
+	writer.bufWriter = bufio.NewWriter(writer.file)
+
+	writer.doneWG.Add(1)
+	go writer.writerDaemon()
+
+	globals.writer = writer
+
+	err = nil
+	return
+}
+
+// closeWhileLocked stops the writer (once it has written all queued records) and closes LogFilePath.
+func closeWhileLocked() {
+	if nil != globals.writer {
+		close(globals.writer.requestChan)
+		globals.writer.doneWG.Wait()
+		globals.writer = nil
+	}
+
+	globals.settings = settingsStruct{}
+}
+
+// rotate renames LogFilePath to LogFilePath.<Seq> (scheduling its upload to Swift if configured) and
+// opens an empty LogFilePath in its place.
+func (writer *writerStruct) rotate() {
+	var (
+		err         error
+		rotatedPath string
+	)
+
+	if nil == writer.file {
+		return
+	}
+
+	writer.close()
+
+	rotatedPath = fmt.Sprintf("%s.%016X", writer.settings.logFilePath, writer.fileFirstSeq)
+
+	err = os.Rename(writer.settings.logFilePath, rotatedPath)
+	if nil != err {
+		logger.ErrorfWithError(err, "audit LogFilePath %v could not be rotated", writer.settings.logFilePath)
+	} else if "" != writer.settings.swiftAccountName {
+		globals.uploadWG.Add(1)
+		go uploadRotatedFile(writer.settings.swiftAccountName, writer.settings.swiftContainerName, rotatedPath)
+	}
+
+	writer.file, err = os.OpenFile(writer.settings.logFilePath, os.O_APPEND|os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if nil != err {
+		writer.file = nil
+		logger.ErrorfWithError(err, "audit LogFilePath %v could not be reopened", writer.settings.logFilePath)
+	} else {
+		writer.bufWriter.Reset(writer.file)
+	}
+
+	writer.fileSize = 0
+	writer.fileFirstSeq = 0
+}
+
+// uploadRotatedFile PUTs rotatedPath into the specified container and, if successful, removes it.
+func uploadRotatedFile(accountName string, containerName string, rotatedPath string) {
+	var (
+		buf               []byte
+		chunkedPutContext swiftclient.ChunkedPutContext
+		err               error
+		objectName        string
+	)
+
+	defer globals.uploadWG.Done()
+
+	objectName = filepath.Base(rotatedPath)
+
+	buf, err = ioutil.ReadFile(rotatedPath)
+	if nil != err {
+		logger.ErrorfWithError(err, "audit rotated file %v could not be read", rotatedPath)
+		return
+	}
+
+	chunkedPutContext, err = swiftclient.ObjectFetchChunkedPutContext(accountName, containerName, objectName, "")
+	if nil == err {
+		err = chunkedPutContext.SendChunk(buf)
+		if nil == err {
+			err = chunkedPutContext.Close()
+		} else {
+			_ = chunkedPutContext.Close()
+		}
+	}
+	if nil != err {
+		logger.ErrorfWithError(err, "audit rotated file %v could not be uploaded to %v/%v/%v", rotatedPath, accountName, containerName, objectName)
+		return
+	}
+
+	err = os.Remove(rotatedPath)
+	if nil != err {
+		logger.ErrorfWithError(err, "audit rotated file %v could not be removed following upload", rotatedPath)
+	}
+}
+
+func verify(reader io.Reader, prevSeq uint64, prevHash string) (lastSeq uint64, lastHash string, err error) {
+	var (
+		hash       string
+		line       []byte
+		lineNumber uint64
+		ok         bool
+		record     Record
+		scanner    *bufio.Scanner
+	)
+
+	lastSeq = prevSeq
+	lastHash = prevHash
+
+	scanner = bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+
+	for scanner.Scan() {
+		lineNumber++
+		line = scanner.Bytes()
+
+		record = Record{}
+
+		err = json.Unmarshal(line, &record)
+		if nil != err {
+			err = fmt.Errorf("line %v: malformed record: %v", lineNumber, err)
+			return
+		}
+
+		hash, ok = hashOfLine(line, record.Hash)
+		if !ok || (hash != record.Hash) {
+			err = fmt.Errorf("line %v (Seq %v): Hash mismatch... record has been altered", lineNumber, record.Seq)
+			return
+		}
+
+		if (lastSeq + 1) != record.Seq {
+			err = fmt.Errorf("line %v (Seq %v): expected Seq %v... records are missing or out of order", lineNumber, record.Seq, lastSeq+1)
+			return
+		}
+
+		if lastHash != record.PrevHash {
+			err = fmt.Errorf("line %v (Seq %v): PrevHash does not match Hash of Seq %v", lineNumber, record.Seq, lastSeq)
+			return
+		}
+
+		lastSeq = record.Seq
+		lastHash = record.Hash
+	}
+
+	err = scanner.Err()
+	if nil != err {
+		err = fmt.Errorf("line %v: %v", lineNumber+1, err)
+		return
+	}
+
+	err = nil
+	return
+}
@@ -63,6 +63,7 @@ const (
 	NotPermError          FsError = FsError(int(unix.EPERM))        // Operation not permitted
 	NotFoundError         FsError = FsError(int(unix.ENOENT))       // No such file or directory
 	IOError               FsError = FsError(int(unix.EIO))          // I/O error
+	NoDeviceOrAddrError   FsError = FsError(int(unix.ENXIO))        // No such device or address
 	TooBigError           FsError = FsError(int(unix.E2BIG))        // Argument list too long
 	TooManyArgsError      FsError = FsError(int(unix.E2BIG))        // Arg list too long
 	BadFileError          FsError = FsError(int(unix.EBADF))        // Bad file number
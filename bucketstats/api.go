@@ -117,6 +117,30 @@ func SprintStats(stringFmt StatStringFormat, pkgName string, statsGroupName stri
 	return sprintStats(stringFmt, pkgName, statsGroupName)
 }
 
+// Print one or more groups of statistics in the Prometheus text exposition
+// format (version 0.0.4).
+//
+// pkgName and statsGroupName select groups as for SprintStats().  Statistics
+// whose names end in Usec, Bytes, Entries, Errors, Failure, or RetryOps become
+// series, labeled with the "operation" preceding the suffix, of a metric family
+// per package and suffix (e.g. "proxyfs.fs" statistic "ReadUsec" becomes
+// proxyfs_fs_usec{operation="Read"}).  Other statistics get a family of their
+// own.  A Total is a counter, an Average a summary, and a bucketized statistic
+// a histogram with a bucket per bucket of the statistic.
+//
+// constLabels (e.g. the peer) are added to every series, as is a "group" label
+// for groups registered with both a pkgName and a statsGroupName.
+//
+// Series carry no "volume" label: each group is a single set of statistics
+// (those registered by ProxyFS packages being aggregated across all volumes a
+// peer serves), and nothing identifies a group with a volume.  A package
+// keeping per-volume statistics would register a group per volume (e.g. named
+// for the volume) and so have them labeled by "group".
+//
+func SprintPrometheus(pkgName string, statsGroupName string, constLabels map[string]string) (values string) {
+	return sprintPrometheus(pkgName, statsGroupName, constLabels)
+}
+
 // Total is a simple totaler. It supports the Totaler interface.
 //
 // Name must be unique within statistics in the structure.  If it is "" then
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -545,6 +546,80 @@ func TestSprintStats(t *testing.T) {
 	}
 }
 
+func TestSprintPrometheus(t *testing.T) {
+
+	type prometheusStats struct {
+		ReadUsec     BucketLog2Round
+		WriteUsec    BucketLog2Round
+		ReadErrors   Total
+		RetryAverage Average
+		ReadBytes    BucketLogRoot2Round
+		WriteBytes   Average // not a histogram, so not part of the "bytes" family
+	}
+
+	var myStats prometheusStats
+
+	Register("test.prom", "grp", &myStats)
+	defer UnRegister("test.prom", "grp")
+
+	myStats.ReadUsec.Add(1)
+	myStats.ReadUsec.Add(3)
+	myStats.ReadUsec.Add(100)
+	myStats.ReadErrors.Add(2)
+	myStats.RetryAverage.Add(4)
+	myStats.RetryAverage.Add(6)
+	myStats.ReadBytes.Add(4096)
+	myStats.WriteBytes.Add(8)
+
+	promString := SprintPrometheus("test.prom", "grp", map[string]string{"peer": "P\"0"})
+
+	labels := `peer="P\"0",group="grp"`
+	expectedLines := []string{
+		"# TYPE test_prom_usec histogram",
+		"test_prom_usec_bucket{" + labels + `,operation="Read",le="+Inf"} 3`,
+		"test_prom_usec_count{" + labels + `,operation="Read"} 3`,
+		"test_prom_usec_bucket{" + labels + `,operation="Write",le="+Inf"} 0`,
+		"test_prom_usec_count{" + labels + `,operation="Write"} 0`,
+		"# TYPE test_prom_errors_total counter",
+		"test_prom_errors_total{" + labels + `,operation="Read"} 2`,
+		"# TYPE test_prom_retry_average summary",
+		"test_prom_retry_average_sum{" + labels + "} 10",
+		"test_prom_retry_average_count{" + labels + "} 2",
+		"# TYPE test_prom_bytes histogram",
+		"test_prom_bytes_count{" + labels + `,operation="Read"} 1`,
+		"# TYPE test_prom_write_bytes summary",
+		"test_prom_write_bytes_sum{" + labels + "} 8",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(promString, expectedLine+"\n") {
+			t.Errorf("SprintPrometheus() output lacks line '%s':\n%s", expectedLine, promString)
+		}
+	}
+
+	// each family is introduced once... and the buckets of a histogram are cumulative
+	typeLines := make(map[string]bool)
+	lastCount := uint64(0)
+	for _, line := range strings.Split(promString, "\n") {
+		if strings.HasPrefix(line, "# TYPE ") {
+			if typeLines[line] {
+				t.Errorf("SprintPrometheus() repeated '%s'", line)
+			}
+			typeLines[line] = true
+		}
+		if strings.HasPrefix(line, "test_prom_usec_bucket{"+labels+`,operation="Read"`) {
+			var count uint64
+			_, err := fmt.Sscanf(line[strings.LastIndex(line, " ")+1:], "%d", &count)
+			if err != nil || count < lastCount {
+				t.Errorf("SprintPrometheus() histogram bucket not cumulative: '%s'", line)
+			}
+			lastCount = count
+		}
+	}
+	if lastCount != 3 {
+		t.Errorf("SprintPrometheus() histogram buckets should have reached a count of 3 (got %d)", lastCount)
+	}
+}
+
 // Invoke function aFunc, which is expected to panic.  If it does, return the
 // value returned by recover() as a string, otherwise return the empty string.
 //
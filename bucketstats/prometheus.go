@@ -0,0 +1,295 @@
+// Rendering of statistics in the Prometheus text exposition format (version 0.0.4).
+
+package bucketstats
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Statistic names ending in one of these suffixes become a series (labeled by the operation
+// preceding the suffix) of a metric family shared by all such statistics of a package.
+var prometheusSuffixList = []struct {
+	suffix string
+	unit   string
+}{
+	{"Usec", "usec"},
+	{"Bytes", "bytes"},
+	{"Entries", "entries"},
+	{"Errors", "errors"},
+	{"Failure", "failures"},
+	{"RetryOps", "retry_ops"},
+}
+
+var prometheusLabelValueReplacer = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+var prometheusHelpReplacer = strings.NewReplacer("\\", "\\\\", "\n", "\\n")
+
+type prometheusFamilyStruct struct {
+	name       string
+	help       string
+	metricType string // One of "counter", "summary", or "histogram"
+	samples    []string
+}
+
+// Return the selected group(s) of statistics in the Prometheus text exposition format.
+func sprintPrometheus(pkgName string, statsGroupName string, constLabels map[string]string) (statValues string) {
+
+	statsNameMapLock.Lock()
+	defer statsNameMapLock.Unlock()
+
+	var (
+		pkgNameMap   map[string]map[string]interface{}
+		groupNameMap map[string]interface{}
+	)
+	if pkgName == "*" {
+		pkgNameMap = pkgNameToGroupName
+	} else {
+		// make a map with a single entry for the pkgName
+		pkgNameMap = map[string]map[string]interface{}{pkgName: nil}
+	}
+
+	// the constant labels are rendered once (in sorted order)
+	constLabelNames := make([]string, 0, len(constLabels))
+	for labelName := range constLabels {
+		constLabelNames = append(constLabelNames, labelName)
+	}
+	sort.Strings(constLabelNames)
+
+	constLabelPairs := make([]string, 0, len(constLabels))
+	for _, labelName := range constLabelNames {
+		constLabelPairs = append(constLabelPairs, prometheusLabelPair(labelName, constLabels[labelName]))
+	}
+
+	familyMap := make(map[string]*prometheusFamilyStruct)
+
+	for pkg := range pkgNameMap {
+		if statsGroupName == "*" {
+			groupNameMap = pkgNameToGroupName[pkg]
+		} else {
+			// make a map with a single entry for the statsGroupName
+			groupNameMap = map[string]interface{}{statsGroupName: nil}
+		}
+
+		for group := range groupNameMap {
+			_, ok := pkgNameToGroupName[pkg][group]
+			if !ok {
+				panic(fmt.Sprintf(
+					"bucketstats.sprintPrometheus(): statistics group '%s.%s' is not registered",
+					pkg, group))
+			}
+			prometheusStatsStruct(familyMap, constLabelPairs, pkg, group, pkgNameToGroupName[pkg][group])
+		}
+	}
+
+	// families are emitted in name order, each with its HELP and TYPE
+	familyNames := make([]string, 0, len(familyMap))
+	for familyName := range familyMap {
+		familyNames = append(familyNames, familyName)
+	}
+	sort.Strings(familyNames)
+
+	var builder strings.Builder
+
+	for _, familyName := range familyNames {
+		family := familyMap[familyName]
+		fmt.Fprintf(&builder, "# HELP %s %s\n", family.name, prometheusHelpReplacer.Replace(family.help))
+		fmt.Fprintf(&builder, "# TYPE %s %s\n", family.name, family.metricType)
+		for _, sample := range family.samples {
+			builder.WriteString(sample)
+		}
+	}
+
+	statValues = builder.String()
+	return
+}
+
+// Add the samples of the statistics in statsStruct to the metric families in familyMap.
+func prometheusStatsStruct(familyMap map[string]*prometheusFamilyStruct, constLabelPairs []string,
+	pkgName string, statsGroupName string, statsStruct interface{}) {
+
+	structAsValue := reflect.ValueOf(statsStruct).Elem()
+	structAsType := structAsValue.Type()
+
+	for i := 0; i < structAsType.NumField(); i++ {
+		fieldAsType := structAsType.Field(i).Type
+		fieldAsValue := structAsValue.Field(i)
+
+		// ignore fields that are not a BucketStats type
+		var (
+			countStat          Total
+			averageStat        Average
+			bucketLog2Stat     BucketLog2Round
+			bucketLogRoot2Stat BucketLogRoot2Round
+		)
+		if fieldAsType != reflect.TypeOf(countStat) &&
+			fieldAsType != reflect.TypeOf(averageStat) &&
+			fieldAsType != reflect.TypeOf(bucketLog2Stat) &&
+			fieldAsType != reflect.TypeOf(bucketLogRoot2Stat) {
+			continue
+		}
+
+		switch v := (fieldAsValue.Addr().Interface()).(type) {
+		case *Total:
+			family, labelPairs := prometheusFamily(familyMap, constLabelPairs, pkgName, statsGroupName, v.Name, "counter")
+			family.samples = append(family.samples,
+				prometheusSample(family.name, labelPairs, v.TotalGet()))
+		case *Average:
+			family, labelPairs := prometheusFamily(familyMap, constLabelPairs, pkgName, statsGroupName, v.Name, "summary")
+			family.samples = append(family.samples,
+				prometheusSample(family.name+"_sum", labelPairs, v.TotalGet()),
+				prometheusSample(family.name+"_count", labelPairs, v.CountGet()))
+		case *BucketLog2Round:
+			family, labelPairs := prometheusFamily(familyMap, constLabelPairs, pkgName, statsGroupName, v.Name, "histogram")
+			family.samples = append(family.samples, prometheusHistogramSamples(family.name, labelPairs, v.DistGet())...)
+		case *BucketLogRoot2Round:
+			family, labelPairs := prometheusFamily(familyMap, constLabelPairs, pkgName, statsGroupName, v.Name, "histogram")
+			family.samples = append(family.samples, prometheusHistogramSamples(family.name, labelPairs, v.DistGet())...)
+		default:
+			panic(fmt.Sprintf("Unknown type in struct: %s", fieldAsType.Name()))
+		}
+	}
+}
+
+// Find (or create) the metric family for statistic statName, returning it along with the labels
+// identifying the statistic's series within it.
+//
+// If the family that statName's suffix selects already holds statistics of another type, the
+// statistic gets a family of its own (named for the whole statName) instead.
+func prometheusFamily(familyMap map[string]*prometheusFamilyStruct, constLabelPairs []string,
+	pkgName string, statsGroupName string, statName string, metricType string) (family *prometheusFamilyStruct, labelPairs []string) {
+
+	var (
+		familyName string
+		help       string
+		ok         bool
+		operation  string
+		prefix     string
+		source     string
+	)
+
+	labelPairs = append([]string{}, constLabelPairs...)
+
+	// the package name prefixes the family name... or, if "", the group name does
+	if pkgName == "" {
+		source = statsGroupName
+		prefix = prometheusScrubName(statsGroupName) + "_"
+	} else {
+		source = pkgName
+		prefix = prometheusScrubName(pkgName) + "_"
+		if statsGroupName != "" {
+			labelPairs = append(labelPairs, prometheusLabelPair("group", statsGroupName))
+		}
+	}
+
+	for _, prometheusSuffix := range prometheusSuffixList {
+		if strings.HasSuffix(statName, prometheusSuffix.suffix) && (len(statName) > len(prometheusSuffix.suffix)) {
+			familyName = prefix + prometheusSuffix.unit
+			operation = strings.TrimSuffix(statName, prometheusSuffix.suffix)
+			help = fmt.Sprintf("%s *%s statistics by operation", source, prometheusSuffix.suffix)
+			break
+		}
+	}
+	if metricType == "counter" && familyName != "" {
+		familyName += "_total"
+	}
+
+	family, ok = familyMap[familyName]
+	if familyName == "" || (ok && family.metricType != metricType) {
+		familyName = prefix + prometheusSnakeCase(statName)
+		if metricType == "counter" {
+			familyName += "_total"
+		}
+		operation = ""
+		help = fmt.Sprintf("%s %s statistic", source, statName)
+		family, ok = familyMap[familyName]
+	}
+
+	if !ok {
+		family = &prometheusFamilyStruct{
+			name:       familyName,
+			help:       help,
+			metricType: metricType,
+		}
+		familyMap[familyName] = family
+	}
+
+	if operation != "" {
+		labelPairs = append(labelPairs, prometheusLabelPair("operation", operation))
+	}
+	return
+}
+
+// Return the cumulative "_bucket" samples (one per bucket, empty or not, such that every series
+// of a family has the same buckets, plus the "+Inf" bucket) followed by the "_sum" and "_count"
+// samples of a bucketized statistic.
+func prometheusHistogramSamples(familyName string, labelPairs []string, bucketInfo []BucketInfo) (samples []string) {
+
+	_, _, count, sum, _ := bucketCalcStat(bucketInfo)
+
+	var cumulativeCount uint64
+	for idx := 0; idx < len(bucketInfo); idx += 1 {
+		cumulativeCount += bucketInfo[idx].Count
+		leLabelPair := prometheusLabelPair("le", fmt.Sprintf("%d", bucketInfo[idx].RangeHigh))
+		samples = append(samples,
+			prometheusSample(familyName+"_bucket", append(labelPairs[:len(labelPairs):len(labelPairs)], leLabelPair), cumulativeCount))
+	}
+	samples = append(samples,
+		prometheusSample(familyName+"_bucket", append(labelPairs[:len(labelPairs):len(labelPairs)], prometheusLabelPair("le", "+Inf")), count),
+		prometheusSample(familyName+"_sum", labelPairs, sum),
+		prometheusSample(familyName+"_count", labelPairs, count))
+	return
+}
+
+func prometheusSample(sampleName string, labelPairs []string, value uint64) string {
+	if len(labelPairs) == 0 {
+		return fmt.Sprintf("%s %d\n", sampleName, value)
+	}
+	return fmt.Sprintf("%s{%s} %d\n", sampleName, strings.Join(labelPairs, ","), value)
+}
+
+func prometheusLabelPair(labelName string, labelValue string) string {
+	return labelName + "=\"" + prometheusLabelValueReplacer.Replace(labelValue) + "\""
+}
+
+// Replace characters not allowed in Prometheus metric names with underbar (`_`).
+func prometheusScrubName(name string) string {
+
+	replaceChar := func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r
+		case r >= '0' && r <= '9':
+			return r
+		case r == '_' || r == ':':
+			return r
+		}
+		return '_'
+	}
+
+	return strings.Map(replaceChar, name)
+}
+
+// Convert a (CamelCase) statistic name to snake_case (e.g. "ObjectPutCtxtBytesPut" to
+// "object_put_ctxt_bytes_put").
+func prometheusSnakeCase(name string) string {
+
+	var (
+		prevRune rune
+		snake    []rune
+	)
+
+	for i, r := range prometheusScrubName(name) {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(prevRune) || unicode.IsDigit(prevRune)) {
+			snake = append(snake, '_')
+		}
+		snake = append(snake, unicode.ToLower(r))
+		prevRune = r
+	}
+
+	return string(snake)
+}
@@ -0,0 +1,89 @@
+// Package cachebudget shares a single memory budget amongst the various caches of ProxyFS.
+//
+// Rather than each cache being sized by its own static entry or line count limits, caches register
+// here and are periodically handed a byte budget. The total budget is [Peer:<WhoAmI>]CacheMemoryFraction
+// of platform.MemSize() (discounted by platform.GoHeapAllocationMultiplier). Each cache receives a
+// minimum share with the remainder divided in proportion to its recent misses... such that a cache
+// suffering misses grows at the expense of those that are not (in the spirit of ARC's adaptation).
+//
+// Within its share, each cache evicts per the 2Q algorithm (both the B+Tree node caches provided by
+// sortedmap and the Volume Group Read Caches in package inode) such that scans do not flush out
+// repeatedly referenced entries.
+//
+// If CacheMemoryFraction is zero (or absent), each cache is handed a zero budget meaning it should
+// adopt its statically configured limits. In either case, per-cache stats are available via Dump().
+//
+// The budget is re-read when ProxyFS is signaled (e.g. via SIGHUP).
+package cachebudget
+
+import (
+	"github.com/swiftstack/sortedmap"
+)
+
+// Cache is the interface a cache must implement to draw from the shared budget.
+type Cache interface {
+	// Stats returns the cumulative hits & misses of the cache as well as the bytes it currently holds.
+	Stats() (hits uint64, misses uint64, bytes uint64)
+	// SetBudget adjusts the cache's size to budgetBytes... or, if zero, to its static configuration.
+	SetBudget(budgetBytes uint64)
+}
+
+// CacheStats is the per-cache information returned by Dump().
+type CacheStats struct {
+	Hits        uint64
+	Misses      uint64
+	Bytes       uint64
+	BudgetBytes uint64 // zero if no shared budget is in force
+}
+
+// Register adds a cache to those sharing the budget. The name must be unique and suitable for use
+// in a metric name (e.g. "headhunter_inodeRec" or "readcache_<VolumeGroupName>").
+func Register(name string, cache Cache) {
+	register(name, cache)
+}
+
+// UnRegister removes a cache previously added via Register().
+func UnRegister(name string) {
+	unRegister(name)
+}
+
+// Dump returns the current CacheStats of every registered cache (keyed by name).
+func Dump() (cacheStatsMap map[string]CacheStats) {
+	cacheStatsMap = dump()
+	return
+}
+
+// BPlusTreeCache adapts a sortedmap.BPlusTreeCache (whose limits are in nodes) to the Cache interface.
+//
+// Stats() reports the bytes held as accounted by sortedmap (the serialized size of each cached node).
+// As budgets must be converted to limits in nodes, the caller should report the size of each node
+// it fetches via NoteNodeBytes() so that a running average node size may be used to do so.
+type BPlusTreeCache struct {
+	sortedmap.BPlusTreeCache
+	staticEvictLowLimit  uint64
+	staticEvictHighLimit uint64
+	avgNodeBytes         uint64 // accessed atomically
+}
+
+// NewBPlusTreeCache constructs a sortedmap.BPlusTreeCache with static limits evictLowLimit & evictHighLimit
+// (used whenever no shared budget is in force) along with its BPlusTreeCache adapter.
+func NewBPlusTreeCache(evictLowLimit uint64, evictHighLimit uint64, nodeBytesEstimate uint64) (bPlusTreeCache *BPlusTreeCache) {
+	bPlusTreeCache = newBPlusTreeCache(evictLowLimit, evictHighLimit, nodeBytesEstimate)
+	return
+}
+
+// NoteNodeBytes folds the size of a just fetched node into the running average node size.
+func (bPlusTreeCache *BPlusTreeCache) NoteNodeBytes(nodeBytes uint64) {
+	bPlusTreeCache.noteNodeBytes(nodeBytes)
+}
+
+// Stats implements Cache.Stats().
+func (bPlusTreeCache *BPlusTreeCache) Stats() (hits uint64, misses uint64, bytes uint64) {
+	hits, misses, bytes = bPlusTreeCache.stats()
+	return
+}
+
+// SetBudget implements Cache.SetBudget().
+func (bPlusTreeCache *BPlusTreeCache) SetBudget(budgetBytes uint64) {
+	bPlusTreeCache.setBudget(budgetBytes)
+}
@@ -0,0 +1,160 @@
+package cachebudget
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/transitions"
+)
+
+type testCacheStruct struct {
+	sync.Mutex
+	hits        uint64
+	misses      uint64
+	budgetBytes uint64
+}
+
+func (testCache *testCacheStruct) Stats() (hits uint64, misses uint64, bytes uint64) {
+	testCache.Lock()
+	hits = testCache.hits
+	misses = testCache.misses
+	bytes = testCache.budgetBytes
+	testCache.Unlock()
+	return
+}
+
+func (testCache *testCacheStruct) SetBudget(budgetBytes uint64) {
+	testCache.Lock()
+	testCache.budgetBytes = budgetBytes
+	testCache.Unlock()
+}
+
+func TestAPI(t *testing.T) {
+	testConfMapStrings := []string{
+		"Logging.LogFilePath=/dev/null",
+		"Cluster.WhoAmI=Peer0",
+		"Peer:Peer0.CacheMemoryFraction=0.25",
+		"Peer:Peer0.CacheRebalanceInterval=1h",
+		"FSGlobals.VolumeGroupList=",
+		"FSGlobals.TryLockBackoffMin=100us",
+		"FSGlobals.TryLockBackoffMax=300us",
+		"FSGlobals.SymlinkMax=32",
+	}
+
+	testConfMap, err := conf.MakeConfMapFromStrings(testConfMapStrings)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	err = transitions.Up(testConfMap)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	totalBudgetBytes := globals.totalBudgetBytes
+	if 0 == totalBudgetBytes {
+		t.Fatalf("CacheMemoryFraction=0.25 should have established a non-zero budget")
+	}
+
+	testCacheA := &testCacheStruct{}
+	testCacheB := &testCacheStruct{}
+
+	Register("testCacheA", testCacheA)
+	Register("testCacheB", testCacheB)
+
+	// With no misses, the budget should be split evenly
+
+	if (testCacheA.budgetBytes != testCacheB.budgetBytes) || (testCacheA.budgetBytes+testCacheB.budgetBytes > totalBudgetBytes) {
+		t.Fatalf("Idle caches should share the budget evenly (got %v & %v of %v)", testCacheA.budgetBytes, testCacheB.budgetBytes, totalBudgetBytes)
+	}
+
+	// A cache suffering misses should grow at the expense of one that isn't... but never below its floor
+
+	for i := 0; i < 8; i++ {
+		testCacheA.Lock()
+		testCacheA.misses += 1000
+		testCacheA.Unlock()
+		rebalance()
+	}
+
+	if testCacheA.budgetBytes <= testCacheB.budgetBytes {
+		t.Fatalf("Missing cache should have a larger budget (got %v vs %v)", testCacheA.budgetBytes, testCacheB.budgetBytes)
+	}
+	if testCacheB.budgetBytes < totalBudgetBytes/8 {
+		t.Fatalf("Idle cache should retain at least its floor (got %v of %v)", testCacheB.budgetBytes, totalBudgetBytes)
+	}
+	if testCacheA.budgetBytes+testCacheB.budgetBytes > totalBudgetBytes {
+		t.Fatalf("Budgets exceed total (got %v + %v > %v)", testCacheA.budgetBytes, testCacheB.budgetBytes, totalBudgetBytes)
+	}
+
+	cacheStatsMap := Dump()
+	if 2 != len(cacheStatsMap) {
+		t.Fatalf("Dump() should have returned 2 caches (not %v)", len(cacheStatsMap))
+	}
+	cacheStats, ok := cacheStatsMap["testCacheA"]
+	if !ok {
+		t.Fatalf("Dump() missing testCacheA")
+	}
+	if (8000 != cacheStats.Misses) || (testCacheA.budgetBytes != cacheStats.BudgetBytes) || (testCacheA.budgetBytes != cacheStats.Bytes) {
+		t.Fatalf("Dump() returned unexpected testCacheA stats: %+v", cacheStats)
+	}
+
+	// Dropping CacheMemoryFraction upon a signal should revert caches to their static configuration
+
+	err = testConfMap.UpdateFromString("Peer:Peer0.CacheMemoryFraction=0")
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	err = transitions.Signaled(testConfMap)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if (0 != testCacheA.budgetBytes) || (0 != testCacheB.budgetBytes) {
+		t.Fatalf("Zero CacheMemoryFraction should have reset budgets (got %v & %v)", testCacheA.budgetBytes, testCacheB.budgetBytes)
+	}
+
+	UnRegister("testCacheA")
+	UnRegister("testCacheB")
+
+	err = transitions.Down(testConfMap)
+	if nil != err {
+		t.Fatal(err)
+	}
+}
+
+func TestBPlusTreeCache(t *testing.T) {
+	bPlusTreeCache := NewBPlusTreeCache(80, 100, 1000)
+
+	_, _, bytes := bPlusTreeCache.Stats()
+	if 0 != bytes {
+		t.Fatalf("Stats() returned bytes == %v for an empty cache (expected 0)", bytes)
+	}
+
+	bPlusTreeCache.SetBudget(1000 * 1000)
+
+	_, _, evictLowLimit, evictHighLimit := bPlusTreeCache.BPlusTreeCache.Stats()
+	if (800 != evictLowLimit) || (1000 != evictHighLimit) {
+		t.Fatalf("SetBudget() yielded limits %v/%v (expected 800/1000)", evictLowLimit, evictHighLimit)
+	}
+
+	for i := 0; i < 256; i++ {
+		bPlusTreeCache.NoteNodeBytes(2000)
+	}
+
+	bPlusTreeCache.SetBudget(1000 * 1000)
+
+	_, _, evictLowLimit, evictHighLimit = bPlusTreeCache.BPlusTreeCache.Stats()
+	if (evictHighLimit < 500) || (evictHighLimit > 520) {
+		t.Fatalf("SetBudget() after larger nodes yielded evictHighLimit %v (expected ~500)", evictHighLimit)
+	}
+
+	bPlusTreeCache.SetBudget(0)
+
+	_, _, evictLowLimit, evictHighLimit = bPlusTreeCache.BPlusTreeCache.Stats()
+	if (80 != evictLowLimit) || (100 != evictHighLimit) {
+		t.Fatalf("SetBudget(0) yielded limits %v/%v (expected static 80/100)", evictLowLimit, evictHighLimit)
+	}
+}
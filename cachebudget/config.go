@@ -0,0 +1,148 @@
+package cachebudget
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/platform"
+	"github.com/swiftstack/ProxyFS/trackedlock"
+	"github.com/swiftstack/ProxyFS/transitions"
+)
+
+type cacheStruct struct {
+	name         string
+	cache        Cache
+	priorMisses  uint64
+	missPressure uint64 // decaying average of misses per rebalanceInterval
+	budgetBytes  uint64
+}
+
+type globalsStruct struct {
+	trackedlock.Mutex
+	cacheMap          map[string]*cacheStruct // key == cacheStruct.name
+	totalBudgetBytes  uint64                  // if zero, each cache adopts its static configuration
+	rebalanceInterval time.Duration
+	rebalanceStopChan chan struct{}
+	rebalanceDoneChan chan struct{}
+}
+
+var globals globalsStruct
+
+func init() {
+	transitions.Register("cachebudget", &globals)
+}
+
+func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
+	globals.cacheMap = make(map[string]*cacheStruct)
+
+	// The budget is fetched (and the rebalancer started) in SignaledFinish() that follows
+
+	err = nil
+	return
+}
+
+func (dummy *globalsStruct) VolumeGroupCreated(confMap conf.ConfMap, volumeGroupName string, activePeer string, virtualIPAddr string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) VolumeGroupMoved(confMap conf.ConfMap, volumeGroupName string, activePeer string, virtualIPAddr string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) VolumeGroupDestroyed(confMap conf.ConfMap, volumeGroupName string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) VolumeCreated(confMap conf.ConfMap, volumeName string, volumeGroupName string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) VolumeMoved(confMap conf.ConfMap, volumeName string, volumeGroupName string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) VolumeDestroyed(confMap conf.ConfMap, volumeName string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) ServeVolume(confMap conf.ConfMap, volumeName string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) UnserveVolume(confMap conf.ConfMap, volumeName string) (err error) {
+	return nil
+}
+
+func (dummy *globalsStruct) SignaledStart(confMap conf.ConfMap) (err error) {
+	stopRebalancer()
+
+	err = nil
+	return
+}
+
+func (dummy *globalsStruct) SignaledFinish(confMap conf.ConfMap) (err error) {
+	err = fetchBudget(confMap)
+	if nil != err {
+		return
+	}
+
+	rebalance()
+
+	startRebalancer()
+
+	err = nil
+	return
+}
+
+func (dummy *globalsStruct) Down(confMap conf.ConfMap) (err error) {
+	stopRebalancer()
+
+	if 0 != len(globals.cacheMap) {
+		err = fmt.Errorf("cachebudget.Down() called with 0 != len(globals.cacheMap)")
+		return
+	}
+
+	err = nil
+	return
+}
+
+func fetchBudget(confMap conf.ConfMap) (err error) {
+	var (
+		cacheMemoryFraction float64
+		rebalanceInterval   time.Duration
+		totalBudgetBytes    uint64
+		whoAmI              string
+	)
+
+	whoAmI, err = confMap.FetchOptionValueString("Cluster", "WhoAmI")
+	if nil != err {
+		return
+	}
+
+	cacheMemoryFraction, err = confMap.FetchOptionValueFloat64("Peer:"+whoAmI, "CacheMemoryFraction")
+	if nil != err {
+		cacheMemoryFraction = 0 // TODO: Eventually, just return
+	}
+	if (0 > cacheMemoryFraction) || (1 < cacheMemoryFraction) {
+		err = fmt.Errorf("%s.CacheMemoryFraction (%v) must be between 0 and 1", whoAmI, cacheMemoryFraction)
+		return
+	}
+
+	rebalanceInterval, err = confMap.FetchOptionValueDuration("Peer:"+whoAmI, "CacheRebalanceInterval")
+	if nil != err {
+		rebalanceInterval = 10 * time.Second // TODO: Eventually, just return
+	}
+	if 0 == rebalanceInterval {
+		err = fmt.Errorf("%s.CacheRebalanceInterval must be non-zero", whoAmI)
+		return
+	}
+
+	totalBudgetBytes = uint64(float64(platform.MemSize()) * cacheMemoryFraction / platform.GoHeapAllocationMultiplier)
+
+	if 0 < totalBudgetBytes {
+		logger.Infof("...CacheMemoryFraction(%v) establishes a shared cache budget of 0x%016X bytes", cacheMemoryFraction, totalBudgetBytes)
+	}
+
+	globals.Lock()
+	globals.totalBudgetBytes = totalBudgetBytes
+	globals.rebalanceInterval = rebalanceInterval
+	globals.Unlock()
+
+	err = nil
+	return
+}
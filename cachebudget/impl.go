@@ -0,0 +1,219 @@
+package cachebudget
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/swiftstack/sortedmap"
+
+	"github.com/swiftstack/ProxyFS/logger"
+)
+
+func register(name string, cache Cache) {
+	globals.Lock()
+
+	_, ok := globals.cacheMap[name]
+	if ok {
+		globals.Unlock()
+		logger.Fatalf("cachebudget.Register(\"%s\") called for already registered cache", name)
+	}
+
+	_, priorMisses, _ := cache.Stats()
+
+	globals.cacheMap[name] = &cacheStruct{
+		name:         name,
+		cache:        cache,
+		priorMisses:  priorMisses,
+		missPressure: 0,
+		budgetBytes:  0,
+	}
+
+	if 0 < globals.totalBudgetBytes {
+		rebalanceWhileLocked()
+	}
+
+	globals.Unlock()
+}
+
+func unRegister(name string) {
+	globals.Lock()
+
+	_, ok := globals.cacheMap[name]
+	if !ok {
+		globals.Unlock()
+		logger.Fatalf("cachebudget.UnRegister(\"%s\") called for unregistered cache", name)
+	}
+
+	delete(globals.cacheMap, name)
+
+	if 0 < globals.totalBudgetBytes {
+		rebalanceWhileLocked()
+	}
+
+	globals.Unlock()
+}
+
+func dump() (cacheStatsMap map[string]CacheStats) {
+	var (
+		cacheStats CacheStats
+	)
+
+	globals.Lock()
+
+	cacheStatsMap = make(map[string]CacheStats)
+
+	for name, cache := range globals.cacheMap {
+		cacheStats.Hits, cacheStats.Misses, cacheStats.Bytes = cache.cache.Stats()
+		cacheStats.BudgetBytes = cache.budgetBytes
+		cacheStatsMap[name] = cacheStats
+	}
+
+	globals.Unlock()
+
+	return
+}
+
+func rebalance() {
+	globals.Lock()
+	rebalanceWhileLocked()
+	globals.Unlock()
+}
+
+// rebalanceWhileLocked updates each cache's missPressure and then divides the total budget such that
+// each cache is guaranteed an equal share of one quarter of it with the remaining three quarters
+// divided in proportion to each cache's missPressure.
+//
+func rebalanceWhileLocked() {
+	var (
+		cache             *cacheStruct
+		floorBytes        uint64
+		misses            uint64
+		missPressureSum   float64
+		proportionalBytes uint64
+	)
+
+	if 0 == len(globals.cacheMap) {
+		return
+	}
+
+	for _, cache = range globals.cacheMap {
+		_, misses, _ = cache.cache.Stats()
+		cache.missPressure = ((3 * cache.missPressure) + (misses - cache.priorMisses)) / 4
+		cache.priorMisses = misses
+		missPressureSum += float64(cache.missPressure + 1)
+	}
+
+	if 0 == globals.totalBudgetBytes {
+		for _, cache = range globals.cacheMap {
+			if 0 != cache.budgetBytes {
+				cache.budgetBytes = 0
+				cache.cache.SetBudget(0)
+			}
+		}
+		return
+	}
+
+	floorBytes = globals.totalBudgetBytes / uint64(4*len(globals.cacheMap))
+	proportionalBytes = globals.totalBudgetBytes - (floorBytes * uint64(len(globals.cacheMap)))
+
+	for _, cache = range globals.cacheMap {
+		cache.budgetBytes = floorBytes + uint64(float64(proportionalBytes)*float64(cache.missPressure+1)/missPressureSum)
+		cache.cache.SetBudget(cache.budgetBytes)
+	}
+}
+
+func startRebalancer() {
+	globals.rebalanceStopChan = make(chan struct{})
+	globals.rebalanceDoneChan = make(chan struct{})
+
+	go rebalancer(globals.rebalanceInterval, globals.rebalanceStopChan, globals.rebalanceDoneChan)
+}
+
+func stopRebalancer() {
+	if nil == globals.rebalanceStopChan {
+		return
+	}
+
+	close(globals.rebalanceStopChan)
+	<-globals.rebalanceDoneChan
+
+	globals.rebalanceStopChan = nil
+	globals.rebalanceDoneChan = nil
+}
+
+func rebalancer(rebalanceInterval time.Duration, stopChan chan struct{}, doneChan chan struct{}) {
+	ticker := time.NewTicker(rebalanceInterval)
+
+	for {
+		select {
+		case <-ticker.C:
+			rebalance()
+		case <-stopChan:
+			ticker.Stop()
+			close(doneChan)
+			return
+		}
+	}
+}
+
+func newBPlusTreeCache(evictLowLimit uint64, evictHighLimit uint64, nodeBytesEstimate uint64) (bPlusTreeCache *BPlusTreeCache) {
+	bPlusTreeCache = &BPlusTreeCache{
+		BPlusTreeCache:       sortedmap.NewBPlusTreeCache(evictLowLimit, evictHighLimit),
+		staticEvictLowLimit:  evictLowLimit,
+		staticEvictHighLimit: evictHighLimit,
+		avgNodeBytes:         nodeBytesEstimate,
+	}
+
+	return
+}
+
+func (bPlusTreeCache *BPlusTreeCache) noteNodeBytes(nodeBytes uint64) {
+	for {
+		oldAvgNodeBytes := atomic.LoadUint64(&bPlusTreeCache.avgNodeBytes)
+		newAvgNodeBytes := ((15 * oldAvgNodeBytes) + nodeBytes) / 16
+		if 0 == oldAvgNodeBytes {
+			newAvgNodeBytes = nodeBytes
+		}
+		if atomic.CompareAndSwapUint64(&bPlusTreeCache.avgNodeBytes, oldAvgNodeBytes, newAvgNodeBytes) {
+			return
+		}
+	}
+}
+
+func (bPlusTreeCache *BPlusTreeCache) stats() (hits uint64, misses uint64, bytes uint64) {
+	hits, misses, _, _ = bPlusTreeCache.BPlusTreeCache.Stats()
+
+	bytes = bPlusTreeCache.BPlusTreeCache.BytesHeld()
+
+	return
+}
+
+func (bPlusTreeCache *BPlusTreeCache) setBudget(budgetBytes uint64) {
+	var (
+		avgNodeBytes   uint64
+		evictHighLimit uint64
+		evictLowLimit  uint64
+	)
+
+	avgNodeBytes = atomic.LoadUint64(&bPlusTreeCache.avgNodeBytes)
+
+	if (0 == budgetBytes) || (0 == avgNodeBytes) {
+		bPlusTreeCache.UpdateLimits(bPlusTreeCache.staticEvictLowLimit, bPlusTreeCache.staticEvictHighLimit)
+		return
+	}
+
+	evictHighLimit = budgetBytes / avgNodeBytes
+	if 0 == evictHighLimit {
+		evictHighLimit = 1
+	}
+
+	// Preserve the statically configured hysteresis between evictLowLimit & evictHighLimit
+
+	if 0 == bPlusTreeCache.staticEvictHighLimit {
+		evictLowLimit = evictHighLimit
+	} else {
+		evictLowLimit = uint64(float64(evictHighLimit) * float64(bPlusTreeCache.staticEvictLowLimit) / float64(bPlusTreeCache.staticEvictHighLimit))
+	}
+
+	bPlusTreeCache.UpdateLimits(evictLowLimit, evictHighLimit)
+}
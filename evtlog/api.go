@@ -60,6 +60,11 @@ const (
 	FormatFlushInodesErrorOnInode
 	FormatFlushInodesErrorOnHeadhunterPut
 	FormatFlushInodesExit
+	FormatFileDataChecksumMismatch
+	FormatHeadhunterRecordTransactionPutFingerprintRec
+	FormatHeadhunterRecordTransactionDeleteFingerprintRec
+	FormatHeadhunterRecordTransactionPutExpiryRec
+	FormatHeadhunterRecordTransactionDeleteExpiryRec
 	//
 	formatTypeCount // Used to quickly check upper limit of FormatType values
 )
@@ -319,6 +324,26 @@ var (
 			patternType:  patternS016Xslice,
 			formatString: "%s inode.flushInodes() exited for Volume '%s' Inode#'s [0x%016X]",
 		},
+		eventType{ // FormatFileDataChecksumMismatch
+			patternType:  patternS016X016X016X,
+			formatString: "%s inode detected data checksum mismatch for Volume '%s' Inode# 0x%016X LogSegment# 0x%016X at Offset 0x%016X",
+		},
+		eventType{ // FormatHeadhunterRecordTransactionPutFingerprintRec
+			patternType:  patternS016X,
+			formatString: "%s Headhunter recording PutFingerprintRec for Volume '%s' Fingerprint# 0x%016X",
+		},
+		eventType{ // FormatHeadhunterRecordTransactionDeleteFingerprintRec
+			patternType:  patternS016X,
+			formatString: "%s Headhunter recording DeleteFingerprintRec for Volume '%s' Fingerprint# 0x%016X",
+		},
+		eventType{ // FormatHeadhunterRecordTransactionPutExpiryRec
+			patternType:  patternS016X,
+			formatString: "%s Headhunter recording PutExpiryRec for Volume '%s' Inode# 0x%016X",
+		},
+		eventType{ // FormatHeadhunterRecordTransactionDeleteExpiryRec
+			patternType:  patternS016X,
+			formatString: "%s Headhunter recording DeleteExpiryRec for Volume '%s' Inode# 0x%016X",
+		},
 	}
 )
 
@@ -42,6 +42,7 @@ type ContainerEntry struct {
 	NumWrites        uint64
 	InodeNumber      uint64
 	Metadata         []byte
+	ETag             string // MD5 of the file's content (only maintained if the volume is configured to do so)
 }
 
 type HeadResponse struct {
@@ -52,6 +53,36 @@ type HeadResponse struct {
 	IsDir            bool
 	InodeNumber      inode.InodeNumber
 	NumWrites        uint64
+	ETag             string // MD5 of the file's content (only maintained if the volume is configured to do so)
+	ExpireAt         uint64 // seconds since epoch (0 if the object never expires)
+}
+
+// Returned by MiddlewareListVersions
+//
+type VersionEntry struct {
+	VersionID        string // Name of the prior version in its object's versions directory
+	ArchiveTime      uint64 // nanoseconds since epoch (when the version was replaced or deleted)
+	FileSize         uint64
+	ModificationTime uint64 // nanoseconds since epoch
+	AttrChangeTime   uint64 // nanoseconds since epoch
+	NumWrites        uint64
+	InodeNumber      uint64
+	Metadata         []byte
+	ETag             string // MD5 of the file's content (only maintained if the volume is configured to do so)
+}
+
+// Returned by TrashList
+//
+type TrashEntry struct {
+	TrashID        string            // Name of the trashed inode in its owner's trash directory
+	UserID         inode.InodeUserID // Owner of the trash directory holding the trashed inode
+	DirInodeNumber inode.InodeNumber // Directory the inode was deleted from
+	Basename       string            // Name the inode was deleted from DirInodeNumber as
+	Path           string            // Path (relative to the root directory) the inode was deleted from (if deleted via the middleware)
+	DeleteTime     uint64            // nanoseconds since epoch
+	InodeNumber    inode.InodeNumber
+	InodeType      inode.InodeType
+	FileSize       uint64
 }
 
 // The following constants are used to ensure that the length of file fullpath and basenames are POSIX-compliant
@@ -66,6 +97,28 @@ const MaxSymlinks = 8 // same as Linux; see include/linux/namei.h in Linux's Git
 // Constant defining the name of the alternate data stream used by Swift Middleware
 const MiddlewareStream = "middleware"
 
+// Constants supporting object versioning of bimodal containers (see MiddlewareSetVersioning())
+const (
+	VersionsDirName  = ".versions"  // Hidden top-level directory holding prior versions of objects
+	VersioningStream = "versioning" // Alternate data stream of a container holding its versioning configuration
+
+	VersioningModeNone    = ""        // Replaced and deleted objects are discarded
+	VersioningModeStack   = "stack"   // X-Versions-Location semantics: deleting an object restores its prior version
+	VersioningModeHistory = "history" // X-History-Location semantics: deleting an object archives it as well
+)
+
+// Constants supporting the (optional) per-volume trash (see TrashList() and TrashRestore())
+const (
+	TrashDirName        = ".trash"       // Hidden top-level directory holding a directory of trashed inodes per UserID
+	TrashDirEntryStream = "trash.dirent" // Alternate data stream of a trashed inode holding the directory (InodeNumber) & basename it was deleted from
+	TrashPathStream     = "trash.path"   // Alternate data stream of a trashed inode holding the path it was deleted from (if deleted via the middleware)
+	TrashTimeStream     = "trash.time"   // Alternate data stream of a trashed inode holding when it was deleted (nanoseconds since epoch)
+)
+
+// Constant defining the name of the alternate data stream holding an object's expiration time
+// (see MiddlewareSetExpiration())
+const ExpirationStream = "expiration"
+
 // Base-2 constants
 const (
 	Kibi = 1024
@@ -82,6 +135,23 @@ const (
 	DefaultReportedNumInodes    uint64 = 100 * Gibi
 )
 
+// DefaultNotifyQueueDepth is the number of NotifyEvents queued for each subscription
+// (awaiting NotifyFetch()) if FSGlobals.NotifyQueueDepth is not specified
+const DefaultNotifyQueueDepth uint64 = 4096
+
+// DefaultNotifySubscriptionIdleTimeout is how long a subscription may go without a NotifyFetch()
+// (e.g. because its client has disappeared) before it is discarded if
+// FSGlobals.NotifySubscriptionIdleTimeout is not specified
+const DefaultNotifySubscriptionIdleTimeout = 10 * time.Minute
+
+// DefaultExpirationReapInterval is how often each volume's expired objects are unlinked
+// if FSGlobals.ExpirationReapInterval is not specified
+const DefaultExpirationReapInterval = time.Minute
+
+// DefaultTrashPurgeInterval is how often each volume's trash is purged of items older than its
+// TrashRetention if FSGlobals.TrashPurgeInterval is not specified
+const DefaultTrashPurgeInterval = time.Hour
+
 type FlockStruct struct {
 	Type   int32
 	Whence int32
@@ -96,6 +166,40 @@ const (
 	MountReadOnly MountOptions = 1 << iota
 )
 
+// NotifyEventType identifies the kind of change reported in a NotifyEvent. Values
+// may be or'd together to form the eventMask passed to NotifySubscribe().
+type NotifyEventType uint32
+
+const (
+	NotifyCreate     NotifyEventType = 1 << iota // File, directory, symlink, or hard link created in DirInodeNumber
+	NotifyUnlink                                 // Basename unlinked (or rmdir'd) from DirInodeNumber
+	NotifyRename                                 // Basename in DirInodeNumber renamed to NewBasename in NewDirInodeNumber
+	NotifySetattr                                // Attributes (including size) of InodeNumber changed
+	NotifyWriteClose                             // Data written to InodeNumber has been flushed
+	NotifyXAttr                                  // An extended attribute of InodeNumber was set or removed
+
+	NotifyAll = NotifyCreate | NotifyUnlink | NotifyRename | NotifySetattr | NotifyWriteClose | NotifyXAttr
+)
+
+// NotifyEvent describes a single change delivered to a subscription by NotifyFetch().
+//
+// For NotifySetattr, NotifyWriteClose, and NotifyXAttr events, DirInodeNumber and Basename
+// name the most recently observed directory entry referencing InodeNumber. Such events are
+// only delivered for inodes whose name has been observed (via Create, Lookup, Rename, etc.)
+// since a subscription on the volume was first established.
+//
+type NotifyEvent struct {
+	Sequence          uint64 // Per-volume and monotonically increasing
+	Type              NotifyEventType
+	DirInodeNumber    inode.InodeNumber
+	Basename          string
+	InodeNumber       inode.InodeNumber
+	NewDirInodeNumber inode.InodeNumber // Only set for NotifyRename
+	NewBasename       string            // Only set for NotifyRename
+}
+
+type NotifySubscriptionID uint64
+
 type StatKey uint64
 
 const (
@@ -161,7 +265,9 @@ func MountByVolumeName(volumeName string, mountOptions MountOptions) (mountHandl
 type MountHandle interface {
 	Access(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, accessMode inode.InodeMode) (accessReturn bool)
 	CallInodeToProvisionObject() (pPath string, err error)
+	CopyFileRange(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, srcInodeNumber inode.InodeNumber, srcOffset uint64, dstInodeNumber inode.InodeNumber, dstOffset uint64, length uint64) (copiedLength uint64, err error)
 	Create(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, dirInodeNumber inode.InodeNumber, basename string, filePerm inode.InodeMode) (fileInodeNumber inode.InodeNumber, err error)
+	Fallocate(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, mode inode.FallocateMode, offset uint64, length uint64) (err error)
 	FetchReadPlan(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64, length uint64) (readPlan []inode.ReadPlanStep, err error)
 	Flush(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber) (err error)
 	Flock(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, lockCmd int32, inFlockStruct *FlockStruct) (outFlockStruct *FlockStruct, err error)
@@ -176,28 +282,42 @@ type MountHandle interface {
 	Lookup(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, dirInodeNumber inode.InodeNumber, basename string) (inodeNumber inode.InodeNumber, err error)
 	LookupPath(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, fullpath string) (inodeNumber inode.InodeNumber, err error)
 	MiddlewareCoalesce(destPath string, elementPaths []string) (ino uint64, numWrites uint64, modificationTime uint64, err error)
+	MiddlewareCopy(srcPath string, dstPath string, metadata []byte) (mtime uint64, ctime uint64, fileInodeNumber inode.InodeNumber, numWrites uint64, err error)
 	MiddlewareDelete(parentDir string, baseName string) (err error)
 	MiddlewareGetAccount(maxEntries uint64, marker string, endmarker string) (accountEnts []AccountEntry, mtime uint64, ctime uint64, err error)
 	MiddlewareGetContainer(vContainerName string, maxEntries uint64, marker string, endmarker string, prefix string, delimiter string) (containerEnts []ContainerEntry, err error)
-	MiddlewareGetObject(containerObjectPath string, readRangeIn []ReadRangeIn, readRangeOut *[]inode.ReadPlanStep) (fileSize uint64, lastModified uint64, lastChanged uint64, ino uint64, numWrites uint64, serializedMetadata []byte, err error)
+	MiddlewareGetObject(containerObjectPath string, readRangeIn []ReadRangeIn, readRangeOut *[]inode.ReadPlanStep) (fileSize uint64, lastModified uint64, lastChanged uint64, ino uint64, numWrites uint64, serializedMetadata []byte, eTag string, expireAt uint64, err error)
 	MiddlewareHeadResponse(entityPath string) (response HeadResponse, err error)
+	MiddlewareListVersions(vContainerName string, vObjectPath string) (versions []VersionEntry, err error)
 	MiddlewareMkdir(vContainerName string, vObjectPath string, metadata []byte) (mtime uint64, ctime uint64, inodeNumber inode.InodeNumber, numWrites uint64, err error)
 	MiddlewarePost(parentDir string, baseName string, newMetaData []byte, oldMetaData []byte) (err error)
-	MiddlewarePutComplete(vContainerName string, vObjectPath string, pObjectPaths []string, pObjectLengths []uint64, pObjectMetadata []byte) (mtime uint64, ctime uint64, fileInodeNumber inode.InodeNumber, numWrites uint64, err error)
+	MiddlewarePutComplete(vContainerName string, vObjectPath string, pObjectPaths []string, pObjectLengths []uint64, pObjectBlockCRC32s [][]uint32, pObjectMetadata []byte) (mtime uint64, ctime uint64, fileInodeNumber inode.InodeNumber, numWrites uint64, err error)
 	MiddlewarePutContainer(containerName string, oldMetadata []byte, newMetadata []byte) (err error)
+	MiddlewareRestoreVersion(vContainerName string, vObjectPath string, versionID string) (err error)
+	MiddlewareSetExpiration(vContainerName string, vObjectPath string, expireAt uint64) (err error)
+	MiddlewareSetVersioning(containerName string, mode string, maxVersions uint64) (err error)
 	Mkdir(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, basename string, filePerm inode.InodeMode) (newDirInodeNumber inode.InodeNumber, err error)
+	NotifyFetch(subscriptionID NotifySubscriptionID, maxEvents uint64, timeout time.Duration) (events []NotifyEvent, overflowed bool, err error)
+	NotifySubscribe(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, dirInodeNumber inode.InodeNumber, subtree bool, eventMask NotifyEventType) (subscriptionID NotifySubscriptionID, err error)
+	NotifyUnsubscribe(subscriptionID NotifySubscriptionID) (err error)
 	RemoveXAttr(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, streamName string) (err error)
-	Rename(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, srcDirInodeNumber inode.InodeNumber, srcBasename string, dstDirInodeNumber inode.InodeNumber, dstBasename string) (err error)
+	Rename(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, srcDirInodeNumber inode.InodeNumber, srcBasename string, dstDirInodeNumber inode.InodeNumber, dstBasename string, flags inode.RenameFlags) (err error)
 	Read(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64, length uint64, profiler *utils.Profiler) (buf []byte, err error)
+	ReadWithReadAhead(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64, length uint64, readAheadState *inode.ReadAheadState, profiler *utils.Profiler) (buf []byte, err error)
 	Readdir(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, maxEntries uint64, prevReturned ...interface{}) (entries []inode.DirEntry, numEntries uint64, areMoreEntries bool, err error)
 	ReaddirPlus(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, maxEntries uint64, prevReturned ...interface{}) (dirEntries []inode.DirEntry, statEntries []Stat, numEntries uint64, areMoreEntries bool, err error)
 	Readsymlink(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber) (target string, err error)
 	Resize(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, newSize uint64) (err error)
 	Rmdir(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, basename string) (err error)
+	SeekData(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64) (dataOffset uint64, err error)
+	SeekHole(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64) (holeOffset uint64, err error)
+	SetClientAddr(clientAddr string)
 	Setstat(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, stat Stat) (err error)
 	SetXAttr(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, streamName string, value []byte, flags int) (err error)
 	StatVfs() (statVFS StatVFS, err error)
 	Symlink(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, basename string, target string) (symlinkInodeNumber inode.InodeNumber, err error)
+	TrashList(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID) (entries []TrashEntry, err error)
+	TrashRestore(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, trashUserID inode.InodeUserID, trashID string, restorePath string) (err error)
 	Unlink(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, basename string) (err error)
 	VolumeName() (volumeName string)
 	Write(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64, buf []byte, profiler *utils.Profiler) (size uint64, err error)
@@ -11,6 +11,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/swiftstack/ProxyFS/audit"
 	"github.com/swiftstack/ProxyFS/blunder"
 	"github.com/swiftstack/ProxyFS/dlm"
 	"github.com/swiftstack/ProxyFS/inode"
@@ -329,6 +330,118 @@ func (mS *mountStruct) CallInodeToProvisionObject() (pPath string, err error) {
 	return
 }
 
+func (mS *mountStruct) CopyFileRange(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, srcInodeNumber inode.InodeNumber, srcOffset uint64, dstInodeNumber inode.InodeNumber, dstOffset uint64, length uint64) (copiedLength uint64, err error) {
+	var (
+		dlmCallerID       dlm.CallerID
+		heldLocks         *heldLocksStruct
+		inodeType         inode.InodeType
+		inodeVolumeHandle inode.VolumeHandle
+		restartBackoff    time.Duration
+		retryRequired     bool
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.CopyFileRangeUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		globals.CopyFileRangeBytes.Add(copiedLength)
+		if err != nil {
+			globals.CopyFileRangeErrors.Add(1)
+		}
+	}()
+
+	mS.volStruct.jobRWMutex.RLock()
+	defer mS.volStruct.jobRWMutex.RUnlock()
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	// Retry until done or failure (starting with ZERO backoff)
+
+	restartBackoff = time.Duration(0)
+
+Restart:
+
+	// Perform backoff and update for each restart (starting with ZERO backoff of course)
+
+	restartBackoff, err = utils.PerformDelayAndComputeNextDelay(restartBackoff, globals.tryLockBackoffMin, globals.tryLockBackoffMax)
+	if nil != err {
+		logger.Fatalf("CopyFileRange(): failed in restartBackoff: %v", err)
+	}
+
+	// Construct fresh heldLocks for this restart... obtaining the WriteLock on dstInode first
+
+	heldLocks = newHeldLocks()
+
+	dlmCallerID = dlm.GenerateCallerID()
+
+	retryRequired = heldLocks.attemptExclusiveLock(inodeVolumeHandle, dlmCallerID, dstInodeNumber)
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+	retryRequired = heldLocks.attemptSharedLock(inodeVolumeHandle, dlmCallerID, srcInodeNumber)
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	defer heldLocks.free()
+
+	if !inodeVolumeHandle.Access(srcInodeNumber, userID, groupID, otherGroupIDs, inode.F_OK,
+		inode.NoOverride) {
+
+		err = blunder.NewError(blunder.NotFoundError, "ENOENT")
+		return
+	}
+	if !inodeVolumeHandle.Access(srcInodeNumber, userID, groupID, otherGroupIDs, inode.R_OK,
+		inode.OwnerOverride) {
+
+		err = blunder.NewError(blunder.PermDeniedError, "EACCES")
+		return
+	}
+	if !inodeVolumeHandle.Access(dstInodeNumber, userID, groupID, otherGroupIDs, inode.F_OK,
+		inode.NoOverride) {
+
+		err = blunder.NewError(blunder.NotFoundError, "ENOENT")
+		return
+	}
+	if !inodeVolumeHandle.Access(dstInodeNumber, userID, groupID, otherGroupIDs, inode.W_OK,
+		inode.OwnerOverride) {
+
+		err = blunder.NewError(blunder.PermDeniedError, "EACCES")
+		return
+	}
+
+	inodeType, err = inodeVolumeHandle.GetType(srcInodeNumber)
+	if nil != err {
+		return
+	}
+	if inode.FileType != inodeType {
+		err = blunder.NewError(blunder.InvalidArgError, "EINVAL: source inode is not a file")
+		return
+	}
+	inodeType, err = inodeVolumeHandle.GetType(dstInodeNumber)
+	if nil != err {
+		return
+	}
+	if inode.FileType != inodeType {
+		err = blunder.NewError(blunder.InvalidArgError, "EINVAL: destination inode is not a file")
+		return
+	}
+
+	copiedLength, err = inodeVolumeHandle.CopyRange(srcInodeNumber, srcOffset, dstInodeNumber, dstOffset, length)
+
+	// CopyRange() flushes both srcInode & dstInode
+
+	mS.volStruct.untrackInFlightFileInodeData(srcInodeNumber, false)
+	mS.volStruct.untrackInFlightFileInodeData(dstInodeNumber, false)
+
+	if (nil == err) && (0 < copiedLength) {
+		mS.volStruct.notifyInodeEvent(NotifyWriteClose, dstInodeNumber)
+	}
+
+	return
+}
+
 func (mS *mountStruct) Create(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, dirInodeNumber inode.InodeNumber, basename string, filePerm inode.InodeMode) (fileInodeNumber inode.InodeNumber, err error) {
 	startTime := time.Now()
 	defer func() {
@@ -337,6 +450,9 @@ func (mS *mountStruct) Create(userID inode.InodeUserID, groupID inode.InodeGroup
 			globals.CreateErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditDirEntry(audit.OpCreate, userID, groupID, dirInodeNumber, basename, fileInodeNumber, nil, err)
+	}()
 
 	mS.volStruct.jobRWMutex.RLock()
 	defer mS.volStruct.jobRWMutex.RUnlock()
@@ -346,6 +462,11 @@ func (mS *mountStruct) Create(userID inode.InodeUserID, groupID inode.InodeGroup
 		return 0, err
 	}
 
+	if isVersionsDirEntry(dirInodeNumber, basename) {
+		err = blunder.NewError(blunder.NotPermError, "EPERM")
+		return
+	}
+
 	// Lock the directory inode before doing the link
 	dirInodeLock, err := mS.volStruct.inodeVolumeHandle.InitInodeLock(dirInodeNumber, nil)
 	if err != nil {
@@ -381,9 +502,57 @@ func (mS *mountStruct) Create(userID inode.InodeUserID, groupID inode.InodeGroup
 		return 0, err
 	}
 
+	mS.volStruct.notifyNamespaceEvent(NotifyCreate, dirInodeNumber, basename, fileInodeNumber)
+
 	return fileInodeNumber, nil
 }
 
+func (mS *mountStruct) Fallocate(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, mode inode.FallocateMode, offset uint64, length uint64) (err error) {
+
+	startTime := time.Now()
+	defer func() {
+		globals.FallocateUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.FallocateErrors.Add(1)
+		}
+	}()
+
+	mS.volStruct.jobRWMutex.RLock()
+	defer mS.volStruct.jobRWMutex.RUnlock()
+
+	inodeLock, err := mS.volStruct.inodeVolumeHandle.InitInodeLock(inodeNumber, nil)
+	if err != nil {
+		return
+	}
+	err = inodeLock.WriteLock()
+	if err != nil {
+		return
+	}
+	defer inodeLock.Unlock()
+
+	if !mS.volStruct.inodeVolumeHandle.Access(inodeNumber, userID, groupID, otherGroupIDs, inode.F_OK,
+		inode.NoOverride) {
+
+		err = blunder.NewError(blunder.NotFoundError, "ENOENT")
+		return
+	}
+	if !mS.volStruct.inodeVolumeHandle.Access(inodeNumber, userID, groupID, otherGroupIDs, inode.W_OK,
+		inode.OwnerOverride) {
+
+		err = blunder.NewError(blunder.PermDeniedError, "EACCES")
+		return
+	}
+
+	err = mS.volStruct.inodeVolumeHandle.Fallocate(inodeNumber, mode, offset, length)
+	mS.volStruct.untrackInFlightFileInodeData(inodeNumber, false)
+
+	if (nil == err) && (inode.FallocateKeepSize != mode) {
+		mS.volStruct.notifyInodeEvent(NotifySetattr, inodeNumber)
+	}
+
+	return err
+}
+
 func (mS *mountStruct) FetchReadPlan(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64, length uint64) (readPlan []inode.ReadPlanStep, err error) {
 	var (
 		inodeLock   *dlm.RWLockStruct
@@ -503,8 +672,16 @@ func (mS *mountStruct) Flush(userID inode.InodeUserID, groupID inode.InodeGroupI
 	err = mS.volStruct.inodeVolumeHandle.Flush(inodeNumber, false)
 	mS.volStruct.untrackInFlightFileInodeData(inodeNumber, false)
 
+	if nil == err {
+		mS.volStruct.queueETagUpdate(inodeNumber)
+	}
+
 	mS.doInlineCheckpointIfEnabled()
 
+	if nil == err {
+		mS.volStruct.notifyInodeEvent(NotifyWriteClose, inodeNumber)
+	}
+
 	return
 }
 
@@ -1113,6 +1290,9 @@ func (mS *mountStruct) Link(userID inode.InodeUserID, groupID inode.InodeGroupID
 			globals.LinkErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditDirEntry(audit.OpLink, userID, groupID, dirInodeNumber, basename, targetInodeNumber, nil, err)
+	}()
 
 	mS.volStruct.jobRWMutex.RLock()
 	defer mS.volStruct.jobRWMutex.RUnlock()
@@ -1126,6 +1306,11 @@ func (mS *mountStruct) Link(userID inode.InodeUserID, groupID inode.InodeGroupID
 		return
 	}
 
+	if isVersionsDirEntry(dirInodeNumber, basename) {
+		err = blunder.NewError(blunder.NotPermError, "EPERM")
+		return
+	}
+
 	// We need both dirInodelock and the targetInode lock to make sure they
 	// don't go away and linkCount is updated correctly.
 	callerID := dlm.GenerateCallerID()
@@ -1201,6 +1386,10 @@ func (mS *mountStruct) Link(userID inode.InodeUserID, groupID inode.InodeGroupID
 		mS.volStruct.untrackInFlightFileInodeData(targetInodeNumber, false)
 	}
 
+	if err == nil {
+		mS.volStruct.notifyNamespaceEvent(NotifyCreate, dirInodeNumber, basename, targetInodeNumber)
+	}
+
 	return err
 }
 
@@ -1282,7 +1471,15 @@ func (mS *mountStruct) Lookup(userID inode.InodeUserID, groupID inode.InodeGroup
 		return
 	}
 
+	if isVersionsDirEntry(dirInodeNumber, basename) {
+		err = blunder.NewError(blunder.NotFoundError, "ENOENT")
+		return
+	}
+
 	inodeNumber, err = mS.volStruct.inodeVolumeHandle.Lookup(dirInodeNumber, basename)
+	if (nil == err) && ("." != basename) && (".." != basename) {
+		mS.volStruct.notifyNoteName(dirInodeNumber, basename, inodeNumber)
+	}
 	return inodeNumber, err
 }
 
@@ -1329,6 +1526,12 @@ func (mS *mountStruct) LookupPath(userID inode.InodeUserID, groupID inode.InodeG
 			return
 		}
 
+		if isVersionsDirEntry(cursorInodeNumber, segment) {
+			cursorInodeLock.Unlock()
+			err = blunder.NewError(blunder.NotFoundError, "ENOENT")
+			return
+		}
+
 		cursorInodeNumber, err = mS.volStruct.inodeVolumeHandle.Lookup(cursorInodeNumber, segment)
 		cursorInodeLock.Unlock()
 
@@ -1364,6 +1567,9 @@ func (mS *mountStruct) MiddlewareCoalesce(destPath string, elementPaths []string
 			globals.MiddlewareCoalesceErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditMiddleware(audit.OpMiddlewareCoalesce, destPath, "", inode.InodeNumber(ino), err)
+	}()
 
 	mS.volStruct.jobRWMutex.RLock()
 	defer mS.volStruct.jobRWMutex.RUnlock()
@@ -1418,7 +1624,7 @@ Restart:
 		}
 	}
 
-	_, dirEntryInodeNumber, _, _, retryRequired, err =
+	dirInodeNumber, dirEntryInodeNumber, dirEntryBasename, _, retryRequired, err =
 		mS.resolvePath(
 			inode.RootDirInodeNumber,
 			destPath,
@@ -1426,6 +1632,7 @@ Restart:
 			resolvePathFollowDirEntrySymlinks|
 				resolvePathFollowDirSymlinks|
 				resolvePathCreateMissingPathElements|
+				resolvePathNotifyCreatedPathElements|
 				resolvePathRequireExclusiveLockOnDirEntryInode)
 
 	if nil != err {
@@ -1438,9 +1645,21 @@ Restart:
 		goto Restart
 	}
 
+	// Preserve the FileInode about to be replaced if its container is versioned
+
+	destFileInodeNumber, retryRequired, err = mS.versionBeforeReplaceWhileLocked(heldLocks, destPath, dirInodeNumber, dirEntryBasename, dirEntryInodeNumber)
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
 	// Invoke package inode to actually perform the Coalesce operation
 
-	destFileInodeNumber = dirEntryInodeNumber
 	coalesceTime, numWrites, _, err = mS.volStruct.inodeVolumeHandle.Coalesce(destFileInodeNumber, coalesceElementList)
 
 	// We can now release all the WriteLocks we are currently holding
@@ -1452,22 +1671,197 @@ Restart:
 	ino = uint64(destFileInodeNumber)
 	modificationTime = uint64(coalesceTime.UnixNano())
 
+	if nil == err {
+		for _, coalesceElement := range coalesceElementList {
+			mS.volStruct.notifyNamespaceEvent(NotifyUnlink, coalesceElement.ContainingDirectoryInodeNumber, coalesceElement.ElementName, coalesceElement.ElementInodeNumber)
+		}
+	}
+
 	return
 }
 
-func (mS *mountStruct) MiddlewareDelete(parentDir string, basename string) (err error) {
+func (mS *mountStruct) MiddlewareCopy(srcPath string, dstPath string, metadata []byte) (mtime uint64, ctime uint64, fileInodeNumber inode.InodeNumber, numWrites uint64, err error) {
 	var (
+		copySize            uint64
 		dirEntryBasename    string
 		dirEntryInodeNumber inode.InodeNumber
 		dirInodeNumber      inode.InodeNumber
-		doDestroy           bool
 		heldLocks           *heldLocksStruct
-		inodeType           inode.InodeType
 		inodeVolumeHandle   inode.VolumeHandle
-		linkCount           uint64
-		numDirEntries       uint64
 		restartBackoff      time.Duration
 		retryRequired       bool
+		srcInodeNumber      inode.InodeNumber
+		srcMetadata         *inode.MetadataStruct
+		stat                Stat
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.MiddlewareCopyUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		globals.MiddlewareCopyBytes.Add(copySize)
+		if err != nil {
+			globals.MiddlewareCopyErrors.Add(1)
+		}
+	}()
+	defer func() {
+		mS.auditMiddleware(audit.OpMiddlewareCopy, srcPath, dstPath, fileInodeNumber, err)
+	}()
+
+	mS.volStruct.jobRWMutex.RLock()
+	defer mS.volStruct.jobRWMutex.RUnlock()
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	// Retry until done or failure (starting with ZERO backoff)
+
+	restartBackoff = time.Duration(0)
+
+Restart:
+
+	// Perform backoff and update for each restart (starting with ZERO backoff of course)
+
+	restartBackoff, err = utils.PerformDelayAndComputeNextDelay(restartBackoff, globals.tryLockBackoffMin, globals.tryLockBackoffMax)
+	if nil != err {
+		logger.Fatalf("MiddlewareCopy(): failed in restartBackoff: %v", err)
+	}
+
+	// Construct fresh heldLocks for this restart
+
+	heldLocks = newHeldLocks()
+
+	// Assemble ReadLock on the source FileInode
+
+	_, srcInodeNumber, _, _, retryRequired, err =
+		mS.resolvePath(
+			inode.RootDirInodeNumber,
+			srcPath,
+			heldLocks,
+			resolvePathFollowDirEntrySymlinks|
+				resolvePathFollowDirSymlinks|
+				resolvePathDirEntryInodeMustBeFile)
+
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	// Assemble WriteLock on the destination FileInode (creating it if necessary)
+
+	dirInodeNumber, dirEntryInodeNumber, dirEntryBasename, _, retryRequired, err =
+		mS.resolvePath(
+			inode.RootDirInodeNumber,
+			dstPath,
+			heldLocks,
+			resolvePathFollowDirEntrySymlinks|
+				resolvePathFollowDirSymlinks|
+				resolvePathCreateMissingPathElements|
+				resolvePathNotifyCreatedPathElements|
+				resolvePathDirEntryInodeMustBeFile|
+				resolvePathRequireExclusiveLockOnDirEntryInode)
+
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	// Preserve the destination FileInode about to be replaced if its container is versioned
+
+	dirEntryInodeNumber, retryRequired, err = mS.versionBeforeReplaceWhileLocked(heldLocks, dstPath, dirInodeNumber, dirEntryBasename, dirEntryInodeNumber)
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	// Replace the contents of the destination FileInode with (extents shared with) the source FileInode
+
+	if srcInodeNumber != dirEntryInodeNumber {
+		srcMetadata, err = inodeVolumeHandle.GetMetadata(srcInodeNumber)
+		if nil != err {
+			heldLocks.free()
+			return
+		}
+
+		err = inodeVolumeHandle.SetSize(dirEntryInodeNumber, 0)
+		if nil != err {
+			heldLocks.free()
+			return
+		}
+
+		copySize, err = inodeVolumeHandle.CopyRange(srcInodeNumber, 0, dirEntryInodeNumber, 0, srcMetadata.Size)
+		if nil != err {
+			heldLocks.free()
+			logger.DebugfIDWithError(internalDebug, err, "MiddlewareCopy(): failed CopyRange() from srcInodeNumber 0x%016X to dirEntryInodeNumber 0x%016X", srcInodeNumber, dirEntryInodeNumber)
+			return
+		}
+
+		mS.volStruct.untrackInFlightFileInodeData(srcInodeNumber, false)
+	}
+
+	// Apply metadata to FileInode (this will flush it as well)
+
+	err = inodeVolumeHandle.PutStream(dirEntryInodeNumber, MiddlewareStream, metadata)
+	if err != nil {
+		heldLocks.free()
+		logger.DebugfIDWithError(internalDebug, err, "MiddlewareCopy(): failed PutStream() for dirEntryInodeNumber 0x%016X (metadata: %v)", dirEntryInodeNumber, metadata)
+		return
+	}
+
+	stat, err = mS.getstatHelperWhileLocked(dirEntryInodeNumber)
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	mtime = stat[StatMTime]
+	ctime = stat[StatCTime]
+	fileInodeNumber = dirEntryInodeNumber
+	numWrites = stat[StatNumWrites]
+
+	heldLocks.free()
+
+	mS.volStruct.notifyNoteName(dirInodeNumber, dirEntryBasename, fileInodeNumber)
+	mS.volStruct.notifyInodeEvent(NotifyWriteClose, fileInodeNumber)
+
+	return
+}
+
+func (mS *mountStruct) MiddlewareDelete(parentDir string, basename string) (err error) {
+	var (
+		containerName          string
+		dirEntryBasename       string
+		dirEntryInodeNumber    inode.InodeNumber
+		dirInodeNumber         inode.InodeNumber
+		doDestroy              bool
+		heldLocks              *heldLocksStruct
+		inodeType              inode.InodeType
+		inodeVolumeHandle      inode.VolumeHandle
+		linkCount              uint64
+		metadata               *inode.MetadataStruct
+		numDirEntries          uint64
+		objectPath             string
+		restartBackoff         time.Duration
+		restoreInodeNumber     inode.InodeNumber
+		restoreVersionID       string
+		retryRequired          bool
+		trashed                bool
+		versionable            bool
+		versioning             versioningStruct
+		versionsDirInodeNumber inode.InodeNumber
 	)
 
 	startTime := time.Now()
@@ -1477,6 +1871,9 @@ func (mS *mountStruct) MiddlewareDelete(parentDir string, basename string) (err
 			globals.MiddlewareDeleteErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditMiddleware(audit.OpMiddlewareDelete, parentDir+"/"+basename, "", 0, err)
+	}()
 
 	// Retry until done or failure (starting with ZERO backoff)
 
@@ -1495,6 +1892,9 @@ Restart:
 
 	heldLocks = newHeldLocks()
 
+	restoreVersionID = ""
+	trashed = false
+
 	dirInodeNumber, dirEntryInodeNumber, dirEntryBasename, _, retryRequired, err =
 		mS.resolvePath(
 			inode.RootDirInodeNumber,
@@ -1524,38 +1924,122 @@ Restart:
 		return
 	}
 
-	if inode.DirType == inodeType {
-		numDirEntries, err = inodeVolumeHandle.NumDirEntries(dirEntryInodeNumber)
+	if inode.DirType == inodeType {
+		numDirEntries, err = inodeVolumeHandle.NumDirEntries(dirEntryInodeNumber)
+		if nil != err {
+			heldLocks.free()
+			return
+		}
+
+		if 2 != numDirEntries {
+			heldLocks.free()
+			err = blunder.NewError(blunder.NotEmptyError, "%s/%s not empty", parentDir, basename)
+			return
+		}
+
+		doDestroy = true
+	} else {
+		linkCount, err = inodeVolumeHandle.GetLinkCount(dirEntryInodeNumber)
+		if nil != err {
+			heldLocks.free()
+			return
+		}
+
+		doDestroy = (1 == linkCount)
+
+		// Objects in versioned containers are either archived (VersioningModeHistory)
+		// or replaced by their most recent prior version (VersioningModeStack)
+
+		containerName, objectPath = splitContainerObjectPath(parentDir + "/" + basename)
+
+		versioning, retryRequired, err = mS.fetchVersioningWhileLocked(heldLocks, containerName)
+		if nil != err {
+			heldLocks.free()
+			return
+		}
+
+		if retryRequired {
+			heldLocks.free()
+			goto Restart
+		}
+
+		switch versioning.Mode {
+		case VersioningModeHistory:
+			versionable, err = mS.isVersionableWhileLocked(dirEntryInodeNumber)
+			if nil != err {
+				heldLocks.free()
+				return
+			}
+
+			if versionable {
+				retryRequired, err = mS.archiveVersionWhileLocked(heldLocks, &versioning, containerName, objectPath, dirInodeNumber, dirEntryBasename)
+				if nil != err {
+					heldLocks.free()
+					return
+				}
+
+				if retryRequired {
+					heldLocks.free()
+					goto Restart
+				}
+
+				heldLocks.free()
+
+				mS.volStruct.notifyNamespaceEvent(NotifyUnlink, dirInodeNumber, dirEntryBasename, dirEntryInodeNumber)
+
+				err = nil
+				return
+			}
+		case VersioningModeStack:
+			versionsDirInodeNumber, restoreVersionID, restoreInodeNumber, retryRequired, err = mS.lockLatestVersionWhileLocked(heldLocks, containerName, objectPath)
+			if nil != err {
+				heldLocks.free()
+				return
+			}
+
+			if retryRequired {
+				heldLocks.free()
+				goto Restart
+			}
+		}
+	}
+
+	// If the volume has a trash, what would be destroyed is moved there on behalf of its owner instead
+
+	if doDestroy && mS.volStruct.trashEnabled && !isTrashPath(parentDir+"/"+basename) {
+		metadata, err = inodeVolumeHandle.GetMetadata(dirEntryInodeNumber)
 		if nil != err {
 			heldLocks.free()
 			return
 		}
 
-		if 2 != numDirEntries {
+		retryRequired, err = mS.trashWithHeldLocks(heldLocks, metadata.UserID, dirInodeNumber, dirEntryBasename, dirEntryInodeNumber, "/"+strings.Trim(parentDir+"/"+basename, "/"))
+		if nil != err {
 			heldLocks.free()
-			err = blunder.NewError(blunder.NotEmptyError, "%s/%s not empty", parentDir, basename)
 			return
 		}
 
-		doDestroy = true
-	} else {
-		linkCount, err = inodeVolumeHandle.GetLinkCount(dirEntryInodeNumber)
-		if nil != err {
+		if retryRequired {
 			heldLocks.free()
-			return
+			goto Restart
 		}
 
-		doDestroy = (1 == linkCount)
+		doDestroy = false
+		trashed = true
 	}
 
 	// Now perform the Unlink() and (potentially) Destroy()
 
-	err = inodeVolumeHandle.Unlink(dirInodeNumber, dirEntryBasename, false)
-	if nil != err {
-		heldLocks.free()
-		return
+	if !trashed {
+		err = inodeVolumeHandle.Unlink(dirInodeNumber, dirEntryBasename, false)
+		if nil != err {
+			heldLocks.free()
+			return
+		}
 	}
 
+	mS.volStruct.notifyNamespaceEvent(NotifyUnlink, dirInodeNumber, dirEntryBasename, dirEntryInodeNumber)
+
 	if doDestroy {
 		err = inodeVolumeHandle.Destroy(dirEntryInodeNumber)
 		if nil != err {
@@ -1563,7 +2047,19 @@ Restart:
 		}
 	}
 
-	// Release heldLocks and exit with success (even if Destroy() failed earlier)
+	// Restore the most recent prior version (if any) in its place
+
+	if "" != restoreVersionID {
+		err = inodeVolumeHandle.Move(versionsDirInodeNumber, restoreVersionID, dirInodeNumber, dirEntryBasename, inode.RenameNoReplace)
+		if nil == err {
+			mS.volStruct.expirationMovedWhileLocked(restoreInodeNumber, dirInodeNumber, dirEntryBasename)
+			mS.volStruct.notifyNamespaceEvent(NotifyCreate, dirInodeNumber, dirEntryBasename, restoreInodeNumber)
+		} else {
+			logger.ErrorfWithError(err, "fs.MiddlewareDelete() failed to restore version %s of %s/%s", restoreVersionID, containerName, objectPath)
+		}
+	}
+
+	// Release heldLocks and exit with success (even if Destroy() or restoring failed earlier)
 
 	heldLocks.free()
 
@@ -1688,8 +2184,8 @@ func (mS *mountStruct) MiddlewareGetAccount(maxEntries uint64, marker string, en
 				moreEntries = false
 				break
 			}
-			if ("." != dirEntrySliceElement.Basename) && (".." != dirEntrySliceElement.Basename) {
-				// So we've skipped "." & ".." - now also skip non-DirInodes
+			if ("." != dirEntrySliceElement.Basename) && (".." != dirEntrySliceElement.Basename) && (VersionsDirName != dirEntrySliceElement.Basename) && (TrashDirName != dirEntrySliceElement.Basename) {
+				// So we've skipped "." & ".." (and the hidden VersionsDirName & TrashDirName) - now also skip non-DirInodes
 				if inode.DirType == dirEntrySliceElement.Type {
 					statResult, err = mS.Getstat(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dirEntrySliceElement.InodeNumber)
 					if nil != err {
@@ -2261,6 +2757,7 @@ Restart:
 			IsDir:            (dirEntrySliceElement.Type == inode.DirType),
 			NumWrites:        dirEntryMetadata.NumWrites,
 			InodeNumber:      uint64(dirEntrySliceElement.InodeNumber),
+			ETag:             fetchETag(inodeVolumeHandle, dirEntrySliceElement.InodeNumber),
 		}
 
 		containerEntry.Metadata, err = inodeVolumeHandle.GetStream(dirEntrySliceElement.InodeNumber, MiddlewareStream)
@@ -2328,7 +2825,7 @@ Restart:
 	return
 }
 
-func (mS *mountStruct) MiddlewareGetObject(containerObjectPath string, readRangeIn []ReadRangeIn, readRangeOut *[]inode.ReadPlanStep) (fileSize uint64, lastModified uint64, lastChanged uint64, ino uint64, numWrites uint64, serializedMetadata []byte, err error) {
+func (mS *mountStruct) MiddlewareGetObject(containerObjectPath string, readRangeIn []ReadRangeIn, readRangeOut *[]inode.ReadPlanStep) (fileSize uint64, lastModified uint64, lastChanged uint64, ino uint64, numWrites uint64, serializedMetadata []byte, eTag string, expireAt uint64, err error) {
 	var (
 		dirEntryInodeNumber inode.InodeNumber
 		fileOffset          uint64
@@ -2390,6 +2887,15 @@ Restart:
 		goto Restart
 	}
 
+	// An expired object is gone... even if the expiration reaper has yet to unlink it
+
+	expireAt = fetchExpireAt(mS.volStruct.inodeVolumeHandle, dirEntryInodeNumber)
+	if isExpired(expireAt) {
+		heldLocks.free()
+		err = blunder.NewError(blunder.NotFoundError, "MiddlewareGetObject(): %s has expired", containerObjectPath)
+		return
+	}
+
 	// Now assemble response
 
 	stat, err = mS.getstatHelperWhileLocked(dirEntryInodeNumber)
@@ -2403,6 +2909,7 @@ Restart:
 	lastChanged = stat[StatCTime]
 	ino = uint64(dirEntryInodeNumber)
 	numWrites = stat[StatNumWrites]
+	eTag = fetchETag(mS.volStruct.inodeVolumeHandle, dirEntryInodeNumber)
 
 	serializedMetadata, err = mS.volStruct.inodeVolumeHandle.GetStream(dirEntryInodeNumber, MiddlewareStream)
 	if nil != err {
@@ -2501,6 +3008,15 @@ Restart:
 		goto Restart
 	}
 
+	// An expired object is gone... even if the expiration reaper has yet to unlink it
+
+	response.ExpireAt = fetchExpireAt(mS.volStruct.inodeVolumeHandle, dirEntryInodeNumber)
+	if isExpired(response.ExpireAt) {
+		heldLocks.free()
+		err = blunder.NewError(blunder.NotFoundError, "MiddlewareHeadResponse(): %s has expired", entityPath)
+		return
+	}
+
 	// Now assemble response
 
 	stat, err = mS.getstatHelperWhileLocked(dirEntryInodeNumber)
@@ -2515,6 +3031,7 @@ Restart:
 	response.IsDir = (stat[StatFType] == uint64(inode.DirType))
 	response.InodeNumber = dirEntryInodeNumber
 	response.NumWrites = stat[StatNumWrites]
+	response.ETag = fetchETag(mS.volStruct.inodeVolumeHandle, dirEntryInodeNumber)
 
 	response.Metadata, err = mS.volStruct.inodeVolumeHandle.GetStream(dirEntryInodeNumber, MiddlewareStream)
 	if nil != err {
@@ -2577,6 +3094,7 @@ Restart:
 			resolvePathFollowDirEntrySymlinks|
 				resolvePathFollowDirSymlinks|
 				resolvePathCreateMissingPathElements|
+				resolvePathNotifyCreatedPathElements|
 				resolvePathRequireExclusiveLockOnDirEntryInode)
 
 	if nil != err {
@@ -2629,13 +3147,16 @@ Restart:
 	return
 }
 
-func (mS *mountStruct) MiddlewarePutComplete(vContainerName string, vObjectPath string, pObjectPaths []string, pObjectLengths []uint64, pObjectMetadata []byte) (mtime uint64, ctime uint64, fileInodeNumber inode.InodeNumber, numWrites uint64, err error) {
+func (mS *mountStruct) MiddlewarePutComplete(vContainerName string, vObjectPath string, pObjectPaths []string, pObjectLengths []uint64, pObjectBlockCRC32s [][]uint32, pObjectMetadata []byte) (mtime uint64, ctime uint64, fileInodeNumber inode.InodeNumber, numWrites uint64, err error) {
 	var (
+		dirEntryBasename    string
 		dirEntryInodeNumber inode.InodeNumber
+		dirInodeNumber      inode.InodeNumber
 		fileOffset          uint64
 		heldLocks           *heldLocksStruct
 		inodeVolumeHandle   inode.VolumeHandle
 		numPObjects         int
+		pObjectBlockCRC32   []uint32
 		pObjectIndex        int
 		restartBackoff      time.Duration
 		retryRequired       bool
@@ -2649,8 +3170,11 @@ func (mS *mountStruct) MiddlewarePutComplete(vContainerName string, vObjectPath
 			globals.MiddlewareMkdirErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditMiddleware(audit.OpMiddlewarePutComplete, vContainerName+"/"+vObjectPath, "", fileInodeNumber, err)
+	}()
 
-	// Validate (pObjectPaths,pObjectLengths) args
+	// Validate (pObjectPaths,pObjectLengths,pObjectBlockCRC32s) args
 
 	numPObjects = len(pObjectPaths)
 
@@ -2659,6 +3183,11 @@ func (mS *mountStruct) MiddlewarePutComplete(vContainerName string, vObjectPath
 		return
 	}
 
+	if (nil != pObjectBlockCRC32s) && (numPObjects != len(pObjectBlockCRC32s)) {
+		err = blunder.NewError(blunder.InvalidArgError, "MiddlewarePutComplete() expects len(pObjectPaths) == len(pObjectBlockCRC32s) if pObjectBlockCRC32s supplied")
+		return
+	}
+
 	// Retry until done or failure (starting with ZERO backoff)
 
 	restartBackoff = time.Duration(0)
@@ -2676,7 +3205,7 @@ Restart:
 
 	heldLocks = newHeldLocks()
 
-	_, dirEntryInodeNumber, _, _, retryRequired, err =
+	dirInodeNumber, dirEntryInodeNumber, dirEntryBasename, _, retryRequired, err =
 		mS.resolvePath(
 			inode.RootDirInodeNumber,
 			vContainerName+"/"+vObjectPath,
@@ -2684,6 +3213,7 @@ Restart:
 			resolvePathFollowDirEntrySymlinks|
 				resolvePathFollowDirSymlinks|
 				resolvePathCreateMissingPathElements|
+				resolvePathNotifyCreatedPathElements|
 				resolvePathDirEntryInodeMustBeFile|
 				resolvePathRequireExclusiveLockOnDirEntryInode)
 
@@ -2697,6 +3227,19 @@ Restart:
 		goto Restart
 	}
 
+	// Preserve the FileInode about to be replaced if vContainerName is versioned
+
+	dirEntryInodeNumber, retryRequired, err = mS.versionBeforeReplaceWhileLocked(heldLocks, vContainerName+"/"+vObjectPath, dirInodeNumber, dirEntryBasename, dirEntryInodeNumber)
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
 	// Apply (pObjectPaths,pObjectLengths) to (erased) FileInode
 
 	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
@@ -2704,12 +3247,19 @@ Restart:
 	fileOffset = 0
 
 	for pObjectIndex = 0; pObjectIndex < numPObjects; pObjectIndex++ {
+		if nil == pObjectBlockCRC32s {
+			pObjectBlockCRC32 = nil
+		} else {
+			pObjectBlockCRC32 = pObjectBlockCRC32s[pObjectIndex]
+		}
+
 		err = inodeVolumeHandle.Wrote(
 			dirEntryInodeNumber,
 			fileOffset,
 			pObjectPaths[pObjectIndex],
 			0,
 			pObjectLengths[pObjectIndex],
+			pObjectBlockCRC32,
 			pObjectIndex > 0) // Initial pObjectIndex == 0 case will implicitly SetSize(,0)
 		if nil != err {
 			heldLocks.free()
@@ -2741,6 +3291,10 @@ Restart:
 	numWrites = stat[StatNumWrites]
 
 	heldLocks.free()
+
+	mS.volStruct.notifyNoteName(dirInodeNumber, dirEntryBasename, fileInodeNumber)
+	mS.volStruct.notifyInodeEvent(NotifyWriteClose, fileInodeNumber)
+
 	return
 }
 
@@ -2760,6 +3314,9 @@ func (mS *mountStruct) MiddlewareMkdir(vContainerName string, vObjectPath string
 			globals.MiddlewareMkdirErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditMiddleware(audit.OpMiddlewareMkdir, vContainerName+"/"+vObjectPath, "", inodeNumber, err)
+	}()
 
 	// Retry until done or failure (starting with ZERO backoff)
 
@@ -2785,6 +3342,7 @@ Restart:
 			heldLocks,
 			resolvePathFollowDirSymlinks|
 				resolvePathCreateMissingPathElements|
+				resolvePathNotifyCreatedPathElements|
 				resolvePathDirEntryInodeMustBeDirectory|
 				resolvePathRequireExclusiveLockOnDirEntryInode)
 
@@ -2839,6 +3397,14 @@ func (mS *mountStruct) MiddlewarePutContainer(containerName string, oldMetadata
 			globals.MiddlewarePutContainerErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditMiddleware(audit.OpMiddlewarePutContainer, containerName, "", 0, err)
+	}()
+
+	if (VersionsDirName == containerName) || (TrashDirName == containerName) {
+		err = blunder.NewError(blunder.PermDeniedError, "MiddlewarePutContainer() called for reserved container %s", containerName)
+		return
+	}
 
 	mS.volStruct.jobRWMutex.RLock()
 	defer mS.volStruct.jobRWMutex.RUnlock()
@@ -2917,6 +3483,9 @@ func (mS *mountStruct) Mkdir(userID inode.InodeUserID, groupID inode.InodeGroupI
 			globals.MkdirErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditDirEntry(audit.OpMkdir, userID, groupID, inodeNumber, basename, newDirInodeNumber, nil, err)
+	}()
 
 	mS.volStruct.jobRWMutex.RLock()
 	defer mS.volStruct.jobRWMutex.RUnlock()
@@ -2927,6 +3496,11 @@ func (mS *mountStruct) Mkdir(userID inode.InodeUserID, groupID inode.InodeGroupI
 		return 0, err
 	}
 
+	if isVersionsDirEntry(inodeNumber, basename) {
+		err = blunder.NewError(blunder.NotPermError, "EPERM")
+		return
+	}
+
 	newDirInodeNumber, err = mS.volStruct.inodeVolumeHandle.CreateDir(filePerm, userID, groupID)
 	if err != nil {
 		logger.ErrorWithError(err)
@@ -2973,6 +3547,8 @@ func (mS *mountStruct) Mkdir(userID inode.InodeUserID, groupID inode.InodeGroupI
 		return 0, err
 	}
 
+	mS.volStruct.notifyNamespaceEvent(NotifyCreate, inodeNumber, basename, newDirInodeNumber)
+
 	return newDirInodeNumber, nil
 }
 
@@ -2985,6 +3561,14 @@ func (mS *mountStruct) RemoveXAttr(userID inode.InodeUserID, groupID inode.Inode
 			globals.RemoveXAttrErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditInode(audit.OpRemoveXAttr, userID, groupID, inodeNumber, map[string]string{"Name": streamName}, err)
+	}()
+
+	if isReservedStreamName(streamName) {
+		err = blunder.NewError(blunder.NotPermError, "EPERM")
+		return
+	}
 
 	mS.volStruct.jobRWMutex.RLock()
 	defer mS.volStruct.jobRWMutex.RUnlock()
@@ -3013,6 +3597,8 @@ func (mS *mountStruct) RemoveXAttr(userID inode.InodeUserID, groupID inode.Inode
 	err = mS.volStruct.inodeVolumeHandle.DeleteStream(inodeNumber, streamName)
 	if err != nil {
 		logger.ErrorfWithError(err, "Failed to delete XAttr %v of inode %v", streamName, inodeNumber)
+	} else {
+		mS.volStruct.notifyInodeEvent(NotifyXAttr, inodeNumber)
 	}
 
 	mS.volStruct.untrackInFlightFileInodeData(inodeNumber, false)
@@ -3020,14 +3606,16 @@ func (mS *mountStruct) RemoveXAttr(userID inode.InodeUserID, groupID inode.Inode
 	return
 }
 
-func (mS *mountStruct) Rename(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, srcDirInodeNumber inode.InodeNumber, srcBasename string, dstDirInodeNumber inode.InodeNumber, dstBasename string) (err error) {
+func (mS *mountStruct) Rename(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, srcDirInodeNumber inode.InodeNumber, srcBasename string, dstDirInodeNumber inode.InodeNumber, dstBasename string, flags inode.RenameFlags) (err error) {
 	var (
 		dirEntryBasename    string
 		dirEntryInodeNumber inode.InodeNumber
 		dirInodeNumber      inode.InodeNumber
+		dstInodeNumber      inode.InodeNumber
 		heldLocks           *heldLocksStruct
 		restartBackoff      time.Duration
 		retryRequired       bool
+		srcInodeNumber      inode.InodeNumber
 	)
 
 	startTime := time.Now()
@@ -3037,6 +3625,9 @@ func (mS *mountStruct) Rename(userID inode.InodeUserID, groupID inode.InodeGroup
 			globals.RenameErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditRename(userID, groupID, srcDirInodeNumber, srcBasename, dstDirInodeNumber, dstBasename, flags, err)
+	}()
 
 	mS.volStruct.jobRWMutex.RLock()
 	defer mS.volStruct.jobRWMutex.RUnlock()
@@ -3051,6 +3642,11 @@ func (mS *mountStruct) Rename(userID inode.InodeUserID, groupID inode.InodeGroup
 		return
 	}
 
+	if isVersionsDirEntry(srcDirInodeNumber, srcBasename) || isVersionsDirEntry(dstDirInodeNumber, dstBasename) {
+		err = blunder.NewError(blunder.NotPermError, "EPERM")
+		return
+	}
+
 	// Retry until done or failure (starting with ZERO backoff)
 
 	restartBackoff = time.Duration(0)
@@ -3070,7 +3666,7 @@ Restart:
 
 	// Acquire WriteLock on {srcDirInodeNumber,srcBasename} & perform Access Check
 
-	dirInodeNumber, _, dirEntryBasename, _, retryRequired, err =
+	dirInodeNumber, srcInodeNumber, dirEntryBasename, _, retryRequired, err =
 		mS.resolvePath(
 			srcDirInodeNumber,
 			srcBasename,
@@ -3135,7 +3731,7 @@ Restart:
 
 	// Acquire WriteLock on dstBasename if it exists
 
-	dirInodeNumber, _, dirEntryBasename, _, retryRequired, err =
+	dirInodeNumber, dstInodeNumber, dirEntryBasename, _, retryRequired, err =
 		mS.resolvePath(
 			dstDirInodeNumber,
 			dstBasename,
@@ -3155,11 +3751,56 @@ Restart:
 		}
 	} else {
 		// This is actually OK... it means the target path of the Rename() isn't being potentially replaced
+
+		dstInodeNumber = inode.InodeNumber(0)
+	}
+
+	// If the volume has a trash, move a (non-directory) target about to be replaced there first
+
+	if mS.volStruct.trashEnabled && (inode.InodeNumber(0) != dstInodeNumber) && (srcInodeNumber != dstInodeNumber) && (0 == (flags & (inode.RenameNoReplace | inode.RenameExchange))) {
+		dstInodeType, trashErr := mS.volStruct.inodeVolumeHandle.GetType(dstInodeNumber)
+		if nil != trashErr {
+			heldLocks.free()
+			err = trashErr
+			return
+		}
+		trashable, trashErr := mS.isTrashableWhileLocked(dstInodeNumber)
+		if nil != trashErr {
+			heldLocks.free()
+			err = trashErr
+			return
+		}
+		if (inode.DirType != dstInodeType) && trashable {
+			if !mS.volStruct.isInTrash(dstDirInodeNumber) {
+				retryRequired, err = mS.trashWithHeldLocks(heldLocks, userID, dstDirInodeNumber, dstBasename, dstInodeNumber, "")
+				if nil != err {
+					heldLocks.free()
+					return
+				}
+
+				if retryRequired {
+					heldLocks.free()
+					goto Restart
+				}
+			}
+		}
 	}
 
 	// Locks held & Access Checks succeeded... time to do the Move
 
-	err = mS.volStruct.inodeVolumeHandle.Move(srcDirInodeNumber, srcBasename, dstDirInodeNumber, dstBasename)
+	err = mS.volStruct.inodeVolumeHandle.Move(srcDirInodeNumber, srcBasename, dstDirInodeNumber, dstBasename, flags)
+
+	if nil == err {
+		mS.volStruct.expirationMovedWhileLocked(srcInodeNumber, dstDirInodeNumber, dstBasename)
+		if (inode.InodeNumber(0) != dstInodeNumber) && (srcInodeNumber != dstInodeNumber) && (0 == (flags & inode.RenameExchange)) {
+			mS.volStruct.notifyNamespaceEvent(NotifyUnlink, dstDirInodeNumber, dstBasename, dstInodeNumber)
+		}
+		mS.volStruct.notifyRenameEvent(srcDirInodeNumber, srcBasename, dstDirInodeNumber, dstBasename, srcInodeNumber)
+		if (inode.RenameExchange == (flags & inode.RenameExchange)) && (srcInodeNumber != dstInodeNumber) {
+			mS.volStruct.expirationMovedWhileLocked(dstInodeNumber, srcDirInodeNumber, srcBasename)
+			mS.volStruct.notifyRenameEvent(dstDirInodeNumber, dstBasename, srcDirInodeNumber, srcBasename, dstInodeNumber)
+		}
+	}
 
 	heldLocks.free()
 
@@ -3167,6 +3808,11 @@ Restart:
 }
 
 func (mS *mountStruct) Read(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64, length uint64, profiler *utils.Profiler) (buf []byte, err error) {
+	buf, err = mS.ReadWithReadAhead(userID, groupID, otherGroupIDs, inodeNumber, offset, length, nil, profiler)
+	return
+}
+
+func (mS *mountStruct) ReadWithReadAhead(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64, length uint64, readAheadState *inode.ReadAheadState, profiler *utils.Profiler) (buf []byte, err error) {
 
 	startTime := time.Now()
 	defer func() {
@@ -3214,7 +3860,7 @@ func (mS *mountStruct) Read(userID inode.InodeUserID, groupID inode.InodeGroupID
 	}
 
 	profiler.AddEventNow("before inode.Read()")
-	buf, err = mS.volStruct.inodeVolumeHandle.Read(inodeNumber, offset, length, profiler)
+	buf, err = mS.volStruct.inodeVolumeHandle.ReadWithReadAhead(inodeNumber, offset, length, readAheadState, profiler)
 	profiler.AddEventNow("after inode.Read()")
 	if uint64(len(buf)) > length {
 		err = fmt.Errorf("%s: Buf length %v is greater than supplied length %v", utils.GetFnName(), uint64(len(buf)), length)
@@ -3271,6 +3917,20 @@ Restart:
 
 	dirEntries, areMoreEntries, err = inodeVolumeHandle.ReadDir(inodeNumber, maxEntries, 0, prevReturned...)
 
+	// Hide VersionsDirName... reading past it should it have been the only entry returned
+
+	if (nil == err) && (inode.RootDirInodeNumber == inodeNumber) {
+		for dirEntryIndex = 0; dirEntryIndex < uint64(len(dirEntries)); dirEntryIndex++ {
+			if VersionsDirName == dirEntries[dirEntryIndex].Basename {
+				dirEntries = append(dirEntries[:dirEntryIndex], dirEntries[dirEntryIndex+1:]...)
+				if (0 == len(dirEntries)) && areMoreEntries {
+					dirEntries, areMoreEntries, err = inodeVolumeHandle.ReadDir(inodeNumber, maxEntries, 0, VersionsDirName)
+				}
+				break
+			}
+		}
+	}
+
 	internalErr = inodeLock.Unlock()
 	if nil != internalErr {
 		logger.Fatalf("Failure unlocking a held LockID %s: %v", inodeLock.LockID, internalErr)
@@ -3422,6 +4082,10 @@ func (mS *mountStruct) Resize(userID inode.InodeUserID, groupID inode.InodeGroup
 	err = mS.volStruct.inodeVolumeHandle.SetSize(inodeNumber, newSize)
 	mS.volStruct.untrackInFlightFileInodeData(inodeNumber, false)
 
+	if nil == err {
+		mS.volStruct.notifyInodeEvent(NotifySetattr, inodeNumber)
+	}
+
 	return err
 }
 
@@ -3434,10 +4098,29 @@ func (mS *mountStruct) Rmdir(userID inode.InodeUserID, groupID inode.InodeGroupI
 			globals.RmdirErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditDirEntry(audit.OpRmdir, userID, groupID, inodeNumber, basename, 0, nil, err)
+	}()
 
 	mS.volStruct.jobRWMutex.RLock()
 	defer mS.volStruct.jobRWMutex.RUnlock()
 
+	if isVersionsDirEntry(inodeNumber, basename) {
+		err = blunder.NewError(blunder.NotPermError, "EPERM")
+		return
+	}
+
+	// If the volume has a trash, ensure the caller's trash directory exists before locking anything
+
+	trashDirInodeNumber := inode.InodeNumber(0)
+	if mS.volStruct.trashEnabled {
+		trashDirInodeNumber, err = mS.ensureTrashDir(userID)
+		if nil != err {
+			logger.ErrorfWithError(err, "fs.Rmdir() unable to create trash directory for UserID %v... failing removal of %v/%v", userID, inodeNumber, basename)
+			return
+		}
+	}
+
 	callerID := dlm.GenerateCallerID()
 	inodeLock, err := mS.volStruct.inodeVolumeHandle.InitInodeLock(inodeNumber, callerID)
 	if err != nil {
@@ -3499,11 +4182,25 @@ func (mS *mountStruct) Rmdir(userID inode.InodeUserID, groupID inode.InodeGroupI
 		return
 	}
 
+	if inode.InodeNumber(0) != trashDirInodeNumber {
+		trashed, trashErr := mS.trashWithCallerID(callerID, trashDirInodeNumber, inodeNumber, basename, basenameInodeNumber)
+		if nil != trashErr {
+			err = trashErr
+			return
+		}
+		if trashed {
+			mS.volStruct.notifyNamespaceEvent(NotifyUnlink, inodeNumber, basename, basenameInodeNumber)
+			return
+		}
+	}
+
 	err = mS.volStruct.inodeVolumeHandle.Unlink(inodeNumber, basename, false)
 	if nil != err {
 		return
 	}
 
+	mS.volStruct.notifyNamespaceEvent(NotifyUnlink, inodeNumber, basename, basenameInodeNumber)
+
 	err = mS.volStruct.inodeVolumeHandle.Destroy(basenameInodeNumber)
 	if nil != err {
 		return
@@ -3512,6 +4209,79 @@ func (mS *mountStruct) Rmdir(userID inode.InodeUserID, groupID inode.InodeGroupI
 	return
 }
 
+func (mS *mountStruct) SeekData(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64) (dataOffset uint64, err error) {
+	startTime := time.Now()
+	defer func() {
+		globals.SeekDataUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.SeekDataErrors.Add(1)
+		}
+	}()
+
+	dataOffset, err = mS.seek(userID, groupID, otherGroupIDs, inodeNumber, offset, true)
+	return
+}
+
+func (mS *mountStruct) SeekHole(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64) (holeOffset uint64, err error) {
+	startTime := time.Now()
+	defer func() {
+		globals.SeekHoleUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.SeekHoleErrors.Add(1)
+		}
+	}()
+
+	holeOffset, err = mS.seek(userID, groupID, otherGroupIDs, inodeNumber, offset, false)
+	return
+}
+
+func (mS *mountStruct) seek(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, offset uint64, seekData bool) (newOffset uint64, err error) {
+	mS.volStruct.jobRWMutex.RLock()
+	defer mS.volStruct.jobRWMutex.RUnlock()
+
+	inodeLock, err := mS.volStruct.inodeVolumeHandle.InitInodeLock(inodeNumber, nil)
+	if err != nil {
+		return
+	}
+	err = inodeLock.ReadLock()
+	if err != nil {
+		return
+	}
+	defer inodeLock.Unlock()
+
+	if !mS.volStruct.inodeVolumeHandle.Access(inodeNumber, userID, groupID, otherGroupIDs, inode.F_OK,
+		inode.NoOverride) {
+		err = blunder.NewError(blunder.NotFoundError, "ENOENT")
+		return
+	}
+	if !mS.volStruct.inodeVolumeHandle.Access(inodeNumber, userID, groupID, otherGroupIDs, inode.R_OK,
+		inode.OwnerOverride) {
+		err = blunder.NewError(blunder.PermDeniedError, "EACCES")
+		return
+	}
+
+	inodeType, err := mS.volStruct.inodeVolumeHandle.GetType(inodeNumber)
+	if err != nil {
+		logger.ErrorfWithError(err, "couldn't get type for inode %v", inodeNumber)
+		return
+	}
+	// Make sure the inode number is for a file inode
+	if inodeType != inode.FileType {
+		err = fmt.Errorf("%s: expected inode %v to be a file inode, got %v", utils.GetFnName(), inodeNumber, inodeType)
+		logger.ErrorWithError(err)
+		err = blunder.AddError(err, blunder.NotFileError)
+		return
+	}
+
+	if seekData {
+		newOffset, err = mS.volStruct.inodeVolumeHandle.SeekData(inodeNumber, offset)
+	} else {
+		newOffset, err = mS.volStruct.inodeVolumeHandle.SeekHole(inodeNumber, offset)
+	}
+
+	return
+}
+
 func (mS *mountStruct) Setstat(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, inodeNumber inode.InodeNumber, stat Stat) (err error) {
 
 	startTime := time.Now()
@@ -3521,6 +4291,9 @@ func (mS *mountStruct) Setstat(userID inode.InodeUserID, groupID inode.InodeGrou
 			globals.SetstatErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditSetstat(userID, groupID, inodeNumber, stat, err)
+	}()
 
 	mS.volStruct.jobRWMutex.RLock()
 	defer mS.volStruct.jobRWMutex.RUnlock()
@@ -3704,6 +4477,8 @@ func (mS *mountStruct) Setstat(userID inode.InodeUserID, groupID inode.InodeGrou
 		}
 	}
 
+	mS.volStruct.notifyInodeEvent(NotifySetattr, inodeNumber)
+
 	return
 }
 
@@ -3722,6 +4497,14 @@ func (mS *mountStruct) SetXAttr(userID inode.InodeUserID, groupID inode.InodeGro
 			globals.SetXAttrErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditInode(audit.OpSetXAttr, userID, groupID, inodeNumber, map[string]string{"Name": streamName}, err)
+	}()
+
+	if isReservedStreamName(streamName) {
+		err = blunder.NewError(blunder.NotPermError, "EPERM")
+		return
+	}
 
 	mS.volStruct.jobRWMutex.RLock()
 	defer mS.volStruct.jobRWMutex.RUnlock()
@@ -3767,6 +4550,8 @@ func (mS *mountStruct) SetXAttr(userID inode.InodeUserID, groupID inode.InodeGro
 	err = mS.volStruct.inodeVolumeHandle.PutStream(inodeNumber, streamName, value)
 	if err != nil {
 		logger.ErrorfWithError(err, "Failed to set XAttr %v to inode %v", streamName, inodeNumber)
+	} else {
+		mS.volStruct.notifyInodeEvent(NotifyXAttr, inodeNumber)
 	}
 
 	mS.volStruct.untrackInFlightFileInodeData(inodeNumber, false)
@@ -3813,6 +4598,9 @@ func (mS *mountStruct) Symlink(userID inode.InodeUserID, groupID inode.InodeGrou
 			globals.SymlinkErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditDirEntry(audit.OpSymlink, userID, groupID, inodeNumber, basename, symlinkInodeNumber, map[string]string{"Target": target}, err)
+	}()
 
 	mS.volStruct.jobRWMutex.RLock()
 	defer mS.volStruct.jobRWMutex.RUnlock()
@@ -3822,6 +4610,11 @@ func (mS *mountStruct) Symlink(userID inode.InodeUserID, groupID inode.InodeGrou
 		return
 	}
 
+	if isVersionsDirEntry(inodeNumber, basename) {
+		err = blunder.NewError(blunder.NotPermError, "EPERM")
+		return
+	}
+
 	err = validateFullPath(target)
 	if err != nil {
 		return
@@ -3873,6 +4666,8 @@ func (mS *mountStruct) Symlink(userID inode.InodeUserID, groupID inode.InodeGrou
 		return
 	}
 
+	mS.volStruct.notifyNamespaceEvent(NotifyCreate, inodeNumber, basename, symlinkInodeNumber)
+
 	return
 }
 
@@ -3885,10 +4680,29 @@ func (mS *mountStruct) Unlink(userID inode.InodeUserID, groupID inode.InodeGroup
 			globals.UnlinkErrors.Add(1)
 		}
 	}()
+	defer func() {
+		mS.auditDirEntry(audit.OpUnlink, userID, groupID, inodeNumber, basename, 0, nil, err)
+	}()
 
 	mS.volStruct.jobRWMutex.RLock()
 	defer mS.volStruct.jobRWMutex.RUnlock()
 
+	if isVersionsDirEntry(inodeNumber, basename) {
+		err = blunder.NewError(blunder.NotPermError, "EPERM")
+		return
+	}
+
+	// If the volume has a trash, ensure the caller's trash directory exists before locking anything
+
+	trashDirInodeNumber := inode.InodeNumber(0)
+	if mS.volStruct.trashEnabled {
+		trashDirInodeNumber, err = mS.ensureTrashDir(userID)
+		if nil != err {
+			logger.ErrorfWithError(err, "fs.Unlink() unable to create trash directory for UserID %v... failing removal of %v/%v", userID, inodeNumber, basename)
+			return
+		}
+	}
+
 	callerID := dlm.GenerateCallerID()
 	inodeLock, err := mS.volStruct.inodeVolumeHandle.InitInodeLock(inodeNumber, callerID)
 	if err != nil {
@@ -3937,11 +4751,25 @@ func (mS *mountStruct) Unlink(userID inode.InodeUserID, groupID inode.InodeGroup
 		return
 	}
 
+	if inode.InodeNumber(0) != trashDirInodeNumber {
+		trashed, trashErr := mS.trashWithCallerID(callerID, trashDirInodeNumber, inodeNumber, basename, basenameInodeNumber)
+		if nil != trashErr {
+			err = trashErr
+			return
+		}
+		if trashed {
+			mS.volStruct.notifyNamespaceEvent(NotifyUnlink, inodeNumber, basename, basenameInodeNumber)
+			return
+		}
+	}
+
 	err = mS.volStruct.inodeVolumeHandle.Unlink(inodeNumber, basename, false)
 	if nil != err {
 		return
 	}
 
+	mS.volStruct.notifyNamespaceEvent(NotifyUnlink, inodeNumber, basename, basenameInodeNumber)
+
 	basenameLinkCount, err := mS.volStruct.inodeVolumeHandle.GetLinkCount(basenameInodeNumber)
 	if nil != err {
 		return
@@ -4109,10 +4937,42 @@ func (mS *mountStruct) removeObstacleToObjectPut(callerID dlm.CallerID, dirInode
 	return nil
 }
 
+// Utility function to identify the alternate data streams maintained by ProxyFS itself (rather than
+// on behalf of XAttr callers that might otherwise forge or remove them)
+func isReservedStreamName(streamName string) (reserved bool) {
+	switch streamName {
+	case inode.ETagStreamName, ExpirationStream, TrashDirEntryStream, TrashPathStream, TrashTimeStream, VersioningStream:
+		reserved = true
+	default:
+		reserved = false
+	}
+	return
+}
+
+// Utility function to fetch the ETag maintained (if any) for a FileInode
+func fetchETag(inodeVolumeHandle inode.VolumeHandle, inodeNumber inode.InodeNumber) (eTag string) {
+	eTagBuf, err := inodeVolumeHandle.GetStream(inodeNumber, inode.ETagStreamName)
+	if nil != err {
+		eTag = ""
+	} else {
+		eTag = string(eTagBuf)
+	}
+	return
+}
+
 // Utility function to append entries to reply
 func appendReadPlanEntries(readPlan []inode.ReadPlanStep, readRangeOut *[]inode.ReadPlanStep) (numEntries uint64) {
 	for i := range readPlan {
-		entry := inode.ReadPlanStep{ObjectPath: readPlan[i].ObjectPath, Offset: readPlan[i].Offset, Length: readPlan[i].Length}
+		entry := inode.ReadPlanStep{
+			ObjectPath:             readPlan[i].ObjectPath,
+			Offset:                 readPlan[i].Offset,
+			Length:                 readPlan[i].Length,
+			Compression:            readPlan[i].Compression,
+			Encryption:             readPlan[i].Encryption,
+			CompressedObjectOffset: readPlan[i].CompressedObjectOffset,
+			CompressedObjectLength: readPlan[i].CompressedObjectLength,
+			InlineData:             readPlan[i].InlineData,
+		}
 		*readRangeOut = append(*readRangeOut, entry)
 		numEntries++
 	}
@@ -435,7 +435,7 @@ func TestBadRename(t *testing.T) {
 	}
 
 	// Try to rename a valid file to a name that is too long
-	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInode, validFile, testDirInode, nameTooLong)
+	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInode, validFile, testDirInode, nameTooLong, 0)
 	if nil != err {
 		if blunder.IsNot(err, blunder.NameTooLongError) {
 			t.Fatalf("Link() returned error %v, expected %v(%d).", blunder.Errno(err), blunder.NameTooLongError, blunder.NameTooLongError.Value())
@@ -448,7 +448,7 @@ func TestBadRename(t *testing.T) {
 	expectDirectory(t, inode.InodeRootUserID, inode.InodeGroupID(0), testDirInode, entriesExpected)
 
 	// Try to rename a nonexistent file with a name that is too long
-	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInode, nameTooLong, testDirInode, "AlsoAGoodFilename")
+	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInode, nameTooLong, testDirInode, "AlsoAGoodFilename", 0)
 	if nil != err {
 		if blunder.IsNot(err, blunder.NameTooLongError) {
 			t.Fatalf("Link() returned error %v, expected %v(%d).", blunder.Errno(err), blunder.NameTooLongError, blunder.NameTooLongError.Value())
@@ -463,6 +463,79 @@ func TestBadRename(t *testing.T) {
 	testTeardown(t)
 }
 
+func TestRenameFlags(t *testing.T) {
+	testSetup(t, false)
+
+	testDirInode := createTestDirectory(t, "RenameFlags")
+
+	fileInode, err := testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInode, "file", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	otherFileInode, err := testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInode, "otherFile", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	subDirInode, err := testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInode, "subDir", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+	subSubDirInode, err := testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, subDirInode, "subSubDir", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+
+	expectLookup := func(dirInode inode.InodeNumber, basename string, expectedInode inode.InodeNumber) {
+		lookupInode, err := testMountStruct.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dirInode, basename)
+		if nil != err {
+			t.Fatalf("Lookup(\"%v\") returned error: %v", basename, err)
+		}
+		if expectedInode != lookupInode {
+			t.Fatalf("Lookup(\"%v\") returned %v (expected %v)", basename, lookupInode, expectedInode)
+		}
+	}
+
+	// RENAME_NOREPLACE must not replace an existing target
+
+	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInode, "file", testDirInode, "otherFile", inode.RenameNoReplace)
+	if blunder.IsNot(err, blunder.FileExistsError) {
+		t.Fatalf("Rename(RenameNoReplace) returned %v (expected EEXIST)", err)
+	}
+	expectLookup(testDirInode, "file", fileInode)
+	expectLookup(testDirInode, "otherFile", otherFileInode)
+
+	// RENAME_EXCHANGE of two files in the same directory
+
+	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInode, "file", testDirInode, "otherFile", inode.RenameExchange)
+	if nil != err {
+		t.Fatalf("Rename(RenameExchange) returned error: %v", err)
+	}
+	expectLookup(testDirInode, "file", otherFileInode)
+	expectLookup(testDirInode, "otherFile", fileInode)
+
+	// RENAME_EXCHANGE of a file and a directory in different directories
+
+	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInode, "file", subDirInode, "subSubDir", inode.RenameExchange)
+	if nil != err {
+		t.Fatalf("Rename(RenameExchange) returned error: %v", err)
+	}
+	expectLookup(testDirInode, "file", subSubDirInode)
+	expectLookup(subDirInode, "subSubDir", otherFileInode)
+	expectLookup(subSubDirInode, "..", testDirInode)
+
+	entriesExpected := []string{".", "..", "file", "otherFile", "subDir"}
+	expectDirectory(t, inode.InodeRootUserID, inode.InodeGroupID(0), testDirInode, entriesExpected)
+
+	// RENAME_EXCHANGE requires the target to exist
+
+	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInode, "otherFile", testDirInode, "missing", inode.RenameExchange)
+	if blunder.IsNot(err, blunder.NotFoundError) {
+		t.Fatalf("Rename(RenameExchange) returned %v (expected ENOENT)", err)
+	}
+
+	testTeardown(t)
+}
+
 func TestBadChownChmod(t *testing.T) {
 	var (
 		err error
@@ -991,7 +1064,7 @@ func TestStaleInodes(t *testing.T) {
 
 	// Rename -- two cases, one with stale src directory and one with stale dest
 	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil,
-		testDirInodeNumber, "fubar", staleDirInodeNumber, "barfu")
+		testDirInodeNumber, "fubar", staleDirInodeNumber, "barfu", 0)
 	if nil == err {
 		t.Fatalf("Rename(1) should not have returned success")
 	}
@@ -1000,7 +1073,7 @@ func TestStaleInodes(t *testing.T) {
 	}
 
 	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil,
-		staleDirInodeNumber, "fubar", testDirInodeNumber, "barfu")
+		staleDirInodeNumber, "fubar", testDirInodeNumber, "barfu", 0)
 	if nil == err {
 		t.Fatalf("Rename(2) should not have returned success")
 	}
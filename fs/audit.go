@@ -0,0 +1,157 @@
+package fs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/swiftstack/ProxyFS/audit"
+	"github.com/swiftstack/ProxyFS/inode"
+)
+
+// Namespace and permission changing operations are reported to package audit. Each such operation
+// defers a call to one of the helpers below before obtaining any locks. As deferred calls run in
+// reverse order, the record is made after the operation has completed (successfully or not) and
+// released its locks. Operations addressing their target by directory inode and basename record just
+// those... only the middleware (as well as TrashRestore() and the trash purger) supply paths. Those
+// changes made by the expiration reaper and trash purger are recorded as made by the root user.
+
+// SetClientAddr records the address of the client on whose behalf this mount operates.
+func (mS *mountStruct) SetClientAddr(clientAddr string) {
+	mS.clientAddr = clientAddr
+}
+
+func (mS *mountStruct) auditRecord(op string, userID inode.InodeUserID, groupID inode.InodeGroupID) (record *audit.Record) {
+	record = &audit.Record{
+		Op:         op,
+		Volume:     mS.volStruct.volumeName,
+		UserID:     uint32(userID),
+		GroupID:    uint32(groupID),
+		ClientAddr: mS.clientAddr,
+	}
+	return
+}
+
+// auditDirEntry records op upon dirInodeNumber/basename (that references inodeNumber if known).
+func (mS *mountStruct) auditDirEntry(op string, userID inode.InodeUserID, groupID inode.InodeGroupID, dirInodeNumber inode.InodeNumber, basename string, inodeNumber inode.InodeNumber, attrs map[string]string, err error) {
+	if !audit.Enabled() {
+		return
+	}
+
+	record := mS.auditRecord(op, userID, groupID)
+	record.InodeNumber = uint64(inodeNumber)
+	record.DirInodeNumber = uint64(dirInodeNumber)
+	record.Basename = basename
+	record.Attrs = attrs
+
+	audit.Log(record, err)
+}
+
+// auditInode records op upon inodeNumber (whose path is not known).
+func (mS *mountStruct) auditInode(op string, userID inode.InodeUserID, groupID inode.InodeGroupID, inodeNumber inode.InodeNumber, attrs map[string]string, err error) {
+	if !audit.Enabled() {
+		return
+	}
+
+	record := mS.auditRecord(op, userID, groupID)
+	record.InodeNumber = uint64(inodeNumber)
+	record.Attrs = attrs
+
+	audit.Log(record, err)
+}
+
+// auditRename records a rename of srcDirInodeNumber/srcBasename to dstDirInodeNumber/dstBasename.
+func (mS *mountStruct) auditRename(userID inode.InodeUserID, groupID inode.InodeGroupID, srcDirInodeNumber inode.InodeNumber, srcBasename string, dstDirInodeNumber inode.InodeNumber, dstBasename string, flags inode.RenameFlags, err error) {
+	if !audit.Enabled() {
+		return
+	}
+
+	record := mS.auditRecord(audit.OpRename, userID, groupID)
+	record.DirInodeNumber = uint64(srcDirInodeNumber)
+	record.Basename = srcBasename
+	record.NewDirInodeNumber = uint64(dstDirInodeNumber)
+	record.NewBasename = dstBasename
+	if 0 != flags {
+		record.Attrs = map[string]string{"Flags": fmt.Sprintf("0x%X", uint32(flags))}
+	}
+
+	audit.Log(record, err)
+}
+
+// auditMiddleware records op (issued via the middleware on behalf of the root user) upon path.
+func (mS *mountStruct) auditMiddleware(op string, path string, newPath string, inodeNumber inode.InodeNumber, err error) {
+	if !audit.Enabled() {
+		return
+	}
+
+	record := mS.auditRecord(op, inode.InodeRootUserID, inode.InodeGroupID(0))
+	record.InodeNumber = uint64(inodeNumber)
+	record.Path = "/" + strings.Trim(path, "/")
+	if "" != newPath {
+		record.NewPath = "/" + strings.Trim(newPath, "/")
+	}
+
+	audit.Log(record, err)
+}
+
+// auditTrashRestore records a TrashRestore() of trashUserID's trashID to restorePath.
+func (mS *mountStruct) auditTrashRestore(userID inode.InodeUserID, groupID inode.InodeGroupID, trashUserID inode.InodeUserID, trashID string, restorePath string, err error) {
+	if !audit.Enabled() {
+		return
+	}
+
+	record := mS.auditRecord(audit.OpTrashRestore, userID, groupID)
+	record.Path = "/" + trashUserDirPath(trashUserID) + "/" + trashID
+	if "" != restorePath {
+		record.NewPath = "/" + strings.Trim(restorePath, "/")
+	}
+
+	audit.Log(record, err)
+}
+
+// auditTrashPurge records the trash purger's destruction of a trashed inode.
+func (mS *mountStruct) auditTrashPurge(entry *TrashEntry, err error) {
+	if !audit.Enabled() {
+		return
+	}
+
+	record := mS.auditRecord(audit.OpTrashPurge, inode.InodeRootUserID, inode.InodeGroupID(0))
+	record.InodeNumber = uint64(entry.InodeNumber)
+	record.Path = "/" + trashUserDirPath(entry.UserID) + "/" + entry.TrashID
+
+	audit.Log(record, err)
+}
+
+// auditSetstat records a Setstat() of inodeNumber if (and only if) it changes Mode, UserID, and/or GroupID.
+func (mS *mountStruct) auditSetstat(userID inode.InodeUserID, groupID inode.InodeGroupID, inodeNumber inode.InodeNumber, stat Stat, err error) {
+	var (
+		attrs map[string]string
+	)
+
+	if !audit.Enabled() {
+		return
+	}
+
+	for _, statKey := range []StatKey{StatMode, StatUserID, StatGroupID} {
+		value, ok := stat[statKey]
+		if !ok {
+			continue
+		}
+		if nil == attrs {
+			attrs = make(map[string]string)
+		}
+		switch statKey {
+		case StatMode:
+			attrs["Mode"] = fmt.Sprintf("0%o", value)
+		case StatUserID:
+			attrs["UserID"] = fmt.Sprintf("%d", value)
+		case StatGroupID:
+			attrs["GroupID"] = fmt.Sprintf("%d", value)
+		}
+	}
+
+	if nil == attrs {
+		return
+	}
+
+	mS.auditInode(audit.OpSetstat, userID, groupID, inodeNumber, attrs, err)
+}
@@ -0,0 +1,296 @@
+package fs
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/audit"
+	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/transitions"
+)
+
+func TestAudit(t *testing.T) {
+	var (
+		dirName     string             = "audit_test"
+		testGroupID inode.InodeGroupID = inode.InodeGroupID(1000)
+		testUserID  inode.InodeUserID  = inode.InodeUserID(1000)
+	)
+
+	testSetup(t, false)
+
+	testAuditDir, err := ioutil.TempDir("", "fs_audit_test")
+	if nil != err {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(testAuditDir)
+
+	logFilePath := filepath.Join(testAuditDir, "audit.log")
+
+	err = testConfMap.UpdateFromStrings([]string{"Audit.Enabled=true", "Audit.LogFilePath=" + logFilePath})
+	if nil != err {
+		t.Fatalf("testConfMap.UpdateFromStrings() failed: %v", err)
+	}
+	err = transitions.Signaled(testConfMap)
+	if nil != err {
+		t.Fatalf("transitions.Signaled() failed: %v", err)
+	}
+
+	testMountStruct.SetClientAddr("127.0.0.1:54321")
+
+	dirInodeNumber, err := testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.RootDirInodeNumber, dirName, inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+
+	fileInodeNumber, err := testMountStruct.Create(testUserID, testGroupID, nil, dirInodeNumber, "file", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+
+	err = testMountStruct.Setstat(testUserID, testGroupID, nil, fileInodeNumber, Stat{StatMode: uint64(0600)})
+	if nil != err {
+		t.Fatalf("Setstat() returned error: %v", err)
+	}
+
+	err = testMountStruct.Setstat(testUserID, testGroupID, nil, fileInodeNumber, Stat{StatMTime: uint64(1)})
+	if nil != err {
+		t.Fatalf("Setstat() of MTime returned error: %v", err)
+	}
+
+	err = testMountStruct.SetXAttr(testUserID, testGroupID, nil, fileInodeNumber, "user.test", []byte("value"), 0)
+	if nil != err {
+		t.Fatalf("SetXAttr() returned error: %v", err)
+	}
+
+	err = testMountStruct.Rename(testUserID, testGroupID, nil, dirInodeNumber, "file", dirInodeNumber, "renamed", 0)
+	if nil != err {
+		t.Fatalf("Rename() returned error: %v", err)
+	}
+
+	err = testMountStruct.Unlink(testUserID, testGroupID, nil, dirInodeNumber, "missing")
+	if nil == err {
+		t.Fatalf("Unlink() of missing file should have failed")
+	}
+
+	err = testMountStruct.Unlink(testUserID, testGroupID, nil, dirInodeNumber, "renamed")
+	if nil != err {
+		t.Fatalf("Unlink() returned error: %v", err)
+	}
+
+	err = testMountStruct.Rmdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.RootDirInodeNumber, dirName)
+	if nil != err {
+		t.Fatalf("Rmdir() returned error: %v", err)
+	}
+
+	// Disabling auditing closes LogFilePath... and stops further records
+
+	err = testConfMap.UpdateFromStrings([]string{"Audit.Enabled=false"})
+	if nil != err {
+		t.Fatalf("testConfMap.UpdateFromStrings() failed: %v", err)
+	}
+	err = transitions.Signaled(testConfMap)
+	if nil != err {
+		t.Fatalf("transitions.Signaled() failed: %v", err)
+	}
+
+	_, err = testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.RootDirInodeNumber, dirName, inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+
+	buf, err := ioutil.ReadFile(logFilePath)
+	if nil != err {
+		t.Fatalf("ioutil.ReadFile() failed: %v", err)
+	}
+
+	_, _, err = audit.Verify(bytes.NewReader(buf), 0, "")
+	if nil != err {
+		t.Fatalf("audit.Verify() failed: %v", err)
+	}
+
+	expectedRecords := []audit.Record{
+		{Op: audit.OpMkdir, DirInodeNumber: uint64(inode.RootDirInodeNumber), Basename: dirName, InodeNumber: uint64(dirInodeNumber), Result: audit.ResultOK},
+		{Op: audit.OpCreate, UserID: uint32(testUserID), GroupID: uint32(testGroupID), DirInodeNumber: uint64(dirInodeNumber), Basename: "file", InodeNumber: uint64(fileInodeNumber), Result: audit.ResultOK},
+		{Op: audit.OpSetstat, UserID: uint32(testUserID), GroupID: uint32(testGroupID), InodeNumber: uint64(fileInodeNumber), Result: audit.ResultOK},
+		{Op: audit.OpSetXAttr, UserID: uint32(testUserID), GroupID: uint32(testGroupID), InodeNumber: uint64(fileInodeNumber), Result: audit.ResultOK},
+		{Op: audit.OpRename, UserID: uint32(testUserID), GroupID: uint32(testGroupID), DirInodeNumber: uint64(dirInodeNumber), Basename: "file", NewDirInodeNumber: uint64(dirInodeNumber), NewBasename: "renamed", Result: audit.ResultOK},
+		{Op: audit.OpUnlink, UserID: uint32(testUserID), GroupID: uint32(testGroupID), DirInodeNumber: uint64(dirInodeNumber), Basename: "missing"},
+		{Op: audit.OpUnlink, UserID: uint32(testUserID), GroupID: uint32(testGroupID), DirInodeNumber: uint64(dirInodeNumber), Basename: "renamed", Result: audit.ResultOK},
+		{Op: audit.OpRmdir, DirInodeNumber: uint64(inode.RootDirInodeNumber), Basename: dirName, Result: audit.ResultOK},
+	}
+
+	lines := bytes.Split(bytes.TrimRight(buf, "\n"), []byte("\n"))
+	if len(expectedRecords) != len(lines) {
+		t.Fatalf("Expected %v audit records... got %v:\n%s", len(expectedRecords), len(lines), buf)
+	}
+
+	for i, line := range lines {
+		record := audit.Record{}
+		err = json.Unmarshal(line, &record)
+		if nil != err {
+			t.Fatalf("json.Unmarshal() of audit record %v failed: %v", i, err)
+		}
+		expected := &expectedRecords[i]
+		if (expected.Op != record.Op) || (expected.UserID != record.UserID) || (expected.GroupID != record.GroupID) || (expected.DirInodeNumber != record.DirInodeNumber) || (expected.Basename != record.Basename) || (expected.NewDirInodeNumber != record.NewDirInodeNumber) || (expected.NewBasename != record.NewBasename) || (expected.Path != record.Path) || (expected.NewPath != record.NewPath) || (expected.InodeNumber != record.InodeNumber) {
+			t.Fatalf("Audit record %v unexpected: %s", i, line)
+		}
+		if ("" == expected.Result) == (audit.ResultOK == record.Result) {
+			t.Fatalf("Audit record %v has unexpected Result: %s", i, line)
+		}
+		if (testMountStruct.volStruct.volumeName != record.Volume) || ("127.0.0.1:54321" != record.ClientAddr) {
+			t.Fatalf("Audit record %v has unexpected Volume or ClientAddr: %s", i, line)
+		}
+	}
+
+	setstatRecord := audit.Record{}
+	_ = json.Unmarshal(lines[2], &setstatRecord)
+	if "0600" != setstatRecord.Attrs["Mode"] {
+		t.Fatalf("Setstat() audit record should have recorded Mode 0600: %s", lines[2])
+	}
+
+	testTeardown(t)
+}
+
+func TestAuditMaintenance(t *testing.T) {
+	var (
+		containerName string = "audit_maintenance_test"
+	)
+
+	testSetup(t, false)
+
+	testAuditDir, err := ioutil.TempDir("", "fs_audit_test")
+	if nil != err {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(testAuditDir)
+
+	logFilePath := filepath.Join(testAuditDir, "audit.log")
+
+	containerInodeNumber, err := testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.RootDirInodeNumber, containerName, inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+
+	createObject := func(basename string) (fileInodeNumber inode.InodeNumber) {
+		fileInodeNumber, err = testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, basename, inode.PosixModePerm)
+		if nil != err {
+			t.Fatalf("Create() returned error: %v", err)
+		}
+		_, err = testMountStruct.Write(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, 0, []byte(basename), nil)
+		if nil != err {
+			t.Fatalf("Write() returned error: %v", err)
+		}
+		return
+	}
+
+	// Prepare a prior version to restore, an expired object to reap, and a trashed file to purge
+
+	err = testMountStruct.MiddlewareSetVersioning(containerName, VersioningModeHistory, 0)
+	if nil != err {
+		t.Fatalf("MiddlewareSetVersioning() returned error: %v", err)
+	}
+	versionedInodeNumber := createObject("versioned")
+	err = testMountStruct.MiddlewareDelete(containerName, "versioned")
+	if nil != err {
+		t.Fatalf("MiddlewareDelete() returned error: %v", err)
+	}
+	versions, err := testMountStruct.MiddlewareListVersions(containerName, "versioned")
+	if (nil != err) || (1 != len(versions)) {
+		t.Fatalf("MiddlewareListVersions() returned %v versions (err: %v)... expected 1", len(versions), err)
+	}
+	err = testMountStruct.MiddlewareSetVersioning(containerName, VersioningModeNone, 0)
+	if nil != err {
+		t.Fatalf("MiddlewareSetVersioning() returned error: %v", err)
+	}
+
+	expiredInodeNumber := createObject("expired")
+	err = testMountStruct.MiddlewareSetExpiration(containerName, "expired", uint64(time.Now().Unix())-1)
+	if nil != err {
+		t.Fatalf("MiddlewareSetExpiration() returned error: %v", err)
+	}
+
+	testMountStruct.volStruct.trashEnabled = true
+	trashedInodeNumber := createObject("trashed")
+	err = testMountStruct.Unlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "trashed")
+	if nil != err {
+		t.Fatalf("Unlink() returned error: %v", err)
+	}
+	entries, err := testMountStruct.TrashList(inode.InodeRootUserID, inode.InodeGroupID(0), nil)
+	if (nil != err) || (1 != len(entries)) {
+		t.Fatalf("TrashList() returned %v entries (err: %v)... expected 1", len(entries), err)
+	}
+
+	// Now audit the restore, reap, and purge
+
+	err = testConfMap.UpdateFromStrings([]string{"Audit.Enabled=true", "Audit.LogFilePath=" + logFilePath})
+	if nil != err {
+		t.Fatalf("testConfMap.UpdateFromStrings() failed: %v", err)
+	}
+	err = transitions.Signaled(testConfMap)
+	if nil != err {
+		t.Fatalf("transitions.Signaled() failed: %v", err)
+	}
+
+	err = testMountStruct.MiddlewareRestoreVersion(containerName, "versioned", versions[0].VersionID)
+	if nil != err {
+		t.Fatalf("MiddlewareRestoreVersion() returned error: %v", err)
+	}
+
+	if 1 != testMountStruct.volStruct.reapExpiredObjects() {
+		t.Fatalf("reapExpiredObjects() should have reaped the expired object")
+	}
+
+	testMountStruct.volStruct.trashRetention = time.Nanosecond
+	if 1 != testMountStruct.volStruct.purgeTrash() {
+		t.Fatalf("purgeTrash() should have purged the trashed file")
+	}
+	testMountStruct.volStruct.trashEnabled = false
+	testMountStruct.volStruct.trashRetention = time.Duration(0)
+
+	err = testConfMap.UpdateFromStrings([]string{"Audit.Enabled=false"})
+	if nil != err {
+		t.Fatalf("testConfMap.UpdateFromStrings() failed: %v", err)
+	}
+	err = transitions.Signaled(testConfMap)
+	if nil != err {
+		t.Fatalf("transitions.Signaled() failed: %v", err)
+	}
+
+	buf, err := ioutil.ReadFile(logFilePath)
+	if nil != err {
+		t.Fatalf("ioutil.ReadFile() failed: %v", err)
+	}
+
+	expectedRecords := []audit.Record{
+		{Op: audit.OpMiddlewareRestoreVersion, InodeNumber: uint64(versionedInodeNumber), Path: "/" + VersionsDirName + "/" + containerName + "/versioned/" + versions[0].VersionID, NewPath: "/" + containerName + "/versioned"},
+		{Op: audit.OpExpirationReap, InodeNumber: uint64(expiredInodeNumber), DirInodeNumber: uint64(containerInodeNumber), Basename: "expired"},
+		{Op: audit.OpTrashPurge, InodeNumber: uint64(trashedInodeNumber), Path: "/" + trashUserDirPath(inode.InodeRootUserID) + "/" + entries[0].TrashID},
+	}
+
+	lines := bytes.Split(bytes.TrimRight(buf, "\n"), []byte("\n"))
+	if len(expectedRecords) != len(lines) {
+		t.Fatalf("Expected %v audit records... got %v:\n%s", len(expectedRecords), len(lines), buf)
+	}
+
+	for i, line := range lines {
+		record := audit.Record{}
+		err = json.Unmarshal(line, &record)
+		if nil != err {
+			t.Fatalf("json.Unmarshal() of audit record %v failed: %v", i, err)
+		}
+		expected := &expectedRecords[i]
+		if (expected.Op != record.Op) || (expected.InodeNumber != record.InodeNumber) || (expected.DirInodeNumber != record.DirInodeNumber) || (expected.Basename != record.Basename) || (expected.Path != record.Path) || (expected.NewPath != record.NewPath) || (audit.ResultOK != record.Result) {
+			t.Fatalf("Audit record %v unexpected: %s", i, line)
+		}
+		if uint32(inode.InodeRootUserID) != record.UserID {
+			t.Fatalf("Audit record %v should have been made as the root user: %s", i, line)
+		}
+	}
+
+	testTeardown(t)
+}
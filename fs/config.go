@@ -28,9 +28,10 @@ type inFlightFileInodeDataStruct struct {
 const inFlightFileInodeDataControlBuffering = 100
 
 type mountStruct struct {
-	id        MountID
-	options   MountOptions
-	volStruct *volumeStruct
+	id         MountID
+	options    MountOptions
+	volStruct  *volumeStruct
+	clientAddr string // Reported in audit records (if known)
 }
 
 type volumeStruct struct {
@@ -48,6 +49,12 @@ type volumeStruct struct {
 	jobRWMutex               trackedlock.RWMutex
 	inodeVolumeHandle        inode.VolumeHandle
 	headhunterVolumeHandle   headhunter.VolumeHandle
+	notify                   notifyVolumeStruct
+	expirationReaper         expirationReaperStruct
+	eTagUpdater              eTagUpdaterStruct
+	trashEnabled             bool
+	trashRetention           time.Duration // 0 means trashed inodes are never purged
+	trashPurger              trashPurgerStruct
 }
 
 type globalsStruct struct {
@@ -59,9 +66,16 @@ type globalsStruct struct {
 	tryLockBackoffMin         time.Duration
 	tryLockBackoffMax         time.Duration
 	symlinkMax                uint16
+	notifyQueueDepth          uint64
+	notifyIdleTimeout         time.Duration // 0 means subscriptions never expire
+	expirationReapInterval    time.Duration
+	trashPurgeInterval        time.Duration
 
 	AccessUsec         bucketstats.BucketLog2Round
+	CopyFileRangeUsec  bucketstats.BucketLog2Round
+	CopyFileRangeBytes bucketstats.BucketLog2Round
 	CreateUsec         bucketstats.BucketLog2Round
+	FallocateUsec      bucketstats.BucketLog2Round
 	FlushUsec          bucketstats.BucketLog2Round
 	FlockGetUsec       bucketstats.BucketLog2Round
 	FlockLockUsec      bucketstats.BucketLog2Round
@@ -90,6 +104,8 @@ type globalsStruct struct {
 	ReadsymlinkUsec    bucketstats.BucketLog2Round
 	ResizeUsec         bucketstats.BucketLog2Round
 	RmdirUsec          bucketstats.BucketLog2Round
+	SeekDataUsec       bucketstats.BucketLog2Round
+	SeekHoleUsec       bucketstats.BucketLog2Round
 	SetstatUsec        bucketstats.BucketLog2Round
 	SetXAttrUsec       bucketstats.BucketLog2Round
 	StatVfsUsec        bucketstats.BucketLog2Round
@@ -99,7 +115,9 @@ type globalsStruct struct {
 	WriteUsec          bucketstats.BucketLog2Round
 	WriteBytes         bucketstats.BucketLog2Round
 
+	CopyFileRangeErrors  bucketstats.Total
 	CreateErrors         bucketstats.Total
+	FallocateErrors      bucketstats.Total
 	FetchReadPlanErrors  bucketstats.Total
 	FlushErrors          bucketstats.Total
 	FlockOtherErrors     bucketstats.Total
@@ -127,6 +145,8 @@ type globalsStruct struct {
 	ReadsymlinkErrors    bucketstats.Total
 	ResizeErrors         bucketstats.Total
 	RmdirErrors          bucketstats.Total
+	SeekDataErrors       bucketstats.Total
+	SeekHoleErrors       bucketstats.Total
 	SetstatErrors        bucketstats.Total
 	SetXAttrErrors       bucketstats.Total
 	StatVfsErrors        bucketstats.Total
@@ -138,12 +158,15 @@ type globalsStruct struct {
 	CallInodeToProvisionObjectUsec bucketstats.BucketLog2Round
 	MiddlewareCoalesceUsec         bucketstats.BucketLog2Round
 	MiddlewareCoalesceBytes        bucketstats.BucketLog2Round
+	MiddlewareCopyUsec             bucketstats.BucketLog2Round
+	MiddlewareCopyBytes            bucketstats.BucketLog2Round
 	MiddlewareDeleteUsec           bucketstats.BucketLog2Round
 	MiddlewareGetAccountUsec       bucketstats.BucketLog2Round
 	MiddlewareGetContainerUsec     bucketstats.BucketLog2Round
 	MiddlewareGetObjectUsec        bucketstats.BucketLog2Round
 	MiddlewareGetObjectBytes       bucketstats.BucketLog2Round
 	MiddlewareHeadResponseUsec     bucketstats.BucketLog2Round
+	MiddlewareListVersionsUsec     bucketstats.BucketLog2Round
 	MiddlewareMkdirUsec            bucketstats.BucketLog2Round
 	MiddlewarePostUsec             bucketstats.BucketLog2Round
 	MiddlewarePostBytes            bucketstats.BucketLog2Round
@@ -151,18 +174,49 @@ type globalsStruct struct {
 	MiddlewarePutCompleteBytes     bucketstats.BucketLog2Round
 	MiddlewarePutContainerUsec     bucketstats.BucketLog2Round
 	MiddlewarePutContainerBytes    bucketstats.BucketLog2Round
+	MiddlewareRestoreVersionUsec   bucketstats.BucketLog2Round
+	MiddlewareSetExpirationUsec    bucketstats.BucketLog2Round
+	MiddlewareSetVersioningUsec    bucketstats.BucketLog2Round
 
 	CallInodeToProvisionObjectErrors bucketstats.Total
 	MiddlewareCoalesceErrors         bucketstats.Total
+	MiddlewareCopyErrors             bucketstats.Total
 	MiddlewareDeleteErrors           bucketstats.Total
 	MiddlewareGetAccountErrors       bucketstats.Total
 	MiddlewareGetContainerErrors     bucketstats.Total
 	MiddlewareGetObjectErrors        bucketstats.Total
 	MiddlewareHeadResponseErrors     bucketstats.Total
+	MiddlewareListVersionsErrors     bucketstats.Total
 	MiddlewareMkdirErrors            bucketstats.Total
 	MiddlewarePostErrors             bucketstats.Total
 	MiddlewarePutCompleteErrors      bucketstats.Total
 	MiddlewarePutContainerErrors     bucketstats.Total
+	MiddlewareRestoreVersionErrors   bucketstats.Total
+	MiddlewareSetExpirationErrors    bucketstats.Total
+	MiddlewareSetVersioningErrors    bucketstats.Total
+
+	ExpirationReapUsec   bucketstats.BucketLog2Round
+	ExpiredObjectsReaped bucketstats.Total
+	ExpirationReapErrors bucketstats.Total
+
+	TrashListUsec      bucketstats.BucketLog2Round
+	TrashRestoreUsec   bucketstats.BucketLog2Round
+	TrashPurgeUsec     bucketstats.BucketLog2Round
+	TrashedInodes      bucketstats.Total
+	TrashPurgedInodes  bucketstats.Total
+	TrashListErrors    bucketstats.Total
+	TrashRestoreErrors bucketstats.Total
+	TrashPurgeErrors   bucketstats.Total
+
+	NotifyFetchUsec            bucketstats.BucketLog2Round
+	NotifyFetchEvents          bucketstats.BucketLog2Round
+	NotifySubscribeUsec        bucketstats.BucketLog2Round
+	NotifyUnsubscribeUsec      bucketstats.BucketLog2Round
+	NotifyFetchErrors          bucketstats.Total
+	NotifySubscribeErrors      bucketstats.Total
+	NotifyUnsubscribeErrors    bucketstats.Total
+	NotifyEventsDropped        bucketstats.Total
+	NotifySubscriptionsExpired bucketstats.Total
 
 	MountUsec                               bucketstats.BucketLog2Round
 	MountErrors                             bucketstats.BucketLog2Round
@@ -202,6 +256,22 @@ func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
 	if nil != err {
 		globals.symlinkMax = 32 // TODO: Eventually, just return
 	}
+	globals.notifyQueueDepth, err = confMap.FetchOptionValueUint64("FSGlobals", "NotifyQueueDepth")
+	if nil != err {
+		globals.notifyQueueDepth = DefaultNotifyQueueDepth
+	}
+	globals.notifyIdleTimeout, err = confMap.FetchOptionValueDuration("FSGlobals", "NotifySubscriptionIdleTimeout")
+	if nil != err {
+		globals.notifyIdleTimeout = DefaultNotifySubscriptionIdleTimeout
+	}
+	globals.expirationReapInterval, err = confMap.FetchOptionValueDuration("FSGlobals", "ExpirationReapInterval")
+	if nil != err {
+		globals.expirationReapInterval = DefaultExpirationReapInterval
+	}
+	globals.trashPurgeInterval, err = confMap.FetchOptionValueDuration("FSGlobals", "TrashPurgeInterval")
+	if nil != err {
+		globals.trashPurgeInterval = DefaultTrashPurgeInterval
+	}
 
 	err = nil
 	return
@@ -240,6 +310,8 @@ func (dummy *globalsStruct) ServeVolume(confMap conf.ConfMap, volumeName string)
 		mountList:                make([]MountID, 0),
 	}
 
+	volume.notifyInit()
+
 	volumeSectionName = "Volume:" + volumeName
 
 	replayLogFileName, err = confMap.FetchOptionValueString(volumeSectionName, "ReplayLogFileName")
@@ -272,6 +344,14 @@ func (dummy *globalsStruct) ServeVolume(confMap conf.ConfMap, volumeName string)
 	if nil != err {
 		volume.reportedNumInodes = DefaultReportedNumInodes // TODO: Eventually, just return
 	}
+	volume.trashEnabled, err = confMap.FetchOptionValueBool(volumeSectionName, "TrashEnabled")
+	if nil != err {
+		volume.trashEnabled = false // TODO: Eventually, just return
+	}
+	volume.trashRetention, err = confMap.FetchOptionValueDuration(volumeSectionName, "TrashRetention")
+	if nil != err {
+		volume.trashRetention = time.Duration(0) // TODO: Eventually, just return
+	}
 
 	volume.inodeVolumeHandle, err = inode.FetchVolumeHandle(volumeName)
 	if nil != err {
@@ -284,6 +364,10 @@ func (dummy *globalsStruct) ServeVolume(confMap conf.ConfMap, volumeName string)
 
 	globals.volumeMap[volumeName] = volume
 
+	volume.expirationReaperStart()
+	volume.trashPurgerStart()
+	volume.eTagUpdaterStart()
+
 	return nil
 }
 
@@ -307,6 +391,12 @@ func (dummy *globalsStruct) UnserveVolume(confMap conf.ConfMap, volumeName strin
 
 	volume.untrackInFlightFileInodeDataAll()
 
+	volume.notifyUnsubscribeAll()
+
+	volume.expirationReaperStop()
+	volume.trashPurgerStop()
+	volume.eTagUpdaterStop()
+
 	delete(globals.volumeMap, volumeName)
 
 	err = nil
@@ -0,0 +1,132 @@
+package fs
+
+import (
+	"sync"
+
+	"github.com/swiftstack/ProxyFS/dlm"
+	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/logger"
+)
+
+// Computing a FileInode's ETag (for volumes configured with MaintainETag) requires reading the entire
+// file. Rather than doing so in line with each Flush(), Flush() merely queues the FileInode for the
+// volume's ETag updater. The updater computes each queued FileInode's ETag holding only a shared lock
+// on it (so that readers are not blocked) and then briefly takes an exclusive lock to record it. Should
+// the FileInode have been modified in the meantime, inode.SetETag() discards the stale ETag (and the
+// Flush() following that modification will have queued the FileInode again). Until its ETag has been
+// recorded, a FileInode simply has none (as is the case for FileInodes that have never been flushed).
+
+type eTagUpdaterStruct struct {
+	sync.Mutex
+	pendingMap map[inode.InodeNumber]struct{} // FileInodes awaiting an ETag update
+	kickChan   chan struct{}                  // signaled (without blocking) as FileInodes are queued
+	stopChan   chan struct{}
+	doneWG     sync.WaitGroup
+}
+
+func (vS *volumeStruct) eTagUpdaterStart() {
+	vS.eTagUpdater.pendingMap = make(map[inode.InodeNumber]struct{})
+	vS.eTagUpdater.kickChan = make(chan struct{}, 1)
+	vS.eTagUpdater.stopChan = make(chan struct{})
+	vS.eTagUpdater.doneWG.Add(1)
+
+	go vS.eTagUpdaterDaemon()
+}
+
+func (vS *volumeStruct) eTagUpdaterStop() {
+	if nil == vS.eTagUpdater.stopChan {
+		return
+	}
+
+	close(vS.eTagUpdater.stopChan)
+	vS.eTagUpdater.doneWG.Wait()
+
+	vS.eTagUpdater.stopChan = nil
+}
+
+// queueETagUpdate schedules the (re)computation of a FileInode's ETag.
+func (vS *volumeStruct) queueETagUpdate(inodeNumber inode.InodeNumber) {
+	vS.eTagUpdater.Lock()
+	vS.eTagUpdater.pendingMap[inodeNumber] = struct{}{}
+	vS.eTagUpdater.Unlock()
+
+	select {
+	case vS.eTagUpdater.kickChan <- struct{}{}:
+	default:
+		// The daemon has yet to consume a prior signal... so it will see this FileInode as well
+	}
+}
+
+func (vS *volumeStruct) eTagUpdaterDaemon() {
+	var (
+		inodeNumber inode.InodeNumber
+		pendingMap  map[inode.InodeNumber]struct{}
+	)
+
+	defer vS.eTagUpdater.doneWG.Done()
+
+	for {
+		select {
+		case <-vS.eTagUpdater.stopChan:
+			return
+		case <-vS.eTagUpdater.kickChan:
+			vS.eTagUpdater.Lock()
+			pendingMap = vS.eTagUpdater.pendingMap
+			vS.eTagUpdater.pendingMap = make(map[inode.InodeNumber]struct{})
+			vS.eTagUpdater.Unlock()
+
+			for inodeNumber = range pendingMap {
+				select {
+				case <-vS.eTagUpdater.stopChan:
+					return
+				default:
+					vS.updateETag(inodeNumber)
+				}
+			}
+		}
+	}
+}
+
+// updateETag computes and records the ETag of a FileInode. Failing to do so merely leaves it absent.
+func (vS *volumeStruct) updateETag(inodeNumber inode.InodeNumber) {
+	var (
+		eTag           string
+		eTagGeneration uint64
+		err            error
+		inodeLock      *dlm.RWLockStruct
+	)
+
+	vS.jobRWMutex.RLock()
+	defer vS.jobRWMutex.RUnlock()
+
+	inodeLock, err = vS.inodeVolumeHandle.InitInodeLock(inodeNumber, nil)
+	if nil != err {
+		return
+	}
+
+	err = inodeLock.ReadLock()
+	if nil != err {
+		return
+	}
+	eTag, eTagGeneration, err = vS.inodeVolumeHandle.ComputeETag(inodeNumber)
+	_ = inodeLock.Unlock()
+	if nil != err {
+		logger.WarnfWithError(err, "fs.updateETag() of volume %s unable to compute ETag of inode 0x%016X", vS.volumeName, inodeNumber)
+		return
+	}
+
+	if "" == eTag {
+		// Not maintained (or already up to date)
+		return
+	}
+
+	err = inodeLock.WriteLock()
+	if nil != err {
+		return
+	}
+	err = vS.inodeVolumeHandle.SetETag(inodeNumber, eTag, eTagGeneration)
+	_ = inodeLock.Unlock()
+	if nil != err {
+		logger.WarnfWithError(err, "fs.updateETag() of volume %s unable to record ETag of inode 0x%016X", vS.volumeName, inodeNumber)
+	}
+}
@@ -0,0 +1,88 @@
+package fs
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/inode"
+)
+
+func TestETag(t *testing.T) {
+	var (
+		rootDirInodeNumber inode.InodeNumber = inode.RootDirInodeNumber
+	)
+
+	testSetup(t, false)
+
+	fileInodeNumber, err := testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, "etag_test", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+
+	waitForETag := func(expectedBuf []byte) {
+		expectedMD5 := md5.Sum(expectedBuf)
+		expectedETag := hex.EncodeToString(expectedMD5[:])
+		for i := 0; i < 100; i++ {
+			eTag := fetchETag(testMountStruct.volStruct.inodeVolumeHandle, fileInodeNumber)
+			if expectedETag == eTag {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("ETag never became %s", expectedETag)
+	}
+
+	fileBuf := []byte("some file content")
+
+	_, err = testMountStruct.Write(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, 0, fileBuf, nil)
+	if nil != err {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	err = testMountStruct.Flush(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Flush() returned error: %v", err)
+	}
+
+	waitForETag(fileBuf)
+
+	// The ETag (like other streams ProxyFS maintains itself) may not be forged or removed via XAttrs
+
+	err = testMountStruct.SetXAttr(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, inode.ETagStreamName, []byte("forged"), 0)
+	if !blunder.Is(err, blunder.NotPermError) {
+		t.Fatalf("SetXAttr(inode.ETagStreamName) should have failed with NotPermError... got: %v", err)
+	}
+	err = testMountStruct.RemoveXAttr(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, inode.ETagStreamName)
+	if !blunder.Is(err, blunder.NotPermError) {
+		t.Fatalf("RemoveXAttr(inode.ETagStreamName) should have failed with NotPermError... got: %v", err)
+	}
+	err = testMountStruct.SetXAttr(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, ExpirationStream, []byte("1"), 0)
+	if !blunder.Is(err, blunder.NotPermError) {
+		t.Fatalf("SetXAttr(ExpirationStream) should have failed with NotPermError... got: %v", err)
+	}
+
+	waitForETag(fileBuf)
+
+	// Modifying the content discards the ETag until the next Flush()
+
+	_, err = testMountStruct.Write(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, 0, []byte("S"), nil)
+	if nil != err {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	fileBuf[0] = 'S'
+
+	if "" != fetchETag(testMountStruct.volStruct.inodeVolumeHandle, fileInodeNumber) {
+		t.Fatalf("Write() should have discarded the ETag")
+	}
+
+	err = testMountStruct.Flush(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Flush() returned error: %v", err)
+	}
+
+	waitForETag(fileBuf)
+
+	testTeardown(t)
+}
@@ -0,0 +1,430 @@
+package fs
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/audit"
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/dlm"
+	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+// Swift's object expirer never sees objects inside a ProxyFS volume, so expiration (X-Delete-At and
+// X-Delete-After) is handled here instead. MiddlewareSetExpiration() records an object's expiration
+// time both in its FileInode's ExpirationStream (so that MiddlewareHeadResponse() and
+// MiddlewareGetObject() may treat it as missing the moment it expires) and in the volume's ExpiryRec
+// B+Tree keyed by (ExpireAt, InodeNumber) (so that each pass of the expiration reaper need only visit
+// those ExpiryRec's that are due). Each ExpiryRec records the directory entry at which its object
+// lives, kept current by expirationMovedWhileLocked() as the object is renamed. An object that is no
+// longer found there (e.g. its original name was unlinked leaving only a hard link) has its
+// ExpirationStream removed rather than being left missing (yet unreaped) forever. ExpiryRec's that
+// no longer match their FileInode's ExpirationStream are simply dropped.
+
+type expiryRecStruct struct {
+	DirInodeNumber uint64
+	Basename       string
+}
+
+type expirationReaperStruct struct {
+	stopChan chan struct{}
+	doneWG   sync.WaitGroup
+}
+
+func fetchExpireAt(inodeVolumeHandle inode.VolumeHandle, inodeNumber inode.InodeNumber) (expireAt uint64) {
+	expireAtBuf, err := inodeVolumeHandle.GetStream(inodeNumber, ExpirationStream)
+	if nil != err {
+		expireAt = 0
+		return
+	}
+	expireAt, err = strconv.ParseUint(string(expireAtBuf), 10, 64)
+	if nil != err {
+		expireAt = 0
+	}
+	return
+}
+
+func isExpired(expireAt uint64) (expired bool) {
+	expired = (0 != expireAt) && (expireAt <= uint64(time.Now().Unix()))
+	return
+}
+
+func (mS *mountStruct) MiddlewareSetExpiration(vContainerName string, vObjectPath string, expireAt uint64) (err error) {
+	var (
+		dirEntryBasename  string
+		dirInodeNumber    inode.InodeNumber
+		fileInodeNumber   inode.InodeNumber
+		fileInodeType     inode.InodeType
+		heldLocks         *heldLocksStruct
+		inodeVolumeHandle inode.VolumeHandle
+		oldExpireAt       uint64
+		path              string
+		restartBackoff    time.Duration
+		retryRequired     bool
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.MiddlewareSetExpirationUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.MiddlewareSetExpirationErrors.Add(1)
+		}
+	}()
+
+	mS.volStruct.jobRWMutex.RLock()
+	defer mS.volStruct.jobRWMutex.RUnlock()
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	path = vContainerName + "/" + vObjectPath
+
+	// Retry until done or failure (starting with ZERO backoff)
+
+	restartBackoff = time.Duration(0)
+
+Restart:
+
+	// Perform backoff and update for each restart (starting with ZERO backoff of course)
+
+	restartBackoff, err = utils.PerformDelayAndComputeNextDelay(restartBackoff, globals.tryLockBackoffMin, globals.tryLockBackoffMax)
+	if nil != err {
+		logger.Fatalf("MiddlewareSetExpiration(): failed in restartBackoff: %v", err)
+	}
+
+	// Construct fresh heldLocks for this restart
+
+	heldLocks = newHeldLocks()
+
+	dirInodeNumber, fileInodeNumber, dirEntryBasename, fileInodeType, retryRequired, err =
+		mS.resolvePath(
+			inode.RootDirInodeNumber,
+			path,
+			heldLocks,
+			resolvePathFollowDirSymlinks|
+				resolvePathRequireExclusiveLockOnDirEntryInode)
+
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	defer heldLocks.free()
+
+	if inode.FileType != fileInodeType {
+		err = blunder.NewError(blunder.NotFileError, "MiddlewareSetExpiration(): %s is not a file", path)
+		return
+	}
+
+	// Any prior ExpiryRec is keyed by the prior expiration time
+
+	oldExpireAt = fetchExpireAt(inodeVolumeHandle, fileInodeNumber)
+	if 0 != oldExpireAt {
+		_ = mS.volStruct.headhunterVolumeHandle.DeleteExpiryRec(oldExpireAt, uint64(fileInodeNumber))
+	}
+
+	if 0 == expireAt {
+		err = inodeVolumeHandle.DeleteStream(fileInodeNumber, ExpirationStream)
+		if blunder.Is(err, blunder.StreamNotFound) {
+			err = nil
+		}
+		return
+	}
+
+	err = inodeVolumeHandle.PutStream(fileInodeNumber, ExpirationStream, []byte(strconv.FormatUint(expireAt, 10)))
+	if nil != err {
+		return
+	}
+
+	err = mS.volStruct.putExpiryRec(expireAt, fileInodeNumber, dirInodeNumber, dirEntryBasename)
+
+	return
+}
+
+func (vS *volumeStruct) putExpiryRec(expireAt uint64, inodeNumber inode.InodeNumber, dirInodeNumber inode.InodeNumber, basename string) (err error) {
+	var (
+		expiryRecBuf []byte
+	)
+
+	expiryRecBuf, err = json.Marshal(&expiryRecStruct{DirInodeNumber: uint64(dirInodeNumber), Basename: basename})
+	if nil != err {
+		logger.Fatalf("fs.putExpiryRec(): failed to marshal ExpiryRec: %v", err)
+	}
+
+	err = vS.headhunterVolumeHandle.PutExpiryRec(expireAt, uint64(inodeNumber), expiryRecBuf)
+
+	return
+}
+
+// expirationMovedWhileLocked updates the ExpiryRec (if any) of inodeNumber to reflect that it is now found at
+// dirInodeNumber/basename. Callers must hold an exclusive lock on inodeNumber.
+func (vS *volumeStruct) expirationMovedWhileLocked(inodeNumber inode.InodeNumber, dirInodeNumber inode.InodeNumber, basename string) {
+	var (
+		err      error
+		expireAt uint64
+	)
+
+	expireAt = fetchExpireAt(vS.inodeVolumeHandle, inodeNumber)
+	if 0 == expireAt {
+		return
+	}
+
+	err = vS.putExpiryRec(expireAt, inodeNumber, dirInodeNumber, basename)
+	if nil != err {
+		logger.ErrorfWithError(err, "fs.expirationMovedWhileLocked() of volume %s failed to update ExpiryRec for inode 0x%016X", vS.volumeName, inodeNumber)
+	}
+}
+
+func (vS *volumeStruct) expirationReaperStart() {
+	if 0 == globals.expirationReapInterval {
+		vS.expirationReaper.stopChan = nil
+		return
+	}
+
+	vS.expirationReaper.stopChan = make(chan struct{})
+	vS.expirationReaper.doneWG.Add(1)
+
+	go vS.expirationReaperDaemon()
+}
+
+func (vS *volumeStruct) expirationReaperStop() {
+	if nil == vS.expirationReaper.stopChan {
+		return
+	}
+
+	close(vS.expirationReaper.stopChan)
+	vS.expirationReaper.doneWG.Wait()
+
+	vS.expirationReaper.stopChan = nil
+}
+
+func (vS *volumeStruct) expirationReaperDaemon() {
+	defer vS.expirationReaper.doneWG.Done()
+
+	for {
+		select {
+		case <-vS.expirationReaper.stopChan:
+			return
+		case <-time.After(globals.expirationReapInterval):
+			vS.reapExpiredObjects()
+		}
+	}
+}
+
+type dueExpiryRecStruct struct {
+	expireAt    uint64
+	inodeNumber inode.InodeNumber
+	expiryRec   *expiryRecStruct
+}
+
+// reapExpiredObjects performs a single pass of the expiration reaper, returning the number of
+// objects unlinked. As the ExpiryRec B+Tree is ordered by ExpireAt, the pass stops at the first
+// ExpiryRec not yet due.
+func (vS *volumeStruct) reapExpiredObjects() (reaped uint64) {
+	var (
+		dueExpiryRec  *dueExpiryRecStruct
+		dueExpiryRecs []*dueExpiryRecStruct
+		err           error
+		expireAt      uint64
+		expiryRecBuf  []byte
+		index         uint64
+		inodeNumber   uint64
+		ok            bool
+		wasReaped     bool
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.ExpirationReapUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		globals.ExpiredObjectsReaped.Add(reaped)
+	}()
+
+	// First, collect the ExpiryRec's that are due (the B+Tree is modified as they are reaped)
+
+	dueExpiryRecs = make([]*dueExpiryRecStruct, 0)
+
+	for index = 0; ; index++ {
+		expireAt, inodeNumber, expiryRecBuf, ok, err = vS.headhunterVolumeHandle.IndexedExpiryRec(index)
+		if nil != err {
+			logger.ErrorfWithError(err, "fs.reapExpiredObjects() of volume %s failed to index ExpiryRec B+Tree", vS.volumeName)
+			globals.ExpirationReapErrors.Add(1)
+			return
+		}
+		if !ok || !isExpired(expireAt) {
+			break
+		}
+
+		dueExpiryRec = &dueExpiryRecStruct{
+			expireAt:    expireAt,
+			inodeNumber: inode.InodeNumber(inodeNumber),
+			expiryRec:   &expiryRecStruct{},
+		}
+
+		err = json.Unmarshal(expiryRecBuf, dueExpiryRec.expiryRec)
+		if nil != err {
+			logger.ErrorfWithError(err, "fs.reapExpiredObjects() of volume %s found unparseable ExpiryRec for inode 0x%016X", vS.volumeName, inodeNumber)
+			dueExpiryRec.expiryRec = nil // Ensure object is located via its FileInode alone
+		}
+
+		dueExpiryRecs = append(dueExpiryRecs, dueExpiryRec)
+	}
+
+	// Now reap each of them
+
+	for _, dueExpiryRec = range dueExpiryRecs {
+		wasReaped, err = vS.reapExpiredObject(dueExpiryRec.expireAt, dueExpiryRec.inodeNumber, dueExpiryRec.expiryRec)
+		if nil != err {
+			logger.ErrorfWithError(err, "fs.reapExpiredObjects() of volume %s failed to reap inode 0x%016X", vS.volumeName, dueExpiryRec.inodeNumber)
+			globals.ExpirationReapErrors.Add(1)
+			continue
+		}
+		if wasReaped {
+			reaped++
+		}
+	}
+
+	return
+}
+
+func (vS *volumeStruct) reapExpiredObject(expireAt uint64, inodeNumber inode.InodeNumber, expiryRec *expiryRecStruct) (reaped bool, err error) {
+	var (
+		currentExpiryRec    *expiryRecStruct
+		dirEntryBasename    string
+		dirEntryInodeNumber inode.InodeNumber
+		dirInodeNumber      inode.InodeNumber
+		expiryRecBuf        []byte
+		heldLocks           *heldLocksStruct
+		inodeVolumeHandle   inode.VolumeHandle
+		mS                  *mountStruct
+		ok                  bool
+		restartBackoff      time.Duration
+		retryRequired       bool
+	)
+
+	// resolvePath() (as well as auditing) only needs a mountStruct for its volStruct
+
+	mS = &mountStruct{volStruct: vS}
+
+	defer func() {
+		if reaped || (nil != err) {
+			mS.auditDirEntry(audit.OpExpirationReap, inode.InodeRootUserID, inode.InodeGroupID(0), dirInodeNumber, dirEntryBasename, inodeNumber, nil, err)
+		}
+	}()
+
+	vS.jobRWMutex.RLock()
+	defer vS.jobRWMutex.RUnlock()
+
+	inodeVolumeHandle = vS.inodeVolumeHandle
+
+	// Retry until done or failure (starting with ZERO backoff)
+
+	restartBackoff = time.Duration(0)
+
+Restart:
+
+	// Perform backoff and update for each restart (starting with ZERO backoff of course)
+
+	restartBackoff, err = utils.PerformDelayAndComputeNextDelay(restartBackoff, globals.tryLockBackoffMin, globals.tryLockBackoffMax)
+	if nil != err {
+		logger.Fatalf("reapExpiredObject(): failed in restartBackoff: %v", err)
+	}
+
+	// Construct fresh heldLocks for this restart
+
+	heldLocks = newHeldLocks()
+
+	dirEntryInodeNumber = inode.InodeNumber(0)
+
+	if nil != expiryRec {
+		dirInodeNumber, dirEntryInodeNumber, dirEntryBasename, _, retryRequired, err =
+			mS.resolvePath(
+				inode.InodeNumber(expiryRec.DirInodeNumber),
+				expiryRec.Basename,
+				heldLocks,
+				resolvePathRequireExclusiveLockOnDirEntryInode|
+					resolvePathRequireExclusiveLockOnDirInode)
+
+		if nil == err {
+			if retryRequired {
+				heldLocks.free()
+				goto Restart
+			}
+		} else {
+			if !blunder.Is(err, blunder.NotFoundError) && !blunder.Is(err, blunder.NotDirError) {
+				heldLocks.free()
+				return
+			}
+			dirEntryInodeNumber = inode.InodeNumber(0)
+		}
+	}
+
+	if inodeNumber != dirEntryInodeNumber {
+		// The object has since been deleted, replaced, or moved... but the ExpiryRec's
+		// FileInode may still exist (with an updated expiration) and must be checked
+
+		retryRequired = heldLocks.attemptExclusiveLock(inodeVolumeHandle, dlm.GenerateCallerID(), inodeNumber)
+		if retryRequired {
+			heldLocks.free()
+			goto Restart
+		}
+	}
+
+	defer heldLocks.free()
+
+	expiryRecBuf, ok, err = vS.headhunterVolumeHandle.GetExpiryRec(expireAt, uint64(inodeNumber))
+	if (nil != err) || !ok {
+		// Already handled by MiddlewareSetExpiration() or a previous pass
+
+		return
+	}
+
+	// The object may have been moved (updating its ExpiryRec) before the above locks were obtained
+
+	currentExpiryRec = &expiryRecStruct{}
+	err = json.Unmarshal(expiryRecBuf, currentExpiryRec)
+	if (nil == err) && ((nil == expiryRec) || (*currentExpiryRec != *expiryRec)) {
+		expiryRec = currentExpiryRec
+		heldLocks.free()
+		goto Restart
+	}
+
+	if fetchExpireAt(inodeVolumeHandle, inodeNumber) != expireAt {
+		// Expiration was changed or cleared (or the FileInode is gone)... so this ExpiryRec is stale
+
+		err = vS.headhunterVolumeHandle.DeleteExpiryRec(expireAt, uint64(inodeNumber))
+		return
+	}
+
+	if inodeNumber == dirEntryInodeNumber {
+		err = mS.unlinkWhileLocked(dirInodeNumber, dirEntryBasename, inodeNumber)
+		if nil != err {
+			return
+		}
+
+		vS.notifyNamespaceEvent(NotifyUnlink, dirInodeNumber, dirEntryBasename, inodeNumber)
+
+		reaped = true
+	} else {
+		// The object can no longer be found where its ExpiryRec says it lives... rather than
+		// leave it inaccessible (yet unreaped) forever, make it visible again
+
+		logger.Warnf("fs.reapExpiredObject() of volume %s could not locate expired inode 0x%016X... removing its expiration", vS.volumeName, inodeNumber)
+
+		err = inodeVolumeHandle.DeleteStream(inodeNumber, ExpirationStream)
+		if nil != err {
+			return
+		}
+	}
+
+	err = vS.headhunterVolumeHandle.DeleteExpiryRec(expireAt, uint64(inodeNumber))
+
+	return
+}
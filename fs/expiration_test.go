@@ -0,0 +1,274 @@
+package fs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/inode"
+)
+
+func TestExpiration(t *testing.T) {
+	var (
+		containerName      string = "expiration_test"
+		futureExpireAt     uint64 = uint64(time.Now().Unix()) + 3600
+		pastExpireAt       uint64 = uint64(time.Now().Unix()) - 1
+		readRangeOut       []inode.ReadPlanStep
+		rootDirInodeNumber inode.InodeNumber = inode.RootDirInodeNumber
+	)
+
+	testSetup(t, false)
+
+	headhunterVolumeHandle := testMountStruct.volStruct.headhunterVolumeHandle
+
+	containerInodeNumber, err := testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, containerName, inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+
+	createObject := func(objectName string) (fileInodeNumber inode.InodeNumber) {
+		fileInodeNumber, err = testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, objectName, inode.PosixModePerm)
+		if nil != err {
+			t.Fatalf("Create(\"%s\") returned error: %v", objectName, err)
+		}
+		_, err = testMountStruct.Write(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, 0, []byte("expiring"), nil)
+		if nil != err {
+			t.Fatalf("Write(\"%s\") returned error: %v", objectName, err)
+		}
+		return
+	}
+
+	// An object expiring in the future is visible (and reports its expiration) but not reaped
+
+	objectInodeNumber := createObject("object")
+
+	err = testMountStruct.MiddlewareSetExpiration(containerName, "object", futureExpireAt)
+	if nil != err {
+		t.Fatalf("MiddlewareSetExpiration(future) returned error: %v", err)
+	}
+
+	headResponse, err := testMountStruct.MiddlewareHeadResponse(containerName + "/object")
+	if nil != err {
+		t.Fatalf("MiddlewareHeadResponse() of unexpired object returned error: %v", err)
+	}
+	if futureExpireAt != headResponse.ExpireAt {
+		t.Fatalf("MiddlewareHeadResponse() returned ExpireAt %v... expected %v", headResponse.ExpireAt, futureExpireAt)
+	}
+
+	if 0 != testMountStruct.volStruct.reapExpiredObjects() {
+		t.Fatalf("reapExpiredObjects() unexpectedly reaped an unexpired object")
+	}
+
+	// Once expired, the object is missing even before it is reaped
+
+	err = testMountStruct.MiddlewareSetExpiration(containerName, "object", pastExpireAt)
+	if nil != err {
+		t.Fatalf("MiddlewareSetExpiration(past) returned error: %v", err)
+	}
+
+	_, err = testMountStruct.MiddlewareHeadResponse(containerName + "/object")
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("MiddlewareHeadResponse() of expired object should have returned NotFoundError... got: %v", err)
+	}
+
+	_, _, _, _, _, _, _, _, err = testMountStruct.MiddlewareGetObject(containerName+"/object", []ReadRangeIn{}, &readRangeOut)
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("MiddlewareGetObject() of expired object should have returned NotFoundError... got: %v", err)
+	}
+
+	if 1 != testMountStruct.volStruct.reapExpiredObjects() {
+		t.Fatalf("reapExpiredObjects() should have reaped the expired object")
+	}
+
+	_, err = testMountStruct.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "object")
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("Lookup() of reaped object should have returned NotFoundError... got: %v", err)
+	}
+
+	_, ok, err := headhunterVolumeHandle.GetExpiryRec(pastExpireAt, uint64(objectInodeNumber))
+	if (nil != err) || ok {
+		t.Fatalf("GetExpiryRec() of reaped object should have returned !ok (err: %v)", err)
+	}
+
+	// Pushing out an expiration replaces its ExpiryRec
+
+	objectInodeNumber = createObject("pushed")
+
+	err = testMountStruct.MiddlewareSetExpiration(containerName, "pushed", pastExpireAt)
+	if nil != err {
+		t.Fatalf("MiddlewareSetExpiration(past) returned error: %v", err)
+	}
+	err = testMountStruct.MiddlewareSetExpiration(containerName, "pushed", futureExpireAt)
+	if nil != err {
+		t.Fatalf("MiddlewareSetExpiration(future) returned error: %v", err)
+	}
+
+	_, ok, err = headhunterVolumeHandle.GetExpiryRec(pastExpireAt, uint64(objectInodeNumber))
+	if (nil != err) || ok {
+		t.Fatalf("GetExpiryRec() of replaced expiration should have returned !ok (err: %v)", err)
+	}
+	_, ok, err = headhunterVolumeHandle.GetExpiryRec(futureExpireAt, uint64(objectInodeNumber))
+	if (nil != err) || !ok {
+		t.Fatalf("GetExpiryRec() of pushed out expiration should have returned ok (err: %v)", err)
+	}
+
+	if 0 != testMountStruct.volStruct.reapExpiredObjects() {
+		t.Fatalf("reapExpiredObjects() unexpectedly reaped an object whose expiration was pushed out")
+	}
+
+	err = testMountStruct.MiddlewareSetExpiration(containerName, "pushed", 0)
+	if nil != err {
+		t.Fatalf("MiddlewareSetExpiration(0) returned error: %v", err)
+	}
+	err = testMountStruct.Unlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "pushed")
+	if nil != err {
+		t.Fatalf("Unlink() returned error: %v", err)
+	}
+
+	// An expired object that has been renamed is reaped at its new location
+
+	objectInodeNumber = createObject("renamed")
+
+	err = testMountStruct.MiddlewareSetExpiration(containerName, "renamed", pastExpireAt)
+	if nil != err {
+		t.Fatalf("MiddlewareSetExpiration(past) returned error: %v", err)
+	}
+
+	subdirInodeNumber, err := testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "subdir", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+
+	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "renamed", subdirInodeNumber, "moved", inode.RenameFlags(0))
+	if nil != err {
+		t.Fatalf("Rename() returned error: %v", err)
+	}
+
+	if 1 != testMountStruct.volStruct.reapExpiredObjects() {
+		t.Fatalf("reapExpiredObjects() should have reaped the renamed object")
+	}
+
+	_, err = testMountStruct.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, subdirInodeNumber, "moved")
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("Lookup() of reaped renamed object should have returned NotFoundError... got: %v", err)
+	}
+
+	_, ok, err = headhunterVolumeHandle.GetExpiryRec(pastExpireAt, uint64(objectInodeNumber))
+	if (nil != err) || ok {
+		t.Fatalf("GetExpiryRec() of reaped renamed object should have returned !ok (err: %v)", err)
+	}
+
+	err = testMountStruct.Rmdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "subdir")
+	if nil != err {
+		t.Fatalf("Rmdir() returned error: %v", err)
+	}
+
+	// An expired object no longer found where its ExpiryRec says it lives is made visible again
+
+	objectInodeNumber = createObject("original")
+
+	err = testMountStruct.Link(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "alias", objectInodeNumber)
+	if nil != err {
+		t.Fatalf("Link() returned error: %v", err)
+	}
+
+	err = testMountStruct.MiddlewareSetExpiration(containerName, "original", pastExpireAt)
+	if nil != err {
+		t.Fatalf("MiddlewareSetExpiration(past) returned error: %v", err)
+	}
+
+	err = testMountStruct.Unlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "original")
+	if nil != err {
+		t.Fatalf("Unlink() returned error: %v", err)
+	}
+
+	if 0 != testMountStruct.volStruct.reapExpiredObjects() {
+		t.Fatalf("reapExpiredObjects() unexpectedly reaped an object not found at its ExpiryRec's location")
+	}
+
+	headResponse, err = testMountStruct.MiddlewareHeadResponse(containerName + "/alias")
+	if nil != err {
+		t.Fatalf("MiddlewareHeadResponse() of relocated expired object returned error: %v", err)
+	}
+	if 0 != headResponse.ExpireAt {
+		t.Fatalf("MiddlewareHeadResponse() of relocated expired object returned ExpireAt %v", headResponse.ExpireAt)
+	}
+
+	_, ok, err = headhunterVolumeHandle.GetExpiryRec(pastExpireAt, uint64(objectInodeNumber))
+	if (nil != err) || ok {
+		t.Fatalf("GetExpiryRec() of relocated expired object should have returned !ok (err: %v)", err)
+	}
+
+	err = testMountStruct.Unlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "alias")
+	if nil != err {
+		t.Fatalf("Unlink() returned error: %v", err)
+	}
+
+	// Clearing an expiration removes its ExpiryRec
+
+	objectInodeNumber = createObject("cleared")
+
+	err = testMountStruct.MiddlewareSetExpiration(containerName, "cleared", futureExpireAt)
+	if nil != err {
+		t.Fatalf("MiddlewareSetExpiration(future) returned error: %v", err)
+	}
+	err = testMountStruct.MiddlewareSetExpiration(containerName, "cleared", 0)
+	if nil != err {
+		t.Fatalf("MiddlewareSetExpiration(0) returned error: %v", err)
+	}
+
+	headResponse, err = testMountStruct.MiddlewareHeadResponse(containerName + "/cleared")
+	if nil != err {
+		t.Fatalf("MiddlewareHeadResponse() of cleared object returned error: %v", err)
+	}
+	if 0 != headResponse.ExpireAt {
+		t.Fatalf("MiddlewareHeadResponse() of cleared object returned ExpireAt %v", headResponse.ExpireAt)
+	}
+
+	_, ok, err = headhunterVolumeHandle.GetExpiryRec(futureExpireAt, uint64(objectInodeNumber))
+	if (nil != err) || ok {
+		t.Fatalf("GetExpiryRec() of cleared object should have returned !ok (err: %v)", err)
+	}
+
+	// An ExpiryRec left behind by an object unlinked some other way is simply dropped
+
+	objectInodeNumber = createObject("unlinked")
+
+	err = testMountStruct.MiddlewareSetExpiration(containerName, "unlinked", pastExpireAt)
+	if nil != err {
+		t.Fatalf("MiddlewareSetExpiration(past) returned error: %v", err)
+	}
+
+	err = testMountStruct.Unlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "unlinked")
+	if nil != err {
+		t.Fatalf("Unlink() returned error: %v", err)
+	}
+
+	if 0 != testMountStruct.volStruct.reapExpiredObjects() {
+		t.Fatalf("reapExpiredObjects() unexpectedly reaped an already unlinked object")
+	}
+
+	_, ok, err = headhunterVolumeHandle.GetExpiryRec(pastExpireAt, uint64(objectInodeNumber))
+	if (nil != err) || ok {
+		t.Fatalf("GetExpiryRec() of unlinked object should have returned !ok (err: %v)", err)
+	}
+
+	// Expiration only applies to objects
+
+	err = testMountStruct.MiddlewareSetExpiration(containerName, "", futureExpireAt)
+	if !blunder.Is(err, blunder.NotFileError) {
+		t.Fatalf("MiddlewareSetExpiration() of a directory should have returned NotFileError... got: %v", err)
+	}
+
+	err = testMountStruct.Unlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "cleared")
+	if nil != err {
+		t.Fatalf("Unlink() returned error: %v", err)
+	}
+
+	err = testMountStruct.Rmdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, containerName)
+	if nil != err {
+		t.Fatalf("Rmdir() returned error: %v", err)
+	}
+
+	testTeardown(t)
+}
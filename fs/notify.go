@@ -0,0 +1,537 @@
+package fs
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/trackedlock"
+)
+
+// notifyNameCacheMax limits the number of InodeNumber->(DirInodeNumber,Basename) mappings
+// retained in order to deliver NotifySetattr, NotifyWriteClose, and NotifyXAttr events
+const notifyNameCacheMax = 65536
+
+// notifyAncestorDepthMax limits how far up the directory tree a subtree subscription is matched
+const notifyAncestorDepthMax = 256
+
+// notifyIdleCheckInterval limits how often subscriptions are checked for having gone idle
+const notifyIdleCheckInterval = time.Second
+
+type notifyNameStruct struct {
+	dirInodeNumber inode.InodeNumber
+	basename       string
+}
+
+type notifySubscriptionStruct struct {
+	id             NotifySubscriptionID
+	mountID        MountID
+	dirInodeNumber inode.InodeNumber
+	subtree        bool
+	eventMask      NotifyEventType
+	events         []NotifyEvent
+	overflowed     bool
+	wakeChan       chan struct{} // Buffered (1) to signal NotifyFetch() waiter that events (or unsubscribe) are pending
+	lastFetchTime  time.Time     // Time of NotifySubscribe() or the most recent NotifyFetch()
+	fetchers       uint64        // Count of NotifyFetch() callers waiting for events
+}
+
+type notifyVolumeStruct struct {
+	mutex                trackedlock.Mutex
+	subscriptionCount    int64 // Accessed atomically so that mutation paths may quickly skip notification
+	lastSubscriptionID   NotifySubscriptionID
+	lastSequence         uint64
+	subscriptionMap      map[NotifySubscriptionID]*notifySubscriptionStruct
+	subtreeSubscriptions uint64 // Count of subscriptionMap entries with subtree == true
+	nameCache            map[inode.InodeNumber]notifyNameStruct
+	lastIdleCheckTime    time.Time
+}
+
+func (vS *volumeStruct) notifyInit() {
+	vS.notify.subscriptionMap = make(map[NotifySubscriptionID]*notifySubscriptionStruct)
+	vS.notify.nameCache = make(map[inode.InodeNumber]notifyNameStruct)
+}
+
+func (vS *volumeStruct) notifyActive() (active bool) {
+	active = (0 != atomic.LoadInt64(&vS.notify.subscriptionCount))
+	return
+}
+
+// notifyUnsubscribeAll removes all subscriptions, waking up any NotifyFetch() callers.
+func (vS *volumeStruct) notifyUnsubscribeAll() {
+	var (
+		subscription *notifySubscriptionStruct
+	)
+
+	vS.notify.mutex.Lock()
+
+	for _, subscription = range vS.notify.subscriptionMap {
+		subscription.wake()
+	}
+
+	vS.notify.subscriptionMap = make(map[NotifySubscriptionID]*notifySubscriptionStruct)
+	vS.notify.subtreeSubscriptions = 0
+	vS.notify.nameCache = make(map[inode.InodeNumber]notifyNameStruct)
+
+	atomic.StoreInt64(&vS.notify.subscriptionCount, 0)
+
+	vS.notify.mutex.Unlock()
+}
+
+// notifyRemoveWhileLocked removes subscription, waking up any NotifyFetch() callers.
+func (vS *volumeStruct) notifyRemoveWhileLocked(subscription *notifySubscriptionStruct) {
+	delete(vS.notify.subscriptionMap, subscription.id)
+	if subscription.subtree {
+		vS.notify.subtreeSubscriptions--
+	}
+
+	if 0 == len(vS.notify.subscriptionMap) {
+		vS.notify.nameCache = make(map[inode.InodeNumber]notifyNameStruct)
+	}
+
+	atomic.StoreInt64(&vS.notify.subscriptionCount, int64(len(vS.notify.subscriptionMap)))
+
+	subscription.wake()
+}
+
+// notifyExpireIdleWhileLocked removes those subscriptions not fetched from within FSGlobals.NotifySubscriptionIdleTimeout
+// (e.g. because the client has disappeared without calling NotifyUnsubscribe()).
+func (vS *volumeStruct) notifyExpireIdleWhileLocked(now time.Time) {
+	var (
+		subscription *notifySubscriptionStruct
+	)
+
+	if (0 == globals.notifyIdleTimeout) || (now.Sub(vS.notify.lastIdleCheckTime) < notifyIdleCheckInterval) {
+		return
+	}
+
+	vS.notify.lastIdleCheckTime = now
+
+	for _, subscription = range vS.notify.subscriptionMap {
+		if (0 == subscription.fetchers) && (now.Sub(subscription.lastFetchTime) > globals.notifyIdleTimeout) {
+			vS.notifyRemoveWhileLocked(subscription)
+			globals.NotifySubscriptionsExpired.Add(1)
+		}
+	}
+}
+
+func (subscription *notifySubscriptionStruct) wake() {
+	select {
+	case subscription.wakeChan <- struct{}{}:
+	default:
+		// A wakeup is already pending
+	}
+}
+
+// notifyNoteName records the directory entry most recently observed to reference inodeNumber.
+func (vS *volumeStruct) notifyNoteName(dirInodeNumber inode.InodeNumber, basename string, inodeNumber inode.InodeNumber) {
+	if !vS.notifyActive() {
+		return
+	}
+
+	vS.notify.mutex.Lock()
+	vS.notifyNoteNameWhileLocked(dirInodeNumber, basename, inodeNumber)
+	vS.notify.mutex.Unlock()
+}
+
+func (vS *volumeStruct) notifyNoteNameWhileLocked(dirInodeNumber inode.InodeNumber, basename string, inodeNumber inode.InodeNumber) {
+	var (
+		evictInodeNumber inode.InodeNumber
+		ok               bool
+	)
+
+	_, ok = vS.notify.nameCache[inodeNumber]
+	if !ok && (notifyNameCacheMax <= len(vS.notify.nameCache)) {
+		for evictInodeNumber = range vS.notify.nameCache {
+			delete(vS.notify.nameCache, evictInodeNumber)
+			break
+		}
+	}
+
+	vS.notify.nameCache[inodeNumber] = notifyNameStruct{dirInodeNumber: dirInodeNumber, basename: basename}
+}
+
+// notifyNamespaceEvent delivers a NotifyCreate or NotifyUnlink event for basename in dirInodeNumber.
+func (vS *volumeStruct) notifyNamespaceEvent(eventType NotifyEventType, dirInodeNumber inode.InodeNumber, basename string, inodeNumber inode.InodeNumber) {
+	if !vS.notifyActive() {
+		return
+	}
+
+	vS.notifyEvent(NotifyEvent{
+		Type:           eventType,
+		DirInodeNumber: dirInodeNumber,
+		Basename:       basename,
+		InodeNumber:    inodeNumber,
+	})
+}
+
+// notifyRenameEvent delivers a NotifyRename event.
+func (vS *volumeStruct) notifyRenameEvent(srcDirInodeNumber inode.InodeNumber, srcBasename string, dstDirInodeNumber inode.InodeNumber, dstBasename string, inodeNumber inode.InodeNumber) {
+	if !vS.notifyActive() {
+		return
+	}
+
+	vS.notifyEvent(NotifyEvent{
+		Type:              NotifyRename,
+		DirInodeNumber:    srcDirInodeNumber,
+		Basename:          srcBasename,
+		InodeNumber:       inodeNumber,
+		NewDirInodeNumber: dstDirInodeNumber,
+		NewBasename:       dstBasename,
+	})
+}
+
+// notifyInodeEvent delivers a NotifySetattr, NotifyWriteClose, or NotifyXAttr event for
+// inodeNumber. The event is dropped if the name of inodeNumber has not been observed.
+func (vS *volumeStruct) notifyInodeEvent(eventType NotifyEventType, inodeNumber inode.InodeNumber) {
+	var (
+		name notifyNameStruct
+		ok   bool
+	)
+
+	if !vS.notifyActive() {
+		return
+	}
+
+	vS.notify.mutex.Lock()
+	name, ok = vS.notify.nameCache[inodeNumber]
+	vS.notify.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	vS.notifyEvent(NotifyEvent{
+		Type:           eventType,
+		DirInodeNumber: name.dirInodeNumber,
+		Basename:       name.basename,
+		InodeNumber:    inodeNumber,
+	})
+}
+
+// notifyAncestors returns the set of directories from dirInodeNumber up to the root directory.
+//
+// Note that this is a best-effort walk of ".." entries done without holding any locks.
+//
+func (vS *volumeStruct) notifyAncestors(dirInodeNumber inode.InodeNumber) (ancestors map[inode.InodeNumber]struct{}) {
+	var (
+		depth             int
+		err               error
+		parentInodeNumber inode.InodeNumber
+	)
+
+	ancestors = make(map[inode.InodeNumber]struct{})
+
+	for depth = 0; depth < notifyAncestorDepthMax; depth++ {
+		ancestors[dirInodeNumber] = struct{}{}
+
+		if inode.RootDirInodeNumber == dirInodeNumber {
+			return
+		}
+
+		parentInodeNumber, err = vS.inodeVolumeHandle.Lookup(dirInodeNumber, "..")
+		if (nil != err) || (parentInodeNumber == dirInodeNumber) {
+			return
+		}
+
+		dirInodeNumber = parentInodeNumber
+	}
+
+	return
+}
+
+func (vS *volumeStruct) notifyEvent(event NotifyEvent) {
+	var (
+		ancestors     map[inode.InodeNumber]struct{}
+		matched       bool
+		newAncestors  map[inode.InodeNumber]struct{}
+		needAncestors bool
+		subscription  *notifySubscriptionStruct
+	)
+
+	vS.notify.mutex.Lock()
+	needAncestors = (0 < vS.notify.subtreeSubscriptions)
+	vS.notify.mutex.Unlock()
+
+	if needAncestors {
+		ancestors = vS.notifyAncestors(event.DirInodeNumber)
+		if (NotifyRename == event.Type) && (event.NewDirInodeNumber != event.DirInodeNumber) {
+			newAncestors = vS.notifyAncestors(event.NewDirInodeNumber)
+		}
+	}
+
+	// Maintain nameCache for subsequent inode-only events
+
+	vS.notify.mutex.Lock()
+	defer vS.notify.mutex.Unlock()
+
+	vS.notifyExpireIdleWhileLocked(time.Now())
+
+	switch event.Type {
+	case NotifyCreate:
+		vS.notifyNoteNameWhileLocked(event.DirInodeNumber, event.Basename, event.InodeNumber)
+	case NotifyUnlink:
+		if name, ok := vS.notify.nameCache[event.InodeNumber]; ok && (name.dirInodeNumber == event.DirInodeNumber) && (name.basename == event.Basename) {
+			delete(vS.notify.nameCache, event.InodeNumber)
+		}
+	case NotifyRename:
+		vS.notifyNoteNameWhileLocked(event.NewDirInodeNumber, event.NewBasename, event.InodeNumber)
+	}
+
+	vS.notify.lastSequence++
+	event.Sequence = vS.notify.lastSequence
+
+	for _, subscription = range vS.notify.subscriptionMap {
+		if 0 == (subscription.eventMask & event.Type) {
+			continue
+		}
+
+		matched = (subscription.dirInodeNumber == event.DirInodeNumber)
+		if !matched && (NotifyRename == event.Type) {
+			matched = (subscription.dirInodeNumber == event.NewDirInodeNumber)
+		}
+		if !matched && subscription.subtree {
+			_, matched = ancestors[subscription.dirInodeNumber]
+			if !matched && (nil != newAncestors) {
+				_, matched = newAncestors[subscription.dirInodeNumber]
+			}
+		}
+
+		if !matched {
+			continue
+		}
+
+		if uint64(len(subscription.events)) >= globals.notifyQueueDepth {
+			if !subscription.overflowed {
+				subscription.overflowed = true
+				subscription.wake()
+			}
+			globals.NotifyEventsDropped.Add(1)
+			continue
+		}
+
+		subscription.events = append(subscription.events, event)
+		subscription.wake()
+	}
+}
+
+func (mS *mountStruct) NotifySubscribe(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, dirInodeNumber inode.InodeNumber, subtree bool, eventMask NotifyEventType) (subscriptionID NotifySubscriptionID, err error) {
+	var (
+		inodeType    inode.InodeType
+		subscription *notifySubscriptionStruct
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.NotifySubscribeUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.NotifySubscribeErrors.Add(1)
+		}
+	}()
+
+	mS.volStruct.jobRWMutex.RLock()
+	defer mS.volStruct.jobRWMutex.RUnlock()
+
+	if (0 == (eventMask & NotifyAll)) || (0 != (eventMask &^ NotifyAll)) {
+		err = blunder.NewError(blunder.InvalidArgError, "NotifySubscribe() passed invalid eventMask 0x%08X", uint32(eventMask))
+		return
+	}
+
+	inodeLock, err := mS.volStruct.inodeVolumeHandle.InitInodeLock(dirInodeNumber, nil)
+	if nil != err {
+		return
+	}
+	err = inodeLock.ReadLock()
+	if nil != err {
+		return
+	}
+	defer inodeLock.Unlock()
+
+	if !mS.volStruct.inodeVolumeHandle.Access(dirInodeNumber, userID, groupID, otherGroupIDs, inode.F_OK, inode.NoOverride) {
+		err = blunder.NewError(blunder.NotFoundError, "ENOENT")
+		return
+	}
+	if !mS.volStruct.inodeVolumeHandle.Access(dirInodeNumber, userID, groupID, otherGroupIDs, inode.R_OK|inode.X_OK, inode.NoOverride) {
+		err = blunder.NewError(blunder.PermDeniedError, "EACCES")
+		return
+	}
+
+	inodeType, err = mS.volStruct.inodeVolumeHandle.GetType(dirInodeNumber)
+	if nil != err {
+		return
+	}
+	if inode.DirType != inodeType {
+		err = blunder.NewError(blunder.NotDirError, "NotifySubscribe() called on non-Directory")
+		return
+	}
+
+	mS.volStruct.notify.mutex.Lock()
+
+	mS.volStruct.notifyExpireIdleWhileLocked(startTime)
+
+	mS.volStruct.notify.lastSubscriptionID++
+
+	subscription = &notifySubscriptionStruct{
+		id:             mS.volStruct.notify.lastSubscriptionID,
+		mountID:        mS.id,
+		dirInodeNumber: dirInodeNumber,
+		subtree:        subtree,
+		eventMask:      eventMask,
+		events:         make([]NotifyEvent, 0),
+		overflowed:     false,
+		wakeChan:       make(chan struct{}, 1),
+		lastFetchTime:  startTime,
+		fetchers:       0,
+	}
+
+	mS.volStruct.notify.subscriptionMap[subscription.id] = subscription
+	if subtree {
+		mS.volStruct.notify.subtreeSubscriptions++
+	}
+
+	atomic.StoreInt64(&mS.volStruct.notify.subscriptionCount, int64(len(mS.volStruct.notify.subscriptionMap)))
+
+	mS.volStruct.notify.mutex.Unlock()
+
+	subscriptionID = subscription.id
+	err = nil
+	return
+}
+
+// lookupNotifySubscription must be called with mS.volStruct.notify.mutex held.
+func (mS *mountStruct) lookupNotifySubscription(subscriptionID NotifySubscriptionID) (subscription *notifySubscriptionStruct, err error) {
+	var (
+		ok bool
+	)
+
+	subscription, ok = mS.volStruct.notify.subscriptionMap[subscriptionID]
+	if !ok || (subscription.mountID != mS.id) {
+		err = fmt.Errorf("Unknown subscriptionID: %v", subscriptionID)
+		err = blunder.AddError(err, blunder.NotFoundError)
+		return
+	}
+
+	err = nil
+	return
+}
+
+func (mS *mountStruct) NotifyFetch(subscriptionID NotifySubscriptionID, maxEvents uint64, timeout time.Duration) (events []NotifyEvent, overflowed bool, err error) {
+	var (
+		numEvents    uint64
+		subscription *notifySubscriptionStruct
+		timer        *time.Timer
+		timerChan    <-chan time.Time
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.NotifyFetchUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		globals.NotifyFetchEvents.Add(uint64(len(events)))
+		if err != nil {
+			globals.NotifyFetchErrors.Add(1)
+		}
+	}()
+
+	// Note that jobRWMutex is not held while waiting for events to arrive
+
+	if 0 < timeout {
+		timer = time.NewTimer(timeout)
+		defer timer.Stop()
+		timerChan = timer.C
+	}
+
+	for {
+		mS.volStruct.notify.mutex.Lock()
+
+		subscription, err = mS.lookupNotifySubscription(subscriptionID)
+		if nil != err {
+			mS.volStruct.notify.mutex.Unlock()
+			return
+		}
+
+		subscription.lastFetchTime = time.Now()
+
+		numEvents = uint64(len(subscription.events))
+
+		if (0 < numEvents) || subscription.overflowed {
+			if (0 < maxEvents) && (maxEvents < numEvents) {
+				numEvents = maxEvents
+			}
+
+			events = make([]NotifyEvent, numEvents)
+			copy(events, subscription.events[:numEvents])
+			subscription.events = append(make([]NotifyEvent, 0, len(subscription.events)-int(numEvents)), subscription.events[numEvents:]...)
+
+			overflowed = subscription.overflowed
+			subscription.overflowed = false
+
+			mS.volStruct.notify.mutex.Unlock()
+
+			err = nil
+			return
+		}
+
+		if nil == timerChan {
+			mS.volStruct.notify.mutex.Unlock()
+
+			events = make([]NotifyEvent, 0)
+			overflowed = false
+			err = nil
+			return
+		}
+
+		// While waiting, subscription is not idle
+
+		subscription.fetchers++
+
+		mS.volStruct.notify.mutex.Unlock()
+
+		select {
+		case <-subscription.wakeChan:
+			mS.volStruct.notify.mutex.Lock()
+			subscription.fetchers--
+			mS.volStruct.notify.mutex.Unlock()
+
+			// Loop back to check for events (or that we've been unsubscribed)
+		case <-timerChan:
+			mS.volStruct.notify.mutex.Lock()
+			subscription.fetchers--
+			subscription.lastFetchTime = time.Now()
+			mS.volStruct.notify.mutex.Unlock()
+
+			events = make([]NotifyEvent, 0)
+			overflowed = false
+			err = nil
+			return
+		}
+	}
+}
+
+func (mS *mountStruct) NotifyUnsubscribe(subscriptionID NotifySubscriptionID) (err error) {
+	var (
+		subscription *notifySubscriptionStruct
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.NotifyUnsubscribeUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.NotifyUnsubscribeErrors.Add(1)
+		}
+	}()
+
+	mS.volStruct.notify.mutex.Lock()
+	defer mS.volStruct.notify.mutex.Unlock()
+
+	subscription, err = mS.lookupNotifySubscription(subscriptionID)
+	if nil != err {
+		return
+	}
+
+	mS.volStruct.notifyRemoveWhileLocked(subscription)
+
+	err = nil
+	return
+}
@@ -0,0 +1,309 @@
+package fs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/inode"
+)
+
+func expectNotifyEvents(t *testing.T, subscriptionID NotifySubscriptionID, expectedEvents []NotifyEvent) {
+	events, overflowed, err := testMountStruct.NotifyFetch(subscriptionID, 0, time.Second)
+	if nil != err {
+		t.Fatalf("NotifyFetch() returned error: %v", err)
+	}
+	if overflowed {
+		t.Fatalf("NotifyFetch() unexpectedly returned overflowed == true")
+	}
+	if len(expectedEvents) != len(events) {
+		t.Fatalf("NotifyFetch() returned %d events (%+v)... expected %d (%+v)", len(events), events, len(expectedEvents), expectedEvents)
+	}
+
+	for i := range events {
+		if 0 < i && events[i].Sequence <= events[i-1].Sequence {
+			t.Fatalf("NotifyFetch() returned events out of Sequence order: %+v", events)
+		}
+		events[i].Sequence = 0
+		if expectedEvents[i] != events[i] {
+			t.Fatalf("NotifyFetch() returned event[%d] == %+v... expected %+v", i, events[i], expectedEvents[i])
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var (
+		rootDirInodeNumber inode.InodeNumber = inode.RootDirInodeNumber
+		testDirname        string            = "notify_test"
+		subDirname         string            = "sub"
+		fileName           string            = "file"
+		renamedFileName    string            = "renamed"
+		err                error
+	)
+
+	testSetup(t, false)
+
+	testDirInodeNumber, err := testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, testDirname, 0755)
+	if nil != err {
+		t.Fatalf("Mkdir() '%s' returned error: %v", testDirname, err)
+	}
+
+	_, err = testMountStruct.NotifySubscribe(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInodeNumber, false, 0)
+	if !blunder.Is(err, blunder.InvalidArgError) {
+		t.Fatalf("NotifySubscribe() with empty eventMask should have failed with EINVAL: %v", err)
+	}
+
+	dirSubscriptionID, err := testMountStruct.NotifySubscribe(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInodeNumber, false, NotifyAll)
+	if nil != err {
+		t.Fatalf("NotifySubscribe() returned error: %v", err)
+	}
+	treeSubscriptionID, err := testMountStruct.NotifySubscribe(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, true, NotifyCreate|NotifyUnlink)
+	if nil != err {
+		t.Fatalf("NotifySubscribe() returned error: %v", err)
+	}
+
+	// An idle subscription should return nothing after the timeout
+
+	startTime := time.Now()
+	events, _, err := testMountStruct.NotifyFetch(dirSubscriptionID, 0, 100*time.Millisecond)
+	if nil != err {
+		t.Fatalf("NotifyFetch() returned error: %v", err)
+	}
+	if 0 != len(events) {
+		t.Fatalf("NotifyFetch() on idle subscription returned events: %+v", events)
+	}
+	if time.Since(startTime) < 100*time.Millisecond {
+		t.Fatalf("NotifyFetch() on idle subscription returned before timeout")
+	}
+
+	// A waiting NotifyFetch() should be woken by a mutation
+
+	fetchDone := make(chan []NotifyEvent)
+	go func() {
+		events, _, _ := testMountStruct.NotifyFetch(dirSubscriptionID, 0, 10*time.Second)
+		fetchDone <- events
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	fileInodeNumber, err := testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInodeNumber, fileName, 0644)
+	if nil != err {
+		t.Fatalf("Create() '%s' returned error: %v", fileName, err)
+	}
+
+	select {
+	case events = <-fetchDone:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("NotifyFetch() not woken by Create()")
+	}
+	if (1 != len(events)) || (NotifyCreate != events[0].Type) || (fileInodeNumber != events[0].InodeNumber) || (fileName != events[0].Basename) {
+		t.Fatalf("NotifyFetch() returned unexpected events: %+v", events)
+	}
+
+	// Exercise each remaining event type
+
+	_, err = testMountStruct.Write(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, 0, []byte("data"), nil)
+	if nil != err {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	err = testMountStruct.Flush(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Flush() returned error: %v", err)
+	}
+	err = testMountStruct.Setstat(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, Stat{StatMode: 0600})
+	if nil != err {
+		t.Fatalf("Setstat() returned error: %v", err)
+	}
+	err = testMountStruct.SetXAttr(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, "user.test", []byte("value"), 0)
+	if nil != err {
+		t.Fatalf("SetXAttr() returned error: %v", err)
+	}
+	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInodeNumber, fileName, testDirInodeNumber, renamedFileName, 0)
+	if nil != err {
+		t.Fatalf("Rename() returned error: %v", err)
+	}
+	err = testMountStruct.RemoveXAttr(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, "user.test")
+	if nil != err {
+		t.Fatalf("RemoveXAttr() returned error: %v", err)
+	}
+	subDirInodeNumber, err := testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInodeNumber, subDirname, 0755)
+	if nil != err {
+		t.Fatalf("Mkdir() '%s' returned error: %v", subDirname, err)
+	}
+	subFileInodeNumber, err := testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, subDirInodeNumber, fileName, 0644)
+	if nil != err {
+		t.Fatalf("Create() '%s' returned error: %v", fileName, err)
+	}
+	err = testMountStruct.Unlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, subDirInodeNumber, fileName)
+	if nil != err {
+		t.Fatalf("Unlink() '%s' returned error: %v", fileName, err)
+	}
+	err = testMountStruct.Rmdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInodeNumber, subDirname)
+	if nil != err {
+		t.Fatalf("Rmdir() '%s' returned error: %v", subDirname, err)
+	}
+	err = testMountStruct.Unlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, testDirInodeNumber, renamedFileName)
+	if nil != err {
+		t.Fatalf("Unlink() '%s' returned error: %v", renamedFileName, err)
+	}
+
+	// Non-subtree subscription sees only events in testDirInodeNumber itself
+
+	expectNotifyEvents(t, dirSubscriptionID, []NotifyEvent{
+		{Type: NotifyWriteClose, DirInodeNumber: testDirInodeNumber, Basename: fileName, InodeNumber: fileInodeNumber},
+		{Type: NotifySetattr, DirInodeNumber: testDirInodeNumber, Basename: fileName, InodeNumber: fileInodeNumber},
+		{Type: NotifyXAttr, DirInodeNumber: testDirInodeNumber, Basename: fileName, InodeNumber: fileInodeNumber},
+		{Type: NotifyRename, DirInodeNumber: testDirInodeNumber, Basename: fileName, InodeNumber: fileInodeNumber, NewDirInodeNumber: testDirInodeNumber, NewBasename: renamedFileName},
+		{Type: NotifyXAttr, DirInodeNumber: testDirInodeNumber, Basename: renamedFileName, InodeNumber: fileInodeNumber},
+		{Type: NotifyCreate, DirInodeNumber: testDirInodeNumber, Basename: subDirname, InodeNumber: subDirInodeNumber},
+		{Type: NotifyUnlink, DirInodeNumber: testDirInodeNumber, Basename: subDirname, InodeNumber: subDirInodeNumber},
+		{Type: NotifyUnlink, DirInodeNumber: testDirInodeNumber, Basename: renamedFileName, InodeNumber: fileInodeNumber},
+	})
+
+	// Subtree subscription (filtered to NotifyCreate|NotifyUnlink) sees events from all levels
+
+	expectNotifyEvents(t, treeSubscriptionID, []NotifyEvent{
+		{Type: NotifyCreate, DirInodeNumber: testDirInodeNumber, Basename: fileName, InodeNumber: fileInodeNumber},
+		{Type: NotifyCreate, DirInodeNumber: testDirInodeNumber, Basename: subDirname, InodeNumber: subDirInodeNumber},
+		{Type: NotifyCreate, DirInodeNumber: subDirInodeNumber, Basename: fileName, InodeNumber: subFileInodeNumber},
+		{Type: NotifyUnlink, DirInodeNumber: subDirInodeNumber, Basename: fileName, InodeNumber: subFileInodeNumber},
+		{Type: NotifyUnlink, DirInodeNumber: testDirInodeNumber, Basename: subDirname, InodeNumber: subDirInodeNumber},
+		{Type: NotifyUnlink, DirInodeNumber: testDirInodeNumber, Basename: renamedFileName, InodeNumber: fileInodeNumber},
+	})
+
+	err = testMountStruct.NotifyUnsubscribe(dirSubscriptionID)
+	if nil != err {
+		t.Fatalf("NotifyUnsubscribe() returned error: %v", err)
+	}
+	err = testMountStruct.NotifyUnsubscribe(dirSubscriptionID)
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("NotifyUnsubscribe() of unknown subscriptionID should have failed with ENOENT: %v", err)
+	}
+	_, _, err = testMountStruct.NotifyFetch(dirSubscriptionID, 0, 0)
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("NotifyFetch() of unknown subscriptionID should have failed with ENOENT: %v", err)
+	}
+	err = testMountStruct.NotifyUnsubscribe(treeSubscriptionID)
+	if nil != err {
+		t.Fatalf("NotifyUnsubscribe() returned error: %v", err)
+	}
+
+	err = testMountStruct.Rmdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, testDirname)
+	if nil != err {
+		t.Fatalf("Rmdir() '%s' returned error: %v", testDirname, err)
+	}
+
+	testTeardown(t)
+}
+
+func TestNotifyMiddlewareAndReplace(t *testing.T) {
+	var (
+		containerName      string            = "notify_middleware_test"
+		rootDirInodeNumber inode.InodeNumber = inode.RootDirInodeNumber
+	)
+
+	testSetup(t, false)
+
+	containerInodeNumber, err := testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, containerName, 0755)
+	if nil != err {
+		t.Fatalf("Mkdir() '%s' returned error: %v", containerName, err)
+	}
+
+	subscriptionID, err := testMountStruct.NotifySubscribe(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, true, NotifyCreate|NotifyUnlink|NotifyRename)
+	if nil != err {
+		t.Fatalf("NotifySubscribe() returned error: %v", err)
+	}
+
+	// Directories and objects created by the middleware (including missing path elements) are reported
+
+	_, _, dirInodeNumber, _, err := testMountStruct.MiddlewareMkdir(containerName, "dir", nil)
+	if nil != err {
+		t.Fatalf("MiddlewareMkdir() returned error: %v", err)
+	}
+
+	_, _, objInodeNumber, _, err := testMountStruct.MiddlewarePutComplete(containerName, "dir/sub/obj", []string{}, []uint64{}, nil, nil)
+	if nil != err {
+		t.Fatalf("MiddlewarePutComplete() returned error: %v", err)
+	}
+	subDirInodeNumber, err := testMountStruct.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dirInodeNumber, "sub")
+	if nil != err {
+		t.Fatalf("Lookup() returned error: %v", err)
+	}
+
+	_, _, copyInodeNumber, _, err := testMountStruct.MiddlewareCopy(containerName+"/dir/sub/obj", containerName+"/copies/copy", nil)
+	if nil != err {
+		t.Fatalf("MiddlewareCopy() returned error: %v", err)
+	}
+	copiesDirInodeNumber, err := testMountStruct.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "copies")
+	if nil != err {
+		t.Fatalf("Lookup() returned error: %v", err)
+	}
+
+	// Rewriting an existing object creates nothing
+
+	_, _, _, _, err = testMountStruct.MiddlewarePutComplete(containerName, "dir/sub/obj", []string{}, []uint64{}, nil, nil)
+	if nil != err {
+		t.Fatalf("MiddlewarePutComplete() returned error: %v", err)
+	}
+
+	// A Rename() replacing its target reports the target's removal
+
+	srcInodeNumber, err := testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "src", 0644)
+	if nil != err {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	dstInodeNumber, err := testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "dst", 0644)
+	if nil != err {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "src", containerInodeNumber, "dst", 0)
+	if nil != err {
+		t.Fatalf("Rename() returned error: %v", err)
+	}
+
+	expectNotifyEvents(t, subscriptionID, []NotifyEvent{
+		{Type: NotifyCreate, DirInodeNumber: containerInodeNumber, Basename: "dir", InodeNumber: dirInodeNumber},
+		{Type: NotifyCreate, DirInodeNumber: dirInodeNumber, Basename: "sub", InodeNumber: subDirInodeNumber},
+		{Type: NotifyCreate, DirInodeNumber: subDirInodeNumber, Basename: "obj", InodeNumber: objInodeNumber},
+		{Type: NotifyCreate, DirInodeNumber: containerInodeNumber, Basename: "copies", InodeNumber: copiesDirInodeNumber},
+		{Type: NotifyCreate, DirInodeNumber: copiesDirInodeNumber, Basename: "copy", InodeNumber: copyInodeNumber},
+		{Type: NotifyCreate, DirInodeNumber: containerInodeNumber, Basename: "src", InodeNumber: srcInodeNumber},
+		{Type: NotifyCreate, DirInodeNumber: containerInodeNumber, Basename: "dst", InodeNumber: dstInodeNumber},
+		{Type: NotifyUnlink, DirInodeNumber: containerInodeNumber, Basename: "dst", InodeNumber: dstInodeNumber},
+		{Type: NotifyRename, DirInodeNumber: containerInodeNumber, Basename: "src", InodeNumber: srcInodeNumber, NewDirInodeNumber: containerInodeNumber, NewBasename: "dst"},
+	})
+
+	err = testMountStruct.NotifyUnsubscribe(subscriptionID)
+	if nil != err {
+		t.Fatalf("NotifyUnsubscribe() returned error: %v", err)
+	}
+
+	// A subscription no longer fetched from expires
+
+	savedNotifyIdleTimeout := globals.notifyIdleTimeout
+	globals.notifyIdleTimeout = time.Millisecond
+	defer func() { globals.notifyIdleTimeout = savedNotifyIdleTimeout }()
+
+	subscriptionID, err = testMountStruct.NotifySubscribe(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, false, NotifyAll)
+	if nil != err {
+		t.Fatalf("NotifySubscribe() returned error: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	testMountStruct.volStruct.notify.mutex.Lock()
+	testMountStruct.volStruct.notify.lastIdleCheckTime = time.Time{}
+	testMountStruct.volStruct.notify.mutex.Unlock()
+
+	_, err = testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "expire", 0644)
+	if nil != err {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+
+	_, _, err = testMountStruct.NotifyFetch(subscriptionID, 0, 0)
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("NotifyFetch() of expired subscription should have failed with ENOENT: %v", err)
+	}
+
+	testTeardown(t)
+}
@@ -22,6 +22,7 @@ const (
 	resolvePathRequireExclusiveLockOnDirEntryInode                    //
 	resolvePathRequireExclusiveLockOnDirInode                         // Presumably only useful if resolvePathRequireExclusiveLockOnDirEntryInode also specified
 	resolvePathRequireSharedLockOnDirInode                            // Not valid if resolvePathRequireExclusiveLockOnDirInode also specified
+	resolvePathNotifyCreatedPathElements                              // Deliver NotifyCreate for each DirEntry created via resolvePathCreateMissingPathElements
 )
 
 func resolvePathOptionsCheck(optionsRequested uint32, optionsToCheckFor uint32) (optionsRequestedIncludesCheckFor bool) {
@@ -716,6 +717,10 @@ RestartAfterFollowingSymlink:
 					}
 					return
 				}
+
+				if resolvePathOptionsCheck(options, resolvePathNotifyCreatedPathElements) {
+					mS.volStruct.notifyNamespaceEvent(NotifyCreate, dirInodeNumber, pathSplitPart, dirEntryInodeNumber)
+				}
 			} else {
 				// Don't create missing Inode... so its a failure
 				// But first, free locks not recorded in heldLocks (if any)
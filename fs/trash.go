@@ -0,0 +1,1078 @@
+package fs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/dlm"
+	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+// Volumes configured with TrashEnabled don't immediately discard what Unlink(), Rmdir(),
+// MiddlewareDelete(), and (replacing) Rename() remove. Instead, an inode that would otherwise have
+// been destroyed (i.e. a FileInode or SymlinkInode losing its last link or an empty DirInode) is
+// moved into a hidden, per-user trash directory:
+//
+//     /<TrashDirName>/<UserID>/<TrashID>
+//
+// where TrashID is the time (in nanoseconds since the epoch) at which the inode was trashed formatted
+// as %016X (such that TrashIDs sort in the order they were trashed). The directory (InodeNumber) and
+// basename the inode was deleted from and the time it was deleted are recorded in its TrashDirEntryStream
+// and TrashTimeStream. Deletions made via the middleware also record the path that was deleted in
+// TrashPathStream (POSIX operations, addressing directories by InodeNumber, have no path to record).
+// Deletions made via POSIX operations are trashed on behalf of the caller while those made via the
+// middleware are trashed on behalf of the owner of the deleted inode. Anything deleted from within the
+// trash itself is discarded as usual.
+//
+// Trashed inodes may be listed by TrashList() and moved back into place by TrashRestore(). Unless
+// TrashRetention is zero, the trash purger will eventually destroy those trashed before then.
+//
+
+type trashPurgerStruct struct {
+	stopChan chan struct{}
+	doneWG   sync.WaitGroup
+}
+
+// parseTrashID decodes trashID (formatted just like a VersionID) into the time it was trashed.
+func parseTrashID(trashID string) (deleteTime uint64, ok bool) {
+	deleteTime, ok = parseVersionID(trashID)
+	return
+}
+
+func trashUserDirPath(userID inode.InodeUserID) (path string) {
+	path = TrashDirName + "/" + strconv.FormatUint(uint64(userID), 10)
+	return
+}
+
+// isTrashPath reports whether path (relative to the root directory) is within the trash.
+func isTrashPath(path string) (inTrash bool) {
+	containerName, _ := splitContainerObjectPath(path)
+	inTrash = (TrashDirName == containerName)
+	return
+}
+
+// isTrashableWhileLocked reports whether removing inodeNumber from the namespace would destroy it (and,
+// hence, it should be moved to the trash instead).
+func (mS *mountStruct) isTrashableWhileLocked(inodeNumber inode.InodeNumber) (trashable bool, err error) {
+	var (
+		metadata      *inode.MetadataStruct
+		numDirEntries uint64
+	)
+
+	metadata, err = mS.volStruct.inodeVolumeHandle.GetMetadata(inodeNumber)
+	if nil != err {
+		return
+	}
+
+	if inode.DirType == metadata.InodeType {
+		numDirEntries, err = mS.volStruct.inodeVolumeHandle.NumDirEntries(inodeNumber)
+		if nil != err {
+			return
+		}
+		trashable = (2 == numDirEntries)
+	} else {
+		trashable = (1 == metadata.LinkCount)
+	}
+
+	return
+}
+
+// formatTrashDirEntry encodes the directory and basename an inode was trashed from for its TrashDirEntryStream.
+func formatTrashDirEntry(dirInodeNumber inode.InodeNumber, basename string) (dirEntryBuf []byte) {
+	dirEntryBuf = []byte(fmt.Sprintf("%016X/%s", uint64(dirInodeNumber), basename))
+	return
+}
+
+// parseTrashDirEntry decodes a TrashDirEntryStream (as formatted by formatTrashDirEntry()).
+func parseTrashDirEntry(dirEntryBuf []byte) (dirInodeNumber inode.InodeNumber, basename string, ok bool) {
+	var (
+		dirInodeNumberAsU64 uint64
+		err                 error
+		dirEntrySplit       []string
+	)
+
+	dirEntrySplit = strings.SplitN(string(dirEntryBuf), "/", 2)
+	if (2 != len(dirEntrySplit)) || ("" == dirEntrySplit[1]) {
+		ok = false
+		return
+	}
+
+	dirInodeNumberAsU64, err = strconv.ParseUint(dirEntrySplit[0], 16, 64)
+	if nil != err {
+		ok = false
+		return
+	}
+
+	dirInodeNumber = inode.InodeNumber(dirInodeNumberAsU64)
+	basename = dirEntrySplit[1]
+	ok = true
+
+	return
+}
+
+// isInTrash reports whether dirInodeNumber is (or is within) the trash.
+//
+// Note that, like notifyAncestors(), this is a best-effort walk of ".." entries done without holding
+// any locks (other than those the caller holds).
+func (vS *volumeStruct) isInTrash(dirInodeNumber inode.InodeNumber) (inTrash bool) {
+	var (
+		depth                int
+		err                  error
+		parentInodeNumber    inode.InodeNumber
+		trashRootInodeNumber inode.InodeNumber
+	)
+
+	trashRootInodeNumber, err = vS.inodeVolumeHandle.Lookup(inode.RootDirInodeNumber, TrashDirName)
+	if nil != err {
+		inTrash = false
+		return
+	}
+
+	for depth = 0; depth < notifyAncestorDepthMax; depth++ {
+		if trashRootInodeNumber == dirInodeNumber {
+			inTrash = true
+			return
+		}
+
+		if inode.RootDirInodeNumber == dirInodeNumber {
+			break
+		}
+
+		parentInodeNumber, err = vS.inodeVolumeHandle.Lookup(dirInodeNumber, "..")
+		if (nil != err) || (parentInodeNumber == dirInodeNumber) {
+			break
+		}
+
+		dirInodeNumber = parentInodeNumber
+	}
+
+	inTrash = false
+	return
+}
+
+// ensureOwnerAndModeWhileLocked sets the owner and mode of (trash) directory dirInodeNumber if they differ
+// from those specified.
+func (mS *mountStruct) ensureOwnerAndModeWhileLocked(heldLocks *heldLocksStruct, dirInodeNumber inode.InodeNumber, userID inode.InodeUserID, filePerm inode.InodeMode) (retryRequired bool, err error) {
+	var (
+		inodeVolumeHandle inode.VolumeHandle
+		metadata          *inode.MetadataStruct
+	)
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	metadata, err = inodeVolumeHandle.GetMetadata(dirInodeNumber)
+	if nil != err {
+		return
+	}
+
+	if (userID == metadata.UserID) && (filePerm == (metadata.Mode & inode.PosixModePerm)) {
+		retryRequired = false
+		return
+	}
+
+	retryRequired = heldLocks.attemptExclusiveLock(inodeVolumeHandle, dlm.GenerateCallerID(), dirInodeNumber)
+	if retryRequired {
+		return
+	}
+
+	err = inodeVolumeHandle.SetOwnerUserID(dirInodeNumber, userID)
+	if nil != err {
+		return
+	}
+
+	err = inodeVolumeHandle.SetPermMode(dirInodeNumber, filePerm)
+
+	return
+}
+
+// resolveTrashDirWhileLocked exclusively locks (creating if necessary) the trash directory of userID.
+func (mS *mountStruct) resolveTrashDirWhileLocked(heldLocks *heldLocksStruct, userID inode.InodeUserID) (trashDirInodeNumber inode.InodeNumber, retryRequired bool, err error) {
+	var (
+		trashRootInodeNumber inode.InodeNumber
+	)
+
+	trashRootInodeNumber, trashDirInodeNumber, _, _, retryRequired, err =
+		mS.resolvePath(
+			inode.RootDirInodeNumber,
+			trashUserDirPath(userID),
+			heldLocks,
+			resolvePathCreateMissingPathElements|
+				resolvePathDirEntryInodeMustBeDirectory|
+				resolvePathRequireExclusiveLockOnDirEntryInode)
+
+	if (nil != err) || retryRequired {
+		return
+	}
+
+	retryRequired, err = mS.ensureOwnerAndModeWhileLocked(heldLocks, trashRootInodeNumber, inode.InodeRootUserID, inode.InodeMode(0711))
+	if (nil != err) || retryRequired {
+		return
+	}
+
+	retryRequired, err = mS.ensureOwnerAndModeWhileLocked(heldLocks, trashDirInodeNumber, userID, inode.InodeMode(0700))
+
+	return
+}
+
+// ensureTrashDir returns the trash directory of userID (creating it if necessary). It is used by those
+// operations (i.e. Unlink() and Rmdir()) that then lock it without the aid of a heldLocksStruct.
+func (mS *mountStruct) ensureTrashDir(userID inode.InodeUserID) (trashDirInodeNumber inode.InodeNumber, err error) {
+	var (
+		heldLocks      *heldLocksStruct
+		restartBackoff time.Duration
+		retryRequired  bool
+	)
+
+	// Retry until done or failure (starting with ZERO backoff)
+
+	restartBackoff = time.Duration(0)
+
+Restart:
+
+	// Perform backoff and update for each restart (starting with ZERO backoff of course)
+
+	restartBackoff, err = utils.PerformDelayAndComputeNextDelay(restartBackoff, globals.tryLockBackoffMin, globals.tryLockBackoffMax)
+	if nil != err {
+		logger.Fatalf("ensureTrashDir(): failed in restartBackoff: %v", err)
+	}
+
+	// Construct fresh heldLocks for this restart
+
+	heldLocks = newHeldLocks()
+
+	trashDirInodeNumber, retryRequired, err = mS.resolveTrashDirWhileLocked(heldLocks, userID)
+
+	heldLocks.free()
+
+	if (nil == err) && retryRequired {
+		goto Restart
+	}
+
+	return
+}
+
+// deleteTrashStreams removes those streams recorded by trashWhileLocked() (ignoring any not present).
+func (vS *volumeStruct) deleteTrashStreams(inodeNumber inode.InodeNumber) {
+	_ = vS.inodeVolumeHandle.DeleteStream(inodeNumber, TrashDirEntryStream)
+	_ = vS.inodeVolumeHandle.DeleteStream(inodeNumber, TrashPathStream)
+	_ = vS.inodeVolumeHandle.DeleteStream(inodeNumber, TrashTimeStream)
+}
+
+// trashWhileLocked moves dirInodeNumber/basename (referencing inodeNumber) into trashDirInodeNumber after
+// recording where it came from and the current time in its TrashDirEntryStream and TrashTimeStream (as well
+// as path, if known, in its TrashPathStream). The caller must hold exclusive locks on dirInodeNumber,
+// inodeNumber, and trashDirInodeNumber.
+func (mS *mountStruct) trashWhileLocked(trashDirInodeNumber inode.InodeNumber, dirInodeNumber inode.InodeNumber, basename string, inodeNumber inode.InodeNumber, path string) (err error) {
+	var (
+		deleteTime        uint64
+		inodeVolumeHandle inode.VolumeHandle
+		trashID           string
+	)
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	// Pick a TrashID not already in use (in the unlikely event of a collision)
+
+	deleteTime = uint64(time.Now().UnixNano())
+
+	for {
+		trashID = fmt.Sprintf("%016X", deleteTime)
+		_, err = inodeVolumeHandle.Lookup(trashDirInodeNumber, trashID)
+		if nil != err {
+			break
+		}
+		deleteTime++
+	}
+
+	err = inodeVolumeHandle.PutStream(inodeNumber, TrashDirEntryStream, formatTrashDirEntry(dirInodeNumber, basename))
+	if nil != err {
+		return
+	}
+
+	if "" != path {
+		err = inodeVolumeHandle.PutStream(inodeNumber, TrashPathStream, []byte(path))
+		if nil != err {
+			_ = inodeVolumeHandle.DeleteStream(inodeNumber, TrashDirEntryStream)
+			return
+		}
+	}
+
+	err = inodeVolumeHandle.PutStream(inodeNumber, TrashTimeStream, []byte(strconv.FormatUint(deleteTime, 10)))
+	if nil != err {
+		mS.volStruct.deleteTrashStreams(inodeNumber)
+		return
+	}
+
+	err = inodeVolumeHandle.Move(dirInodeNumber, basename, trashDirInodeNumber, trashID, inode.RenameNoReplace)
+	if nil != err {
+		mS.volStruct.deleteTrashStreams(inodeNumber)
+		return
+	}
+
+	mS.volStruct.expirationMovedWhileLocked(inodeNumber, trashDirInodeNumber, trashID)
+
+	globals.TrashedInodes.Add(1)
+
+	return
+}
+
+// trashWithCallerID is used by those operations (i.e. Unlink() and Rmdir()) holding (via dlmCallerID)
+// exclusive locks on dirInodeNumber and inodeNumber. If inodeNumber would have been destroyed (and is not
+// already within the trash), it is moved into trashDirInodeNumber (as returned by ensureTrashDir()).
+func (mS *mountStruct) trashWithCallerID(dlmCallerID dlm.CallerID, trashDirInodeNumber inode.InodeNumber, dirInodeNumber inode.InodeNumber, basename string, inodeNumber inode.InodeNumber) (trashed bool, err error) {
+	var (
+		trashDirLock    *dlm.RWLockStruct
+		trashDirType    inode.InodeType
+		trashDirTypeErr error
+		trashable       bool
+	)
+
+	trashed = false
+
+	if (trashDirInodeNumber == dirInodeNumber) || (trashDirInodeNumber == inodeNumber) {
+		err = nil
+		return
+	}
+
+	trashable, err = mS.isTrashableWhileLocked(inodeNumber)
+	if (nil != err) || !trashable {
+		return
+	}
+
+	if mS.volStruct.isInTrash(dirInodeNumber) {
+		err = nil
+		return
+	}
+
+	trashDirLock, err = mS.volStruct.inodeVolumeHandle.InitInodeLock(trashDirInodeNumber, dlmCallerID)
+	if nil != err {
+		return
+	}
+	err = trashDirLock.WriteLock()
+	if nil != err {
+		return
+	}
+	defer trashDirLock.Unlock()
+
+	// The trash directory may have been removed since ensureTrashDir() returned it... in which case the
+	// removal fails rather than discarding what should have been trashed
+
+	trashDirType, trashDirTypeErr = mS.volStruct.inodeVolumeHandle.GetType(trashDirInodeNumber)
+	if (nil != trashDirTypeErr) || (inode.DirType != trashDirType) {
+		logger.Warnf("fs.trashWithCallerID() found trash directory 0x%016X missing... failing removal of %v/%v", trashDirInodeNumber, dirInodeNumber, basename)
+		err = blunder.NewError(blunder.IOError, "fs.trashWithCallerID() found trash directory 0x%016X missing", trashDirInodeNumber)
+		return
+	}
+
+	err = mS.trashWhileLocked(trashDirInodeNumber, dirInodeNumber, basename, inodeNumber, "")
+	if nil != err {
+		return
+	}
+
+	trashed = true
+	return
+}
+
+// trashWithHeldLocks is used by those operations (i.e. MiddlewareDelete() and Rename()) holding exclusive
+// locks on dirInodeNumber and inodeNumber via heldLocks. It moves inodeNumber into the trash directory of
+// userID (recording path if the caller resolved one). As all further locks are obtained first, only
+// (missing) trash directories may have been created if retryRequired is returned.
+func (mS *mountStruct) trashWithHeldLocks(heldLocks *heldLocksStruct, userID inode.InodeUserID, dirInodeNumber inode.InodeNumber, basename string, inodeNumber inode.InodeNumber, path string) (retryRequired bool, err error) {
+	var (
+		trashDirInodeNumber inode.InodeNumber
+	)
+
+	trashDirInodeNumber, retryRequired, err = mS.resolveTrashDirWhileLocked(heldLocks, userID)
+	if (nil != err) || retryRequired {
+		return
+	}
+
+	if (trashDirInodeNumber == dirInodeNumber) || (trashDirInodeNumber == inodeNumber) {
+		err = blunder.NewError(blunder.InvalidArgError, "fs.trashWithHeldLocks() cannot trash %v/%v", dirInodeNumber, basename)
+		return
+	}
+
+	err = mS.trashWhileLocked(trashDirInodeNumber, dirInodeNumber, basename, inodeNumber, path)
+
+	return
+}
+
+// fetchTrashEntriesWhileLocked appends the TrashEntry's found in the trash directory of userID.
+func (mS *mountStruct) fetchTrashEntriesWhileLocked(heldLocks *heldLocksStruct, userID inode.InodeUserID, trashDirInodeNumber inode.InodeNumber, entries []TrashEntry) (updatedEntries []TrashEntry, retryRequired bool, err error) {
+	var (
+		deleteTime                uint64
+		dirEntry                  inode.DirEntry
+		dirEntryBuf               []byte
+		dirEntrySlice             []inode.DirEntry
+		dlmCallerID               dlm.CallerID
+		inodeVolumeHandle         inode.VolumeHandle
+		metadata                  *inode.MetadataStruct
+		ok                        bool
+		pathBuf                   []byte
+		trashedFromBasename       string
+		trashedFromDirInodeNumber inode.InodeNumber
+	)
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	updatedEntries = entries
+
+	dlmCallerID = dlm.GenerateCallerID()
+
+	retryRequired = heldLocks.attemptSharedLock(inodeVolumeHandle, dlmCallerID, trashDirInodeNumber)
+	if retryRequired {
+		return
+	}
+
+	dirEntrySlice, _, err = inodeVolumeHandle.ReadDir(trashDirInodeNumber, 0, 0)
+	if nil != err {
+		return
+	}
+
+	for _, dirEntry = range dirEntrySlice {
+		deleteTime, ok = parseTrashID(dirEntry.Basename)
+		if !ok {
+			continue
+		}
+
+		retryRequired = heldLocks.attemptSharedLock(inodeVolumeHandle, dlmCallerID, dirEntry.InodeNumber)
+		if retryRequired {
+			return
+		}
+
+		metadata, err = inodeVolumeHandle.GetMetadata(dirEntry.InodeNumber)
+		if nil != err {
+			return
+		}
+
+		dirEntryBuf, err = inodeVolumeHandle.GetStream(dirEntry.InodeNumber, TrashDirEntryStream)
+		if nil != err {
+			if blunder.IsNot(err, blunder.StreamNotFound) {
+				return
+			}
+			dirEntryBuf = []byte{}
+		}
+
+		trashedFromDirInodeNumber, trashedFromBasename, _ = parseTrashDirEntry(dirEntryBuf)
+
+		pathBuf, err = inodeVolumeHandle.GetStream(dirEntry.InodeNumber, TrashPathStream)
+		if nil != err {
+			if blunder.IsNot(err, blunder.StreamNotFound) {
+				return
+			}
+			pathBuf = []byte{}
+		}
+
+		updatedEntries = append(updatedEntries, TrashEntry{
+			TrashID:        dirEntry.Basename,
+			UserID:         userID,
+			DirInodeNumber: trashedFromDirInodeNumber,
+			Basename:       trashedFromBasename,
+			Path:           string(pathBuf),
+			DeleteTime:     deleteTime,
+			InodeNumber:    dirEntry.InodeNumber,
+			InodeType:      metadata.InodeType,
+			FileSize:       metadata.Size,
+		})
+	}
+
+	err = nil
+	return
+}
+
+// fetchTrashEntries returns the trashed inodes of userID (or of all users if allUsers is specified).
+func (mS *mountStruct) fetchTrashEntries(userID inode.InodeUserID, allUsers bool) (entries []TrashEntry, err error) {
+	var (
+		dirEntry             inode.DirEntry
+		dirEntrySlice        []inode.DirEntry
+		heldLocks            *heldLocksStruct
+		parsedUserID         uint64
+		restartBackoff       time.Duration
+		retryRequired        bool
+		trashDirInodeNumber  inode.InodeNumber
+		trashRootInodeNumber inode.InodeNumber
+	)
+
+	// Retry until done or failure (starting with ZERO backoff)
+
+	restartBackoff = time.Duration(0)
+
+Restart:
+
+	// Perform backoff and update for each restart (starting with ZERO backoff of course)
+
+	restartBackoff, err = utils.PerformDelayAndComputeNextDelay(restartBackoff, globals.tryLockBackoffMin, globals.tryLockBackoffMax)
+	if nil != err {
+		logger.Fatalf("fetchTrashEntries(): failed in restartBackoff: %v", err)
+	}
+
+	// Construct fresh heldLocks for this restart
+
+	heldLocks = newHeldLocks()
+
+	entries = make([]TrashEntry, 0)
+
+	_, trashRootInodeNumber, _, _, retryRequired, err =
+		mS.resolvePath(
+			inode.RootDirInodeNumber,
+			TrashDirName,
+			heldLocks,
+			resolvePathDirEntryInodeMustBeDirectory)
+
+	if nil != err {
+		heldLocks.free()
+		if blunder.Is(err, blunder.NotFoundError) {
+			// Nothing has been trashed
+
+			err = nil
+		}
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	if allUsers {
+		dirEntrySlice, _, err = mS.volStruct.inodeVolumeHandle.ReadDir(trashRootInodeNumber, 0, 0)
+		if nil != err {
+			heldLocks.free()
+			return
+		}
+	} else {
+		trashDirInodeNumber, err = mS.volStruct.inodeVolumeHandle.Lookup(trashRootInodeNumber, strconv.FormatUint(uint64(userID), 10))
+		if nil != err {
+			heldLocks.free()
+			if blunder.Is(err, blunder.NotFoundError) {
+				err = nil
+			}
+			return
+		}
+
+		dirEntrySlice = []inode.DirEntry{{InodeNumber: trashDirInodeNumber, Basename: strconv.FormatUint(uint64(userID), 10)}}
+	}
+
+	for _, dirEntry = range dirEntrySlice {
+		parsedUserID, err = strconv.ParseUint(dirEntry.Basename, 10, 32)
+		if nil != err {
+			continue
+		}
+
+		entries, retryRequired, err = mS.fetchTrashEntriesWhileLocked(heldLocks, inode.InodeUserID(parsedUserID), dirEntry.InodeNumber, entries)
+		if nil != err {
+			heldLocks.free()
+			return
+		}
+
+		if retryRequired {
+			heldLocks.free()
+			goto Restart
+		}
+	}
+
+	heldLocks.free()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].UserID != entries[j].UserID {
+			return entries[i].UserID < entries[j].UserID
+		}
+		return entries[i].TrashID < entries[j].TrashID
+	})
+
+	err = nil
+	return
+}
+
+func (mS *mountStruct) TrashList(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID) (entries []TrashEntry, err error) {
+	startTime := time.Now()
+	defer func() {
+		globals.TrashListUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.TrashListErrors.Add(1)
+		}
+	}()
+
+	mS.volStruct.jobRWMutex.RLock()
+	defer mS.volStruct.jobRWMutex.RUnlock()
+
+	// The root user sees everyone's trash
+
+	entries, err = mS.fetchTrashEntries(userID, inode.InodeRootUserID == userID)
+
+	return
+}
+
+func (mS *mountStruct) TrashRestore(userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, trashUserID inode.InodeUserID, trashID string, restorePath string) (err error) {
+	var (
+		basename            string
+		dirInodeNumber      inode.InodeNumber
+		heldLocks           *heldLocksStruct
+		inodeVolumeHandle   inode.VolumeHandle
+		ok                  bool
+		pathBuf             []byte
+		restartBackoff      time.Duration
+		retryRequired       bool
+		trashDirInodeNumber inode.InodeNumber
+		trashedInodeNumber  inode.InodeNumber
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.TrashRestoreUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.TrashRestoreErrors.Add(1)
+		}
+	}()
+	defer func() {
+		mS.auditTrashRestore(userID, groupID, trashUserID, trashID, restorePath, err)
+	}()
+
+	_, ok = parseTrashID(trashID)
+	if !ok {
+		err = blunder.NewError(blunder.InvalidArgError, "TrashRestore() called with invalid trashID (\"%s\")", trashID)
+		return
+	}
+
+	if (inode.InodeRootUserID != userID) && (trashUserID != userID) {
+		err = blunder.NewError(blunder.PermDeniedError, "EACCES")
+		return
+	}
+
+	mS.volStruct.jobRWMutex.RLock()
+	defer mS.volStruct.jobRWMutex.RUnlock()
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	// Retry until done or failure (starting with ZERO backoff)
+
+	restartBackoff = time.Duration(0)
+
+Restart:
+
+	// Perform backoff and update for each restart (starting with ZERO backoff of course)
+
+	restartBackoff, err = utils.PerformDelayAndComputeNextDelay(restartBackoff, globals.tryLockBackoffMin, globals.tryLockBackoffMax)
+	if nil != err {
+		logger.Fatalf("TrashRestore(): failed in restartBackoff: %v", err)
+	}
+
+	// Construct fresh heldLocks for this restart
+
+	heldLocks = newHeldLocks()
+
+	// Assemble WriteLocks on the trashed inode and its containing trash directory
+
+	trashDirInodeNumber, trashedInodeNumber, _, _, retryRequired, err =
+		mS.resolvePath(
+			inode.RootDirInodeNumber,
+			trashUserDirPath(trashUserID)+"/"+trashID,
+			heldLocks,
+			resolvePathRequireExclusiveLockOnDirEntryInode|
+				resolvePathRequireExclusiveLockOnDirInode)
+
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	// Determine where it is to be restored (defaulting to where it was deleted from)
+
+	if "" == restorePath {
+		pathBuf, err = inodeVolumeHandle.GetStream(trashedInodeNumber, TrashPathStream)
+		if nil == err {
+			restorePath = string(pathBuf)
+		} else if blunder.IsNot(err, blunder.StreamNotFound) {
+			heldLocks.free()
+			return
+		}
+	}
+
+	if "" == restorePath {
+		dirInodeNumber, basename, retryRequired, err = mS.resolveTrashedFromDirWhileLocked(heldLocks, userID, groupID, otherGroupIDs, trashedInodeNumber)
+	} else {
+		dirInodeNumber, basename, retryRequired, err = mS.resolveRestorePathWhileLocked(heldLocks, userID, groupID, otherGroupIDs, restorePath)
+	}
+
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	// Locks held & Access Checks succeeded... time to do the Move (failing if something is already there)
+
+	err = inodeVolumeHandle.Move(trashDirInodeNumber, trashID, dirInodeNumber, basename, inode.RenameNoReplace)
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	mS.volStruct.deleteTrashStreams(trashedInodeNumber)
+
+	mS.volStruct.expirationMovedWhileLocked(trashedInodeNumber, dirInodeNumber, basename)
+
+	heldLocks.free()
+
+	mS.volStruct.notifyNamespaceEvent(NotifyCreate, dirInodeNumber, basename, trashedInodeNumber)
+
+	err = nil
+	return
+}
+
+// resolveRestorePathWhileLocked exclusively locks the directory into which an inode is to be restored at
+// restorePath (creating, on the caller's behalf, any missing directories the caller could have created).
+func (mS *mountStruct) resolveRestorePathWhileLocked(heldLocks *heldLocksStruct, userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, restorePath string) (dirInodeNumber inode.InodeNumber, basename string, retryRequired bool, err error) {
+	var (
+		canonicalPathSplit  []string
+		dirEntryInodeNumber inode.InodeNumber
+		inodeVolumeHandle   inode.VolumeHandle
+		parentPathSplit     []string
+		pathSplitIndex      int
+	)
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	canonicalPathSplit, err = canonicalizePath(restorePath)
+	if (nil != err) || (0 == len(canonicalPathSplit)) || (TrashDirName == canonicalPathSplit[0]) {
+		err = blunder.NewError(blunder.InvalidArgError, "TrashRestore() called with invalid restorePath (\"%s\")", restorePath)
+		return
+	}
+
+	basename = canonicalPathSplit[len(canonicalPathSplit)-1]
+
+	err = validateBaseName(basename)
+	if nil != err {
+		return
+	}
+
+	parentPathSplit = canonicalPathSplit[:len(canonicalPathSplit)-1]
+
+	// Walk the existing portion of the path to the directory it is to be restored into (requiring search
+	// permission on each directory traversed) without (yet) creating any missing path elements
+
+	dirInodeNumber = inode.RootDirInodeNumber
+
+	for pathSplitIndex = 0; pathSplitIndex < len(parentPathSplit); pathSplitIndex++ {
+		if !inodeVolumeHandle.Access(dirInodeNumber, userID, groupID, otherGroupIDs, inode.X_OK, inode.NoOverride) {
+			err = blunder.NewError(blunder.PermDeniedError, "EACCES")
+			return
+		}
+
+		_, dirEntryInodeNumber, _, _, retryRequired, err =
+			mS.resolvePath(
+				dirInodeNumber,
+				parentPathSplit[pathSplitIndex],
+				heldLocks,
+				resolvePathFollowDirEntrySymlinks|
+					resolvePathFollowDirSymlinks|
+					resolvePathDirEntryInodeMustBeDirectory)
+
+		if nil != err {
+			if blunder.Is(err, blunder.NotFoundError) {
+				err = nil
+				break
+			}
+			return
+		}
+
+		if retryRequired {
+			return
+		}
+
+		dirInodeNumber = dirEntryInodeNumber
+	}
+
+	// The deepest existing directory will either receive the restored inode or the first missing path element
+
+	if !inodeVolumeHandle.Access(dirInodeNumber, userID, groupID, otherGroupIDs, inode.W_OK|inode.X_OK, inode.NoOverride) {
+		err = blunder.NewError(blunder.PermDeniedError, "EACCES")
+		return
+	}
+
+	// Assemble WriteLock on that directory
+
+	retryRequired = heldLocks.attemptExclusiveLock(inodeVolumeHandle, dlm.GenerateCallerID(), dirInodeNumber)
+	if retryRequired {
+		return
+	}
+
+	// Create any missing path elements on the caller's behalf
+
+	for ; pathSplitIndex < len(parentPathSplit); pathSplitIndex++ {
+		dirEntryInodeNumber, err = inodeVolumeHandle.CreateDir(inode.InodeMode(0755), userID, groupID)
+		if nil != err {
+			return
+		}
+
+		err = inodeVolumeHandle.Link(dirInodeNumber, parentPathSplit[pathSplitIndex], dirEntryInodeNumber, false)
+		if nil != err {
+			_ = inodeVolumeHandle.Destroy(dirEntryInodeNumber)
+			return
+		}
+
+		retryRequired = heldLocks.attemptExclusiveLock(inodeVolumeHandle, dlm.GenerateCallerID(), dirEntryInodeNumber)
+		if retryRequired {
+			logger.Fatalf("fs.resolveRestorePathWhileLocked(): failed to exclusively lock just-created DirInode 0x%016X", dirEntryInodeNumber)
+		}
+
+		mS.volStruct.notifyNamespaceEvent(NotifyCreate, dirInodeNumber, parentPathSplit[pathSplitIndex], dirEntryInodeNumber)
+
+		dirInodeNumber = dirEntryInodeNumber
+	}
+
+	retryRequired = false
+	err = nil
+	return
+}
+
+// resolveTrashedFromDirWhileLocked exclusively locks the directory trashedInodeNumber was deleted from (as
+// recorded in its TrashDirEntryStream) so that it may be restored there... provided that directory still
+// exists (outside of the trash) and the caller could have created an entry in it.
+func (mS *mountStruct) resolveTrashedFromDirWhileLocked(heldLocks *heldLocksStruct, userID inode.InodeUserID, groupID inode.InodeGroupID, otherGroupIDs []inode.InodeGroupID, trashedInodeNumber inode.InodeNumber) (dirInodeNumber inode.InodeNumber, basename string, retryRequired bool, err error) {
+	var (
+		dirEntryBuf       []byte
+		dirInodeType      inode.InodeType
+		inodeVolumeHandle inode.VolumeHandle
+		ok                bool
+	)
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	dirEntryBuf, err = inodeVolumeHandle.GetStream(trashedInodeNumber, TrashDirEntryStream)
+	if nil != err {
+		err = blunder.NewError(blunder.InvalidArgError, "TrashRestore() of 0x%016X requires a restorePath: %v", trashedInodeNumber, err)
+		return
+	}
+
+	dirInodeNumber, basename, ok = parseTrashDirEntry(dirEntryBuf)
+	if !ok {
+		err = blunder.NewError(blunder.InvalidArgError, "TrashRestore() of 0x%016X requires a restorePath (found \"%s\")", trashedInodeNumber, string(dirEntryBuf))
+		return
+	}
+
+	retryRequired = heldLocks.attemptExclusiveLock(inodeVolumeHandle, dlm.GenerateCallerID(), dirInodeNumber)
+	if retryRequired {
+		return
+	}
+
+	dirInodeType, err = inodeVolumeHandle.GetType(dirInodeNumber)
+	if (nil != err) || (inode.DirType != dirInodeType) || mS.volStruct.isInTrash(dirInodeNumber) {
+		err = blunder.NewError(blunder.InvalidArgError, "TrashRestore() of 0x%016X requires a restorePath (directory 0x%016X no longer exists)", trashedInodeNumber, dirInodeNumber)
+		return
+	}
+
+	if !inodeVolumeHandle.Access(dirInodeNumber, userID, groupID, otherGroupIDs, inode.W_OK|inode.X_OK, inode.NoOverride) {
+		err = blunder.NewError(blunder.PermDeniedError, "EACCES")
+		return
+	}
+
+	err = nil
+	return
+}
+
+func (vS *volumeStruct) trashPurgerStart() {
+	if (0 == globals.trashPurgeInterval) || !vS.trashEnabled || (0 == vS.trashRetention) {
+		vS.trashPurger.stopChan = nil
+		return
+	}
+
+	vS.trashPurger.stopChan = make(chan struct{})
+	vS.trashPurger.doneWG.Add(1)
+
+	go vS.trashPurgerDaemon()
+}
+
+func (vS *volumeStruct) trashPurgerStop() {
+	if nil == vS.trashPurger.stopChan {
+		return
+	}
+
+	close(vS.trashPurger.stopChan)
+	vS.trashPurger.doneWG.Wait()
+
+	vS.trashPurger.stopChan = nil
+}
+
+func (vS *volumeStruct) trashPurgerDaemon() {
+	defer vS.trashPurger.doneWG.Done()
+
+	for {
+		select {
+		case <-vS.trashPurger.stopChan:
+			return
+		case <-time.After(globals.trashPurgeInterval):
+			vS.purgeTrash()
+		}
+	}
+}
+
+// purgeTrash performs a single pass of the trash purger, returning the number of trashed inodes
+// destroyed.
+func (vS *volumeStruct) purgeTrash() (purged uint64) {
+	var (
+		entries     []TrashEntry
+		err         error
+		mS          *mountStruct
+		purgeBefore uint64
+		wasPurged   bool
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.TrashPurgeUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		globals.TrashPurgedInodes.Add(purged)
+	}()
+
+	vS.jobRWMutex.RLock()
+	defer vS.jobRWMutex.RUnlock()
+
+	// resolvePath() only needs a mountStruct for its volStruct
+
+	mS = &mountStruct{volStruct: vS}
+
+	entries, err = mS.fetchTrashEntries(inode.InodeRootUserID, true)
+	if nil != err {
+		logger.ErrorfWithError(err, "fs.purgeTrash() of volume %s failed to list trash", vS.volumeName)
+		globals.TrashPurgeErrors.Add(1)
+		return
+	}
+
+	purgeBefore = uint64(startTime.Add(-vS.trashRetention).UnixNano())
+
+	for _, entry := range entries {
+		if entry.DeleteTime >= purgeBefore {
+			continue
+		}
+
+		wasPurged, err = mS.purgeTrashEntry(&entry)
+		if nil != err {
+			logger.ErrorfWithError(err, "fs.purgeTrash() of volume %s failed to purge %s (inode 0x%016X)", vS.volumeName, entry.Path, entry.InodeNumber)
+			globals.TrashPurgeErrors.Add(1)
+			continue
+		}
+		if wasPurged {
+			purged++
+		}
+	}
+
+	return
+}
+
+func (mS *mountStruct) purgeTrashEntry(entry *TrashEntry) (purged bool, err error) {
+	var (
+		heldLocks           *heldLocksStruct
+		inodeVolumeHandle   inode.VolumeHandle
+		linkCount           uint64
+		numDirEntries       uint64
+		restartBackoff      time.Duration
+		retryRequired       bool
+		trashDirInodeNumber inode.InodeNumber
+		trashedInodeNumber  inode.InodeNumber
+		trashedInodeType    inode.InodeType
+	)
+
+	defer func() {
+		if purged || (nil != err) {
+			mS.auditTrashPurge(entry, err)
+		}
+	}()
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	// Retry until done or failure (starting with ZERO backoff)
+
+	restartBackoff = time.Duration(0)
+
+Restart:
+
+	// Perform backoff and update for each restart (starting with ZERO backoff of course)
+
+	restartBackoff, err = utils.PerformDelayAndComputeNextDelay(restartBackoff, globals.tryLockBackoffMin, globals.tryLockBackoffMax)
+	if nil != err {
+		logger.Fatalf("purgeTrashEntry(): failed in restartBackoff: %v", err)
+	}
+
+	// Construct fresh heldLocks for this restart
+
+	heldLocks = newHeldLocks()
+
+	trashDirInodeNumber, trashedInodeNumber, _, trashedInodeType, retryRequired, err =
+		mS.resolvePath(
+			inode.RootDirInodeNumber,
+			trashUserDirPath(entry.UserID)+"/"+entry.TrashID,
+			heldLocks,
+			resolvePathRequireExclusiveLockOnDirEntryInode|
+				resolvePathRequireExclusiveLockOnDirInode)
+
+	if nil != err {
+		heldLocks.free()
+		if blunder.Is(err, blunder.NotFoundError) {
+			// Already restored (or otherwise removed)
+
+			err = nil
+		}
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	defer heldLocks.free()
+
+	if entry.InodeNumber != trashedInodeNumber {
+		err = nil
+		return
+	}
+
+	if inode.DirType == trashedInodeType {
+		numDirEntries, err = inodeVolumeHandle.NumDirEntries(trashedInodeNumber)
+		if nil != err {
+			return
+		}
+		if 2 != numDirEntries {
+			err = blunder.NewError(blunder.NotEmptyError, "trashed directory %s/%s not empty", trashUserDirPath(entry.UserID), entry.TrashID)
+			return
+		}
+		linkCount = 1
+	} else {
+		linkCount, err = inodeVolumeHandle.GetLinkCount(trashedInodeNumber)
+		if nil != err {
+			return
+		}
+	}
+
+	err = inodeVolumeHandle.Unlink(trashDirInodeNumber, entry.TrashID, false)
+	if nil != err {
+		return
+	}
+
+	if 1 == linkCount {
+		mS.volStruct.untrackInFlightFileInodeData(trashedInodeNumber, false)
+		err = inodeVolumeHandle.Destroy(trashedInodeNumber)
+		if nil != err {
+			return
+		}
+	}
+
+	purged = true
+	return
+}
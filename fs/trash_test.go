@@ -0,0 +1,340 @@
+package fs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/inode"
+)
+
+func TestTrash(t *testing.T) {
+	var (
+		dirName            string            = "trash_test"
+		otherUserID        inode.InodeUserID = inode.InodeUserID(2000)
+		rootDirInodeNumber inode.InodeNumber = inode.RootDirInodeNumber
+		testUserID         inode.InodeUserID = inode.InodeUserID(1000)
+	)
+
+	testSetup(t, false)
+
+	testMountStruct.volStruct.trashEnabled = true
+
+	dirInodeNumber, err := testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, dirName, inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+
+	createFile := func(userID inode.InodeUserID, basename string) (fileInodeNumber inode.InodeNumber) {
+		fileInodeNumber, err = testMountStruct.Create(userID, inode.InodeGroupID(0), nil, dirInodeNumber, basename, inode.PosixModePerm)
+		if nil != err {
+			t.Fatalf("Create(\"%s\") returned error: %v", basename, err)
+		}
+		_, err = testMountStruct.Write(userID, inode.InodeGroupID(0), nil, fileInodeNumber, 0, []byte(basename), nil)
+		if nil != err {
+			t.Fatalf("Write(\"%s\") returned error: %v", basename, err)
+		}
+		return
+	}
+
+	fetchTrash := func(userID inode.InodeUserID, expectedNumEntries int) (entries []TrashEntry) {
+		entries, err = testMountStruct.TrashList(userID, inode.InodeGroupID(0), nil)
+		if nil != err {
+			t.Fatalf("TrashList(%v) returned error: %v", userID, err)
+		}
+		if expectedNumEntries != len(entries) {
+			t.Fatalf("TrashList(%v) returned %v entries... expected %v", userID, len(entries), expectedNumEntries)
+		}
+		return
+	}
+
+	verifyLookup := func(basename string, expectedInodeNumber inode.InodeNumber) {
+		inodeNumber, err := testMountStruct.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dirInodeNumber, basename)
+		if inode.InodeNumber(0) == expectedInodeNumber {
+			if !blunder.Is(err, blunder.NotFoundError) {
+				t.Fatalf("Lookup(\"%s\") should have returned NotFoundError... got: %v", basename, err)
+			}
+			return
+		}
+		if nil != err {
+			t.Fatalf("Lookup(\"%s\") returned error: %v", basename, err)
+		}
+		if expectedInodeNumber != inodeNumber {
+			t.Fatalf("Lookup(\"%s\") returned 0x%016X... expected 0x%016X", basename, inodeNumber, expectedInodeNumber)
+		}
+	}
+
+	// Nothing has been trashed yet
+
+	fetchTrash(inode.InodeRootUserID, 0)
+
+	// Unlink() trashes a file on behalf of the caller
+
+	aInodeNumber := createFile(testUserID, "a")
+
+	err = testMountStruct.Unlink(testUserID, inode.InodeGroupID(0), nil, dirInodeNumber, "a")
+	if nil != err {
+		t.Fatalf("Unlink() returned error: %v", err)
+	}
+	verifyLookup("a", inode.InodeNumber(0))
+
+	entries := fetchTrash(testUserID, 1)
+	if (dirInodeNumber != entries[0].DirInodeNumber) || ("a" != entries[0].Basename) || ("" != entries[0].Path) || (aInodeNumber != entries[0].InodeNumber) || (testUserID != entries[0].UserID) || (inode.FileType != entries[0].InodeType) || (1 != entries[0].FileSize) {
+		t.Fatalf("TrashList() returned unexpected %+v", entries[0])
+	}
+	aTrashID := entries[0].TrashID
+
+	fetchTrash(otherUserID, 0)
+
+	trashRootInodeNumber, err := testMountStruct.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, TrashDirName)
+	if nil != err {
+		t.Fatalf("Lookup(TrashDirName) returned error: %v", err)
+	}
+	trashDirInodeNumber, err := testMountStruct.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, trashRootInodeNumber, "1000")
+	if nil != err {
+		t.Fatalf("Lookup(TrashDirName/1000) returned error: %v", err)
+	}
+	stat, err := testMountStruct.Getstat(inode.InodeRootUserID, inode.InodeGroupID(0), nil, trashDirInodeNumber)
+	if nil != err {
+		t.Fatalf("Getstat(TrashDirName/1000) returned error: %v", err)
+	}
+	if (uint64(testUserID) != stat[StatUserID]) || (uint64(0700) != (stat[StatMode] & uint64(inode.PosixModePerm))) {
+		t.Fatalf("TrashDirName/1000 has unexpected owner (%v) or mode (0%o)", stat[StatUserID], stat[StatMode])
+	}
+
+	// Rmdir() trashes an empty directory
+
+	_, err = testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dirInodeNumber, "d", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir(\"d\") returned error: %v", err)
+	}
+
+	err = testMountStruct.Rmdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dirInodeNumber, "d")
+	if nil != err {
+		t.Fatalf("Rmdir() returned error: %v", err)
+	}
+	verifyLookup("d", inode.InodeNumber(0))
+
+	entries = fetchTrash(inode.InodeRootUserID, 2)
+	if (inode.InodeRootUserID != entries[0].UserID) || (inode.DirType != entries[0].InodeType) || (testUserID != entries[1].UserID) {
+		t.Fatalf("TrashList() of the root user returned unexpected %+v", entries)
+	}
+
+	// A Rename() replacing a file trashes the replaced file
+
+	bInodeNumber := createFile(testUserID, "b")
+	cInodeNumber := createFile(testUserID, "c")
+
+	err = testMountStruct.Rename(testUserID, inode.InodeGroupID(0), nil, dirInodeNumber, "c", dirInodeNumber, "b", 0)
+	if nil != err {
+		t.Fatalf("Rename() returned error: %v", err)
+	}
+	verifyLookup("b", cInodeNumber)
+	verifyLookup("c", inode.InodeNumber(0))
+
+	entries = fetchTrash(testUserID, 2)
+	if (dirInodeNumber != entries[1].DirInodeNumber) || ("b" != entries[1].Basename) || (bInodeNumber != entries[1].InodeNumber) {
+		t.Fatalf("TrashList() returned unexpected %+v", entries[1])
+	}
+	bTrashID := entries[1].TrashID
+
+	// A file with other links is not trashed
+
+	err = testMountStruct.Link(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dirInodeNumber, "b.link", cInodeNumber)
+	if nil != err {
+		t.Fatalf("Link() returned error: %v", err)
+	}
+	err = testMountStruct.Unlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dirInodeNumber, "b.link")
+	if nil != err {
+		t.Fatalf("Unlink() of additional link returned error: %v", err)
+	}
+	fetchTrash(inode.InodeRootUserID, 3)
+
+	// MiddlewareDelete() trashes an object on behalf of its owner
+
+	createFile(otherUserID, "object")
+
+	err = testMountStruct.MiddlewareDelete(dirName, "object")
+	if nil != err {
+		t.Fatalf("MiddlewareDelete() returned error: %v", err)
+	}
+	verifyLookup("object", inode.InodeNumber(0))
+
+	entries = fetchTrash(otherUserID, 1)
+	if (dirInodeNumber != entries[0].DirInodeNumber) || ("object" != entries[0].Basename) || ("/"+dirName+"/object" != entries[0].Path) {
+		t.Fatalf("TrashList() returned unexpected %+v", entries[0])
+	}
+
+	// TrashRestore() is only permitted to the owner of the trash (or the root user)
+
+	err = testMountStruct.TrashRestore(otherUserID, inode.InodeGroupID(0), nil, testUserID, aTrashID, "")
+	if !blunder.Is(err, blunder.PermDeniedError) {
+		t.Fatalf("TrashRestore() by another user should have returned PermDeniedError... got: %v", err)
+	}
+
+	err = testMountStruct.TrashRestore(testUserID, inode.InodeGroupID(0), nil, testUserID, aTrashID, "")
+	if nil != err {
+		t.Fatalf("TrashRestore() returned error: %v", err)
+	}
+	verifyLookup("a", aInodeNumber)
+
+	_, err = testMountStruct.GetXAttr(inode.InodeRootUserID, inode.InodeGroupID(0), nil, aInodeNumber, TrashDirEntryStream)
+	if nil == err {
+		t.Fatalf("TrashRestore() should have removed TrashDirEntryStream")
+	}
+
+	err = testMountStruct.TrashRestore(testUserID, inode.InodeGroupID(0), nil, testUserID, aTrashID, "")
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("TrashRestore() of already restored inode should have returned NotFoundError... got: %v", err)
+	}
+
+	// TrashRestore() won't replace an existing entry... but may restore elsewhere (creating missing directories)
+
+	err = testMountStruct.TrashRestore(testUserID, inode.InodeGroupID(0), nil, testUserID, bTrashID, "")
+	if !blunder.Is(err, blunder.FileExistsError) {
+		t.Fatalf("TrashRestore() onto existing entry should have returned FileExistsError... got: %v", err)
+	}
+
+	err = testMountStruct.TrashRestore(testUserID, inode.InodeGroupID(0), nil, testUserID, bTrashID, "/"+TrashDirName+"/b")
+	if !blunder.Is(err, blunder.InvalidArgError) {
+		t.Fatalf("TrashRestore() into the trash should have returned InvalidArgError... got: %v", err)
+	}
+
+	// Missing directories are only created where the caller could have created them
+
+	_, err = testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dirInodeNumber, "locked", inode.InodeMode(0755))
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+
+	err = testMountStruct.TrashRestore(testUserID, inode.InodeGroupID(0), nil, testUserID, bTrashID, dirName+"/locked/restored/b")
+	if !blunder.Is(err, blunder.PermDeniedError) {
+		t.Fatalf("TrashRestore() into unwritable directory should have returned PermDeniedError... got: %v", err)
+	}
+
+	_, err = testMountStruct.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dirName+"/locked/restored")
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("TrashRestore() into unwritable directory should not have created missing directories... got: %v", err)
+	}
+
+	err = testMountStruct.TrashRestore(testUserID, inode.InodeGroupID(0), nil, testUserID, bTrashID, dirName+"/restored/b")
+	if nil != err {
+		t.Fatalf("TrashRestore() to alternate path returned error: %v", err)
+	}
+
+	restoredInodeNumber, err := testMountStruct.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dirName+"/restored/b")
+	if nil != err {
+		t.Fatalf("LookupPath() of restored file returned error: %v", err)
+	}
+	if bInodeNumber != restoredInodeNumber {
+		t.Fatalf("LookupPath() of restored file returned 0x%016X... expected 0x%016X", restoredInodeNumber, bInodeNumber)
+	}
+
+	restoredDirInodeNumber, err := testMountStruct.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dirName+"/restored")
+	if nil != err {
+		t.Fatalf("LookupPath() of created directory returned error: %v", err)
+	}
+	restoredDirStat, err := testMountStruct.Getstat(inode.InodeRootUserID, inode.InodeGroupID(0), nil, restoredDirInodeNumber)
+	if nil != err {
+		t.Fatalf("Getstat() of created directory returned error: %v", err)
+	}
+	if uint64(testUserID) != restoredDirStat[StatUserID] {
+		t.Fatalf("TrashRestore() created directory owned by %v... expected %v", restoredDirStat[StatUserID], testUserID)
+	}
+
+	// Deleting from within the trash discards the inode
+
+	entries = fetchTrash(inode.InodeRootUserID, 2)
+
+	err = testMountStruct.Rmdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, trashRootInodeNumber, "0")
+	if !blunder.Is(err, blunder.NotEmptyError) {
+		t.Fatalf("Rmdir() of non-empty trash directory should have returned NotEmptyError... got: %v", err)
+	}
+
+	rootTrashDirInodeNumber, err := testMountStruct.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, trashRootInodeNumber, "0")
+	if nil != err {
+		t.Fatalf("Lookup(TrashDirName/0) returned error: %v", err)
+	}
+
+	err = testMountStruct.Rmdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootTrashDirInodeNumber, entries[0].TrashID)
+	if nil != err {
+		t.Fatalf("Rmdir() within the trash returned error: %v", err)
+	}
+
+	fetchTrash(inode.InodeRootUserID, 1)
+
+	// The purger destroys trashed inodes older than TrashRetention
+
+	testMountStruct.volStruct.trashRetention = time.Hour
+
+	if 0 != testMountStruct.volStruct.purgeTrash() {
+		t.Fatalf("purgeTrash() unexpectedly purged a recently trashed inode")
+	}
+
+	testMountStruct.volStruct.trashRetention = time.Nanosecond
+
+	if 1 != testMountStruct.volStruct.purgeTrash() {
+		t.Fatalf("purgeTrash() should have purged the remaining trashed inode")
+	}
+
+	fetchTrash(inode.InodeRootUserID, 0)
+
+	testMountStruct.volStruct.trashEnabled = false
+	testMountStruct.volStruct.trashRetention = time.Duration(0)
+
+	testTeardown(t)
+}
+
+func TestTrashUnavailable(t *testing.T) {
+	var (
+		rootDirInodeNumber inode.InodeNumber = inode.RootDirInodeNumber
+	)
+
+	testSetup(t, false)
+
+	testMountStruct.volStruct.trashEnabled = true
+
+	// A file occupying TrashDirName prevents any trash directory from being created
+
+	trashRootInodeNumber, err := testMountStruct.volStruct.inodeVolumeHandle.CreateFile(inode.PosixModePerm, inode.InodeRootUserID, inode.InodeGroupID(0))
+	if nil != err {
+		t.Fatalf("inode.CreateFile() returned error: %v", err)
+	}
+	err = testMountStruct.volStruct.inodeVolumeHandle.Link(rootDirInodeNumber, TrashDirName, trashRootInodeNumber, false)
+	if nil != err {
+		t.Fatalf("inode.Link() returned error: %v", err)
+	}
+
+	fileInodeNumber, err := testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, "file", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	_, err = testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, "dir", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+
+	// Rather than discarding what should have been trashed, Unlink() and Rmdir() fail
+
+	err = testMountStruct.Unlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, "file")
+	if nil == err {
+		t.Fatalf("Unlink() without a trash directory should have failed")
+	}
+	inodeNumber, err := testMountStruct.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, "file")
+	if (nil != err) || (fileInodeNumber != inodeNumber) {
+		t.Fatalf("Unlink() without a trash directory should have left file in place (err: %v)", err)
+	}
+
+	err = testMountStruct.Rmdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, "dir")
+	if nil == err {
+		t.Fatalf("Rmdir() without a trash directory should have failed")
+	}
+	_, err = testMountStruct.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, "dir")
+	if nil != err {
+		t.Fatalf("Rmdir() without a trash directory should have left dir in place (err: %v)", err)
+	}
+
+	testMountStruct.volStruct.trashEnabled = false
+
+	testTeardown(t)
+}
@@ -1254,7 +1254,12 @@ func (sVS *scrubVolumeStruct) scrubVolumeInode(inodeNumber uint64) {
 	defer inodeLock.Unlock()
 
 	err = sVS.inodeVolumeHandle.Validate(inode.InodeNumber(inodeNumber), true)
-	if nil != err {
+	if blunder.Is(err, blunder.IOError) {
+		// The inode is intact but some of the file data it references failed checksum
+		// verification (or could not be read)... so report it rather than remove the inode
+
+		sVS.jobLogErr("Got inode.Validate(0x%016X) data checksum failure: %v", inodeNumber, err)
+	} else if nil != err {
 		sVS.jobLogInfo("Got inode.Validate(0x%016X) failure: %v ... removing it", inodeNumber, err)
 
 		err = sVS.headhunterVolumeHandle.DeleteInodeRec(inodeNumber)
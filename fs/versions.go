@@ -0,0 +1,865 @@
+package fs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/audit"
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/dlm"
+	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+// Bimodal containers may be configured (via MiddlewareSetVersioning()) to retain prior versions of
+// their objects. Rather than copying an object being replaced (by MiddlewarePutComplete(),
+// MiddlewareCoalesce(), or MiddlewareCopy()) or deleted (by MiddlewareDelete()) into some other
+// container as Swift would, its FileInode is simply moved into a hidden directory:
+//
+//     /<VersionsDirName>/<container>/<object path>/<VersionID>
+//
+// where VersionID is the time (in nanoseconds since the epoch) at which the version was archived
+// formatted as %016X (such that VersionIDs sort in the order they were archived). With
+// VersioningModeStack, deleting an object restores its most recent prior version (if any). With
+// VersioningModeHistory, the deleted object is archived as well. A non-zero maxVersions caps the
+// number of prior versions retained for each object (discarding the oldest ones first).
+//
+// FileInodes that have neither been written nor PUT via the middleware (e.g. those just created by
+// resolvePath()) have nothing worth keeping... so are never archived.
+//
+
+type versioningStruct struct {
+	Mode        string
+	MaxVersions uint64
+}
+
+// isVersionsDirEntry reports whether dirInodeNumber/basename is VersionsDirName. As only the middleware
+// is to see or modify prior versions, the POSIX (i.e. FUSE & SMB) APIs hide it from view and refuse to
+// create, remove, or rename it.
+func isVersionsDirEntry(dirInodeNumber inode.InodeNumber, basename string) bool {
+	return (inode.RootDirInodeNumber == dirInodeNumber) && (VersionsDirName == basename)
+}
+
+func splitContainerObjectPath(path string) (containerName string, objectPath string) {
+	pathSplit := strings.SplitN(strings.Trim(path, "/"), "/", 2)
+
+	containerName = pathSplit[0]
+
+	if 2 == len(pathSplit) {
+		objectPath = pathSplit[1]
+	} else {
+		objectPath = ""
+	}
+
+	return
+}
+
+func parseVersionID(versionID string) (archiveTime uint64, ok bool) {
+	var (
+		err error
+	)
+
+	archiveTime, err = strconv.ParseUint(versionID, 16, 64)
+	if nil != err {
+		ok = false
+		return
+	}
+
+	ok = (fmt.Sprintf("%016X", archiveTime) == versionID)
+
+	return
+}
+
+func (mS *mountStruct) fetchVersioningWhileLocked(heldLocks *heldLocksStruct, containerName string) (versioning versioningStruct, retryRequired bool, err error) {
+	var (
+		containerInodeNumber inode.InodeNumber
+		inodeVolumeHandle    inode.VolumeHandle
+		versioningBuf        []byte
+	)
+
+	versioning = versioningStruct{Mode: VersioningModeNone}
+	retryRequired = false
+
+	if ("" == containerName) || (VersionsDirName == containerName) {
+		err = nil
+		return
+	}
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	containerInodeNumber, err = inodeVolumeHandle.Lookup(inode.RootDirInodeNumber, containerName)
+	if nil != err {
+		if blunder.Is(err, blunder.NotFoundError) {
+			err = nil
+		}
+		return
+	}
+
+	retryRequired = heldLocks.attemptSharedLock(inodeVolumeHandle, dlm.GenerateCallerID(), containerInodeNumber)
+	if retryRequired {
+		return
+	}
+
+	versioningBuf, err = inodeVolumeHandle.GetStream(containerInodeNumber, VersioningStream)
+	if nil != err {
+		if blunder.Is(err, blunder.StreamNotFound) {
+			err = nil
+		}
+		return
+	}
+
+	err = json.Unmarshal(versioningBuf, &versioning)
+	if nil != err {
+		err = blunder.NewError(blunder.BadFileError, "container %s has unparseable %s stream: %v", containerName, VersioningStream, err)
+		return
+	}
+
+	return
+}
+
+func (mS *mountStruct) isVersionableWhileLocked(fileInodeNumber inode.InodeNumber) (versionable bool, err error) {
+	var (
+		metadata   *inode.MetadataStruct
+		streamName string
+	)
+
+	metadata, err = mS.volStruct.inodeVolumeHandle.GetMetadata(fileInodeNumber)
+	if nil != err {
+		return
+	}
+
+	if inode.FileType != metadata.InodeType {
+		versionable = false
+		return
+	}
+
+	if (0 != metadata.Size) || (0 != metadata.NumWrites) {
+		versionable = true
+		return
+	}
+
+	for _, streamName = range metadata.InodeStreamNameSlice {
+		if MiddlewareStream == streamName {
+			versionable = true
+			return
+		}
+	}
+
+	versionable = false
+	return
+}
+
+func (mS *mountStruct) resolveVersionsDir(heldLocks *heldLocksStruct, containerName string, objectPath string, createMissing bool) (versionsDirInodeNumber inode.InodeNumber, retryRequired bool, err error) {
+	var (
+		options uint32
+	)
+
+	options = resolvePathDirEntryInodeMustBeDirectory | resolvePathRequireExclusiveLockOnDirEntryInode
+	if createMissing {
+		options |= resolvePathCreateMissingPathElements
+	}
+
+	_, versionsDirInodeNumber, _, _, retryRequired, err =
+		mS.resolvePath(
+			inode.RootDirInodeNumber,
+			VersionsDirName+"/"+containerName+"/"+objectPath,
+			heldLocks,
+			options)
+
+	return
+}
+
+// fetchVersionIDsWhileLocked returns the (sorted) VersionIDs found in versionsDirInodeNumber other than
+// excludeVersionID. Entries that aren't FileInodes (e.g. the versions directories of objects "below"
+// this one) are skipped.
+func (mS *mountStruct) fetchVersionIDsWhileLocked(versionsDirInodeNumber inode.InodeNumber, excludeVersionID string) (versionIDs []string, err error) {
+	var (
+		dirEntry          inode.DirEntry
+		dirEntrySlice     []inode.DirEntry
+		inodeType         inode.InodeType
+		inodeVolumeHandle inode.VolumeHandle
+		ok                bool
+	)
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	dirEntrySlice, _, err = inodeVolumeHandle.ReadDir(versionsDirInodeNumber, 0, 0)
+	if nil != err {
+		return
+	}
+
+	versionIDs = make([]string, 0, len(dirEntrySlice))
+
+	for _, dirEntry = range dirEntrySlice {
+		if excludeVersionID == dirEntry.Basename {
+			continue
+		}
+		_, ok = parseVersionID(dirEntry.Basename)
+		if !ok {
+			continue
+		}
+		inodeType, err = inodeVolumeHandle.GetType(dirEntry.InodeNumber)
+		if nil != err {
+			return
+		}
+		if inode.FileType == inodeType {
+			versionIDs = append(versionIDs, dirEntry.Basename)
+		}
+	}
+
+	sort.Strings(versionIDs)
+
+	err = nil
+	return
+}
+
+// unlinkWhileLocked removes dirInodeNumber/basename... destroying inodeNumber if that was its last link.
+func (mS *mountStruct) unlinkWhileLocked(dirInodeNumber inode.InodeNumber, basename string, inodeNumber inode.InodeNumber) (err error) {
+	var (
+		inodeVolumeHandle inode.VolumeHandle
+		linkCount         uint64
+	)
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	linkCount, err = inodeVolumeHandle.GetLinkCount(inodeNumber)
+	if nil != err {
+		return
+	}
+
+	err = inodeVolumeHandle.Unlink(dirInodeNumber, basename, false)
+	if nil != err {
+		return
+	}
+
+	if 1 == linkCount {
+		err = inodeVolumeHandle.Destroy(inodeNumber)
+		if nil != err {
+			logger.Errorf("fs.unlinkWhileLocked() failed to Destroy inodeNumber 0x%016X: %v", inodeNumber, err)
+		}
+	}
+
+	err = nil
+	return
+}
+
+// prepareArchiveWhileLocked resolves (creating if necessary) the versions directory of containerName/objectPath,
+// exclusively locks the oldest prior versions that must be pruned to make room for one more given
+// versioning.MaxVersions (none if versioning is nil), and picks an unused VersionID for the version about to be
+// archived. If supplied, keepVersionID (presumably about to be restored) is neither counted nor pruned. Nothing
+// beyond (missing) versions directories will have been created if retryRequired is returned.
+func (mS *mountStruct) prepareArchiveWhileLocked(heldLocks *heldLocksStruct, versioning *versioningStruct, containerName string, objectPath string, keepVersionID string) (versionsDirInodeNumber inode.InodeNumber, archiveVersionID string, pruneVersionIDs []string, pruneInodeNumbers []inode.InodeNumber, retryRequired bool, err error) {
+	var (
+		archiveTime       uint64
+		dlmCallerID       dlm.CallerID
+		inodeVolumeHandle inode.VolumeHandle
+		pruneIndex        int
+		versionID         string
+		versionIDs        []string
+	)
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	versionsDirInodeNumber, retryRequired, err = mS.resolveVersionsDir(heldLocks, containerName, objectPath, true)
+	if (nil != err) || retryRequired {
+		return
+	}
+
+	// Lock the prior versions that will need to be pruned
+
+	versionIDs, err = mS.fetchVersionIDsWhileLocked(versionsDirInodeNumber, keepVersionID)
+	if nil != err {
+		return
+	}
+
+	if (nil != versioning) && (0 != versioning.MaxVersions) && (uint64(len(versionIDs)) >= versioning.MaxVersions) {
+		pruneVersionIDs = versionIDs[:uint64(len(versionIDs))+1-versioning.MaxVersions]
+	} else {
+		pruneVersionIDs = make([]string, 0)
+	}
+
+	pruneInodeNumbers = make([]inode.InodeNumber, len(pruneVersionIDs))
+
+	dlmCallerID = dlm.GenerateCallerID()
+
+	for pruneIndex, versionID = range pruneVersionIDs {
+		pruneInodeNumbers[pruneIndex], err = inodeVolumeHandle.Lookup(versionsDirInodeNumber, versionID)
+		if nil != err {
+			return
+		}
+
+		retryRequired = heldLocks.attemptExclusiveLock(inodeVolumeHandle, dlmCallerID, pruneInodeNumbers[pruneIndex])
+		if retryRequired {
+			return
+		}
+	}
+
+	// Pick a VersionID not already in use (in the unlikely event of a collision)
+
+	archiveTime = uint64(time.Now().UnixNano())
+
+	for {
+		archiveVersionID = fmt.Sprintf("%016X", archiveTime)
+		_, err = inodeVolumeHandle.Lookup(versionsDirInodeNumber, archiveVersionID)
+		if nil != err {
+			break
+		}
+		archiveTime++
+	}
+
+	err = nil
+	return
+}
+
+// pruneVersionsWhileLocked discards the prior versions locked by prepareArchiveWhileLocked(). Failures are
+// logged rather than returned as the version being archived has, by now, already been moved.
+func (mS *mountStruct) pruneVersionsWhileLocked(containerName string, objectPath string, versionsDirInodeNumber inode.InodeNumber, pruneVersionIDs []string, pruneInodeNumbers []inode.InodeNumber) {
+	var (
+		err        error
+		pruneIndex int
+		versionID  string
+	)
+
+	for pruneIndex, versionID = range pruneVersionIDs {
+		err = mS.unlinkWhileLocked(versionsDirInodeNumber, versionID, pruneInodeNumbers[pruneIndex])
+		if nil != err {
+			logger.ErrorfWithError(err, "fs.pruneVersionsWhileLocked() failed to prune version %s of %s/%s", versionID, containerName, objectPath)
+		}
+	}
+}
+
+// archiveVersionWhileLocked moves dirInodeNumber/basename (the current version of containerName/objectPath)
+// into its versions directory and then prunes the oldest prior versions beyond versioning.MaxVersions. The
+// caller must hold exclusive locks on dirInodeNumber and the FileInode at basename. As all further locks are
+// obtained before anything is moved or pruned, only (missing) versions directories may have been created if
+// retryRequired is returned.
+func (mS *mountStruct) archiveVersionWhileLocked(heldLocks *heldLocksStruct, versioning *versioningStruct, containerName string, objectPath string, dirInodeNumber inode.InodeNumber, basename string) (retryRequired bool, err error) {
+	var (
+		archiveVersionID       string
+		archivedInodeNumber    inode.InodeNumber
+		inodeVolumeHandle      inode.VolumeHandle
+		pruneInodeNumbers      []inode.InodeNumber
+		pruneVersionIDs        []string
+		versionsDirInodeNumber inode.InodeNumber
+	)
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	versionsDirInodeNumber, archiveVersionID, pruneVersionIDs, pruneInodeNumbers, retryRequired, err =
+		mS.prepareArchiveWhileLocked(heldLocks, versioning, containerName, objectPath, "")
+	if (nil != err) || retryRequired {
+		return
+	}
+
+	// Now archive the current version and prune the oldest prior versions
+
+	archivedInodeNumber, err = inodeVolumeHandle.Lookup(dirInodeNumber, basename)
+	if nil != err {
+		return
+	}
+
+	err = inodeVolumeHandle.Move(dirInodeNumber, basename, versionsDirInodeNumber, archiveVersionID, inode.RenameNoReplace)
+	if nil != err {
+		return
+	}
+
+	mS.volStruct.expirationMovedWhileLocked(archivedInodeNumber, versionsDirInodeNumber, archiveVersionID)
+
+	mS.pruneVersionsWhileLocked(containerName, objectPath, versionsDirInodeNumber, pruneVersionIDs, pruneInodeNumbers)
+
+	err = nil
+	return
+}
+
+// versionBeforeReplaceWhileLocked is called by operations about to replace the contents of fileInodeNumber
+// (found at dirInodeNumber/basename resolved from path). If the container is versioned, the FileInode is
+// archived and replaced (at basename) by a new, empty FileInode (exclusively locked) that is returned in its
+// place. Otherwise, fileInodeNumber is simply returned. The caller must hold an exclusive lock on
+// fileInodeNumber. As with archiveVersionWhileLocked(), the FileInode will not have been moved if
+// retryRequired is returned.
+func (mS *mountStruct) versionBeforeReplaceWhileLocked(heldLocks *heldLocksStruct, path string, dirInodeNumber inode.InodeNumber, basename string, fileInodeNumber inode.InodeNumber) (newFileInodeNumber inode.InodeNumber, retryRequired bool, err error) {
+	var (
+		containerName     string
+		inodeVolumeHandle inode.VolumeHandle
+		metadata          *inode.MetadataStruct
+		objectPath        string
+		versionable       bool
+		versioning        versioningStruct
+	)
+
+	newFileInodeNumber = fileInodeNumber
+
+	containerName, objectPath = splitContainerObjectPath(path)
+	if "" == objectPath {
+		retryRequired = false
+		err = nil
+		return
+	}
+
+	versioning, retryRequired, err = mS.fetchVersioningWhileLocked(heldLocks, containerName)
+	if (nil != err) || retryRequired || (VersioningModeNone == versioning.Mode) {
+		return
+	}
+
+	versionable, err = mS.isVersionableWhileLocked(fileInodeNumber)
+	if (nil != err) || !versionable {
+		return
+	}
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	retryRequired = heldLocks.attemptExclusiveLock(inodeVolumeHandle, dlm.GenerateCallerID(), dirInodeNumber)
+	if retryRequired {
+		return
+	}
+
+	metadata, err = inodeVolumeHandle.GetMetadata(fileInodeNumber)
+	if nil != err {
+		return
+	}
+
+	retryRequired, err = mS.archiveVersionWhileLocked(heldLocks, &versioning, containerName, objectPath, dirInodeNumber, basename)
+	if (nil != err) || retryRequired {
+		return
+	}
+
+	newFileInodeNumber, err = inodeVolumeHandle.CreateFile(metadata.Mode&inode.PosixModePerm, metadata.UserID, metadata.GroupID)
+	if nil != err {
+		return
+	}
+
+	if heldLocks.attemptExclusiveLock(inodeVolumeHandle, dlm.GenerateCallerID(), newFileInodeNumber) {
+		logger.Fatalf("fs.versionBeforeReplaceWhileLocked(): failed to exclusively lock just-created Inode 0x%016X", newFileInodeNumber)
+	}
+
+	err = inodeVolumeHandle.Link(dirInodeNumber, basename, newFileInodeNumber, false)
+	if nil != err {
+		_ = inodeVolumeHandle.Destroy(newFileInodeNumber)
+		return
+	}
+
+	return
+}
+
+// lockLatestVersionWhileLocked locates (and exclusively locks) the most recent prior version of
+// containerName/objectPath. If there is none, latestVersionID will be returned as "".
+func (mS *mountStruct) lockLatestVersionWhileLocked(heldLocks *heldLocksStruct, containerName string, objectPath string) (versionsDirInodeNumber inode.InodeNumber, latestVersionID string, latestVersionInodeNumber inode.InodeNumber, retryRequired bool, err error) {
+	var (
+		inodeVolumeHandle inode.VolumeHandle
+		versionIDs        []string
+	)
+
+	latestVersionID = ""
+
+	versionsDirInodeNumber, retryRequired, err = mS.resolveVersionsDir(heldLocks, containerName, objectPath, false)
+	if nil != err {
+		if blunder.Is(err, blunder.NotFoundError) {
+			err = nil
+		}
+		return
+	}
+	if retryRequired {
+		return
+	}
+
+	versionIDs, err = mS.fetchVersionIDsWhileLocked(versionsDirInodeNumber, "")
+	if (nil != err) || (0 == len(versionIDs)) {
+		return
+	}
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	latestVersionID = versionIDs[len(versionIDs)-1]
+
+	latestVersionInodeNumber, err = inodeVolumeHandle.Lookup(versionsDirInodeNumber, latestVersionID)
+	if nil != err {
+		return
+	}
+
+	retryRequired = heldLocks.attemptExclusiveLock(inodeVolumeHandle, dlm.GenerateCallerID(), latestVersionInodeNumber)
+
+	return
+}
+
+func (mS *mountStruct) MiddlewareSetVersioning(containerName string, mode string, maxVersions uint64) (err error) {
+	var (
+		containerInodeLock   *dlm.RWLockStruct
+		containerInodeNumber inode.InodeNumber
+		inodeVolumeHandle    inode.VolumeHandle
+		versioningBuf        []byte
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.MiddlewareSetVersioningUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.MiddlewareSetVersioningErrors.Add(1)
+		}
+	}()
+
+	switch mode {
+	case VersioningModeNone:
+	case VersioningModeStack:
+	case VersioningModeHistory:
+	default:
+		err = blunder.NewError(blunder.InvalidArgError, "MiddlewareSetVersioning() called with unknown mode \"%s\"", mode)
+		return
+	}
+
+	if VersionsDirName == containerName {
+		err = blunder.NewError(blunder.PermDeniedError, "MiddlewareSetVersioning() called for reserved container %s", containerName)
+		return
+	}
+
+	mS.volStruct.jobRWMutex.RLock()
+	defer mS.volStruct.jobRWMutex.RUnlock()
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	containerInodeNumber, err = inodeVolumeHandle.Lookup(inode.RootDirInodeNumber, containerName)
+	if nil != err {
+		return
+	}
+
+	containerInodeLock, err = inodeVolumeHandle.GetWriteLock(containerInodeNumber, nil)
+	if nil != err {
+		return
+	}
+	defer containerInodeLock.Unlock()
+
+	if VersioningModeNone == mode {
+		err = inodeVolumeHandle.DeleteStream(containerInodeNumber, VersioningStream)
+		if (nil != err) && blunder.Is(err, blunder.StreamNotFound) {
+			err = nil
+		}
+		return
+	}
+
+	versioningBuf, err = json.Marshal(&versioningStruct{Mode: mode, MaxVersions: maxVersions})
+	if nil != err {
+		logger.Fatalf("MiddlewareSetVersioning(): failed to marshal versioning configuration: %v", err)
+	}
+
+	err = inodeVolumeHandle.PutStream(containerInodeNumber, VersioningStream, versioningBuf)
+
+	return
+}
+
+func (mS *mountStruct) MiddlewareListVersions(vContainerName string, vObjectPath string) (versions []VersionEntry, err error) {
+	var (
+		archiveTime            uint64
+		dlmCallerID            dlm.CallerID
+		heldLocks              *heldLocksStruct
+		inodeVolumeHandle      inode.VolumeHandle
+		metadata               []byte
+		restartBackoff         time.Duration
+		retryRequired          bool
+		stat                   Stat
+		versionID              string
+		versionIDs             []string
+		versionInodeNumber     inode.InodeNumber
+		versionsDirInodeNumber inode.InodeNumber
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.MiddlewareListVersionsUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.MiddlewareListVersionsErrors.Add(1)
+		}
+	}()
+
+	mS.volStruct.jobRWMutex.RLock()
+	defer mS.volStruct.jobRWMutex.RUnlock()
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	// Retry until done or failure (starting with ZERO backoff)
+
+	restartBackoff = time.Duration(0)
+
+Restart:
+
+	// Perform backoff and update for each restart (starting with ZERO backoff of course)
+
+	restartBackoff, err = utils.PerformDelayAndComputeNextDelay(restartBackoff, globals.tryLockBackoffMin, globals.tryLockBackoffMax)
+	if nil != err {
+		logger.Fatalf("MiddlewareListVersions(): failed in restartBackoff: %v", err)
+	}
+
+	// Construct fresh heldLocks for this restart
+
+	heldLocks = newHeldLocks()
+
+	_, versionsDirInodeNumber, _, _, retryRequired, err =
+		mS.resolvePath(
+			inode.RootDirInodeNumber,
+			VersionsDirName+"/"+vContainerName+"/"+vObjectPath,
+			heldLocks,
+			resolvePathDirEntryInodeMustBeDirectory)
+
+	if nil != err {
+		heldLocks.free()
+		if blunder.Is(err, blunder.NotFoundError) {
+			// No prior versions have been archived
+
+			versions = make([]VersionEntry, 0)
+			err = nil
+		}
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	versionIDs, err = mS.fetchVersionIDsWhileLocked(versionsDirInodeNumber, "")
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	versions = make([]VersionEntry, 0, len(versionIDs))
+
+	dlmCallerID = dlm.GenerateCallerID()
+
+	for _, versionID = range versionIDs {
+		versionInodeNumber, err = inodeVolumeHandle.Lookup(versionsDirInodeNumber, versionID)
+		if nil != err {
+			heldLocks.free()
+			return
+		}
+
+		retryRequired = heldLocks.attemptSharedLock(inodeVolumeHandle, dlmCallerID, versionInodeNumber)
+		if retryRequired {
+			heldLocks.free()
+			goto Restart
+		}
+
+		stat, err = mS.getstatHelperWhileLocked(versionInodeNumber)
+		if nil != err {
+			heldLocks.free()
+			return
+		}
+
+		metadata, err = inodeVolumeHandle.GetStream(versionInodeNumber, MiddlewareStream)
+		if nil != err {
+			if blunder.IsNot(err, blunder.StreamNotFound) {
+				heldLocks.free()
+				return
+			}
+			metadata = []byte{}
+		}
+
+		archiveTime, _ = parseVersionID(versionID)
+
+		versions = append(versions, VersionEntry{
+			VersionID:        versionID,
+			ArchiveTime:      archiveTime,
+			FileSize:         stat[StatSize],
+			ModificationTime: stat[StatMTime],
+			AttrChangeTime:   stat[StatCTime],
+			NumWrites:        stat[StatNumWrites],
+			InodeNumber:      uint64(versionInodeNumber),
+			Metadata:         metadata,
+			ETag:             fetchETag(inodeVolumeHandle, versionInodeNumber),
+		})
+	}
+
+	heldLocks.free()
+
+	err = nil
+	return
+}
+
+func (mS *mountStruct) MiddlewareRestoreVersion(vContainerName string, vObjectPath string, versionID string) (err error) {
+	var (
+		archiveVersionID       string
+		archiving              bool
+		dirEntryBasename       string
+		dirEntryInodeNumber    inode.InodeNumber
+		dirInodeNumber         inode.InodeNumber
+		heldLocks              *heldLocksStruct
+		inodeVolumeHandle      inode.VolumeHandle
+		ok                     bool
+		pruneInodeNumbers      []inode.InodeNumber
+		pruneVersionIDs        []string
+		pVersioning            *versioningStruct
+		restartBackoff         time.Duration
+		retryRequired          bool
+		versionable            bool
+		versionInodeNumber     inode.InodeNumber
+		versioning             versioningStruct
+		versionsDirInodeNumber inode.InodeNumber
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.MiddlewareRestoreVersionUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.MiddlewareRestoreVersionErrors.Add(1)
+		}
+	}()
+	defer func() {
+		mS.auditMiddleware(audit.OpMiddlewareRestoreVersion, VersionsDirName+"/"+vContainerName+"/"+vObjectPath+"/"+versionID, vContainerName+"/"+vObjectPath, versionInodeNumber, err)
+	}()
+
+	_, ok = parseVersionID(versionID)
+	if !ok || ("" == vObjectPath) {
+		err = blunder.NewError(blunder.InvalidArgError, "MiddlewareRestoreVersion() called with invalid object path (\"%s\") or versionID (\"%s\")", vObjectPath, versionID)
+		return
+	}
+
+	mS.volStruct.jobRWMutex.RLock()
+	defer mS.volStruct.jobRWMutex.RUnlock()
+
+	// Retry until done or failure (starting with ZERO backoff)
+
+	restartBackoff = time.Duration(0)
+
+Restart:
+
+	// Perform backoff and update for each restart (starting with ZERO backoff of course)
+
+	restartBackoff, err = utils.PerformDelayAndComputeNextDelay(restartBackoff, globals.tryLockBackoffMin, globals.tryLockBackoffMax)
+	if nil != err {
+		logger.Fatalf("MiddlewareRestoreVersion(): failed in restartBackoff: %v", err)
+	}
+
+	// Construct fresh heldLocks for this restart
+
+	heldLocks = newHeldLocks()
+
+	// Assemble WriteLocks on the version to be restored and its containing versions directory
+
+	versionsDirInodeNumber, versionInodeNumber, _, _, retryRequired, err =
+		mS.resolvePath(
+			inode.RootDirInodeNumber,
+			VersionsDirName+"/"+vContainerName+"/"+vObjectPath+"/"+versionID,
+			heldLocks,
+			resolvePathDirEntryInodeMustBeFile|
+				resolvePathRequireExclusiveLockOnDirEntryInode|
+				resolvePathRequireExclusiveLockOnDirInode)
+
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	// Assemble WriteLocks on the current version (creating it if necessary) and its containing directory
+
+	dirInodeNumber, dirEntryInodeNumber, dirEntryBasename, _, retryRequired, err =
+		mS.resolvePath(
+			inode.RootDirInodeNumber,
+			vContainerName+"/"+vObjectPath,
+			heldLocks,
+			resolvePathFollowDirSymlinks|
+				resolvePathCreateMissingPathElements|
+				resolvePathDirEntryInodeMustBeFile|
+				resolvePathRequireExclusiveLockOnDirEntryInode|
+				resolvePathRequireExclusiveLockOnDirInode)
+
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	// Archive (or discard) the current version to make way for the restored one
+
+	versioning, retryRequired, err = mS.fetchVersioningWhileLocked(heldLocks, vContainerName)
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	versionable, err = mS.isVersionableWhileLocked(dirEntryInodeNumber)
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	archiving = versionable && (VersioningModeNone != versioning.Mode)
+
+	if archiving {
+		pVersioning = &versioning
+	} else {
+		pVersioning = nil
+	}
+
+	_, archiveVersionID, pruneVersionIDs, pruneInodeNumbers, retryRequired, err =
+		mS.prepareArchiveWhileLocked(heldLocks, pVersioning, vContainerName, vObjectPath, versionID)
+	if nil != err {
+		heldLocks.free()
+		return
+	}
+
+	if retryRequired {
+		heldLocks.free()
+		goto Restart
+	}
+
+	// Move the current version aside (into the versions directory) first so that it may be put back
+	// should the restored version fail to take its place
+
+	inodeVolumeHandle = mS.volStruct.inodeVolumeHandle
+
+	err = inodeVolumeHandle.Move(dirInodeNumber, dirEntryBasename, versionsDirInodeNumber, archiveVersionID, inode.RenameNoReplace)
+	if nil != err {
+		heldLocks.free()
+		logger.ErrorfWithError(err, "MiddlewareRestoreVersion(): failed to move aside current version of %s/%s", vContainerName, vObjectPath)
+		return
+	}
+
+	err = inodeVolumeHandle.Move(versionsDirInodeNumber, versionID, dirInodeNumber, dirEntryBasename, inode.RenameNoReplace)
+	if nil != err {
+		logger.ErrorfWithError(err, "MiddlewareRestoreVersion(): failed to restore version %s of %s/%s", versionID, vContainerName, vObjectPath)
+		moveBackErr := inodeVolumeHandle.Move(versionsDirInodeNumber, archiveVersionID, dirInodeNumber, dirEntryBasename, inode.RenameNoReplace)
+		if nil != moveBackErr {
+			logger.ErrorfWithError(moveBackErr, "MiddlewareRestoreVersion(): failed to put back current version of %s/%s (left as version %s)", vContainerName, vObjectPath, archiveVersionID)
+		}
+		heldLocks.free()
+		return
+	}
+
+	mS.volStruct.expirationMovedWhileLocked(versionInodeNumber, dirInodeNumber, dirEntryBasename)
+
+	// Only now that the restored version is in place, drop (or keep as archived) the prior current version
+
+	if archiving {
+		mS.volStruct.expirationMovedWhileLocked(dirEntryInodeNumber, versionsDirInodeNumber, archiveVersionID)
+		mS.pruneVersionsWhileLocked(vContainerName, vObjectPath, versionsDirInodeNumber, pruneVersionIDs, pruneInodeNumbers)
+	} else {
+		err = mS.unlinkWhileLocked(versionsDirInodeNumber, archiveVersionID, dirEntryInodeNumber)
+		if nil != err {
+			logger.ErrorfWithError(err, "MiddlewareRestoreVersion(): failed to discard prior current version of %s/%s", vContainerName, vObjectPath)
+		}
+	}
+
+	heldLocks.free()
+
+	mS.volStruct.notifyNamespaceEvent(NotifyCreate, dirInodeNumber, dirEntryBasename, versionInodeNumber)
+
+	err = nil
+	return
+}
@@ -0,0 +1,243 @@
+package fs
+
+import (
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/inode"
+)
+
+func TestRestoreVersion(t *testing.T) {
+	var (
+		containerName      string            = "versions_test"
+		rootDirInodeNumber inode.InodeNumber = inode.RootDirInodeNumber
+	)
+
+	testSetup(t, false)
+
+	containerInodeNumber, err := testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, containerName, inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+
+	err = testMountStruct.MiddlewareSetVersioning(containerName, VersioningModeHistory, 0)
+	if nil != err {
+		t.Fatalf("MiddlewareSetVersioning() returned error: %v", err)
+	}
+
+	createObject := func(contents string) {
+		fileInodeNumber, err := testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "obj", inode.PosixModePerm)
+		if nil != err {
+			t.Fatalf("Create() returned error: %v", err)
+		}
+		_, err = testMountStruct.Write(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, 0, []byte(contents), nil)
+		if nil != err {
+			t.Fatalf("Write() returned error: %v", err)
+		}
+	}
+
+	fetchVersionIDs := func(expectedNumVersions int) (versionIDs []string) {
+		versions, err := testMountStruct.MiddlewareListVersions(containerName, "obj")
+		if nil != err {
+			t.Fatalf("MiddlewareListVersions() returned error: %v", err)
+		}
+		if expectedNumVersions != len(versions) {
+			t.Fatalf("MiddlewareListVersions() returned %v versions... expected %v", len(versions), expectedNumVersions)
+		}
+		versionIDs = make([]string, 0, len(versions))
+		for _, version := range versions {
+			versionIDs = append(versionIDs, version.VersionID)
+		}
+		return
+	}
+
+	verifyObject := func(expectedContents string) {
+		fileInodeNumber, err := testMountStruct.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "obj")
+		if nil != err {
+			t.Fatalf("Lookup() returned error: %v", err)
+		}
+		buf, err := testMountStruct.Read(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, 0, 1024, nil)
+		if nil != err {
+			t.Fatalf("Read() returned error: %v", err)
+		}
+		if expectedContents != string(buf) {
+			t.Fatalf("Read() returned \"%s\"... expected \"%s\"", string(buf), expectedContents)
+		}
+	}
+
+	// Archive "one" by deleting it, then replace it with "two"
+
+	createObject("one")
+
+	err = testMountStruct.MiddlewareDelete(containerName, "obj")
+	if nil != err {
+		t.Fatalf("MiddlewareDelete() returned error: %v", err)
+	}
+
+	versionIDs := fetchVersionIDs(1)
+
+	createObject("two")
+
+	// Restoring "one" archives "two" in its place
+
+	err = testMountStruct.MiddlewareRestoreVersion(containerName, "obj", versionIDs[0])
+	if nil != err {
+		t.Fatalf("MiddlewareRestoreVersion() returned error: %v", err)
+	}
+
+	verifyObject("one")
+	versionIDs = fetchVersionIDs(1)
+
+	// Restoring a missing version must leave the current version intact
+
+	err = testMountStruct.MiddlewareRestoreVersion(containerName, "obj", "0000000000000001")
+	if nil == err {
+		t.Fatalf("MiddlewareRestoreVersion() of a missing version should have failed")
+	}
+
+	verifyObject("one")
+	fetchVersionIDs(1)
+
+	// Without versioning, restoring "two" discards "one"
+
+	err = testMountStruct.MiddlewareSetVersioning(containerName, VersioningModeNone, 0)
+	if nil != err {
+		t.Fatalf("MiddlewareSetVersioning() returned error: %v", err)
+	}
+
+	err = testMountStruct.MiddlewareRestoreVersion(containerName, "obj", versionIDs[0])
+	if nil != err {
+		t.Fatalf("MiddlewareRestoreVersion() returned error: %v", err)
+	}
+
+	verifyObject("two")
+	fetchVersionIDs(0)
+
+	testTeardown(t)
+}
+
+func TestVersionsDirHidden(t *testing.T) {
+	var (
+		containerName      string            = "versions_hidden_test"
+		rootDirInodeNumber inode.InodeNumber = inode.RootDirInodeNumber
+	)
+
+	testSetup(t, false)
+
+	containerInodeNumber, err := testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, containerName, inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Mkdir() returned error: %v", err)
+	}
+
+	err = testMountStruct.MiddlewareSetVersioning(containerName, VersioningModeHistory, 0)
+	if nil != err {
+		t.Fatalf("MiddlewareSetVersioning() returned error: %v", err)
+	}
+
+	fileInodeNumber, err := testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerInodeNumber, "obj", inode.PosixModePerm)
+	if nil != err {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	_, err = testMountStruct.Write(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInodeNumber, 0, []byte("obj"), nil)
+	if nil != err {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+
+	// Archiving obj creates VersionsDirName
+
+	err = testMountStruct.MiddlewareDelete(containerName, "obj")
+	if nil != err {
+		t.Fatalf("MiddlewareDelete() returned error: %v", err)
+	}
+
+	_, err = testMountStruct.volStruct.inodeVolumeHandle.Lookup(rootDirInodeNumber, VersionsDirName)
+	if nil != err {
+		t.Fatalf("inode.Lookup() of %s returned error: %v", VersionsDirName, err)
+	}
+
+	// Yet it is invisible to the POSIX APIs
+
+	_, err = testMountStruct.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, VersionsDirName)
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("Lookup() of %s should have returned NotFoundError... got: %v", VersionsDirName, err)
+	}
+
+	_, err = testMountStruct.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, VersionsDirName+"/"+containerName)
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("LookupPath() into %s should have returned NotFoundError... got: %v", VersionsDirName, err)
+	}
+
+	// ...including when reading the root directory one entry at a time
+
+	dirEntries, _, _, err := testMountStruct.Readdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, 0)
+	if nil != err {
+		t.Fatalf("Readdir() returned error: %v", err)
+	}
+	for _, dirEntry := range dirEntries {
+		if VersionsDirName == dirEntry.Basename {
+			t.Fatalf("Readdir() returned %s", VersionsDirName)
+		}
+	}
+
+	prevReturned := ""
+	numEntries := 0
+	for {
+		dirEntries, _, areMoreEntries, err := testMountStruct.Readdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, 1, prevReturned)
+		if nil != err {
+			t.Fatalf("Readdir() returned error: %v", err)
+		}
+		for _, dirEntry := range dirEntries {
+			if VersionsDirName == dirEntry.Basename {
+				t.Fatalf("Readdir() returned %s", VersionsDirName)
+			}
+			prevReturned = dirEntry.Basename
+			numEntries++
+		}
+		if !areMoreEntries {
+			break
+		}
+		if 0 == len(dirEntries) {
+			t.Fatalf("Readdir() returned no entries yet areMoreEntries")
+		}
+	}
+	if len(dirEntries) != numEntries {
+		t.Fatalf("Readdir() one at a time returned %v entries... expected %v", numEntries, len(dirEntries))
+	}
+
+	// ...and may not be created, removed, or renamed
+
+	verifyNotPerm := func(op string, err error) {
+		if !blunder.Is(err, blunder.NotPermError) {
+			t.Fatalf("%s of %s should have returned NotPermError... got: %v", op, VersionsDirName, err)
+		}
+	}
+
+	_, err = testMountStruct.Create(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, VersionsDirName, inode.PosixModePerm)
+	verifyNotPerm("Create()", err)
+	_, err = testMountStruct.Mkdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, VersionsDirName, inode.PosixModePerm)
+	verifyNotPerm("Mkdir()", err)
+	_, err = testMountStruct.Symlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, VersionsDirName, containerName)
+	verifyNotPerm("Symlink()", err)
+	err = testMountStruct.Link(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, VersionsDirName, containerInodeNumber)
+	verifyNotPerm("Link()", err)
+	err = testMountStruct.Rmdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, VersionsDirName)
+	verifyNotPerm("Rmdir()", err)
+	err = testMountStruct.Unlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, VersionsDirName)
+	verifyNotPerm("Unlink()", err)
+	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, VersionsDirName, rootDirInodeNumber, "renamed", 0)
+	verifyNotPerm("Rename() from", err)
+	err = testMountStruct.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, rootDirInodeNumber, containerName, rootDirInodeNumber, VersionsDirName, 0)
+	verifyNotPerm("Rename() onto", err)
+
+	// The middleware continues to find the archived version
+
+	versions, err := testMountStruct.MiddlewareListVersions(containerName, "obj")
+	if nil != err {
+		t.Fatalf("MiddlewareListVersions() returned error: %v", err)
+	}
+	if 1 != len(versions) {
+		t.Fatalf("MiddlewareListVersions() returned %v versions... expected 1", len(versions))
+	}
+
+	testTeardown(t)
+}
@@ -267,7 +267,8 @@ func (d Dir) Create(ctx context.Context, req *fuselib.CreateRequest, resp *fusel
 		return nil, nil, err
 	}
 	file := File{mountHandle: d.mountHandle, inodeNumber: inodeNumber}
-	return file, file, nil
+	handle := File{mountHandle: d.mountHandle, inodeNumber: inodeNumber, readAheadState: &inode.ReadAheadState{}}
+	return file, handle, nil
 }
 
 func (d Dir) Flush(ctx context.Context, req *fuselib.FlushRequest) error {
@@ -314,7 +315,7 @@ func (d Dir) Rename(ctx context.Context, req *fuselib.RenameRequest, newDir fuse
 	if !ok {
 		return fuselib.EIO
 	}
-	err := d.mountHandle.Rename(inode.InodeUserID(req.Header.Uid), inode.InodeGroupID(req.Header.Gid), nil, d.inodeNumber, req.OldName, dstDir.inodeNumber, req.NewName)
+	err := d.mountHandle.Rename(inode.InodeUserID(req.Header.Uid), inode.InodeGroupID(req.Header.Gid), nil, d.inodeNumber, req.OldName, dstDir.inodeNumber, req.NewName, inode.RenameFlags(req.Flags))
 	if err != nil {
 		err = newFuseError(err)
 	}
@@ -3,20 +3,37 @@ package fuse
 import (
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"syscall"
 	"time"
 
 	fuselib "bazil.org/fuse"
+	fusefslib "bazil.org/fuse/fs"
 	"golang.org/x/net/context"
 
 	"github.com/swiftstack/ProxyFS/blunder"
 	"github.com/swiftstack/ProxyFS/fs"
 	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/qos"
+)
+
+// Whence values of fuselib.LseekRequest (from lseek(2)).
+const (
+	seekData = 3 // SEEK_DATA
+	seekHole = 4 // SEEK_HOLE
 )
 
 type File struct {
-	mountHandle fs.MountHandle
-	inodeNumber inode.InodeNumber
+	mountHandle    fs.MountHandle
+	inodeNumber    inode.InodeNumber
+	readAheadState *inode.ReadAheadState // nil unless File is serving as an open file handle
+}
+
+// Open returns a copy of f (serving as the open file handle) with its own read-ahead state.
+func (f File) Open(ctx context.Context, req *fuselib.OpenRequest, resp *fuselib.OpenResponse) (fusefslib.Handle, error) {
+	f.readAheadState = &inode.ReadAheadState{}
+	return f, nil
 }
 
 func (f File) Access(ctx context.Context, req *fuselib.AccessRequest) error {
@@ -155,10 +172,12 @@ func (f File) Fsync(ctx context.Context, req *fuselib.FsyncRequest) error {
 }
 
 func (f File) Read(ctx context.Context, req *fuselib.ReadRequest, resp *fuselib.ReadResponse) (err error) {
+	qos.Wait(f.mountHandle, req.Header.Uid, qos.Read, uint64(req.Size))
+
 	enterGate()
 	defer leaveGate()
 
-	buf, err := f.mountHandle.Read(inode.InodeUserID(req.Header.Uid), inode.InodeGroupID(req.Header.Gid), nil, f.inodeNumber, uint64(req.Offset), uint64(req.Size), nil)
+	buf, err := f.mountHandle.ReadWithReadAhead(inode.InodeUserID(req.Header.Uid), inode.InodeGroupID(req.Header.Gid), nil, f.inodeNumber, uint64(req.Offset), uint64(req.Size), f.readAheadState, nil)
 	if err != nil && err != io.EOF {
 		err = newFuseError(err)
 		return err
@@ -167,7 +186,88 @@ func (f File) Read(ctx context.Context, req *fuselib.ReadRequest, resp *fuselib.
 	return nil
 }
 
+func (f File) CopyFileRange(ctx context.Context, req *fuselib.CopyFileRangeRequest, handleOut fusefslib.Handle, resp *fuselib.CopyFileRangeResponse) (err error) {
+	var (
+		copiedLength uint64
+		fileOut      File
+		length       uint64
+		ok           bool
+	)
+
+	qos.Wait(f.mountHandle, req.Header.Uid, qos.Read, req.Length)
+	qos.Wait(f.mountHandle, req.Header.Uid, qos.Write, req.Length)
+
+	enterGate()
+	defer leaveGate()
+
+	fileOut, ok = handleOut.(File)
+	if !ok {
+		err = fuselib.Errno(syscall.EINVAL)
+		return
+	}
+
+	// The reply can only convey a uint32 count of bytes copied
+
+	length = req.Length
+	if length > math.MaxUint32 {
+		length = math.MaxUint32
+	}
+
+	copiedLength, err = f.mountHandle.CopyFileRange(inode.InodeUserID(req.Header.Uid), inode.InodeGroupID(req.Header.Gid), nil, f.inodeNumber, req.Offset, fileOut.inodeNumber, req.OffsetOut, length)
+	if nil != err {
+		err = newFuseError(err)
+		return
+	}
+
+	resp.Size = int(copiedLength)
+	return
+}
+
+func (f File) Fallocate(ctx context.Context, req *fuselib.FallocateRequest) (err error) {
+	enterGate()
+	defer leaveGate()
+
+	err = f.mountHandle.Fallocate(inode.InodeUserID(req.Header.Uid), inode.InodeGroupID(req.Header.Gid), nil, f.inodeNumber, inode.FallocateMode(req.Mode), req.Offset, req.Length)
+	if nil != err {
+		err = newFuseError(err)
+	}
+	return
+}
+
+func (f File) Lseek(ctx context.Context, req *fuselib.LseekRequest, resp *fuselib.LseekResponse) (err error) {
+	var (
+		newOffset uint64
+	)
+
+	enterGate()
+	defer leaveGate()
+
+	if 0 > req.Offset {
+		err = fuselib.Errno(syscall.ENXIO)
+		return
+	}
+
+	switch req.Whence {
+	case seekData:
+		newOffset, err = f.mountHandle.SeekData(inode.InodeUserID(req.Header.Uid), inode.InodeGroupID(req.Header.Gid), nil, f.inodeNumber, uint64(req.Offset))
+	case seekHole:
+		newOffset, err = f.mountHandle.SeekHole(inode.InodeUserID(req.Header.Uid), inode.InodeGroupID(req.Header.Gid), nil, f.inodeNumber, uint64(req.Offset))
+	default:
+		err = fuselib.Errno(syscall.EINVAL)
+		return
+	}
+	if nil != err {
+		err = newFuseError(err)
+		return
+	}
+
+	resp.Offset = int64(newOffset)
+	return
+}
+
 func (f File) Write(ctx context.Context, req *fuselib.WriteRequest, resp *fuselib.WriteResponse) error {
+	qos.Wait(f.mountHandle, req.Header.Uid, qos.Write, uint64(len(req.Data)))
+
 	enterGate()
 	defer leaveGate()
 
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/swiftstack/sortedmap"
+
+	"github.com/swiftstack/ProxyFS/keyprovider"
 )
 
 type BPlusTreeType uint32
@@ -17,8 +19,17 @@ const (
 	BPlusTreeObjectBPlusTree
 	CreatedObjectsBPlusTree
 	DeletedObjectsBPlusTree
+	FingerprintRecBPlusTree
+	ExpiryRecBPlusTree
 )
 
+// A LogSegmentRec's value begins with the name of the container holding the LogSegment. The caller
+// (e.g. package inode) may follow it with a LogSegmentRecMetadataSeparator (a NUL, which Swift never
+// permits in a container name) and metadata describing the LogSegment that is opaque to headhunter.
+// Only the container name is retained (in order to eventually DELETE the LogSegment) once the
+// LogSegmentRec has been deleted.
+const LogSegmentRecMetadataSeparator = byte(0x00)
+
 type SnapShotIDType uint8
 
 const (
@@ -42,6 +53,7 @@ type VolumeHandle interface {
 	RegisterForEvents(listener VolumeEventListener)
 	UnregisterForEvents(listener VolumeEventListener)
 	FetchAccountAndCheckpointContainerNames() (accountName string, checkpointContainerName string)
+	FetchDataKey() (dataKey keyprovider.DataKey) // Returns nil if the volume is not encrypted
 	FetchNonce() (nonce uint64, err error)
 	GetInodeRec(inodeNumber uint64) (value []byte, ok bool, err error)
 	PutInodeRec(inodeNumber uint64, value []byte) (err error)
@@ -52,6 +64,13 @@ type VolumeHandle interface {
 	PutLogSegmentRec(logSegmentNumber uint64, value []byte) (err error)
 	DeleteLogSegmentRec(logSegmentNumber uint64) (err error)
 	IndexedLogSegmentNumber(index uint64) (logSegmentNumber uint64, ok bool, err error)
+	GetFingerprintRec(fingerprint uint64) (value []byte, ok bool, err error)
+	PutFingerprintRec(fingerprint uint64, value []byte) (err error)
+	DeleteFingerprintRec(fingerprint uint64) (err error)
+	GetExpiryRec(expireAt uint64, inodeNumber uint64) (value []byte, ok bool, err error)
+	PutExpiryRec(expireAt uint64, inodeNumber uint64, value []byte) (err error)
+	DeleteExpiryRec(expireAt uint64, inodeNumber uint64) (err error)
+	IndexedExpiryRec(index uint64) (expireAt uint64, inodeNumber uint64, value []byte, ok bool, err error) // In order of expireAt
 	GetBPlusTreeObject(objectNumber uint64) (value []byte, err error)
 	PutBPlusTreeObject(objectNumber uint64, value []byte) (err error)
 	DeleteBPlusTreeObject(objectNumber uint64) (err error)
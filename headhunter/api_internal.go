@@ -1,6 +1,7 @@
 package headhunter
 
 import (
+	"bytes"
 	"container/list"
 	"fmt"
 	"sync"
@@ -9,6 +10,7 @@ import (
 	"github.com/swiftstack/sortedmap"
 
 	"github.com/swiftstack/ProxyFS/evtlog"
+	"github.com/swiftstack/ProxyFS/keyprovider"
 	"github.com/swiftstack/ProxyFS/logger"
 	"github.com/swiftstack/ProxyFS/swiftclient"
 )
@@ -57,6 +59,11 @@ func (volume *volumeStruct) FetchAccountAndCheckpointContainerNames() (accountNa
 	return
 }
 
+func (volume *volumeStruct) FetchDataKey() (dataKey keyprovider.DataKey) {
+	dataKey = volume.dataKey
+	return
+}
+
 func (volume *volumeStruct) fetchNextCheckPointDoneWaitGroupWhileLocked() (wg *sync.WaitGroup) {
 	if nil == volume.checkpointDoneWaitGroup {
 		volume.checkpointDoneWaitGroup = &sync.WaitGroup{}
@@ -84,12 +91,7 @@ func (volume *volumeStruct) fetchNonceWhileLocked() (nonce uint64, err error) {
 		// TODO: Move this inside recordTransaction() once it is a, uh, transaction :-)
 		evtlog.Record(evtlog.FormatHeadhunterRecordTransactionNonceRangeReserve, volume.volumeName, volume.nextNonce, newReservedToNonce-1)
 
-		checkpointHeaderValue = fmt.Sprintf("%016X %016X %016X %016X",
-			volume.checkpointHeader.checkpointVersion,
-			volume.checkpointHeader.checkpointObjectTrailerStructObjectNumber,
-			volume.checkpointHeader.checkpointObjectTrailerStructObjectLength,
-			newReservedToNonce,
-		)
+		checkpointHeaderValue = volume.checkpointHeader.format(newReservedToNonce)
 
 		checkpointHeaderValues = []string{checkpointHeaderValue}
 
@@ -345,6 +347,18 @@ func (volume *volumeStruct) IndexedInodeNumber(index uint64) (inodeNumber uint64
 	return
 }
 
+// logSegmentRecContainerName returns the container name with which a LogSegmentRec's value begins
+// (i.e. stripping any metadata following a LogSegmentRecMetadataSeparator).
+func logSegmentRecContainerName(value []byte) (containerName []byte) {
+	separatorIndex := bytes.IndexByte(value, LogSegmentRecMetadataSeparator)
+	if 0 > separatorIndex {
+		containerName = value
+	} else {
+		containerName = value[:separatorIndex]
+	}
+	return
+}
+
 func (volume *volumeStruct) GetLogSegmentRec(logSegmentNumber uint64) (value []byte, err error) {
 
 	startTime := time.Now()
@@ -419,7 +433,7 @@ func (volume *volumeStruct) PutLogSegmentRec(logSegmentNumber uint64, value []by
 	}
 
 	if nil != volume.priorView {
-		_, err = volume.priorView.createdObjectsWrapper.bPlusTree.Put(logSegmentNumber, valueToTree)
+		_, err = volume.priorView.createdObjectsWrapper.bPlusTree.Put(logSegmentNumber, logSegmentRecContainerName(valueToTree))
 		if nil != err {
 			volume.Unlock()
 			return
@@ -459,6 +473,7 @@ func (volume *volumeStruct) DeleteLogSegmentRec(logSegmentNumber uint64) (err er
 		err = fmt.Errorf("Missing logSegmentNumber (0x%016X) in volume %v LogSegmentRec B+Tree", logSegmentNumber, volume.volumeName)
 		return
 	}
+	containerNameAsValue = logSegmentRecContainerName(containerNameAsValue.([]byte))
 
 	_, err = volume.liveView.logSegmentRecWrapper.bPlusTree.DeleteByKey(logSegmentNumber)
 	if nil != err {
@@ -520,6 +535,234 @@ func (volume *volumeStruct) IndexedLogSegmentNumber(index uint64) (logSegmentNum
 	return
 }
 
+func (volume *volumeStruct) GetFingerprintRec(fingerprint uint64) (value []byte, ok bool, err error) {
+
+	startTime := time.Now()
+	defer func() {
+		globals.GetFingerprintRecUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.GetFingerprintRecErrors.Add(1)
+		}
+	}()
+
+	volume.Lock()
+
+	valueAsValue, ok, err := volume.liveView.fingerprintRecWrapper.bPlusTree.GetByKey(fingerprint)
+	if nil != err {
+		volume.Unlock()
+		return
+	}
+	if !ok {
+		volume.Unlock()
+		return
+	}
+	valueFromTree := valueAsValue.([]byte)
+	value = make([]byte, len(valueFromTree))
+	copy(value, valueFromTree)
+
+	volume.Unlock()
+
+	err = nil
+	return
+}
+
+func (volume *volumeStruct) PutFingerprintRec(fingerprint uint64, value []byte) (err error) {
+
+	startTime := time.Now()
+	defer func() {
+		globals.PutFingerprintRecUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.PutFingerprintRecErrors.Add(1)
+		}
+	}()
+
+	valueToTree := make([]byte, len(value))
+	copy(valueToTree, value)
+
+	volume.Lock()
+
+	ok, err := volume.liveView.fingerprintRecWrapper.bPlusTree.PatchByKey(fingerprint, valueToTree)
+	if nil != err {
+		volume.Unlock()
+		return
+	}
+	if !ok {
+		_, err = volume.liveView.fingerprintRecWrapper.bPlusTree.Put(fingerprint, valueToTree)
+		if nil != err {
+			volume.Unlock()
+			return
+		}
+	}
+
+	volume.recordTransaction(transactionPutFingerprintRec, fingerprint, value)
+
+	volume.Unlock()
+
+	err = nil
+	return
+}
+
+func (volume *volumeStruct) DeleteFingerprintRec(fingerprint uint64) (err error) {
+	var (
+		ok bool
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.DeleteFingerprintRecUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.DeleteFingerprintRecErrors.Add(1)
+		}
+	}()
+
+	volume.Lock()
+	defer volume.Unlock()
+
+	ok, err = volume.liveView.fingerprintRecWrapper.bPlusTree.DeleteByKey(fingerprint)
+	if nil != err {
+		return
+	}
+	if !ok {
+		err = fmt.Errorf("Missing fingerprint (0x%016X) in volume %v FingerprintRec B+Tree", fingerprint, volume.volumeName)
+		return
+	}
+
+	volume.recordTransaction(transactionDeleteFingerprintRec, fingerprint, nil)
+
+	return
+}
+
+func (volume *volumeStruct) GetExpiryRec(expireAt uint64, inodeNumber uint64) (value []byte, ok bool, err error) {
+
+	startTime := time.Now()
+	defer func() {
+		globals.GetExpiryRecUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.GetExpiryRecErrors.Add(1)
+		}
+	}()
+
+	volume.Lock()
+
+	valueAsValue, ok, err := volume.liveView.expiryRecWrapper.bPlusTree.GetByKey(expiryRecKeyStruct{expireAt: expireAt, inodeNumber: inodeNumber})
+	if nil != err {
+		volume.Unlock()
+		return
+	}
+	if !ok {
+		volume.Unlock()
+		return
+	}
+	valueFromTree := valueAsValue.([]byte)
+	value = make([]byte, len(valueFromTree))
+	copy(value, valueFromTree)
+
+	volume.Unlock()
+
+	err = nil
+	return
+}
+
+func (volume *volumeStruct) PutExpiryRec(expireAt uint64, inodeNumber uint64, value []byte) (err error) {
+
+	startTime := time.Now()
+	defer func() {
+		globals.PutExpiryRecUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.PutExpiryRecErrors.Add(1)
+		}
+	}()
+
+	valueToTree := make([]byte, len(value))
+	copy(valueToTree, value)
+
+	key := expiryRecKeyStruct{expireAt: expireAt, inodeNumber: inodeNumber}
+
+	volume.Lock()
+
+	ok, err := volume.liveView.expiryRecWrapper.bPlusTree.PatchByKey(key, valueToTree)
+	if nil != err {
+		volume.Unlock()
+		return
+	}
+	if !ok {
+		_, err = volume.liveView.expiryRecWrapper.bPlusTree.Put(key, valueToTree)
+		if nil != err {
+			volume.Unlock()
+			return
+		}
+	}
+
+	volume.recordTransaction(transactionPutExpiryRec, key, value)
+
+	volume.Unlock()
+
+	err = nil
+	return
+}
+
+func (volume *volumeStruct) DeleteExpiryRec(expireAt uint64, inodeNumber uint64) (err error) {
+	var (
+		key = expiryRecKeyStruct{expireAt: expireAt, inodeNumber: inodeNumber}
+		ok  bool
+	)
+
+	startTime := time.Now()
+	defer func() {
+		globals.DeleteExpiryRecUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.DeleteExpiryRecErrors.Add(1)
+		}
+	}()
+
+	volume.Lock()
+	defer volume.Unlock()
+
+	ok, err = volume.liveView.expiryRecWrapper.bPlusTree.DeleteByKey(key)
+	if nil != err {
+		return
+	}
+	if !ok {
+		err = fmt.Errorf("Missing expireAt (%v) inodeNumber (0x%016X) in volume %v ExpiryRec B+Tree", expireAt, inodeNumber, volume.volumeName)
+		return
+	}
+
+	volume.recordTransaction(transactionDeleteExpiryRec, key, nil)
+
+	return
+}
+
+func (volume *volumeStruct) IndexedExpiryRec(index uint64) (expireAt uint64, inodeNumber uint64, value []byte, ok bool, err error) {
+
+	startTime := time.Now()
+	defer func() {
+		globals.IndexedExpiryRecUsec.Add(uint64(time.Since(startTime) / time.Microsecond))
+		if err != nil {
+			globals.IndexedExpiryRecErrors.Add(1)
+		}
+	}()
+
+	volume.Lock()
+	key, valueAsValue, ok, err := volume.liveView.expiryRecWrapper.bPlusTree.GetByIndex(int(index))
+	if nil != err {
+		volume.Unlock()
+		return
+	}
+	if !ok {
+		volume.Unlock()
+		return
+	}
+	valueFromTree := valueAsValue.([]byte)
+	value = make([]byte, len(valueFromTree))
+	copy(value, valueFromTree)
+	volume.Unlock()
+
+	expireAt = key.(expiryRecKeyStruct).expireAt
+	inodeNumber = key.(expiryRecKeyStruct).inodeNumber
+
+	return
+}
+
 func (volume *volumeStruct) GetBPlusTreeObject(objectNumber uint64) (value []byte, err error) {
 
 	startTime := time.Now()
@@ -702,6 +945,12 @@ func (volume *volumeStruct) fetchLayoutReport(treeType BPlusTreeType) (layoutRep
 	case DeletedObjectsBPlusTree:
 		treeName = "DeletedObjectObject"
 		treeWrapper = volume.liveView.deletedObjectsWrapper
+	case FingerprintRecBPlusTree:
+		treeName = "FingerprintRec"
+		treeWrapper = volume.liveView.fingerprintRecWrapper
+	case ExpiryRecBPlusTree:
+		treeName = "ExpiryRec"
+		treeWrapper = volume.liveView.expiryRecWrapper
 	default:
 		err = fmt.Errorf("fetchLayoutReport(treeType %d): bad tree type", treeType)
 		logger.ErrorfWithError(err, "volume '%s'", volume.volumeName)
@@ -782,7 +1031,7 @@ func (volume *volumeStruct) FetchLayoutReport(treeType BPlusTreeType) (layoutRep
 	defer volume.Unlock()
 
 	if MergedBPlusTree == treeType {
-		// First, accumulate the 5 B+Tree sortedmap.LayoutReport's
+		// First, accumulate the 7 B+Tree sortedmap.LayoutReport's
 
 		layoutReport, _, err = volume.fetchLayoutReport(InodeRecBPlusTree)
 		if nil != err {
@@ -836,6 +1085,30 @@ func (volume *volumeStruct) FetchLayoutReport(treeType BPlusTreeType) (layoutRep
 				layoutReport[objectNumber] = perTreeObjectBytes
 			}
 		}
+		perTreeLayoutReport, _, err = volume.fetchLayoutReport(FingerprintRecBPlusTree)
+		if nil != err {
+			return
+		}
+		for objectNumber, perTreeObjectBytes = range perTreeLayoutReport {
+			objectBytes, ok = layoutReport[objectNumber]
+			if ok {
+				layoutReport[objectNumber] = objectBytes + perTreeObjectBytes
+			} else {
+				layoutReport[objectNumber] = perTreeObjectBytes
+			}
+		}
+		perTreeLayoutReport, _, err = volume.fetchLayoutReport(ExpiryRecBPlusTree)
+		if nil != err {
+			return
+		}
+		for objectNumber, perTreeObjectBytes = range perTreeLayoutReport {
+			objectBytes, ok = layoutReport[objectNumber]
+			if ok {
+				layoutReport[objectNumber] = objectBytes + perTreeObjectBytes
+			} else {
+				layoutReport[objectNumber] = perTreeObjectBytes
+			}
+		}
 
 		// Now, add in the checkpointLayoutReport
 
@@ -44,6 +44,22 @@ func logsegmentRecPutGet(t *testing.T, volume VolumeHandle, key uint64, value []
 	}
 }
 
+func fingerprintRecPutGet(t *testing.T, volume VolumeHandle, key uint64, value []byte) {
+	err := volume.PutFingerprintRec(key, value)
+	if nil != err {
+		t.Fatalf("Failed to Put %d %s :	 %v", key, value, err)
+	}
+
+	value1, ok, err := volume.GetFingerprintRec(key)
+	if nil != err || !ok {
+		t.Fatalf("Failed to Get %d %s :	 %v", key, value, err)
+	}
+
+	if 0 != bytes.Compare(value, value1) {
+		t.Fatalf("Get Value does not match Inital Value: %d %v %v", key, value, err)
+	}
+}
+
 func putInodeRecsTest(t *testing.T, volume VolumeHandle) {
 	var keys []uint64
 	var values [][]byte
@@ -252,6 +268,13 @@ func TestHeadHunterAPI(t *testing.T) {
 		t.Fatalf("FetchNonce() [case 2] returned unexpected nonce: %v (should have been > %v)", secondUpNonce, firstUpNonce)
 	}
 
+	// No FingerprintRec's nor ExpiryRec's have been put (nor is TestVolume deduplicated)... so the
+	// checkpoint need not have recorded the fingerprintRec nor expiryRec B+Trees
+
+	if checkpointVersion3 != volume.(*volumeStruct).checkpointHeader.checkpointVersion {
+		t.Fatalf("checkpointVersion [case 2] should have been %v... was %v", checkpointVersion3, volume.(*volumeStruct).checkpointHeader.checkpointVersion)
+	}
+
 	key = 1234
 	value = []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
 
@@ -277,13 +300,136 @@ func TestHeadHunterAPI(t *testing.T) {
 		t.Fatalf("Delete of key %d failed: %v", key, err)
 	}
 
-	// Shutdown packages
+	// Ensure only the container name (not any following metadata) is recorded for a deleted LogSegment
+
+	value = append([]byte("TestContainer"), LogSegmentRecMetadataSeparator, 1, 2, 3)
+	logsegmentRecPutGet(t, volume, key+100, value)
+
+	err = volume.DeleteLogSegmentRec(key + 100)
+	if nil != err {
+		t.Fatalf("Delete of key %d failed: %v", key+100, err)
+	}
+
+	deletedObjectsValue, ok, err := volume.(*volumeStruct).liveView.deletedObjectsWrapper.bPlusTree.GetByKey(key + 100)
+	if nil != err {
+		t.Fatalf("deletedObjects GetByKey(%d) failed: %v", key+100, err)
+	}
+	if !ok {
+		t.Fatalf("deletedObjects GetByKey(%d) should have returned ok", key+100)
+	}
+	if "TestContainer" != string(deletedObjectsValue.([]byte)) {
+		t.Fatalf("deletedObjects GetByKey(%d) returned %q... expected \"TestContainer\"", key+100, deletedObjectsValue.([]byte))
+	}
+
+	_, ok, err = volume.GetFingerprintRec(key)
+	if nil != err {
+		t.Fatalf("GetFingerprintRec() of missing key %d failed: %v", key, err)
+	}
+	if ok {
+		t.Fatalf("GetFingerprintRec() of missing key %d should have returned !ok", key)
+	}
+
+	fingerprintRecPutGet(t, volume, key, value)
+
+	// Put ExpiryRec's out of expireAt order to ensure they are indexed in expireAt order
+
+	err = volume.PutExpiryRec(200, key, value)
+	if nil != err {
+		t.Fatalf("PutExpiryRec(200, %d) failed: %v", key, err)
+	}
+
+	err = volume.PutExpiryRec(100, key+1, value)
+	if nil != err {
+		t.Fatalf("PutExpiryRec(100, %d) failed: %v", key+1, err)
+	}
+
+	// Ensure FingerprintRec's & ExpiryRec's survive a restart
 
 	err = transitions.Down(confMap)
 	if nil != err {
 		t.Fatalf("transitions.Down() [case 2] returned error: %v", err)
 	}
 
+	err = transitions.Up(confMap)
+	if nil != err {
+		t.Fatalf("transitions.Up() [case 3] returned error: %v", err)
+	}
+
+	volume, err = FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") [case 3] returned error: %v", err)
+	}
+
+	if checkpointVersion5 != volume.(*volumeStruct).checkpointHeader.checkpointVersion {
+		t.Fatalf("checkpointVersion [case 3] should have been %v... was %v", checkpointVersion5, volume.(*volumeStruct).checkpointHeader.checkpointVersion)
+	}
+
+	value1, ok, err := volume.GetFingerprintRec(key)
+	if nil != err || !ok {
+		t.Fatalf("GetFingerprintRec() of key %d after restart failed: %v", key, err)
+	}
+	if 0 != bytes.Compare(value, value1) {
+		t.Fatalf("GetFingerprintRec() of key %d after restart returned unexpected value: %v", key, value1)
+	}
+
+	err = volume.DeleteFingerprintRec(key)
+	if nil != err {
+		t.Fatalf("DeleteFingerprintRec() of key %d failed: %v", key, err)
+	}
+
+	err = volume.DeleteFingerprintRec(key)
+	if nil == err {
+		t.Fatalf("DeleteFingerprintRec() of deleted key %d should have failed", key)
+	}
+
+	value1, ok, err = volume.GetExpiryRec(200, key)
+	if nil != err || !ok {
+		t.Fatalf("GetExpiryRec(200, %d) after restart failed: %v", key, err)
+	}
+	if 0 != bytes.Compare(value, value1) {
+		t.Fatalf("GetExpiryRec(200, %d) after restart returned unexpected value: %v", key, value1)
+	}
+
+	_, ok, err = volume.GetExpiryRec(100, key)
+	if nil != err || ok {
+		t.Fatalf("GetExpiryRec(100, %d) should have returned !ok (err: %v)", key, err)
+	}
+
+	expireAt, inodeNumber, _, ok, err := volume.IndexedExpiryRec(0)
+	if nil != err || !ok || 100 != expireAt || key+1 != inodeNumber {
+		t.Fatalf("IndexedExpiryRec(0) returned unexpected (%d, %d, %v, %v)", expireAt, inodeNumber, ok, err)
+	}
+	expireAt, inodeNumber, _, ok, err = volume.IndexedExpiryRec(1)
+	if nil != err || !ok || 200 != expireAt || key != inodeNumber {
+		t.Fatalf("IndexedExpiryRec(1) returned unexpected (%d, %d, %v, %v)", expireAt, inodeNumber, ok, err)
+	}
+	_, _, _, ok, err = volume.IndexedExpiryRec(2)
+	if nil != err || ok {
+		t.Fatalf("IndexedExpiryRec(2) should have returned !ok (err: %v)", err)
+	}
+
+	err = volume.DeleteExpiryRec(200, key)
+	if nil != err {
+		t.Fatalf("DeleteExpiryRec(200, %d) failed: %v", key, err)
+	}
+
+	_, ok, err = volume.GetExpiryRec(200, key)
+	if nil != err || ok {
+		t.Fatalf("GetExpiryRec(200, %d) of deleted ExpiryRec should have returned !ok (err: %v)", key, err)
+	}
+
+	err = volume.DeleteExpiryRec(100, key+1)
+	if nil != err {
+		t.Fatalf("DeleteExpiryRec(100, %d) failed: %v", key+1, err)
+	}
+
+	// Shutdown packages
+
+	err = transitions.Down(confMap)
+	if nil != err {
+		t.Fatalf("transitions.Down() [case 3] returned error: %v", err)
+	}
+
 	/*
 		// The following is now obsolete given the deprecation of ReplayLog in practice
 
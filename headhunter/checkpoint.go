@@ -2,6 +2,7 @@ package headhunter
 
 import (
 	"container/list"
+	"encoding/hex"
 	"fmt"
 	"hash/crc64"
 	"io"
@@ -17,6 +18,7 @@ import (
 
 	"github.com/swiftstack/ProxyFS/blunder"
 	"github.com/swiftstack/ProxyFS/evtlog"
+	"github.com/swiftstack/ProxyFS/keyprovider"
 	"github.com/swiftstack/ProxyFS/logger"
 	"github.com/swiftstack/ProxyFS/platform"
 	"github.com/swiftstack/ProxyFS/stats"
@@ -35,20 +37,46 @@ type uint64Struct struct {
 const (
 	checkpointVersion2 uint64 = iota + 2
 	checkpointVersion3
-	// uint64 in %016X indicating checkpointVersion2 or checkpointVersion3
+	checkpointVersion4
+	checkpointVersion5
+	// uint64 in %016X indicating checkpointVersion2, checkpointVersion3, checkpointVersion4, or checkpointVersion5
 	// ' '
 	// uint64 in %016X indicating objectNumber containing checkpoint record at tail of object
 	// ' '
 	// uint64 in %016X indicating length of               checkpoint record at tail of object
 	// ' '
 	// uint64 in %016X indicating reservedToNonce
+	// if the volume is encrypted:
+	//   ' '
+	//   string        indicating the KeyProvider keyID used to wrap the volume's data key
+	//   ' '
+	//   []byte in hex indicating the wrapped data key
 )
 
 type checkpointHeaderStruct struct {
-	checkpointVersion                         uint64 // either checkpointVersion2 or checkpointVersion3
+	checkpointVersion                         uint64 // either checkpointVersion2, checkpointVersion3, checkpointVersion4, or checkpointVersion5
 	checkpointObjectTrailerStructObjectNumber uint64 // checkpointObjectTrailerV?Struct found at "tail" of object
 	checkpointObjectTrailerStructObjectLength uint64 // this length includes appended non-fixed sized arrays
 	reservedToNonce                           uint64 // highest nonce value reserved
+	encryptionKeyID                           string // if != "", volume is encrypted with a data key wrapped by this keyID
+	wrappedDataKey                            []byte // the data key as wrapped by encryptionKeyID
+}
+
+// format returns the value of the CheckpointHeaderName header describing checkpointHeader
+// (but with the supplied reservedToNonce).
+func (checkpointHeader *checkpointHeaderStruct) format(reservedToNonce uint64) (checkpointHeaderValue string) {
+	checkpointHeaderValue = fmt.Sprintf("%016X %016X %016X %016X",
+		checkpointHeader.checkpointVersion,
+		checkpointHeader.checkpointObjectTrailerStructObjectNumber,
+		checkpointHeader.checkpointObjectTrailerStructObjectLength,
+		reservedToNonce,
+	)
+
+	if "" != checkpointHeader.encryptionKeyID {
+		checkpointHeaderValue += fmt.Sprintf(" %s %X", checkpointHeader.encryptionKeyID, checkpointHeader.wrappedDataKey)
+	}
+
+	return
 }
 
 type checkpointObjectTrailerV2Struct struct {
@@ -95,6 +123,28 @@ type checkpointObjectTrailerV3Struct struct {
 	// snapShotList                   serialized as [snapShotListNumElements                  ]elementOfSnapShotListStruct
 }
 
+type checkpointObjectTrailerV4ExtensionStruct struct { //  immediately follows checkpointObjectTrailerV3Struct if checkpointVersion4
+	FingerprintRecBPlusTreeObjectNumber      uint64 // if != 0, objectNumber-named Object in <accountName>.<checkpointContainerName> where root of fingerprintRec  B+Tree
+	FingerprintRecBPlusTreeObjectOffset      uint64 // ...and offset into the Object where root starts
+	FingerprintRecBPlusTreeObjectLength      uint64 // ...and length if that root node
+	FingerprintRecBPlusTreeLayoutNumElements uint64 // elements immediately follow deletedObjectsBPlusTreeLayout
+	// fingerprintRecBPlusTreeLayout  serialized as [FingerprintRecBPlusTreeLayoutNumElements ]elementOfBPlusTreeLayoutStruct
+	//                                                 (and is followed by the snapShotList described above)
+	//
+	// Note that the fingerprintRec B+Tree is only maintained for the liveView (i.e. SnapShots do not record it)
+}
+
+type checkpointObjectTrailerV5ExtensionStruct struct { //  immediately follows checkpointObjectTrailerV4ExtensionStruct if checkpointVersion5
+	ExpiryRecBPlusTreeObjectNumber      uint64 // if != 0, objectNumber-named Object in <accountName>.<checkpointContainerName> where root of expiryRec B+Tree
+	ExpiryRecBPlusTreeObjectOffset      uint64 // ...and offset into the Object where root starts
+	ExpiryRecBPlusTreeObjectLength      uint64 // ...and length if that root node
+	ExpiryRecBPlusTreeLayoutNumElements uint64 // elements immediately follow fingerprintRecBPlusTreeLayout
+	// expiryRecBPlusTreeLayout       serialized as [ExpiryRecBPlusTreeLayoutNumElements      ]elementOfBPlusTreeLayoutStruct
+	//                                                 (and is followed by the snapShotList described above)
+	//
+	// Note that the expiryRec B+Tree is only maintained for the liveView (i.e. SnapShots do not record it)
+}
+
 type elementOfBPlusTreeLayoutStruct struct {
 	ObjectNumber uint64
 	ObjectBytes  uint64
@@ -147,6 +197,10 @@ const (
 	transactionDeleteLogSegmentRec
 	transactionPutBPlusTreeObject
 	transactionDeleteBPlusTreeObject
+	transactionPutFingerprintRec
+	transactionDeleteFingerprintRec
+	transactionPutExpiryRec
+	transactionDeleteExpiryRec
 )
 
 type replayLogTransactionFixedPartStruct struct { //          transactions begin on a replayLogWriteBufferAlignment boundary
@@ -198,6 +252,7 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 	var (
 		bytesNeeded                  uint64
 		err                          error
+		expiryRecKey                 expiryRecKeyStruct
 		i                            int
 		multipleKeys                 []uint64
 		multipleValues               [][]byte
@@ -217,13 +272,21 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 	case transactionDeleteInodeRec:
 		evtlog.Record(evtlog.FormatHeadhunterRecordTransactionDeleteInodeRec, volume.volumeName, keys.(uint64))
 	case transactionPutLogSegmentRec:
-		evtlog.Record(evtlog.FormatHeadhunterRecordTransactionPutLogSegmentRec, volume.volumeName, keys.(uint64), string(values.([]byte)[:]))
+		evtlog.Record(evtlog.FormatHeadhunterRecordTransactionPutLogSegmentRec, volume.volumeName, keys.(uint64), string(logSegmentRecContainerName(values.([]byte))))
 	case transactionDeleteLogSegmentRec:
 		evtlog.Record(evtlog.FormatHeadhunterRecordTransactionDeleteLogSegmentRec, volume.volumeName, keys.(uint64))
 	case transactionPutBPlusTreeObject:
 		evtlog.Record(evtlog.FormatHeadhunterRecordTransactionPutBPlusTreeObject, volume.volumeName, keys.(uint64))
 	case transactionDeleteBPlusTreeObject:
 		evtlog.Record(evtlog.FormatHeadhunterRecordTransactionDeleteBPlusTreeObject, volume.volumeName, keys.(uint64))
+	case transactionPutFingerprintRec:
+		evtlog.Record(evtlog.FormatHeadhunterRecordTransactionPutFingerprintRec, volume.volumeName, keys.(uint64))
+	case transactionDeleteFingerprintRec:
+		evtlog.Record(evtlog.FormatHeadhunterRecordTransactionDeleteFingerprintRec, volume.volumeName, keys.(uint64))
+	case transactionPutExpiryRec:
+		evtlog.Record(evtlog.FormatHeadhunterRecordTransactionPutExpiryRec, volume.volumeName, keys.(expiryRecKeyStruct).inodeNumber)
+	case transactionDeleteExpiryRec:
+		evtlog.Record(evtlog.FormatHeadhunterRecordTransactionDeleteExpiryRec, volume.volumeName, keys.(expiryRecKeyStruct).inodeNumber)
 	default:
 		logger.Fatalf("headhunter.recordTransaction(transactionType==%v,,) invalid", transactionType)
 	}
@@ -319,6 +382,52 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 				globals.uint64Size + //               last checkpointHeaderStruct.checkpointObjectTrailerStructObjectNumber
 				globals.uint64Size + //               transactionType == transactionDeleteBPlusTreeObject
 				globals.uint64Size //                 objectNumber
+	case transactionPutFingerprintRec:
+		singleKey = keys.(uint64)
+		singleValue = values.([]byte)
+		bytesNeeded = //                              transactions begin on a replayLogWriteBufferAlignment boundary
+			globals.uint64Size + //                   checksum of everything after this field
+				globals.uint64Size + //               bytes following in this transaction
+				globals.uint64Size + //               last checkpointHeaderStruct.checkpointObjectTrailerStructObjectNumber
+				globals.uint64Size + //               transactionType == transactionPutFingerprintRec
+				globals.uint64Size + //               fingerprint
+				globals.uint64Size + //               len(value)
+				uint64(len(singleValue)) //           value
+	case transactionDeleteFingerprintRec:
+		singleKey = keys.(uint64)
+		if nil != values {
+			logger.Fatalf("headhunter.recordTransaction(transactionType==transactionDeleteFingerprintRec,,) passed non-nil values")
+		}
+		bytesNeeded = //                              transactions begin on a replayLogWriteBufferAlignment boundary
+			globals.uint64Size + //                   checksum of everything after this field
+				globals.uint64Size + //               bytes following in this transaction
+				globals.uint64Size + //               last checkpointHeaderStruct.checkpointObjectTrailerStructObjectNumber
+				globals.uint64Size + //               transactionType == transactionDeleteFingerprintRec
+				globals.uint64Size //                 fingerprint
+	case transactionPutExpiryRec:
+		expiryRecKey = keys.(expiryRecKeyStruct)
+		singleValue = values.([]byte)
+		bytesNeeded = //                              transactions begin on a replayLogWriteBufferAlignment boundary
+			globals.uint64Size + //                   checksum of everything after this field
+				globals.uint64Size + //               bytes following in this transaction
+				globals.uint64Size + //               last checkpointHeaderStruct.checkpointObjectTrailerStructObjectNumber
+				globals.uint64Size + //               transactionType == transactionPutExpiryRec
+				globals.uint64Size + //               expireAt
+				globals.uint64Size + //               inodeNumber
+				globals.uint64Size + //               len(value)
+				uint64(len(singleValue)) //           value
+	case transactionDeleteExpiryRec:
+		expiryRecKey = keys.(expiryRecKeyStruct)
+		if nil != values {
+			logger.Fatalf("headhunter.recordTransaction(transactionType==transactionDeleteExpiryRec,,) passed non-nil values")
+		}
+		bytesNeeded = //                              transactions begin on a replayLogWriteBufferAlignment boundary
+			globals.uint64Size + //                   checksum of everything after this field
+				globals.uint64Size + //               bytes following in this transaction
+				globals.uint64Size + //               last checkpointHeaderStruct.checkpointObjectTrailerStructObjectNumber
+				globals.uint64Size + //               transactionType == transactionDeleteExpiryRec
+				globals.uint64Size + //               expireAt
+				globals.uint64Size //                 inodeNumber
 	default:
 		logger.Fatalf("headhunter.recordTransaction(transactionType==%v,,) invalid", transactionType)
 	}
@@ -487,6 +596,80 @@ func (volume *volumeStruct) recordTransaction(transactionType uint64, keys inter
 		}
 		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
 		replayLogWriteBufferPosition += globals.uint64Size
+	case transactionPutFingerprintRec:
+		// Fill in fingerprint
+
+		packedUint64, err = cstruct.Pack(singleKey, LittleEndian)
+		if nil != err {
+			logger.Fatalf("cstruct.Pack() unexpectedly returned error: %v", err)
+		}
+		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
+		replayLogWriteBufferPosition += globals.uint64Size
+
+		// Fill in len(value) and value
+
+		packedUint64, err = cstruct.Pack(uint64(len(singleValue)), LittleEndian)
+		if nil != err {
+			logger.Fatalf("cstruct.Pack() unexpectedly returned error: %v", err)
+		}
+		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
+		replayLogWriteBufferPosition += globals.uint64Size
+
+		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], singleValue)
+		replayLogWriteBufferPosition += uint64(len(singleValue))
+	case transactionDeleteFingerprintRec:
+		// Fill in fingerprint
+
+		packedUint64, err = cstruct.Pack(singleKey, LittleEndian)
+		if nil != err {
+			logger.Fatalf("cstruct.Pack() unexpectedly returned error: %v", err)
+		}
+		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
+		replayLogWriteBufferPosition += globals.uint64Size
+	case transactionPutExpiryRec:
+		// Fill in expireAt and inodeNumber
+
+		packedUint64, err = cstruct.Pack(expiryRecKey.expireAt, LittleEndian)
+		if nil != err {
+			logger.Fatalf("cstruct.Pack() unexpectedly returned error: %v", err)
+		}
+		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
+		replayLogWriteBufferPosition += globals.uint64Size
+
+		packedUint64, err = cstruct.Pack(expiryRecKey.inodeNumber, LittleEndian)
+		if nil != err {
+			logger.Fatalf("cstruct.Pack() unexpectedly returned error: %v", err)
+		}
+		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
+		replayLogWriteBufferPosition += globals.uint64Size
+
+		// Fill in len(value) and value
+
+		packedUint64, err = cstruct.Pack(uint64(len(singleValue)), LittleEndian)
+		if nil != err {
+			logger.Fatalf("cstruct.Pack() unexpectedly returned error: %v", err)
+		}
+		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
+		replayLogWriteBufferPosition += globals.uint64Size
+
+		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], singleValue)
+		replayLogWriteBufferPosition += uint64(len(singleValue))
+	case transactionDeleteExpiryRec:
+		// Fill in expireAt and inodeNumber
+
+		packedUint64, err = cstruct.Pack(expiryRecKey.expireAt, LittleEndian)
+		if nil != err {
+			logger.Fatalf("cstruct.Pack() unexpectedly returned error: %v", err)
+		}
+		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
+		replayLogWriteBufferPosition += globals.uint64Size
+
+		packedUint64, err = cstruct.Pack(expiryRecKey.inodeNumber, LittleEndian)
+		if nil != err {
+			logger.Fatalf("cstruct.Pack() unexpectedly returned error: %v", err)
+		}
+		_ = copy(replayLogWriteBuffer[replayLogWriteBufferPosition:], packedUint64)
+		replayLogWriteBufferPosition += globals.uint64Size
 	default:
 		logger.Fatalf("headhunter.recordTransaction(transactionType==%v,,) invalid", transactionType)
 	}
@@ -560,7 +743,7 @@ func (volume *volumeStruct) fetchCheckpointLayoutReport() (layoutReport sortedma
 
 	checkpointHeaderValueSlice = strings.Split(checkpointHeaderValue, " ")
 
-	if 4 != len(checkpointHeaderValueSlice) {
+	if (4 != len(checkpointHeaderValueSlice)) && (6 != len(checkpointHeaderValueSlice)) {
 		err = fmt.Errorf("Cannot parse %v/%v header %v: %v (wrong number of fields)", volume.accountName, volume.checkpointContainerName, CheckpointHeaderName, checkpointHeaderValue)
 		return
 	}
@@ -600,13 +783,20 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 		checkpointObjectTrailerBuf                         []byte
 		checkpointObjectTrailerV2                          *checkpointObjectTrailerV2Struct
 		checkpointObjectTrailerV3                          *checkpointObjectTrailerV3Struct
+		checkpointObjectTrailerV4Extension                 *checkpointObjectTrailerV4ExtensionStruct
+		checkpointObjectTrailerV5Extension                 *checkpointObjectTrailerV5ExtensionStruct
 		computedCRC64                                      uint64
 		containerNameAsValue                               sortedmap.Value
 		createdObjectsWrapperBPlusTreeTracker              *bPlusTreeTrackerStruct
+		dataKeyBytes                                       []byte
 		defaultReplayLogReadBuffer                         []byte
 		deletedObjectsWrapperBPlusTreeTracker              *bPlusTreeTrackerStruct
 		elementOfBPlusTreeLayout                           elementOfBPlusTreeLayoutStruct
 		expectedCheckpointObjectTrailerSize                uint64
+		expireAt                                           uint64
+		expiryRecWrapperBPlusTreeTracker                   *bPlusTreeTrackerStruct
+		fingerprint                                        uint64
+		fingerprintRecWrapperBPlusTreeTracker              *bPlusTreeTrackerStruct
 		inodeIndex                                         uint64
 		inodeNumber                                        uint64
 		inodeRecWrapperBPlusTreeTracker                    *bPlusTreeTrackerStruct
@@ -675,12 +865,26 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 
 			checkpointHeader.reservedToNonce = firstNonceToProvide // First FetchNonce() will trigger a reserve step
 
-			checkpointHeaderValue = fmt.Sprintf("%016X %016X %016X %016X",
-				checkpointHeader.checkpointVersion,
-				checkpointHeader.checkpointObjectTrailerStructObjectNumber,
-				checkpointHeader.checkpointObjectTrailerStructObjectLength,
-				checkpointHeader.reservedToNonce,
-			)
+			if nil != volume.keyProvider {
+				// Encryption may only be enabled at format time... so generate & wrap the volume's data key now
+
+				checkpointHeader.encryptionKeyID, err = volume.keyProvider.CurrentKeyID()
+				if nil != err {
+					return
+				}
+
+				dataKeyBytes, err = keyprovider.GenerateDataKey()
+				if nil != err {
+					return
+				}
+
+				checkpointHeader.wrappedDataKey, err = volume.keyProvider.WrapKey(checkpointHeader.encryptionKeyID, dataKeyBytes)
+				if nil != err {
+					return
+				}
+			}
+
+			checkpointHeaderValue = checkpointHeader.format(checkpointHeader.reservedToNonce)
 
 			checkpointHeaderValues = []string{checkpointHeaderValue}
 
@@ -717,7 +921,7 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 
 	checkpointHeaderValueSlice = strings.Split(checkpointHeaderValue, " ")
 
-	if 4 != len(checkpointHeaderValueSlice) {
+	if (4 != len(checkpointHeaderValueSlice)) && (6 != len(checkpointHeaderValueSlice)) {
 		err = fmt.Errorf("Cannot parse %v/%v header %v: %v (wrong number of fields)", volume.accountName, volume.checkpointContainerName, CheckpointHeaderName, checkpointHeaderValue)
 		return
 	}
@@ -747,8 +951,63 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 		return
 	}
 
+	if 6 == len(checkpointHeaderValueSlice) {
+		volume.checkpointHeader.encryptionKeyID = checkpointHeaderValueSlice[4]
+
+		volume.checkpointHeader.wrappedDataKey, err = hex.DecodeString(checkpointHeaderValueSlice[5])
+		if nil != err {
+			err = fmt.Errorf("Cannot parse %v/%v header %v: %v (bad wrappedDataKey)", volume.accountName, volume.checkpointContainerName, CheckpointHeaderName, checkpointHeaderValue)
+			return
+		}
+
+		if nil == volume.keyProvider {
+			err = fmt.Errorf("Volume %v is encrypted (keyID %v) but has no EncryptionKeyProvider", volume.volumeName, volume.checkpointHeader.encryptionKeyID)
+			return
+		}
+
+		dataKeyBytes, err = volume.keyProvider.UnwrapKey(volume.checkpointHeader.encryptionKeyID, volume.checkpointHeader.wrappedDataKey)
+		if nil != err {
+			err = fmt.Errorf("Volume %v data key could not be unwrapped: %v", volume.volumeName, err)
+			return
+		}
+
+		volume.dataKey, err = keyprovider.NewDataKey(dataKeyBytes)
+		if nil != err {
+			return
+		}
+	} else {
+		if nil != volume.keyProvider {
+			err = fmt.Errorf("Volume %v was not formatted with encryption so cannot specify an EncryptionKeyProvider", volume.volumeName)
+			return
+		}
+
+		volume.dataKey = nil
+	}
+
 	volume.liveView = &volumeViewStruct{volume: volume}
 
+	// The fingerprintRec B+Tree only appears in checkpointVersion4 checkpoints... it starts out empty otherwise
+
+	checkpointObjectTrailerV4Extension = &checkpointObjectTrailerV4ExtensionStruct{
+		FingerprintRecBPlusTreeObjectNumber:      0,
+		FingerprintRecBPlusTreeObjectOffset:      0,
+		FingerprintRecBPlusTreeObjectLength:      0,
+		FingerprintRecBPlusTreeLayoutNumElements: 0,
+	}
+
+	fingerprintRecWrapperBPlusTreeTracker = &bPlusTreeTrackerStruct{bPlusTreeLayout: make(sortedmap.LayoutReport)}
+
+	// Similarly, the expiryRec B+Tree only appears in checkpointVersion5 checkpoints
+
+	checkpointObjectTrailerV5Extension = &checkpointObjectTrailerV5ExtensionStruct{
+		ExpiryRecBPlusTreeObjectNumber:      0,
+		ExpiryRecBPlusTreeObjectOffset:      0,
+		ExpiryRecBPlusTreeObjectLength:      0,
+		ExpiryRecBPlusTreeLayoutNumElements: 0,
+	}
+
+	expiryRecWrapperBPlusTreeTracker = &bPlusTreeTrackerStruct{bPlusTreeLayout: make(sortedmap.LayoutReport)}
+
 	if checkpointVersion2 == volume.checkpointHeader.checkpointVersion {
 		if 0 == volume.checkpointHeader.checkpointObjectTrailerStructObjectNumber {
 			// Initialize based on zero-filled checkpointObjectTrailerV2Struct
@@ -1066,7 +1325,7 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 		for snapShotID = uint64(1); snapShotID < volume.dotSnapShotDirSnapShotID; snapShotID++ {
 			volume.availableSnapShotIDList.PushBack(snapShotID)
 		}
-	} else if checkpointVersion3 == volume.checkpointHeader.checkpointVersion {
+	} else if (checkpointVersion3 == volume.checkpointHeader.checkpointVersion) || (checkpointVersion4 == volume.checkpointHeader.checkpointVersion) || (checkpointVersion5 == volume.checkpointHeader.checkpointVersion) {
 		if 0 == volume.checkpointHeader.checkpointObjectTrailerStructObjectNumber {
 			// Initialize based on zero-filled checkpointObjectTrailerV3Struct
 
@@ -1203,6 +1462,22 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 			}
 			checkpointObjectTrailerBuf = checkpointObjectTrailerBuf[bytesConsumed:]
 
+			if (checkpointVersion4 == volume.checkpointHeader.checkpointVersion) || (checkpointVersion5 == volume.checkpointHeader.checkpointVersion) {
+				bytesConsumed, err = cstruct.Unpack(checkpointObjectTrailerBuf, checkpointObjectTrailerV4Extension, LittleEndian)
+				if nil != err {
+					return
+				}
+				checkpointObjectTrailerBuf = checkpointObjectTrailerBuf[bytesConsumed:]
+			}
+
+			if checkpointVersion5 == volume.checkpointHeader.checkpointVersion {
+				bytesConsumed, err = cstruct.Unpack(checkpointObjectTrailerBuf, checkpointObjectTrailerV5Extension, LittleEndian)
+				if nil != err {
+					return
+				}
+				checkpointObjectTrailerBuf = checkpointObjectTrailerBuf[bytesConsumed:]
+			}
+
 			// Load liveView.{inodeRec|logSegmentRec|bPlusTreeObject}Wrapper B+Trees
 
 			inodeRecWrapperBPlusTreeTracker = &bPlusTreeTrackerStruct{bPlusTreeLayout: make(sortedmap.LayoutReport)}
@@ -1326,6 +1601,8 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 			expectedCheckpointObjectTrailerSize += checkpointObjectTrailerV3.BPlusTreeObjectBPlusTreeLayoutNumElements
 			expectedCheckpointObjectTrailerSize += checkpointObjectTrailerV3.CreatedObjectsBPlusTreeLayoutNumElements
 			expectedCheckpointObjectTrailerSize += checkpointObjectTrailerV3.DeletedObjectsBPlusTreeLayoutNumElements
+			expectedCheckpointObjectTrailerSize += checkpointObjectTrailerV4Extension.FingerprintRecBPlusTreeLayoutNumElements
+			expectedCheckpointObjectTrailerSize += checkpointObjectTrailerV5Extension.ExpiryRecBPlusTreeLayoutNumElements
 			expectedCheckpointObjectTrailerSize *= globals.elementOfBPlusTreeLayoutStructSize
 			expectedCheckpointObjectTrailerSize += checkpointObjectTrailerV3.SnapShotListTotalSize
 
@@ -1386,6 +1663,26 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 				volume.liveView.deletedObjectsWrapper.bPlusTreeTracker.bPlusTreeLayout[elementOfBPlusTreeLayout.ObjectNumber] = elementOfBPlusTreeLayout.ObjectBytes
 			}
 
+			for layoutReportIndex = 0; layoutReportIndex < checkpointObjectTrailerV4Extension.FingerprintRecBPlusTreeLayoutNumElements; layoutReportIndex++ {
+				bytesConsumed, err = cstruct.Unpack(checkpointObjectTrailerBuf, &elementOfBPlusTreeLayout, LittleEndian)
+				if nil != err {
+					return
+				}
+				checkpointObjectTrailerBuf = checkpointObjectTrailerBuf[bytesConsumed:]
+
+				fingerprintRecWrapperBPlusTreeTracker.bPlusTreeLayout[elementOfBPlusTreeLayout.ObjectNumber] = elementOfBPlusTreeLayout.ObjectBytes
+			}
+
+			for layoutReportIndex = 0; layoutReportIndex < checkpointObjectTrailerV5Extension.ExpiryRecBPlusTreeLayoutNumElements; layoutReportIndex++ {
+				bytesConsumed, err = cstruct.Unpack(checkpointObjectTrailerBuf, &elementOfBPlusTreeLayout, LittleEndian)
+				if nil != err {
+					return
+				}
+				checkpointObjectTrailerBuf = checkpointObjectTrailerBuf[bytesConsumed:]
+
+				expiryRecWrapperBPlusTreeTracker.bPlusTreeLayout[elementOfBPlusTreeLayout.ObjectNumber] = elementOfBPlusTreeLayout.ObjectBytes
+			}
+
 			// Compute SnapShotID shortcuts
 
 			volume.snapShotIDShift = uint64(64) - uint64(volume.snapShotIDNumBits)
@@ -1880,6 +2177,62 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 		return
 	}
 
+	// Load (or initialize) liveView.fingerprintRecWrapper B+Tree
+
+	volume.liveView.fingerprintRecWrapper = &bPlusTreeWrapperStruct{
+		volumeView:       volume.liveView,
+		bPlusTreeTracker: fingerprintRecWrapperBPlusTreeTracker,
+	}
+
+	if 0 == checkpointObjectTrailerV4Extension.FingerprintRecBPlusTreeObjectNumber {
+		volume.liveView.fingerprintRecWrapper.bPlusTree =
+			sortedmap.NewBPlusTree(
+				volume.maxFingerprintsPerMetadataNode,
+				sortedmap.CompareUint64,
+				volume.liveView.fingerprintRecWrapper,
+				globals.logSegmentRecCache)
+	} else {
+		volume.liveView.fingerprintRecWrapper.bPlusTree, err =
+			sortedmap.OldBPlusTree(
+				checkpointObjectTrailerV4Extension.FingerprintRecBPlusTreeObjectNumber,
+				checkpointObjectTrailerV4Extension.FingerprintRecBPlusTreeObjectOffset,
+				checkpointObjectTrailerV4Extension.FingerprintRecBPlusTreeObjectLength,
+				sortedmap.CompareUint64,
+				volume.liveView.fingerprintRecWrapper,
+				globals.logSegmentRecCache)
+		if nil != err {
+			return
+		}
+	}
+
+	// Load (or initialize) liveView.expiryRecWrapper B+Tree
+
+	volume.liveView.expiryRecWrapper = &bPlusTreeWrapperStruct{
+		volumeView:       volume.liveView,
+		bPlusTreeTracker: expiryRecWrapperBPlusTreeTracker,
+	}
+
+	if 0 == checkpointObjectTrailerV5Extension.ExpiryRecBPlusTreeObjectNumber {
+		volume.liveView.expiryRecWrapper.bPlusTree =
+			sortedmap.NewBPlusTree(
+				volume.maxExpiryRecsPerMetadataNode,
+				compareExpiryRecKey,
+				&expiryRecCallbacksStruct{volume.liveView.expiryRecWrapper},
+				globals.inodeRecCache)
+	} else {
+		volume.liveView.expiryRecWrapper.bPlusTree, err =
+			sortedmap.OldBPlusTree(
+				checkpointObjectTrailerV5Extension.ExpiryRecBPlusTreeObjectNumber,
+				checkpointObjectTrailerV5Extension.ExpiryRecBPlusTreeObjectOffset,
+				checkpointObjectTrailerV5Extension.ExpiryRecBPlusTreeObjectLength,
+				compareExpiryRecKey,
+				&expiryRecCallbacksStruct{volume.liveView.expiryRecWrapper},
+				globals.inodeRecCache)
+		if nil != err {
+			return
+		}
+	}
+
 	volume.maxNonce = (1 << (64 - volume.snapShotIDNumBits)) - 1
 	volume.nextNonce = volume.checkpointHeader.reservedToNonce
 
@@ -2060,7 +2413,7 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 				}
 			}
 			if nil != volume.priorView {
-				_, err = volume.priorView.createdObjectsWrapper.bPlusTree.Put(logSegmentNumber, value)
+				_, err = volume.priorView.createdObjectsWrapper.bPlusTree.Put(logSegmentNumber, logSegmentRecContainerName(value))
 				if nil != err {
 					logger.Fatalf("Reply Log for Volume %s hit unexpected volume.priorView.createdObjectsWrapper.bPlusTree.Put() failure: %v", volume.volumeName, err)
 				}
@@ -2077,6 +2430,7 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 			if !ok {
 				logger.Fatalf("Replay Log for Volume %s hit unexpected missing logSegmentNumber (0x%016X) in LogSegmentRecB+Tree", volume.volumeName, logSegmentNumber)
 			}
+			containerNameAsValue = logSegmentRecContainerName(containerNameAsValue.([]byte))
 			_, err = volume.liveView.logSegmentRecWrapper.bPlusTree.DeleteByKey(logSegmentNumber)
 			if nil != err {
 				logger.Fatalf("Reply Log for Volume %s hit unexpected volume.liveView.logSegmentRecWrapper.bPlusTree.DeleteByKey() failure: %v", volume.volumeName, err)
@@ -2135,6 +2489,80 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 			if nil != err {
 				logger.Fatalf("Reply Log for Volume %s hit unexpected volume.liveView.bPlusTreeObjectWrapper.bPlusTree.DeleteByKey() failure: %v", volume.volumeName, err)
 			}
+		case transactionPutFingerprintRec:
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &fingerprint, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+			replayLogReadBufferPosition += globals.uint64Size
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &valueLen, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+			replayLogReadBufferPosition += globals.uint64Size
+			value = make([]byte, valueLen)
+			copy(value, replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+valueLen])
+			ok, err = volume.liveView.fingerprintRecWrapper.bPlusTree.PatchByKey(fingerprint, value)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected volume.liveView.fingerprintRecWrapper.bPlusTree.PatchByKey() failure: %v", volume.volumeName, err)
+			}
+			if !ok {
+				_, err = volume.liveView.fingerprintRecWrapper.bPlusTree.Put(fingerprint, value)
+				if nil != err {
+					logger.Fatalf("Reply Log for Volume %s hit unexpected volume.liveView.fingerprintRecWrapper.bPlusTree.Put() failure: %v", volume.volumeName, err)
+				}
+			}
+		case transactionDeleteFingerprintRec:
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &fingerprint, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+			_, err = volume.liveView.fingerprintRecWrapper.bPlusTree.DeleteByKey(fingerprint)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected volume.liveView.fingerprintRecWrapper.bPlusTree.DeleteByKey() failure: %v", volume.volumeName, err)
+			}
+		case transactionPutExpiryRec:
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &expireAt, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+			replayLogReadBufferPosition += globals.uint64Size
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &inodeNumber, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+			replayLogReadBufferPosition += globals.uint64Size
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &valueLen, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+			replayLogReadBufferPosition += globals.uint64Size
+			value = make([]byte, valueLen)
+			copy(value, replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+valueLen])
+			ok, err = volume.liveView.expiryRecWrapper.bPlusTree.PatchByKey(expiryRecKeyStruct{expireAt: expireAt, inodeNumber: inodeNumber}, value)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected volume.liveView.expiryRecWrapper.bPlusTree.PatchByKey() failure: %v", volume.volumeName, err)
+			}
+			if !ok {
+				_, err = volume.liveView.expiryRecWrapper.bPlusTree.Put(expiryRecKeyStruct{expireAt: expireAt, inodeNumber: inodeNumber}, value)
+				if nil != err {
+					logger.Fatalf("Reply Log for Volume %s hit unexpected volume.liveView.expiryRecWrapper.bPlusTree.Put() failure: %v", volume.volumeName, err)
+				}
+			}
+		case transactionDeleteExpiryRec:
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &expireAt, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+			replayLogReadBufferPosition += globals.uint64Size
+			_, err = cstruct.Unpack(replayLogReadBuffer[replayLogReadBufferPosition:replayLogReadBufferPosition+globals.uint64Size], &inodeNumber, LittleEndian)
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected cstruct.Unpack() failure: %v", volume.volumeName, err)
+			}
+			_, err = volume.liveView.expiryRecWrapper.bPlusTree.DeleteByKey(expiryRecKeyStruct{expireAt: expireAt, inodeNumber: inodeNumber})
+			if nil != err {
+				logger.Fatalf("Reply Log for Volume %s hit unexpected volume.liveView.expiryRecWrapper.bPlusTree.DeleteByKey() failure: %v", volume.volumeName, err)
+			}
 		default:
 			// Corruption in replayLogTransactionFixedPart - so exit as if Replay Log ended here
 
@@ -2157,6 +2585,39 @@ func (volume *volumeStruct) getCheckpoint(autoFormat bool) (err error) {
 	return
 }
 
+// checkpointVersionToPut returns the checkpointVersion of the next checkpoint. The fingerprintRec
+// B+Tree is only recorded (requiring checkpointVersion4) if the volume is deduplicated or the B+Tree
+// has been used. Similarly, the expiryRec B+Tree is only recorded (requiring checkpointVersion5) once
+// it has been used. Once recorded, either B+Tree will continue to be (lest its contents be forgotten).
+func (volume *volumeStruct) checkpointVersionToPut() (checkpointVersion uint64, err error) {
+	var (
+		expiryRecCount      int
+		fingerprintRecCount int
+	)
+
+	checkpointVersion = checkpointVersion3
+
+	fingerprintRecCount, err = volume.liveView.fingerprintRecWrapper.bPlusTree.Len()
+	if nil != err {
+		return
+	}
+
+	if volume.deduplication || (0 < fingerprintRecCount) || (checkpointVersion4 == volume.checkpointHeader.checkpointVersion) {
+		checkpointVersion = checkpointVersion4
+	}
+
+	expiryRecCount, err = volume.liveView.expiryRecWrapper.bPlusTree.Len()
+	if nil != err {
+		return
+	}
+
+	if (0 < expiryRecCount) || (checkpointVersion5 == volume.checkpointHeader.checkpointVersion) {
+		checkpointVersion = checkpointVersion5
+	}
+
+	return
+}
+
 func (volume *volumeStruct) putCheckpoint() (err error) {
 	var (
 		bytesUsedCumulative                                uint64
@@ -2165,9 +2626,14 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 		checkpointHeaderValue                              string
 		checkpointHeaderValues                             []string
 		checkpointObjectTrailer                            *checkpointObjectTrailerV3Struct
+		checkpointObjectTrailerV4Extension                 *checkpointObjectTrailerV4ExtensionStruct
+		checkpointObjectTrailerV5Extension                 *checkpointObjectTrailerV5ExtensionStruct
 		checkpointObjectTrailerBeginningOffset             uint64
 		checkpointObjectTrailerEndingOffset                uint64
 		checkpointTrailerBuf                               []byte
+		checkpointTrailerV4ExtensionBuf                    []byte
+		checkpointTrailerV5ExtensionBuf                    []byte
+		checkpointVersion                                  uint64
 		combinedBPlusTreeLayout                            sortedmap.LayoutReport
 		containerNameAsByteSlice                           []byte
 		containerNameAsValue                               sortedmap.Value
@@ -2230,7 +2696,14 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 		volumeViewIndex                                    int
 	)
 
+	checkpointVersion, err = volume.checkpointVersionToPut()
+	if nil != err {
+		return
+	}
+
 	checkpointObjectTrailer = &checkpointObjectTrailerV3Struct{}
+	checkpointObjectTrailerV4Extension = &checkpointObjectTrailerV4ExtensionStruct{}
+	checkpointObjectTrailerV5Extension = &checkpointObjectTrailerV5ExtensionStruct{}
 
 	checkpointObjectTrailer.InodeRecBPlusTreeObjectNumber,
 		checkpointObjectTrailer.InodeRecBPlusTreeObjectOffset,
@@ -2253,6 +2726,24 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 	if nil != err {
 		return
 	}
+	if checkpointVersion4 <= checkpointVersion {
+		checkpointObjectTrailerV4Extension.FingerprintRecBPlusTreeObjectNumber,
+			checkpointObjectTrailerV4Extension.FingerprintRecBPlusTreeObjectOffset,
+			checkpointObjectTrailerV4Extension.FingerprintRecBPlusTreeObjectLength,
+			err = volume.liveView.fingerprintRecWrapper.bPlusTree.Flush(false)
+		if nil != err {
+			return
+		}
+	}
+	if checkpointVersion5 == checkpointVersion {
+		checkpointObjectTrailerV5Extension.ExpiryRecBPlusTreeObjectNumber,
+			checkpointObjectTrailerV5Extension.ExpiryRecBPlusTreeObjectOffset,
+			checkpointObjectTrailerV5Extension.ExpiryRecBPlusTreeObjectLength,
+			err = volume.liveView.expiryRecWrapper.bPlusTree.Flush(false)
+		if nil != err {
+			return
+		}
+	}
 
 	volumeViewCount, err = volume.viewTreeByNonce.Len()
 	if nil != err {
@@ -2348,18 +2839,38 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 	if nil != err {
 		return
 	}
+	if checkpointVersion4 <= checkpointVersion {
+		err = volume.liveView.fingerprintRecWrapper.bPlusTree.Prune()
+		if nil != err {
+			return
+		}
+	}
+	if checkpointVersion5 == checkpointVersion {
+		err = volume.liveView.expiryRecWrapper.bPlusTree.Prune()
+		if nil != err {
+			return
+		}
+	}
 
 	checkpointObjectTrailer.InodeRecBPlusTreeLayoutNumElements = uint64(len(volume.liveView.inodeRecWrapper.bPlusTreeTracker.bPlusTreeLayout))
 	checkpointObjectTrailer.LogSegmentRecBPlusTreeLayoutNumElements = uint64(len(volume.liveView.logSegmentRecWrapper.bPlusTreeTracker.bPlusTreeLayout))
 	checkpointObjectTrailer.BPlusTreeObjectBPlusTreeLayoutNumElements = uint64(len(volume.liveView.bPlusTreeObjectWrapper.bPlusTreeTracker.bPlusTreeLayout))
 	checkpointObjectTrailer.CreatedObjectsBPlusTreeLayoutNumElements = uint64(len(volume.liveView.createdObjectsWrapper.bPlusTreeTracker.bPlusTreeLayout))
 	checkpointObjectTrailer.DeletedObjectsBPlusTreeLayoutNumElements = uint64(len(volume.liveView.deletedObjectsWrapper.bPlusTreeTracker.bPlusTreeLayout))
+	if checkpointVersion4 <= checkpointVersion {
+		checkpointObjectTrailerV4Extension.FingerprintRecBPlusTreeLayoutNumElements = uint64(len(volume.liveView.fingerprintRecWrapper.bPlusTreeTracker.bPlusTreeLayout))
+	}
+	if checkpointVersion5 == checkpointVersion {
+		checkpointObjectTrailerV5Extension.ExpiryRecBPlusTreeLayoutNumElements = uint64(len(volume.liveView.expiryRecWrapper.bPlusTreeTracker.bPlusTreeLayout))
+	}
 
 	treeLayoutBufSize = checkpointObjectTrailer.InodeRecBPlusTreeLayoutNumElements
 	treeLayoutBufSize += checkpointObjectTrailer.LogSegmentRecBPlusTreeLayoutNumElements
 	treeLayoutBufSize += checkpointObjectTrailer.BPlusTreeObjectBPlusTreeLayoutNumElements
 	treeLayoutBufSize += checkpointObjectTrailer.CreatedObjectsBPlusTreeLayoutNumElements
 	treeLayoutBufSize += checkpointObjectTrailer.DeletedObjectsBPlusTreeLayoutNumElements
+	treeLayoutBufSize += checkpointObjectTrailerV4Extension.FingerprintRecBPlusTreeLayoutNumElements
+	treeLayoutBufSize += checkpointObjectTrailerV5Extension.ExpiryRecBPlusTreeLayoutNumElements
 	treeLayoutBufSize *= globals.elementOfBPlusTreeLayoutStructSize
 
 	treeLayoutBuf = make([]byte, 0, treeLayoutBufSize)
@@ -2404,6 +2915,26 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 		treeLayoutBuf = append(treeLayoutBuf, elementOfBPlusTreeLayoutBuf...)
 	}
 
+	if checkpointVersion4 <= checkpointVersion {
+		for elementOfBPlusTreeLayout.ObjectNumber, elementOfBPlusTreeLayout.ObjectBytes = range volume.liveView.fingerprintRecWrapper.bPlusTreeTracker.bPlusTreeLayout {
+			elementOfBPlusTreeLayoutBuf, err = cstruct.Pack(&elementOfBPlusTreeLayout, LittleEndian)
+			if nil != err {
+				logger.Fatalf("cstruct.Pack(&elementOfBPlusTreeLayout, LittleEndian) for volume %v fingerprintRec failed: %v", volume.volumeName, err)
+			}
+			treeLayoutBuf = append(treeLayoutBuf, elementOfBPlusTreeLayoutBuf...)
+		}
+	}
+
+	if checkpointVersion5 == checkpointVersion {
+		for elementOfBPlusTreeLayout.ObjectNumber, elementOfBPlusTreeLayout.ObjectBytes = range volume.liveView.expiryRecWrapper.bPlusTreeTracker.bPlusTreeLayout {
+			elementOfBPlusTreeLayoutBuf, err = cstruct.Pack(&elementOfBPlusTreeLayout, LittleEndian)
+			if nil != err {
+				logger.Fatalf("cstruct.Pack(&elementOfBPlusTreeLayout, LittleEndian) for volume %v expiryRec failed: %v", volume.volumeName, err)
+			}
+			treeLayoutBuf = append(treeLayoutBuf, elementOfBPlusTreeLayoutBuf...)
+		}
+	}
+
 	checkpointObjectTrailer.SnapShotIDNumBits = uint64(volume.snapShotIDNumBits)
 
 	checkpointObjectTrailer.SnapShotListNumElements = uint64(volumeViewCount)
@@ -2567,6 +3098,20 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 		return
 	}
 
+	if checkpointVersion4 <= checkpointVersion {
+		checkpointTrailerV4ExtensionBuf, err = cstruct.Pack(checkpointObjectTrailerV4Extension, LittleEndian)
+		if nil != err {
+			return
+		}
+	}
+
+	if checkpointVersion5 == checkpointVersion {
+		checkpointTrailerV5ExtensionBuf, err = cstruct.Pack(checkpointObjectTrailerV5Extension, LittleEndian)
+		if nil != err {
+			return
+		}
+	}
+
 	err = volume.openCheckpointChunkedPutContextIfNecessary()
 	if nil != err {
 		return
@@ -2582,6 +3127,20 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 		return
 	}
 
+	if checkpointVersion4 <= checkpointVersion {
+		err = volume.sendChunkToCheckpointChunkedPutContext(checkpointTrailerV4ExtensionBuf)
+		if nil != err {
+			return
+		}
+	}
+
+	if checkpointVersion5 == checkpointVersion {
+		err = volume.sendChunkToCheckpointChunkedPutContext(checkpointTrailerV5ExtensionBuf)
+		if nil != err {
+			return
+		}
+	}
+
 	err = volume.sendChunkToCheckpointChunkedPutContext(treeLayoutBuf)
 	if nil != err {
 		return
@@ -2614,17 +3173,12 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 
 	// Now update checkpointHeader atomically indicating checkpoint is complete
 
-	volume.checkpointHeader.checkpointVersion = checkpointVersion3
+	volume.checkpointHeader.checkpointVersion = checkpointVersion
 
 	volume.checkpointHeader.checkpointObjectTrailerStructObjectNumber = volume.checkpointChunkedPutContextObjectNumber
 	volume.checkpointHeader.checkpointObjectTrailerStructObjectLength = checkpointObjectTrailerEndingOffset - checkpointObjectTrailerBeginningOffset
 
-	checkpointHeaderValue = fmt.Sprintf("%016X %016X %016X %016X",
-		volume.checkpointHeader.checkpointVersion,
-		volume.checkpointHeader.checkpointObjectTrailerStructObjectNumber,
-		volume.checkpointHeader.checkpointObjectTrailerStructObjectLength,
-		volume.checkpointHeader.reservedToNonce,
-	)
+	checkpointHeaderValue = volume.checkpointHeader.format(volume.checkpointHeader.reservedToNonce)
 
 	checkpointHeaderValues = []string{checkpointHeaderValue}
 
@@ -2698,6 +3252,22 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 			combinedBPlusTreeLayout[objectNumber] = bytesUsedThisBPlusTree
 		}
 	}
+	for objectNumber, bytesUsedThisBPlusTree = range volume.liveView.fingerprintRecWrapper.bPlusTreeTracker.bPlusTreeLayout {
+		bytesUsedCumulative, ok = combinedBPlusTreeLayout[objectNumber]
+		if ok {
+			combinedBPlusTreeLayout[objectNumber] = bytesUsedCumulative + bytesUsedThisBPlusTree
+		} else {
+			combinedBPlusTreeLayout[objectNumber] = bytesUsedThisBPlusTree
+		}
+	}
+	for objectNumber, bytesUsedThisBPlusTree = range volume.liveView.expiryRecWrapper.bPlusTreeTracker.bPlusTreeLayout {
+		bytesUsedCumulative, ok = combinedBPlusTreeLayout[objectNumber]
+		if ok {
+			combinedBPlusTreeLayout[objectNumber] = bytesUsedCumulative + bytesUsedThisBPlusTree
+		} else {
+			combinedBPlusTreeLayout[objectNumber] = bytesUsedThisBPlusTree
+		}
+	}
 
 	logSegmentObjectsToDelete, err = volume.liveView.deletedObjectsWrapper.bPlusTree.Len()
 	if nil != err {
@@ -2713,6 +3283,8 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 			delete(volume.liveView.bPlusTreeObjectWrapper.bPlusTreeTracker.bPlusTreeLayout, objectNumber)
 			delete(volume.liveView.createdObjectsWrapper.bPlusTreeTracker.bPlusTreeLayout, objectNumber)
 			delete(volume.liveView.deletedObjectsWrapper.bPlusTreeTracker.bPlusTreeLayout, objectNumber)
+			delete(volume.liveView.fingerprintRecWrapper.bPlusTreeTracker.bPlusTreeLayout, objectNumber)
+			delete(volume.liveView.expiryRecWrapper.bPlusTreeTracker.bPlusTreeLayout, objectNumber)
 
 			if nil == volume.priorView {
 				delayedObjectDeleteList = append(delayedObjectDeleteList, delayedObjectDeleteStruct{containerName: volume.checkpointContainerName, objectNumber: objectNumber})
@@ -2755,7 +3327,9 @@ func (volume *volumeStruct) putCheckpoint() (err error) {
 			logger.Fatalf("containerNameAsValue.([]byte) returned !ok")
 		}
 
-		delayedObjectDeleteList = append(delayedObjectDeleteList, delayedObjectDeleteStruct{containerName: string(containerNameAsByteSlice[:]), objectNumber: objectNumber})
+		// Entries recorded before DeleteLogSegmentRec() stripped LogSegmentRec metadata may still carry it
+
+		delayedObjectDeleteList = append(delayedObjectDeleteList, delayedObjectDeleteStruct{containerName: string(logSegmentRecContainerName(containerNameAsByteSlice)), objectNumber: objectNumber})
 
 		ok, err = volume.liveView.deletedObjectsWrapper.bPlusTree.DeleteByIndex(0)
 		if nil != err {
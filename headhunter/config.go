@@ -12,7 +12,9 @@ import (
 	"github.com/swiftstack/sortedmap"
 
 	"github.com/swiftstack/ProxyFS/bucketstats"
+	"github.com/swiftstack/ProxyFS/cachebudget"
 	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/keyprovider"
 	"github.com/swiftstack/ProxyFS/swiftclient"
 	"github.com/swiftstack/ProxyFS/trackedlock"
 	"github.com/swiftstack/ProxyFS/transitions"
@@ -26,6 +28,10 @@ const (
 	liveSnapShotID = uint64(0)
 )
 
+const (
+	bPlusTreeNodeBytesEstimate = uint64(4096) // Initial estimate (refined as nodes are fetched) of B+Tree node size for cachebudget
+)
+
 const (
 	AccountHeaderName           = "X-ProxyFS-BiModal"
 	AccountHeaderNameTranslated = "X-Account-Sysmeta-Proxyfs-Bimodal"
@@ -41,7 +47,7 @@ type bPlusTreeTrackerStruct struct {
 type bPlusTreeWrapperStruct struct {
 	volumeView       *volumeViewStruct
 	bPlusTree        sortedmap.BPlusTree
-	bPlusTreeTracker *bPlusTreeTrackerStruct // For inodeRecWrapper, logSegmentRecWrapper, & bPlusTreeObjectWrapper:
+	bPlusTreeTracker *bPlusTreeTrackerStruct // For inodeRecWrapper, logSegmentRecWrapper, bPlusTreeObjectWrapper, fingerprintRecWrapper, & expiryRecWrapper:
 	//                                            only valid for liveView... nil otherwise
 	//                                          For createdObjectsWrapper & deletedObjectsWrapper:
 	//                                            all volumeView's share the corresponding one created for liveView
@@ -58,6 +64,8 @@ type volumeViewStruct struct {
 	inodeRecWrapper        *bPlusTreeWrapperStruct
 	logSegmentRecWrapper   *bPlusTreeWrapperStruct
 	bPlusTreeObjectWrapper *bPlusTreeWrapperStruct
+	fingerprintRecWrapper  *bPlusTreeWrapperStruct //  only maintained for the liveView... nil otherwise
+	expiryRecWrapper       *bPlusTreeWrapperStruct //  only maintained for the liveView... nil otherwise
 	createdObjectsWrapper  *bPlusTreeWrapperStruct // if volumeView is     the liveView, should be empty
 	//                                                if volumeView is not the liveView, tracks objects created between this and the next volumeView
 	deletedObjectsWrapper *bPlusTreeWrapperStruct //  if volumeView is     the liveView, tracks objects to be deleted at next checkpoint
@@ -115,6 +123,9 @@ type volumeStruct struct {
 	maxLogSegmentsPerMetadataNode           uint64
 	maxDirFileNodesPerMetadataNode          uint64
 	maxCreatedDeletedObjectsPerMetadataNode uint64
+	maxFingerprintsPerMetadataNode          uint64
+	maxExpiryRecsPerMetadataNode            uint64
+	deduplication                           bool //                 if true, the fingerprintRec B+Tree is always recorded
 	checkpointContainerName                 string
 	checkpointContainerStoragePolicy        string
 	checkpointInterval                      time.Duration
@@ -122,6 +133,9 @@ type volumeStruct struct {
 	replayLogFile                           *os.File //           opened on first Put or Delete after checkpoint
 	//                                                            closed/deleted on successful checkpoint
 
+	keyProvider keyprovider.KeyProvider // if != nil, used to wrap/unwrap dataKey
+	dataKey     keyprovider.DataKey     // if != nil, B+Tree nodes (and LogSegments) are encrypted
+
 	volumeGroup *volumeGroupStruct
 	served      bool
 
@@ -163,15 +177,19 @@ type globalsStruct struct {
 	elementOfBPlusTreeLayoutStructSize      uint64
 	replayLogTransactionFixedPartStructSize uint64
 
+	inodeRecCacheBudget                        *cachebudget.BPlusTreeCache
 	inodeRecCache                              sortedmap.BPlusTreeCache
 	inodeRecCachePriorCacheHits                uint64
 	inodeRecCachePriorCacheMisses              uint64
+	logSegmentRecCacheBudget                   *cachebudget.BPlusTreeCache
 	logSegmentRecCache                         sortedmap.BPlusTreeCache
 	logSegmentRecCachePriorCacheHits           uint64
 	logSegmentRecCachePriorCacheMisses         uint64
+	bPlusTreeObjectCacheBudget                 *cachebudget.BPlusTreeCache
 	bPlusTreeObjectCache                       sortedmap.BPlusTreeCache
 	bPlusTreeObjectCachePriorCacheHits         uint64
 	bPlusTreeObjectCachePriorCacheMisses       uint64
+	createdDeletedObjectsCacheBudget           *cachebudget.BPlusTreeCache
 	createdDeletedObjectsCache                 sortedmap.BPlusTreeCache
 	createdDeletedObjectsCachePriorCacheHits   uint64
 	createdDeletedObjectsCachePriorCacheMisses uint64
@@ -192,6 +210,13 @@ type globalsStruct struct {
 	PutLogSegmentRecUsec                      bucketstats.BucketLog2Round
 	DeleteLogSegmentRecUsec                   bucketstats.BucketLog2Round
 	IndexedLogSegmentNumberUsec               bucketstats.BucketLog2Round
+	GetFingerprintRecUsec                     bucketstats.BucketLog2Round
+	PutFingerprintRecUsec                     bucketstats.BucketLog2Round
+	DeleteFingerprintRecUsec                  bucketstats.BucketLog2Round
+	GetExpiryRecUsec                          bucketstats.BucketLog2Round
+	PutExpiryRecUsec                          bucketstats.BucketLog2Round
+	DeleteExpiryRecUsec                       bucketstats.BucketLog2Round
+	IndexedExpiryRecUsec                      bucketstats.BucketLog2Round
 	GetBPlusTreeObjectUsec                    bucketstats.BucketLog2Round
 	GetBPlusTreeObjectBytes                   bucketstats.BucketLog2Round
 	PutBPlusTreeObjectUsec                    bucketstats.BucketLog2Round
@@ -221,6 +246,13 @@ type globalsStruct struct {
 	PutLogSegmentRecErrors             bucketstats.BucketLog2Round
 	DeleteLogSegmentRecErrors          bucketstats.BucketLog2Round
 	IndexedLogSegmentNumberErrors      bucketstats.BucketLog2Round
+	GetFingerprintRecErrors            bucketstats.BucketLog2Round
+	PutFingerprintRecErrors            bucketstats.BucketLog2Round
+	DeleteFingerprintRecErrors         bucketstats.BucketLog2Round
+	GetExpiryRecErrors                 bucketstats.BucketLog2Round
+	PutExpiryRecErrors                 bucketstats.BucketLog2Round
+	DeleteExpiryRecErrors              bucketstats.BucketLog2Round
+	IndexedExpiryRecErrors             bucketstats.BucketLog2Round
 	GetBPlusTreeObjectErrors           bucketstats.BucketLog2Round
 	PutBPlusTreeObjectErrors           bucketstats.BucketLog2Round
 	DeleteBPlusTreeObjectErrors        bucketstats.BucketLog2Round
@@ -291,7 +323,9 @@ func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
 		return
 	}
 
-	globals.inodeRecCache = sortedmap.NewBPlusTreeCache(inodeRecCacheEvictLowLimit, inodeRecCacheEvictHighLimit)
+	globals.inodeRecCacheBudget = cachebudget.NewBPlusTreeCache(inodeRecCacheEvictLowLimit, inodeRecCacheEvictHighLimit, bPlusTreeNodeBytesEstimate)
+	globals.inodeRecCache = globals.inodeRecCacheBudget.BPlusTreeCache
+	cachebudget.Register("headhunter_inodeRecCache", globals.inodeRecCacheBudget)
 
 	globals.inodeRecCachePriorCacheHits = 0
 	globals.inodeRecCachePriorCacheMisses = 0
@@ -305,7 +339,9 @@ func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
 		return
 	}
 
-	globals.logSegmentRecCache = sortedmap.NewBPlusTreeCache(logSegmentRecCacheEvictLowLimit, logSegmentRecCacheEvictHighLimit)
+	globals.logSegmentRecCacheBudget = cachebudget.NewBPlusTreeCache(logSegmentRecCacheEvictLowLimit, logSegmentRecCacheEvictHighLimit, bPlusTreeNodeBytesEstimate)
+	globals.logSegmentRecCache = globals.logSegmentRecCacheBudget.BPlusTreeCache
+	cachebudget.Register("headhunter_logSegmentRecCache", globals.logSegmentRecCacheBudget)
 
 	globals.logSegmentRecCachePriorCacheHits = 0
 	globals.logSegmentRecCachePriorCacheMisses = 0
@@ -319,7 +355,9 @@ func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
 		return
 	}
 
-	globals.bPlusTreeObjectCache = sortedmap.NewBPlusTreeCache(bPlusTreeObjectCacheEvictLowLimit, bPlusTreeObjectCacheEvictHighLimit)
+	globals.bPlusTreeObjectCacheBudget = cachebudget.NewBPlusTreeCache(bPlusTreeObjectCacheEvictLowLimit, bPlusTreeObjectCacheEvictHighLimit, bPlusTreeNodeBytesEstimate)
+	globals.bPlusTreeObjectCache = globals.bPlusTreeObjectCacheBudget.BPlusTreeCache
+	cachebudget.Register("headhunter_bPlusTreeObjectCache", globals.bPlusTreeObjectCacheBudget)
 
 	globals.bPlusTreeObjectCachePriorCacheHits = 0
 	globals.bPlusTreeObjectCachePriorCacheMisses = 0
@@ -333,7 +371,9 @@ func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
 		createdDeletedObjectsCacheEvictHighLimit = logSegmentRecCacheEvictHighLimit // TODO: Eventually just return
 	}
 
-	globals.createdDeletedObjectsCache = sortedmap.NewBPlusTreeCache(createdDeletedObjectsCacheEvictLowLimit, createdDeletedObjectsCacheEvictHighLimit)
+	globals.createdDeletedObjectsCacheBudget = cachebudget.NewBPlusTreeCache(createdDeletedObjectsCacheEvictLowLimit, createdDeletedObjectsCacheEvictHighLimit, bPlusTreeNodeBytesEstimate)
+	globals.createdDeletedObjectsCache = globals.createdDeletedObjectsCacheBudget.BPlusTreeCache
+	cachebudget.Register("headhunter_createdDeletedObjectsCache", globals.createdDeletedObjectsCacheBudget)
 
 	globals.createdDeletedObjectsCachePriorCacheHits = 0
 	globals.createdDeletedObjectsCachePriorCacheMisses = 0
@@ -537,6 +577,11 @@ func (dummy *globalsStruct) Down(confMap conf.ConfMap) (err error) {
 		return
 	}
 
+	cachebudget.UnRegister("headhunter_inodeRecCache")
+	cachebudget.UnRegister("headhunter_logSegmentRecCache")
+	cachebudget.UnRegister("headhunter_bPlusTreeObjectCache")
+	cachebudget.UnRegister("headhunter_createdDeletedObjectsCache")
+
 	bucketstats.UnRegister("proxyfs.headhunter", "")
 
 	err = nil
@@ -594,6 +639,21 @@ func (volume *volumeStruct) up(confMap conf.ConfMap) (err error) {
 		volume.maxCreatedDeletedObjectsPerMetadataNode = volume.maxLogSegmentsPerMetadataNode // TODO: Eventually just return
 	}
 
+	volume.maxFingerprintsPerMetadataNode, err = confMap.FetchOptionValueUint64(volumeSectionName, "MaxFingerprintsPerMetadataNode")
+	if nil != err {
+		volume.maxFingerprintsPerMetadataNode = volume.maxLogSegmentsPerMetadataNode // TODO: Eventually just return
+	}
+
+	volume.maxExpiryRecsPerMetadataNode, err = confMap.FetchOptionValueUint64(volumeSectionName, "MaxExpiryRecsPerMetadataNode")
+	if nil != err {
+		volume.maxExpiryRecsPerMetadataNode = volume.maxInodesPerMetadataNode // TODO: Eventually just return
+	}
+
+	volume.deduplication, err = confMap.FetchOptionValueBool(volumeSectionName, "Deduplication")
+	if nil != err {
+		volume.deduplication = false // TODO: Eventually, just return
+	}
+
 	volume.checkpointContainerName, err = confMap.FetchOptionValueString(volumeSectionName, "CheckpointContainerName")
 	if nil != err {
 		return
@@ -631,6 +691,11 @@ func (volume *volumeStruct) up(confMap conf.ConfMap) (err error) {
 		return
 	}
 
+	volume.keyProvider, err = keyprovider.FetchKeyProvider(confMap, volumeSectionName)
+	if nil != err {
+		return
+	}
+
 	autoFormatStringSlice, err = confMap.FetchOptionValueStringSlice(volumeSectionName, "AutoFormat")
 	if nil == err {
 		if 1 != len(autoFormatStringSlice) {
@@ -0,0 +1,205 @@
+package headhunter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/ramswift"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+	"github.com/swiftstack/ProxyFS/transitions"
+)
+
+func TestHeadHunterEncryption(t *testing.T) {
+	var (
+		confMap                conf.ConfMap
+		confStrings            []string
+		doneChan               chan bool
+		err                    error
+		keyFile                string
+		objectBuf              []byte
+		objectLength           uint64
+		objectList             []string
+		objectName             string
+		ok                     bool
+		signalHandlerIsArmedWG sync.WaitGroup
+		testDir                string
+		value                  []byte
+		volume                 VolumeHandle
+	)
+
+	testDir, err = ioutil.TempDir("", "headhunter")
+	if nil != err {
+		t.Fatalf("ioutil.TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(testDir)
+
+	keyFile = filepath.Join(testDir, "volume.keys")
+
+	err = ioutil.WriteFile(keyFile, []byte("test 000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F\n"), 0600)
+	if nil != err {
+		t.Fatalf("ioutil.WriteFile() returned error: %v", err)
+	}
+
+	confStrings = []string{
+		"Logging.LogFilePath=/dev/null",
+		"Stats.IPAddr=localhost",
+		"Stats.UDPPort=52184",
+		"Stats.BufferLength=100",
+		"Stats.MaxLatency=1s",
+		"SwiftClient.NoAuthIPAddr=127.0.0.1",
+
+		"TrackedLock.LockHoldTimeLimit=0s",
+		"TrackedLock.LockCheckPeriod=0s",
+
+		"SwiftClient.NoAuthTCPPort=9999",
+		"SwiftClient.Timeout=10s",
+		"SwiftClient.RetryLimit=0",
+		"SwiftClient.RetryLimitObject=0",
+		"SwiftClient.RetryDelay=1s",
+		"SwiftClient.RetryDelayObject=1s",
+		"SwiftClient.RetryExpBackoff=1.2",
+		"SwiftClient.RetryExpBackoffObject=2.0",
+		"SwiftClient.ChunkedConnectionPoolSize=64",
+		"SwiftClient.NonChunkedConnectionPoolSize=32",
+		"Cluster.WhoAmI=Peer0",
+		"Peer:Peer0.ReadCacheQuotaFraction=0.20",
+		"Volume:TestVolume.PrimaryPeer=Peer0",
+		"Volume:TestVolume.AccountName=TestAccount",
+		"Volume:TestVolume.CheckpointContainerName=.__checkpoint__",
+		"Volume:TestVolume.CheckpointContainerStoragePolicy=gold",
+		"Volume:TestVolume.CheckpointInterval=10s",
+		"Volume:TestVolume.MaxFlushSize=10000000",
+		"Volume:TestVolume.NonceValuesToReserve=100",
+		"Volume:TestVolume.MaxInodesPerMetadataNode=32",
+		"Volume:TestVolume.MaxLogSegmentsPerMetadataNode=64",
+		"Volume:TestVolume.MaxDirFileNodesPerMetadataNode=16",
+		"VolumeGroup:TestVolumeGroup.VolumeList=TestVolume",
+		"VolumeGroup:TestVolumeGroup.VirtualIPAddr=",
+		"VolumeGroup:TestVolumeGroup.PrimaryPeer=Peer0",
+		"FSGlobals.VolumeGroupList=TestVolumeGroup",
+		"FSGlobals.TryLockBackoffMin=100us",
+		"FSGlobals.TryLockBackoffMax=300us",
+		"FSGlobals.SymlinkMax=32",
+		"FSGlobals.InodeRecCacheEvictLowLimit=10000",
+		"FSGlobals.InodeRecCacheEvictHighLimit=10010",
+		"FSGlobals.LogSegmentRecCacheEvictLowLimit=10000",
+		"FSGlobals.LogSegmentRecCacheEvictHighLimit=10010",
+		"FSGlobals.BPlusTreeObjectCacheEvictLowLimit=10000",
+		"FSGlobals.BPlusTreeObjectCacheEvictHighLimit=10010",
+		"RamSwiftInfo.MaxAccountNameLength=256",
+		"RamSwiftInfo.MaxContainerNameLength=256",
+		"RamSwiftInfo.MaxObjectNameLength=1024",
+		"RamSwiftInfo.AccountListingLimit=10000",
+		"RamSwiftInfo.ContainerListingLimit=10000",
+	}
+
+	// Launch a ramswift instance
+
+	signalHandlerIsArmedWG.Add(1)
+	doneChan = make(chan bool, 1) // Must be buffered to avoid race
+
+	go ramswift.Daemon("/dev/null", confStrings, &signalHandlerIsArmedWG, doneChan, unix.SIGTERM)
+
+	signalHandlerIsArmedWG.Wait()
+
+	confStrings = append(confStrings,
+		"Volume:TestVolume.EncryptionKeyProvider=TestKeyProvider",
+		"KeyProvider:TestKeyProvider.Type=KeyFile",
+		"KeyProvider:TestKeyProvider.KeyFile="+keyFile,
+		"Volume:TestVolume.AutoFormat=true",
+	)
+
+	confMap, err = conf.MakeConfMapFromStrings(confStrings)
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings(confStrings) returned error: %v", err)
+	}
+
+	// Up packages (TestVolume will be formatted with a wrapped DataKey)
+
+	err = transitions.Up(confMap)
+	if nil != err {
+		t.Fatalf("transitions.Up() [case 1] returned error: %v", err)
+	}
+
+	err = confMap.UpdateFromString("Volume:TestVolume.AutoFormat=false")
+	if nil != err {
+		t.Fatalf("conf.UpdateFromString(\"Volume:TestVolume.AutoFormat=false\") returned error: %v", err)
+	}
+
+	volume, err = FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") [case 1] returned error: %v", err)
+	}
+
+	if nil == volume.FetchDataKey() {
+		t.Fatalf("FetchDataKey() [case 1] should have returned a DataKey")
+	}
+
+	inodeRecPutGet(t, volume, 1234, []byte("secret inode contents"))
+
+	err = volume.DoCheckpoint()
+	if nil != err {
+		t.Fatalf("DoCheckpoint() returned error: %v", err)
+	}
+
+	_, objectList, err = swiftclient.ContainerGet("TestAccount", ".__checkpoint__")
+	if nil != err {
+		t.Fatalf("swiftclient.ContainerGet() returned error: %v", err)
+	}
+	for _, objectName = range objectList {
+		objectLength, err = swiftclient.ObjectContentLength("TestAccount", ".__checkpoint__", objectName)
+		if nil != err {
+			t.Fatalf("swiftclient.ObjectContentLength() returned error: %v", err)
+		}
+		objectBuf, err = swiftclient.ObjectGet("TestAccount", ".__checkpoint__", objectName, 0, objectLength)
+		if nil != err {
+			t.Fatalf("swiftclient.ObjectGet() returned error: %v", err)
+		}
+		if bytes.Contains(objectBuf, []byte("secret inode contents")) {
+			t.Fatalf("Checkpoint object %s should not have contained plaintext", objectName)
+		}
+	}
+
+	err = transitions.Down(confMap)
+	if nil != err {
+		t.Fatalf("transitions.Down() [case 1] returned error: %v", err)
+	}
+
+	// Up packages again (DataKey must be unwrapped from the checkpoint header)
+
+	err = transitions.Up(confMap)
+	if nil != err {
+		t.Fatalf("transitions.Up() [case 2] returned error: %v", err)
+	}
+
+	volume, err = FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") [case 2] returned error: %v", err)
+	}
+
+	value, ok, err = volume.GetInodeRec(1234)
+	if (nil != err) || !ok {
+		t.Fatalf("GetInodeRec() [case 2] returned ok == %v err == %v", ok, err)
+	}
+	if !bytes.Equal([]byte("secret inode contents"), value) {
+		t.Fatalf("GetInodeRec() [case 2] returned unexpected value: %v", value)
+	}
+
+	err = transitions.Down(confMap)
+	if nil != err {
+		t.Fatalf("transitions.Down() [case 2] returned error: %v", err)
+	}
+
+	// Send ourself a SIGTERM to terminate ramswift.Daemon()
+
+	unix.Kill(unix.Getpid(), unix.SIGTERM)
+
+	_ = <-doneChan
+}
@@ -5,7 +5,9 @@ import (
 
 	"github.com/swiftstack/sortedmap"
 
+	"github.com/swiftstack/ProxyFS/cachebudget"
 	"github.com/swiftstack/ProxyFS/evtlog"
+	"github.com/swiftstack/ProxyFS/keyprovider"
 	"github.com/swiftstack/ProxyFS/logger"
 	"github.com/swiftstack/ProxyFS/stats"
 	"github.com/swiftstack/ProxyFS/swiftclient"
@@ -61,6 +63,13 @@ func (bPlusTreeWrapper *bPlusTreeWrapperStruct) GetNode(objectNumber uint64, obj
 	stats.IncrementOperations(&stats.HeadhunterBPlusTreeNodeFaults)
 	evtlog.Record(evtlog.FormatHeadhunterBPlusTreeNodeFault, bPlusTreeWrapper.volumeView.volume.volumeName, objectNumber, objectOffset, objectLength)
 
+	dataKey := bPlusTreeWrapper.volumeView.volume.dataKey
+
+	if nil != dataKey {
+		// Sealed nodes occupy keyprovider.SealOverhead more bytes than sortedmap is aware of
+		objectLength += keyprovider.SealOverhead
+	}
+
 	nodeByteSlice, err =
 		swiftclient.ObjectGet(
 			bPlusTreeWrapper.volumeView.volume.accountName,
@@ -68,10 +77,49 @@ func (bPlusTreeWrapper *bPlusTreeWrapperStruct) GetNode(objectNumber uint64, obj
 			utils.Uint64ToHexStr(objectNumber),
 			objectOffset,
 			objectLength)
+	if nil != err {
+		return
+	}
+
+	bPlusTreeWrapper.cacheBudget().NoteNodeBytes(uint64(len(nodeByteSlice)))
+
+	if nil == dataKey {
+		return
+	}
+
+	nodeByteSlice, err = dataKey.Open(nodeByteSlice, nodeAdditionalData(objectNumber, objectOffset))
+	if nil != err {
+		err = fmt.Errorf("headhunter.bPlusTreeWrapper.GetNode() failed to decrypt objectNumber 0x%016X objectOffset 0x%016X: %v", objectNumber, objectOffset, err)
+		logger.ErrorWithError(err)
+	}
 
 	return
 }
 
+// cacheBudget returns the cachebudget.BPlusTreeCache adapter of the B+Tree cache used by bPlusTreeWrapper.
+func (bPlusTreeWrapper *bPlusTreeWrapperStruct) cacheBudget() (bPlusTreeCacheBudget *cachebudget.BPlusTreeCache) {
+	volumeView := bPlusTreeWrapper.volumeView
+
+	switch bPlusTreeWrapper {
+	case volumeView.inodeRecWrapper, volumeView.expiryRecWrapper:
+		bPlusTreeCacheBudget = globals.inodeRecCacheBudget
+	case volumeView.logSegmentRecWrapper, volumeView.fingerprintRecWrapper:
+		bPlusTreeCacheBudget = globals.logSegmentRecCacheBudget
+	case volumeView.bPlusTreeObjectWrapper:
+		bPlusTreeCacheBudget = globals.bPlusTreeObjectCacheBudget
+	default:
+		bPlusTreeCacheBudget = globals.createdDeletedObjectsCacheBudget
+	}
+
+	return
+}
+
+// nodeAdditionalData binds a sealed node to its location such that it cannot be substituted for another.
+func nodeAdditionalData(objectNumber uint64, objectOffset uint64) (additionalData []byte) {
+	additionalData = append(utils.Uint64ToByteSlice(objectNumber), utils.Uint64ToByteSlice(objectOffset)...)
+	return
+}
+
 func (bPlusTreeWrapper *bPlusTreeWrapperStruct) PutNode(nodeByteSlice []byte) (objectNumber uint64, objectOffset uint64, err error) {
 	var (
 		bytesUsed uint64
@@ -90,7 +138,11 @@ func (bPlusTreeWrapper *bPlusTreeWrapperStruct) PutNode(nodeByteSlice []byte) (o
 		return
 	}
 
-	err = bPlusTreeWrapper.volumeView.volume.sendChunkToCheckpointChunkedPutContext(nodeByteSlice)
+	if nil == bPlusTreeWrapper.volumeView.volume.dataKey {
+		err = bPlusTreeWrapper.volumeView.volume.sendChunkToCheckpointChunkedPutContext(nodeByteSlice)
+	} else {
+		err = bPlusTreeWrapper.volumeView.volume.sendChunkToCheckpointChunkedPutContext(bPlusTreeWrapper.volumeView.volume.dataKey.Seal(nodeByteSlice, nodeAdditionalData(objectNumber, objectOffset)))
+	}
 	if nil != err {
 		return
 	}
@@ -198,3 +250,81 @@ func (bPlusTreeWrapper *bPlusTreeWrapperStruct) UnpackValue(payloadData []byte)
 	err = nil
 	return
 }
+
+// expiryRecKeyStruct is the key of the expiryRec B+Tree... ordering ExpiryRec's by when they are
+// due (and, amongst those due at the same time, by inodeNumber).
+type expiryRecKeyStruct struct {
+	expireAt    uint64
+	inodeNumber uint64
+}
+
+// expiryRecCallbacksStruct adapts the expiryRec B+Tree's bPlusTreeWrapperStruct to its expiryRecKeyStruct keys.
+type expiryRecCallbacksStruct struct {
+	*bPlusTreeWrapperStruct
+}
+
+func compareExpiryRecKey(key1 sortedmap.Key, key2 sortedmap.Key) (result int, err error) {
+	key1AsExpiryRecKey, ok := key1.(expiryRecKeyStruct)
+	if !ok {
+		err = fmt.Errorf("compareExpiryRecKey(non-expiryRecKeyStruct,) not supported")
+		return
+	}
+	key2AsExpiryRecKey, ok := key2.(expiryRecKeyStruct)
+	if !ok {
+		err = fmt.Errorf("compareExpiryRecKey(expiryRecKeyStruct, non-expiryRecKeyStruct) not supported")
+		return
+	}
+
+	result, err = sortedmap.CompareUint64(key1AsExpiryRecKey.expireAt, key2AsExpiryRecKey.expireAt)
+	if (nil == err) && (0 == result) {
+		result, err = sortedmap.CompareUint64(key1AsExpiryRecKey.inodeNumber, key2AsExpiryRecKey.inodeNumber)
+	}
+
+	return
+}
+
+func (expiryRecCallbacks *expiryRecCallbacksStruct) DumpKey(key sortedmap.Key) (keyAsString string, err error) {
+	keyAsExpiryRecKey, ok := key.(expiryRecKeyStruct)
+	if !ok {
+		err = fmt.Errorf("headhunter.expiryRecCallbacks.DumpKey() could not parse key as an expiryRecKeyStruct")
+		return
+	}
+
+	keyAsString = fmt.Sprintf("%d:0x%016X", keyAsExpiryRecKey.expireAt, keyAsExpiryRecKey.inodeNumber)
+
+	err = nil
+	return
+}
+
+func (expiryRecCallbacks *expiryRecCallbacksStruct) PackKey(key sortedmap.Key) (packedKey []byte, err error) {
+	keyAsExpiryRecKey, ok := key.(expiryRecKeyStruct)
+	if !ok {
+		err = fmt.Errorf("*expiryRecCallbacks.PackKey(key == %v) failed to convert key to expiryRecKeyStruct", key)
+		return
+	}
+	packedKey = utils.Uint64ToByteSlice(keyAsExpiryRecKey.expireAt)
+	packedKey = append(packedKey, utils.Uint64ToByteSlice(keyAsExpiryRecKey.inodeNumber)...)
+	err = nil
+	return
+}
+
+func (expiryRecCallbacks *expiryRecCallbacksStruct) UnpackKey(payloadData []byte) (key sortedmap.Key, bytesConsumed uint64, err error) {
+	if 16 > len(payloadData) {
+		err = fmt.Errorf("*expiryRecCallbacks.UnpackKey(payloadData) failed - len(payloadData) must be atleast 16 (was %v)", len(payloadData))
+		return
+	}
+	expireAt, ok := utils.ByteSliceToUint64(payloadData[:8])
+	if !ok {
+		err = fmt.Errorf("*expiryRecCallbacks.UnpackKey(payloadData) failed in call to utils.ByteSliceToUint64() [case 1]")
+		return
+	}
+	inodeNumber, ok := utils.ByteSliceToUint64(payloadData[8:16])
+	if !ok {
+		err = fmt.Errorf("*expiryRecCallbacks.UnpackKey(payloadData) failed in call to utils.ByteSliceToUint64() [case 2]")
+		return
+	}
+	key = expiryRecKeyStruct{expireAt: expireAt, inodeNumber: inodeNumber}
+	bytesConsumed = 16
+	err = nil
+	return
+}
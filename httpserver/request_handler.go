@@ -9,19 +9,24 @@ import (
 	"net/http/pprof"
 	"net/url"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/swiftstack/sortedmap"
 
+	"github.com/swiftstack/ProxyFS/audit"
+	"github.com/swiftstack/ProxyFS/blunder"
 	"github.com/swiftstack/ProxyFS/bucketstats"
+	"github.com/swiftstack/ProxyFS/cachebudget"
 	"github.com/swiftstack/ProxyFS/fs"
 	"github.com/swiftstack/ProxyFS/halter"
 	"github.com/swiftstack/ProxyFS/headhunter"
 	"github.com/swiftstack/ProxyFS/inode"
 	"github.com/swiftstack/ProxyFS/liveness"
 	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/qos"
 	"github.com/swiftstack/ProxyFS/stats"
 	"github.com/swiftstack/ProxyFS/utils"
 	"github.com/swiftstack/ProxyFS/version"
@@ -118,6 +123,7 @@ func doDeleteOfVolume(responseWriter http.ResponseWriter, request *http.Request)
 	}
 
 	err = volume.inodeVolumeHandle.SnapShotDelete(snapShotID)
+	auditSnapShot(request, audit.OpSnapShotDelete, volume, map[string]string{"SnapShotID": pathSplit[4]}, err)
 	if nil == err {
 		responseWriter.WriteHeader(http.StatusNoContent)
 	} else {
@@ -409,8 +415,39 @@ func doGetOfMetrics(responseWriter http.ResponseWriter, request *http.Request) {
 		metricsMap[metricKey] = statValue
 	}
 
+	// Per-cache statistics.
+	for cacheName, cacheStats := range cachebudget.Dump() {
+		metricKey = "proxyfs_cache_" + strings.Replace(strings.Replace(cacheName, ".", "_", -1), "-", "_", -1)
+		metricsMap[metricKey+"_hits"] = cacheStats.Hits
+		metricsMap[metricKey+"_misses"] = cacheStats.Misses
+		metricsMap[metricKey+"_bytes"] = cacheStats.Bytes
+		metricsMap[metricKey+"_budget_bytes"] = cacheStats.BudgetBytes
+	}
+
+	// Per-volume I/O (and throttling thereof) statistics.
+	for volumeName, volumeStats := range qos.Dump() {
+		metricKey = "proxyfs_qos_" + strings.Replace(strings.Replace(volumeName, ".", "_", -1), "-", "_", -1)
+		metricsMap[metricKey+"_read_ops"] = volumeStats.ReadOps
+		metricsMap[metricKey+"_read_bytes"] = volumeStats.ReadBytes
+		metricsMap[metricKey+"_write_ops"] = volumeStats.WriteOps
+		metricsMap[metricKey+"_write_bytes"] = volumeStats.WriteBytes
+		metricsMap[metricKey+"_throttled_read_ops"] = volumeStats.ThrottledReadOps
+		metricsMap[metricKey+"_throttled_write_ops"] = volumeStats.ThrottledWriteOps
+		metricsMap[metricKey+"_throttled_usec"] = volumeStats.ThrottledUsec
+	}
+
 	acceptHeader = request.Header.Get("Accept")
 
+	// Prometheus scrapers ask for OpenMetrics (or the version 0.0.4 text format) but accept the latter
+
+	if ("prometheus" == request.URL.Query().Get("format")) || strings.Contains(acceptHeader, "application/openmetrics-text") || strings.Contains(acceptHeader, "version=0.0.4") {
+		responseWriter.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		responseWriter.WriteHeader(http.StatusOK)
+
+		_, _ = responseWriter.Write([]byte(sprintPrometheusMetrics(metricsMap, statsMap)))
+		return
+	}
+
 	if strings.Contains(acceptHeader, "application/json") {
 		formatResponseAsHTML = false
 		formatResponseAsJSON = true
@@ -481,6 +518,149 @@ func doGetOfMetrics(responseWriter http.ResponseWriter, request *http.Request) {
 	}
 }
 
+// prometheusRuntimeCounters lists the go_runtime_MemStats_* metrics that only ever increase.
+var prometheusRuntimeCounters = map[string]bool{
+	"go_runtime_MemStats_TotalAlloc":   true,
+	"go_runtime_MemStats_Lookups":      true,
+	"go_runtime_MemStats_Mallocs":      true,
+	"go_runtime_MemStats_Frees":        true,
+	"go_runtime_MemStats_PauseTotalNs": true,
+	"go_runtime_MemStats_NumGC":        true,
+}
+
+var prometheusLabelValueReplacer = strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+
+func prometheusLabelPair(labelName string, labelValue string) string {
+	return labelName + "=\"" + prometheusLabelValueReplacer.Replace(labelValue) + "\""
+}
+
+// sprintPrometheusMetrics renders the metrics of doGetOfMetrics() in the Prometheus text exposition
+// format (version 0.0.4). Rather than folding cache and volume names into metric names, the
+// per-cache and per-volume metrics are labeled with "cache" and "volume" respectively. The
+// bucketstats statistics are rendered as histograms (labeled by "operation"). Every series is
+// labeled with the "peer" serving it. Note that the bucketstats statistics are aggregated across
+// the volumes served by the peer (and hence lack a "volume" label).
+func sprintPrometheusMetrics(metricsMap map[string]uint64, statsMap map[string]uint64) (metrics string) {
+	var (
+		buf           bytes.Buffer
+		cacheName     string
+		cacheNameList []string
+		metricKey     string
+		metricKeyList []string
+		peerLabelPair string
+		statKey       string
+		volumeName    string
+		volumeList    []string
+	)
+
+	peerLabelPair = prometheusLabelPair("peer", globals.whoAmI)
+
+	writeFamily := func(familyName string, help string, metricType string) {
+		_, _ = buf.WriteString(fmt.Sprintf("# HELP %s %s\n# TYPE %s %s\n", familyName, help, familyName, metricType))
+	}
+	writeSample := func(familyName string, labelPairs string, value uint64) {
+		_, _ = buf.WriteString(fmt.Sprintf("%s{%s} %d\n", familyName, labelPairs, value))
+	}
+
+	// Go runtime statistics.
+
+	for metricKey = range metricsMap {
+		if strings.HasPrefix(metricKey, "go_runtime_") {
+			metricKeyList = append(metricKeyList, metricKey)
+		}
+	}
+	sort.Strings(metricKeyList)
+
+	for _, metricKey = range metricKeyList {
+		if prometheusRuntimeCounters[metricKey] {
+			writeFamily(metricKey, "Go runtime.MemStats "+strings.TrimPrefix(metricKey, "go_runtime_MemStats_"), "counter")
+		} else {
+			writeFamily(metricKey, "Go runtime.MemStats "+strings.TrimPrefix(metricKey, "go_runtime_MemStats_"), "gauge")
+		}
+		writeSample(metricKey, peerLabelPair, metricsMap[metricKey])
+	}
+
+	// General statistics (all of which are counters).
+
+	metricKeyList = make([]string, 0, len(statsMap))
+	for statKey = range statsMap {
+		metricKeyList = append(metricKeyList, statKey)
+	}
+	sort.Strings(metricKeyList)
+
+	for _, statKey = range metricKeyList {
+		metricKey = strings.Replace(statKey, ".", "_", -1)
+		metricKey = strings.Replace(metricKey, "-", "_", -1)
+		writeFamily(metricKey, "ProxyFS statistic "+statKey, "counter")
+		writeSample(metricKey, peerLabelPair, statsMap[statKey])
+	}
+
+	// Per-cache statistics.
+
+	cacheStatsMap := cachebudget.Dump()
+	for cacheName = range cacheStatsMap {
+		cacheNameList = append(cacheNameList, cacheName)
+	}
+	sort.Strings(cacheNameList)
+
+	for _, cacheMetric := range []struct {
+		suffix     string
+		help       string
+		metricType string
+		value      func(cacheStats cachebudget.CacheStats) uint64
+	}{
+		{"hits", "Cache hits", "counter", func(cacheStats cachebudget.CacheStats) uint64 { return cacheStats.Hits }},
+		{"misses", "Cache misses", "counter", func(cacheStats cachebudget.CacheStats) uint64 { return cacheStats.Misses }},
+		{"bytes", "Bytes cached", "gauge", func(cacheStats cachebudget.CacheStats) uint64 { return cacheStats.Bytes }},
+		{"budget_bytes", "Bytes of the shared cache budget allotted (zero if none)", "gauge", func(cacheStats cachebudget.CacheStats) uint64 { return cacheStats.BudgetBytes }},
+	} {
+		if 0 == len(cacheNameList) {
+			break
+		}
+		writeFamily("proxyfs_cache_"+cacheMetric.suffix, cacheMetric.help, cacheMetric.metricType)
+		for _, cacheName = range cacheNameList {
+			writeSample("proxyfs_cache_"+cacheMetric.suffix, peerLabelPair+","+prometheusLabelPair("cache", cacheName), cacheMetric.value(cacheStatsMap[cacheName]))
+		}
+	}
+
+	// Per-volume I/O (and throttling thereof) statistics.
+
+	volumeStatsMap := qos.Dump()
+	for volumeName = range volumeStatsMap {
+		volumeList = append(volumeList, volumeName)
+	}
+	sort.Strings(volumeList)
+
+	for _, volumeMetric := range []struct {
+		suffix string
+		help   string
+		value  func(volumeStats qos.VolumeStats) uint64
+	}{
+		{"read_ops", "Read operations", func(volumeStats qos.VolumeStats) uint64 { return volumeStats.ReadOps }},
+		{"read_bytes", "Bytes read", func(volumeStats qos.VolumeStats) uint64 { return volumeStats.ReadBytes }},
+		{"write_ops", "Write operations", func(volumeStats qos.VolumeStats) uint64 { return volumeStats.WriteOps }},
+		{"write_bytes", "Bytes written", func(volumeStats qos.VolumeStats) uint64 { return volumeStats.WriteBytes }},
+		{"throttled_read_ops", "Read operations delayed by QoS limits", func(volumeStats qos.VolumeStats) uint64 { return volumeStats.ThrottledReadOps }},
+		{"throttled_write_ops", "Write operations delayed by QoS limits", func(volumeStats qos.VolumeStats) uint64 { return volumeStats.ThrottledWriteOps }},
+		{"throttled_usec", "Total delay imposed by QoS limits", func(volumeStats qos.VolumeStats) uint64 { return volumeStats.ThrottledUsec }},
+	} {
+		if 0 == len(volumeList) {
+			break
+		}
+		writeFamily("proxyfs_qos_"+volumeMetric.suffix, volumeMetric.help, "counter")
+		for _, volumeName = range volumeList {
+			writeSample("proxyfs_qos_"+volumeMetric.suffix, peerLabelPair+","+prometheusLabelPair("volume", volumeName), volumeMetric.value(volumeStatsMap[volumeName]))
+		}
+	}
+
+	// Bucketized statistics.
+
+	_, _ = buf.WriteString(bucketstats.SprintPrometheus("*", "*", map[string]string{"peer": globals.whoAmI}))
+
+	metrics = buf.String()
+	return
+}
+
 func doGetOfStats(responseWriter http.ResponseWriter, request *http.Request) {
 
 	responseWriter.Header().Set("Content-Type", "text/plain")
@@ -747,6 +927,7 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 		// Form: /volume/<volume-name>/layout-report
 		// Form: /volume/<volume-name>/scrub-job
 		// Form: /volume/<volume-name>/snapshot
+		// Form: /volume/<volume-name>/trash
 	case 4:
 		// Form: /volume/<volume-name>/extent-map/<basename>
 		// Form: /volume/<volume-name>/fsck-job/<job-id>
@@ -864,6 +1045,9 @@ func doGetOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 	case "snapshot":
 		doGetOfSnapShot(responseWriter, request, requestState)
 
+	case "trash":
+		doGetOfTrash(responseWriter, request, requestState)
+
 	default:
 		responseWriter.WriteHeader(http.StatusNotFound)
 		return
@@ -1247,7 +1431,7 @@ func doLayoutReport(responseWriter http.ResponseWriter, request *http.Request, r
 		err                       error
 		layoutReportIndex         int
 		layoutReportMap           sortedmap.LayoutReport
-		layoutReportSet           [6]*layoutReportSetElementStruct
+		layoutReportSet           [8]*layoutReportSetElementStruct
 		layoutReportSetElement    *layoutReportSetElementStruct
 		layoutReportSetJSON       bytes.Buffer
 		layoutReportSetJSONPacked []byte
@@ -1274,6 +1458,12 @@ func doLayoutReport(responseWriter http.ResponseWriter, request *http.Request, r
 	layoutReportSet[headhunter.DeletedObjectsBPlusTree] = &layoutReportSetElementStruct{
 		TreeName: "Deleted Objects B+Tree",
 	}
+	layoutReportSet[headhunter.FingerprintRecBPlusTree] = &layoutReportSetElementStruct{
+		TreeName: "Fingerprint Record B+Tree",
+	}
+	layoutReportSet[headhunter.ExpiryRecBPlusTree] = &layoutReportSetElementStruct{
+		TreeName: "Expiry Record B+Tree",
+	}
 
 	for treeTypeIndex, layoutReportSetElement = range layoutReportSet {
 		layoutReportMap, err = requestState.volume.headhunterVolumeHandle.FetchLayoutReport(headhunter.BPlusTreeType(treeTypeIndex))
@@ -1406,6 +1596,47 @@ func doGetOfSnapShot(responseWriter http.ResponseWriter, request *http.Request,
 	}
 }
 
+func doGetOfTrash(responseWriter http.ResponseWriter, request *http.Request, requestState requestState) {
+	var (
+		err            error
+		list           []fs.TrashEntry
+		listJSON       bytes.Buffer
+		listJSONPacked []byte
+	)
+
+	if 3 != requestState.numPathParts {
+		responseWriter.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// The root user is returned the trashed inodes of all users
+
+	list, err = requestState.volume.fsMountHandle.TrashList(inode.InodeRootUserID, inode.InodeGroupID(0), nil)
+	if nil != err {
+		responseWriter.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// There is no HTML rendering of the trash... so the response is always formatted as JSON
+
+	listJSONPacked, err = json.Marshal(list)
+	if nil != err {
+		responseWriter.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(http.StatusOK)
+
+	if requestState.formatResponseCompactly {
+		_, _ = responseWriter.Write(listJSONPacked)
+	} else {
+		json.Indent(&listJSON, listJSONPacked, "", "\t")
+		_, _ = responseWriter.Write(listJSON.Bytes())
+		_, _ = responseWriter.Write([]byte("\n"))
+	}
+}
+
 func doPost(responseWriter http.ResponseWriter, request *http.Request) {
 	switch {
 	case strings.HasPrefix(request.URL.Path, "/trigger"):
@@ -1514,6 +1745,8 @@ func doPostOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 	case 4:
 		// Form: /volume/<volume-name>/fsck-job/<job-id>
 		// Form: /volume/<volume-name>/scrub-job/<job-id>
+	case 5:
+		// Form: /volume/<volume-name>/trash/<user-id>/<trash-id>
 	default:
 		responseWriter.WriteHeader(http.StatusNotFound)
 		return
@@ -1543,6 +1776,13 @@ func doPostOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 		}
 		doPostOfSnapShot(responseWriter, request, volume)
 		return
+	case "trash":
+		if 5 != numPathParts {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			return
+		}
+		doPostOfTrash(responseWriter, request, volume, pathSplit[4], pathSplit[5])
+		return
 	default:
 		responseWriter.WriteHeader(http.StatusNotFound)
 		return
@@ -1716,11 +1956,17 @@ func doPostOfVolume(responseWriter http.ResponseWriter, request *http.Request) {
 
 func doPostOfSnapShot(responseWriter http.ResponseWriter, request *http.Request, volume *volumeStruct) {
 	var (
+		auditAttrs map[string]string
 		err        error
 		snapShotID uint64
 	)
 
 	snapShotID, err = volume.inodeVolumeHandle.SnapShotCreate(request.FormValue("name"))
+	auditAttrs = map[string]string{"Name": request.FormValue("name")}
+	if nil == err {
+		auditAttrs["SnapShotID"] = strconv.FormatUint(snapShotID, 10)
+	}
+	auditSnapShot(request, audit.OpSnapShotCreate, volume, auditAttrs, err)
 	if nil == err {
 		responseWriter.Header().Set("Location", fmt.Sprintf("/volume/%v/snapshot/%v", volume.name, snapShotID))
 
@@ -1730,6 +1976,49 @@ func doPostOfSnapShot(responseWriter http.ResponseWriter, request *http.Request,
 	}
 }
 
+// auditSnapShot records a snapshot operation requested by request's client.
+func auditSnapShot(request *http.Request, op string, volume *volumeStruct, attrs map[string]string, err error) {
+	if !audit.Enabled() {
+		return
+	}
+
+	audit.Log(&audit.Record{
+		Op:         op,
+		Volume:     volume.name,
+		UserID:     uint32(inode.InodeRootUserID),
+		ClientAddr: request.RemoteAddr,
+		Attrs:      attrs,
+	}, err)
+}
+
+// doPostOfTrash restores a trashed inode to where it was deleted from (or to the path specified
+// by the "path" form value).
+func doPostOfTrash(responseWriter http.ResponseWriter, request *http.Request, volume *volumeStruct, userIDAsString string, trashID string) {
+	var (
+		err    error
+		userID uint64
+	)
+
+	userID, err = strconv.ParseUint(userIDAsString, 10, 32)
+	if nil != err {
+		responseWriter.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err = volume.fsMountHandle.TrashRestore(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeUserID(userID), trashID, request.FormValue("path"))
+	if nil == err {
+		responseWriter.WriteHeader(http.StatusNoContent)
+	} else if blunder.Is(err, blunder.NotFoundError) {
+		responseWriter.WriteHeader(http.StatusNotFound)
+	} else if blunder.Is(err, blunder.FileExistsError) {
+		responseWriter.WriteHeader(http.StatusConflict)
+	} else if blunder.Is(err, blunder.InvalidArgError) {
+		responseWriter.WriteHeader(http.StatusBadRequest)
+	} else {
+		responseWriter.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
 func sortedTwoColumnResponseWriter(llrb sortedmap.LLRBTree, responseWriter http.ResponseWriter) {
 	var (
 		err                  error
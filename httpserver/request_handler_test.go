@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io/ioutil"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -62,3 +63,71 @@ func TestConfigExpansion(t *testing.T) {
 
 	return
 }
+
+func TestMetricsPrometheus(t *testing.T) {
+	testSetup(t)
+	defer testTeardown(t)
+
+	testMetrics := func(url string, acceptHeader string) (contentType string, body string) {
+		req := httptest.NewRequest("GET", "http://pfs.com"+url, nil)
+		if "" != acceptHeader {
+			req.Header.Set("Accept", acceptHeader)
+		}
+		w := httptest.NewRecorder()
+
+		doGet(w, req)
+		resp := w.Result()
+		bodyAsBytes, _ := ioutil.ReadAll(resp.Body)
+
+		if resp.StatusCode != 200 {
+			t.Fatalf("[%s]: Metrics response was %d; expected 200", url, resp.StatusCode)
+		}
+
+		contentType = resp.Header.Get("Content-Type")
+		body = string(bodyAsBytes)
+		return
+	}
+
+	// The existing formats are unchanged
+
+	contentType, _ := testMetrics("/metrics", "")
+	if "application/json" != contentType {
+		t.Errorf("Default metrics Content-Type was %s; expected application/json", contentType)
+	}
+
+	// Prometheus text exposition is selected explicitly... or by a Prometheus scraper's Accept header
+
+	for _, testCase := range []struct{ url, acceptHeader string }{
+		{"/metrics?format=prometheus", ""},
+		{"/metrics", "application/openmetrics-text; version=0.0.1,text/plain;version=0.0.4;q=0.5,*/*;q=0.1"},
+	} {
+		contentType, body := testMetrics(testCase.url, testCase.acceptHeader)
+
+		if !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+			t.Errorf("[%s]: Prometheus metrics Content-Type was %s", testCase.url, contentType)
+		}
+
+		for _, expected := range []string{
+			"# TYPE go_runtime_MemStats_HeapAlloc gauge\n",
+			"# TYPE go_runtime_MemStats_NumGC counter\n",
+			"go_runtime_MemStats_Alloc{peer=\"Peer0\"} ",
+			"# TYPE proxyfs_fs_usec histogram\n",
+			"proxyfs_fs_usec_bucket{peer=\"Peer0\",operation=\"Read\",le=\"+Inf\"} ",
+			"# TYPE proxyfs_swiftclient_usec histogram\n",
+		} {
+			if !strings.Contains(body, expected) {
+				t.Errorf("[%s]: Prometheus metrics lack \"%s\"", testCase.url, expected)
+			}
+		}
+
+		for _, line := range strings.Split(strings.TrimRight(body, "\n"), "\n") {
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+			if !strings.Contains(line, "peer=\"Peer0\"") {
+				t.Errorf("[%s]: Prometheus metric lacks peer label: %s", testCase.url, line)
+				break
+			}
+		}
+	}
+}
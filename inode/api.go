@@ -2,6 +2,7 @@
 package inode
 
 import (
+	"sync"
 	"time"
 	"unsafe"
 
@@ -88,14 +89,62 @@ type CoalesceElement struct {
 	ElementName                    string
 }
 
+// CompressionDeflate is the ReadPlanStep.Compression value indicating raw DEFLATE (RFC 1951) compression.
+const CompressionDeflate = "Deflate"
+
+// EncryptionAESGCM is the ReadPlanStep.Encryption value indicating the unit is sealed with the volume's
+// data key (see keyprovider.DataKey). Decryption precedes any decompression.
+const EncryptionAESGCM = "AES-GCM"
+
+// FallocateMode is a bit mask of the Fallocate() modes (matching fallocate(2)'s FALLOC_FL_* values).
+type FallocateMode uint32
+
+const (
+	FallocateKeepSize  FallocateMode = 0x01 // FALLOC_FL_KEEP_SIZE
+	FallocatePunchHole FallocateMode = 0x02 // FALLOC_FL_PUNCH_HOLE
+	FallocateZeroRange FallocateMode = 0x10 // FALLOC_FL_ZERO_RANGE
+)
+
+// ETagStreamName is the stream holding the (hex) MD5 of a FileInode's content in volumes configured
+// with MaintainETag. It is discarded whenever that content changes.
+const ETagStreamName = "etag"
+
+// CaseInsensitiveStreamName is the stream (settable as an xattr) of a DirInode that, if it parses
+// as a boolean, overrides the volume's CaseInsensitiveLookup setting for lookups in that directory.
+const CaseInsensitiveStreamName = "user.proxyfs.caseinsensitive"
+
+// RenameFlags is a bit mask of the Move() flags (matching renameat2(2)'s RENAME_* values).
+type RenameFlags uint32
+
+const (
+	RenameNoReplace RenameFlags = 0x01 // RENAME_NOREPLACE
+	RenameExchange  RenameFlags = 0x02 // RENAME_EXCHANGE
+	RenameWhiteout  RenameFlags = 0x04 // RENAME_WHITEOUT
+)
+
 type ReadPlanStep struct {
-	LogSegmentNumber uint64 // If == 0, Length specifies zero-file size
-	Offset           uint64 // If zero-fill case, == 0
-	Length           uint64 // Must != 0
-	AccountName      string // If == "", Length specifies a zero-fill size
-	ContainerName    string // If == "", Length specifies a zero-fill size
-	ObjectName       string // If == "", Length specifies a zero-fill size
-	ObjectPath       string // If == "", Length specifies a zero-fill size
+	LogSegmentNumber       uint64 // If == 0, Length specifies zero-file size
+	Offset                 uint64 // If zero-fill case, == 0 (if Compression or Encryption != "", relative to the decoded unit)
+	Length                 uint64 // Must != 0
+	AccountName            string // If == "", Length specifies a zero-fill size
+	ContainerName          string // If == "", Length specifies a zero-fill size
+	ObjectName             string // If == "", Length specifies a zero-fill size
+	ObjectPath             string // If == "", Length specifies a zero-fill size
+	Compression            string // If == "" (and Encryption == ""), Offset & Length specify the object bytes directly
+	Encryption             string // If == "" (and Compression == ""), Offset & Length specify the object bytes directly
+	CompressedObjectOffset uint64 // Otherwise, object bytes [CompressedObjectOffset:CompressedObjectOffset+CompressedObjectLength)
+	CompressedObjectLength uint64 //   must be fetched & decoded to obtain the unit that Offset & Length select from
+	InlineData             []byte // If != nil, the Length bytes of file data (at file offset Offset) held inline in the FileInode
+}
+
+// ReadAheadState holds the sequential Read() detection state of a single open file handle. A caller
+// able to identify its open file handles should allocate one per handle (the zero value is ready for
+// use) and pass it to each ReadWithReadAhead() issued via that handle.
+type ReadAheadState struct {
+	sync.Mutex
+	nextOffset    uint64 // offset a sequential Read() would next request
+	window        uint64 // current read-ahead window (in read cache lines)
+	prefetchedEnd uint64 // end of the range already prefetched
 }
 
 const (
@@ -180,27 +229,42 @@ type VolumeHandle interface {
 	Optimize(inodeNumber InodeNumber, maxDuration time.Duration) (err error)
 	Validate(inodeNumber InodeNumber, deeply bool) (err error)
 
+	// On-disk inode format methods, implemented in ondisk_v2.go
+
+	UpgradeOnDiskInodes() (upgradedInodes uint64, err error)
+
 	// Directory Inode specific methods, implemented in dir.go
 
 	CreateDir(filePerm InodeMode, userID InodeUserID, groupID InodeGroupID) (dirInodeNumber InodeNumber, err error)
 	Link(dirInodeNumber InodeNumber, basename string, targetInodeNumber InodeNumber, insertOnly bool) (err error)
 	Unlink(dirInodeNumber InodeNumber, basename string, removeOnly bool) (err error)
-	Move(srcDirInodeNumber InodeNumber, srcBasename string, dstDirInodeNumber InodeNumber, dstBasename string) (err error)
+	Move(srcDirInodeNumber InodeNumber, srcBasename string, dstDirInodeNumber InodeNumber, dstBasename string, flags RenameFlags) (err error)
 	Lookup(dirInodeNumber InodeNumber, basename string) (targetInodeNumber InodeNumber, err error)
 	NumDirEntries(dirInodeNumber InodeNumber) (numEntries uint64, err error)
 	ReadDir(dirInodeNumber InodeNumber, maxEntries uint64, maxBufSize uint64, prevReturned ...interface{}) (dirEntrySlice []DirEntry, moreEntries bool, err error)
 
-	// File Inode specific methods, implemented in file.go
+	// File Inode specific methods, implemented in file.go, clone.go & etag.go
 
 	CreateFile(filePerm InodeMode, userID InodeUserID, groupID InodeGroupID) (fileInodeNumber InodeNumber, err error)
 	Read(inodeNumber InodeNumber, offset uint64, length uint64, profiler *utils.Profiler) (buf []byte, err error)
+	ReadWithReadAhead(inodeNumber InodeNumber, offset uint64, length uint64, readAheadState *ReadAheadState, profiler *utils.Profiler) (buf []byte, err error) // readAheadState == nil disables read-ahead (as with Read())
 	GetReadPlan(fileInodeNumber InodeNumber, offset *uint64, length *uint64) (readPlan []ReadPlanStep, err error)
 	Write(fileInodeNumber InodeNumber, offset uint64, buf []byte, profiler *utils.Profiler) (err error)
 	ProvisionObject() (objectPath string, err error)
-	Wrote(fileInodeNumber InodeNumber, fileOffset uint64, objectPath string, objectOffset uint64, length uint64, patchOnly bool) (err error)
+	Wrote(fileInodeNumber InodeNumber, fileOffset uint64, objectPath string, objectOffset uint64, length uint64, objectBlockCRC32 []uint32, patchOnly bool) (err error) // objectBlockCRC32 (if non-nil) holds the CRC32 of each 256KiB block of objectPath[0:objectOffset+length]
 	SetSize(fileInodeNumber InodeNumber, Size uint64) (err error)
 	Flush(fileInodeNumber InodeNumber, andPurge bool) (err error)
 	Coalesce(destInodeNumber InodeNumber, elements []*CoalesceElement) (modificationTime time.Time, numWrites uint64, fileSize uint64, err error)
+	CopyRange(srcFileInodeNumber InodeNumber, srcOffset uint64, dstFileInodeNumber InodeNumber, dstOffset uint64, length uint64) (copiedLength uint64, err error)
+	ComputeETag(fileInodeNumber InodeNumber) (eTag string, eTagGeneration uint64, err error) // Caller must (at least) hold a shared lock on fileInodeNumber
+	SetETag(fileInodeNumber InodeNumber, eTag string, eTagGeneration uint64) (err error)     // Caller must hold an exclusive lock on fileInodeNumber
+	UpdateETag(fileInodeNumber InodeNumber) (err error)                                      // Caller must hold an exclusive lock on fileInodeNumber
+
+	// Sparse File Inode methods, implemented in seek.go & fallocate.go
+
+	SeekData(fileInodeNumber InodeNumber, offset uint64) (dataOffset uint64, err error)
+	SeekHole(fileInodeNumber InodeNumber, offset uint64) (holeOffset uint64, err error)
+	Fallocate(fileInodeNumber InodeNumber, mode FallocateMode, offset uint64, length uint64) (err error)
 
 	// Symlink Inode specific methods, implemented in symlink.go
 
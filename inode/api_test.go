@@ -1039,7 +1039,7 @@ func TestAPI(t *testing.T) {
 		t.Fatalf("GetMetadata(fileInodeNumber) failed: %v", err)
 	}
 	checkMetadata(t, postMetadata, testMetadata, MetadataNumWritesField, "GetMetadata() before Wrote(,,,,true)")
-	err = testVolumeHandle.Wrote(fileInodeNumber, 1, fileInodeObjectPath, 0, 3, true)
+	err = testVolumeHandle.Wrote(fileInodeNumber, 1, fileInodeObjectPath, 0, 3, nil, true)
 	if nil != err {
 		t.Fatalf("Wrote(fileInodeNumber, 1, fileInodeObjectPath, 0, 3, true) failed: %v", err)
 	}
@@ -1193,7 +1193,7 @@ func TestAPI(t *testing.T) {
 		t.Fatalf("GetMetadata(fileInodeNumber) failed: %v", err)
 	}
 	checkMetadata(t, postMetadata, testMetadata, MetadataNumWritesField, "GetMetadata() before Wrote(,,,,false)")
-	err = testVolumeHandle.Wrote(fileInodeNumber, 0, fileInodeObjectPath, 0, 2, false)
+	err = testVolumeHandle.Wrote(fileInodeNumber, 0, fileInodeObjectPath, 0, 2, nil, false)
 	if nil != err {
 		t.Fatalf("Wrote(fileInodeNumber, 0, fileInodeObjectPath, 0, 2, false) failed: %v", err)
 	}
@@ -1283,7 +1283,7 @@ func TestAPI(t *testing.T) {
 		t.Fatalf("ReadDir(RootDirInodeNumber, 0, 0) returned unexpected dirEntrySlice[2]")
 	}
 
-	err = testVolumeHandle.Move(RootDirInodeNumber, "1stLocation", RootDirInodeNumber, "2ndLocation")
+	err = testVolumeHandle.Move(RootDirInodeNumber, "1stLocation", RootDirInodeNumber, "2ndLocation", 0)
 	if nil != err {
 		t.Fatalf("Move(RootDirInodeNumber, \"1stLocation\", RootDirInodeNumber, \"2ndLocation\") failed: %v", err)
 	}
@@ -1315,7 +1315,7 @@ func TestAPI(t *testing.T) {
 	if nil != err {
 		t.Fatalf("Unlink(RootDirInodeNumber, \"3rdLocation\", false) failed: %v", err)
 	}
-	err = testVolumeHandle.Move(RootDirInodeNumber, "2ndLocation", RootDirInodeNumber, "3rdLocation")
+	err = testVolumeHandle.Move(RootDirInodeNumber, "2ndLocation", RootDirInodeNumber, "3rdLocation", 0)
 	if nil != err {
 		t.Fatalf("Move(RootDirInodeNumber, \"2ndLocation\", RootDirInodeNumber, \"3rdLocation\") failed: %v", err)
 	}
@@ -1468,7 +1468,7 @@ func TestAPI(t *testing.T) {
 		t.Fatalf("ReadDir(subDirInode, 0, 0) returned unexpected dirEntrySlice[1]")
 	}
 
-	err = testVolumeHandle.Move(RootDirInodeNumber, "3rdLocation", subDirInode, "4thLocation")
+	err = testVolumeHandle.Move(RootDirInodeNumber, "3rdLocation", subDirInode, "4thLocation", 0)
 	if nil != err {
 		t.Fatalf("Move(RootDirInodeNumber, \"3rdLocation\", subDirInode, \"4thLocation\") failed: %v", err)
 	}
@@ -2009,7 +2009,7 @@ func TestAPI(t *testing.T) {
 
 	time.Sleep(positiveDurationToDelayOrSkew)
 
-	err = testVolumeHandle.Move(dirInode, "loc_1", dirInode, "loc_2")
+	err = testVolumeHandle.Move(dirInode, "loc_1", dirInode, "loc_2", 0)
 	if nil != err {
 		t.Fatalf("Move(dirInode, \"loc_1\", dirInode, \"loc_2\") failed: %v", err)
 	}
@@ -2132,7 +2132,7 @@ func TestAPI(t *testing.T) {
 	if nil != err {
 		t.Fatalf("ProvisionObject() failed: %v", err)
 	}
-	err = testVolumeHandle.Wrote(fileInode, uint64(0), objectPath, uint64(0), uint64(2), false)
+	err = testVolumeHandle.Wrote(fileInode, uint64(0), objectPath, uint64(0), uint64(2), nil, false)
 	if nil != err {
 		t.Fatalf("Wrote(fileInode, uint64(0), objectPath, uint64(0), uint64(2), false) failed: %v", err)
 	}
@@ -58,8 +58,8 @@ func adoptVolumeGroupReadCacheParameters(confMap conf.ConfMap) (err error) {
 			}
 
 			volumeGroup.Lock()
-			volumeGroup.readCacheLineCount = readCacheLineCount
-			volumeGroup.capReadCacheWhileLocked()
+			volumeGroup.readCacheStaticLineCount = readCacheLineCount
+			volumeGroup.adoptReadCacheLineCountWhileLocked()
 			volumeGroup.Unlock()
 
 			logger.Infof("...0x%08X cache lines (each of size 0x%08X) totalling 0x%016X for Volume Group %v",
@@ -74,6 +74,40 @@ func adoptVolumeGroupReadCacheParameters(confMap conf.ConfMap) (err error) {
 	return
 }
 
+// adoptReadCacheLineCountWhileLocked sizes the Read Cache per the cachebudget-supplied readCacheBudgetBytes
+// (if non-zero) or else the readCacheStaticLineCount computed by adoptVolumeGroupReadCacheParameters().
+//
+func (volumeGroup *volumeGroupStruct) adoptReadCacheLineCountWhileLocked() {
+	if 0 == volumeGroup.readCacheBudgetBytes {
+		volumeGroup.readCacheLineCount = volumeGroup.readCacheStaticLineCount
+	} else {
+		volumeGroup.readCacheLineCount = volumeGroup.readCacheBudgetBytes / volumeGroup.readCacheLineSize
+		if 0 == volumeGroup.readCacheLineCount {
+			volumeGroup.readCacheLineCount = 1
+		}
+	}
+
+	volumeGroup.capReadCacheWhileLocked()
+}
+
+// Stats implements cachebudget.Cache.Stats() for the VolumeGroup's Read Cache.
+func (volumeGroup *volumeGroupStruct) Stats() (hits uint64, misses uint64, bytes uint64) {
+	volumeGroup.Lock()
+	hits = volumeGroup.readCacheHits
+	misses = volumeGroup.readCacheMisses
+	bytes = volumeGroup.readCacheBytes
+	volumeGroup.Unlock()
+	return
+}
+
+// SetBudget implements cachebudget.Cache.SetBudget() for the VolumeGroup's Read Cache.
+func (volumeGroup *volumeGroupStruct) SetBudget(budgetBytes uint64) {
+	volumeGroup.Lock()
+	volumeGroup.readCacheBudgetBytes = budgetBytes
+	volumeGroup.adoptReadCacheLineCountWhileLocked()
+	volumeGroup.Unlock()
+}
+
 func startInodeCacheDiscard(confMap conf.ConfMap, volume *volumeStruct, volumeSectionName string) (err error) {
 	var (
 		LRUCacheMaxBytes       uint64
@@ -0,0 +1,107 @@
+package inode
+
+import (
+	"testing"
+)
+
+func TestReadCache2Q(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	testVolume := testVolumeHandle.(*volumeStruct)
+	volumeGroup := testVolume.volumeGroup
+	readCacheLineSize := volumeGroup.readCacheLineSize
+
+	insert := func(cacheLineTag uint64) {
+		readCacheElement := &readCacheElementStruct{
+			readCacheKey: readCacheKeyStruct{
+				volumeName:       testVolume.volumeName,
+				logSegmentNumber: 1,
+				cacheLineTag:     cacheLineTag,
+			},
+			next:      nil,
+			prev:      nil,
+			cacheLine: make([]byte, readCacheLineSize),
+		}
+		volumeGroup.Lock()
+		volumeGroup.insertReadCacheElementWhileLocked(readCacheElement)
+		volumeGroup.Unlock()
+	}
+
+	fetch := func(cacheLineTag uint64) (readCacheElement *readCacheElementStruct) {
+		volumeGroup.Lock()
+		readCacheElement = volumeGroup.readCache[readCacheKeyStruct{
+			volumeName:       testVolume.volumeName,
+			logSegmentNumber: 1,
+			cacheLineTag:     cacheLineTag,
+		}]
+		volumeGroup.Unlock()
+		return
+	}
+
+	volumeGroup.Lock()
+	volumeGroup.purgeReadCacheWhileLocked()
+	volumeGroup.Unlock()
+
+	// A budget of 8 cache lines yields an A1in of 2 cache lines and an A1out of 4 keys
+
+	volumeGroup.SetBudget(8 * readCacheLineSize)
+
+	if 8 != volumeGroup.readCacheLineCount {
+		t.Fatalf("SetBudget() should have resulted in readCacheLineCount == 8 (not %v)", volumeGroup.readCacheLineCount)
+	}
+
+	// Cache lines 0 & 1 missed a second time (while their keys are in A1out) should land in Am
+
+	insert(0)
+	insert(1)
+	for cacheLineTag := uint64(100); cacheLineTag < 108; cacheLineTag++ {
+		insert(cacheLineTag)
+	}
+	if (nil != fetch(0)) || (nil != fetch(1)) {
+		t.Fatalf("Cache lines 0 & 1 should have been evicted from A1in")
+	}
+	insert(0)
+	insert(1)
+	if (nil == fetch(0)) || fetch(0).inA1in || (nil == fetch(1)) || fetch(1).inA1in {
+		t.Fatalf("Cache lines 0 & 1 should have been promoted to Am")
+	}
+
+	// A long sequential scan should not flush cache lines 0 & 1
+
+	for cacheLineTag := uint64(1000); cacheLineTag < 1100; cacheLineTag++ {
+		insert(cacheLineTag)
+	}
+	if (nil == fetch(0)) || (nil == fetch(1)) {
+		t.Fatalf("Cache lines 0 & 1 should have survived a sequential scan")
+	}
+	if 8 < len(volumeGroup.readCache) {
+		t.Fatalf("Read Cache should hold at most 8 cache lines (not %v)", len(volumeGroup.readCache))
+	}
+	if uint64(len(volumeGroup.readCache))*readCacheLineSize != volumeGroup.readCacheBytes {
+		t.Fatalf("readCacheBytes (%v) inconsistent with Read Cache contents", volumeGroup.readCacheBytes)
+	}
+
+	// Shrinking the budget should immediately shrink the Read Cache
+
+	volumeGroup.SetBudget(2 * readCacheLineSize)
+
+	if 2 < len(volumeGroup.readCache) {
+		t.Fatalf("Read Cache should have shrunk to at most 2 cache lines (not %v)", len(volumeGroup.readCache))
+	}
+
+	volumeGroup.SetBudget(0)
+
+	if volumeGroup.readCacheStaticLineCount != volumeGroup.readCacheLineCount {
+		t.Fatalf("SetBudget(0) should have restored readCacheLineCount to %v (not %v)", volumeGroup.readCacheStaticLineCount, volumeGroup.readCacheLineCount)
+	}
+
+	volumeGroup.Lock()
+	volumeGroup.purgeReadCacheWhileLocked()
+	volumeGroup.Unlock()
+
+	testTeardown(t)
+}
@@ -0,0 +1,248 @@
+package inode
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/swiftstack/sortedmap"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+)
+
+// DirInodes of volumes configured with CaseInsensitiveLookup (or whose CaseInsensitiveStreamName
+// stream overrides that setting) match basenames case-insensitively while preserving the spelling
+// each entry was created with. The directory B+Tree remains keyed by that original spelling (so
+// ReadDir() is unchanged); alongside it, an in-memory index from case-folded basename to original
+// basename is built the first time it is needed and maintained as entries are added and removed.
+// Creating an entry that differs from an existing one only in case fails with FileExistsError.
+//
+// Should the mode be enabled on a DirInode already holding entries that differ only in case, an
+// exact match is always preferred... otherwise the first such entry (in B+Tree order) is found.
+// The remaining such entries are tracked (per case-folded basename) in a collision list such that
+// removing the entry found promotes the next one rather than requiring the index to be rebuilt.
+
+// caseFold returns the Unicode simple case folding of basename (each rune mapped to the smallest
+// rune of its unicode.SimpleFold() orbit) such that caseFold(a) == caseFold(b) iff strings.EqualFold(a, b).
+func caseFold(basename string) (folded string) {
+	var (
+		foldedBuilder strings.Builder
+	)
+
+	foldedBuilder.Grow(len(basename))
+
+	for _, r := range basename {
+		foldedRune := r
+		for orbitRune := unicode.SimpleFold(r); orbitRune != r; orbitRune = unicode.SimpleFold(orbitRune) {
+			if orbitRune < foldedRune {
+				foldedRune = orbitRune
+			}
+		}
+		foldedBuilder.WriteRune(foldedRune)
+	}
+
+	folded = foldedBuilder.String()
+	return
+}
+
+// isCaseInsensitive reports whether lookups in dirInode match basenames case-insensitively.
+func isCaseInsensitive(dirInode *inMemoryInodeStruct) (caseInsensitive bool) {
+	caseInsensitiveBuf, ok := dirInode.StreamMap[CaseInsensitiveStreamName]
+	if ok {
+		caseInsensitive, err := strconv.ParseBool(string(caseInsensitiveBuf))
+		if nil == err {
+			return caseInsensitive
+		}
+	}
+
+	caseInsensitive = dirInode.volume.caseInsensitiveLookup
+	return
+}
+
+// fetchCaseFoldIndexWhileLocked returns the case-folded basename index of dirInode (building it if necessary).
+//
+// Caller must hold dirInode.caseFoldLock.
+func fetchCaseFoldIndexWhileLocked(dirInode *inMemoryInodeStruct) (caseFoldIndex map[string]string, err error) {
+	var (
+		basename   string
+		dirMapping sortedmap.BPlusTree
+		folded     string
+		index      int
+		key        sortedmap.Key
+		numEntries int
+		ok         bool
+	)
+
+	if nil != dirInode.caseFoldIndex {
+		caseFoldIndex = dirInode.caseFoldIndex
+		err = nil
+		return
+	}
+
+	dirMapping = dirInode.payload.(sortedmap.BPlusTree)
+
+	numEntries, err = dirMapping.Len()
+	if nil != err {
+		err = blunder.AddError(err, blunder.IOError)
+		return
+	}
+
+	caseFoldIndex = make(map[string]string, numEntries)
+	dirInode.caseFoldCollisions = make(map[string][]string)
+
+	for index = 0; index < numEntries; index++ {
+		key, _, ok, err = dirMapping.GetByIndex(index)
+		if nil != err {
+			err = blunder.AddError(err, blunder.IOError)
+			return
+		}
+		if !ok {
+			break
+		}
+
+		basename = key.(string)
+		if ("." == basename) || (".." == basename) {
+			continue
+		}
+
+		folded = caseFold(basename)
+
+		_, ok = caseFoldIndex[folded]
+		if ok {
+			// B+Tree order ensures collision lists are built sorted
+			dirInode.caseFoldCollisions[folded] = append(dirInode.caseFoldCollisions[folded], basename)
+		} else {
+			caseFoldIndex[folded] = basename
+		}
+	}
+
+	dirInode.caseFoldIndex = caseFoldIndex
+
+	err = nil
+	return
+}
+
+// resolveBasename returns the basename under which an entry matching basename is actually held in
+// dirInode's B+Tree... which, for case-insensitive DirInodes, may differ from basename in case.
+func resolveBasename(dirInode *inMemoryInodeStruct, basename string) (storedBasename string, ok bool, err error) {
+	var (
+		caseFoldIndex map[string]string
+	)
+
+	dirMapping := dirInode.payload.(sortedmap.BPlusTree)
+
+	_, ok, err = dirMapping.GetByKey(basename)
+	if nil != err {
+		err = blunder.AddError(err, blunder.IOError)
+		return
+	}
+	if ok {
+		storedBasename = basename
+		return
+	}
+
+	if !isCaseInsensitive(dirInode) || ("." == basename) || (".." == basename) {
+		return
+	}
+
+	dirInode.caseFoldLock.Lock()
+	defer dirInode.caseFoldLock.Unlock()
+
+	caseFoldIndex, err = fetchCaseFoldIndexWhileLocked(dirInode)
+	if nil != err {
+		return
+	}
+
+	storedBasename, ok = caseFoldIndex[caseFold(basename)]
+
+	return
+}
+
+// checkCaseOnlyCollision fails with FileExistsError if dirInode is case-insensitive and already
+// holds an entry whose basename differs from basename only in case.
+func checkCaseOnlyCollision(dirInode *inMemoryInodeStruct, basename string) (err error) {
+	storedBasename, ok, err := resolveBasename(dirInode, basename)
+	if nil != err {
+		return
+	}
+	if ok && (storedBasename != basename) {
+		err = blunder.NewError(blunder.FileExistsError, "entry '%v' differing only in case from '%v' exists in case-insensitive directory inode %v", storedBasename, basename, dirInode.InodeNumber)
+	}
+	return
+}
+
+// caseFoldIndexInsert records a newly added entry in dirInode's case-folded basename index (if built).
+func caseFoldIndexInsert(dirInode *inMemoryInodeStruct, basename string) {
+	dirInode.caseFoldLock.Lock()
+	defer dirInode.caseFoldLock.Unlock()
+
+	if nil == dirInode.caseFoldIndex {
+		return
+	}
+
+	folded := caseFold(basename)
+
+	indexedBasename, ok := dirInode.caseFoldIndex[folded]
+	if !ok {
+		dirInode.caseFoldIndex[folded] = basename
+		return
+	}
+
+	// Keep the first (in B+Tree order) of the colliding basenames in the index
+
+	if basename < indexedBasename {
+		dirInode.caseFoldIndex[folded] = basename
+		basename = indexedBasename
+	}
+
+	collisions := dirInode.caseFoldCollisions[folded]
+	collisionIndex := sort.SearchStrings(collisions, basename)
+	collisions = append(collisions, "")
+	copy(collisions[collisionIndex+1:], collisions[collisionIndex:])
+	collisions[collisionIndex] = basename
+	dirInode.caseFoldCollisions[folded] = collisions
+}
+
+// caseFoldIndexRemove forgets a removed entry in dirInode's case-folded basename index (if built).
+func caseFoldIndexRemove(dirInode *inMemoryInodeStruct, basename string) {
+	dirInode.caseFoldLock.Lock()
+	defer dirInode.caseFoldLock.Unlock()
+
+	if nil == dirInode.caseFoldIndex {
+		return
+	}
+
+	folded := caseFold(basename)
+
+	indexedBasename, ok := dirInode.caseFoldIndex[folded]
+	if !ok {
+		return
+	}
+
+	collisions := dirInode.caseFoldCollisions[folded]
+
+	if basename == indexedBasename {
+		if 0 == len(collisions) {
+			delete(dirInode.caseFoldIndex, folded)
+			return
+		}
+
+		// Promote the next colliding basename (in B+Tree order) into the index
+
+		dirInode.caseFoldIndex[folded] = collisions[0]
+		collisions = collisions[1:]
+	} else {
+		collisionIndex := sort.SearchStrings(collisions, basename)
+		if (collisionIndex == len(collisions)) || (basename != collisions[collisionIndex]) {
+			return
+		}
+		collisions = append(collisions[:collisionIndex], collisions[collisionIndex+1:]...)
+	}
+
+	if 0 == len(collisions) {
+		delete(dirInode.caseFoldCollisions, folded)
+	} else {
+		dirInode.caseFoldCollisions[folded] = collisions
+	}
+}
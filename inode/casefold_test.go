@@ -0,0 +1,266 @@
+package inode
+
+import (
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+)
+
+func TestCaseFold(t *testing.T) {
+	for _, equivalents := range [][]string{
+		{"readme.txt", "README.TXT", "ReadMe.Txt"},
+		{"äpfel", "ÄPFEL"},
+		{"ǆ", "ǅ", "Ǆ"},
+		{"k", "K", "K"}, // KELVIN SIGN
+	} {
+		for _, basename := range equivalents[1:] {
+			if caseFold(equivalents[0]) != caseFold(basename) {
+				t.Fatalf("caseFold(\"%v\") != caseFold(\"%v\")", equivalents[0], basename)
+			}
+		}
+	}
+
+	if caseFold("a") == caseFold("á") {
+		t.Fatalf("caseFold() should not have removed an accent")
+	}
+}
+
+func TestCaseFoldCollisionList(t *testing.T) {
+	dirInode := &inMemoryInodeStruct{
+		caseFoldIndex:      make(map[string]string),
+		caseFoldCollisions: make(map[string][]string),
+	}
+
+	verify := func(expectedIndexed string, expectedCollisions ...string) {
+		folded := caseFold("x")
+		indexed, ok := dirInode.caseFoldIndex[folded]
+		if "" == expectedIndexed {
+			if ok {
+				t.Fatalf("caseFoldIndex should not have held \"%v\"", indexed)
+			}
+		} else if expectedIndexed != indexed {
+			t.Fatalf("caseFoldIndex held \"%v\" (expected \"%v\")", indexed, expectedIndexed)
+		}
+		collisions := dirInode.caseFoldCollisions[folded]
+		if len(expectedCollisions) != len(collisions) {
+			t.Fatalf("caseFoldCollisions held %v (expected %v)", collisions, expectedCollisions)
+		}
+		for i := range collisions {
+			if expectedCollisions[i] != collisions[i] {
+				t.Fatalf("caseFoldCollisions held %v (expected %v)", collisions, expectedCollisions)
+			}
+		}
+	}
+
+	caseFoldIndexInsert(dirInode, "x")
+	verify("x")
+	caseFoldIndexInsert(dirInode, "X")
+	verify("X", "x")
+	caseFoldIndexRemove(dirInode, "x")
+	verify("X")
+	caseFoldIndexInsert(dirInode, "x")
+	verify("X", "x")
+	caseFoldIndexRemove(dirInode, "X")
+	verify("x")
+	caseFoldIndexRemove(dirInode, "x")
+	verify("")
+}
+
+func TestCaseInsensitiveLookup(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	testVolume := testVolumeHandle.(*volumeStruct)
+
+	createDir := func(basename string) (dirInodeNumber InodeNumber) {
+		dirInodeNumber, err = testVolumeHandle.CreateDir(PosixModePerm, 0, 0)
+		if nil != err {
+			t.Fatalf("CreateDir() failed: %v", err)
+		}
+		err = testVolumeHandle.Link(RootDirInodeNumber, basename, dirInodeNumber, false)
+		if nil != err {
+			t.Fatalf("Link(RootDirInodeNumber, \"%v\") failed: %v", basename, err)
+		}
+		return
+	}
+
+	createFile := func(dirInodeNumber InodeNumber, basename string) (fileInodeNumber InodeNumber) {
+		fileInodeNumber, err = testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+		if nil != err {
+			t.Fatalf("CreateFile() failed: %v", err)
+		}
+		err = testVolumeHandle.Link(dirInodeNumber, basename, fileInodeNumber, false)
+		if nil != err {
+			t.Fatalf("Link(0x%016X, \"%v\") failed: %v", dirInodeNumber, basename, err)
+		}
+		return
+	}
+
+	verifyLookup := func(dirInodeNumber InodeNumber, basename string, expectedInodeNumber InodeNumber) {
+		inodeNumber, err := testVolumeHandle.Lookup(dirInodeNumber, basename)
+		if InodeNumber(0) == expectedInodeNumber {
+			if !blunder.Is(err, blunder.NotFoundError) {
+				t.Fatalf("Lookup(0x%016X, \"%v\") should have failed with NotFoundError (got %v)", dirInodeNumber, basename, err)
+			}
+			return
+		}
+		if nil != err {
+			t.Fatalf("Lookup(0x%016X, \"%v\") failed: %v", dirInodeNumber, basename, err)
+		}
+		if expectedInodeNumber != inodeNumber {
+			t.Fatalf("Lookup(0x%016X, \"%v\") returned 0x%016X (expected 0x%016X)", dirInodeNumber, basename, inodeNumber, expectedInodeNumber)
+		}
+	}
+
+	verifyBasenames := func(dirInodeNumber InodeNumber, expectedBasenames ...string) {
+		dirEntries, _, err := testVolumeHandle.ReadDir(dirInodeNumber, 0, 0)
+		if nil != err {
+			t.Fatalf("ReadDir(0x%016X) failed: %v", dirInodeNumber, err)
+		}
+		basenames := make([]string, 0, len(dirEntries))
+		for _, dirEntry := range dirEntries {
+			if ("." != dirEntry.Basename) && (".." != dirEntry.Basename) {
+				basenames = append(basenames, dirEntry.Basename)
+			}
+		}
+		if len(expectedBasenames) != len(basenames) {
+			t.Fatalf("ReadDir(0x%016X) returned %v (expected %v)", dirInodeNumber, basenames, expectedBasenames)
+		}
+		for i := range basenames {
+			if expectedBasenames[i] != basenames[i] {
+				t.Fatalf("ReadDir(0x%016X) returned %v (expected %v)", dirInodeNumber, basenames, expectedBasenames)
+			}
+		}
+	}
+
+	// Per-volume mode
+
+	testVolume.caseInsensitiveLookup = true
+
+	dirInodeNumber := createDir("CaseInsensitiveDir")
+
+	fooInodeNumber := createFile(dirInodeNumber, "Foo.txt")
+
+	verifyLookup(dirInodeNumber, "Foo.txt", fooInodeNumber)
+	verifyLookup(dirInodeNumber, "FOO.TXT", fooInodeNumber)
+	verifyLookup(dirInodeNumber, "foo.txt", fooInodeNumber)
+	verifyLookup(dirInodeNumber, "bar.txt", InodeNumber(0))
+	verifyBasenames(dirInodeNumber, "Foo.txt")
+
+	otherInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Link(dirInodeNumber, "fOO.tXT", otherInodeNumber, false)
+	if !blunder.Is(err, blunder.FileExistsError) {
+		t.Fatalf("Link() differing only in case should have failed with FileExistsError (got %v)", err)
+	}
+
+	// Changing only the case of a basename
+
+	err = testVolumeHandle.Move(dirInodeNumber, "foo.TXT", dirInodeNumber, "FOO.txt", 0)
+	if nil != err {
+		t.Fatalf("Move() changing only case failed: %v", err)
+	}
+	verifyBasenames(dirInodeNumber, "FOO.txt")
+	verifyLookup(dirInodeNumber, "foo.txt", fooInodeNumber)
+
+	// Replacing an entry (whose spelling is retained)
+
+	barInodeNumber := createFile(dirInodeNumber, "Bar.txt")
+
+	err = testVolumeHandle.Move(dirInodeNumber, "foo.txt", dirInodeNumber, "BAR.TXT", 0)
+	if nil != err {
+		t.Fatalf("Move() replacing an entry failed: %v", err)
+	}
+	verifyBasenames(dirInodeNumber, "Bar.txt")
+	verifyLookup(dirInodeNumber, "bar.txt", fooInodeNumber)
+	verifyLookup(dirInodeNumber, "foo.txt", InodeNumber(0))
+
+	err = testVolumeHandle.Destroy(barInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	// Unlink via a differently cased basename
+
+	err = testVolumeHandle.Unlink(dirInodeNumber, "bAR.Txt", false)
+	if nil != err {
+		t.Fatalf("Unlink() via differently cased basename failed: %v", err)
+	}
+	verifyBasenames(dirInodeNumber)
+	verifyLookup(dirInodeNumber, "Bar.txt", InodeNumber(0))
+
+	// Now that it's gone, the spelling is available again
+
+	err = testVolumeHandle.Link(dirInodeNumber, "bar.TXT", otherInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Link() of previously colliding basename failed: %v", err)
+	}
+	verifyLookup(dirInodeNumber, "BAR.txt", otherInodeNumber)
+
+	// Per-directory override of the per-volume mode
+
+	err = testVolumeHandle.PutStream(dirInodeNumber, CaseInsensitiveStreamName, []byte("false"))
+	if nil != err {
+		t.Fatalf("PutStream(CaseInsensitiveStreamName) failed: %v", err)
+	}
+	verifyLookup(dirInodeNumber, "BAR.txt", InodeNumber(0))
+	verifyLookup(dirInodeNumber, "bar.TXT", otherInodeNumber)
+
+	testVolume.caseInsensitiveLookup = false
+
+	sensitiveDirInodeNumber := createDir("CaseSensitiveDir")
+
+	lowerInodeNumber := createFile(sensitiveDirInodeNumber, "a")
+	upperInodeNumber := createFile(sensitiveDirInodeNumber, "A")
+
+	verifyLookup(sensitiveDirInodeNumber, "a", lowerInodeNumber)
+	verifyLookup(sensitiveDirInodeNumber, "A", upperInodeNumber)
+
+	// Enabling the mode on a directory already holding entries differing only in case
+
+	err = testVolumeHandle.PutStream(sensitiveDirInodeNumber, CaseInsensitiveStreamName, []byte("true"))
+	if nil != err {
+		t.Fatalf("PutStream(CaseInsensitiveStreamName) failed: %v", err)
+	}
+	verifyLookup(sensitiveDirInodeNumber, "a", lowerInodeNumber)
+	verifyLookup(sensitiveDirInodeNumber, "A", upperInodeNumber)
+
+	err = testVolumeHandle.Unlink(sensitiveDirInodeNumber, "A", false)
+	if nil != err {
+		t.Fatalf("Unlink() failed: %v", err)
+	}
+	err = testVolumeHandle.Destroy(upperInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+	verifyLookup(sensitiveDirInodeNumber, "A", lowerInodeNumber)
+
+	// ...which should have promoted "a" from the collision list rather than discarding the index
+
+	sensitiveDirInode, ok, err := testVolume.fetchInode(sensitiveDirInodeNumber)
+	if (nil != err) || !ok {
+		t.Fatalf("fetchInode() failed: %v", err)
+	}
+	sensitiveDirInode.caseFoldLock.Lock()
+	if (nil == sensitiveDirInode.caseFoldIndex) || ("a" != sensitiveDirInode.caseFoldIndex[caseFold("a")]) || (0 != len(sensitiveDirInode.caseFoldCollisions)) {
+		t.Fatalf("Unlink() of the indexed colliding entry should have promoted the other (index: %v collisions: %v)", sensitiveDirInode.caseFoldIndex, sensitiveDirInode.caseFoldCollisions)
+	}
+	sensitiveDirInode.caseFoldLock.Unlock()
+
+	err = testVolumeHandle.Unlink(sensitiveDirInodeNumber, "A", false)
+	if nil != err {
+		t.Fatalf("Unlink() failed: %v", err)
+	}
+	err = testVolumeHandle.Destroy(lowerInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+	verifyBasenames(sensitiveDirInodeNumber)
+
+	testTeardown(t)
+}
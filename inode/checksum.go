@@ -0,0 +1,317 @@
+package inode
+
+import (
+	"hash/crc32"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/evtlog"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+)
+
+// LogSegment data is checksummed in fixed size blocks in LogSegment (rather than file) coordinates.
+// As LogSegments are immutable once their Chunked PUT completes, these checksums remain valid no
+// matter how the extents referencing them are later split, trimmed, or coalesced. They are kept in
+// the LogSegment's record (see logSegmentRecStruct) rather than in the inodes referencing it.
+//
+// The (IEEE) CRC32 is used so that other agents PUTing LogSegments (i.e. pfs_middleware, whose
+// zlib.crc32() computes the same thing) can supply the checksums of the data they sent to Wrote().
+//
+const (
+	logSegmentChecksumBlockSize = uint64(256 * 1024)
+
+	logSegmentChecksumBlocksPerGet = uint64(16) // Used when reading a LogSegment back to validate it
+)
+
+var logSegmentChecksumTable = crc32.IEEETable
+
+type logSegmentChecksumsStruct struct {
+	Length     uint64   // Total number of bytes in the LogSegment
+	BlockCRC32 []uint32 // One CRC32 per logSegmentChecksumBlockSize block (the last one may be short)
+}
+
+type logSegmentChecksummerStruct struct {
+	checksums      *logSegmentChecksumsStruct
+	blockCRC32     uint32
+	blockBytesSeen uint64
+}
+
+func newLogSegmentChecksummer() (checksummer *logSegmentChecksummerStruct) {
+	checksummer = &logSegmentChecksummerStruct{
+		checksums: &logSegmentChecksumsStruct{
+			Length:     0,
+			BlockCRC32: make([]uint32, 0),
+		},
+		blockCRC32:     0,
+		blockBytesSeen: 0,
+	}
+
+	return
+}
+
+// update folds the next buf of LogSegment data (following any previously supplied) into the checksums.
+//
+func (checksummer *logSegmentChecksummerStruct) update(buf []byte) {
+	var (
+		blockBytesRemaining uint64
+	)
+
+	for 0 < len(buf) {
+		blockBytesRemaining = logSegmentChecksumBlockSize - checksummer.blockBytesSeen
+		if uint64(len(buf)) < blockBytesRemaining {
+			blockBytesRemaining = uint64(len(buf))
+		}
+
+		checksummer.blockCRC32 = crc32.Update(checksummer.blockCRC32, logSegmentChecksumTable, buf[:blockBytesRemaining])
+		checksummer.blockBytesSeen += blockBytesRemaining
+		checksummer.checksums.Length += blockBytesRemaining
+
+		if logSegmentChecksumBlockSize == checksummer.blockBytesSeen {
+			checksummer.checksums.BlockCRC32 = append(checksummer.checksums.BlockCRC32, checksummer.blockCRC32)
+			checksummer.blockCRC32 = 0
+			checksummer.blockBytesSeen = 0
+		}
+
+		buf = buf[blockBytesRemaining:]
+	}
+}
+
+// finish returns the checksums of all data supplied via update() including any trailing short block.
+//
+func (checksummer *logSegmentChecksummerStruct) finish() (checksums *logSegmentChecksumsStruct) {
+	if 0 < checksummer.blockBytesSeen {
+		checksummer.checksums.BlockCRC32 = append(checksummer.checksums.BlockCRC32, checksummer.blockCRC32)
+		checksummer.blockCRC32 = 0
+		checksummer.blockBytesSeen = 0
+	}
+
+	checksums = checksummer.checksums
+
+	return
+}
+
+// newLogSegmentChecksums returns the checksums supplied (e.g. via Wrote()) for a LogSegment of the specified
+// length... or nil if they cannot possibly describe it.
+//
+func newLogSegmentChecksums(length uint64, blockCRC32 []uint32) (checksums *logSegmentChecksumsStruct) {
+	if uint64(len(blockCRC32)) != ((length + logSegmentChecksumBlockSize - 1) / logSegmentChecksumBlockSize) {
+		checksums = nil
+		return
+	}
+
+	checksums = &logSegmentChecksumsStruct{
+		Length:     length,
+		BlockCRC32: append([]uint32(nil), blockCRC32...),
+	}
+
+	return
+}
+
+// fetchLogSegmentChecksums returns the checksums (if known) for the LogSegment referenced by a ReadPlanStep.
+// As only volumes with DataChecksums enabled record (or verify) them, the LogSegmentRec is otherwise not consulted.
+//
+func (vS *volumeStruct) fetchLogSegmentChecksums(logSegmentNumber uint64) (checksums *logSegmentChecksumsStruct) {
+	if !vS.dataChecksums {
+		checksums = nil
+		return
+	}
+
+	logSegmentRec, err := vS.fetchLogSegmentRec(logSegmentNumber)
+	if nil != err {
+		checksums = nil
+		return
+	}
+
+	checksums = logSegmentRec.checksums
+
+	return
+}
+
+// getLogSegmentRange GETs length bytes (fewer should the object end first) of the LogSegment object referenced
+// by step starting at offset. If checksums are known, the GET is widened to whole checksum blocks such that
+// they may be verified without issuing further GETs. Only the requested bytes are returned.
+//
+func (vS *volumeStruct) getLogSegmentRange(inodeNumber InodeNumber, step ReadPlanStep, checksums *logSegmentChecksumsStruct, offset uint64, length uint64) (buf []byte, err error) {
+	var (
+		bufEnd        uint64
+		bufStart      uint64
+		widenedBuf    []byte
+		widenedEnd    uint64
+		widenedOffset uint64
+	)
+
+	if nil == checksums {
+		buf, err = swiftclient.ObjectGet(step.AccountName, step.ContainerName, step.ObjectName, offset, length)
+		if nil != err {
+			err = blunder.AddError(err, blunder.SegReadError)
+		}
+		return
+	}
+
+	widenedOffset = (offset / logSegmentChecksumBlockSize) * logSegmentChecksumBlockSize
+	widenedEnd = ((offset + length + logSegmentChecksumBlockSize - 1) / logSegmentChecksumBlockSize) * logSegmentChecksumBlockSize
+
+	widenedBuf, err = swiftclient.ObjectGet(step.AccountName, step.ContainerName, step.ObjectName, widenedOffset, widenedEnd-widenedOffset)
+	if nil != err {
+		err = blunder.AddError(err, blunder.SegReadError)
+		return
+	}
+
+	err = vS.verifyLogSegmentChecksums(inodeNumber, step.LogSegmentNumber, checksums, widenedOffset, widenedBuf)
+	if nil != err {
+		return
+	}
+
+	bufEnd = offset - widenedOffset + length
+	if bufEnd > uint64(len(widenedBuf)) {
+		bufEnd = uint64(len(widenedBuf))
+	}
+	bufStart = offset - widenedOffset
+	if bufStart > bufEnd {
+		bufStart = bufEnd
+	}
+
+	buf = widenedBuf[bufStart:bufEnd]
+
+	err = nil
+	return
+}
+
+// verifyLogSegmentChecksums checks buf (read from LogSegment offset) against the LogSegment's checksums. As
+// only whole blocks may be verified, offset must be block aligned and buf must extend to the end of a block
+// (or of the LogSegment). A nil checksums (i.e. for a LogSegment written before checksums were enabled) is
+// not verified.
+//
+func (vS *volumeStruct) verifyLogSegmentChecksums(inodeNumber InodeNumber, logSegmentNumber uint64, checksums *logSegmentChecksumsStruct, offset uint64, buf []byte) (err error) {
+	var (
+		blockCRC32      uint32
+		blockEnd        uint64
+		blockIndex      uint64
+		blockStart      uint64
+		end             uint64
+		firstBlockIndex uint64
+		lastBlockIndex  uint64
+	)
+
+	if (nil == checksums) || (0 == len(buf)) {
+		err = nil
+		return
+	}
+
+	if 0 != (offset % logSegmentChecksumBlockSize) {
+		err = blunder.NewError(blunder.SegReadError, "Volume '%s' LogSegment# 0x%016X Offset 0x%016X is not checksum block aligned", vS.volumeName, logSegmentNumber, offset)
+		logger.ErrorWithError(err)
+		return
+	}
+
+	end = offset + uint64(len(buf))
+
+	if end > checksums.Length {
+		err = vS.reportChecksumMismatch(inodeNumber, logSegmentNumber, checksums.Length, "LogSegment extends beyond its checksummed length")
+		return
+	}
+
+	firstBlockIndex = offset / logSegmentChecksumBlockSize
+	lastBlockIndex = (end - 1) / logSegmentChecksumBlockSize
+
+	if lastBlockIndex >= uint64(len(checksums.BlockCRC32)) {
+		err = vS.reportChecksumMismatch(inodeNumber, logSegmentNumber, offset, "LogSegment checksums are incomplete")
+		return
+	}
+
+	for blockIndex = firstBlockIndex; blockIndex <= lastBlockIndex; blockIndex++ {
+		blockStart = blockIndex * logSegmentChecksumBlockSize
+		blockEnd = blockStart + logSegmentChecksumBlockSize
+		if blockEnd > checksums.Length {
+			blockEnd = checksums.Length
+		}
+
+		if blockEnd > end {
+			err = vS.reportChecksumMismatch(inodeNumber, logSegmentNumber, blockStart, "LogSegment is shorter than its checksummed length")
+			return
+		}
+
+		blockCRC32 = crc32.Checksum(buf[(blockStart-offset):(blockEnd-offset)], logSegmentChecksumTable)
+
+		if blockCRC32 != checksums.BlockCRC32[blockIndex] {
+			err = vS.reportChecksumMismatch(inodeNumber, logSegmentNumber, blockStart, "block CRC32 mismatch")
+			return
+		}
+	}
+
+	err = nil
+	return
+}
+
+func (vS *volumeStruct) reportChecksumMismatch(inodeNumber InodeNumber, logSegmentNumber uint64, offset uint64, reason string) (err error) {
+	evtlog.Record(evtlog.FormatFileDataChecksumMismatch, vS.volumeName, uint64(inodeNumber), logSegmentNumber, offset)
+
+	err = blunder.NewError(blunder.IOError, "Volume '%s' Inode# 0x%016X LogSegment# 0x%016X at Offset 0x%016X failed data checksum verification: %s", vS.volumeName, inodeNumber, logSegmentNumber, offset, reason)
+	logger.ErrorWithError(err)
+
+	return
+}
+
+// validateFileDataChecksums reads back every LogSegment byte referenced by the file
+// and verifies it against the recorded checksums (where they are known).
+//
+func (vS *volumeStruct) validateFileDataChecksums(snapShotID uint64, ourInode *inMemoryInodeStruct) (err error) {
+	var (
+		end                 uint64
+		length              uint64
+		logSegmentChecksums *logSegmentChecksumsStruct
+		offset              uint64
+		readPlan            []ReadPlanStep
+		step                ReadPlanStep
+		zero                = uint64(0)
+	)
+
+	readPlan, _, err = vS.getReadPlanHelper(snapShotID, ourInode, &zero, nil)
+	if nil != err {
+		return
+	}
+
+	readPlan, err = vS.translateReadPlan(ourInode, readPlan)
+	if nil != err {
+		return
+	}
+
+	for _, step = range readPlan {
+		if 0 == step.LogSegmentNumber {
+			// Holes in a sparse file have no data to verify
+			continue
+		}
+
+		logSegmentChecksums = vS.fetchLogSegmentChecksums(step.LogSegmentNumber)
+		if nil == logSegmentChecksums {
+			continue
+		}
+
+		if !stepReferencesUnit(&step) {
+			offset = step.Offset
+			end = step.Offset + step.Length
+		} else {
+			offset = step.CompressedObjectOffset
+			end = step.CompressedObjectOffset + step.CompressedObjectLength
+		}
+
+		offset = (offset / logSegmentChecksumBlockSize) * logSegmentChecksumBlockSize
+
+		for offset < end {
+			length = logSegmentChecksumBlocksPerGet * logSegmentChecksumBlockSize
+			if (offset + length) > end {
+				length = end - offset
+			}
+
+			_, err = vS.getLogSegmentRange(ourInode.InodeNumber, step, logSegmentChecksums, offset, length)
+			if nil != err {
+				return
+			}
+
+			offset += length
+		}
+	}
+
+	err = nil
+	return
+}
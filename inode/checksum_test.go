@@ -0,0 +1,286 @@
+package inode
+
+import (
+	"bytes"
+	"hash/crc32"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+func TestLogSegmentChecksummer(t *testing.T) {
+	buf := make([]byte, 2*logSegmentChecksumBlockSize+12345)
+	for i := range buf {
+		buf[i] = byte(i % 251)
+	}
+
+	wholeChecksummer := newLogSegmentChecksummer()
+	wholeChecksummer.update(buf)
+	wholeChecksums := wholeChecksummer.finish()
+
+	if (uint64(len(buf)) != wholeChecksums.Length) || (3 != len(wholeChecksums.BlockCRC32)) {
+		t.Fatalf("finish() returned unexpected checksums: Length %v with %v blocks", wholeChecksums.Length, len(wholeChecksums.BlockCRC32))
+	}
+
+	piecewiseChecksummer := newLogSegmentChecksummer()
+	for offset := 0; offset < len(buf); offset += 7777 {
+		end := offset + 7777
+		if end > len(buf) {
+			end = len(buf)
+		}
+		piecewiseChecksummer.update(buf[offset:end])
+	}
+	piecewiseChecksums := piecewiseChecksummer.finish()
+
+	if piecewiseChecksums.Length != wholeChecksums.Length {
+		t.Fatalf("piecewise update() yielded Length %v... expected %v", piecewiseChecksums.Length, wholeChecksums.Length)
+	}
+	for blockIndex, blockCRC32 := range wholeChecksums.BlockCRC32 {
+		if piecewiseChecksums.BlockCRC32[blockIndex] != blockCRC32 {
+			t.Fatalf("piecewise update() yielded a different CRC32 for block %v", blockIndex)
+		}
+	}
+}
+
+func TestDataChecksums(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	testVolume := testVolumeHandle.(*volumeStruct)
+
+	testVolume.dataChecksums = true
+	defer func() {
+		testVolume.dataChecksums = false
+	}()
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+
+	writeBuf := make([]byte, 2*logSegmentChecksumBlockSize+12345)
+	for i := range writeBuf {
+		writeBuf[i] = byte(i % 253)
+	}
+
+	err = testVolumeHandle.Write(fileInodeNumber, 0, writeBuf, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	var zero uint64
+	readPlan, err := testVolumeHandle.GetReadPlan(fileInodeNumber, &zero, nil)
+	if (nil != err) || (1 != len(readPlan)) {
+		t.Fatalf("GetReadPlan() returned unexpected readPlan (%v) or error: %v", readPlan, err)
+	}
+
+	logSegmentNumber, err := strconv.ParseUint(readPlan[0].ObjectName, 16, 64)
+	if nil != err {
+		t.Fatalf("strconv.ParseUint(\"%s\") failed: %v", readPlan[0].ObjectName, err)
+	}
+	logSegmentChecksums := testVolume.fetchLogSegmentChecksums(logSegmentNumber)
+	if nil == logSegmentChecksums {
+		t.Fatalf("LogSegmentRec should have recorded checksums")
+	}
+	if (uint64(len(writeBuf)) != logSegmentChecksums.Length) || (3 != len(logSegmentChecksums.BlockCRC32)) {
+		t.Fatalf("LogSegmentRec recorded unexpected Length %v with %v blocks", logSegmentChecksums.Length, len(logSegmentChecksums.BlockCRC32))
+	}
+
+	// Reads straddling (and landing within) checksum blocks should verify cleanly
+
+	readBuf, err := testVolumeHandle.Read(fileInodeNumber, logSegmentChecksumBlockSize-10, logSegmentChecksumBlockSize+20, nil)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if !bytes.Equal(writeBuf[logSegmentChecksumBlockSize-10:2*logSegmentChecksumBlockSize+10], readBuf) {
+		t.Fatalf("Read() returned unexpected data")
+	}
+
+	// Unaligned ranges (e.g. Read Cache Lines) are widened to whole blocks (even past the end of the LogSegment)
+
+	rangeBuf, err := testVolume.getLogSegmentRange(fileInodeNumber, readPlan[0], logSegmentChecksums, logSegmentChecksumBlockSize+1, logSegmentChecksumBlockSize+12343)
+	if nil != err {
+		t.Fatalf("getLogSegmentRange() failed: %v", err)
+	}
+	if !bytes.Equal(writeBuf[logSegmentChecksumBlockSize+1:2*logSegmentChecksumBlockSize+12344], rangeBuf) {
+		t.Fatalf("getLogSegmentRange() returned unexpected data")
+	}
+
+	err = testVolumeHandle.Validate(fileInodeNumber, true)
+	if nil != err {
+		t.Fatalf("Validate() failed on presumably-good inode: %v", err)
+	}
+
+	// Now silently corrupt a byte in the middle of the LogSegment
+
+	corruptBuf := make([]byte, len(writeBuf))
+	copy(corruptBuf, writeBuf)
+	corruptBuf[logSegmentChecksumBlockSize+1]++
+
+	chunkedPutContext, err := swiftclient.ObjectFetchChunkedPutContext(readPlan[0].AccountName, readPlan[0].ContainerName, readPlan[0].ObjectName, "")
+	if nil != err {
+		t.Fatalf("ObjectFetchChunkedPutContext() failed: %v", err)
+	}
+	err = chunkedPutContext.SendChunk(corruptBuf)
+	if nil != err {
+		t.Fatalf("SendChunk() failed: %v", err)
+	}
+	err = chunkedPutContext.Close()
+	if nil != err {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	testVolume.volumeGroup.Lock()
+	testVolume.volumeGroup.purgeReadCacheWhileLocked()
+	testVolume.volumeGroup.Unlock()
+
+	// Reads covering the corrupted block should now fail with EIO
+
+	_, err = testVolumeHandle.Read(fileInodeNumber, logSegmentChecksumBlockSize+100, 100, nil)
+	if !blunder.Is(err, blunder.IOError) {
+		t.Fatalf("Read() of corrupted block should have failed with EIO: %v", err)
+	}
+
+	err = testVolumeHandle.Validate(fileInodeNumber, true)
+	if !blunder.Is(err, blunder.IOError) {
+		t.Fatalf("Validate() of inode referencing corrupted data should have failed with EIO: %v", err)
+	}
+
+	// The inode itself should not have been marked corrupt
+
+	_, ok, err := testVolume.fetchOnDiskInode(fileInodeNumber)
+	if (nil != err) || !ok {
+		t.Fatalf("fetchOnDiskInode() should have succeeded: ok == %v err == %v", ok, err)
+	}
+
+	testTeardown(t)
+}
+
+func TestLogSegmentRecEncoding(t *testing.T) {
+	logSegmentRec := &logSegmentRecStruct{containerName: "container"}
+
+	buf := encodeLogSegmentRec(logSegmentRec)
+	if "container" != string(buf) {
+		t.Fatalf("encodeLogSegmentRec() without metadata should have returned just the containerName (got %v)", buf)
+	}
+
+	logSegmentRec.checksums = &logSegmentChecksumsStruct{Length: 300000, BlockCRC32: []uint32{0xDEADBEEF, 0x01020304}}
+	logSegmentRec.compressionUnits = []logSegmentCompressionUnitStruct{
+		{LogicalOffset: 0, LogicalLength: 60, ObjectOffset: 0, ObjectLength: 20, Encrypted: false},
+		{LogicalOffset: 60, LogicalLength: 40, ObjectOffset: 20, ObjectLength: 56, Encrypted: true},
+	}
+
+	buf = encodeLogSegmentRec(logSegmentRec)
+
+	decodedLogSegmentRec, err := decodeLogSegmentRec(buf)
+	if nil != err {
+		t.Fatalf("decodeLogSegmentRec() failed: %v", err)
+	}
+	if !reflect.DeepEqual(logSegmentRec, decodedLogSegmentRec) {
+		t.Fatalf("decodeLogSegmentRec() returned %+v (expected %+v)", decodedLogSegmentRec, logSegmentRec)
+	}
+
+	for bufLen := len(logSegmentRec.containerName) + 2; bufLen < len(buf); bufLen++ {
+		decodedLogSegmentRec, err = decodeLogSegmentRec(buf[:bufLen])
+		if (nil == err) && reflect.DeepEqual(logSegmentRec, decodedLogSegmentRec) {
+			t.Fatalf("decodeLogSegmentRec() of truncated (%v of %v bytes) buf should not have returned the original", bufLen, len(buf))
+		}
+	}
+}
+
+func TestWroteChecksums(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	testVolume := testVolumeHandle.(*volumeStruct)
+
+	testVolume.dataChecksums = true
+	defer func() {
+		testVolume.dataChecksums = false
+	}()
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+
+	// PUT a LogSegment (as pfs_middleware would) along with its per-block CRC32s
+
+	objectBuf := make([]byte, logSegmentChecksumBlockSize+100)
+	for i := range objectBuf {
+		objectBuf[i] = byte(i % 251)
+	}
+	objectBlockCRC32 := []uint32{
+		crc32.ChecksumIEEE(objectBuf[:logSegmentChecksumBlockSize]),
+		crc32.ChecksumIEEE(objectBuf[logSegmentChecksumBlockSize:]),
+	}
+
+	objectPath, err := testVolumeHandle.ProvisionObject()
+	if nil != err {
+		t.Fatalf("ProvisionObject() failed: %v", err)
+	}
+	accountName, containerName, objectName, err := utils.PathToAcctContObj(objectPath)
+	if nil != err {
+		t.Fatalf("PathToAcctContObj() failed: %v", err)
+	}
+	chunkedPutContext, err := swiftclient.ObjectFetchChunkedPutContext(accountName, containerName, objectName, "")
+	if nil != err {
+		t.Fatalf("ObjectFetchChunkedPutContext() failed: %v", err)
+	}
+	err = chunkedPutContext.SendChunk(objectBuf)
+	if nil != err {
+		t.Fatalf("SendChunk() failed: %v", err)
+	}
+	err = chunkedPutContext.Close()
+	if nil != err {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	err = testVolumeHandle.Wrote(fileInodeNumber, 0, objectPath, 0, uint64(len(objectBuf)), objectBlockCRC32, false)
+	if nil != err {
+		t.Fatalf("Wrote() failed: %v", err)
+	}
+
+	logSegmentNumber, err := strconv.ParseUint(objectName, 16, 64)
+	if nil != err {
+		t.Fatalf("strconv.ParseUint(\"%s\") failed: %v", objectName, err)
+	}
+	logSegmentChecksums := testVolume.fetchLogSegmentChecksums(logSegmentNumber)
+	if (nil == logSegmentChecksums) || !reflect.DeepEqual(objectBlockCRC32, logSegmentChecksums.BlockCRC32) {
+		t.Fatalf("Wrote() should have recorded the supplied checksums (got %+v)", logSegmentChecksums)
+	}
+
+	readBuf, err := testVolumeHandle.Read(fileInodeNumber, 0, uint64(len(objectBuf)), nil)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if !bytes.Equal(objectBuf, readBuf) {
+		t.Fatalf("Read() returned unexpected data")
+	}
+
+	// Checksums not matching the LogSegment's length are dropped rather than later failing every Read()
+
+	err = testVolumeHandle.Wrote(fileInodeNumber, 0, objectPath, 0, uint64(len(objectBuf)), objectBlockCRC32[:1], false)
+	if nil != err {
+		t.Fatalf("Wrote() failed: %v", err)
+	}
+	if nil != testVolume.fetchLogSegmentChecksums(logSegmentNumber) {
+		t.Fatalf("Wrote() should not have recorded mismatched checksums")
+	}
+
+	testTeardown(t)
+}
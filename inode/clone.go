@@ -0,0 +1,242 @@
+package inode
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/swiftstack/sortedmap"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+// CopyRange follows the semantics of copy_file_range(2): up to length bytes starting at srcOffset of
+// the source FileInode are copied to dstOffset of the destination FileInode (extending its Size as
+// necessary). Fewer bytes (possibly zero) are copied if the source ends before srcOffset + length.
+//
+// Rather than copying data, the extents covering the source range are duplicated into the destination's
+// extent map so that both FileInodes reference the same LogSegments. Each LogSegment thus newly shared
+// is given (or has incremented) the RefCount of its dedupLogSegmentRecStruct just as if deduplication
+// had found it... such that releaseLogSegment() only deletes it once no FileInode references it. Holes
+// in the source range remain holes in the destination. Data held in a source FileInode's InlineData is
+// simply written to the destination.
+//
+// Both FileInodes must be in the same volume (the one for which CopyRange() is invoked). Overlapping
+// ranges of the same FileInode are not allowed.
+//
+
+type copyRangePieceStruct struct {
+	fileOffset       uint64
+	length           uint64
+	logSegmentNumber uint64
+	logSegmentOffset uint64
+}
+
+func (vS *volumeStruct) CopyRange(srcFileInodeNumber InodeNumber, srcOffset uint64, dstFileInodeNumber InodeNumber, dstOffset uint64, length uint64) (copiedLength uint64, err error) {
+	var (
+		curExtent        *fileExtentStruct
+		curExtentIndex   int
+		dstInode         *inMemoryInodeStruct
+		extents          sortedmap.BPlusTree
+		logSegmentNumber uint64
+		logSegmentRec    *dedupLogSegmentRecStruct
+		ok               bool
+		overlapEnd       uint64
+		overlapStart     uint64
+		piece            copyRangePieceStruct
+		pieces           []copyRangePieceStruct
+		srcEnd           uint64
+		srcInode         *inMemoryInodeStruct
+		updateTime       time.Time
+	)
+
+	snapShotIDType, _, _ := vS.headhunterVolumeHandle.SnapShotU64Decode(uint64(dstFileInodeNumber))
+	if headhunter.SnapShotIDTypeLive != snapShotIDType {
+		err = fmt.Errorf("CopyRange() to non-LiveView dstFileInodeNumber not allowed")
+		return
+	}
+
+	if ((srcOffset + length) < srcOffset) || ((dstOffset + length) < dstOffset) {
+		err = blunder.NewError(blunder.FileTooLargeError, "CopyRange() called with offset + length overflowing")
+		return
+	}
+
+	srcInode, err = vS.fetchInodeType(srcFileInodeNumber, FileType)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+	dstInode, err = vS.fetchInodeType(dstFileInodeNumber, FileType)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+
+	if (srcFileInodeNumber == dstFileInodeNumber) && (srcOffset < (dstOffset + length)) && (dstOffset < (srcOffset + length)) {
+		err = blunder.NewError(blunder.InvalidArgError, "CopyRange() called with overlapping ranges of inode 0x%016X", srcFileInodeNumber)
+		return
+	}
+
+	stats.IncrementOperations(&stats.FileCopyRangeOps)
+
+	if srcOffset >= srcInode.Size {
+		copiedLength = 0
+		err = nil
+		return
+	}
+
+	copiedLength = srcInode.Size - srcOffset
+	if copiedLength > length {
+		copiedLength = length
+	}
+	if 0 == copiedLength {
+		err = nil
+		return
+	}
+
+	srcEnd = srcOffset + copiedLength
+
+	if srcInode.isInline() {
+		// No extents to share... so just write the (small amount of) InlineData to dstInode
+
+		err = vS.Write(dstFileInodeNumber, dstOffset, append([]byte(nil), srcInode.InlineData[srcOffset:srcEnd]...), nil)
+		if nil != err {
+			logger.ErrorWithError(err)
+			return
+		}
+	} else {
+		// Ensure srcInode's extents only reference LogSegments that have been fully written
+
+		if srcInode.dirty {
+			err = vS.flushInode(srcInode)
+			if nil != err {
+				logger.ErrorWithError(err)
+				return
+			}
+		}
+
+		err = vS.promoteInlineData(dstInode)
+		if nil != err {
+			logger.ErrorWithError(err)
+			return
+		}
+
+		invalidateETag(dstInode)
+
+		// Collect the pieces of srcInode's extents overlapping the source range before modifying anything
+
+		pieces = make([]copyRangePieceStruct, 0)
+
+		extents = srcInode.payload.(sortedmap.BPlusTree)
+
+		curExtentIndex, _, err = extents.BisectLeft(srcOffset)
+		if nil != err {
+			panic(err)
+		}
+		if 0 > curExtentIndex {
+			curExtentIndex = 0
+		}
+
+		for {
+			curExtent = fetchFileExtent(extents, curExtentIndex)
+			if (nil == curExtent) || (curExtent.FileOffset >= srcEnd) {
+				break
+			}
+
+			overlapStart = curExtent.FileOffset
+			if overlapStart < srcOffset {
+				overlapStart = srcOffset
+			}
+			overlapEnd = curExtent.FileOffset + curExtent.Length
+			if overlapEnd > srcEnd {
+				overlapEnd = srcEnd
+			}
+
+			if overlapStart < overlapEnd {
+				pieces = append(pieces, copyRangePieceStruct{
+					fileOffset:       dstOffset + (overlapStart - srcOffset),
+					length:           overlapEnd - overlapStart,
+					logSegmentNumber: curExtent.LogSegmentNumber,
+					logSegmentOffset: curExtent.LogSegmentOffset + (overlapStart - curExtent.FileOffset),
+				})
+			}
+
+			curExtentIndex++
+		}
+
+		// Account for dstInode's new references to LogSegments it doesn't already reference
+
+		vS.dedupLock.Lock()
+
+		for _, piece = range pieces {
+			logSegmentNumber = piece.logSegmentNumber
+
+			_, ok = dstInode.LogSegmentMap[logSegmentNumber]
+			if ok {
+				continue
+			}
+
+			logSegmentRec, ok, err = vS.fetchDedupLogSegmentRec(logSegmentNumber)
+			if nil != err {
+				vS.dedupLock.Unlock()
+				logger.ErrorWithError(err)
+				return
+			}
+			if ok {
+				logSegmentRec.RefCount++
+			} else {
+				logSegmentRec = &dedupLogSegmentRecStruct{
+					RefCount:     2,
+					Fingerprints: make([]uint64, 0),
+				}
+			}
+
+			err = vS.putDedupLogSegmentRec(logSegmentNumber, logSegmentRec)
+			if nil != err {
+				vS.dedupLock.Unlock()
+				logger.ErrorWithError(err)
+				return
+			}
+
+			dstInode.LogSegmentMap[logSegmentNumber] = 0
+		}
+
+		vS.dedupLock.Unlock()
+
+		// Now replace the destination range with the collected pieces (leaving holes where srcInode had them)
+
+		pruneExtents(dstInode, dstOffset, copiedLength)
+
+		for _, piece = range pieces {
+			err = recordWrite(dstInode, piece.fileOffset, piece.length, piece.logSegmentNumber, piece.logSegmentOffset)
+			if nil != err {
+				logger.ErrorWithError(err)
+				return
+			}
+		}
+
+		if (dstOffset + copiedLength) > dstInode.Size {
+			dstInode.Size = dstOffset + copiedLength
+		}
+
+		dstInode.dirty = true
+
+		updateTime = time.Now()
+		dstInode.ModificationTime = updateTime
+		dstInode.AttrChangeTime = updateTime
+		dstInode.NumWrites++
+	}
+
+	err = vS.flushInode(dstInode)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+
+	stats.IncrementOperationsBy(&stats.FileCopyRangeBytes, copiedLength)
+
+	err = nil
+	return
+}
@@ -0,0 +1,154 @@
+package inode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+)
+
+func TestCopyRange(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	testVolume := testVolumeHandle.(*volumeStruct)
+
+	// Lay out the source as data [0:300), hole [300:400), data [400:500)
+
+	srcFileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	dstFileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+
+	srcBuf := make([]byte, 500)
+	copy(srcBuf[0:300], bytes.Repeat([]byte{0xA5}, 300))
+	copy(srcBuf[400:500], bytes.Repeat([]byte{0x5A}, 100))
+
+	err = testVolumeHandle.Write(srcFileInodeNumber, 0, srcBuf[0:300], nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(srcFileInodeNumber, 400, srcBuf[400:500], nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	dstBuf := bytes.Repeat([]byte{0xFF}, 100)
+
+	err = testVolumeHandle.Write(dstFileInodeNumber, 0, dstBuf, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	verify := func(fileInodeNumber InodeNumber, expectedBuf []byte) {
+		readBuf, err := testVolumeHandle.Read(fileInodeNumber, 0, uint64(len(expectedBuf))+1, nil)
+		if nil != err {
+			t.Fatalf("Read() failed: %v", err)
+		}
+		if !bytes.Equal(expectedBuf, readBuf) {
+			t.Fatalf("Read() of inode 0x%016X returned unexpected data", fileInodeNumber)
+		}
+	}
+
+	// Invalid requests
+
+	_, err = testVolumeHandle.CopyRange(srcFileInodeNumber, 0, srcFileInodeNumber, 100, 200)
+	if !blunder.Is(err, blunder.InvalidArgError) {
+		t.Fatalf("CopyRange() of overlapping ranges should have failed with EINVAL (got %v)", err)
+	}
+
+	copiedLength, err := testVolumeHandle.CopyRange(srcFileInodeNumber, 500, dstFileInodeNumber, 0, 100)
+	if (nil != err) || (0 != copiedLength) {
+		t.Fatalf("CopyRange() at end of source returned %v, %v (expected 0)", copiedLength, err)
+	}
+
+	// Copy [200:600) of the source to [50:450) of the destination... clipped to [200:500)
+
+	copiedLength, err = testVolumeHandle.CopyRange(srcFileInodeNumber, 200, dstFileInodeNumber, 50, 400)
+	if nil != err {
+		t.Fatalf("CopyRange() failed: %v", err)
+	}
+	if 300 != copiedLength {
+		t.Fatalf("CopyRange() should have copied 300 bytes (not %v)", copiedLength)
+	}
+
+	dstBuf = append(dstBuf[0:50], srcBuf[200:500]...)
+
+	verify(srcFileInodeNumber, srcBuf)
+	verify(dstFileInodeNumber, dstBuf)
+
+	holeOffset, err := testVolumeHandle.SeekHole(dstFileInodeNumber, 50)
+	if (nil != err) || (150 != holeOffset) {
+		t.Fatalf("SeekHole() after CopyRange() returned %v, %v (expected 150)", holeOffset, err)
+	}
+
+	// Every LogSegment referenced by both FileInodes should now be shared
+
+	srcInode, ok, err := testVolume.fetchInode(srcFileInodeNumber)
+	if (nil != err) || !ok {
+		t.Fatalf("fetchInode() failed: %v", err)
+	}
+	dstInode, ok, err := testVolume.fetchInode(dstFileInodeNumber)
+	if (nil != err) || !ok {
+		t.Fatalf("fetchInode() failed: %v", err)
+	}
+
+	sharedLogSegments := 0
+
+	for logSegmentNumber := range srcInode.LogSegmentMap {
+		_, ok = dstInode.LogSegmentMap[logSegmentNumber]
+		if !ok {
+			continue
+		}
+		sharedLogSegments++
+		logSegmentRec, ok, err := testVolume.fetchDedupLogSegmentRec(logSegmentNumber)
+		if (nil != err) || !ok {
+			t.Fatalf("fetchDedupLogSegmentRec(0x%016X) failed: %v", logSegmentNumber, err)
+		}
+		if 2 != logSegmentRec.RefCount {
+			t.Fatalf("LogSegment 0x%016X should have RefCount == 2 (not %v)", logSegmentNumber, logSegmentRec.RefCount)
+		}
+	}
+	if 0 == sharedLogSegments {
+		t.Fatalf("CopyRange() should have resulted in shared LogSegments")
+	}
+
+	// Overwriting & destroying the source must leave the destination readable
+
+	err = testVolumeHandle.Write(srcFileInodeNumber, 0, make([]byte, 500), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(srcFileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	verify(dstFileInodeNumber, dstBuf)
+
+	err = testVolumeHandle.Destroy(srcFileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	err = testVolumeHandle.Flush(dstFileInodeNumber, true)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	verify(dstFileInodeNumber, dstBuf)
+
+	err = testVolumeHandle.Destroy(dstFileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	testTeardown(t)
+}
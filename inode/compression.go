@@ -0,0 +1,531 @@
+package inode
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/keyprovider"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+	"github.com/swiftstack/ProxyFS/utils"
+)
+
+// When a volume's Compression is enabled (or the volume is encrypted), LogSegment data is accumulated
+// into units of (up to) compressionUnitSize bytes that are each compressed and/or encrypted independently
+// before being sent. Extents continue to reference LogSegments in logical (i.e. decoded) coordinates while
+// the LogSegment's LogSegmentRec (see logsegment_rec.go) describes where each unit's (possibly encoded) bytes reside.
+//
+const (
+	compressionNone = "None"
+
+	compressionUnitSize = uint64(256 * 1024)
+)
+
+type logSegmentCompressionUnitStruct struct { // Used (in a slice) in logSegmentRecStruct & inFlightLogSegmentStruct
+	LogicalOffset uint64 //                      Offset of the unit's data as referenced by fileExtentStruct.LogSegmentOffset
+	LogicalLength uint64 //                      Number of bytes of data in the unit
+	ObjectOffset  uint64 //                      Offset of the unit's (possibly encoded) bytes in the LogSegment object
+	ObjectLength  uint64 //                      If == LogicalLength (and !Encrypted), the unit is stored as is
+	Encrypted     bool   //                      If true, the unit's bytes are sealed with the volume's dataKey
+}
+
+// sealedPayloadLength returns the number of (possibly compressed) bytes a unit holds once any encryption is removed.
+//
+func (unit *logSegmentCompressionUnitStruct) sealedPayloadLength() (payloadLength uint64) {
+	payloadLength = unit.ObjectLength
+	if unit.Encrypted {
+		payloadLength -= keyprovider.SealOverhead
+	}
+	return
+}
+
+// unitAdditionalData binds a sealed unit to its location such that it cannot be substituted for another.
+//
+func unitAdditionalData(logSegmentNonce uint64, objectOffset uint64) (additionalData []byte) {
+	additionalData = append(utils.Uint64ToByteSlice(logSegmentNonce), utils.Uint64ToByteSlice(objectOffset)...)
+	return
+}
+
+// stepReferencesUnit returns true if a (translated) ReadPlanStep selects from a unit that must be decoded.
+//
+func stepReferencesUnit(step *ReadPlanStep) bool {
+	return ("" != step.Compression) || ("" != step.Encryption)
+}
+
+var compressionFlateWriterPool = sync.Pool{
+	New: func() interface{} {
+		flateWriter, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return flateWriter
+	},
+}
+
+// compressUnit returns the compressed form of buf... or buf itself if compression would not shrink it.
+//
+func compressUnit(buf []byte) (objectBuf []byte) {
+	var (
+		compressedBuffer bytes.Buffer
+		err              error
+		flateWriter      *flate.Writer
+	)
+
+	flateWriter = compressionFlateWriterPool.Get().(*flate.Writer)
+	defer compressionFlateWriterPool.Put(flateWriter)
+
+	flateWriter.Reset(&compressedBuffer)
+
+	_, err = flateWriter.Write(buf)
+	if nil == err {
+		err = flateWriter.Close()
+	}
+	if (nil != err) || (compressedBuffer.Len() >= len(buf)) {
+		objectBuf = buf
+		return
+	}
+
+	objectBuf = compressedBuffer.Bytes()
+
+	return
+}
+
+// decompressUnit returns the data of a compressed unit given its (decrypted) bytes fetched from the LogSegment.
+//
+func decompressUnit(objectBuf []byte) (buf []byte, err error) {
+	var (
+		flateReader io.ReadCloser
+	)
+
+	flateReader = flate.NewReader(bytes.NewReader(objectBuf))
+
+	buf, err = ioutil.ReadAll(flateReader)
+	_ = flateReader.Close()
+	if nil != err {
+		err = blunder.NewError(blunder.IOError, "LogSegment compression unit failed to decompress: %v", err)
+		return
+	}
+
+	err = nil
+	return
+}
+
+// decodeUnit returns the data of the unit referenced by a translated ReadPlanStep given its bytes fetched
+// from the LogSegment.
+//
+func (vS *volumeStruct) decodeUnit(step *ReadPlanStep, objectBuf []byte) (buf []byte, err error) {
+	buf = objectBuf
+
+	if "" != step.Encryption {
+		if nil == vS.dataKey {
+			err = blunder.NewError(blunder.IOError, "LogSegment %s is encrypted but Volume '%s' has no data key", step.ObjectPath, vS.volumeName)
+			return
+		}
+
+		_, _, logSegmentNonce := vS.headhunterVolumeHandle.SnapShotU64Decode(step.LogSegmentNumber)
+
+		buf, err = vS.dataKey.Open(objectBuf, unitAdditionalData(logSegmentNonce, step.CompressedObjectOffset))
+		if nil != err {
+			err = blunder.NewError(blunder.IOError, "LogSegment %s unit at ObjectOffset 0x%016X failed to decrypt: %v", step.ObjectPath, step.CompressedObjectOffset, err)
+			return
+		}
+	}
+
+	if "" != step.Compression {
+		buf, err = decompressUnit(buf)
+		if nil != err {
+			return
+		}
+	}
+
+	err = nil
+	return
+}
+
+// fetchLogSegmentCompressionUnits returns the compression units (if any) of the LogSegment referenced by a
+// ReadPlanStep. A nil return indicates the LogSegment data is not compressed. Callers must hold fileInode's
+// Lock as units are appended to an inFlightLogSegment's slice as its data is sent.
+//
+func (vS *volumeStruct) fetchLogSegmentCompressionUnits(fileInode *inMemoryInodeStruct, logSegmentNumber uint64) (units []logSegmentCompressionUnitStruct) {
+	var (
+		inFlightLogSegment *inFlightLogSegmentStruct
+		ok                 bool
+	)
+
+	inFlightLogSegment, ok = fileInode.inFlightLogSegmentMap[logSegmentNumber]
+	if ok {
+		if inFlightLogSegment.unitized {
+			units = inFlightLogSegment.compressionUnits
+		} else {
+			units = nil
+		}
+		return
+	}
+
+	units = vS.fetchClosedLogSegmentCompressionUnits(logSegmentNumber)
+
+	return
+}
+
+// fetchClosedLogSegmentCompressionUnits returns the compression units (if any) recorded for a LogSegment that
+// is no longer in flight (and, hence, needs no fileInode Lock). As only volumes with Compression enabled (or
+// that are encrypted) record them, the LogSegmentRec is otherwise not consulted.
+//
+func (vS *volumeStruct) fetchClosedLogSegmentCompressionUnits(logSegmentNumber uint64) (units []logSegmentCompressionUnitStruct) {
+	var (
+		err           error
+		logSegmentRec *logSegmentRecStruct
+	)
+
+	if !vS.unitizesLogSegments() {
+		units = nil
+		return
+	}
+
+	logSegmentRec, err = vS.fetchLogSegmentRec(logSegmentNumber)
+	if nil != err {
+		units = nil
+		return
+	}
+
+	units = logSegmentRec.compressionUnits
+
+	return
+}
+
+// unitizesLogSegments returns whether LogSegment data written to the volume is compressed and/or encrypted
+// in units.
+//
+func (vS *volumeStruct) unitizesLogSegments() (unitized bool) {
+	unitized = (compressionNone != vS.compression) || (nil != vS.dataKey)
+	return
+}
+
+// translateReadPlanStep splits a ReadPlanStep (in logical LogSegment coordinates) along the boundaries of
+// the LogSegment's compression units. Steps landing in units stored as is are rewritten to reference the
+// LogSegment object directly while the others describe the unit to fetch and decode.
+//
+func translateReadPlanStep(step ReadPlanStep, units []logSegmentCompressionUnitStruct) (translatedSteps []ReadPlanStep, err error) {
+	var (
+		offset          uint64
+		remainingLength uint64
+		translatedStep  ReadPlanStep
+		unit            *logSegmentCompressionUnitStruct
+		unitIndex       int
+		unitSkip        uint64
+	)
+
+	translatedSteps = make([]ReadPlanStep, 0, 1)
+
+	offset = step.Offset
+	remainingLength = step.Length
+
+	unitIndex = sort.Search(len(units), func(i int) bool {
+		return (units[i].LogicalOffset + units[i].LogicalLength) > offset
+	})
+
+	for 0 < remainingLength {
+		if unitIndex >= len(units) {
+			err = fmt.Errorf("LogSegment %s has no compression unit for Offset 0x%016X", step.ObjectPath, offset)
+			return
+		}
+
+		unit = &units[unitIndex]
+
+		if unit.LogicalOffset > offset {
+			err = fmt.Errorf("LogSegment %s compression units skip Offset 0x%016X", step.ObjectPath, offset)
+			return
+		}
+
+		translatedStep = step
+		unitSkip = offset - unit.LogicalOffset
+		translatedStep.Length = unit.LogicalLength - unitSkip
+		if translatedStep.Length > remainingLength {
+			translatedStep.Length = remainingLength
+		}
+
+		if !unit.Encrypted && (unit.ObjectLength == unit.LogicalLength) {
+			translatedStep.Offset = unit.ObjectOffset + unitSkip
+		} else {
+			translatedStep.Offset = unitSkip
+			if unit.sealedPayloadLength() != unit.LogicalLength {
+				translatedStep.Compression = CompressionDeflate
+			}
+			if unit.Encrypted {
+				translatedStep.Encryption = EncryptionAESGCM
+			}
+			translatedStep.CompressedObjectOffset = unit.ObjectOffset
+			translatedStep.CompressedObjectLength = unit.ObjectLength
+		}
+
+		translatedSteps = append(translatedSteps, translatedStep)
+
+		offset += translatedStep.Length
+		remainingLength -= translatedStep.Length
+		unitIndex++
+	}
+
+	err = nil
+	return
+}
+
+// translateReadPlan applies translateReadPlanStep() to every step of readPlan referencing a LogSegment
+// holding compressed (or encrypted) data. The fileInode must have been flushed such that no such LogSegment is in flight.
+//
+func (vS *volumeStruct) translateReadPlan(fileInode *inMemoryInodeStruct, readPlan []ReadPlanStep) (translatedReadPlan []ReadPlanStep, err error) {
+	var (
+		step            ReadPlanStep
+		translatedSteps []ReadPlanStep
+		units           []logSegmentCompressionUnitStruct
+	)
+
+	translatedReadPlan = make([]ReadPlanStep, 0, len(readPlan))
+
+	for _, step = range readPlan {
+		if 0 != step.LogSegmentNumber {
+			fileInode.Lock()
+			units = vS.fetchLogSegmentCompressionUnits(fileInode, step.LogSegmentNumber)
+			fileInode.Unlock()
+
+			if nil != units {
+				translatedSteps, err = translateReadPlanStep(step, units)
+				if nil != err {
+					return
+				}
+				translatedReadPlan = append(translatedReadPlan, translatedSteps...)
+				continue
+			}
+		}
+
+		translatedReadPlan = append(translatedReadPlan, step)
+	}
+
+	err = nil
+	return
+}
+
+// sendCompressedChunk is called in place of SendChunk() for inFlightLogSegments of volumes with Compression
+// enabled (or that are encrypted). The returned logSegmentOffset is the logical offset at which buf will reside in the LogSegment.
+// Callers must hold fileInode's Lock.
+//
+func (vS *volumeStruct) sendCompressedChunk(inFlightLogSegment *inFlightLogSegmentStruct, buf []byte) (logSegmentOffset uint64, err error) {
+	logSegmentOffset = inFlightLogSegment.compressionPendingOffset + uint64(len(inFlightLogSegment.compressionPendingBuf))
+
+	inFlightLogSegment.compressionPendingBuf = append(inFlightLogSegment.compressionPendingBuf, buf...)
+
+	for uint64(len(inFlightLogSegment.compressionPendingBuf)) >= compressionUnitSize {
+		err = vS.sendCompressionUnit(inFlightLogSegment, compressionUnitSize)
+		if nil != err {
+			return
+		}
+	}
+
+	err = nil
+	return
+}
+
+// sendCompressionUnit compresses and/or encrypts and sends the first logicalLength bytes of an inFlightLogSegment's
+// pending data. Callers must hold the associated fileInode's Lock.
+//
+func (vS *volumeStruct) sendCompressionUnit(inFlightLogSegment *inFlightLogSegmentStruct, logicalLength uint64) (err error) {
+	var (
+		objectBuf    []byte
+		objectOffset uint64
+		unit         logSegmentCompressionUnitStruct
+	)
+
+	objectOffset, err = inFlightLogSegment.BytesPut()
+	if nil != err {
+		logger.ErrorfWithError(err, "Failed to get current LogSegment ObjectOffset")
+		return
+	}
+
+	objectBuf = inFlightLogSegment.compressionPendingBuf[:logicalLength]
+
+	if compressionNone != vS.compression {
+		objectBuf = compressUnit(objectBuf)
+	}
+
+	if nil != vS.dataKey {
+		objectBuf = vS.dataKey.Seal(objectBuf, unitAdditionalData(inFlightLogSegment.logSegmentNumber, objectOffset))
+	}
+
+	err = inFlightLogSegment.ChunkedPutContext.SendChunk(objectBuf)
+	if nil != err {
+		logger.ErrorfWithError(err, "Sending Chunked PUT chunk to LogSegment failed")
+		return
+	}
+
+	if nil != inFlightLogSegment.checksummer {
+		inFlightLogSegment.checksummer.update(objectBuf)
+	}
+
+	unit = logSegmentCompressionUnitStruct{
+		LogicalOffset: inFlightLogSegment.compressionPendingOffset,
+		LogicalLength: logicalLength,
+		ObjectOffset:  objectOffset,
+		ObjectLength:  uint64(len(objectBuf)),
+		Encrypted:     (nil != vS.dataKey),
+	}
+
+	inFlightLogSegment.compressionUnits = append(inFlightLogSegment.compressionUnits, unit)
+
+	// The ChunkedPutContext may retain objectBuf (which could be a slice of compressionPendingBuf) for
+	// reading back, so start a fresh compressionPendingBuf for whatever remains
+
+	inFlightLogSegment.compressionPendingBuf = append(make([]byte, 0, compressionUnitSize), inFlightLogSegment.compressionPendingBuf[logicalLength:]...)
+	inFlightLogSegment.compressionPendingOffset += logicalLength
+
+	err = nil
+	return
+}
+
+// readInFlightLogSegment reads [offset:offset+length) (in logical LogSegment coordinates) of an inFlightLogSegment.
+// Callers must hold the associated fileInode's Lock.
+//
+func (vS *volumeStruct) readInFlightLogSegment(inFlightLogSegment *inFlightLogSegmentStruct, offset uint64, length uint64) (buf []byte, err error) {
+	var (
+		objectBuf       []byte
+		pendingSkip     uint64
+		step            ReadPlanStep
+		translatedStep  ReadPlanStep
+		translatedSteps []ReadPlanStep
+		unitBuf         []byte
+		units           []logSegmentCompressionUnitStruct
+	)
+
+	if !inFlightLogSegment.unitized {
+		buf, err = inFlightLogSegment.Read(offset, length)
+		return
+	}
+
+	buf = make([]byte, 0, length)
+
+	if offset < inFlightLogSegment.compressionPendingOffset {
+		// Some (or all) of the requested range has already been sent
+
+		step = ReadPlanStep{LogSegmentNumber: inFlightLogSegment.logSegmentNumber, Offset: offset, Length: length}
+		if (offset + length) > inFlightLogSegment.compressionPendingOffset {
+			step.Length = inFlightLogSegment.compressionPendingOffset - offset
+		}
+
+		units = inFlightLogSegment.compressionUnits
+
+		translatedSteps, err = translateReadPlanStep(step, units)
+		if nil != err {
+			return
+		}
+
+		for _, translatedStep = range translatedSteps {
+			if !stepReferencesUnit(&translatedStep) {
+				objectBuf, err = inFlightLogSegment.Read(translatedStep.Offset, translatedStep.Length)
+				if nil != err {
+					return
+				}
+				buf = append(buf, objectBuf...)
+			} else {
+				objectBuf, err = inFlightLogSegment.Read(translatedStep.CompressedObjectOffset, translatedStep.CompressedObjectLength)
+				if nil != err {
+					return
+				}
+				unitBuf, err = vS.decodeUnit(&translatedStep, objectBuf)
+				if nil != err {
+					return
+				}
+				if (translatedStep.Offset + translatedStep.Length) > uint64(len(unitBuf)) {
+					err = fmt.Errorf("Invalid range for compression unit of inFlightLogSegment")
+					return
+				}
+				buf = append(buf, unitBuf[translatedStep.Offset:(translatedStep.Offset+translatedStep.Length)]...)
+			}
+		}
+
+		offset += step.Length
+		length -= step.Length
+	}
+
+	if 0 < length {
+		// The remainder of the requested range is still pending
+
+		pendingSkip = offset - inFlightLogSegment.compressionPendingOffset
+		if (pendingSkip + length) > uint64(len(inFlightLogSegment.compressionPendingBuf)) {
+			err = fmt.Errorf("Invalid range for pending compression unit of inFlightLogSegment")
+			return
+		}
+
+		buf = append(buf, inFlightLogSegment.compressionPendingBuf[pendingSkip:(pendingSkip+length)]...)
+	}
+
+	err = nil
+	return
+}
+
+// readCompressedUnit returns the decoded data of the unit described by a translated ReadPlanStep
+// by way of the Read Cache. The unit's bytes are verified against the LogSegment's checksums (if known)
+// when fetched.
+//
+func (vS *volumeStruct) readCompressedUnit(fileInode *inMemoryInodeStruct, step ReadPlanStep) (unitBuf []byte, err error) {
+	var (
+		objectBuf        []byte
+		readCacheElement *readCacheElementStruct
+		readCacheHit     bool
+		readCacheKey     readCacheKeyStruct
+		volumeGroup      *volumeGroupStruct
+	)
+
+	volumeGroup = vS.volumeGroup
+
+	readCacheKey = readCacheKeyStruct{
+		volumeName:       vS.volumeName,
+		logSegmentNumber: step.LogSegmentNumber,
+		cacheLineTag:     step.CompressedObjectOffset,
+		compressedUnit:   true,
+	}
+
+	volumeGroup.Lock()
+	readCacheElement, readCacheHit = volumeGroup.readCache[readCacheKey]
+	if readCacheHit {
+		volumeGroup.touchReadCacheElementWhileLocked(readCacheElement)
+		unitBuf = readCacheElement.cacheLine
+		volumeGroup.Unlock()
+		stats.IncrementOperations(&stats.FileReadcacheHitOps)
+		err = nil
+		return
+	}
+	volumeGroup.Unlock()
+
+	stats.IncrementOperations(&stats.FileReadcacheMissOps)
+
+	objectBuf, err = vS.getLogSegmentRange(fileInode.InodeNumber, step, vS.fetchLogSegmentChecksums(step.LogSegmentNumber), step.CompressedObjectOffset, step.CompressedObjectLength)
+	if nil != err {
+		logger.ErrorfWithError(err, "Reading compression unit from LogSegment object failed")
+		return
+	}
+
+	unitBuf, err = vS.decodeUnit(&step, objectBuf)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+	if (step.Offset + step.Length) > uint64(len(unitBuf)) {
+		err = blunder.NewError(blunder.SegReadError, "Invalid range for decoded LogSegment compression unit")
+		logger.ErrorWithError(err)
+		return
+	}
+
+	readCacheElement = &readCacheElementStruct{
+		readCacheKey: readCacheKey,
+		next:         nil,
+		prev:         nil,
+		cacheLine:    unitBuf,
+	}
+	volumeGroup.Lock()
+	volumeGroup.insertReadCacheElementWhileLocked(readCacheElement)
+	volumeGroup.Unlock()
+
+	err = nil
+	return
+}
@@ -0,0 +1,177 @@
+package inode
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/swiftclient"
+)
+
+func TestTranslateReadPlanStep(t *testing.T) {
+	units := []logSegmentCompressionUnitStruct{
+		{LogicalOffset: 0, LogicalLength: 100, ObjectOffset: 0, ObjectLength: 10},
+		{LogicalOffset: 100, LogicalLength: 50, ObjectOffset: 10, ObjectLength: 50},
+		{LogicalOffset: 150, LogicalLength: 100, ObjectOffset: 60, ObjectLength: 20},
+	}
+
+	translatedSteps, err := translateReadPlanStep(ReadPlanStep{LogSegmentNumber: 1, Offset: 90, Length: 100}, units)
+	if nil != err {
+		t.Fatalf("translateReadPlanStep() failed: %v", err)
+	}
+	if 3 != len(translatedSteps) {
+		t.Fatalf("translateReadPlanStep() returned %v steps... expected 3", len(translatedSteps))
+	}
+	if (CompressionDeflate != translatedSteps[0].Compression) || (0 != translatedSteps[0].CompressedObjectOffset) || (10 != translatedSteps[0].CompressedObjectLength) || (90 != translatedSteps[0].Offset) || (10 != translatedSteps[0].Length) {
+		t.Fatalf("translateReadPlanStep() returned unexpected 1st step: %+v", translatedSteps[0])
+	}
+	if ("" != translatedSteps[1].Compression) || (10 != translatedSteps[1].Offset) || (50 != translatedSteps[1].Length) {
+		t.Fatalf("translateReadPlanStep() returned unexpected 2nd step: %+v", translatedSteps[1])
+	}
+	if (CompressionDeflate != translatedSteps[2].Compression) || (60 != translatedSteps[2].CompressedObjectOffset) || (0 != translatedSteps[2].Offset) || (40 != translatedSteps[2].Length) {
+		t.Fatalf("translateReadPlanStep() returned unexpected 3rd step: %+v", translatedSteps[2])
+	}
+
+	_, err = translateReadPlanStep(ReadPlanStep{LogSegmentNumber: 1, Offset: 200, Length: 100}, units)
+	if nil == err {
+		t.Fatalf("translateReadPlanStep() beyond last unit should have failed")
+	}
+}
+
+func TestCompression(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	testVolume := testVolumeHandle.(*volumeStruct)
+
+	testVolume.compression = CompressionDeflate
+	defer func() {
+		testVolume.compression = compressionNone
+	}()
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+
+	// Write a highly compressible (CSV-like) prefix followed by an incompressible suffix
+
+	var expectedBuf bytes.Buffer
+	for row := 0; expectedBuf.Len() < int(2*compressionUnitSize); row++ {
+		fmt.Fprintf(&expectedBuf, "%d,2026-10-18T00:00:00Z,INFO,some repetitive log message\n", row)
+	}
+	randomBuf := make([]byte, compressionUnitSize)
+	rand.New(rand.NewSource(0)).Read(randomBuf)
+	expectedBuf.Write(randomBuf)
+	expected := expectedBuf.Bytes()
+
+	for offset := 0; offset < len(expected); offset += 10000 {
+		end := offset + 10000
+		if end > len(expected) {
+			end = len(expected)
+		}
+		err = testVolumeHandle.Write(fileInodeNumber, uint64(offset), expected[offset:end], nil)
+		if nil != err {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+
+	// Read back while the LogSegment is still in flight (covering both sent and pending data)
+
+	readBuf, err := testVolumeHandle.Read(fileInodeNumber, 0, uint64(len(expected)), nil)
+	if nil != err {
+		t.Fatalf("Read() of inFlightLogSegment failed: %v", err)
+	}
+	if !bytes.Equal(expected, readBuf) {
+		t.Fatalf("Read() of inFlightLogSegment returned unexpected data")
+	}
+
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	var zero uint64
+	readPlan, err := testVolumeHandle.GetReadPlan(fileInodeNumber, &zero, nil)
+	if nil != err {
+		t.Fatalf("GetReadPlan() failed: %v", err)
+	}
+
+	// The compression units should have been recorded with the LogSegment
+
+	logSegmentRec, err := testVolume.fetchLogSegmentRec(readPlan[0].LogSegmentNumber)
+	if nil != err {
+		t.Fatalf("fetchLogSegmentRec() failed: %v", err)
+	}
+	if uint64(len(logSegmentRec.compressionUnits)) != ((uint64(len(expected)) + compressionUnitSize - 1) / compressionUnitSize) {
+		t.Fatalf("LogSegmentRec recorded unexpected number of compression units (%v)", len(logSegmentRec.compressionUnits))
+	}
+	compressedSteps := 0
+	for _, step := range readPlan {
+		if "" != step.Compression {
+			compressedSteps++
+		}
+	}
+	if (0 == compressedSteps) || (compressedSteps == len(readPlan)) {
+		t.Fatalf("GetReadPlan() should have returned both compressed & uncompressed steps: %+v", readPlan)
+	}
+
+	objectLength, err := swiftclient.ObjectContentLength(readPlan[0].AccountName, readPlan[0].ContainerName, readPlan[0].ObjectName)
+	if nil != err {
+		t.Fatalf("ObjectContentLength() failed: %v", err)
+	}
+	if objectLength >= (compressionUnitSize + compressionUnitSize) {
+		t.Fatalf("LogSegment object should have been compressed (ObjectContentLength() returned %v)", objectLength)
+	}
+
+	// Read back (ranges straddling compression units) from Swift
+
+	testVolume.volumeGroup.Lock()
+	testVolume.volumeGroup.purgeReadCacheWhileLocked()
+	testVolume.volumeGroup.Unlock()
+
+	readBuf, err = testVolumeHandle.Read(fileInodeNumber, 0, uint64(len(expected)), nil)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if !bytes.Equal(expected, readBuf) {
+		t.Fatalf("Read() returned unexpected data")
+	}
+
+	readBuf, err = testVolumeHandle.Read(fileInodeNumber, 2*compressionUnitSize-5, 10, nil)
+	if nil != err {
+		t.Fatalf("Read() straddling compression units failed: %v", err)
+	}
+	if !bytes.Equal(expected[2*compressionUnitSize-5:2*compressionUnitSize+5], readBuf) {
+		t.Fatalf("Read() straddling compression units returned unexpected data")
+	}
+
+	// Overwrite a portion and confirm the result merges logical data correctly
+
+	err = testVolumeHandle.Write(fileInodeNumber, 100, []byte("OVERWRITTEN"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	copy(expected[100:], []byte("OVERWRITTEN"))
+
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	readBuf, err = testVolumeHandle.Read(fileInodeNumber, 0, uint64(len(expected)), nil)
+	if (nil != err) || !bytes.Equal(expected, readBuf) {
+		t.Fatalf("Read() after overwrite returned unexpected data or error: %v", err)
+	}
+
+	err = testVolumeHandle.Validate(fileInodeNumber, true)
+	if nil != err {
+		t.Fatalf("Validate() failed on compressed file: %v", err)
+	}
+
+	testTeardown(t)
+}
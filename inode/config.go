@@ -2,14 +2,17 @@ package inode
 
 import (
 	"fmt"
+	"sync"
 	"time"
 	"unsafe"
 
 	"github.com/swiftstack/cstruct"
 	"github.com/swiftstack/sortedmap"
 
+	"github.com/swiftstack/ProxyFS/cachebudget"
 	"github.com/swiftstack/ProxyFS/conf"
 	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/keyprovider"
 	"github.com/swiftstack/ProxyFS/swiftclient"
 	"github.com/swiftstack/ProxyFS/trackedlock"
 	"github.com/swiftstack/ProxyFS/transitions"
@@ -18,29 +21,51 @@ import (
 type readCacheKeyStruct struct {
 	volumeName       string
 	logSegmentNumber uint64
-	cacheLineTag     uint64 // LogSegment offset / readCacheLineSize
+	cacheLineTag     uint64 // LogSegment offset / readCacheLineSize (or ObjectOffset of a compression unit)
+	compressedUnit   bool   // If true, the cacheLine holds a decoded compression unit
 }
 
 type readCacheElementStruct struct {
 	readCacheKey readCacheKeyStruct
-	next         *readCacheElementStruct // nil if MRU element of volumeGroupStruct.readCache
-	prev         *readCacheElementStruct // nil if LRU element of volumeGroupStruct.readCache
+	next         *readCacheElementStruct // nil if MRU element of its (A1in or Am) list
+	prev         *readCacheElementStruct // nil if LRU element of its (A1in or Am) list
 	cacheLine    []byte
+	prefetched   bool // true if inserted by read-ahead and not yet hit
+	inA1in       bool // true if on the A1in list... false if on the Am list
+}
+
+type readCacheA1outEntryStruct struct {
+	readCacheKey readCacheKeyStruct
+	seq          uint64 // only matches volumeGroupStruct.readCacheA1out[readCacheKey] if this is the latest eviction of readCacheKey
 }
 
 type volumeGroupStruct struct {
 	trackedlock.Mutex
-	name                    string
-	volumeMap               map[string]*volumeStruct // key == volumeStruct.volumeName
-	numServed               uint64
-	virtualIPAddr           string
-	activePeerPrivateIPAddr string
-	readCacheLineSize       uint64
-	readCacheWeight         uint64
-	readCacheLineCount      uint64
-	readCache               map[readCacheKeyStruct]*readCacheElementStruct
-	readCacheMRU            *readCacheElementStruct
-	readCacheLRU            *readCacheElementStruct
+	name                     string
+	volumeMap                map[string]*volumeStruct // key == volumeStruct.volumeName
+	numServed                uint64
+	virtualIPAddr            string
+	activePeerPrivateIPAddr  string
+	readCacheLineSize        uint64
+	readCacheWeight          uint64
+	readCacheLineCount       uint64
+	readCacheStaticLineCount uint64 // as computed by adoptVolumeGroupReadCacheParameters()
+	readCacheBudgetBytes     uint64 // if non-zero, determines readCacheLineCount (see cachebudget)
+	readCache                map[readCacheKeyStruct]*readCacheElementStruct
+	readCacheMRU             *readCacheElementStruct       // MRU element of the Am list
+	readCacheLRU             *readCacheElementStruct       // LRU element of the Am list
+	readCacheA1inMRU         *readCacheElementStruct       // most recently inserted element of the A1in list
+	readCacheA1inLRU         *readCacheElementStruct       // least recently inserted element of the A1in list
+	readCacheA1inCount       uint64                        // number of elements on the A1in list
+	readCacheA1out           map[readCacheKeyStruct]uint64 // keys recently evicted from the A1in list (value == readCacheA1outNextSeq at eviction)
+	readCacheA1outFIFO       []readCacheA1outEntryStruct   // readCacheA1out in eviction order
+	readCacheA1outNextSeq    uint64
+	readCacheBytes           uint64
+	readCacheHits            uint64
+	readCacheMisses          uint64
+	readAheadMaxLines        uint64                          // maximum read-ahead window (in cache lines) of a FileInode (0 disables)
+	readAheadMaxInFlight     uint64                          // maximum number of cache lines being prefetched at once
+	readAheadInFlight        map[readCacheKeyStruct]struct{} // cache lines currently being prefetched
 }
 
 type physicalContainerLayoutStruct struct {
@@ -68,6 +93,16 @@ type volumeStruct struct {
 	maxExtentsPerFileNode          uint64
 	defaultPhysicalContainerLayout *physicalContainerLayoutStruct
 	maxFlushSize                   uint64
+	dataChecksums                  bool
+	compression                    string
+	deduplication                  bool
+	maintainETag                   bool                // if true, UpdateETag() maintains FileInode content MD5s
+	eTagGenerations                uint64              // incremented (atomically) by each invalidateETag() and inode fetch
+	caseInsensitiveLookup          bool                // if true, DirInodes match basenames case-insensitively (see casefold.go)
+	dedupLock                      trackedlock.Mutex   // serializes updates to the fingerprint index & LogSegment reference counts
+	inlineDataThreshold            uint64              // FileInodes no larger than this hold their data in InlineData (0 disables)
+	onDiskInodeVersion             Version             // Version used when (re)writing inode records (V1 or V2)
+	dataKey                        keyprovider.DataKey // if != nil, LogSegment data is encrypted
 	headhunterVolumeHandle         headhunter.VolumeHandle
 	inodeCache                     sortedmap.LLRBTree //                        key == InodeNumber; value == *inMemoryInodeStruct
 	inodeCacheLRUHead              *inMemoryInodeStruct
@@ -77,15 +112,18 @@ type volumeStruct struct {
 	inodeCacheLRUTicker            *time.Ticker
 	inodeCacheLRUTickerInterval    time.Duration
 	snapShotPolicy                 *snapShotPolicyStruct
+	readAheadWG                    sync.WaitGroup // tracks outstanding read-ahead prefetches
 }
 
 type globalsStruct struct {
 	trackedlock.Mutex
 	whoAmI                             string
 	myPrivateIPAddr                    string
+	dirEntryCacheBudget                *cachebudget.BPlusTreeCache
 	dirEntryCache                      sortedmap.BPlusTreeCache
 	dirEntryCachePriorCacheHits        uint64
 	dirEntryCachePriorCacheMisses      uint64
+	fileExtentMapCacheBudget           *cachebudget.BPlusTreeCache
 	fileExtentMapCache                 sortedmap.BPlusTreeCache
 	fileExtentMapCachePriorCacheHits   uint64
 	fileExtentMapCachePriorCacheMisses uint64
@@ -97,7 +135,9 @@ type globalsStruct struct {
 	corruptionDetectedTrueBuf          []byte                        // holds serialized CorruptionDetected == true
 	corruptionDetectedFalseBuf         []byte                        // holds serialized CorruptionDetected == false
 	versionV1Buf                       []byte                        // holds serialized Version            == V1
+	versionV2Buf                       []byte                        // holds serialized Version            == V2
 	inodeRecDefaultPreambleBuf         []byte                        // holds concatenated corruptionDetectedFalseBuf & versionV1Buf
+	inodeRecV2PreambleBuf              []byte                        // holds concatenated corruptionDetectedFalseBuf & versionV2Buf
 	inodeSize                          uint64                        // size of in-memory inode struct
 	openLogSegmentLRUHead              *inFlightLogSegmentStruct
 	openLogSegmentLRUTail              *inFlightLogSegmentStruct
@@ -125,6 +165,7 @@ func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
 		peerPrivateIPAddrMap        map[string]string
 		tempInode                   inMemoryInodeStruct
 		versionV1                   = Version(V1)
+		versionV2                   = Version(V2)
 	)
 
 	peerPrivateIPAddrMap = make(map[string]string)
@@ -162,7 +203,9 @@ func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
 		return
 	}
 
-	globals.dirEntryCache = sortedmap.NewBPlusTreeCache(dirEntryCacheEvictLowLimit, dirEntryCacheEvictHighLimit)
+	globals.dirEntryCacheBudget = cachebudget.NewBPlusTreeCache(dirEntryCacheEvictLowLimit, dirEntryCacheEvictHighLimit, bPlusTreeNodeBytesEstimate)
+	globals.dirEntryCache = globals.dirEntryCacheBudget.BPlusTreeCache
+	cachebudget.Register("inode_dirEntryCache", globals.dirEntryCacheBudget)
 
 	globals.dirEntryCachePriorCacheHits = 0
 	globals.dirEntryCachePriorCacheMisses = 0
@@ -176,7 +219,9 @@ func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
 		return
 	}
 
-	globals.fileExtentMapCache = sortedmap.NewBPlusTreeCache(fileExtentMapEvictLowLimit, fileExtentMapEvictHighLimit)
+	globals.fileExtentMapCacheBudget = cachebudget.NewBPlusTreeCache(fileExtentMapEvictLowLimit, fileExtentMapEvictHighLimit, bPlusTreeNodeBytesEstimate)
+	globals.fileExtentMapCache = globals.fileExtentMapCacheBudget.BPlusTreeCache
+	cachebudget.Register("inode_fileExtentMapCache", globals.fileExtentMapCacheBudget)
 
 	globals.fileExtentMapCachePriorCacheHits = 0
 	globals.fileExtentMapCachePriorCacheMisses = 0
@@ -199,6 +244,7 @@ func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
 	globals.supportedOnDiskInodeVersions = make(map[Version]struct{})
 
 	globals.supportedOnDiskInodeVersions[V1] = struct{}{}
+	globals.supportedOnDiskInodeVersions[V2] = struct{}{}
 
 	globals.corruptionDetectedTrueBuf, err = cstruct.Pack(corruptionDetectedTrue, cstruct.LittleEndian)
 	if nil != err {
@@ -212,11 +258,19 @@ func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
 	if nil != err {
 		return
 	}
+	globals.versionV2Buf, err = cstruct.Pack(versionV2, cstruct.LittleEndian)
+	if nil != err {
+		return
+	}
 
 	globals.inodeRecDefaultPreambleBuf = make([]byte, 0, len(globals.corruptionDetectedFalseBuf)+len(globals.versionV1Buf))
 	globals.inodeRecDefaultPreambleBuf = append(globals.inodeRecDefaultPreambleBuf, globals.corruptionDetectedFalseBuf...)
 	globals.inodeRecDefaultPreambleBuf = append(globals.inodeRecDefaultPreambleBuf, globals.versionV1Buf...)
 
+	globals.inodeRecV2PreambleBuf = make([]byte, 0, len(globals.corruptionDetectedFalseBuf)+len(globals.versionV2Buf))
+	globals.inodeRecV2PreambleBuf = append(globals.inodeRecV2PreambleBuf, globals.corruptionDetectedFalseBuf...)
+	globals.inodeRecV2PreambleBuf = append(globals.inodeRecV2PreambleBuf, globals.versionV2Buf...)
+
 	swiftclient.SetStarvationCallbackFunc(chunkedPutConnectionPoolStarvationCallback)
 
 	err = nil
@@ -238,6 +292,8 @@ func (dummy *globalsStruct) VolumeGroupCreated(confMap conf.ConfMap, volumeGroup
 		readCache:          make(map[readCacheKeyStruct]*readCacheElementStruct),
 		readCacheMRU:       nil,
 		readCacheLRU:       nil,
+		readCacheA1out:     make(map[readCacheKeyStruct]uint64),
+		readAheadInFlight:  make(map[readCacheKeyStruct]struct{}),
 	}
 
 	volumeGroupSectionName = "VolumeGroup:" + volumeGroupName
@@ -270,6 +326,16 @@ func (dummy *globalsStruct) VolumeGroupCreated(confMap conf.ConfMap, volumeGroup
 		return
 	}
 
+	volumeGroup.readAheadMaxLines, err = confMap.FetchOptionValueUint64(volumeGroupSectionName, "ReadAheadMaxLines")
+	if nil != err {
+		volumeGroup.readAheadMaxLines = 0 // TODO: Eventually, just return
+	}
+
+	volumeGroup.readAheadMaxInFlight, err = confMap.FetchOptionValueUint64(volumeGroupSectionName, "ReadAheadMaxInFlightLines")
+	if nil != err {
+		volumeGroup.readAheadMaxInFlight = 16 // TODO: Eventually, just return
+	}
+
 	globals.Lock()
 
 	_, ok = globals.volumeGroupMap[volumeGroupName]
@@ -283,6 +349,8 @@ func (dummy *globalsStruct) VolumeGroupCreated(confMap conf.ConfMap, volumeGroup
 
 	globals.Unlock()
 
+	cachebudget.Register("inode_readCache_"+volumeGroupName, volumeGroup)
+
 	err = nil
 	return
 }
@@ -353,6 +421,8 @@ func (dummy *globalsStruct) VolumeGroupDestroyed(confMap conf.ConfMap, volumeGro
 	volumeGroup.Unlock()
 	globals.Unlock()
 
+	cachebudget.UnRegister("inode_readCache_" + volumeGroupName)
+
 	err = nil
 	return
 }
@@ -521,6 +591,7 @@ func (dummy *globalsStruct) ServeVolume(confMap conf.ConfMap, volumeName string)
 		defaultPhysicalContainerLayoutName        string
 		defaultPhysicalContainerLayoutSectionName string
 		ok                                        bool
+		onDiskInodeVersionAsUint64                uint64
 		volume                                    *volumeStruct
 		volumeSectionName                         string
 	)
@@ -596,6 +667,57 @@ func (dummy *globalsStruct) ServeVolume(confMap conf.ConfMap, volumeName string)
 		return
 	}
 
+	volume.dataChecksums, err = confMap.FetchOptionValueBool(volumeSectionName, "DataChecksums")
+	if nil != err {
+		volume.dataChecksums = false // TODO: Eventually, just return
+	}
+
+	volume.compression, err = confMap.FetchOptionValueString(volumeSectionName, "Compression")
+	if nil != err {
+		volume.compression = compressionNone // TODO: Eventually, just return
+	}
+	switch volume.compression {
+	case compressionNone, CompressionDeflate:
+		// Supported
+	default:
+		err = fmt.Errorf("Volume %s has unsupported Compression \"%s\"", volume.volumeName, volume.compression)
+		globals.Unlock()
+		return
+	}
+
+	volume.deduplication, err = confMap.FetchOptionValueBool(volumeSectionName, "Deduplication")
+	if nil != err {
+		volume.deduplication = false // TODO: Eventually, just return
+	}
+
+	volume.maintainETag, err = confMap.FetchOptionValueBool(volumeSectionName, "MaintainETag")
+	if nil != err {
+		volume.maintainETag = false // TODO: Eventually, just return
+	}
+
+	volume.caseInsensitiveLookup, err = confMap.FetchOptionValueBool(volumeSectionName, "CaseInsensitiveLookup")
+	if nil != err {
+		volume.caseInsensitiveLookup = false // TODO: Eventually, just return
+	}
+
+	volume.inlineDataThreshold, err = confMap.FetchOptionValueUint64(volumeSectionName, "InlineDataThreshold")
+	if nil != err {
+		volume.inlineDataThreshold = 0 // TODO: Eventually, just return
+	}
+
+	onDiskInodeVersionAsUint64, err = confMap.FetchOptionValueUint64(volumeSectionName, "OnDiskInodeVersion")
+	if nil == err {
+		volume.onDiskInodeVersion = Version(onDiskInodeVersionAsUint64)
+		_, ok = globals.supportedOnDiskInodeVersions[volume.onDiskInodeVersion]
+		if !ok {
+			err = fmt.Errorf("Volume %s has unsupported OnDiskInodeVersion (%v)", volume.volumeName, onDiskInodeVersionAsUint64)
+			globals.Unlock()
+			return
+		}
+	} else {
+		volume.onDiskInodeVersion = V2 // TODO: Eventually, just return
+	}
+
 	volume.headhunterVolumeHandle, err = headhunter.FetchVolumeHandle(volume.volumeName)
 	if nil != err {
 		globals.Unlock()
@@ -604,6 +726,8 @@ func (dummy *globalsStruct) ServeVolume(confMap conf.ConfMap, volumeName string)
 
 	volume.headhunterVolumeHandle.RegisterForEvents(volume)
 
+	volume.dataKey = volume.headhunterVolumeHandle.FetchDataKey()
+
 	volume.inodeCache = sortedmap.NewLLRBTree(compareInodeNumber, volume)
 	volume.inodeCacheLRUHead = nil
 	volume.inodeCacheLRUTail = nil
@@ -649,6 +773,8 @@ func (dummy *globalsStruct) UnserveVolume(confMap conf.ConfMap, volumeName strin
 		return
 	}
 
+	volume.readAheadWG.Wait()
+
 	stopInodeCacheDiscard(volume)
 
 	volume.inodeCache = nil
@@ -718,6 +844,9 @@ func (dummy *globalsStruct) Down(confMap conf.ConfMap) (err error) {
 		return
 	}
 
+	cachebudget.UnRegister("inode_dirEntryCache")
+	cachebudget.UnRegister("inode_fileExtentMapCache")
+
 	err = nil
 	return
 }
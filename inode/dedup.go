@@ -0,0 +1,554 @@
+package inode
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+
+	"github.com/swiftstack/cstruct"
+	"github.com/swiftstack/sortedmap"
+
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+// When a volume's Deduplication is enabled, the logical bytes sent to each LogSegment are split into
+// content-defined chunks (using a gear rolling hash). Once the LogSegment's Chunked PUT completes, the
+// next flushInodes() looks up each chunk's SHA-256 in the headhunter fingerprintRec B+Tree. Extents
+// referencing a chunk already found in some other LogSegment are redirected to that LogSegment while
+// chunks not yet seen are added to the index.
+//
+// The fingerprintRec B+Tree holds two kinds of records distinguished by the top bit of their key:
+//
+//   key with dedupFingerprintKeyFlag set:   dedupFingerprintRecStruct (cstruct-packed) locating a chunk
+//   key with dedupFingerprintKeyFlag clear: dedupLogSegmentRecStruct  (JSON) keyed by LogSegment nonce
+//
+// The latter only exists for LogSegments that have (or may have) been shared between inodes. Its RefCount
+// is the number of (live) inodes whose LogSegmentMap references the LogSegment. Such a LogSegment is only
+// handed to headhunter.DeleteLogSegmentRec() once that count drops to zero (snapshot handling of the
+// deleted LogSegment is then left to headhunter as for any other LogSegment). As LogSegment nonces never
+// have any of their snapShotIDNumBits (at least 2) set, the two key spaces cannot collide.
+//
+const (
+	dedupChunkMinSize = uint64(16 * 1024)
+	dedupChunkMaxSize = uint64(256 * 1024)
+	dedupChunkMask    = uint64(0xFFFF) << 48 // Gear hash high bits depend on the most bytes... ~64KiB beyond dedupChunkMinSize on average
+
+	dedupFingerprintKeyFlag = uint64(1) << 63
+)
+
+var dedupGearTable [256]uint64
+
+func init() {
+	var (
+		index int
+		sum   [sha256.Size]byte
+	)
+
+	// Derive the gear table deterministically so that chunk boundaries never change between releases
+
+	for index = range dedupGearTable {
+		sum = sha256.Sum256([]byte{byte(index)})
+		dedupGearTable[index] = binary.BigEndian.Uint64(sum[:8])
+	}
+}
+
+type dedupChunkStruct struct {
+	logSegmentOffset uint64 // Logical LogSegment offset of the chunk
+	length           uint64
+	sha256           [sha256.Size]byte
+}
+
+type dedupChunkerStruct struct {
+	chunks      []dedupChunkStruct
+	chunkOffset uint64 //    Logical LogSegment offset of the chunk currently being accumulated
+	chunkLength uint64
+	gearHash    uint64
+	sha256      hash.Hash
+}
+
+type dedupFingerprintRecStruct struct {
+	SHA256           [sha256.Size]byte
+	LogSegmentNumber uint64
+	LogSegmentOffset uint64
+	Length           uint64
+}
+
+type dedupLogSegmentRecStruct struct {
+	RefCount     uint64
+	Fingerprints []uint64 // Keys of the dedupFingerprintRecStruct's referencing this LogSegment
+}
+
+type dedupRemapStruct struct {
+	fileOffset       uint64
+	length           uint64
+	logSegmentNumber uint64
+	logSegmentOffset uint64
+}
+
+func newDedupChunker() (chunker *dedupChunkerStruct) {
+	chunker = &dedupChunkerStruct{
+		chunks:      make([]dedupChunkStruct, 0),
+		chunkOffset: 0,
+		chunkLength: 0,
+		gearHash:    0,
+		sha256:      sha256.New(),
+	}
+
+	return
+}
+
+// update folds the next buf of (logical) LogSegment data (following any previously supplied) into the chunks.
+//
+func (chunker *dedupChunkerStruct) update(buf []byte) {
+	var (
+		b     byte
+		cut   int
+		index int
+	)
+
+	for 0 < len(buf) {
+		cut = len(buf)
+
+		for index, b = range buf {
+			chunker.gearHash = (chunker.gearHash << 1) + dedupGearTable[b]
+			chunker.chunkLength++
+
+			if (dedupChunkMaxSize == chunker.chunkLength) || ((dedupChunkMinSize <= chunker.chunkLength) && (0 == (chunker.gearHash & dedupChunkMask))) {
+				cut = index + 1
+				break
+			}
+		}
+
+		_, _ = chunker.sha256.Write(buf[:cut])
+
+		if cut < len(buf) || (dedupChunkMaxSize == chunker.chunkLength) || ((dedupChunkMinSize <= chunker.chunkLength) && (0 == (chunker.gearHash & dedupChunkMask))) {
+			chunker.cutChunk()
+		}
+
+		buf = buf[cut:]
+	}
+}
+
+func (chunker *dedupChunkerStruct) cutChunk() {
+	var (
+		chunk dedupChunkStruct
+	)
+
+	chunk.logSegmentOffset = chunker.chunkOffset
+	chunk.length = chunker.chunkLength
+	copy(chunk.sha256[:], chunker.sha256.Sum(nil))
+
+	chunker.chunks = append(chunker.chunks, chunk)
+
+	chunker.chunkOffset += chunker.chunkLength
+	chunker.chunkLength = 0
+	chunker.gearHash = 0
+	chunker.sha256.Reset()
+}
+
+// finish returns the chunks of all data supplied via update() including any trailing short chunk.
+//
+func (chunker *dedupChunkerStruct) finish() (chunks []dedupChunkStruct) {
+	if 0 < chunker.chunkLength {
+		chunker.cutChunk()
+	}
+
+	chunks = chunker.chunks
+
+	return
+}
+
+func dedupFingerprintKey(sum [sha256.Size]byte) (fingerprint uint64) {
+	fingerprint = binary.BigEndian.Uint64(sum[:8]) | dedupFingerprintKeyFlag
+	return
+}
+
+func (vS *volumeStruct) fetchDedupFingerprintRec(fingerprint uint64) (fingerprintRec *dedupFingerprintRecStruct, ok bool, err error) {
+	var (
+		value []byte
+	)
+
+	value, ok, err = vS.headhunterVolumeHandle.GetFingerprintRec(fingerprint)
+	if (nil != err) || !ok {
+		return
+	}
+
+	fingerprintRec = &dedupFingerprintRecStruct{}
+
+	_, err = cstruct.Unpack(value, fingerprintRec, cstruct.LittleEndian)
+
+	return
+}
+
+func (vS *volumeStruct) putDedupFingerprintRec(fingerprint uint64, fingerprintRec *dedupFingerprintRecStruct) (err error) {
+	var (
+		value []byte
+	)
+
+	value, err = cstruct.Pack(fingerprintRec, cstruct.LittleEndian)
+	if nil != err {
+		return
+	}
+
+	err = vS.headhunterVolumeHandle.PutFingerprintRec(fingerprint, value)
+
+	return
+}
+
+func (vS *volumeStruct) fetchDedupLogSegmentRec(logSegmentNumber uint64) (logSegmentRec *dedupLogSegmentRecStruct, ok bool, err error) {
+	var (
+		value []byte
+	)
+
+	value, ok, err = vS.headhunterVolumeHandle.GetFingerprintRec(logSegmentNumber)
+	if (nil != err) || !ok {
+		return
+	}
+
+	logSegmentRec = &dedupLogSegmentRecStruct{}
+
+	err = json.Unmarshal(value, logSegmentRec)
+
+	return
+}
+
+func (vS *volumeStruct) putDedupLogSegmentRec(logSegmentNumber uint64, logSegmentRec *dedupLogSegmentRecStruct) (err error) {
+	var (
+		value []byte
+	)
+
+	value, err = json.Marshal(logSegmentRec)
+	if nil != err {
+		return
+	}
+
+	err = vS.headhunterVolumeHandle.PutFingerprintRec(logSegmentNumber, value)
+
+	return
+}
+
+// dedupFileInode processes the chunks of each LogSegment written to fileInode since the last flush. It
+// must be called after doFileInodeDataFlush() has closed all of fileInode's inFlightLogSegments.
+//
+func (vS *volumeStruct) dedupFileInode(fileInode *inMemoryInodeStruct) (err error) {
+	var (
+		chunks           []dedupChunkStruct
+		logSegmentNumber uint64
+	)
+
+	if 0 == len(fileInode.dedupPendingChunkMap) {
+		err = nil
+		return
+	}
+
+	vS.dedupLock.Lock()
+	defer vS.dedupLock.Unlock()
+
+	for logSegmentNumber, chunks = range fileInode.dedupPendingChunkMap {
+		err = vS.dedupLogSegment(fileInode, logSegmentNumber, chunks)
+		if nil != err {
+			return
+		}
+
+		delete(fileInode.dedupPendingChunkMap, logSegmentNumber)
+	}
+
+	err = nil
+	return
+}
+
+// dedupLogSegment indexes the previously unseen chunks of a newly written LogSegment and redirects
+// fileInode's extents referencing the others to the LogSegments already holding them. Re-applying it
+// (e.g. following a failure part way through) is harmless.
+//
+func (vS *volumeStruct) dedupLogSegment(fileInode *inMemoryInodeStruct, logSegmentNumber uint64, chunks []dedupChunkStruct) (err error) {
+	var (
+		chunk                    dedupChunkStruct
+		chunksToIndex            []dedupChunkStruct
+		duplicateChunks          []dedupChunkStruct
+		duplicateFingerprintRecs []*dedupFingerprintRecStruct
+		extent                   *fileExtentStruct
+		extentAsValue            sortedmap.Value
+		extentIndex              int
+		extents                  sortedmap.BPlusTree
+		extentsLen               int
+		fingerprint              uint64
+		fingerprintRec           *dedupFingerprintRecStruct
+		fingerprintsToIndex      []uint64
+		index                    int
+		logSegmentRec            *dedupLogSegmentRecStruct
+		ok                       bool
+		overlapEnd               uint64
+		overlapStart             uint64
+		remap                    dedupRemapStruct
+		remaps                   []dedupRemapStruct
+		sharedLogSegmentNumber   uint64
+		sharedLogSegmentRec      *dedupLogSegmentRecStruct
+		sharedLogSegments        map[uint64]bool // value == true if sharedLogSegmentNumber may be referenced
+	)
+
+	duplicateChunks = make([]dedupChunkStruct, 0)
+	duplicateFingerprintRecs = make([]*dedupFingerprintRecStruct, 0)
+	chunksToIndex = make([]dedupChunkStruct, 0)
+	fingerprintsToIndex = make([]uint64, 0)
+
+	for _, chunk = range chunks {
+		if dedupChunkMinSize > chunk.length {
+			// Not worth tracking (typically the tail of a LogSegment)
+			continue
+		}
+
+		fingerprint = dedupFingerprintKey(chunk.sha256)
+
+		fingerprintRec, ok, err = vS.fetchDedupFingerprintRec(fingerprint)
+		if nil != err {
+			return
+		}
+
+		if ok {
+			if (fingerprintRec.SHA256 == chunk.sha256) && (fingerprintRec.Length == chunk.length) && (fingerprintRec.LogSegmentNumber != logSegmentNumber) {
+				duplicateChunks = append(duplicateChunks, chunk)
+				duplicateFingerprintRecs = append(duplicateFingerprintRecs, fingerprintRec)
+			}
+			// Otherwise, either already indexed for this LogSegment or (unlikely) a fingerprint collision
+			continue
+		}
+
+		chunksToIndex = append(chunksToIndex, chunk)
+		fingerprintsToIndex = append(fingerprintsToIndex, fingerprint)
+	}
+
+	stats.IncrementOperationsBy(&stats.DedupChunkMisses, uint64(len(chunksToIndex)))
+
+	// Index previously unseen chunks... recording them in the LogSegment's record first
+
+	if 0 < len(chunksToIndex) {
+		logSegmentRec, ok, err = vS.fetchDedupLogSegmentRec(logSegmentNumber)
+		if nil != err {
+			return
+		}
+		if !ok {
+			logSegmentRec = &dedupLogSegmentRecStruct{
+				RefCount:     1,
+				Fingerprints: make([]uint64, 0, len(fingerprintsToIndex)),
+			}
+		}
+
+		logSegmentRec.Fingerprints = append(logSegmentRec.Fingerprints, fingerprintsToIndex...)
+
+		err = vS.putDedupLogSegmentRec(logSegmentNumber, logSegmentRec)
+		if nil != err {
+			return
+		}
+
+		for index, chunk = range chunksToIndex {
+			fingerprintRec = &dedupFingerprintRecStruct{
+				SHA256:           chunk.sha256,
+				LogSegmentNumber: logSegmentNumber,
+				LogSegmentOffset: chunk.logSegmentOffset,
+				Length:           chunk.length,
+			}
+
+			err = vS.putDedupFingerprintRec(fingerprintsToIndex[index], fingerprintRec)
+			if nil != err {
+				return
+			}
+		}
+	}
+
+	if 0 == len(duplicateChunks) {
+		err = nil
+		return
+	}
+
+	// Adopt a reference to each LogSegment now to be shared (unless fileInode already references it)
+
+	sharedLogSegments = make(map[uint64]bool)
+
+	for _, fingerprintRec = range duplicateFingerprintRecs {
+		sharedLogSegmentNumber = fingerprintRec.LogSegmentNumber
+
+		_, ok = sharedLogSegments[sharedLogSegmentNumber]
+		if ok {
+			continue
+		}
+
+		_, ok = fileInode.LogSegmentMap[sharedLogSegmentNumber]
+		if ok {
+			sharedLogSegments[sharedLogSegmentNumber] = true
+			continue
+		}
+
+		sharedLogSegmentRec, ok, err = vS.fetchDedupLogSegmentRec(sharedLogSegmentNumber)
+		if nil != err {
+			return
+		}
+		if !ok {
+			logger.Warnf("Volume '%s' fingerprint references LogSegment 0x%016X lacking a deduplication record", vS.volumeName, sharedLogSegmentNumber)
+			sharedLogSegments[sharedLogSegmentNumber] = false
+			continue
+		}
+
+		sharedLogSegmentRec.RefCount++
+
+		err = vS.putDedupLogSegmentRec(sharedLogSegmentNumber, sharedLogSegmentRec)
+		if nil != err {
+			return
+		}
+
+		fileInode.LogSegmentMap[sharedLogSegmentNumber] = 0
+
+		sharedLogSegments[sharedLogSegmentNumber] = true
+	}
+
+	// Compute the redirections of each extent (or portion thereof) referencing a duplicate chunk
+
+	remaps = make([]dedupRemapStruct, 0)
+
+	extents = fileInode.payload.(sortedmap.BPlusTree)
+
+	extentsLen, err = extents.Len()
+	if nil != err {
+		return
+	}
+
+	for extentIndex = 0; extentIndex < extentsLen; extentIndex++ {
+		_, extentAsValue, ok, err = extents.GetByIndex(extentIndex)
+		if nil != err {
+			return
+		}
+		if !ok {
+			err = fmt.Errorf("dedupLogSegment() unable to fetch extent at index %d", extentIndex)
+			return
+		}
+
+		extent = extentAsValue.(*fileExtentStruct)
+
+		if extent.LogSegmentNumber != logSegmentNumber {
+			continue
+		}
+
+		for index, chunk = range duplicateChunks {
+			fingerprintRec = duplicateFingerprintRecs[index]
+
+			if !sharedLogSegments[fingerprintRec.LogSegmentNumber] {
+				continue
+			}
+
+			overlapStart = chunk.logSegmentOffset
+			if overlapStart < extent.LogSegmentOffset {
+				overlapStart = extent.LogSegmentOffset
+			}
+			overlapEnd = chunk.logSegmentOffset + chunk.length
+			if overlapEnd > (extent.LogSegmentOffset + extent.Length) {
+				overlapEnd = extent.LogSegmentOffset + extent.Length
+			}
+
+			if overlapStart < overlapEnd {
+				remaps = append(remaps, dedupRemapStruct{
+					fileOffset:       extent.FileOffset + (overlapStart - extent.LogSegmentOffset),
+					length:           overlapEnd - overlapStart,
+					logSegmentNumber: fingerprintRec.LogSegmentNumber,
+					logSegmentOffset: fingerprintRec.LogSegmentOffset + (overlapStart - chunk.logSegmentOffset),
+				})
+			}
+		}
+	}
+
+	// Finally, apply the redirections
+
+	for _, remap = range remaps {
+		err = recordWrite(fileInode, remap.fileOffset, remap.length, remap.logSegmentNumber, remap.logSegmentOffset)
+		if nil != err {
+			return
+		}
+
+		stats.IncrementOperationsBy(&stats.DedupBytesSaved, remap.length)
+	}
+
+	if 0 < len(remaps) {
+		fileInode.dirty = true
+	}
+
+	stats.IncrementOperationsBy(&stats.DedupChunkHits, uint64(len(duplicateChunks)))
+
+	err = nil
+	return
+}
+
+// releaseLogSegment drops an inode's reference to a LogSegment. Unless some other inode still shares
+// it, the LogSegment (along with any deduplication records describing it) is deleted.
+//
+func (vS *volumeStruct) releaseLogSegment(logSegmentNumber uint64) (err error) {
+	var (
+		fingerprint    uint64
+		fingerprintRec *dedupFingerprintRecStruct
+		logSegmentRec  *dedupLogSegmentRecStruct
+		ok             bool
+	)
+
+	vS.dedupLock.Lock()
+	defer vS.dedupLock.Unlock()
+
+	logSegmentRec, ok, err = vS.fetchDedupLogSegmentRec(logSegmentNumber)
+	if nil != err {
+		return
+	}
+
+	if ok {
+		if 1 < logSegmentRec.RefCount {
+			logSegmentRec.RefCount--
+			err = vS.putDedupLogSegmentRec(logSegmentNumber, logSegmentRec)
+			return
+		}
+
+		for _, fingerprint = range logSegmentRec.Fingerprints {
+			fingerprintRec, ok, err = vS.fetchDedupFingerprintRec(fingerprint)
+			if nil != err {
+				return
+			}
+			if ok && (fingerprintRec.LogSegmentNumber == logSegmentNumber) {
+				err = vS.headhunterVolumeHandle.DeleteFingerprintRec(fingerprint)
+				if nil != err {
+					return
+				}
+			}
+		}
+
+		err = vS.headhunterVolumeHandle.DeleteFingerprintRec(logSegmentNumber)
+		if nil != err {
+			return
+		}
+	}
+
+	err = vS.headhunterVolumeHandle.DeleteLogSegmentRec(logSegmentNumber)
+
+	return
+}
+
+// mergeLogSegmentReference accounts for two inode references to a shared LogSegment (e.g. by
+// Coalesce()) becoming one. The LogSegment remains referenced so is never deleted here.
+//
+func (vS *volumeStruct) mergeLogSegmentReference(logSegmentNumber uint64) (err error) {
+	var (
+		logSegmentRec *dedupLogSegmentRecStruct
+		ok            bool
+	)
+
+	vS.dedupLock.Lock()
+	defer vS.dedupLock.Unlock()
+
+	logSegmentRec, ok, err = vS.fetchDedupLogSegmentRec(logSegmentNumber)
+	if (nil != err) || !ok || (1 >= logSegmentRec.RefCount) {
+		return
+	}
+
+	logSegmentRec.RefCount--
+
+	err = vS.putDedupLogSegmentRec(logSegmentNumber, logSegmentRec)
+
+	return
+}
@@ -0,0 +1,201 @@
+package inode
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestDedupChunker(t *testing.T) {
+	buf := make([]byte, 2*1024*1024)
+	rand.New(rand.NewSource(0)).Read(buf)
+
+	wholeChunker := newDedupChunker()
+	wholeChunker.update(buf)
+	wholeChunks := wholeChunker.finish()
+
+	pieceChunker := newDedupChunker()
+	for offset := 0; offset < len(buf); offset += 12345 {
+		end := offset + 12345
+		if end > len(buf) {
+			end = len(buf)
+		}
+		pieceChunker.update(buf[offset:end])
+	}
+	pieceChunks := pieceChunker.finish()
+
+	if len(wholeChunks) != len(pieceChunks) {
+		t.Fatalf("dedupChunker produced %v chunks in one update() but %v chunks in pieces", len(wholeChunks), len(pieceChunks))
+	}
+
+	expectedOffset := uint64(0)
+
+	for index, chunk := range wholeChunks {
+		if chunk != pieceChunks[index] {
+			t.Fatalf("dedupChunker chunk[%v] differs when fed in pieces: %+v vs %+v", index, chunk, pieceChunks[index])
+		}
+		if chunk.logSegmentOffset != expectedOffset {
+			t.Fatalf("dedupChunker chunk[%v] at unexpected logSegmentOffset %v (expected %v)", index, chunk.logSegmentOffset, expectedOffset)
+		}
+		if (chunk.length > dedupChunkMaxSize) || ((chunk.length < dedupChunkMinSize) && (index != len(wholeChunks)-1)) {
+			t.Fatalf("dedupChunker chunk[%v] has unexpected length %v", index, chunk.length)
+		}
+		expectedOffset += chunk.length
+	}
+
+	if expectedOffset != uint64(len(buf)) {
+		t.Fatalf("dedupChunker chunks cover %v bytes (expected %v)", expectedOffset, len(buf))
+	}
+
+	// Inserting a byte should only disturb the chunk(s) near the insertion point
+
+	shiftedBuf := append([]byte{0xA5}, buf...)
+
+	shiftedChunker := newDedupChunker()
+	shiftedChunker.update(shiftedBuf)
+	shiftedChunks := shiftedChunker.finish()
+
+	commonChunks := 0
+
+	for _, chunk := range wholeChunks {
+		for _, shiftedChunk := range shiftedChunks {
+			if chunk.sha256 == shiftedChunk.sha256 {
+				commonChunks++
+				break
+			}
+		}
+	}
+
+	if commonChunks < len(wholeChunks)-2 {
+		t.Fatalf("dedupChunker only found %v of %v chunks following a one byte insertion", commonChunks, len(wholeChunks))
+	}
+}
+
+func TestDeduplication(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	testVolume := testVolumeHandle.(*volumeStruct)
+
+	testVolume.deduplication = true
+	defer func() {
+		testVolume.deduplication = false
+	}()
+
+	writeBuf := make([]byte, 1024*1024)
+	rand.New(rand.NewSource(0)).Read(writeBuf)
+
+	firstFileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(firstFileInodeNumber, 0, writeBuf, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(firstFileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	firstFileInode, ok, err := testVolume.fetchInode(firstFileInodeNumber)
+	if (nil != err) || !ok {
+		t.Fatalf("fetchInode() failed: %v", err)
+	}
+	if 1 != len(firstFileInode.LogSegmentMap) {
+		t.Fatalf("First FileInode should reference exactly one LogSegment")
+	}
+	var firstLogSegmentNumber uint64
+	for firstLogSegmentNumber = range firstFileInode.LogSegmentMap {
+	}
+
+	secondFileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(secondFileInodeNumber, 0, writeBuf, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(secondFileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	secondFileInode, ok, err := testVolume.fetchInode(secondFileInodeNumber)
+	if (nil != err) || !ok {
+		t.Fatalf("fetchInode() failed: %v", err)
+	}
+	sharedBytes, ok := secondFileInode.LogSegmentMap[firstLogSegmentNumber]
+	if !ok || (sharedBytes < uint64(len(writeBuf))/2) {
+		t.Fatalf("Second FileInode should mostly reference the first FileInode's LogSegment: %+v", secondFileInode.LogSegmentMap)
+	}
+
+	logSegmentRec, ok, err := testVolume.fetchDedupLogSegmentRec(firstLogSegmentNumber)
+	if (nil != err) || !ok {
+		t.Fatalf("fetchDedupLogSegmentRec() failed: %v", err)
+	}
+	if 2 != logSegmentRec.RefCount {
+		t.Fatalf("Shared LogSegment should have RefCount 2 (not %v)", logSegmentRec.RefCount)
+	}
+
+	readBuf, err := testVolumeHandle.Read(secondFileInodeNumber, 0, uint64(len(writeBuf)), nil)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if !bytes.Equal(writeBuf, readBuf) {
+		t.Fatalf("Read() of deduplicated FileInode returned unexpected data")
+	}
+
+	// Destroying the first FileInode must leave the shared LogSegment intact
+
+	err = testVolumeHandle.Destroy(firstFileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	logSegmentRec, ok, err = testVolume.fetchDedupLogSegmentRec(firstLogSegmentNumber)
+	if (nil != err) || !ok {
+		t.Fatalf("fetchDedupLogSegmentRec() failed: %v", err)
+	}
+	if 1 != logSegmentRec.RefCount {
+		t.Fatalf("Shared LogSegment should have RefCount 1 (not %v)", logSegmentRec.RefCount)
+	}
+
+	testVolume.volumeGroup.Lock()
+	testVolume.volumeGroup.purgeReadCacheWhileLocked()
+	testVolume.volumeGroup.Unlock()
+
+	readBuf, err = testVolumeHandle.Read(secondFileInodeNumber, 0, uint64(len(writeBuf)), nil)
+	if nil != err {
+		t.Fatalf("Read() after Destroy() of first FileInode failed: %v", err)
+	}
+	if !bytes.Equal(writeBuf, readBuf) {
+		t.Fatalf("Read() after Destroy() of first FileInode returned unexpected data")
+	}
+
+	err = testVolumeHandle.Validate(secondFileInodeNumber, true)
+	if nil != err {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+
+	// Destroying the second FileInode must finally remove all deduplication records
+
+	err = testVolumeHandle.Destroy(secondFileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	_, ok, err = testVolume.fetchDedupLogSegmentRec(firstLogSegmentNumber)
+	if nil != err {
+		t.Fatalf("fetchDedupLogSegmentRec() failed: %v", err)
+	}
+	if ok {
+		t.Fatalf("Deduplication record of released LogSegment should have been deleted")
+	}
+
+	testTeardown(t)
+}
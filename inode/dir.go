@@ -103,6 +103,11 @@ func (vS *volumeStruct) CreateDir(filePerm InodeMode, userID InodeUserID, groupI
 }
 
 func linkInMemory(dirInode *inMemoryInodeStruct, targetInode *inMemoryInodeStruct, basename string) error {
+	err := checkCaseOnlyCollision(dirInode, basename)
+	if nil != err {
+		return err
+	}
+
 	dirInode.dirty = true
 	targetInode.dirty = true
 
@@ -118,6 +123,8 @@ func linkInMemory(dirInode *inMemoryInodeStruct, targetInode *inMemoryInodeStruc
 		return blunder.AddError(err, blunder.FileExistsError)
 	}
 
+	caseFoldIndexInsert(dirInode, basename)
+
 	updateTime := time.Now()
 
 	targetInode.LinkCount++
@@ -137,6 +144,11 @@ func linkInMemory(dirInode *inMemoryInodeStruct, targetInode *inMemoryInodeStruc
 
 // Insert-only version of linkInMemory()
 func linkInMemoryInsertOnly(dirInode *inMemoryInodeStruct, basename string, targetInodeNumber InodeNumber) (err error) {
+	err = checkCaseOnlyCollision(dirInode, basename)
+	if nil != err {
+		return
+	}
+
 	// TODO
 	dirInode.dirty = true
 
@@ -152,6 +164,8 @@ func linkInMemoryInsertOnly(dirInode *inMemoryInodeStruct, basename string, targ
 		return blunder.AddError(err, blunder.FileExistsError)
 	}
 
+	caseFoldIndexInsert(dirInode, basename)
+
 	updateTime := time.Now()
 
 	dirInode.AttrChangeTime = updateTime
@@ -255,6 +269,8 @@ func unlinkInMemory(dirInode *inMemoryInodeStruct, untargetInode *inMemoryInodeS
 		panic(err)
 	}
 
+	caseFoldIndexRemove(dirInode, basename)
+
 	untargetInode.LinkCount--
 
 	if DirType == untargetInode.InodeType {
@@ -297,6 +313,8 @@ func unlinkInMemoryRemoveOnly(dirInode *inMemoryInodeStruct, basename string) (e
 		panic(err)
 	}
 
+	caseFoldIndexRemove(dirInode, basename)
+
 	updateTime := time.Now()
 
 	dirInode.AttrChangeTime = updateTime
@@ -338,6 +356,13 @@ func (vS *volumeStruct) Unlink(dirInodeNumber InodeNumber, basename string, remo
 		return err
 	}
 
+	// In a case-insensitive directory, the entry may be held under a differently cased basename
+
+	basename, _, err = resolveBasename(dirInode, basename)
+	if nil != err {
+		return err
+	}
+
 	if removeOnly {
 		err = unlinkInMemoryRemoveOnly(dirInode, basename)
 		if err != nil {
@@ -389,7 +414,19 @@ func (vS *volumeStruct) Unlink(dirInodeNumber InodeNumber, basename string, remo
 	return
 }
 
-func (vS *volumeStruct) Move(srcDirInodeNumber InodeNumber, srcBasename string, dstDirInodeNumber InodeNumber, dstBasename string) (err error) {
+func (vS *volumeStruct) Move(srcDirInodeNumber InodeNumber, srcBasename string, dstDirInodeNumber InodeNumber, dstBasename string, flags RenameFlags) (err error) {
+	if 0 != (flags &^ (RenameNoReplace | RenameExchange | RenameWhiteout)) {
+		err = blunder.NewError(blunder.InvalidArgError, "Move() called with unknown flags 0x%X", flags)
+		return
+	}
+	if (RenameExchange == (flags & RenameExchange)) && (0 != (flags & (RenameNoReplace | RenameWhiteout))) {
+		err = blunder.NewError(blunder.InvalidArgError, "Move() called with RenameExchange combined with other flags")
+		return
+	}
+	if RenameWhiteout == (flags & RenameWhiteout) {
+		err = blunder.NewError(blunder.NotSupportedError, "Move() does not support RenameWhiteout")
+		return
+	}
 	if (RootDirInodeNumber == srcDirInodeNumber) && (SnapShotDirName == srcBasename) {
 		err = blunder.NewError(blunder.InvalidArgError, "Move() from /%v not allowed", SnapShotDirName)
 		return
@@ -418,10 +455,26 @@ func (vS *volumeStruct) Move(srcDirInodeNumber InodeNumber, srcBasename string,
 	}
 	srcDirMapping := srcDirInode.payload.(sortedmap.BPlusTree)
 
+	// In a case-insensitive directory, the entry may be held under a differently cased basename
+
+	storedSrcBasename, ok, err := resolveBasename(srcDirInode, srcBasename)
+	if nil != err {
+		return
+	}
+	if ok {
+		srcBasename = storedSrcBasename
+	}
+
 	var dstDirInode *inMemoryInodeStruct
 	var dstDirMapping sortedmap.BPlusTree
 	if srcDirInodeNumber == dstDirInodeNumber {
 		if srcBasename == dstBasename {
+			if RenameExchange == (flags & RenameExchange) {
+				// Exchanging a directory entry with itself is trivially successful
+
+				err = nil
+				return
+			}
 			err = fmt.Errorf("%v: Source & Target of Move() cannot be identical: %v/%v", utils.GetFnName(), srcDirInodeNumber, srcBasename)
 			logger.ErrorWithError(err)
 			err = blunder.AddError(err, blunder.FileExistsError)
@@ -438,6 +491,18 @@ func (vS *volumeStruct) Move(srcDirInodeNumber InodeNumber, srcBasename string,
 		dstDirMapping = dstDirInode.payload.(sortedmap.BPlusTree)
 	}
 
+	storedDstBasename, ok, err := resolveBasename(dstDirInode, dstBasename)
+	if nil != err {
+		return
+	}
+	if ok && (storedDstBasename != dstBasename) {
+		if (srcDirInodeNumber == dstDirInodeNumber) && (storedDstBasename == srcBasename) {
+			// Just changing the case of srcBasename... so there is no (other) target entry
+		} else {
+			dstBasename = storedDstBasename
+		}
+	}
+
 	srcInodeNumberAsValue, ok, err := srcDirMapping.GetByKey(srcBasename)
 	if nil != err {
 		panic(err)
@@ -499,6 +564,22 @@ func (vS *volumeStruct) Move(srcDirInodeNumber InodeNumber, srcBasename string,
 		dstInode = nil
 	}
 
+	if RenameNoReplace == (flags & RenameNoReplace) {
+		if nil != dstInode {
+			err = blunder.NewError(blunder.FileExistsError, "Move() with RenameNoReplace target exists: %v/%v", dstDirInodeNumber, dstBasename)
+			return
+		}
+	}
+
+	if RenameExchange == (flags & RenameExchange) {
+		if nil == dstInode {
+			err = blunder.NewError(blunder.NotFoundError, "Move() with RenameExchange target not found: %v/%v", dstDirInodeNumber, dstBasename)
+			return
+		}
+		err = vS.moveExchange(srcDirInode, srcBasename, srcInode, dstDirInode, dstBasename, dstInode)
+		return
+	}
+
 	// I believe this is allowed so long at the dstInode is empty --craig
 	if (nil != dstInode) && (DirType == dstInode.InodeType) {
 		err = fmt.Errorf("%v: Target of Move() is an existing directory: %v/%v", utils.GetFnName(), dstDirInodeNumber, dstBasename)
@@ -574,6 +655,8 @@ func (vS *volumeStruct) Move(srcDirInodeNumber InodeNumber, srcBasename string,
 		panic(err)
 	}
 
+	caseFoldIndexRemove(srcDirInode, srcBasename)
+
 	if nil == dstInode {
 		ok, err = dstDirMapping.Put(dstBasename, srcInodeNumber)
 		if nil != err {
@@ -585,6 +668,8 @@ func (vS *volumeStruct) Move(srcDirInodeNumber InodeNumber, srcBasename string,
 			logger.ErrorfWithError(err, "Move(): dstDirInode Put error")
 			panic(err)
 		}
+
+		caseFoldIndexInsert(dstDirInode, dstBasename)
 	} else {
 		dstInode.dirty = true
 		dstInode.AttrChangeTime = updateTime
@@ -616,6 +701,177 @@ func (vS *volumeStruct) Move(srcDirInodeNumber InodeNumber, srcBasename string,
 	return
 }
 
+// isAncestorOf returns whether ancestorInodeNumber is dirInodeNumber or is found walking up from it
+// (via ".." entries) to the root directory.
+func (vS *volumeStruct) isAncestorOf(ancestorInodeNumber InodeNumber, dirInodeNumber InodeNumber) (isAncestor bool, err error) {
+	var (
+		parentInodeNumber InodeNumber
+	)
+
+	for {
+		if ancestorInodeNumber == dirInodeNumber {
+			isAncestor = true
+			err = nil
+			return
+		}
+
+		if RootDirInodeNumber == dirInodeNumber {
+			isAncestor = false
+			err = nil
+			return
+		}
+
+		parentInodeNumber, err = vS.lookup(dirInodeNumber, "..")
+		if nil != err {
+			return
+		}
+
+		if parentInodeNumber == dirInodeNumber {
+			isAncestor = false
+			err = nil
+			return
+		}
+
+		dirInodeNumber = parentInodeNumber
+	}
+}
+
+// moveExchange atomically swaps the directory entries srcDirInode/srcBasename (referencing srcInode)
+// and dstDirInode/dstBasename (referencing dstInode). Either (or both) may be directories... in which
+// case their ".." entries (and their parents' LinkCount's) are adjusted if they change parents.
+func (vS *volumeStruct) moveExchange(srcDirInode *inMemoryInodeStruct, srcBasename string, srcInode *inMemoryInodeStruct, dstDirInode *inMemoryInodeStruct, dstBasename string, dstInode *inMemoryInodeStruct) (err error) {
+	var (
+		ok bool
+	)
+
+	srcDirMapping := srcDirInode.payload.(sortedmap.BPlusTree)
+	dstDirMapping := dstDirInode.payload.(sortedmap.BPlusTree)
+
+	if srcInode.InodeNumber == dstInode.InodeNumber {
+		// Two hard links to the same inode... nothing to exchange
+
+		err = nil
+		return
+	}
+
+	// A directory may not be exchanged with an entry within its own subtree (making it its own ancestor)
+
+	for _, exchange := range []struct{ dirInode, otherDirInode *inMemoryInodeStruct }{{srcInode, dstDirInode}, {dstInode, srcDirInode}} {
+		if DirType == exchange.dirInode.InodeType {
+			ok, err = vS.isAncestorOf(exchange.dirInode.InodeNumber, exchange.otherDirInode.InodeNumber)
+			if nil != err {
+				return
+			}
+			if ok {
+				err = blunder.NewError(blunder.InvalidArgError, "Move() with RenameExchange of a directory and an entry within its own subtree not allowed")
+				return
+			}
+		}
+	}
+
+	for _, exchangedInode := range []*inMemoryInodeStruct{srcInode, dstInode} {
+		if FileType == exchangedInode.InodeType {
+			// Pre-flush exchangedInode so that no time-based (implicit) flushes will occur during this transaction
+			err = vS.flushInode(exchangedInode)
+			if nil != err {
+				logger.ErrorfWithError(err, "Move(): exchanged inode flush error")
+				panic(err)
+			}
+		}
+	}
+
+	updateTime := time.Now()
+
+	inodes := make([]*inMemoryInodeStruct, 0, 4)
+
+	srcDirInode.dirty = true
+	srcDirInode.AttrChangeTime = updateTime
+	srcDirInode.ModificationTime = updateTime
+	inodes = append(inodes, srcDirInode)
+
+	if srcDirInode.InodeNumber != dstDirInode.InodeNumber {
+		dstDirInode.dirty = true
+		dstDirInode.AttrChangeTime = updateTime
+		dstDirInode.ModificationTime = updateTime
+		inodes = append(inodes, dstDirInode)
+
+		if DirType == srcInode.InodeType {
+			srcDirInode.LinkCount--
+			dstDirInode.LinkCount++
+
+			srcInodeAsDirMapping := srcInode.payload.(sortedmap.BPlusTree)
+			ok, err = srcInodeAsDirMapping.PatchByKey("..", dstDirInode.InodeNumber)
+			if nil != err {
+				logger.ErrorfWithError(err, "Move(): srcInode PatchByKey error")
+				panic(err)
+			}
+			if !ok {
+				err = fmt.Errorf("Should have found \"..\" entry")
+				logger.ErrorfWithError(err, "Move(): srcInode PatchByKey error")
+				panic(err)
+			}
+		}
+
+		if DirType == dstInode.InodeType {
+			dstDirInode.LinkCount--
+			srcDirInode.LinkCount++
+
+			dstInodeAsDirMapping := dstInode.payload.(sortedmap.BPlusTree)
+			ok, err = dstInodeAsDirMapping.PatchByKey("..", srcDirInode.InodeNumber)
+			if nil != err {
+				logger.ErrorfWithError(err, "Move(): dstInode PatchByKey error")
+				panic(err)
+			}
+			if !ok {
+				err = fmt.Errorf("Should have found \"..\" entry")
+				logger.ErrorfWithError(err, "Move(): dstInode PatchByKey error")
+				panic(err)
+			}
+		}
+	}
+
+	srcInode.dirty = true
+	srcInode.AttrChangeTime = updateTime
+	inodes = append(inodes, srcInode)
+
+	dstInode.dirty = true
+	dstInode.AttrChangeTime = updateTime
+	inodes = append(inodes, dstInode)
+
+	ok, err = srcDirMapping.PatchByKey(srcBasename, dstInode.InodeNumber)
+	if nil != err {
+		logger.ErrorfWithError(err, "Move(): srcDirInode PatchByKey error")
+		panic(err)
+	}
+	if !ok {
+		err = fmt.Errorf("Should have been able to PatchByKey \"%v\" entry", srcBasename)
+		logger.ErrorfWithError(err, "Move(): srcDirInode PatchByKey error")
+		panic(err)
+	}
+
+	ok, err = dstDirMapping.PatchByKey(dstBasename, srcInode.InodeNumber)
+	if nil != err {
+		logger.ErrorfWithError(err, "Move(): dstDirInode PatchByKey error")
+		panic(err)
+	}
+	if !ok {
+		err = fmt.Errorf("Should have been able to PatchByKey \"%v\" entry", dstBasename)
+		logger.ErrorfWithError(err, "Move(): dstDirInode PatchByKey error")
+		panic(err)
+	}
+
+	// Finally flush the multi-inode transaction
+
+	err = vS.flushInodes(inodes)
+	if err != nil {
+		logger.ErrorfWithError(err, "flushInodes(%v) error", inodes)
+		panic(err)
+	}
+
+	stats.IncrementOperations(&stats.DirRenameSuccessOps)
+	return
+}
+
 func (vS *volumeStruct) lookup(dirInodeNumber InodeNumber, basename string) (targetInodeNumber InodeNumber, err error) {
 	var (
 		dirInode               *inMemoryInodeStruct
@@ -626,6 +882,7 @@ func (vS *volumeStruct) lookup(dirInodeNumber InodeNumber, basename string) (tar
 		ok                     bool
 		value                  sortedmap.Value
 		snapShot               headhunter.SnapShotStruct
+		storedBasename         string
 	)
 
 	dirInodeSnapShotIDType, dirInodeSnapShotID, dirInodeNonce = vS.headhunterVolumeHandle.SnapShotU64Decode(uint64(dirInodeNumber))
@@ -687,6 +944,18 @@ func (vS *volumeStruct) lookup(dirInodeNumber InodeNumber, basename string) (tar
 	if nil != err {
 		panic(err)
 	}
+	if !ok && isCaseInsensitive(dirInode) {
+		storedBasename, ok, err = resolveBasename(dirInode, basename)
+		if nil != err {
+			return
+		}
+		if ok {
+			value, ok, err = dirMapping.GetByKey(storedBasename)
+			if nil != err {
+				panic(err)
+			}
+		}
+	}
 	if !ok {
 		err = fmt.Errorf("unable to find basename %v in dirInode %v", basename, dirInodeNumber)
 		// There are cases where failing to find an inode is not an error.
@@ -0,0 +1,125 @@
+package inode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/keyprovider"
+	"github.com/swiftstack/ProxyFS/swiftclient"
+)
+
+func TestEncryption(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	testVolume := testVolumeHandle.(*volumeStruct)
+
+	dataKeyBytes, err := keyprovider.GenerateDataKey()
+	if nil != err {
+		t.Fatalf("keyprovider.GenerateDataKey() failed: %v", err)
+	}
+	testVolume.dataKey, err = keyprovider.NewDataKey(dataKeyBytes)
+	if nil != err {
+		t.Fatalf("keyprovider.NewDataKey() failed: %v", err)
+	}
+	defer func() {
+		testVolume.dataKey = nil
+	}()
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+
+	expected := bytes.Repeat([]byte("secret file contents "), int(compressionUnitSize)/10)
+
+	for offset := 0; offset < len(expected); offset += 10000 {
+		end := offset + 10000
+		if end > len(expected) {
+			end = len(expected)
+		}
+		err = testVolumeHandle.Write(fileInodeNumber, uint64(offset), expected[offset:end], nil)
+		if nil != err {
+			t.Fatalf("Write() failed: %v", err)
+		}
+	}
+
+	// Read back while the LogSegment is still in flight (covering both sent and pending data)
+
+	readBuf, err := testVolumeHandle.Read(fileInodeNumber, 0, uint64(len(expected)), nil)
+	if nil != err {
+		t.Fatalf("Read() of inFlightLogSegment failed: %v", err)
+	}
+	if !bytes.Equal(expected, readBuf) {
+		t.Fatalf("Read() of inFlightLogSegment returned unexpected data")
+	}
+
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	var zero uint64
+	readPlan, err := testVolumeHandle.GetReadPlan(fileInodeNumber, &zero, nil)
+	if nil != err {
+		t.Fatalf("GetReadPlan() failed: %v", err)
+	}
+	for _, step := range readPlan {
+		if EncryptionAESGCM != step.Encryption {
+			t.Fatalf("GetReadPlan() returned unencrypted step: %+v", step)
+		}
+	}
+
+	objectBuf, err := swiftclient.ObjectGet(readPlan[0].AccountName, readPlan[0].ContainerName, readPlan[0].ObjectName, 0, uint64(len(expected)))
+	if nil != err {
+		t.Fatalf("ObjectGet() failed: %v", err)
+	}
+	if bytes.Contains(objectBuf, []byte("secret file contents")) {
+		t.Fatalf("LogSegment object should not have contained plaintext")
+	}
+
+	// Read back (ranges straddling units) from Swift
+
+	testVolume.volumeGroup.Lock()
+	testVolume.volumeGroup.purgeReadCacheWhileLocked()
+	testVolume.volumeGroup.Unlock()
+
+	readBuf, err = testVolumeHandle.Read(fileInodeNumber, 0, uint64(len(expected)), nil)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if !bytes.Equal(expected, readBuf) {
+		t.Fatalf("Read() returned unexpected data")
+	}
+
+	readBuf, err = testVolumeHandle.Read(fileInodeNumber, compressionUnitSize-5, 10, nil)
+	if nil != err {
+		t.Fatalf("Read() straddling units failed: %v", err)
+	}
+	if !bytes.Equal(expected[compressionUnitSize-5:compressionUnitSize+5], readBuf) {
+		t.Fatalf("Read() straddling units returned unexpected data")
+	}
+
+	err = testVolumeHandle.Validate(fileInodeNumber, true)
+	if nil != err {
+		t.Fatalf("Validate() failed on encrypted file: %v", err)
+	}
+
+	// Operations that would expose unencrypted (client-written) objects must be refused
+
+	_, err = testVolumeHandle.ProvisionObject()
+	if !blunder.Is(err, blunder.NotSupportedError) {
+		t.Fatalf("ProvisionObject() on encrypted volume should have failed with NotSupportedError: %v", err)
+	}
+
+	err = testVolumeHandle.Wrote(fileInodeNumber, 0, "/v1/AUTH_test/container/object", 0, 1, nil, false)
+	if !blunder.Is(err, blunder.NotSupportedError) {
+		t.Fatalf("Wrote() on encrypted volume should have failed with NotSupportedError: %v", err)
+	}
+
+	testTeardown(t)
+}
@@ -0,0 +1,161 @@
+package inode
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+// For volumes configured with MaintainETag, the MD5 of a FileInode's content is computed (by ComputeETag())
+// once its writes have been flushed and kept (as a hex string) in its ETagStreamName stream (by SetETag()).
+// Any change to the content of the FileInode discards the stream (see invalidateETag()) such that a present
+// ETag is always trustworthy... even for files modified via paths (e.g. FUSE or SMB) that never see an MD5.
+//
+// As computing an ETag requires reading the entire file, callers may do so holding only a shared lock on
+// the FileInode (so as not to block readers) and later take an exclusive lock to record it. Should the
+// content have changed in between, the eTagGeneration returned by ComputeETag() will no longer match
+// and SetETag() quietly discards the (now stale) ETag. As each fetch of an inode into the inode cache
+// assigns it a fresh eTagGeneration, the same holds should the inode have been evicted in between (as
+// evidence of any invalidateETag() would have been lost with it).
+//
+
+const eTagReadSize = uint64(1024 * 1024)
+
+// invalidateETag discards any ETag maintained for fileInode as its content is about to change.
+//
+// Doesn't flush anything.
+func invalidateETag(fileInode *inMemoryInodeStruct) {
+	fileInode.eTagGeneration = atomic.AddUint64(&fileInode.volume.eTagGenerations, 1)
+
+	_, ok := fileInode.StreamMap[ETagStreamName]
+	if ok {
+		delete(fileInode.StreamMap, ETagStreamName)
+		fileInode.dirty = true
+	}
+}
+
+func (vS *volumeStruct) ComputeETag(fileInodeNumber InodeNumber) (eTag string, eTagGeneration uint64, err error) {
+	var (
+		buf       []byte
+		fileInode *inMemoryInodeStruct
+		hasher    = md5.New()
+		length    uint64
+		offset    uint64
+		ok        bool
+	)
+
+	eTag = ""
+
+	if !vS.maintainETag {
+		err = nil
+		return
+	}
+
+	snapShotIDType, _, _ := vS.headhunterVolumeHandle.SnapShotU64Decode(uint64(fileInodeNumber))
+	if headhunter.SnapShotIDTypeLive != snapShotIDType {
+		err = nil
+		return
+	}
+
+	fileInode, ok, err = vS.fetchInode(fileInodeNumber)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+	if !ok || (FileType != fileInode.InodeType) {
+		err = nil
+		return
+	}
+
+	_, ok = fileInode.StreamMap[ETagStreamName]
+	if ok {
+		// Content unchanged since the ETag was computed
+
+		err = nil
+		return
+	}
+
+	eTagGeneration = fileInode.eTagGeneration
+
+	stats.IncrementOperations(&stats.FileETagComputeOps)
+
+	for offset = 0; offset < fileInode.Size; offset += length {
+		length = fileInode.Size - offset
+		if length > eTagReadSize {
+			length = eTagReadSize
+		}
+
+		buf, err = vS.Read(fileInodeNumber, offset, length, nil)
+		if nil != err {
+			logger.ErrorWithError(err)
+			return
+		}
+		if uint64(len(buf)) != length {
+			err = fmt.Errorf("ComputeETag() of inode 0x%016X read %v bytes at offset %v (expected %v)", fileInodeNumber, len(buf), offset, length)
+			logger.ErrorWithError(err)
+			return
+		}
+
+		_, _ = hasher.Write(buf)
+	}
+
+	stats.IncrementOperationsBy(&stats.FileETagComputeBytes, fileInode.Size)
+
+	eTag = hex.EncodeToString(hasher.Sum(nil))
+
+	err = nil
+	return
+}
+
+func (vS *volumeStruct) SetETag(fileInodeNumber InodeNumber, eTag string, eTagGeneration uint64) (err error) {
+	var (
+		fileInode *inMemoryInodeStruct
+	)
+
+	if "" == eTag {
+		err = nil
+		return
+	}
+
+	fileInode, err = vS.fetchInodeType(fileInodeNumber, FileType)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+
+	if fileInode.eTagGeneration != eTagGeneration {
+		// Content changed (or inode was evicted from, and refetched into, the inode cache) since ComputeETag()
+
+		err = nil
+		return
+	}
+
+	// Not a user-visible change to the FileInode... so AttrChangeTime is left alone
+
+	fileInode.StreamMap[ETagStreamName] = []byte(eTag)
+	fileInode.dirty = true
+
+	err = vS.flushInode(fileInode)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+
+	return
+}
+
+func (vS *volumeStruct) UpdateETag(fileInodeNumber InodeNumber) (err error) {
+	eTag, eTagGeneration, err := vS.ComputeETag(fileInodeNumber)
+	if nil != err {
+		return
+	}
+
+	err = vS.SetETag(fileInodeNumber, eTag, eTagGeneration)
+
+	return
+}
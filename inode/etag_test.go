@@ -0,0 +1,232 @@
+package inode
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+)
+
+func TestUpdateETag(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	testVolume := testVolumeHandle.(*volumeStruct)
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+
+	// Spans more than one eTagReadSize chunk... with a hole in the middle
+
+	fileBuf := make([]byte, 2*eTagReadSize+100)
+	for i := range fileBuf {
+		fileBuf[i] = byte(i)
+	}
+	for i := eTagReadSize / 2; i < eTagReadSize; i++ {
+		fileBuf[i] = 0
+	}
+
+	err = testVolumeHandle.Write(fileInodeNumber, 0, fileBuf[:eTagReadSize/2], nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, eTagReadSize, fileBuf[eTagReadSize:], nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	expectETag := func(expectedBuf []byte) {
+		eTag, err := testVolumeHandle.GetStream(fileInodeNumber, ETagStreamName)
+		if nil == expectedBuf {
+			if !blunder.Is(err, blunder.StreamNotFound) {
+				t.Fatalf("GetStream(ETagStreamName) should have failed with StreamNotFound (got %v)", err)
+			}
+			return
+		}
+		if nil != err {
+			t.Fatalf("GetStream(ETagStreamName) failed: %v", err)
+		}
+		expectedMD5 := md5.Sum(expectedBuf)
+		if hex.EncodeToString(expectedMD5[:]) != string(eTag) {
+			t.Fatalf("GetStream(ETagStreamName) returned %s (expected %x)", eTag, expectedMD5)
+		}
+	}
+
+	// Not maintained unless the volume is configured to do so
+
+	err = testVolumeHandle.UpdateETag(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("UpdateETag() failed: %v", err)
+	}
+	expectETag(nil)
+
+	testVolume.maintainETag = true
+	defer func() {
+		testVolume.maintainETag = false
+	}()
+
+	err = testVolumeHandle.UpdateETag(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("UpdateETag() failed: %v", err)
+	}
+	expectETag(fileBuf)
+
+	// Each kind of content change must discard the ETag
+
+	err = testVolumeHandle.Write(fileInodeNumber, 10, []byte{0xFF}, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	fileBuf[10] = 0xFF
+	expectETag(nil)
+
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	err = testVolumeHandle.UpdateETag(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("UpdateETag() failed: %v", err)
+	}
+	expectETag(fileBuf)
+
+	err = testVolumeHandle.SetSize(fileInodeNumber, 1000)
+	if nil != err {
+		t.Fatalf("SetSize() failed: %v", err)
+	}
+	fileBuf = fileBuf[:1000]
+	expectETag(nil)
+
+	err = testVolumeHandle.UpdateETag(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("UpdateETag() failed: %v", err)
+	}
+	expectETag(fileBuf)
+
+	err = testVolumeHandle.Fallocate(fileInodeNumber, FallocatePunchHole|FallocateKeepSize, 0, 100)
+	if nil != err {
+		t.Fatalf("Fallocate() failed: %v", err)
+	}
+	copy(fileBuf[:100], make([]byte, 100))
+	expectETag(nil)
+
+	err = testVolumeHandle.UpdateETag(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("UpdateETag() failed: %v", err)
+	}
+	expectETag(fileBuf)
+
+	// An ETag computed before a content change must not be recorded after it
+
+	err = testVolumeHandle.Write(fileInodeNumber, 0, []byte{0xEE}, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	fileBuf[0] = 0xEE
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	staleETag, staleETagGeneration, err := testVolumeHandle.ComputeETag(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("ComputeETag() failed: %v", err)
+	}
+
+	err = testVolumeHandle.Write(fileInodeNumber, 1, []byte{0xEE}, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	fileBuf[1] = 0xEE
+
+	err = testVolumeHandle.SetETag(fileInodeNumber, staleETag, staleETagGeneration)
+	if nil != err {
+		t.Fatalf("SetETag() failed: %v", err)
+	}
+	expectETag(nil)
+
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	err = testVolumeHandle.UpdateETag(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("UpdateETag() failed: %v", err)
+	}
+	expectETag(fileBuf)
+
+	// ...nor after the inode has been evicted from (and refetched into) the inode cache in between
+
+	evictInode := func() {
+		fileInode, ok, err := testVolume.inodeCacheFetch(fileInodeNumber)
+		if nil != err {
+			t.Fatalf("inodeCacheFetch() failed: %v", err)
+		}
+		if !ok {
+			return
+		}
+		ok, err = testVolume.inodeCacheDrop(fileInode)
+		if nil != err {
+			t.Fatalf("inodeCacheDrop() failed: %v", err)
+		}
+		if !ok {
+			t.Fatalf("inodeCacheDrop() returned !ok")
+		}
+	}
+
+	err = testVolumeHandle.Write(fileInodeNumber, 2, []byte{0xEE}, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	fileBuf[2] = 0xEE
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	evictInode()
+
+	staleETag, staleETagGeneration, err = testVolumeHandle.ComputeETag(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("ComputeETag() failed: %v", err)
+	}
+
+	err = testVolumeHandle.Write(fileInodeNumber, 3, []byte{0xEE}, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	fileBuf[3] = 0xEE
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	evictInode()
+
+	err = testVolumeHandle.SetETag(fileInodeNumber, staleETag, staleETagGeneration)
+	if nil != err {
+		t.Fatalf("SetETag() failed: %v", err)
+	}
+	expectETag(nil)
+
+	err = testVolumeHandle.UpdateETag(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("UpdateETag() failed: %v", err)
+	}
+	expectETag(fileBuf)
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	testTeardown(t)
+}
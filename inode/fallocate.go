@@ -0,0 +1,112 @@
+package inode
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/headhunter"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+// Fallocate follows the semantics of fallocate(2) for the supported modes:
+//
+//   0                                       - "preallocate" [offset:offset+length), extending Size as necessary
+//   FallocateKeepSize                       - "preallocate" [offset:offset+length) without changing Size
+//   FallocatePunchHole | FallocateKeepSize  - deallocate [offset:offset+length)
+//   FallocateZeroRange [| FallocateKeepSize] - deallocate [offset:offset+length), extending Size as necessary
+//
+// As holes in a FileInode read as zeroes and consume no space, there is nothing to actually preallocate
+// while both punching a hole and zeroing a range simply remove the affected extents. The LogSegmentMap
+// byte counts are decremented accordingly such that LogSegments no longer referenced are reclaimed.
+//
+
+func (vS *volumeStruct) Fallocate(fileInodeNumber InodeNumber, mode FallocateMode, offset uint64, length uint64) (err error) {
+	var (
+		deallocateEnd uint64
+		fileInode     *inMemoryInodeStruct
+		newSize       uint64
+		updateTime    time.Time
+	)
+
+	snapShotIDType, _, _ := vS.headhunterVolumeHandle.SnapShotU64Decode(uint64(fileInodeNumber))
+	if headhunter.SnapShotIDTypeLive != snapShotIDType {
+		err = fmt.Errorf("Fallocate() on non-LiveView fileInodeNumber not allowed")
+		return
+	}
+
+	if 0 == length {
+		err = blunder.NewError(blunder.InvalidArgError, "Fallocate() called with length == 0")
+		return
+	}
+	if 0 != (mode &^ (FallocateKeepSize | FallocatePunchHole | FallocateZeroRange)) {
+		err = blunder.NewError(blunder.NotSupportedError, "Fallocate() called with unsupported mode 0x%X", mode)
+		return
+	}
+	if (FallocatePunchHole | FallocateZeroRange) == (mode & (FallocatePunchHole | FallocateZeroRange)) {
+		err = blunder.NewError(blunder.InvalidArgError, "Fallocate() called with both FallocatePunchHole & FallocateZeroRange")
+		return
+	}
+	if (FallocatePunchHole == (mode & FallocatePunchHole)) && (FallocateKeepSize != (mode & FallocateKeepSize)) {
+		err = blunder.NewError(blunder.NotSupportedError, "Fallocate() called with FallocatePunchHole but without FallocateKeepSize")
+		return
+	}
+	if (offset + length) < offset {
+		err = blunder.NewError(blunder.FileTooLargeError, "Fallocate() called with offset + length overflowing")
+		return
+	}
+
+	fileInode, err = vS.fetchInodeType(fileInodeNumber, FileType)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+
+	if 0 != (mode & (FallocatePunchHole | FallocateZeroRange)) {
+		if offset < fileInode.Size {
+			deallocateEnd = offset + length
+			if deallocateEnd > fileInode.Size {
+				deallocateEnd = fileInode.Size
+			}
+
+			invalidateETag(fileInode)
+
+			if fileInode.isInline() {
+				copy(fileInode.InlineData[offset:deallocateEnd], make([]byte, deallocateEnd-offset))
+			} else {
+				pruneExtents(fileInode, offset, deallocateEnd-offset)
+			}
+
+			fileInode.dirty = true
+
+			updateTime = time.Now()
+			fileInode.ModificationTime = updateTime
+			fileInode.AttrChangeTime = updateTime
+		}
+	}
+
+	if FallocateKeepSize != (mode & FallocateKeepSize) {
+		newSize = offset + length
+		if newSize > fileInode.Size {
+			err = setSizeInMemory(fileInode, newSize)
+			if nil != err {
+				logger.ErrorWithError(err)
+				return
+			}
+		}
+	}
+
+	if fileInode.dirty {
+		err = vS.flushInode(fileInode)
+		if nil != err {
+			logger.ErrorWithError(err)
+			return
+		}
+	}
+
+	stats.IncrementOperations(&stats.FileFallocateOps)
+
+	err = nil
+	return
+}
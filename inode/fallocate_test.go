@@ -0,0 +1,136 @@
+package inode
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+)
+
+func TestFallocate(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	testVolume := testVolumeHandle.(*volumeStruct)
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+
+	expectedBuf := bytes.Repeat([]byte{0xA5}, 300)
+
+	err = testVolumeHandle.Write(fileInodeNumber, 0, expectedBuf, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	verify := func(expectedSize uint64, expectedLogSegmentBytes uint64) {
+		fileInode, ok, err := testVolume.fetchInode(fileInodeNumber)
+		if (nil != err) || !ok {
+			t.Fatalf("fetchInode() failed: %v", err)
+		}
+		if expectedSize != fileInode.Size {
+			t.Fatalf("Size should be %v (not %v)", expectedSize, fileInode.Size)
+		}
+		logSegmentBytes := uint64(0)
+		for _, bytesUsed := range fileInode.LogSegmentMap {
+			logSegmentBytes += bytesUsed
+		}
+		if expectedLogSegmentBytes != logSegmentBytes {
+			t.Fatalf("LogSegmentMap should reference %v bytes (not %v)", expectedLogSegmentBytes, logSegmentBytes)
+		}
+		readBuf, err := testVolumeHandle.Read(fileInodeNumber, 0, expectedSize, nil)
+		if nil != err {
+			t.Fatalf("Read() failed: %v", err)
+		}
+		if !bytes.Equal(expectedBuf, readBuf) {
+			t.Fatalf("Read() returned unexpected data")
+		}
+	}
+
+	// Invalid modes
+
+	err = testVolumeHandle.Fallocate(fileInodeNumber, FallocateKeepSize, 0, 0)
+	if !blunder.Is(err, blunder.InvalidArgError) {
+		t.Fatalf("Fallocate() with length == 0 should have failed with EINVAL (got %v)", err)
+	}
+	err = testVolumeHandle.Fallocate(fileInodeNumber, FallocatePunchHole, 0, 1)
+	if !blunder.Is(err, blunder.NotSupportedError) {
+		t.Fatalf("Fallocate(FallocatePunchHole) without FallocateKeepSize should have failed with ENOTSUP (got %v)", err)
+	}
+	err = testVolumeHandle.Fallocate(fileInodeNumber, FallocatePunchHole|FallocateZeroRange|FallocateKeepSize, 0, 1)
+	if !blunder.Is(err, blunder.InvalidArgError) {
+		t.Fatalf("Fallocate(FallocatePunchHole|FallocateZeroRange) should have failed with EINVAL (got %v)", err)
+	}
+
+	// Punch a hole in the middle of the extent
+
+	err = testVolumeHandle.Fallocate(fileInodeNumber, FallocatePunchHole|FallocateKeepSize, 50, 100)
+	if nil != err {
+		t.Fatalf("Fallocate(FallocatePunchHole) failed: %v", err)
+	}
+	copy(expectedBuf[50:150], make([]byte, 100))
+	verify(300, 200)
+
+	holeOffset, err := testVolumeHandle.SeekHole(fileInodeNumber, 0)
+	if (nil != err) || (50 != holeOffset) {
+		t.Fatalf("SeekHole() after Fallocate(FallocatePunchHole) returned %v, %v (expected 50)", holeOffset, err)
+	}
+
+	// Zero a range extending beyond the end of the file
+
+	err = testVolumeHandle.Fallocate(fileInodeNumber, FallocateZeroRange, 250, 150)
+	if nil != err {
+		t.Fatalf("Fallocate(FallocateZeroRange) failed: %v", err)
+	}
+	copy(expectedBuf[250:300], make([]byte, 50))
+	expectedBuf = append(expectedBuf, make([]byte, 100)...)
+	verify(400, 150)
+
+	// Preallocate (with and without FallocateKeepSize)
+
+	err = testVolumeHandle.Fallocate(fileInodeNumber, FallocateKeepSize, 0, 1000)
+	if nil != err {
+		t.Fatalf("Fallocate(FallocateKeepSize) failed: %v", err)
+	}
+	verify(400, 150)
+
+	err = testVolumeHandle.Fallocate(fileInodeNumber, 0, 0, 1000)
+	if nil != err {
+		t.Fatalf("Fallocate(0) failed: %v", err)
+	}
+	expectedBuf = append(expectedBuf, make([]byte, 600)...)
+	verify(1000, 150)
+
+	// Punching out everything should release every LogSegment
+
+	err = testVolumeHandle.Fallocate(fileInodeNumber, FallocatePunchHole|FallocateKeepSize, 0, 1000)
+	if nil != err {
+		t.Fatalf("Fallocate(FallocatePunchHole) failed: %v", err)
+	}
+	expectedBuf = make([]byte, 1000)
+	verify(1000, 0)
+
+	fileInode, ok, err := testVolume.fetchInode(fileInodeNumber)
+	if (nil != err) || !ok {
+		t.Fatalf("fetchInode() failed: %v", err)
+	}
+	if 0 != len(fileInode.LogSegmentMap) {
+		t.Fatalf("LogSegmentMap should be empty (not %v)", fileInode.LogSegmentMap)
+	}
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	testTeardown(t)
+}
@@ -98,6 +98,24 @@ func fileLen(extents sortedmap.BPlusTree) uint64 {
 //
 // Doesn't flush anything.
 func setSizeInMemory(fileInode *inMemoryInodeStruct, size uint64) (err error) {
+	invalidateETag(fileInode)
+
+	if fileInode.isInline() {
+		if fileInode.volume.inlineSetSize(fileInode, size) {
+			fileInode.dirty = true
+
+			updateTime := time.Now()
+			fileInode.ModificationTime = updateTime
+			fileInode.AttrChangeTime = updateTime
+			return
+		}
+
+		err = fileInode.volume.promoteInlineData(fileInode)
+		if nil != err {
+			return
+		}
+	}
+
 	extents := fileInode.payload.(sortedmap.BPlusTree)
 	extentIndex, found, err := extents.BisectLeft(size)
 	if nil != err {
@@ -165,6 +183,11 @@ func setSizeInMemory(fileInode *inMemoryInodeStruct, size uint64) (err error) {
 }
 
 func (vS *volumeStruct) Read(fileInodeNumber InodeNumber, offset uint64, length uint64, profiler *utils.Profiler) (buf []byte, err error) {
+	buf, err = vS.ReadWithReadAhead(fileInodeNumber, offset, length, nil, profiler)
+	return
+}
+
+func (vS *volumeStruct) ReadWithReadAhead(fileInodeNumber InodeNumber, offset uint64, length uint64, readAheadState *ReadAheadState, profiler *utils.Profiler) (buf []byte, err error) {
 	var (
 		fileInode     *inMemoryInodeStruct
 		readPlan      []ReadPlanStep
@@ -192,6 +215,10 @@ func (vS *volumeStruct) Read(fileInodeNumber InodeNumber, offset uint64, length
 		return
 	}
 
+	if nil != readAheadState {
+		vS.readAhead(snapShotID, fileInode, readAheadState, offset, uint64(len(buf)))
+	}
+
 	stats.IncrementOperationsAndBucketedBytes(stats.FileRead, uint64(len(buf)))
 
 	err = nil
@@ -226,6 +253,12 @@ func (vS *volumeStruct) GetReadPlan(fileInodeNumber InodeNumber, offset *uint64,
 		return
 	}
 
+	readPlan, err = vS.translateReadPlan(fileInode, readPlan)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+
 	stats.IncrementOperationsBucketedEntriesAndBucketedBytes(stats.FileReadplan, uint64(len(readPlan)), readPlanBytes)
 	return
 }
@@ -271,6 +304,23 @@ func (vS *volumeStruct) getReadPlanHelper(snapShotID uint64, fileInode *inMemory
 		return
 	}
 
+	if fileInode.isInline() {
+		step := ReadPlanStep{
+			LogSegmentNumber: 0,
+			Offset:           offset,
+			Length:           readPlanBytes,
+			AccountName:      "",
+			ContainerName:    "",
+			ObjectName:       "",
+			ObjectPath:       "",
+			InlineData:       make([]byte, readPlanBytes),
+		}
+		copy(step.InlineData, fileInode.InlineData[offset:(offset+readPlanBytes)])
+		readPlan = append(readPlan, step)
+		err = nil
+		return
+	}
+
 	extents := fileInode.payload.(sortedmap.BPlusTree)
 
 	curOffset := offset
@@ -412,13 +462,12 @@ func decrementLogSegmentMapFileData(fileInode *inMemoryInodeStruct, logSegmentNu
 	}
 }
 
-// `recordWrite` is called by `Write` and `Wrote` to update the file inode
-// payload's record of the extents that compose the file.
-func recordWrite(fileInode *inMemoryInodeStruct, fileOffset uint64, length uint64, logSegmentNumber uint64, logSegmentOffset uint64) (err error) {
+// `pruneExtents` eliminates extents (or portions thereof) of the file inode
+// payload overlapping [fileOffset:fileOffset+length), adjusting LogSegmentMap
+// byte counts accordingly.
+func pruneExtents(fileInode *inMemoryInodeStruct, fileOffset uint64, length uint64) {
 	extents := fileInode.payload.(sortedmap.BPlusTree)
 
-	// First we need to eliminate extents or portions thereof that overlap the specified write
-
 	extentIndex, found, err := extents.BisectLeft(fileOffset)
 	if nil != err {
 		panic(err)
@@ -522,6 +571,16 @@ func recordWrite(fileInode *inMemoryInodeStruct, fileOffset uint64, length uint6
 			break
 		}
 	}
+}
+
+// `recordWrite` is called by `Write` and `Wrote` to update the file inode
+// payload's record of the extents that compose the file.
+func recordWrite(fileInode *inMemoryInodeStruct, fileOffset uint64, length uint64, logSegmentNumber uint64, logSegmentOffset uint64) (err error) {
+	extents := fileInode.payload.(sortedmap.BPlusTree)
+
+	// First we need to eliminate extents or portions thereof that overlap the specified write
+
+	pruneExtents(fileInode, fileOffset, length)
 
 	// Now that there will be no overlap, see if we can append to the preceding fileExtent
 
@@ -608,21 +667,38 @@ func (vS *volumeStruct) Write(fileInodeNumber InodeNumber, offset uint64, buf []
 
 	fileInode.dirty = true
 
-	logSegmentNumber, logSegmentOffset, err := vS.doSendChunk(fileInode, buf)
-	if nil != err {
-		logger.ErrorWithError(err)
-		return
-	}
+	invalidateETag(fileInode)
 
 	length := uint64(len(buf))
 
-	err = recordWrite(fileInode, offset, length, logSegmentNumber, logSegmentOffset)
+	startingSize := fileInode.Size
+
+	inlined, err := vS.inlineWrite(fileInode, offset, buf)
 	if nil != err {
 		logger.ErrorWithError(err)
 		return
 	}
 
-	startingSize := fileInode.Size
+	if !inlined {
+		err = vS.promoteInlineData(fileInode)
+		if nil != err {
+			logger.ErrorWithError(err)
+			return
+		}
+
+		logSegmentNumber, logSegmentOffset, doSendChunkErr := vS.doSendChunk(fileInode, buf)
+		if nil != doSendChunkErr {
+			err = doSendChunkErr
+			logger.ErrorWithError(err)
+			return
+		}
+
+		err = recordWrite(fileInode, offset, length, logSegmentNumber, logSegmentOffset)
+		if nil != err {
+			logger.ErrorWithError(err)
+			return
+		}
+	}
 
 	offsetJustAfterWhereBufLogicallyWritten := offset + length
 
@@ -643,13 +719,19 @@ func (vS *volumeStruct) Write(fileInodeNumber InodeNumber, offset uint64, buf []
 	return
 }
 
-func (vS *volumeStruct) Wrote(fileInodeNumber InodeNumber, fileOffset uint64, objectPath string, objectOffset uint64, length uint64, patchOnly bool) (err error) {
+func (vS *volumeStruct) Wrote(fileInodeNumber InodeNumber, fileOffset uint64, objectPath string, objectOffset uint64, length uint64, objectBlockCRC32 []uint32, patchOnly bool) (err error) {
 	snapShotIDType, _, _ := vS.headhunterVolumeHandle.SnapShotU64Decode(uint64(fileInodeNumber))
 	if headhunter.SnapShotIDTypeLive != snapShotIDType {
 		err = fmt.Errorf("Wrote() on non-LiveView fileInodeNumber not allowed")
 		return
 	}
 
+	if nil != vS.dataKey {
+		// LogSegments written by some other agent would hold unencrypted data
+		err = blunder.NewError(blunder.NotSupportedError, "Wrote() not supported on encrypted Volume '%s'", vS.volumeName)
+		return
+	}
+
 	fileInode, err := vS.fetchInodeType(fileInodeNumber, FileType)
 	if err != nil {
 		logger.ErrorWithError(err)
@@ -672,6 +754,8 @@ func (vS *volumeStruct) Wrote(fileInodeNumber InodeNumber, fileOffset uint64, ob
 		}
 	}
 
+	invalidateETag(fileInode)
+
 	accountName, containerName, logSegmentNumeral, err := utils.PathToAcctContObj(objectPath)
 	if err != nil {
 		return
@@ -686,13 +770,35 @@ func (vS *volumeStruct) Wrote(fileInodeNumber InodeNumber, fileOffset uint64, ob
 		return
 	}
 
-	err = fileInode.volume.setLogSegmentContainer(logSegmentNumber, containerName)
+	logSegmentRec := &logSegmentRecStruct{containerName: containerName}
+
+	if fileInode.volume.dataChecksums && (nil != objectBlockCRC32) {
+		// The LogSegment was PUT by someone else who also supplied its checksums
+
+		logSegmentRec.checksums = newLogSegmentChecksums(objectOffset+length, objectBlockCRC32)
+		if nil == logSegmentRec.checksums {
+			logger.Warnf("Wrote() passed %v block checksums for %v bytes of %s... data will not be verified", len(objectBlockCRC32), objectOffset+length, objectPath)
+		}
+	}
+
+	err = fileInode.volume.putLogSegmentRec(logSegmentNumber, logSegmentRec)
 	if nil != err {
 		return
 	}
 
 	fileInode.dirty = true
 
+	if patchOnly {
+		err = vS.promoteInlineData(fileInode)
+		if nil != err {
+			logger.ErrorWithError(err)
+			return
+		}
+	} else {
+		// The FileInode's prior contents (inline or not) are about to be replaced
+		fileInode.InlineData = nil
+	}
+
 	err = recordWrite(fileInode, fileOffset, length, logSegmentNumber, objectOffset)
 	if err != nil {
 		logger.ErrorWithError(err)
@@ -827,6 +933,8 @@ func (vS *volumeStruct) resetFileInodeInMemory(fileInode *inMemoryInodeStruct) (
 
 	fileInode.dirty = true
 
+	invalidateETag(fileInode)
+
 	fileInodeExtentMap = fileInode.payload.(sortedmap.BPlusTree)
 
 	ok = true
@@ -863,6 +971,7 @@ func (vS *volumeStruct) Coalesce(destInodeNumber InodeNumber, elements []*Coales
 		inodeList                          []*inMemoryInodeStruct
 		inodeMap                           map[InodeNumber]*inMemoryInodeStruct
 		localErr                           error
+		logSegmentNumber                   uint64
 		logSegmentReferencedBytes          uint64
 		ok                                 bool
 		snapShotIDType                     headhunter.SnapShotIDType
@@ -942,6 +1051,16 @@ func (vS *volumeStruct) Coalesce(destInodeNumber InodeNumber, elements []*Coales
 		}
 	}
 
+	// Inline FileInodes lack extents to "append"... so promote any such Elements first
+
+	for _, element = range elements {
+		err = vS.promoteInlineData(inodeMap[element.ElementInodeNumber])
+		if nil != err {
+			err = blunder.NewError(blunder.InvalidArgError, "Coalesce() unable to promote inline ElementInodeNumber 0x%016X: %v", element.ElementInodeNumber, err)
+			return
+		}
+	}
+
 	// Ensure all referenced FileInodes are pre-flushed
 
 	err = vS.flushInodes(inodeList)
@@ -968,6 +1087,16 @@ func (vS *volumeStruct) Coalesce(destInodeNumber InodeNumber, elements []*Coales
 	for _, element = range elements {
 		elementInode = inodeMap[element.ElementInodeNumber]
 		destInode.NumWrites += elementInode.NumWrites
+		// LogSegments shared (via deduplication or CopyRange()) by destInode & elementInode are about to be referenced just once
+		for logSegmentNumber = range elementInode.LogSegmentMap {
+			_, ok = destInode.LogSegmentMap[logSegmentNumber]
+			if ok {
+				localErr = vS.mergeLogSegmentReference(logSegmentNumber)
+				if nil != localErr {
+					logger.Errorf("Coalesce() doing mergeLogSegmentReference(0x%016X) failed: %v", logSegmentNumber, localErr)
+				}
+			}
+		}
 		elementInodeExtentMap = elementInode.payload.(sortedmap.BPlusTree)
 		elementInodeExtentMapLen, err = elementInodeExtentMap.Len()
 		for elementInodeExtentMapIndex = 0; elementInodeExtentMapIndex < elementInodeExtentMapLen; elementInodeExtentMapIndex++ {
@@ -1073,17 +1202,16 @@ func (vS *volumeStruct) Coalesce(destInodeNumber InodeNumber, elements []*Coales
 }
 
 func (vS *volumeStruct) setLogSegmentContainer(logSegmentNumber uint64, containerName string) (err error) {
-	containerNameAsByteSlice := utils.StringToByteSlice(containerName)
-	err = vS.headhunterVolumeHandle.PutLogSegmentRec(logSegmentNumber, containerNameAsByteSlice)
+	err = vS.putLogSegmentRec(logSegmentNumber, &logSegmentRecStruct{containerName: containerName})
 	return
 }
 
 func (vS *volumeStruct) getLogSegmentContainer(logSegmentNumber uint64) (containerName string, err error) {
-	containerNameAsByteSlice, err := vS.headhunterVolumeHandle.GetLogSegmentRec(logSegmentNumber)
+	logSegmentRec, err := vS.fetchLogSegmentRec(logSegmentNumber)
 	if nil != err {
 		return
 	}
-	containerName = utils.ByteSliceToString(containerNameAsByteSlice)
+	containerName = logSegmentRec.containerName
 	return
 }
 
@@ -101,61 +101,186 @@ func openLogSegmentLRURemove(inFlightLogSegment *inFlightLogSegmentStruct) {
 	globals.Unlock()
 }
 
+// The Read Cache is managed per the 2Q algorithm. Newly inserted cache lines enter the A1in list (a FIFO).
+// Cache lines evicted from A1in have their keys remembered (up to half the Read Cache's capacity) in A1out.
+// Only a cache line missed again while its key is in A1out is inserted into the Am list (an LRU). Hence,
+// a single sequential scan will not flush frequently referenced cache lines from the Read Cache.
+//
+// A1in is limited to a quarter of the Read Cache's capacity... unless Am is empty.
+//
+
+func (volumeGroup *volumeGroupStruct) unlinkReadCacheElementWhileLocked(readCacheElement *readCacheElementStruct) {
+	var (
+		lru *(*readCacheElementStruct)
+		mru *(*readCacheElementStruct)
+	)
+
+	if readCacheElement.inA1in {
+		mru = &volumeGroup.readCacheA1inMRU
+		lru = &volumeGroup.readCacheA1inLRU
+		volumeGroup.readCacheA1inCount--
+	} else {
+		mru = &volumeGroup.readCacheMRU
+		lru = &volumeGroup.readCacheLRU
+	}
+
+	if nil == readCacheElement.prev {
+		*mru = readCacheElement.next
+	} else {
+		readCacheElement.prev.next = readCacheElement.next
+	}
+	if nil == readCacheElement.next {
+		*lru = readCacheElement.prev
+	} else {
+		readCacheElement.next.prev = readCacheElement.prev
+	}
+
+	readCacheElement.next = nil
+	readCacheElement.prev = nil
+}
+
+func (volumeGroup *volumeGroupStruct) linkReadCacheElementAtMRUWhileLocked(readCacheElement *readCacheElementStruct) {
+	var (
+		lru *(*readCacheElementStruct)
+		mru *(*readCacheElementStruct)
+	)
+
+	if readCacheElement.inA1in {
+		mru = &volumeGroup.readCacheA1inMRU
+		lru = &volumeGroup.readCacheA1inLRU
+		volumeGroup.readCacheA1inCount++
+	} else {
+		mru = &volumeGroup.readCacheMRU
+		lru = &volumeGroup.readCacheLRU
+	}
+
+	readCacheElement.prev = nil
+	readCacheElement.next = *mru
+
+	if nil == *mru {
+		*lru = readCacheElement
+	} else {
+		(*mru).prev = readCacheElement
+	}
+
+	*mru = readCacheElement
+}
+
 func (volumeGroup *volumeGroupStruct) capReadCacheWhileLocked() {
+	var (
+		a1inMaxCount      uint64
+		a1outEntry        readCacheA1outEntryStruct
+		a1outMaxCount     uint64
+		readCacheElement  *readCacheElementStruct
+		readCacheKeySeq   uint64
+		readCacheKeyFound bool
+	)
+
+	a1inMaxCount = volumeGroup.readCacheLineCount / 4
+	if 0 == a1inMaxCount {
+		a1inMaxCount = 1
+	}
+
 	for uint64(len(volumeGroup.readCache)) > volumeGroup.readCacheLineCount {
-		delete(volumeGroup.readCache, volumeGroup.readCacheLRU.readCacheKey)
-		volumeGroup.readCacheLRU = volumeGroup.readCacheLRU.prev
-		volumeGroup.readCacheLRU.next = nil
+		if (nil != volumeGroup.readCacheA1inLRU) && ((volumeGroup.readCacheA1inCount > a1inMaxCount) || (nil == volumeGroup.readCacheLRU)) {
+			readCacheElement = volumeGroup.readCacheA1inLRU
+			volumeGroup.readCacheA1outNextSeq++
+			volumeGroup.readCacheA1out[readCacheElement.readCacheKey] = volumeGroup.readCacheA1outNextSeq
+			volumeGroup.readCacheA1outFIFO = append(volumeGroup.readCacheA1outFIFO, readCacheA1outEntryStruct{readCacheKey: readCacheElement.readCacheKey, seq: volumeGroup.readCacheA1outNextSeq})
+		} else {
+			readCacheElement = volumeGroup.readCacheLRU
+		}
+		volumeGroup.unlinkReadCacheElementWhileLocked(readCacheElement)
+		if readCacheElement.prefetched {
+			stats.IncrementOperationsBy(&stats.FileReadaheadWastedBytes, uint64(len(readCacheElement.cacheLine)))
+		}
+		delete(volumeGroup.readCache, readCacheElement.readCacheKey)
+		volumeGroup.readCacheBytes -= uint64(len(readCacheElement.cacheLine))
+	}
+
+	a1outMaxCount = volumeGroup.readCacheLineCount / 2
+
+	for uint64(len(volumeGroup.readCacheA1outFIFO)) > a1outMaxCount {
+		a1outEntry = volumeGroup.readCacheA1outFIFO[0]
+		volumeGroup.readCacheA1outFIFO = volumeGroup.readCacheA1outFIFO[1:]
+		readCacheKeySeq, readCacheKeyFound = volumeGroup.readCacheA1out[a1outEntry.readCacheKey]
+		if readCacheKeyFound && (readCacheKeySeq == a1outEntry.seq) {
+			delete(volumeGroup.readCacheA1out, a1outEntry.readCacheKey)
+		}
 	}
 }
 
 func (volumeGroup *volumeGroupStruct) insertReadCacheElementWhileLocked(readCacheElement *readCacheElementStruct) {
+	var (
+		readCacheKeyFound bool
+	)
+
 	volumeGroup.readCache[readCacheElement.readCacheKey] = readCacheElement
-	if nil == volumeGroup.readCacheMRU {
-		volumeGroup.readCacheMRU = readCacheElement
-		volumeGroup.readCacheLRU = readCacheElement
+	volumeGroup.readCacheBytes += uint64(len(readCacheElement.cacheLine))
+	if !readCacheElement.prefetched {
+		volumeGroup.readCacheMisses++
+	}
+
+	_, readCacheKeyFound = volumeGroup.readCacheA1out[readCacheElement.readCacheKey]
+	if readCacheKeyFound {
+		delete(volumeGroup.readCacheA1out, readCacheElement.readCacheKey)
+		readCacheElement.inA1in = false
 	} else {
-		readCacheElement.next = volumeGroup.readCacheMRU
-		readCacheElement.next.prev = readCacheElement
-		volumeGroup.readCacheMRU = readCacheElement
+		readCacheElement.inA1in = true
 	}
+
+	volumeGroup.linkReadCacheElementAtMRUWhileLocked(readCacheElement)
+
 	volumeGroup.capReadCacheWhileLocked()
 }
 
 func (volumeGroup *volumeGroupStruct) touchReadCacheElementWhileLocked(readCacheElement *readCacheElementStruct) {
-	if volumeGroup.readCacheMRU != readCacheElement {
-		if readCacheElement == volumeGroup.readCacheLRU {
-			volumeGroup.readCacheLRU = readCacheElement.prev
-			volumeGroup.readCacheLRU.next = nil
-		} else {
-			readCacheElement.prev.next = readCacheElement.next
-			readCacheElement.next.prev = readCacheElement.prev
-		}
-		readCacheElement.next = volumeGroup.readCacheMRU
-		readCacheElement.prev = nil
-		volumeGroup.readCacheMRU.prev = readCacheElement
-		volumeGroup.readCacheMRU = readCacheElement
+	volumeGroup.readCacheHits++
+
+	if readCacheElement.prefetched {
+		readCacheElement.prefetched = false
+		stats.IncrementOperations(&stats.FileReadaheadHitOps)
 	}
+
+	if !readCacheElement.inA1in && (volumeGroup.readCacheMRU != readCacheElement) {
+		volumeGroup.unlinkReadCacheElementWhileLocked(readCacheElement)
+		volumeGroup.linkReadCacheElementAtMRUWhileLocked(readCacheElement)
+	}
+}
+
+// purgeReadCacheWhileLocked empties the Read Cache (including A1out).
+//
+func (volumeGroup *volumeGroupStruct) purgeReadCacheWhileLocked() {
+	volumeGroup.readCache = make(map[readCacheKeyStruct]*readCacheElementStruct)
+	volumeGroup.readCacheMRU = nil
+	volumeGroup.readCacheLRU = nil
+	volumeGroup.readCacheA1inMRU = nil
+	volumeGroup.readCacheA1inLRU = nil
+	volumeGroup.readCacheA1inCount = 0
+	volumeGroup.readCacheA1out = make(map[readCacheKeyStruct]uint64)
+	volumeGroup.readCacheA1outFIFO = nil
+	volumeGroup.readCacheBytes = 0
 }
 
 func (vS *volumeStruct) doReadPlan(fileInode *inMemoryInodeStruct, readPlan []ReadPlanStep, readPlanBytes uint64) (buf []byte, err error) {
 	var (
-		cacheLine            []byte
-		cacheLineHitLength   uint64
-		cacheLineHitOffset   uint64
-		cacheLineStartOffset uint64
-		chunkOffset          uint64
-		inFlightHit          bool
-		inFlightHitBuf       []byte
-		inFlightLogSegment   *inFlightLogSegmentStruct
-		readCacheElement     *readCacheElementStruct
-		readCacheHit         bool
-		readCacheKey         readCacheKeyStruct
-		readCacheLineSize    uint64
-		remainingLength      uint64
-		step                 ReadPlanStep
-		stepIndex            int
-		volumeGroup          *volumeGroupStruct
+		cacheLine                  []byte
+		cacheLineHitOffset         uint64
+		cacheLineStartOffset       uint64
+		inFlightHit                bool
+		inFlightHitBuf             []byte
+		inFlightLogSegment         *inFlightLogSegmentStruct
+		logSegmentCompressionUnits []logSegmentCompressionUnitStruct
+		readCacheElement           *readCacheElementStruct
+		readCacheHit               bool
+		readCacheKey               readCacheKeyStruct
+		readCacheLineSize          uint64
+		step                       ReadPlanStep
+		stepIndex                  int
+		translatedStep             ReadPlanStep
+		translatedSteps            []ReadPlanStep
+		unitBuf                    []byte
+		volumeGroup                *volumeGroupStruct
 	)
 
 	volumeGroup = vS.volumeGroup
@@ -163,13 +288,22 @@ func (vS *volumeStruct) doReadPlan(fileInode *inMemoryInodeStruct, readPlan []Re
 	readCacheKey.volumeName = vS.volumeName
 
 	if 1 == len(readPlan) {
-		// Possibly a trivial case (allowing for a potential zero-copy return)... three exist:
+		// Possibly a trivial case (allowing for a potential zero-copy return)... four exist:
+		//   Case 0: The lone step is satisfied by data held inline in the FileInode
 		//   Case 1: The lone step calls for a zero-filled []byte
 		//   Case 2: The lone step is satisfied by reading from an inFlightLogSegment
 		//   Case 3: The lone step is satisfied by landing completely within a single Read Cache Line
 
 		step = readPlan[0]
 
+		if nil != step.InlineData {
+			// Case 0: The lone step is satisfied by data held inline in the FileInode (already copied)
+			buf = step.InlineData
+			stats.IncrementOperationsAndBucketedBytes(stats.FileRead, step.Length)
+			err = nil
+			return
+		}
+
 		if 0 == step.LogSegmentNumber {
 			// Case 1: The lone step calls for a zero-filled []byte
 			buf = make([]byte, step.Length)
@@ -184,7 +318,7 @@ func (vS *volumeStruct) doReadPlan(fileInode *inMemoryInodeStruct, readPlan []Re
 		if inFlightHit {
 			// Case 2: The lone step is satisfied by reading from an inFlightLogSegment
 			openLogSegmentLRUTouch(inFlightLogSegment)
-			buf, err = inFlightLogSegment.Read(step.Offset, step.Length)
+			buf, err = vS.readInFlightLogSegment(inFlightLogSegment, step.Offset, step.Length)
 			if nil != err {
 				fileInode.Unlock()
 				logger.ErrorfWithError(err, "Reading back inFlightLogSegment failed - optimal case")
@@ -197,13 +331,15 @@ func (vS *volumeStruct) doReadPlan(fileInode *inMemoryInodeStruct, readPlan []Re
 			return
 		}
 
+		fileInode.Unlock()
+
 		stats.IncrementOperations(&stats.FileWritebackMissOps)
 
-		fileInode.Unlock()
+		logSegmentCompressionUnits = vS.fetchClosedLogSegmentCompressionUnits(step.LogSegmentNumber)
 
 		cacheLineHitOffset = step.Offset % readCacheLineSize
 
-		if (cacheLineHitOffset + step.Length) <= readCacheLineSize {
+		if (nil == logSegmentCompressionUnits) && ((cacheLineHitOffset + step.Length) <= readCacheLineSize) {
 			// Case 3: The lone step is satisfied by landing completely within a single Read Cache Line
 			readCacheKey.logSegmentNumber = step.LogSegmentNumber
 			readCacheKey.cacheLineTag = step.Offset / readCacheLineSize
@@ -222,10 +358,9 @@ func (vS *volumeStruct) doReadPlan(fileInode *inMemoryInodeStruct, readPlan []Re
 				stats.IncrementOperations(&stats.FileReadcacheMissOps)
 				// Make readCacheHit true (at MRU, likely kicking out LRU)
 				cacheLineStartOffset = readCacheKey.cacheLineTag * readCacheLineSize
-				cacheLine, err = swiftclient.ObjectGet(step.AccountName, step.ContainerName, step.ObjectName, cacheLineStartOffset, readCacheLineSize)
+				cacheLine, err = vS.getLogSegmentRange(fileInode.InodeNumber, step, vS.fetchLogSegmentChecksums(step.LogSegmentNumber), cacheLineStartOffset, readCacheLineSize)
 				if nil != err {
 					logger.ErrorfWithError(err, "Reading from LogSegment object failed - optimal case")
-					err = blunder.AddError(err, blunder.SegReadError)
 					return
 				}
 				readCacheElement = &readCacheElementStruct{
@@ -260,7 +395,10 @@ func (vS *volumeStruct) doReadPlan(fileInode *inMemoryInodeStruct, readPlan []Re
 	buf = make([]byte, 0, readPlanBytes)
 
 	for stepIndex, step = range readPlan {
-		if 0 == step.LogSegmentNumber {
+		if nil != step.InlineData {
+			// The step is satisfied by data held inline in the FileInode
+			buf = append(buf, step.InlineData...)
+		} else if 0 == step.LogSegmentNumber {
 			// The step calls for a zero-filled []byte
 			buf = append(buf, make([]byte, step.Length)...)
 		} else {
@@ -269,7 +407,7 @@ func (vS *volumeStruct) doReadPlan(fileInode *inMemoryInodeStruct, readPlan []Re
 			if inFlightHit {
 				// The step is satisfied by reading from an inFlightLogSegment
 				openLogSegmentLRUTouch(inFlightLogSegment)
-				inFlightHitBuf, err = inFlightLogSegment.Read(step.Offset, step.Length)
+				inFlightHitBuf, err = vS.readInFlightLogSegment(inFlightLogSegment, step.Offset, step.Length)
 				if nil != err {
 					fileInode.Unlock()
 					logger.ErrorfWithError(err, "Reading back inFlightLogSegment failed - general case")
@@ -281,6 +419,7 @@ func (vS *volumeStruct) doReadPlan(fileInode *inMemoryInodeStruct, readPlan []Re
 				stats.IncrementOperations(&stats.FileWritebackHitOps)
 			} else {
 				fileInode.Unlock()
+				logSegmentCompressionUnits = vS.fetchClosedLogSegmentCompressionUnits(step.LogSegmentNumber)
 				if (0 == stepIndex) && (1 == len(readPlan)) {
 					// No need to increment stats.FileWritebackMissOps since it was incremented above
 				} else {
@@ -288,57 +427,34 @@ func (vS *volumeStruct) doReadPlan(fileInode *inMemoryInodeStruct, readPlan []Re
 				}
 			}
 			if !inFlightHit {
-				// The step is satisfied by hitting or missing the Read Cache
-				readCacheKey.logSegmentNumber = step.LogSegmentNumber
-				chunkOffset = step.Offset
-				remainingLength = step.Length
-				for 0 < remainingLength {
-					readCacheKey.cacheLineTag = chunkOffset / readCacheLineSize
-					cacheLineHitOffset = chunkOffset % readCacheLineSize
-					if (cacheLineHitOffset + remainingLength) > readCacheLineSize {
-						// When we've got a cache hit, the read extends beyond the cache line
-						cacheLineHitLength = readCacheLineSize - cacheLineHitOffset
-					} else {
-						// When we've got a cache hit, all the data is inside the cache line
-						cacheLineHitLength = remainingLength
-					}
-					volumeGroup.Lock()
-					readCacheElement, readCacheHit = volumeGroup.readCache[readCacheKey]
-					if readCacheHit {
-						volumeGroup.touchReadCacheElementWhileLocked(readCacheElement)
-						cacheLine = readCacheElement.cacheLine
-						volumeGroup.Unlock()
-						stats.IncrementOperations(&stats.FileReadcacheHitOps)
-					} else {
-						volumeGroup.Unlock()
-						stats.IncrementOperations(&stats.FileReadcacheMissOps)
-						// Make readCacheHit true (at MRU, likely kicking out LRU)
-						cacheLineStartOffset = readCacheKey.cacheLineTag * readCacheLineSize
-						cacheLine, err = swiftclient.ObjectGet(step.AccountName, step.ContainerName, step.ObjectName, cacheLineStartOffset, readCacheLineSize)
-						if nil != err {
-							logger.ErrorfWithError(err, "Reading from LogSegment object failed - general case")
-							err = blunder.AddError(err, blunder.SegReadError)
-							return
-						}
-						readCacheElement = &readCacheElementStruct{
-							readCacheKey: readCacheKey,
-							next:         nil,
-							prev:         nil,
-							cacheLine:    cacheLine,
-						}
-						volumeGroup.Lock()
-						volumeGroup.insertReadCacheElementWhileLocked(readCacheElement)
-						volumeGroup.Unlock()
+				if nil == logSegmentCompressionUnits {
+					// The step is satisfied by hitting or missing the Read Cache
+					buf, err = vS.appendFromReadCache(fileInode, buf, step)
+					if nil != err {
+						return
 					}
-					if (cacheLineHitOffset + cacheLineHitLength) > uint64(len(cacheLine)) {
-						err = fmt.Errorf("Invalid range for LogSegment object - general case")
+				} else {
+					// The step must be split along the LogSegment's compression units
+					translatedSteps, err = translateReadPlanStep(step, logSegmentCompressionUnits)
+					if nil != err {
 						logger.ErrorWithError(err)
 						err = blunder.AddError(err, blunder.SegReadError)
 						return
 					}
-					buf = append(buf, cacheLine[cacheLineHitOffset:(cacheLineHitOffset+cacheLineHitLength)]...)
-					chunkOffset += cacheLineHitLength
-					remainingLength -= cacheLineHitLength
+					for _, translatedStep = range translatedSteps {
+						if !stepReferencesUnit(&translatedStep) {
+							buf, err = vS.appendFromReadCache(fileInode, buf, translatedStep)
+							if nil != err {
+								return
+							}
+						} else {
+							unitBuf, err = vS.readCompressedUnit(fileInode, translatedStep)
+							if nil != err {
+								return
+							}
+							buf = append(buf, unitBuf[translatedStep.Offset:(translatedStep.Offset+translatedStep.Length)]...)
+						}
+					}
 				}
 			}
 		}
@@ -350,6 +466,85 @@ func (vS *volumeStruct) doReadPlan(fileInode *inMemoryInodeStruct, readPlan []Re
 	return
 }
 
+// appendFromReadCache appends the LogSegment object bytes [step.Offset:step.Offset+step.Length) to buf
+// by hitting or missing the Read Cache.
+//
+func (vS *volumeStruct) appendFromReadCache(fileInode *inMemoryInodeStruct, buf []byte, step ReadPlanStep) (newBuf []byte, err error) {
+	var (
+		cacheLine            []byte
+		cacheLineHitLength   uint64
+		cacheLineHitOffset   uint64
+		cacheLineStartOffset uint64
+		chunkOffset          uint64
+		readCacheElement     *readCacheElementStruct
+		readCacheHit         bool
+		readCacheKey         readCacheKeyStruct
+		readCacheLineSize    uint64
+		remainingLength      uint64
+		volumeGroup          *volumeGroupStruct
+	)
+
+	volumeGroup = vS.volumeGroup
+	readCacheLineSize = volumeGroup.readCacheLineSize
+	readCacheKey.volumeName = vS.volumeName
+	readCacheKey.logSegmentNumber = step.LogSegmentNumber
+
+	newBuf = buf
+
+	chunkOffset = step.Offset
+	remainingLength = step.Length
+	for 0 < remainingLength {
+		readCacheKey.cacheLineTag = chunkOffset / readCacheLineSize
+		cacheLineHitOffset = chunkOffset % readCacheLineSize
+		if (cacheLineHitOffset + remainingLength) > readCacheLineSize {
+			// When we've got a cache hit, the read extends beyond the cache line
+			cacheLineHitLength = readCacheLineSize - cacheLineHitOffset
+		} else {
+			// When we've got a cache hit, all the data is inside the cache line
+			cacheLineHitLength = remainingLength
+		}
+		volumeGroup.Lock()
+		readCacheElement, readCacheHit = volumeGroup.readCache[readCacheKey]
+		if readCacheHit {
+			volumeGroup.touchReadCacheElementWhileLocked(readCacheElement)
+			cacheLine = readCacheElement.cacheLine
+			volumeGroup.Unlock()
+			stats.IncrementOperations(&stats.FileReadcacheHitOps)
+		} else {
+			volumeGroup.Unlock()
+			stats.IncrementOperations(&stats.FileReadcacheMissOps)
+			// Make readCacheHit true (at MRU, likely kicking out LRU)
+			cacheLineStartOffset = readCacheKey.cacheLineTag * readCacheLineSize
+			cacheLine, err = vS.getLogSegmentRange(fileInode.InodeNumber, step, vS.fetchLogSegmentChecksums(step.LogSegmentNumber), cacheLineStartOffset, readCacheLineSize)
+			if nil != err {
+				logger.ErrorfWithError(err, "Reading from LogSegment object failed - general case")
+				return
+			}
+			readCacheElement = &readCacheElementStruct{
+				readCacheKey: readCacheKey,
+				next:         nil,
+				prev:         nil,
+				cacheLine:    cacheLine,
+			}
+			volumeGroup.Lock()
+			volumeGroup.insertReadCacheElementWhileLocked(readCacheElement)
+			volumeGroup.Unlock()
+		}
+		if (cacheLineHitOffset + cacheLineHitLength) > uint64(len(cacheLine)) {
+			err = fmt.Errorf("Invalid range for LogSegment object - general case")
+			logger.ErrorWithError(err)
+			err = blunder.AddError(err, blunder.SegReadError)
+			return
+		}
+		newBuf = append(newBuf, cacheLine[cacheLineHitOffset:(cacheLineHitOffset+cacheLineHitLength)]...)
+		chunkOffset += cacheLineHitLength
+		remainingLength -= cacheLineHitLength
+	}
+
+	err = nil
+	return
+}
+
 func (vS *volumeStruct) doSendChunk(fileInode *inMemoryInodeStruct, buf []byte) (logSegmentNumber uint64, logSegmentOffset uint64, err error) {
 	var (
 		inFlightLogSegment          *inFlightLogSegmentStruct
@@ -387,6 +582,20 @@ func (vS *volumeStruct) doSendChunk(fileInode *inMemoryInodeStruct, buf []byte)
 			objectName:       utils.Uint64ToHexStr(openLogSegmentObjectNumber),
 		}
 
+		if fileInode.volume.dataChecksums {
+			inFlightLogSegment.checksummer = newLogSegmentChecksummer()
+		}
+
+		if fileInode.volume.unitizesLogSegments() {
+			inFlightLogSegment.unitized = true
+			inFlightLogSegment.compressionPendingBuf = make([]byte, 0, compressionUnitSize)
+			inFlightLogSegment.compressionPendingOffset = 0
+		}
+
+		if fileInode.volume.deduplication {
+			inFlightLogSegment.dedupChunker = newDedupChunker()
+		}
+
 		inFlightLogSegment.ChunkedPutContext, err = swiftclient.ObjectFetchChunkedPutContext(inFlightLogSegment.accountName, inFlightLogSegment.containerName, inFlightLogSegment.objectName, "")
 		if nil != err {
 			logger.ErrorfWithError(err, "Starting Chunked PUT to LogSegment failed")
@@ -408,18 +617,34 @@ func (vS *volumeStruct) doSendChunk(fileInode *inMemoryInodeStruct, buf []byte)
 
 	logSegmentNumber = inFlightLogSegment.logSegmentNumber
 
-	logSegmentOffset, err = inFlightLogSegment.BytesPut()
-	if nil != err {
-		fileInode.Unlock()
-		logger.ErrorfWithError(err, "Failed to get current LogSegmentOffset")
-		return
+	if inFlightLogSegment.unitized {
+		logSegmentOffset, err = vS.sendCompressedChunk(inFlightLogSegment, buf)
+		if nil != err {
+			fileInode.Unlock()
+			return
+		}
+	} else {
+		logSegmentOffset, err = inFlightLogSegment.BytesPut()
+		if nil != err {
+			fileInode.Unlock()
+			logger.ErrorfWithError(err, "Failed to get current LogSegmentOffset")
+			return
+		}
+
+		err = inFlightLogSegment.ChunkedPutContext.SendChunk(buf)
+		if nil != err {
+			fileInode.Unlock()
+			logger.ErrorfWithError(err, "Sending Chunked PUT chunk to LogSegment failed")
+			return
+		}
+
+		if nil != inFlightLogSegment.checksummer {
+			inFlightLogSegment.checksummer.update(buf)
+		}
 	}
 
-	err = inFlightLogSegment.ChunkedPutContext.SendChunk(buf)
-	if nil != err {
-		fileInode.Unlock()
-		logger.ErrorfWithError(err, "Sending Chunked PUT chunk to LogSegment failed")
-		return
+	if nil != inFlightLogSegment.dedupChunker {
+		inFlightLogSegment.dedupChunker.update(buf)
 	}
 
 	if (logSegmentOffset + uint64(len(buf))) >= fileInode.volume.maxFlushSize {
@@ -466,6 +691,7 @@ func (vS *volumeStruct) doFileInodeDataFlush(fileInode *inMemoryInodeStruct) (er
 
 func (vS *volumeStruct) inFlightLogSegmentFlusher(inFlightLogSegment *inFlightLogSegmentStruct, doDone bool) {
 	var (
+		closeErr  error
 		err       error
 		fileInode *inMemoryInodeStruct
 	)
@@ -492,10 +718,19 @@ func (vS *volumeStruct) inFlightLogSegmentFlusher(inFlightLogSegment *inFlightLo
 
 	fileInode.openLogSegment = nil
 
+	// Send any data still pending compression (or encryption)
+
+	if inFlightLogSegment.unitized && (0 < len(inFlightLogSegment.compressionPendingBuf)) {
+		err = vS.sendCompressionUnit(inFlightLogSegment, uint64(len(inFlightLogSegment.compressionPendingBuf)))
+	}
+
 	// Terminate Chunked PUT while not holding fileInode.Lock
 
 	fileInode.Unlock()
-	err = inFlightLogSegment.Close()
+	closeErr = inFlightLogSegment.Close()
+	if nil == err {
+		err = closeErr
+	}
 	fileInode.Lock()
 
 	// Finish up... recording error (if any) in the process
@@ -503,6 +738,26 @@ func (vS *volumeStruct) inFlightLogSegmentFlusher(inFlightLogSegment *inFlightLo
 	if nil != err {
 		err = blunder.AddError(err, blunder.InodeFlushError)
 		fileInode.inFlightLogSegmentErrors[inFlightLogSegment.logSegmentNumber] = err
+	} else if (nil != inFlightLogSegment.checksummer) || inFlightLogSegment.unitized {
+		logSegmentRec := &logSegmentRecStruct{containerName: inFlightLogSegment.containerName}
+		if nil != inFlightLogSegment.checksummer {
+			logSegmentRec.checksums = inFlightLogSegment.checksummer.finish()
+		}
+		if inFlightLogSegment.unitized {
+			logSegmentRec.compressionUnits = append(make([]logSegmentCompressionUnitStruct, 0, len(inFlightLogSegment.compressionUnits)), inFlightLogSegment.compressionUnits...)
+		}
+		err = vS.putLogSegmentRec(inFlightLogSegment.logSegmentNumber, logSegmentRec)
+		if nil != err {
+			err = blunder.AddError(err, blunder.InodeFlushError)
+			fileInode.inFlightLogSegmentErrors[inFlightLogSegment.logSegmentNumber] = err
+		}
+	}
+
+	if (nil == err) && (nil != inFlightLogSegment.dedupChunker) {
+		if nil == fileInode.dedupPendingChunkMap {
+			fileInode.dedupPendingChunkMap = make(map[uint64][]dedupChunkStruct)
+		}
+		fileInode.dedupPendingChunkMap[inFlightLogSegment.logSegmentNumber] = inFlightLogSegment.dedupChunker.finish()
 	}
 
 	delete(inFlightLogSegment.fileInode.inFlightLogSegmentMap, inFlightLogSegment.logSegmentNumber)
@@ -1,6 +1,7 @@
 package inode
 
 import (
+	"bytes"
 	"testing"
 	"time"
 
@@ -14,6 +15,27 @@ type testObjectLocationStruct struct {
 }
 
 func TestEmptySegmentDeletion(t *testing.T) {
+	// '$$$$$$$$$$$$$$$$'
+	ourBytes := []byte{0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24}
+
+	testSegmentDeletion(t, nil, ourBytes)
+}
+
+func TestChecksummedSegmentDeletion(t *testing.T) {
+	// LogSegmentRec's of checksummed LogSegments also record their checksums
+
+	testSegmentDeletion(t, func(volume *volumeStruct) { volume.dataChecksums = true }, bytes.Repeat([]byte{0x24}, 16))
+}
+
+func TestCompressedSegmentDeletion(t *testing.T) {
+	// LogSegmentRec's of compressed LogSegments also record their compression units
+
+	testSegmentDeletion(t, func(volume *volumeStruct) { volume.compression = CompressionDeflate }, bytes.Repeat([]byte{0x24}, 4096))
+}
+
+// testSegmentDeletion verifies that the LogSegments written (as ourBytes) to a file on a volume (first
+// configured by configureVolume, if non-nil) are deleted from Swift once overwritten and checkpointed.
+func testSegmentDeletion(t *testing.T, configureVolume func(volume *volumeStruct), ourBytes []byte) {
 	testSetup(t, false)
 
 	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
@@ -23,17 +45,18 @@ func TestEmptySegmentDeletion(t *testing.T) {
 
 	volume := testVolumeHandle.(*volumeStruct)
 
+	if nil != configureVolume {
+		configureVolume(volume)
+	}
+
 	ino, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
 	if nil != err {
 		t.Fatalf("CreateFile() failed: %v", err)
 	}
 
-	// '$$$$$$$$$$$$$$$$'
-	ourBytes := []byte{0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24, 0x24}
-
 	// repeatedly write and flush to create some log segments
 	for i := 0; i < 5; i++ {
-		err := testVolumeHandle.Write(ino, uint64(i*16), ourBytes, nil)
+		err := testVolumeHandle.Write(ino, uint64(i*len(ourBytes)), ourBytes, nil)
 		if err != nil {
 			t.Fatalf("failed to write: %v", err)
 		}
@@ -61,10 +84,17 @@ func TestEmptySegmentDeletion(t *testing.T) {
 			t.Fatalf("expected to be able to get log segment 0x%016X", segmentNumber)
 		}
 		segmentObjectLocations = append(segmentObjectLocations, testObjectLocationStruct{volume.accountName, containerName, objectName})
+		logSegmentRecValue, getLogSegmentRecErr := volume.headhunterVolumeHandle.GetLogSegmentRec(segmentNumber)
+		if nil != getLogSegmentRecErr {
+			t.Fatalf("expected to be able to get LogSegmentRec for log segment 0x%016X", segmentNumber)
+		}
+		if (nil != configureVolume) != (0 <= bytes.IndexByte(logSegmentRecValue, logSegmentRecMetadataSeparator)) {
+			t.Fatalf("LogSegmentRec for log segment 0x%016X unexpectedly recorded (or didn't record) metadata", segmentNumber)
+		}
 	}
 
 	// overwrite it
-	err = testVolumeHandle.Write(ino, 0, make([]byte, 16*5), nil)
+	err = testVolumeHandle.Write(ino, 0, make([]byte, len(ourBytes)*5), nil)
 	if err != nil {
 		t.Fatalf("expected to be able to write to inode #%v", ino)
 	}
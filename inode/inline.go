@@ -0,0 +1,135 @@
+package inode
+
+import (
+	"github.com/swiftstack/sortedmap"
+
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+// FileInodes no larger than a volume's InlineDataThreshold hold their data in InlineData (much as
+// SymlinkInodes hold SymlinkTarget) rather than in LogSegments referenced by their extent map. Such a
+// FileInode has an empty extent map & LogSegmentMap and len(InlineData) == Size. Once it would grow
+// beyond InlineDataThreshold, promoteInlineData() moves InlineData to a LogSegment-backed extent.
+//
+// Note that an empty InlineData always means "not inline" so that a zero length FileInode (as well as
+// a sparse one lacking any extents) may become inline upon its first (sufficiently small) Write().
+//
+
+func (fileInode *inMemoryInodeStruct) isInline() (inline bool) {
+	inline = (0 < len(fileInode.InlineData))
+	return
+}
+
+// inlineWrite attempts to apply a Write() of buf at offset to fileInode's InlineData. If the result
+// would exceed vS.inlineDataThreshold (or fileInode already has extents), fileInode is left untouched
+// and inlined == false is returned.
+//
+func (vS *volumeStruct) inlineWrite(fileInode *inMemoryInodeStruct, offset uint64, buf []byte) (inlined bool, err error) {
+	var (
+		extents    sortedmap.BPlusTree
+		extentsLen int
+		newSize    uint64
+	)
+
+	newSize = offset + uint64(len(buf))
+	if newSize < fileInode.Size {
+		newSize = fileInode.Size
+	}
+
+	if newSize > vS.inlineDataThreshold {
+		inlined = false
+		err = nil
+		return
+	}
+
+	if !fileInode.isInline() {
+		extents = fileInode.payload.(sortedmap.BPlusTree)
+
+		extentsLen, err = extents.Len()
+		if nil != err {
+			return
+		}
+
+		if 0 != extentsLen {
+			inlined = false
+			err = nil
+			return
+		}
+
+		// Any existing (sparse) Size becomes explicit zeroes
+
+		fileInode.InlineData = make([]byte, fileInode.Size, newSize)
+	}
+
+	if uint64(len(fileInode.InlineData)) < newSize {
+		fileInode.InlineData = append(fileInode.InlineData, make([]byte, newSize-uint64(len(fileInode.InlineData)))...)
+	}
+
+	copy(fileInode.InlineData[offset:], buf)
+
+	fileInode.Size = newSize
+
+	stats.IncrementOperations(&stats.FileInlineWriteOps)
+
+	inlined = true
+	err = nil
+	return
+}
+
+// inlineSetSize applies a size change to an inline fileInode. If the new size would exceed
+// vS.inlineDataThreshold, fileInode is left untouched and done == false is returned (the caller
+// must then promoteInlineData() before proceeding).
+//
+func (vS *volumeStruct) inlineSetSize(fileInode *inMemoryInodeStruct, size uint64) (done bool) {
+	if size <= uint64(len(fileInode.InlineData)) {
+		if 0 == size {
+			fileInode.InlineData = nil
+		} else {
+			fileInode.InlineData = fileInode.InlineData[:size]
+		}
+	} else if size <= vS.inlineDataThreshold {
+		fileInode.InlineData = append(fileInode.InlineData, make([]byte, size-uint64(len(fileInode.InlineData)))...)
+	} else {
+		done = false
+		return
+	}
+
+	fileInode.Size = size
+
+	done = true
+	return
+}
+
+// promoteInlineData moves an inline fileInode's InlineData to a LogSegment.
+//
+func (vS *volumeStruct) promoteInlineData(fileInode *inMemoryInodeStruct) (err error) {
+	var (
+		inlineData       []byte
+		logSegmentNumber uint64
+		logSegmentOffset uint64
+	)
+
+	if !fileInode.isInline() {
+		err = nil
+		return
+	}
+
+	inlineData = fileInode.InlineData
+
+	logSegmentNumber, logSegmentOffset, err = vS.doSendChunk(fileInode, inlineData)
+	if nil != err {
+		return
+	}
+
+	fileInode.InlineData = nil
+	fileInode.dirty = true
+
+	err = recordWrite(fileInode, 0, uint64(len(inlineData)), logSegmentNumber, logSegmentOffset)
+	if nil != err {
+		return
+	}
+
+	stats.IncrementOperations(&stats.FileInlinePromoteOps)
+
+	return
+}
@@ -0,0 +1,148 @@
+package inode
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestInlineData(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	testVolume := testVolumeHandle.(*volumeStruct)
+
+	testVolume.inlineDataThreshold = 4096
+	defer func() {
+		testVolume.inlineDataThreshold = 0
+	}()
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+
+	smallBuf := []byte("a tiny config file")
+
+	err = testVolumeHandle.Write(fileInodeNumber, 0, smallBuf, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, true)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	fileInode, ok, err := testVolume.fetchInode(fileInodeNumber)
+	if (nil != err) || !ok {
+		t.Fatalf("fetchInode() failed: %v", err)
+	}
+	if !fileInode.isInline() || (0 != len(fileInode.LogSegmentMap)) || (uint64(len(smallBuf)) != fileInode.Size) {
+		t.Fatalf("Small FileInode should have been kept inline")
+	}
+
+	readBuf, err := testVolumeHandle.Read(fileInodeNumber, 2, 4, nil)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if !bytes.Equal(smallBuf[2:6], readBuf) {
+		t.Fatalf("Read() of inline FileInode returned unexpected data")
+	}
+
+	zero := uint64(0)
+	readPlan, err := testVolumeHandle.GetReadPlan(fileInodeNumber, &zero, nil)
+	if nil != err {
+		t.Fatalf("GetReadPlan() failed: %v", err)
+	}
+	if (1 != len(readPlan)) || !bytes.Equal(smallBuf, readPlan[0].InlineData) || ("" != readPlan[0].ObjectPath) {
+		t.Fatalf("GetReadPlan() of inline FileInode returned unexpected readPlan: %+v", readPlan)
+	}
+
+	// Shrinking & (modestly) growing should remain inline
+
+	err = testVolumeHandle.SetSize(fileInodeNumber, 6)
+	if nil != err {
+		t.Fatalf("SetSize() failed: %v", err)
+	}
+	err = testVolumeHandle.SetSize(fileInodeNumber, 10)
+	if nil != err {
+		t.Fatalf("SetSize() failed: %v", err)
+	}
+	if !fileInode.isInline() {
+		t.Fatalf("SetSize() within InlineDataThreshold should have remained inline")
+	}
+
+	expectedBuf := append(append([]byte{}, smallBuf[:6]...), make([]byte, 4)...)
+
+	readBuf, err = testVolumeHandle.Read(fileInodeNumber, 0, 10, nil)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if !bytes.Equal(expectedBuf, readBuf) {
+		t.Fatalf("Read() of inline FileInode following SetSize() returned unexpected data")
+	}
+
+	// Growing beyond the threshold should promote InlineData to a LogSegment
+
+	bigBuf := bytes.Repeat([]byte("promote "), 1024)
+
+	err = testVolumeHandle.Write(fileInodeNumber, 100, bigBuf, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	if fileInode.isInline() || (0 == len(fileInode.LogSegmentMap)) {
+		t.Fatalf("FileInode beyond InlineDataThreshold should have been promoted")
+	}
+
+	expectedBuf = append(append(expectedBuf, make([]byte, 90)...), bigBuf...)
+
+	readBuf, err = testVolumeHandle.Read(fileInodeNumber, 0, uint64(len(expectedBuf)), nil)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if !bytes.Equal(expectedBuf, readBuf) {
+		t.Fatalf("Read() of promoted FileInode returned unexpected data")
+	}
+
+	err = testVolumeHandle.Validate(fileInodeNumber, true)
+	if nil != err {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+
+	// Once truncated to zero, a FileInode may become inline again
+
+	err = testVolumeHandle.SetSize(fileInodeNumber, 0)
+	if nil != err {
+		t.Fatalf("SetSize() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 0, smallBuf, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	fileInode, ok, err = testVolume.fetchInode(fileInodeNumber) // Validate() may have evicted the prior one
+	if (nil != err) || !ok {
+		t.Fatalf("fetchInode() failed: %v", err)
+	}
+	if !fileInode.isInline() || (0 != len(fileInode.LogSegmentMap)) {
+		t.Fatalf("Truncated then rewritten small FileInode should have been inline")
+	}
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	testTeardown(t)
+}
@@ -7,6 +7,7 @@ import (
 	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ansel1/merry"
@@ -29,7 +30,8 @@ import (
 var int_inode_debug = logger.DbgInodeInternal
 
 const (
-	optimisticInodeFetchBytes = 2048
+	optimisticInodeFetchBytes  = 2048
+	bPlusTreeNodeBytesEstimate = uint64(4096) // Initial estimate (refined as nodes are fetched) of B+Tree node size for cachebudget
 )
 
 type CorruptionDetected bool
@@ -37,6 +39,7 @@ type Version uint64
 
 const (
 	V1                               Version = iota + 1 // use type/struct onDiskInodeV1Struct
+	V2                                                  // use type/struct onDiskInodeV1Struct binary encoded (see ondisk_v2.go)
 	onDiskInodeV1PayloadObjectOffset uint64  = 0
 )
 
@@ -58,6 +61,7 @@ type onDiskInodeV1Struct struct { // Preceded "on disk" by CorruptionDetected th
 	PayloadObjectLength uint64            // FileInode:    B+Tree Root with Key == fileOffset, Value = fileExtent
 	SymlinkTarget       string            // SymlinkInode: target path of symbolic link
 	LogSegmentMap       map[uint64]uint64 // FileInode:    Key == LogSegment#, Value = file user data byte count
+	InlineData          []byte            // FileInode:    if len() != 0, file data (of len() == Size) held in place of extents
 }
 
 type inFlightLogSegmentStruct struct { //               Used as (by reference) Value for inMemoryInodeStruct.inFlightLogSegmentMap
@@ -69,6 +73,12 @@ type inFlightLogSegmentStruct struct { //               Used as (by reference) V
 	containerName             string
 	objectName                string
 	openLogSegmentListElement list.Element
+	checksummer               *logSegmentChecksummerStruct      // nil if !volume.dataChecksums
+	unitized                  bool                              // true if volume.compression != compressionNone or volume.dataKey != nil
+	compressionPendingBuf     []byte                            // Data not yet compressed/encrypted & sent (if unitized)
+	compressionPendingOffset  uint64                            // Logical LogSegment offset of compressionPendingBuf[0]
+	compressionUnits          []logSegmentCompressionUnitStruct // Units sent so far (recorded in the LogSegmentRec upon Close())
+	dedupChunker              *dedupChunkerStruct               // nil if !volume.deduplication
 	swiftclient.ChunkedPutContext
 }
 
@@ -85,6 +95,11 @@ type inMemoryInodeStruct struct {
 	openLogSegment           *inFlightLogSegmentStruct            // FileInode only... also in inFlightLogSegmentMap
 	inFlightLogSegmentMap    map[uint64]*inFlightLogSegmentStruct // FileInode: key == logSegmentNumber
 	inFlightLogSegmentErrors map[uint64]error                     // FileInode: key == logSegmentNumber; value == err (if non nil)
+	dedupPendingChunkMap     map[uint64][]dedupChunkStruct        // FileInode: key == logSegmentNumber of completed LogSegments awaiting dedupFileInode()
+	eTagGeneration           uint64                               // FileInode: volume.eTagGenerations as of the last invalidateETag() or fetch (never 0)
+	caseFoldLock             sync.Mutex                           // DirInode:  serializes access to caseFoldIndex
+	caseFoldIndex            map[string]string                    // DirInode:  if != nil, key == caseFold(basename), value == basename (see casefold.go)
+	caseFoldCollisions       map[string][]string                  // DirInode:  if caseFoldIndex != nil, key == caseFold(basename) shared by >1 basename, value == the others (sorted)
 	onDiskInodeV1Struct                                           // Real on-disk inode information embedded here
 }
 
@@ -186,17 +201,27 @@ func (vS *volumeStruct) fetchOnDiskInode(inodeNumber InodeNumber) (inMemoryInode
 		err = blunder.AddError(err, blunder.CorruptInodeError)
 		return
 	}
-	if V1 != version {
-		err = fmt.Errorf("%s: inodeRec.Version for inode %d (%v) not supported", utils.GetFnName(), inodeNumber, version)
-		err = blunder.AddError(err, blunder.CorruptInodeError)
-		return
+	onDiskInodeV1 = &onDiskInodeV1Struct{
+		StreamMap: make(map[string][]byte),
 	}
 
-	onDiskInodeV1 = &onDiskInodeV1Struct{StreamMap: make(map[string][]byte)}
-
-	err = json.Unmarshal(inodeRec[bytesConsumedByCorruptionDetected+bytesConsumedByVersion:], onDiskInodeV1)
-	if nil != err {
-		err = fmt.Errorf("%s: inodeRec.<body> for inode %d json.Unmarshal() failed: %v", utils.GetFnName(), inodeNumber, err)
+	switch version {
+	case V1:
+		err = json.Unmarshal(inodeRec[bytesConsumedByCorruptionDetected+bytesConsumedByVersion:], onDiskInodeV1)
+		if nil != err {
+			err = fmt.Errorf("%s: inodeRec.<body> for inode %d json.Unmarshal() failed: %v", utils.GetFnName(), inodeNumber, err)
+			err = blunder.AddError(err, blunder.CorruptInodeError)
+			return
+		}
+	case V2:
+		err = decodeOnDiskInodeV2(inodeRec[bytesConsumedByCorruptionDetected+bytesConsumedByVersion:], onDiskInodeV1)
+		if nil != err {
+			err = fmt.Errorf("%s: inodeRec.<body> for inode %d decodeOnDiskInodeV2() failed: %v", utils.GetFnName(), inodeNumber, err)
+			err = blunder.AddError(err, blunder.CorruptInodeError)
+			return
+		}
+	default:
+		err = fmt.Errorf("%s: inodeRec.Version for inode %d (%v) not supported", utils.GetFnName(), inodeNumber, version)
 		err = blunder.AddError(err, blunder.CorruptInodeError)
 		return
 	}
@@ -210,6 +235,7 @@ func (vS *volumeStruct) fetchOnDiskInode(inodeNumber InodeNumber) (inMemoryInode
 		openLogSegment:           nil,
 		inFlightLogSegmentMap:    make(map[uint64]*inFlightLogSegmentStruct),
 		inFlightLogSegmentErrors: make(map[uint64]error),
+		eTagGeneration:           atomic.AddUint64(&vS.eTagGenerations, 1),
 		onDiskInodeV1Struct:      *onDiskInodeV1,
 	}
 
@@ -563,6 +589,7 @@ func (vS *volumeStruct) makeInMemoryInodeWithThisInodeNumber(inodeType InodeType
 		openLogSegment:           nil,
 		inFlightLogSegmentMap:    make(map[uint64]*inFlightLogSegmentStruct),
 		inFlightLogSegmentErrors: make(map[uint64]error),
+		eTagGeneration:           atomic.AddUint64(&vS.eTagGenerations, 1),
 		onDiskInodeV1Struct: onDiskInodeV1Struct{
 			InodeNumber:      InodeNumber(nonce),
 			InodeType:        inodeType,
@@ -632,6 +659,34 @@ func (inMemoryInode *inMemoryInodeStruct) convertToOnDiskInodeV1() (onDiskInodeV
 	return &onDiskInode, nil
 }
 
+// encodeInodeRec produces the inode record (including preamble) for onDiskInodeV1 in the form
+// dictated by vS.onDiskInodeVersion.
+func (vS *volumeStruct) encodeInodeRec(onDiskInodeV1 *onDiskInodeV1Struct) (inodeRec []byte, err error) {
+	var (
+		onDiskInodeBuf []byte
+		preambleBuf    []byte
+	)
+
+	switch vS.onDiskInodeVersion {
+	case V1:
+		onDiskInodeBuf, err = json.Marshal(onDiskInodeV1)
+		if nil != err {
+			return
+		}
+		preambleBuf = globals.inodeRecDefaultPreambleBuf
+	default:
+		onDiskInodeBuf = encodeOnDiskInodeV2(onDiskInodeV1)
+		preambleBuf = globals.inodeRecV2PreambleBuf
+	}
+
+	inodeRec = make([]byte, 0, len(preambleBuf)+len(onDiskInodeBuf))
+	inodeRec = append(inodeRec, preambleBuf...)
+	inodeRec = append(inodeRec, onDiskInodeBuf...)
+
+	err = nil
+	return
+}
+
 func (vS *volumeStruct) flushInode(inode *inMemoryInodeStruct) (err error) {
 	err = vS.flushInodes([]*inMemoryInodeStruct{inode})
 	return
@@ -655,7 +710,6 @@ func (vS *volumeStruct) flushInodes(inodes []*inMemoryInodeStruct) (err error) {
 		logSegmentNumber          uint64
 		logSegmentValidBytes      uint64
 		onDiskInodeV1             *onDiskInodeV1Struct
-		onDiskInodeV1Buf          []byte
 		payloadAsBPlusTree        sortedmap.BPlusTree
 		payloadObjectLength       uint64
 		payloadObjectNumber       uint64
@@ -686,6 +740,13 @@ func (vS *volumeStruct) flushInodes(inodes []*inMemoryInodeStruct) (err error) {
 				err = blunder.AddError(err, blunder.InodeFlushError)
 				return
 			}
+			err = vS.dedupFileInode(inode)
+			if nil != err {
+				evtlog.Record(evtlog.FormatFlushInodesErrorOnInode, vS.volumeName, uint64(inode.InodeNumber), err.Error())
+				logger.ErrorWithError(err)
+				err = blunder.AddError(err, blunder.InodeFlushError)
+				return
+			}
 			emptyLogSegmentsThisInode = make([]uint64, 0)
 			for logSegmentNumber, logSegmentValidBytes = range inode.LogSegmentMap {
 				if 0 == logSegmentValidBytes {
@@ -739,16 +800,13 @@ func (vS *volumeStruct) flushInodes(inodes []*inMemoryInodeStruct) (err error) {
 				err = blunder.AddError(err, blunder.InodeFlushError)
 				return
 			}
-			onDiskInodeV1Buf, err = json.Marshal(onDiskInodeV1)
+			dirtyInodeRecBytes, err = vS.encodeInodeRec(onDiskInodeV1)
 			if nil != err {
 				evtlog.Record(evtlog.FormatFlushInodesErrorOnInode, vS.volumeName, uint64(inode.InodeNumber), err.Error())
 				logger.ErrorWithError(err)
 				err = blunder.AddError(err, blunder.InodeFlushError)
 				return
 			}
-			dirtyInodeRecBytes = make([]byte, 0, len(globals.inodeRecDefaultPreambleBuf)+len(onDiskInodeV1Buf))
-			dirtyInodeRecBytes = append(dirtyInodeRecBytes, globals.inodeRecDefaultPreambleBuf...)
-			dirtyInodeRecBytes = append(dirtyInodeRecBytes, onDiskInodeV1Buf...)
 			dirtyInodeNumbers = append(dirtyInodeNumbers, uint64(inode.InodeNumber))
 			dirtyInodeRecs = append(dirtyInodeRecs, dirtyInodeRecBytes)
 		}
@@ -771,7 +829,7 @@ func (vS *volumeStruct) flushInodes(inodes []*inMemoryInodeStruct) (err error) {
 	// Now do phase one of garbage collection
 	if 0 < len(emptyLogSegments) {
 		for _, logSegmentNumber = range emptyLogSegments {
-			err = vS.headhunterVolumeHandle.DeleteLogSegmentRec(logSegmentNumber)
+			err = vS.releaseLogSegment(logSegmentNumber)
 			if nil != err {
 				logger.WarnfWithError(err, "couldn't delete garbage log segment")
 			}
@@ -1126,6 +1184,12 @@ func (vS *volumeStruct) Access(inodeNumber InodeNumber, userID InodeUserID, grou
 }
 
 func (vS *volumeStruct) ProvisionObject() (objectPath string, err error) {
+	if nil != vS.dataKey {
+		// Objects PUT by some other agent (e.g. pfs_middleware) would hold unencrypted data
+		err = blunder.NewError(blunder.NotSupportedError, "ProvisionObject() not supported on encrypted Volume '%s'", vS.volumeName)
+		return
+	}
+
 	containerName, objectNumber, err := vS.provisionObject()
 	if nil != err {
 		return
@@ -1237,7 +1301,7 @@ func (vS *volumeStruct) Destroy(inodeNumber InodeNumber) (err error) {
 		}
 
 		for logSegmentNumber := range ourInode.LogSegmentMap {
-			deleteSegmentErr := vS.headhunterVolumeHandle.DeleteLogSegmentRec(logSegmentNumber)
+			deleteSegmentErr := vS.releaseLogSegment(logSegmentNumber)
 			if nil != deleteSegmentErr {
 				logger.WarnfWithError(deleteSegmentErr, "couldn't delete destroy'd log segment")
 				return
@@ -1915,6 +1979,12 @@ func validateFileExtents(snapShotID uint64, ourInode *inMemoryInodeStruct) (err
 		return err
 	}
 
+	// Steps referencing compressed (or encrypted) LogSegments must be expressed in terms of object bytes
+	readPlan, err = ourInode.volume.translateReadPlan(ourInode, readPlan)
+	if err != nil {
+		return blunder.AddError(err, blunder.CorruptInodeError)
+	}
+
 	// We read the whole file, so these should match
 	if readPlanBytes != ourInode.Size {
 		return blunder.NewError(blunder.CorruptInodeError, "inode %v had recorded size %v bytes, but full read plan was only %v bytes", ourInode.InodeNumber, ourInode.Size, readPlanBytes)
@@ -1974,6 +2044,9 @@ func validateFileExtents(snapShotID uint64, ourInode *inMemoryInodeStruct) (err
 			continue
 		}
 		stepEndOffset := planStep.Offset + planStep.Length
+		if stepReferencesUnit(&planStep) {
+			stepEndOffset = planStep.CompressedObjectOffset + planStep.CompressedObjectLength
+		}
 		endOffset, ok := objectPathToEndOffset[planStep.ObjectPath]
 		if !ok || stepEndOffset > endOffset {
 			objectPathToEndOffset[planStep.ObjectPath] = stepEndOffset
@@ -2111,6 +2184,11 @@ func (vS *volumeStruct) Validate(inodeNumber InodeNumber, deeply bool) (err erro
 					_ = vS.markCorrupted(inodeNumber)
 					return
 				}
+				err = vS.validateFileDataChecksums(snapShotID, ourInode)
+				if nil != err {
+					// The inode itself is fine... it's the data it references that is not
+					return
+				}
 			}
 		}
 	case SymlinkType:
@@ -0,0 +1,166 @@
+package inode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/swiftstack/ProxyFS/headhunter"
+)
+
+// A LogSegment's record in headhunter's LogSegmentRec B+Tree begins with the name of the container holding
+// the LogSegment. Metadata describing the LogSegment's data may follow a headhunter.LogSegmentRecMetadataSeparator
+// (which headhunter strips before recording the container name of a deleted LogSegment). Each item of such
+// metadata is encoded (as in V2 inode records) as a uvarint tag followed by:
+//
+//   logSegmentRecTagChecksums        - uvarint Length, uvarint block count, then each block's CRC32 (little endian)
+//   logSegmentRecTagCompressionUnits - uvarint unit count, then each unit's LogicalOffset, LogicalLength,
+//                                      ObjectOffset, & ObjectLength (uvarints) and Encrypted (bool)
+//
+// Keeping this metadata with the LogSegment (rather than in each inode referencing it) means it is only
+// written once no matter how many inodes (e.g. via dedup, clone, or Coalesce()) come to share the LogSegment.
+// It is removed along with the record itself once the LogSegment is deleted. While a LogSegment is in flight,
+// its metadata accumulates in its inFlightLogSegmentStruct and is only recorded here once it has been closed.
+//
+const (
+	logSegmentRecMetadataSeparator = headhunter.LogSegmentRecMetadataSeparator
+
+	logSegmentRecTagChecksums        = uint64(1)
+	logSegmentRecTagCompressionUnits = uint64(2)
+)
+
+type logSegmentRecStruct struct {
+	containerName    string
+	checksums        *logSegmentChecksumsStruct        // nil if unknown
+	compressionUnits []logSegmentCompressionUnitStruct // nil if LogSegment data is not compressed (nor encrypted)
+}
+
+func encodeLogSegmentRec(logSegmentRec *logSegmentRecStruct) (buf []byte) {
+	var (
+		blockCRC32 uint32
+		encoder    onDiskInodeV2EncoderStruct
+		unit       logSegmentCompressionUnitStruct
+	)
+
+	encoder.buf = append(encoder.buf, logSegmentRec.containerName...)
+
+	if (nil == logSegmentRec.checksums) && (nil == logSegmentRec.compressionUnits) {
+		buf = encoder.buf
+		return
+	}
+
+	encoder.buf = append(encoder.buf, logSegmentRecMetadataSeparator)
+
+	if nil != logSegmentRec.checksums {
+		encoder.putUvarint(logSegmentRecTagChecksums)
+		encoder.putUvarint(logSegmentRec.checksums.Length)
+		encoder.putUvarint(uint64(len(logSegmentRec.checksums.BlockCRC32)))
+		for _, blockCRC32 = range logSegmentRec.checksums.BlockCRC32 {
+			encoder.buf = append(encoder.buf, byte(blockCRC32), byte(blockCRC32>>8), byte(blockCRC32>>16), byte(blockCRC32>>24))
+		}
+	}
+
+	if nil != logSegmentRec.compressionUnits {
+		encoder.putUvarint(logSegmentRecTagCompressionUnits)
+		encoder.putUvarint(uint64(len(logSegmentRec.compressionUnits)))
+		for _, unit = range logSegmentRec.compressionUnits {
+			encoder.putUvarint(unit.LogicalOffset)
+			encoder.putUvarint(unit.LogicalLength)
+			encoder.putUvarint(unit.ObjectOffset)
+			encoder.putUvarint(unit.ObjectLength)
+			encoder.putBool(unit.Encrypted)
+		}
+	}
+
+	buf = encoder.buf
+
+	return
+}
+
+func decodeLogSegmentRec(buf []byte) (logSegmentRec *logSegmentRecStruct, err error) {
+	var (
+		blockCount     uint64
+		blockIndex     uint64
+		decoder        onDiskInodeV2DecoderStruct
+		separatorIndex int
+		tag            uint64
+		unitCount      uint64
+		unitIndex      uint64
+	)
+
+	logSegmentRec = &logSegmentRecStruct{}
+
+	separatorIndex = bytes.IndexByte(buf, logSegmentRecMetadataSeparator)
+	if 0 > separatorIndex {
+		logSegmentRec.containerName = string(buf)
+		err = nil
+		return
+	}
+
+	logSegmentRec.containerName = string(buf[:separatorIndex])
+
+	decoder.buf = buf[separatorIndex+1:]
+
+	for (nil == decoder.err) && (0 < len(decoder.buf)) {
+		tag = decoder.getUvarint()
+
+		switch tag {
+		case logSegmentRecTagChecksums:
+			logSegmentRec.checksums = &logSegmentChecksumsStruct{}
+			logSegmentRec.checksums.Length = decoder.getUvarint()
+			blockCount = decoder.getCount(4)
+			if nil != decoder.err {
+				break
+			}
+			logSegmentRec.checksums.BlockCRC32 = make([]uint32, blockCount)
+			for blockIndex = 0; blockIndex < blockCount; blockIndex++ {
+				logSegmentRec.checksums.BlockCRC32[blockIndex] = binary.LittleEndian.Uint32(decoder.buf)
+				decoder.buf = decoder.buf[4:]
+			}
+		case logSegmentRecTagCompressionUnits:
+			unitCount = decoder.getCount(5)
+			if nil != decoder.err {
+				break
+			}
+			logSegmentRec.compressionUnits = make([]logSegmentCompressionUnitStruct, unitCount)
+			for unitIndex = 0; unitIndex < unitCount; unitIndex++ {
+				logSegmentRec.compressionUnits[unitIndex].LogicalOffset = decoder.getUvarint()
+				logSegmentRec.compressionUnits[unitIndex].LogicalLength = decoder.getUvarint()
+				logSegmentRec.compressionUnits[unitIndex].ObjectOffset = decoder.getUvarint()
+				logSegmentRec.compressionUnits[unitIndex].ObjectLength = decoder.getUvarint()
+				logSegmentRec.compressionUnits[unitIndex].Encrypted = decoder.getBool()
+			}
+		default:
+			if nil == decoder.err {
+				decoder.err = fmt.Errorf("unknown tag (%v)", tag)
+			}
+		}
+	}
+
+	err = decoder.err
+
+	return
+}
+
+func (vS *volumeStruct) fetchLogSegmentRec(logSegmentNumber uint64) (logSegmentRec *logSegmentRecStruct, err error) {
+	var (
+		buf []byte
+	)
+
+	buf, err = vS.headhunterVolumeHandle.GetLogSegmentRec(logSegmentNumber)
+	if nil != err {
+		return
+	}
+
+	logSegmentRec, err = decodeLogSegmentRec(buf)
+	if nil != err {
+		err = fmt.Errorf("Volume '%s' LogSegment# 0x%016X has malformed LogSegmentRec: %v", vS.volumeName, logSegmentNumber, err)
+	}
+
+	return
+}
+
+func (vS *volumeStruct) putLogSegmentRec(logSegmentNumber uint64, logSegmentRec *logSegmentRecStruct) (err error) {
+	err = vS.headhunterVolumeHandle.PutLogSegmentRec(logSegmentNumber, encodeLogSegmentRec(logSegmentRec))
+	return
+}
@@ -0,0 +1,392 @@
+package inode
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const upgradeOnDiskInodesBatchSize = 1024
+
+// A V2 inode record holds the same information as a V1 one (i.e. an onDiskInodeV1Struct) following
+// the same CorruptionDetected & Version preamble. Rather than json.Marshal()'ing the struct, however,
+// each field is appended (in struct order) in a compact binary form:
+//
+//   integers      - uvarint (or varint if signed)
+//   bool          - a single byte (0 or 1)
+//   string/[]byte - uvarint length followed by the bytes themselves
+//   time.Time     - varint seconds & uvarint nanoseconds since the Unix epoch
+//   map           - uvarint count followed by each key & value (sorted by key so records are repeatable)
+//
+// V1 records remain readable. As each is subsequently rewritten (see flushInodes()), it is written
+// using the volume's onDiskInodeVersion (V2 by default)... or explicitly via UpgradeOnDiskInodes().
+//
+
+type onDiskInodeV2EncoderStruct struct {
+	buf     []byte
+	scratch [binary.MaxVarintLen64]byte
+}
+
+type onDiskInodeV2DecoderStruct struct {
+	buf []byte
+	err error
+}
+
+func (encoder *onDiskInodeV2EncoderStruct) putUvarint(u64 uint64) {
+	encoder.buf = append(encoder.buf, encoder.scratch[:binary.PutUvarint(encoder.scratch[:], u64)]...)
+}
+
+func (encoder *onDiskInodeV2EncoderStruct) putVarint(i64 int64) {
+	encoder.buf = append(encoder.buf, encoder.scratch[:binary.PutVarint(encoder.scratch[:], i64)]...)
+}
+
+func (encoder *onDiskInodeV2EncoderStruct) putBool(b bool) {
+	if b {
+		encoder.buf = append(encoder.buf, 1)
+	} else {
+		encoder.buf = append(encoder.buf, 0)
+	}
+}
+
+func (encoder *onDiskInodeV2EncoderStruct) putBytes(b []byte) {
+	encoder.putUvarint(uint64(len(b)))
+	encoder.buf = append(encoder.buf, b...)
+}
+
+func (encoder *onDiskInodeV2EncoderStruct) putString(s string) {
+	encoder.putUvarint(uint64(len(s)))
+	encoder.buf = append(encoder.buf, s...)
+}
+
+func (encoder *onDiskInodeV2EncoderStruct) putTime(t time.Time) {
+	encoder.putVarint(t.Unix())
+	encoder.putUvarint(uint64(t.Nanosecond()))
+}
+
+func (decoder *onDiskInodeV2DecoderStruct) getUvarint() (u64 uint64) {
+	var (
+		bytesConsumed int
+	)
+
+	if nil != decoder.err {
+		return
+	}
+
+	u64, bytesConsumed = binary.Uvarint(decoder.buf)
+	if 0 >= bytesConsumed {
+		decoder.err = fmt.Errorf("V2 inode record truncated (or malformed) uvarint")
+		u64 = 0
+		return
+	}
+
+	decoder.buf = decoder.buf[bytesConsumed:]
+
+	return
+}
+
+func (decoder *onDiskInodeV2DecoderStruct) getVarint() (i64 int64) {
+	var (
+		bytesConsumed int
+	)
+
+	if nil != decoder.err {
+		return
+	}
+
+	i64, bytesConsumed = binary.Varint(decoder.buf)
+	if 0 >= bytesConsumed {
+		decoder.err = fmt.Errorf("V2 inode record truncated (or malformed) varint")
+		i64 = 0
+		return
+	}
+
+	decoder.buf = decoder.buf[bytesConsumed:]
+
+	return
+}
+
+func (decoder *onDiskInodeV2DecoderStruct) getBool() (b bool) {
+	if nil != decoder.err {
+		return
+	}
+
+	if 0 == len(decoder.buf) {
+		decoder.err = fmt.Errorf("V2 inode record truncated bool")
+		return
+	}
+
+	switch decoder.buf[0] {
+	case 0:
+		b = false
+	case 1:
+		b = true
+	default:
+		decoder.err = fmt.Errorf("V2 inode record malformed bool (0x%02X)", decoder.buf[0])
+		return
+	}
+
+	decoder.buf = decoder.buf[1:]
+
+	return
+}
+
+// getBytes returns a copy (so as to not pin the inode record's buffer)... or nil if empty.
+//
+func (decoder *onDiskInodeV2DecoderStruct) getBytes() (b []byte) {
+	var (
+		bLen uint64
+	)
+
+	bLen = decoder.getUvarint()
+	if nil != decoder.err {
+		return
+	}
+
+	if bLen > uint64(len(decoder.buf)) {
+		decoder.err = fmt.Errorf("V2 inode record truncated []byte (len %v > %v remaining)", bLen, len(decoder.buf))
+		return
+	}
+
+	if 0 < bLen {
+		b = make([]byte, bLen)
+		copy(b, decoder.buf[:bLen])
+	}
+
+	decoder.buf = decoder.buf[bLen:]
+
+	return
+}
+
+func (decoder *onDiskInodeV2DecoderStruct) getString() (s string) {
+	s = string(decoder.getBytes())
+	return
+}
+
+func (decoder *onDiskInodeV2DecoderStruct) getTime() (t time.Time) {
+	var (
+		nsec uint64
+		sec  int64
+	)
+
+	sec = decoder.getVarint()
+	nsec = decoder.getUvarint()
+	if nil != decoder.err {
+		return
+	}
+
+	t = time.Unix(sec, int64(nsec))
+
+	return
+}
+
+// getCount fetches a map or slice count, sanity checking it against the bytes remaining (given
+// each element must consume at least minElementSize bytes) to avoid huge allocations.
+//
+func (decoder *onDiskInodeV2DecoderStruct) getCount(minElementSize uint64) (count uint64) {
+	count = decoder.getUvarint()
+	if nil != decoder.err {
+		return
+	}
+
+	if count > (uint64(len(decoder.buf)) / minElementSize) {
+		decoder.err = fmt.Errorf("V2 inode record count (%v) exceeds bytes remaining (%v)", count, len(decoder.buf))
+		count = 0
+	}
+
+	return
+}
+
+func sortedUint64MapKeys(keys []uint64) []uint64 {
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func encodeOnDiskInodeV2(onDiskInode *onDiskInodeV1Struct) (buf []byte) {
+	var (
+		encoder           onDiskInodeV2EncoderStruct
+		logSegmentNumber  uint64
+		logSegmentNumbers []uint64
+		streamName        string
+		streamNames       []string
+	)
+
+	encoder.buf = make([]byte, 0, optimisticInodeFetchBytes)
+
+	encoder.putUvarint(uint64(onDiskInode.InodeNumber))
+	encoder.putUvarint(uint64(onDiskInode.InodeType))
+	encoder.putUvarint(onDiskInode.LinkCount)
+	encoder.putUvarint(onDiskInode.Size)
+	encoder.putTime(onDiskInode.CreationTime)
+	encoder.putTime(onDiskInode.ModificationTime)
+	encoder.putTime(onDiskInode.AccessTime)
+	encoder.putTime(onDiskInode.AttrChangeTime)
+	encoder.putUvarint(onDiskInode.NumWrites)
+	encoder.putUvarint(uint64(onDiskInode.Mode))
+	encoder.putUvarint(uint64(onDiskInode.UserID))
+	encoder.putUvarint(uint64(onDiskInode.GroupID))
+
+	streamNames = make([]string, 0, len(onDiskInode.StreamMap))
+	for streamName = range onDiskInode.StreamMap {
+		streamNames = append(streamNames, streamName)
+	}
+	sort.Strings(streamNames)
+	encoder.putUvarint(uint64(len(streamNames)))
+	for _, streamName = range streamNames {
+		encoder.putString(streamName)
+		encoder.putBytes(onDiskInode.StreamMap[streamName])
+	}
+
+	encoder.putUvarint(onDiskInode.PayloadObjectNumber)
+	encoder.putUvarint(onDiskInode.PayloadObjectLength)
+	encoder.putString(onDiskInode.SymlinkTarget)
+
+	logSegmentNumbers = make([]uint64, 0, len(onDiskInode.LogSegmentMap))
+	for logSegmentNumber = range onDiskInode.LogSegmentMap {
+		logSegmentNumbers = append(logSegmentNumbers, logSegmentNumber)
+	}
+	encoder.putUvarint(uint64(len(logSegmentNumbers)))
+	for _, logSegmentNumber = range sortedUint64MapKeys(logSegmentNumbers) {
+		encoder.putUvarint(logSegmentNumber)
+		encoder.putUvarint(onDiskInode.LogSegmentMap[logSegmentNumber])
+	}
+
+	encoder.putBytes(onDiskInode.InlineData)
+
+	buf = encoder.buf
+
+	return
+}
+
+// decodeOnDiskInodeV2 fills in onDiskInode (whose maps must have been allocated) from buf.
+//
+func decodeOnDiskInodeV2(buf []byte, onDiskInode *onDiskInodeV1Struct) (err error) {
+	var (
+		count            uint64
+		decoder          onDiskInodeV2DecoderStruct
+		index            uint64
+		logSegmentNumber uint64
+		streamName       string
+	)
+
+	decoder.buf = buf
+
+	onDiskInode.InodeNumber = InodeNumber(decoder.getUvarint())
+	onDiskInode.InodeType = InodeType(decoder.getUvarint())
+	onDiskInode.LinkCount = decoder.getUvarint()
+	onDiskInode.Size = decoder.getUvarint()
+	onDiskInode.CreationTime = decoder.getTime()
+	onDiskInode.ModificationTime = decoder.getTime()
+	onDiskInode.AccessTime = decoder.getTime()
+	onDiskInode.AttrChangeTime = decoder.getTime()
+	onDiskInode.NumWrites = decoder.getUvarint()
+	onDiskInode.Mode = InodeMode(decoder.getUvarint())
+	onDiskInode.UserID = InodeUserID(decoder.getUvarint())
+	onDiskInode.GroupID = InodeGroupID(decoder.getUvarint())
+
+	count = decoder.getCount(2)
+	for index = 0; index < count; index++ {
+		streamName = decoder.getString()
+		onDiskInode.StreamMap[streamName] = decoder.getBytes()
+	}
+
+	onDiskInode.PayloadObjectNumber = decoder.getUvarint()
+	onDiskInode.PayloadObjectLength = decoder.getUvarint()
+	onDiskInode.SymlinkTarget = decoder.getString()
+
+	count = decoder.getCount(2)
+	onDiskInode.LogSegmentMap = make(map[uint64]uint64, count)
+	for index = 0; index < count; index++ {
+		logSegmentNumber = decoder.getUvarint()
+		onDiskInode.LogSegmentMap[logSegmentNumber] = decoder.getUvarint()
+	}
+
+	onDiskInode.InlineData = decoder.getBytes()
+
+	if (nil == decoder.err) && (0 != len(decoder.buf)) {
+		decoder.err = fmt.Errorf("V2 inode record has %v unexpected trailing bytes", len(decoder.buf))
+	}
+
+	err = decoder.err
+
+	return
+}
+
+// UpgradeOnDiskInodes rewrites every inode record not already in the volume's onDiskInodeVersion.
+// Inode records marked CorruptionDetected are left untouched. As the rewrite is accomplished by simply
+// fetching & (re)flushing each such inode, it is intended to be run while the volume is otherwise idle.
+//
+func (vS *volumeStruct) UpgradeOnDiskInodes() (upgradedInodes uint64, err error) {
+	var (
+		index       uint64
+		inode       *inMemoryInodeStruct
+		inodeNumber uint64
+		inodeRec    []byte
+		inodes      []*inMemoryInodeStruct
+		ok          bool
+		preambleBuf []byte
+	)
+
+	if V1 == vS.onDiskInodeVersion {
+		preambleBuf = globals.inodeRecDefaultPreambleBuf
+	} else {
+		preambleBuf = globals.inodeRecV2PreambleBuf
+	}
+
+	inodes = make([]*inMemoryInodeStruct, 0, upgradeOnDiskInodesBatchSize)
+
+	for index = 0; ; index++ {
+		inodeNumber, ok, err = vS.headhunterVolumeHandle.IndexedInodeNumber(index)
+		if nil != err {
+			return
+		}
+		if !ok {
+			break
+		}
+
+		inodeRec, ok, err = vS.headhunterVolumeHandle.GetInodeRec(inodeNumber)
+		if nil != err {
+			return
+		}
+		if !ok {
+			continue
+		}
+
+		if !bytes.HasPrefix(inodeRec, globals.corruptionDetectedFalseBuf) || bytes.HasPrefix(inodeRec, preambleBuf) {
+			continue
+		}
+
+		inode, ok, err = vS.fetchInode(InodeNumber(inodeNumber))
+		if nil != err {
+			return
+		}
+		if !ok {
+			continue
+		}
+
+		inode.dirty = true
+
+		inodes = append(inodes, inode)
+
+		if upgradeOnDiskInodesBatchSize == len(inodes) {
+			err = vS.flushInodes(inodes)
+			if nil != err {
+				return
+			}
+			upgradedInodes += uint64(len(inodes))
+			inodes = inodes[:0]
+		}
+	}
+
+	if 0 < len(inodes) {
+		err = vS.flushInodes(inodes)
+		if nil != err {
+			return
+		}
+		upgradedInodes += uint64(len(inodes))
+	}
+
+	err = nil
+	return
+}
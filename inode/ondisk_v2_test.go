@@ -0,0 +1,171 @@
+package inode
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOnDiskInodeV2Encoding(t *testing.T) {
+	onDiskInode := &onDiskInodeV1Struct{
+		InodeNumber:         InodeNumber(0x123456789A),
+		InodeType:           FileType,
+		LinkCount:           2,
+		Size:                0x10000000001,
+		CreationTime:        time.Unix(1500000000, 1),
+		ModificationTime:    time.Unix(1500000001, 999999999),
+		AccessTime:          time.Unix(-1, 500),
+		AttrChangeTime:      time.Unix(0, 0),
+		NumWrites:           7,
+		Mode:                PosixModePerm,
+		UserID:              InodeUserID(1000),
+		GroupID:             InodeGroupID(1001),
+		StreamMap:           map[string][]byte{"b": []byte("bValue"), "a": []byte("aValue")},
+		PayloadObjectNumber: 0xFEDCBA9876543210,
+		PayloadObjectLength: 4096,
+		SymlinkTarget:       "",
+		LogSegmentMap:       map[uint64]uint64{3: 100, 1: 200},
+		InlineData:          []byte("inline"),
+	}
+
+	buf := encodeOnDiskInodeV2(onDiskInode)
+
+	if !bytes.Equal(buf, encodeOnDiskInodeV2(onDiskInode)) {
+		t.Fatalf("encodeOnDiskInodeV2() should be repeatable")
+	}
+
+	decodedOnDiskInode := &onDiskInodeV1Struct{
+		StreamMap: make(map[string][]byte),
+	}
+
+	err := decodeOnDiskInodeV2(buf, decodedOnDiskInode)
+	if nil != err {
+		t.Fatalf("decodeOnDiskInodeV2() failed: %v", err)
+	}
+	if !reflect.DeepEqual(onDiskInode, decodedOnDiskInode) {
+		t.Fatalf("decodeOnDiskInodeV2() returned %+v (expected %+v)", decodedOnDiskInode, onDiskInode)
+	}
+
+	for bufLen := 0; bufLen < len(buf); bufLen++ {
+		decodedOnDiskInode = &onDiskInodeV1Struct{
+			StreamMap: make(map[string][]byte),
+		}
+		err = decodeOnDiskInodeV2(buf[:bufLen], decodedOnDiskInode)
+		if nil == err {
+			t.Fatalf("decodeOnDiskInodeV2() of truncated (%v of %v bytes) buf should have failed", bufLen, len(buf))
+		}
+	}
+
+	err = decodeOnDiskInodeV2(append(buf, 0), decodedOnDiskInode)
+	if nil == err {
+		t.Fatalf("decodeOnDiskInodeV2() of buf with trailing bytes should have failed")
+	}
+
+	// A count (here, 1<<62) whose product with the minimum element size wraps must still be rejected
+
+	decoder := &onDiskInodeV2DecoderStruct{buf: []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x40, 0x00, 0x00}}
+	if 0 != decoder.getCount(4) || nil == decoder.err {
+		t.Fatalf("getCount() of a count overflowing count*minElementSize should have failed")
+	}
+}
+
+func TestOnDiskInodeV2Upgrade(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	testVolume := testVolumeHandle.(*volumeStruct)
+
+	// Write a FileInode in the V1 format
+
+	testVolume.onDiskInodeVersion = V1
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.PutStream(fileInodeNumber, "stream", []byte("streamValue"))
+	if nil != err {
+		t.Fatalf("PutStream() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 0, []byte("file data"), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, true)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	inodeRec, ok, err := testVolume.headhunterVolumeHandle.GetInodeRec(uint64(fileInodeNumber))
+	if (nil != err) || !ok {
+		t.Fatalf("GetInodeRec() failed: %v", err)
+	}
+	if !bytes.HasPrefix(inodeRec, globals.inodeRecDefaultPreambleBuf) {
+		t.Fatalf("FileInode should have been written in V1 format")
+	}
+
+	// Upgrade (every inode in) the volume to V2... and verify the FileInode survived intact
+
+	testVolume.onDiskInodeVersion = V2
+
+	upgradedInodes, err := testVolumeHandle.UpgradeOnDiskInodes()
+	if nil != err {
+		t.Fatalf("UpgradeOnDiskInodes() failed: %v", err)
+	}
+	if 0 == upgradedInodes {
+		t.Fatalf("UpgradeOnDiskInodes() should have upgraded at least one inode")
+	}
+
+	inodeRec, ok, err = testVolume.headhunterVolumeHandle.GetInodeRec(uint64(fileInodeNumber))
+	if (nil != err) || !ok {
+		t.Fatalf("GetInodeRec() failed: %v", err)
+	}
+	if !bytes.HasPrefix(inodeRec, globals.inodeRecV2PreambleBuf) {
+		t.Fatalf("FileInode should have been upgraded to V2 format")
+	}
+
+	upgradedInodes, err = testVolumeHandle.UpgradeOnDiskInodes()
+	if nil != err {
+		t.Fatalf("UpgradeOnDiskInodes() failed: %v", err)
+	}
+	if 0 != upgradedInodes {
+		t.Fatalf("Second UpgradeOnDiskInodes() should have found nothing to upgrade (not %v inodes)", upgradedInodes)
+	}
+
+	err = testVolumeHandle.Purge(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Purge() failed: %v", err)
+	}
+
+	streamValue, err := testVolumeHandle.GetStream(fileInodeNumber, "stream")
+	if nil != err {
+		t.Fatalf("GetStream() failed: %v", err)
+	}
+	if "streamValue" != string(streamValue) {
+		t.Fatalf("GetStream() of upgraded FileInode returned unexpected value")
+	}
+
+	readBuf, err := testVolumeHandle.Read(fileInodeNumber, 0, 9, nil)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if "file data" != string(readBuf) {
+		t.Fatalf("Read() of upgraded FileInode returned unexpected data")
+	}
+
+	err = testVolumeHandle.Validate(fileInodeNumber, true)
+	if nil != err {
+		t.Fatalf("Validate() failed: %v", err)
+	}
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	testTeardown(t)
+}
@@ -48,6 +48,14 @@ func (tnl *treeNodeLoadable) GetNode(objectNumber uint64, objectOffset uint64, o
 		return
 	}
 
+	if nil == err {
+		if DirType == tnl.inode.InodeType {
+			globals.dirEntryCacheBudget.NoteNodeBytes(objectLength)
+		} else {
+			globals.fileExtentMapCacheBudget.NoteNodeBytes(objectLength)
+		}
+	}
+
 	return
 }
 
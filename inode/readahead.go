@@ -0,0 +1,223 @@
+package inode
+
+import (
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+// Sequential read-ahead is tracked per open file handle (see ReadAheadState) such that multiple readers
+// of the same FileInode do not disrupt each other's detection. A ReadWithReadAhead() beginning precisely
+// where the handle's previous one ended opens (or doubles, up to the VolumeGroup's ReadAheadMaxLines) a
+// window of Read Cache Lines beyond the current Read() that are then prefetched asynchronously into the
+// VolumeGroup's Read Cache. Any non-sequential Read() via that handle collapses the window. The
+// VolumeGroup's ReadAheadMaxInFlightLines caps the number of prefetches outstanding at any one time...
+// beyond which read-ahead is skipped.
+//
+// Only uncompressed LogSegments that are no longer in flight are prefetched.
+//
+
+func (vS *volumeStruct) readAhead(snapShotID uint64, fileInode *inMemoryInodeStruct, readAheadState *ReadAheadState, offset uint64, length uint64) {
+	var (
+		err               error
+		fileSize          uint64
+		prefetchEnd       uint64
+		prefetchLength    uint64
+		prefetchStart     uint64
+		readCacheLineSize uint64
+		readEnd           uint64
+		readPlan          []ReadPlanStep
+		volumeGroup       *volumeGroupStruct
+	)
+
+	volumeGroup = vS.volumeGroup
+
+	if (0 == volumeGroup.readAheadMaxLines) || (0 == length) {
+		return
+	}
+
+	readCacheLineSize = volumeGroup.readCacheLineSize
+	readEnd = offset + length
+
+	fileInode.Lock()
+	fileSize = fileInode.Size
+	fileInode.Unlock()
+
+	readAheadState.Lock()
+
+	if (0 != offset) && (offset == readAheadState.nextOffset) {
+		if 0 == readAheadState.window {
+			readAheadState.window = 1
+		} else {
+			readAheadState.window *= 2
+			if readAheadState.window > volumeGroup.readAheadMaxLines {
+				readAheadState.window = volumeGroup.readAheadMaxLines
+			}
+		}
+	} else {
+		readAheadState.window = 0
+		readAheadState.prefetchedEnd = 0
+	}
+
+	readAheadState.nextOffset = readEnd
+
+	if 0 == readAheadState.window {
+		readAheadState.Unlock()
+		return
+	}
+
+	prefetchStart = readEnd
+	if prefetchStart < readAheadState.prefetchedEnd {
+		prefetchStart = readAheadState.prefetchedEnd
+	}
+
+	prefetchEnd = readEnd + (readAheadState.window * readCacheLineSize)
+	if prefetchEnd > fileSize {
+		prefetchEnd = fileSize
+	}
+
+	if prefetchStart >= prefetchEnd {
+		readAheadState.Unlock()
+		return
+	}
+
+	readAheadState.prefetchedEnd = prefetchEnd
+
+	readAheadState.Unlock()
+
+	prefetchLength = prefetchEnd - prefetchStart
+
+	readPlan, _, err = vS.getReadPlanHelper(snapShotID, fileInode, &prefetchStart, &prefetchLength)
+	if nil != err {
+		logger.WarnfWithError(err, "read-ahead of inode %v unable to compute readPlan", fileInode.InodeNumber)
+		return
+	}
+
+	for _, step := range readPlan {
+		if !vS.readAheadStep(fileInode, step) {
+			return
+		}
+	}
+}
+
+// readAheadStep launches prefetches of the Read Cache Lines covering step. If the VolumeGroup's
+// ReadAheadMaxInFlightLines has been reached, false is returned so that the caller may stop.
+//
+func (vS *volumeStruct) readAheadStep(fileInode *inMemoryInodeStruct, step ReadPlanStep) (keepGoing bool) {
+	var (
+		cacheLineTag               uint64
+		inFlight                   bool
+		logSegmentCompressionUnits []logSegmentCompressionUnitStruct
+		readCacheHit               bool
+		readCacheKey               readCacheKeyStruct
+		readCacheLineSize          uint64
+		volumeGroup                *volumeGroupStruct
+	)
+
+	keepGoing = true
+
+	if (nil != step.InlineData) || (0 == step.LogSegmentNumber) || (0 == step.Length) {
+		return
+	}
+
+	fileInode.Lock()
+	_, inFlight = fileInode.inFlightLogSegmentMap[step.LogSegmentNumber]
+	fileInode.Unlock()
+
+	if inFlight {
+		return
+	}
+
+	logSegmentCompressionUnits = vS.fetchClosedLogSegmentCompressionUnits(step.LogSegmentNumber)
+	if nil != logSegmentCompressionUnits {
+		return
+	}
+
+	volumeGroup = vS.volumeGroup
+	readCacheLineSize = volumeGroup.readCacheLineSize
+
+	readCacheKey.volumeName = vS.volumeName
+	readCacheKey.logSegmentNumber = step.LogSegmentNumber
+
+	for cacheLineTag = step.Offset / readCacheLineSize; cacheLineTag <= (step.Offset+step.Length-1)/readCacheLineSize; cacheLineTag++ {
+		readCacheKey.cacheLineTag = cacheLineTag
+
+		volumeGroup.Lock()
+
+		_, readCacheHit = volumeGroup.readCache[readCacheKey]
+		if !readCacheHit {
+			_, readCacheHit = volumeGroup.readAheadInFlight[readCacheKey]
+		}
+		if readCacheHit {
+			volumeGroup.Unlock()
+			continue
+		}
+
+		if uint64(len(volumeGroup.readAheadInFlight)) >= volumeGroup.readAheadMaxInFlight {
+			volumeGroup.Unlock()
+			keepGoing = false
+			return
+		}
+
+		volumeGroup.readAheadInFlight[readCacheKey] = struct{}{}
+
+		volumeGroup.Unlock()
+
+		vS.readAheadWG.Add(1)
+		go vS.prefetchReadCacheLine(fileInode.InodeNumber, readCacheKey, step)
+	}
+
+	return
+}
+
+func (vS *volumeStruct) prefetchReadCacheLine(inodeNumber InodeNumber, readCacheKey readCacheKeyStruct, step ReadPlanStep) {
+	var (
+		cacheLine            []byte
+		cacheLineStartOffset uint64
+		err                  error
+		readCacheElement     *readCacheElementStruct
+		readCacheHit         bool
+		volumeGroup          *volumeGroupStruct
+	)
+
+	defer vS.readAheadWG.Done()
+
+	volumeGroup = vS.volumeGroup
+
+	cacheLineStartOffset = readCacheKey.cacheLineTag * volumeGroup.readCacheLineSize
+
+	cacheLine, err = vS.getLogSegmentRange(inodeNumber, step, vS.fetchLogSegmentChecksums(step.LogSegmentNumber), cacheLineStartOffset, volumeGroup.readCacheLineSize)
+
+	volumeGroup.Lock()
+
+	delete(volumeGroup.readAheadInFlight, readCacheKey)
+
+	if nil != err {
+		// Simply drop the prefetch... a subsequent Read() will report any error
+		volumeGroup.Unlock()
+		logger.WarnfWithError(err, "read-ahead of inode %v LogSegment %016X failed", inodeNumber, step.LogSegmentNumber)
+		return
+	}
+
+	stats.IncrementOperations(&stats.FileReadaheadOps)
+	stats.IncrementOperationsBy(&stats.FileReadaheadBytes, uint64(len(cacheLine)))
+
+	_, readCacheHit = volumeGroup.readCache[readCacheKey]
+	if readCacheHit {
+		// A demand Read() beat us to it
+		volumeGroup.Unlock()
+		stats.IncrementOperationsBy(&stats.FileReadaheadWastedBytes, uint64(len(cacheLine)))
+		return
+	}
+
+	readCacheElement = &readCacheElementStruct{
+		readCacheKey: readCacheKey,
+		next:         nil,
+		prev:         nil,
+		cacheLine:    cacheLine,
+		prefetched:   true,
+	}
+
+	volumeGroup.insertReadCacheElementWhileLocked(readCacheElement)
+
+	volumeGroup.Unlock()
+}
@@ -0,0 +1,159 @@
+package inode
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestReadAhead(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+	testVolume := testVolumeHandle.(*volumeStruct)
+	volumeGroup := testVolume.volumeGroup
+	readCacheLineSize := volumeGroup.readCacheLineSize
+
+	volumeGroup.readAheadMaxLines = 4
+	defer func() {
+		volumeGroup.readAheadMaxLines = 0
+	}()
+
+	writeBuf := make([]byte, 3*readCacheLineSize)
+	rand.New(rand.NewSource(0)).Read(writeBuf)
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 0, writeBuf, nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	fileInode, ok, err := testVolume.fetchInode(fileInodeNumber)
+	if (nil != err) || !ok {
+		t.Fatalf("fetchInode() failed: %v", err)
+	}
+	if 1 != len(fileInode.LogSegmentMap) {
+		t.Fatalf("FileInode should reference exactly one LogSegment")
+	}
+	var logSegmentNumber uint64
+	for logSegmentNumber = range fileInode.LogSegmentMap {
+	}
+
+	volumeGroup.Lock()
+	volumeGroup.purgeReadCacheWhileLocked()
+	volumeGroup.Unlock()
+
+	fetchReadCacheElement := func(cacheLineTag uint64) (readCacheElement *readCacheElementStruct) {
+		readCacheKey := readCacheKeyStruct{
+			volumeName:       testVolume.volumeName,
+			logSegmentNumber: logSegmentNumber,
+			cacheLineTag:     cacheLineTag,
+		}
+		volumeGroup.Lock()
+		readCacheElement = volumeGroup.readCache[readCacheKey]
+		volumeGroup.Unlock()
+		return
+	}
+
+	readLength := readCacheLineSize / 10
+
+	readAheadState := &ReadAheadState{}
+	otherReadAheadState := &ReadAheadState{}
+
+	// Read()s not via a handle should not trigger read-ahead
+
+	for offset := uint64(0); offset < 2*readLength; offset += readLength {
+		_, err = testVolumeHandle.Read(fileInodeNumber, offset, readLength, nil)
+		if nil != err {
+			t.Fatalf("Read() failed: %v", err)
+		}
+	}
+
+	testVolume.readAheadWG.Wait()
+
+	if nil != fetchReadCacheElement(1) {
+		t.Fatalf("Cache line 1 should not have been prefetched")
+	}
+
+	// Two sequential Read()s via a handle should prefetch the following cache line
+
+	for offset := uint64(0); offset < 2*readLength; offset += readLength {
+		readBuf, err := testVolumeHandle.ReadWithReadAhead(fileInodeNumber, offset, readLength, readAheadState, nil)
+		if nil != err {
+			t.Fatalf("ReadWithReadAhead() failed: %v", err)
+		}
+		if !bytes.Equal(writeBuf[offset:offset+readLength], readBuf) {
+			t.Fatalf("ReadWithReadAhead() returned unexpected data")
+		}
+	}
+
+	testVolume.readAheadWG.Wait()
+
+	if 1 != readAheadState.window {
+		t.Fatalf("readAheadState.window should be 1 (not %v)", readAheadState.window)
+	}
+	readCacheElement := fetchReadCacheElement(1)
+	if (nil == readCacheElement) || !readCacheElement.prefetched {
+		t.Fatalf("Cache line 1 should have been prefetched")
+	}
+
+	// Continuing sequentially should hit the prefetched cache line (and widen the window)... even
+	// while non-sequential Read()s of the same FileInode are issued via another handle
+
+	for offset := 2 * readLength; offset < readCacheLineSize+readLength; offset += readLength {
+		_, err = testVolumeHandle.ReadWithReadAhead(fileInodeNumber, 0, readLength, otherReadAheadState, nil)
+		if nil != err {
+			t.Fatalf("ReadWithReadAhead() via other handle failed: %v", err)
+		}
+		readBuf, err := testVolumeHandle.ReadWithReadAhead(fileInodeNumber, offset, readLength, readAheadState, nil)
+		if nil != err {
+			t.Fatalf("ReadWithReadAhead() failed: %v", err)
+		}
+		if !bytes.Equal(writeBuf[offset:offset+readLength], readBuf) {
+			t.Fatalf("ReadWithReadAhead() returned unexpected data")
+		}
+	}
+
+	testVolume.readAheadWG.Wait()
+
+	if readCacheElement.prefetched {
+		t.Fatalf("Prefetched cache line 1 should have been hit")
+	}
+	if volumeGroup.readAheadMaxLines != readAheadState.window {
+		t.Fatalf("readAheadState.window should have grown to %v (not %v)", volumeGroup.readAheadMaxLines, readAheadState.window)
+	}
+	if 0 != otherReadAheadState.window {
+		t.Fatalf("otherReadAheadState.window should be 0 (not %v)", otherReadAheadState.window)
+	}
+	readCacheElement = fetchReadCacheElement(2)
+	if (nil == readCacheElement) || !readCacheElement.prefetched {
+		t.Fatalf("Cache line 2 should have been prefetched")
+	}
+
+	// A non-sequential Read() should collapse the window
+
+	_, err = testVolumeHandle.ReadWithReadAhead(fileInodeNumber, 0, readLength, readAheadState, nil)
+	if nil != err {
+		t.Fatalf("ReadWithReadAhead() failed: %v", err)
+	}
+	if 0 != readAheadState.window {
+		t.Fatalf("readAheadState.window should have collapsed (not %v)", readAheadState.window)
+	}
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	testTeardown(t)
+}
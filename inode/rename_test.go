@@ -0,0 +1,198 @@
+package inode
+
+import (
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+)
+
+func TestMoveFlags(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	// Lay out /dirA/subDir, /dirA/fileA & /file1
+
+	dirAInodeNumber, err := testVolumeHandle.CreateDir(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateDir() failed: %v", err)
+	}
+	err = testVolumeHandle.Link(RootDirInodeNumber, "dirA", dirAInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Link(RootDirInodeNumber, \"dirA\") failed: %v", err)
+	}
+	subDirInodeNumber, err := testVolumeHandle.CreateDir(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateDir() failed: %v", err)
+	}
+	err = testVolumeHandle.Link(dirAInodeNumber, "subDir", subDirInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Link(dirA, \"subDir\") failed: %v", err)
+	}
+	fileAInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Link(dirAInodeNumber, "fileA", fileAInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Link(dirA, \"fileA\") failed: %v", err)
+	}
+	file1InodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Link(RootDirInodeNumber, "file1", file1InodeNumber, false)
+	if nil != err {
+		t.Fatalf("Link(RootDirInodeNumber, \"file1\") failed: %v", err)
+	}
+
+	verifyLookup := func(dirInodeNumber InodeNumber, basename string, expectedInodeNumber InodeNumber) {
+		inodeNumber, err := testVolumeHandle.Lookup(dirInodeNumber, basename)
+		if nil != err {
+			t.Fatalf("Lookup(0x%016X, \"%v\") failed: %v", dirInodeNumber, basename, err)
+		}
+		if expectedInodeNumber != inodeNumber {
+			t.Fatalf("Lookup(0x%016X, \"%v\") returned 0x%016X (expected 0x%016X)", dirInodeNumber, basename, inodeNumber, expectedInodeNumber)
+		}
+	}
+
+	verifyLinkCount := func(inodeNumber InodeNumber, expectedLinkCount uint64) {
+		linkCount, err := testVolumeHandle.GetLinkCount(inodeNumber)
+		if nil != err {
+			t.Fatalf("GetLinkCount(0x%016X) failed: %v", inodeNumber, err)
+		}
+		if expectedLinkCount != linkCount {
+			t.Fatalf("GetLinkCount(0x%016X) returned %v (expected %v)", inodeNumber, linkCount, expectedLinkCount)
+		}
+	}
+
+	rootDirLinkCount, err := testVolumeHandle.GetLinkCount(RootDirInodeNumber)
+	if nil != err {
+		t.Fatalf("GetLinkCount(RootDirInodeNumber) failed: %v", err)
+	}
+	verifyLinkCount(dirAInodeNumber, 3)
+
+	// Invalid flag combinations
+
+	err = testVolumeHandle.Move(RootDirInodeNumber, "file1", dirAInodeNumber, "fileA", RenameExchange|RenameNoReplace)
+	if !blunder.Is(err, blunder.InvalidArgError) {
+		t.Fatalf("Move() with RenameExchange|RenameNoReplace should have failed with EINVAL (got %v)", err)
+	}
+	err = testVolumeHandle.Move(RootDirInodeNumber, "file1", dirAInodeNumber, "fileB", RenameWhiteout)
+	if !blunder.Is(err, blunder.NotSupportedError) {
+		t.Fatalf("Move() with RenameWhiteout should have failed with ENOTSUP (got %v)", err)
+	}
+
+	// RenameNoReplace
+
+	err = testVolumeHandle.Move(RootDirInodeNumber, "file1", dirAInodeNumber, "fileA", RenameNoReplace)
+	if !blunder.Is(err, blunder.FileExistsError) {
+		t.Fatalf("Move() with RenameNoReplace onto existing target should have failed with EEXIST (got %v)", err)
+	}
+	verifyLookup(RootDirInodeNumber, "file1", file1InodeNumber)
+	verifyLookup(dirAInodeNumber, "fileA", fileAInodeNumber)
+
+	err = testVolumeHandle.Move(RootDirInodeNumber, "file1", dirAInodeNumber, "fileB", RenameNoReplace)
+	if nil != err {
+		t.Fatalf("Move() with RenameNoReplace onto missing target failed: %v", err)
+	}
+	verifyLookup(dirAInodeNumber, "fileB", file1InodeNumber)
+
+	err = testVolumeHandle.Move(dirAInodeNumber, "fileB", RootDirInodeNumber, "file1", RenameNoReplace)
+	if nil != err {
+		t.Fatalf("Move() with RenameNoReplace back to original location failed: %v", err)
+	}
+
+	// RenameExchange
+
+	err = testVolumeHandle.Move(RootDirInodeNumber, "file1", dirAInodeNumber, "fileB", RenameExchange)
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("Move() with RenameExchange onto missing target should have failed with ENOENT (got %v)", err)
+	}
+
+	err = testVolumeHandle.Move(RootDirInodeNumber, "file1", dirAInodeNumber, "fileA", RenameExchange)
+	if nil != err {
+		t.Fatalf("Move() with RenameExchange of two files failed: %v", err)
+	}
+	verifyLookup(RootDirInodeNumber, "file1", fileAInodeNumber)
+	verifyLookup(dirAInodeNumber, "fileA", file1InodeNumber)
+
+	// Exchange /file1 (now fileA's inode) with /dirA/subDir... subDir moves up to the root
+
+	err = testVolumeHandle.Move(RootDirInodeNumber, "file1", dirAInodeNumber, "subDir", RenameExchange)
+	if nil != err {
+		t.Fatalf("Move() with RenameExchange of a file and a directory failed: %v", err)
+	}
+	verifyLookup(RootDirInodeNumber, "file1", subDirInodeNumber)
+	verifyLookup(dirAInodeNumber, "subDir", fileAInodeNumber)
+	verifyLookup(subDirInodeNumber, "..", RootDirInodeNumber)
+	verifyLinkCount(dirAInodeNumber, 2)
+	verifyLinkCount(RootDirInodeNumber, rootDirLinkCount+1)
+
+	// Exchange back... then exchange two directories in different parents (both ".." entries must be fixed up)
+
+	err = testVolumeHandle.Move(RootDirInodeNumber, "file1", dirAInodeNumber, "subDir", RenameExchange)
+	if nil != err {
+		t.Fatalf("Move() with RenameExchange of a directory and a file failed: %v", err)
+	}
+	verifyLookup(RootDirInodeNumber, "file1", fileAInodeNumber)
+	verifyLookup(dirAInodeNumber, "subDir", subDirInodeNumber)
+	verifyLookup(subDirInodeNumber, "..", dirAInodeNumber)
+	verifyLinkCount(dirAInodeNumber, 3)
+	verifyLinkCount(RootDirInodeNumber, rootDirLinkCount)
+
+	otherDirInodeNumber, err := testVolumeHandle.CreateDir(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateDir() failed: %v", err)
+	}
+	err = testVolumeHandle.Link(RootDirInodeNumber, "otherDir", otherDirInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Link(RootDirInodeNumber, \"otherDir\") failed: %v", err)
+	}
+	rootDirLinkCount++
+
+	err = testVolumeHandle.Move(RootDirInodeNumber, "otherDir", dirAInodeNumber, "subDir", RenameExchange)
+	if nil != err {
+		t.Fatalf("Move() with RenameExchange of two directories failed: %v", err)
+	}
+	verifyLookup(RootDirInodeNumber, "otherDir", subDirInodeNumber)
+	verifyLookup(dirAInodeNumber, "subDir", otherDirInodeNumber)
+	verifyLookup(subDirInodeNumber, "..", RootDirInodeNumber)
+	verifyLookup(otherDirInodeNumber, "..", dirAInodeNumber)
+	verifyLinkCount(dirAInodeNumber, 3)
+	verifyLinkCount(RootDirInodeNumber, rootDirLinkCount)
+
+	// Exchanging a directory with one of its own entries is not allowed
+
+	err = testVolumeHandle.Move(RootDirInodeNumber, "dirA", dirAInodeNumber, "subDir", RenameExchange)
+	if !blunder.Is(err, blunder.InvalidArgError) {
+		t.Fatalf("Move() with RenameExchange of a directory and its own entry should have failed with EINVAL (got %v)", err)
+	}
+
+	// ...nor with an entry deeper within its own subtree (in either direction)
+
+	deeperDirInodeNumber, err := testVolumeHandle.CreateDir(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateDir() failed: %v", err)
+	}
+	err = testVolumeHandle.Link(otherDirInodeNumber, "deeperDir", deeperDirInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Link(otherDirInodeNumber, \"deeperDir\") failed: %v", err)
+	}
+
+	err = testVolumeHandle.Move(RootDirInodeNumber, "dirA", otherDirInodeNumber, "deeperDir", RenameExchange)
+	if !blunder.Is(err, blunder.InvalidArgError) {
+		t.Fatalf("Move() with RenameExchange of a directory and its descendant should have failed with EINVAL (got %v)", err)
+	}
+	err = testVolumeHandle.Move(otherDirInodeNumber, "deeperDir", RootDirInodeNumber, "dirA", RenameExchange)
+	if !blunder.Is(err, blunder.InvalidArgError) {
+		t.Fatalf("Move() with RenameExchange of a descendant and its ancestor directory should have failed with EINVAL (got %v)", err)
+	}
+	verifyLookup(RootDirInodeNumber, "dirA", dirAInodeNumber)
+	verifyLookup(otherDirInodeNumber, "deeperDir", deeperDirInodeNumber)
+
+	testTeardown(t)
+}
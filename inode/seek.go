@@ -0,0 +1,140 @@
+package inode
+
+import (
+	"github.com/swiftstack/sortedmap"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/stats"
+)
+
+// Holes in a FileInode are simply the gaps between (and after) the extents in its extent map. Both
+// SeekData() and SeekHole() follow the semantics of lseek(2)'s SEEK_DATA and SEEK_HOLE: an offset at
+// or beyond the end of the file fails with ENXIO and the end of the file is considered a hole.
+//
+// A FileInode with InlineData is entirely data.
+//
+
+func (vS *volumeStruct) SeekData(fileInodeNumber InodeNumber, offset uint64) (dataOffset uint64, err error) {
+	dataOffset, err = vS.seek(fileInodeNumber, offset, true)
+	return
+}
+
+func (vS *volumeStruct) SeekHole(fileInodeNumber InodeNumber, offset uint64) (holeOffset uint64, err error) {
+	holeOffset, err = vS.seek(fileInodeNumber, offset, false)
+	return
+}
+
+func (vS *volumeStruct) seek(fileInodeNumber InodeNumber, offset uint64, seekData bool) (newOffset uint64, err error) {
+	var (
+		curExtent      *fileExtentStruct
+		curExtentIndex int
+		extents        sortedmap.BPlusTree
+		fileInode      *inMemoryInodeStruct
+	)
+
+	fileInode, err = vS.fetchInodeType(fileInodeNumber, FileType)
+	if nil != err {
+		logger.ErrorWithError(err)
+		return
+	}
+
+	stats.IncrementOperations(&stats.FileSeekOps)
+
+	if offset >= fileInode.Size {
+		err = blunder.NewError(blunder.NoDeviceOrAddrError, "offset 0x%016X at or beyond end of inode 0x%016X", offset, fileInodeNumber)
+		return
+	}
+
+	if fileInode.isInline() {
+		if seekData {
+			newOffset = offset
+		} else {
+			newOffset = fileInode.Size
+		}
+		err = nil
+		return
+	}
+
+	extents = fileInode.payload.(sortedmap.BPlusTree)
+
+	curExtentIndex, _, err = extents.BisectLeft(offset)
+	if nil != err {
+		panic(err)
+	}
+
+	newOffset = offset
+
+	if 0 <= curExtentIndex {
+		curExtent = fetchFileExtent(extents, curExtentIndex)
+		if offset < (curExtent.FileOffset + curExtent.Length) {
+			// offset is within curExtent
+
+			if seekData {
+				err = nil
+				return
+			}
+
+			newOffset = curExtent.FileOffset + curExtent.Length
+		} else {
+			// offset is in a hole following curExtent
+
+			if !seekData {
+				err = nil
+				return
+			}
+		}
+	} else {
+		// offset is in a hole preceding the first extent (if any)
+
+		if !seekData {
+			err = nil
+			return
+		}
+	}
+
+	for {
+		curExtentIndex++
+		curExtent = fetchFileExtent(extents, curExtentIndex)
+
+		if seekData {
+			if (nil == curExtent) || (curExtent.FileOffset >= fileInode.Size) {
+				err = blunder.NewError(blunder.NoDeviceOrAddrError, "no data at or beyond offset 0x%016X of inode 0x%016X", offset, fileInodeNumber)
+				return
+			}
+			newOffset = curExtent.FileOffset
+			err = nil
+			return
+		}
+
+		if (nil == curExtent) || (curExtent.FileOffset != newOffset) {
+			// The hole begins at newOffset
+			break
+		}
+
+		newOffset = curExtent.FileOffset + curExtent.Length
+	}
+
+	if newOffset > fileInode.Size {
+		newOffset = fileInode.Size
+	}
+
+	err = nil
+	return
+}
+
+// fetchFileExtent returns the extent at index of extents... or nil if there is no such extent.
+//
+func fetchFileExtent(extents sortedmap.BPlusTree, index int) (fileExtent *fileExtentStruct) {
+	_, value, ok, err := extents.GetByIndex(index)
+	if nil != err {
+		panic(err)
+	}
+	if !ok {
+		fileExtent = nil
+		return
+	}
+
+	fileExtent = value.(*fileExtentStruct)
+	return
+}
@@ -0,0 +1,92 @@
+package inode
+
+import (
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+)
+
+func TestSeekDataAndHole(t *testing.T) {
+	testSetup(t, false)
+
+	testVolumeHandle, err := FetchVolumeHandle("TestVolume")
+	if nil != err {
+		t.Fatalf("FetchVolumeHandle(\"TestVolume\") failed: %v", err)
+	}
+
+	// Lay out a FileInode as data [0:20), hole [20:100), data [100:110), hole [110:300)
+
+	fileInodeNumber, err := testVolumeHandle.CreateFile(PosixModePerm, 0, 0)
+	if nil != err {
+		t.Fatalf("CreateFile() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 0, make([]byte, 10), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 100, make([]byte, 10), nil)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.Flush(fileInodeNumber, false)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+	err = testVolumeHandle.Write(fileInodeNumber, 10, make([]byte, 10), nil) // adjacent extent in a new LogSegment
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	err = testVolumeHandle.SetSize(fileInodeNumber, 300)
+	if nil != err {
+		t.Fatalf("SetSize() failed: %v", err)
+	}
+
+	testSeek := func(seekData bool, offset uint64, expectedOffset uint64) {
+		var (
+			newOffset uint64
+		)
+		if seekData {
+			newOffset, err = testVolumeHandle.SeekData(fileInodeNumber, offset)
+		} else {
+			newOffset, err = testVolumeHandle.SeekHole(fileInodeNumber, offset)
+		}
+		if nil != err {
+			t.Fatalf("Seek{Data|Hole}(,%v) [seekData==%v] failed: %v", offset, seekData, err)
+		}
+		if expectedOffset != newOffset {
+			t.Fatalf("Seek{Data|Hole}(,%v) [seekData==%v] returned %v (expected %v)", offset, seekData, newOffset, expectedOffset)
+		}
+	}
+
+	testSeek(true, 0, 0)
+	testSeek(true, 15, 15)
+	testSeek(true, 20, 100)
+	testSeek(true, 50, 100)
+	testSeek(true, 105, 105)
+	testSeek(false, 0, 20)
+	testSeek(false, 15, 20)
+	testSeek(false, 50, 50)
+	testSeek(false, 100, 110)
+	testSeek(false, 110, 110)
+	testSeek(false, 299, 299)
+
+	_, err = testVolumeHandle.SeekData(fileInodeNumber, 110)
+	if !blunder.Is(err, blunder.NoDeviceOrAddrError) {
+		t.Fatalf("SeekData() beyond the last data should have failed with ENXIO (got %v)", err)
+	}
+	_, err = testVolumeHandle.SeekData(fileInodeNumber, 300)
+	if !blunder.Is(err, blunder.NoDeviceOrAddrError) {
+		t.Fatalf("SeekData() at end of file should have failed with ENXIO (got %v)", err)
+	}
+	_, err = testVolumeHandle.SeekHole(fileInodeNumber, 300)
+	if !blunder.Is(err, blunder.NoDeviceOrAddrError) {
+		t.Fatalf("SeekHole() at end of file should have failed with ENXIO (got %v)", err)
+	}
+
+	err = testVolumeHandle.Destroy(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+
+	testTeardown(t)
+}
@@ -147,6 +147,8 @@
 package jrpcfs
 
 import (
+	"time"
+
 	"github.com/swiftstack/ProxyFS/fs"
 	"github.com/swiftstack/ProxyFS/inode"
 )
@@ -202,6 +204,23 @@ type ChownPathRequest struct {
 	GroupID int32
 }
 
+// CopyFileRangeRequest is the request object for RpcCopyFileRange.
+//
+// InodeHandle identifies the source file while DstInodeNumber identifies the destination file (in the same volume).
+//
+type CopyFileRangeRequest struct {
+	InodeHandle
+	SrcOffset      uint64
+	DstInodeNumber int64
+	DstOffset      uint64
+	Length         uint64
+}
+
+// CopyFileRangeReply is the reply object for RpcCopyFileRange.
+type CopyFileRangeReply struct {
+	Size uint64 // the number of bytes copied... possibly fewer than CopyFileRangeRequest.Length
+}
+
 // CreateRequest is the request object for RpcCreate.
 type CreateRequest struct {
 	InodeHandle
@@ -230,6 +249,17 @@ type DirEntry struct {
 	NextDirLocation int64
 }
 
+// FallocateRequest is the request object for RpcFallocate.
+//
+// Mode is a bit mask of fallocate(2)'s FALLOC_FL_KEEP_SIZE, FALLOC_FL_PUNCH_HOLE, & FALLOC_FL_ZERO_RANGE.
+//
+type FallocateRequest struct {
+	InodeHandle
+	Mode   uint32
+	Offset uint64
+	Length uint64
+}
+
 // FlushRequest is the request object for RpcFlush.
 type FlushRequest struct {
 	InodeHandle
@@ -351,6 +381,7 @@ type MountByAccountNameRequest struct {
 	MountOptions uint64
 	AuthUserID   uint64
 	AuthGroupID  uint64
+	clientAddr   string // Not sent by the client... filled in from the connection (see clientAddrCodecStruct)
 }
 
 // MountByAccountNameReply is the reply object for RpcMountByAccountName.
@@ -365,6 +396,7 @@ type MountByVolumeNameRequest struct {
 	MountOptions uint64
 	AuthUserID   uint64
 	AuthGroupID  uint64
+	clientAddr   string // Not sent by the client... filled in from the connection (see clientAddrCodecStruct)
 }
 
 // MountByVolumeNameReply is the reply object for RpcMountByVolumeName.
@@ -373,6 +405,67 @@ type MountByVolumeNameReply struct {
 	RootDirInodeNumber int64
 }
 
+// NotifyEvent is used as part of NotifyFetchReply.
+//
+// EventType here will be one of fs.NotifyCreate|NotifyUnlink|NotifyRename|NotifySetattr|NotifyWriteClose|NotifyXAttr.
+//
+type NotifyEvent struct {
+	Sequence          uint64
+	EventType         uint32
+	DirInodeNumber    int64
+	Basename          string
+	InodeNumber       int64
+	NewDirInodeNumber int64  // Only set for fs.NotifyRename
+	NewBasename       string // Only set for fs.NotifyRename
+}
+
+// NotifyFetchTimeoutMax caps the time RpcNotifyFetch will wait for events to arrive.
+const NotifyFetchTimeoutMax = 60 * time.Second
+
+// NotifyFetchRequest is the request object for RpcNotifyFetch.
+//
+// If no events are pending, RpcNotifyFetch waits up to TimeoutMs (capped at NotifyFetchTimeoutMax)
+// for one to arrive. A MaxEvents of zero fetches all pending events.
+//
+type NotifyFetchRequest struct {
+	MountID        MountIDAsString
+	SubscriptionID uint64
+	MaxEvents      uint64
+	TimeoutMs      uint64
+}
+
+// NotifyFetchReply is the reply object for RpcNotifyFetch.
+//
+// Overflowed indicates that events were discarded since the prior RpcNotifyFetch because the
+// subscription's queue was full. The caller should rescan the watched directory (or subtree).
+//
+type NotifyFetchReply struct {
+	Events     []NotifyEvent
+	Overflowed bool
+}
+
+// NotifySubscribeRequest is the request object for RpcNotifySubscribe.
+//
+// EventMask is an or'ing of the fs.Notify* event types to be reported for the directory referenced
+// by InodeNumber (as well as all directories beneath it if Subtree is set).
+//
+type NotifySubscribeRequest struct {
+	InodeHandle
+	Subtree   bool
+	EventMask uint32
+}
+
+// NotifySubscribeReply is the reply object for RpcNotifySubscribe.
+type NotifySubscribeReply struct {
+	SubscriptionID uint64
+}
+
+// NotifyUnsubscribeRequest is the request object for RpcNotifyUnsubscribe.
+type NotifyUnsubscribeRequest struct {
+	MountID        MountIDAsString
+	SubscriptionID uint64
+}
+
 // ReaddirRequest is the request object for RpcReaddir.
 type ReaddirRequest struct {
 	InodeHandle
@@ -444,12 +537,14 @@ type RenameRequest struct {
 	SrcBasename       string
 	DstDirInodeNumber int64
 	DstBasename       string
+	Flags             uint32 // renameat2(2)'s RENAME_* values (see inode.RenameFlags)
 }
 
 // RenamePathRequest is the request object for RpcRenamePath.
 type RenamePathRequest struct {
 	PathHandle
 	DstFullpath string
+	Flags       uint32 // renameat2(2)'s RENAME_* values (see inode.RenameFlags)
 }
 
 // Reply is a generic response object used when no values need to be returned.
@@ -467,6 +562,24 @@ type ResizeRequest struct {
 	NewSize uint64
 }
 
+// Whence values for SeekRequest (matching lseek(2)'s SEEK_DATA & SEEK_HOLE).
+const (
+	SeekData = 3
+	SeekHole = 4
+)
+
+// SeekRequest is the request object for RpcSeek.
+type SeekRequest struct {
+	InodeHandle
+	Offset uint64
+	Whence int // either SeekData or SeekHole
+}
+
+// SeekReply is the reply object for RpcSeek.
+type SeekReply struct {
+	Offset uint64 // the start of the next data (SeekData) or hole (SeekHole) at or after SeekRequest.Offset
+}
+
 // SetstatRequest is the request object for RpcSetstat.
 type SetstatRequest struct {
 	InodeHandle
@@ -554,6 +667,50 @@ type SymlinkPathRequest struct {
 	GroupID        int32
 }
 
+// TrashEntry is used as part of TrashListReply.
+//
+// FileType here will be a uint16 containing DT_DIR|DT_REG|DT_LNK.
+//
+type TrashEntry struct {
+	TrashID        string
+	TrashUserID    int32  // Owner of the trash directory holding the trashed inode
+	DirInodeNumber int64  // Directory the inode was deleted from
+	Basename       string // Name the inode was deleted from DirInodeNumber as
+	Path           string // Path the inode was deleted from (if deleted via the middleware)
+	DeleteTime     uint64 // nanoseconds since epoch
+	InodeNumber    int64
+	FileType       uint16
+	FileSize       uint64
+}
+
+// TrashListRequest is the request object for RpcTrashList.
+//
+// The root user (UserID 0) is returned the trashed inodes of all users.
+//
+type TrashListRequest struct {
+	MountID MountIDAsString
+	UserID  int32
+	GroupID int32
+}
+
+// TrashListReply is the reply object for RpcTrashList.
+type TrashListReply struct {
+	Entries []TrashEntry
+}
+
+// TrashRestoreRequest is the request object for RpcTrashRestore.
+//
+// If RestorePath is empty, the trashed inode is restored to where it was deleted from.
+//
+type TrashRestoreRequest struct {
+	MountID     MountIDAsString
+	UserID      int32
+	GroupID     int32
+	TrashUserID int32
+	TrashID     string // as returned by RpcTrashList
+	RestorePath string
+}
+
 // TypeRequest is the request object for RpcType.
 type TypeRequest struct {
 	InodeHandle
@@ -610,6 +767,8 @@ type HeadReply struct {
 	InodeNumber      int64
 	NumWrites        uint64
 	Metadata         []byte // entity metadata, serialized
+	ETag             string // MD5 of the file's content (empty unless maintained by the volume)
+	ExpireAt         uint64 // seconds since epoch at which the object expires (0 if never)
 }
 
 type HeadReq struct {
@@ -659,6 +818,8 @@ type GetObjectReply struct {
 	ModificationTime uint64 // file's mtime in nanoseconds since the epoch
 	AttrChangeTime   uint64
 	LeaseId          string
+	ETag             string // MD5 of the file's content (empty unless maintained by the volume)
+	ExpireAt         uint64 // seconds since epoch at which the object expires (0 if never)
 }
 
 // GetObjectReq is the request object for RpcGetObject
@@ -709,10 +870,11 @@ type MiddlewareMkdirReq struct {
 
 // PutCompleteReq is the request object for RpcPutComplete
 type PutCompleteReq struct {
-	VirtPath    string
-	PhysPaths   []string
-	PhysLengths []uint64
-	Metadata    []byte
+	VirtPath        string
+	PhysPaths       []string
+	PhysLengths     []uint64
+	PhysBlockCRC32s [][]uint32 // Optional per-PhysPath CRC32s of each 256KiB block (if DataChecksums enabled)
+	Metadata        []byte
 }
 
 // PutCompleteReply is the response object for RpcPutComplete
@@ -776,6 +938,61 @@ type CoalesceReply struct {
 	NumWrites        uint64
 }
 
+// CopyReq is the request object for RpcCopy... a server-side object COPY that shares the
+// source object's extents rather than copying its data
+type CopyReq struct {
+	VirtPath               string // Destination
+	SrcAccountRelativePath string
+	Metadata               []byte
+}
+
+type CopyReply struct {
+	ModificationTime uint64
+	AttrChangeTime   uint64
+	InodeNumber      int64
+	NumWrites        uint64
+}
+
+// SetVersioningReq is the request object for RpcSetVersioning
+type SetVersioningReq struct {
+	VirtPath    string // virtual container path, e.g. /v1/AUTH_acc/some-dir
+	Mode        string // fs.VersioningMode{None|Stack|History}
+	MaxVersions uint64 // prior versions retained for each object (0 means no limit)
+}
+
+// SetVersioningReply is the response object for RpcSetVersioning
+type SetVersioningReply struct {
+}
+
+// ListVersionsReq is the request object for RpcListVersions
+type ListVersionsReq struct {
+	VirtPath string // virtual object path, e.g. /v1/AUTH_acc/some-dir/some-file
+}
+
+// ListVersionsReply is the response object for RpcListVersions
+type ListVersionsReply struct {
+	Versions []fs.VersionEntry // oldest first
+}
+
+// RestoreVersionReq is the request object for RpcRestoreVersion
+type RestoreVersionReq struct {
+	VirtPath  string // virtual object path, e.g. /v1/AUTH_acc/some-dir/some-file
+	VersionID string // as returned by RpcListVersions
+}
+
+// RestoreVersionReply is the response object for RpcRestoreVersion
+type RestoreVersionReply struct {
+}
+
+// SetExpirationReq is the request object for RpcSetExpiration
+type SetExpirationReq struct {
+	VirtPath string // object path, e.g. /v1/AUTH_acc/some-container/some-object
+	ExpireAt uint64 // seconds since epoch (0 to never expire)
+}
+
+type SetExpirationReply struct {
+}
+
 type RenewLeaseReq struct {
 	LeaseId string
 }
@@ -13,10 +13,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/swiftstack/ProxyFS/audit"
 	"github.com/swiftstack/ProxyFS/blunder"
 	"github.com/swiftstack/ProxyFS/fs"
 	"github.com/swiftstack/ProxyFS/inode"
 	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/qos"
 	"github.com/swiftstack/ProxyFS/utils"
 )
 
@@ -78,7 +80,7 @@ func jrpcServerLoop() {
 		globals.connLock.Unlock()
 
 		go func(myConn net.Conn, myElm *list.Element) {
-			srv.ServeCodec(jsonrpc.NewServerCodec(myConn))
+			srv.ServeCodec(newClientAddrCodec(myConn))
 			globals.connLock.Lock()
 			globals.connections.Remove(myElm)
 
@@ -91,6 +93,60 @@ func jrpcServerLoop() {
 	}
 }
 
+// clientAddrCodecStruct wraps the JSON RPC ServerCodec of a connection in order to hand the
+// remote address of that connection to those requests (i.e. mounts) recording it for auditing.
+type clientAddrCodecStruct struct {
+	rpc.ServerCodec
+	clientAddr string
+}
+
+type clientAddrSetter interface {
+	setClientAddr(clientAddr string)
+}
+
+func newClientAddrCodec(conn net.Conn) (codec *clientAddrCodecStruct) {
+	codec = &clientAddrCodecStruct{
+		ServerCodec: jsonrpc.NewServerCodec(conn),
+		clientAddr:  conn.RemoteAddr().String(),
+	}
+	return
+}
+
+func (codec *clientAddrCodecStruct) ReadRequestBody(body interface{}) (err error) {
+	err = codec.ServerCodec.ReadRequestBody(body)
+	if nil == err {
+		setter, ok := body.(clientAddrSetter)
+		if ok {
+			setter.setClientAddr(codec.clientAddr)
+		}
+	}
+	return
+}
+
+func (in *MountByAccountNameRequest) setClientAddr(clientAddr string) {
+	in.clientAddr = clientAddr
+}
+
+func (in *MountByVolumeNameRequest) setClientAddr(clientAddr string) {
+	in.clientAddr = clientAddr
+}
+
+// auditMount records a mount (successful or not) on behalf of clientAddr.
+func auditMount(volumeName string, attrs map[string]string, authUserID uint64, authGroupID uint64, clientAddr string, err error) {
+	if !audit.Enabled() {
+		return
+	}
+
+	audit.Log(&audit.Record{
+		Op:         audit.OpMount,
+		Volume:     volumeName,
+		UserID:     uint32(authUserID),
+		GroupID:    uint32(authGroupID),
+		ClientAddr: clientAddr,
+		Attrs:      attrs,
+	}, err)
+}
+
 // Enumeration of operations, used for stats-related things
 type OpType int
 
@@ -723,6 +779,8 @@ func (s *Server) RpcChown(in *ChownRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	// NOTE: We currently just store and return per-inode ownership info.
 	//       We do not check/enforce it; that is the caller's responsibility.
 
@@ -750,6 +808,8 @@ func (s *Server) RpcChownPath(in *ChownPathRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	// NOTE: We currently just store and return per-inode ownership info.
 	//       We do not check/enforce it; that is the caller's responsibility.
 
@@ -787,6 +847,8 @@ func (s *Server) RpcChmod(in *ChmodRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	// Samba includes the file mode in in.FileMode, but only the permssion
 	// bits can be changed by SetStat().
 	stat := make(fs.Stat)
@@ -808,6 +870,8 @@ func (s *Server) RpcChmodPath(in *ChmodPathRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	// NOTE: We currently just store and return per-inode ownership info.
 	//       We do not check/enforce it; that is the caller's responsibility.
 
@@ -827,6 +891,26 @@ func (s *Server) RpcChmodPath(in *ChmodPathRequest, reply *Reply) (err error) {
 	return
 }
 
+func (s *Server) RpcCopyFileRange(in *CopyFileRangeRequest, reply *CopyFileRangeReply) (err error) {
+	enterGate()
+	defer leaveGate()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	mountHandle, err := lookupMountHandleByMountIDAsString(in.MountID)
+	if nil != err {
+		return
+	}
+
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, in.Length)
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, in.Length)
+
+	reply.Size, err = mountHandle.CopyFileRange(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber), in.SrcOffset, inode.InodeNumber(in.DstInodeNumber), in.DstOffset, in.Length)
+	return
+}
+
 func (s *Server) RpcCreate(in *CreateRequest, reply *InodeReply) (err error) {
 	enterGate()
 	defer leaveGate()
@@ -840,6 +924,8 @@ func (s *Server) RpcCreate(in *CreateRequest, reply *InodeReply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, uint32(in.UserID), qos.Write, 0)
+
 	fino, err := mountHandle.Create(inode.InodeUserID(in.UserID), inode.InodeGroupID(in.GroupID), nil, inode.InodeNumber(in.InodeNumber), in.Basename, inode.InodeMode(in.FileMode))
 	reply.InodeNumber = int64(uint64(fino))
 	return
@@ -858,6 +944,8 @@ func (s *Server) RpcCreatePath(in *CreatePathRequest, reply *InodeReply) (err er
 		return
 	}
 
+	qos.Wait(mountHandle, uint32(in.UserID), qos.Write, 0)
+
 	// Ideally we would like all name/fullpath checking logic to be in the fs package,
 	// however since the fs.Create() and fs.Mkdir() APIs are inode-based, once we are
 	// inside those functions the fullpath is no longer available. The simplest solution is to
@@ -921,6 +1009,25 @@ func UnixNanosec(t time.Time) (ns int64) {
 	return t.UnixNano() - t.Unix()*int64(time.Second)
 }
 
+func (s *Server) RpcFallocate(in *FallocateRequest, reply *Reply) (err error) {
+	enterGate()
+	defer leaveGate()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	mountHandle, err := lookupMountHandleByMountIDAsString(in.MountID)
+	if nil != err {
+		return
+	}
+
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
+	err = mountHandle.Fallocate(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber), inode.FallocateMode(in.Mode), in.Offset, in.Length)
+	return
+}
+
 func (s *Server) RpcFlush(in *FlushRequest, reply *Reply) (err error) {
 	var profiler = utils.NewProfilerIf(doProfiling, "flush")
 
@@ -994,6 +1101,7 @@ func (s *Server) RpcGetStat(in *GetStatRequest, reply *StatStruct) (err error) {
 	profiler.AddEventNow("before fs.Getstat()")
 	mountHandle, err := lookupMountHandleByMountIDAsString(in.MountID)
 	if nil == err {
+		qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
 		stat, err = mountHandle.Getstat(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber))
 	}
 	profiler.AddEventNow("after fs.Getstat()")
@@ -1023,6 +1131,8 @@ func (s *Server) RpcGetStatPath(in *GetStatPathRequest, reply *StatStruct) (err
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
+
 	// Get the inode
 	profiler.AddEventNow("before fs.LookupPath()")
 	ino, err := mountHandle.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, in.Fullpath)
@@ -1062,6 +1172,7 @@ func (s *Server) RpcGetXAttr(in *GetXAttrRequest, reply *GetXAttrReply) (err err
 	profiler.AddEventNow("before fs.GetXAttr()")
 	mountHandle, err := lookupMountHandleByMountIDAsString(in.MountID)
 	if nil == err {
+		qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
 		reply.AttrValue, err = mountHandle.GetXAttr(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber), in.AttrName)
 	}
 	profiler.AddEventNow("after fs.GetXAttr()")
@@ -1088,6 +1199,8 @@ func (s *Server) RpcGetXAttrPath(in *GetXAttrPathRequest, reply *GetXAttrReply)
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
+
 	profiler.AddEventNow("before fs.LookupPath()")
 	ino, err := mountHandle.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, in.Fullpath)
 	profiler.AddEventNow("after fs.LookupPath()")
@@ -1135,6 +1248,8 @@ func (s *Server) RpcLookupPath(in *LookupPathRequest, reply *InodeReply) (err er
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
+
 	profiler.AddEventNow("before fs.LookupPath()")
 	ino, err := mountHandle.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, in.Fullpath)
 	profiler.AddEventNow("after fs.LookupPath()")
@@ -1162,6 +1277,8 @@ func (s *Server) RpcLink(in *LinkRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	err = mountHandle.Link(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber), in.Basename, inode.InodeNumber(in.TargetInodeNumber))
 	return
 }
@@ -1179,6 +1296,8 @@ func (s *Server) RpcLinkPath(in *LinkPathRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	// Split fullpath into parent dir and basename
 	parentDir, basename := splitPath(in.Fullpath)
 
@@ -1212,6 +1331,8 @@ func (s *Server) RpcListXAttr(in *ListXAttrRequest, reply *ListXAttrReply) (err
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
+
 	reply.AttrNames, err = mountHandle.ListXAttr(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber))
 	return
 }
@@ -1229,6 +1350,8 @@ func (s *Server) RpcListXAttrPath(in *ListXAttrPathRequest, reply *ListXAttrRepl
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
+
 	ino, err := mountHandle.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, in.Fullpath)
 	if err != nil {
 		return
@@ -1257,6 +1380,8 @@ func (s *Server) RpcLookup(in *LookupRequest, reply *InodeReply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
+
 	profiler.AddEventNow("before fs.Lookup()")
 	ino, err := mountHandle.Lookup(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber), in.Basename)
 	profiler.AddEventNow("after fs.Lookup()")
@@ -1286,6 +1411,8 @@ func (s *Server) RpcMkdir(in *MkdirRequest, reply *InodeReply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, uint32(in.UserID), qos.Write, 0)
+
 	ino, err := mountHandle.Mkdir(inode.InodeUserID(in.UserID), inode.InodeGroupID(in.GroupID), nil, inode.InodeNumber(in.InodeNumber), in.Basename, inode.InodeMode(in.FileMode))
 	reply.InodeNumber = int64(uint64(ino))
 	return
@@ -1304,6 +1431,8 @@ func (s *Server) RpcMkdirPath(in *MkdirPathRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, uint32(in.UserID), qos.Write, 0)
+
 	// Ideally we would like all name/fullpath checking logic to be in the fs package,
 	// however since the fs.Create() and fs.Mkdir() APIs are inode-based, once we are
 	// inside those functions the fullpath is no longer available. The simplest solution is to
@@ -1337,9 +1466,14 @@ func (s *Server) RpcMountByAccountName(in *MountByAccountNameRequest, reply *Mou
 
 	mountHandle, err := fs.MountByAccountName(in.AccountName, fs.MountOptions(in.MountOptions))
 	if err == nil {
+		mountHandle.SetClientAddr(in.clientAddr)
 		_, reply.MountID = allocateMountID(mountHandle)
 		reply.RootDirInodeNumber = int64(uint64(inode.RootDirInodeNumber))
 	}
+
+	volumeName, _ := inode.AccountNameToVolumeName(in.AccountName)
+	auditMount(volumeName, map[string]string{"AccountName": in.AccountName, "MountOptions": fmt.Sprintf("0x%X", in.MountOptions)}, in.AuthUserID, in.AuthGroupID, in.clientAddr, err)
+
 	return
 }
 
@@ -1353,9 +1487,99 @@ func (s *Server) RpcMountByVolumeName(in *MountByVolumeNameRequest, reply *Mount
 
 	mountHandle, err := fs.MountByVolumeName(in.VolumeName, fs.MountOptions(in.MountOptions))
 	if err == nil {
+		mountHandle.SetClientAddr(in.clientAddr)
 		_, reply.MountID = allocateMountID(mountHandle)
 		reply.RootDirInodeNumber = int64(uint64(inode.RootDirInodeNumber))
 	}
+
+	auditMount(in.VolumeName, map[string]string{"MountOptions": fmt.Sprintf("0x%X", in.MountOptions)}, in.AuthUserID, in.AuthGroupID, in.clientAddr, err)
+
+	return
+}
+
+func (s *Server) RpcNotifyFetch(in *NotifyFetchRequest, reply *NotifyFetchReply) (err error) {
+	var (
+		events      []fs.NotifyEvent
+		eventsIndex int
+		timeout     time.Duration
+	)
+
+	// Only hold the gate while resolving the MountID... the (potentially lengthy) wait for
+	// events must not block SignaledStart() (and, once the volume is unserved, will be woken)
+
+	enterGate()
+	mountHandle, err := lookupMountHandleByMountIDAsString(in.MountID)
+	leaveGate()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	if nil != err {
+		return
+	}
+
+	timeout = time.Duration(in.TimeoutMs) * time.Millisecond
+	if timeout > NotifyFetchTimeoutMax {
+		timeout = NotifyFetchTimeoutMax
+	}
+
+	events, reply.Overflowed, err = mountHandle.NotifyFetch(fs.NotifySubscriptionID(in.SubscriptionID), in.MaxEvents, timeout)
+	if nil != err {
+		return
+	}
+
+	reply.Events = make([]NotifyEvent, len(events))
+
+	for eventsIndex = range events {
+		reply.Events[eventsIndex] = NotifyEvent{
+			Sequence:          events[eventsIndex].Sequence,
+			EventType:         uint32(events[eventsIndex].Type),
+			DirInodeNumber:    int64(uint64(events[eventsIndex].DirInodeNumber)),
+			Basename:          events[eventsIndex].Basename,
+			InodeNumber:       int64(uint64(events[eventsIndex].InodeNumber)),
+			NewDirInodeNumber: int64(uint64(events[eventsIndex].NewDirInodeNumber)),
+			NewBasename:       events[eventsIndex].NewBasename,
+		}
+	}
+
+	return
+}
+
+func (s *Server) RpcNotifySubscribe(in *NotifySubscribeRequest, reply *NotifySubscribeReply) (err error) {
+	enterGate()
+	defer leaveGate()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	mountHandle, err := lookupMountHandleByMountIDAsString(in.MountID)
+	if nil != err {
+		return
+	}
+
+	subscriptionID, err := mountHandle.NotifySubscribe(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber), in.Subtree, fs.NotifyEventType(in.EventMask))
+	if nil == err {
+		reply.SubscriptionID = uint64(subscriptionID)
+	}
+	return
+}
+
+func (s *Server) RpcNotifyUnsubscribe(in *NotifyUnsubscribeRequest, reply *Reply) (err error) {
+	enterGate()
+	defer leaveGate()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	mountHandle, err := lookupMountHandleByMountIDAsString(in.MountID)
+	if nil != err {
+		return
+	}
+
+	err = mountHandle.NotifyUnsubscribe(fs.NotifySubscriptionID(in.SubscriptionID))
 	return
 }
 
@@ -1423,6 +1647,8 @@ func (s *Server) rpcReaddirInternal(in interface{}, reply *ReaddirReply, profile
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
+
 	profiler.AddEventNow("before fs.Readdir()")
 	dirEnts, _, _, err = mountHandle.Readdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(iH.InodeNumber), maxEntries, prevMarker)
 	profiler.AddEventNow("after fs.Readdir()")
@@ -1495,6 +1721,8 @@ func (s *Server) rpcReaddirPlusInternal(in interface{}, reply *ReaddirPlusReply,
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
+
 	profiler.AddEventNow("before fs.ReaddirPlus()")
 	dirEnts, statEnts, _, _, err = mountHandle.ReaddirPlus(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(iH.InodeNumber), maxEntries, prevMarker)
 	profiler.AddEventNow("after fs.ReaddirPlus()")
@@ -1524,6 +1752,8 @@ func (s *Server) RpcReadSymlink(in *ReadSymlinkRequest, reply *ReadSymlinkReply)
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
+
 	target, err := mountHandle.Readsymlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber))
 	reply.Target = target
 	return
@@ -1542,6 +1772,8 @@ func (s *Server) RpcReadSymlinkPath(in *ReadSymlinkPathRequest, reply *ReadSymli
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
+
 	// Get the inode
 	ino, err := mountHandle.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, in.Fullpath)
 	if err != nil {
@@ -1566,6 +1798,8 @@ func (s *Server) RpcRemovetXAttr(in *RemoveXAttrRequest, reply *Reply) (err erro
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	err = mountHandle.RemoveXAttr(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber), in.AttrName)
 	return
 }
@@ -1583,6 +1817,8 @@ func (s *Server) RpcRemoveAttrPath(in *RemoveXAttrPathRequest, reply *Reply) (er
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	ino, err := mountHandle.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, in.Fullpath)
 	if err != nil {
 		return
@@ -1605,7 +1841,9 @@ func (s *Server) RpcRename(in *RenameRequest, reply *Reply) (err error) {
 		return
 	}
 
-	err = mountHandle.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.SrcDirInodeNumber), in.SrcBasename, inode.InodeNumber(in.DstDirInodeNumber), in.DstBasename)
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
+	err = mountHandle.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.SrcDirInodeNumber), in.SrcBasename, inode.InodeNumber(in.DstDirInodeNumber), in.DstBasename, inode.RenameFlags(in.Flags))
 	return
 }
 
@@ -1622,6 +1860,8 @@ func (s *Server) RpcRenamePath(in *RenamePathRequest, reply *Reply) (err error)
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	// Split fullpath into (source) parent dir and new basename
 	srcParentDir, srcBasename := splitPath(in.Fullpath)
 
@@ -1641,7 +1881,7 @@ func (s *Server) RpcRenamePath(in *RenamePathRequest, reply *Reply) (err error)
 	}
 
 	// Do the rename
-	err = mountHandle.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, srcIno, srcBasename, dstIno, dstBasename)
+	err = mountHandle.Rename(inode.InodeRootUserID, inode.InodeGroupID(0), nil, srcIno, srcBasename, dstIno, dstBasename, inode.RenameFlags(in.Flags))
 	return
 }
 
@@ -1658,10 +1898,38 @@ func (s *Server) RpcResize(in *ResizeRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	err = mountHandle.Resize(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber), in.NewSize)
 	return
 }
 
+func (s *Server) RpcSeek(in *SeekRequest, reply *SeekReply) (err error) {
+	enterGate()
+	defer leaveGate()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	mountHandle, err := lookupMountHandleByMountIDAsString(in.MountID)
+	if nil != err {
+		return
+	}
+
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
+
+	switch in.Whence {
+	case SeekData:
+		reply.Offset, err = mountHandle.SeekData(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber), in.Offset)
+	case SeekHole:
+		reply.Offset, err = mountHandle.SeekHole(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber), in.Offset)
+	default:
+		err = blunder.NewError(blunder.InvalidArgError, "RpcSeek() called with invalid Whence (%v)", in.Whence)
+	}
+	return
+}
+
 func (s *Server) RpcRmdir(in *UnlinkRequest, reply *Reply) (err error) {
 	enterGate()
 	defer leaveGate()
@@ -1675,6 +1943,8 @@ func (s *Server) RpcRmdir(in *UnlinkRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	err = mountHandle.Rmdir(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber), in.Basename)
 	return
 }
@@ -1692,6 +1962,8 @@ func (s *Server) RpcRmdirPath(in *UnlinkPathRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	// Split fullpath into parent dir and new basename
 	parentDir, basename := splitPath(in.Fullpath)
 
@@ -1719,6 +1991,8 @@ func (s *Server) RpcSetstat(in *SetstatRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	stat := make(fs.Stat)
 	stat[fs.StatCRTime] = in.CRTimeNs
 	stat[fs.StatCTime] = in.CTimeNs
@@ -1744,6 +2018,8 @@ func (s *Server) RpcSetTime(in *SetTimeRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	stat := make(fs.Stat)
 	stat[fs.StatMTime] = in.MTimeNs
 	stat[fs.StatATime] = in.ATimeNs
@@ -1764,6 +2040,8 @@ func (s *Server) RpcSetTimePath(in *SetTimePathRequest, reply *Reply) (err error
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	// Get the inode
 	ino, err := mountHandle.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, in.Fullpath)
 	if err != nil {
@@ -1790,6 +2068,8 @@ func (s *Server) RpcSetXAttr(in *SetXAttrRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	err = mountHandle.SetXAttr(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber), in.AttrName, in.AttrValue, in.AttrFlags)
 	return
 }
@@ -1807,6 +2087,8 @@ func (s *Server) RpcSetXAttrPath(in *SetXAttrPathRequest, reply *Reply) (err err
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	ino, err := mountHandle.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, in.Fullpath)
 	if err != nil {
 		return
@@ -1829,6 +2111,8 @@ func (s *Server) RpcStatVFS(in *StatVFSRequest, reply *StatVFS) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, 0)
+
 	statvfs, err := mountHandle.StatVfs()
 	if err != nil {
 		return
@@ -1863,6 +2147,8 @@ func (s *Server) RpcSymlink(in *SymlinkRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, uint32(in.UserID), qos.Write, 0)
+
 	_, err = mountHandle.Symlink(inode.InodeUserID(in.UserID), inode.InodeGroupID(in.GroupID), nil, inode.InodeNumber(in.InodeNumber), in.Basename, in.Target)
 	return
 }
@@ -1880,6 +2166,8 @@ func (s *Server) RpcSymlinkPath(in *SymlinkPathRequest, reply *Reply) (err error
 		return
 	}
 
+	qos.Wait(mountHandle, uint32(in.UserID), qos.Write, 0)
+
 	// Split fullpath into (source) parent dir and new basename
 	srcParentDir, srcBasename := splitPath(in.Fullpath)
 
@@ -1893,6 +2181,64 @@ func (s *Server) RpcSymlinkPath(in *SymlinkPathRequest, reply *Reply) (err error
 	return
 }
 
+func (s *Server) RpcTrashList(in *TrashListRequest, reply *TrashListReply) (err error) {
+	enterGate()
+	defer leaveGate()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	mountHandle, err := lookupMountHandleByMountIDAsString(in.MountID)
+	if nil != err {
+		return
+	}
+
+	qos.Wait(mountHandle, uint32(in.UserID), qos.Read, 0)
+
+	entries, err := mountHandle.TrashList(inode.InodeUserID(in.UserID), inode.InodeGroupID(in.GroupID), nil)
+	if nil != err {
+		return
+	}
+
+	reply.Entries = make([]TrashEntry, len(entries))
+
+	for entriesIndex := range entries {
+		reply.Entries[entriesIndex] = TrashEntry{
+			TrashID:        entries[entriesIndex].TrashID,
+			TrashUserID:    int32(entries[entriesIndex].UserID),
+			DirInodeNumber: int64(uint64(entries[entriesIndex].DirInodeNumber)),
+			Basename:       entries[entriesIndex].Basename,
+			Path:           entries[entriesIndex].Path,
+			DeleteTime:     entries[entriesIndex].DeleteTime,
+			InodeNumber:    int64(uint64(entries[entriesIndex].InodeNumber)),
+			FileType:       uint16(entries[entriesIndex].InodeType),
+			FileSize:       entries[entriesIndex].FileSize,
+		}
+	}
+
+	return
+}
+
+func (s *Server) RpcTrashRestore(in *TrashRestoreRequest, reply *Reply) (err error) {
+	enterGate()
+	defer leaveGate()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	mountHandle, err := lookupMountHandleByMountIDAsString(in.MountID)
+	if nil != err {
+		return
+	}
+
+	qos.Wait(mountHandle, uint32(in.UserID), qos.Write, 0)
+
+	err = mountHandle.TrashRestore(inode.InodeUserID(in.UserID), inode.InodeGroupID(in.GroupID), nil, inode.InodeUserID(in.TrashUserID), in.TrashID, in.RestorePath)
+	return
+}
+
 func (s *Server) RpcType(in *TypeRequest, reply *TypeReply) (err error) {
 	enterGate()
 	defer leaveGate()
@@ -1925,6 +2271,8 @@ func (s *Server) RpcUnlink(in *UnlinkRequest, reply *Reply) (err error) {
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	err = mountHandle.Unlink(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(in.InodeNumber), in.Basename)
 	return
 }
@@ -1942,6 +2290,8 @@ func (s *Server) RpcUnlinkPath(in *UnlinkPathRequest, reply *Reply) (err error)
 		return
 	}
 
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
 	// Split fullpath into parent dir and new basename
 	parentDir, basename := splitPath(in.Fullpath)
 
@@ -13,6 +13,7 @@ import (
 	"github.com/swiftstack/ProxyFS/fs"
 	"github.com/swiftstack/ProxyFS/inode"
 	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/qos"
 	"github.com/swiftstack/ProxyFS/stats"
 	"github.com/swiftstack/ProxyFS/utils"
 )
@@ -339,6 +340,16 @@ type ioContext struct {
 	data []byte
 }
 
+// ioReadAheadKeyStruct identifies a file read via an I/O connection. As the I/O protocol carries no
+// open file handle, each connection (typically serving a single client process) stands in for one
+// handle per file when detecting sequential reads.
+type ioReadAheadKeyStruct struct {
+	mountID MountIDAsByteArray
+	inodeID uint64
+}
+
+const ioMaxReadAheadStatesPerConn = 64 // beyond which a connection's read-ahead state is reset
+
 const ioRequestSize int = 8 + 16 + 8 + 8 + 8
 const ioResponseSize int = 8 + 8
 
@@ -361,6 +372,23 @@ func makeReq(bytes []byte, req *ioRequest) {
 	*req = *(*ioRequest)(unsafe.Pointer(&bytes[0]))
 }
 
+func fetchIoReadAheadState(readAheadStateMap map[ioReadAheadKeyStruct]*inode.ReadAheadState, req *ioRequest) (readAheadState *inode.ReadAheadState) {
+	readAheadKey := ioReadAheadKeyStruct{mountID: req.mountID, inodeID: req.inodeID}
+
+	readAheadState, ok := readAheadStateMap[readAheadKey]
+	if !ok {
+		if len(readAheadStateMap) >= ioMaxReadAheadStatesPerConn {
+			for key := range readAheadStateMap {
+				delete(readAheadStateMap, key)
+			}
+		}
+		readAheadState = &inode.ReadAheadState{}
+		readAheadStateMap[readAheadKey] = readAheadState
+	}
+
+	return
+}
+
 func ioHandle(conn net.Conn) {
 	var (
 		mountHandle fs.MountHandle
@@ -370,6 +398,7 @@ func ioHandle(conn net.Conn) {
 	//		 one request at a time.
 	ctxStorage := ioContext{op: InvalidOp}
 	ctx := &ctxStorage
+	readAheadStateMap := make(map[ioReadAheadKeyStruct]*inode.ReadAheadState)
 
 	if printDebugLogs {
 		logger.Infof("got a connection - starting read/write io thread")
@@ -409,6 +438,7 @@ func ioHandle(conn net.Conn) {
 			profiler.AddEventNow("before fs.Write()")
 			mountHandle, err = lookupMountHandleByMountIDAsByteArray(ctx.req.mountID)
 			if err == nil {
+				qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, uint64(len(ctx.data)))
 				ctx.resp.ioSize, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(ctx.req.inodeID), ctx.req.offset, ctx.data, profiler)
 			}
 			profiler.AddEventNow("after fs.Write()")
@@ -427,7 +457,9 @@ func ioHandle(conn net.Conn) {
 			profiler.AddEventNow("before fs.Read()")
 			mountHandle, err = lookupMountHandleByMountIDAsByteArray(ctx.req.mountID)
 			if err == nil {
-				ctx.data, err = mountHandle.Read(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(ctx.req.inodeID), ctx.req.offset, ctx.req.length, profiler)
+				qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, ctx.req.length)
+				readAheadState := fetchIoReadAheadState(readAheadStateMap, &ctx.req)
+				ctx.data, err = mountHandle.ReadWithReadAhead(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inode.InodeNumber(ctx.req.inodeID), ctx.req.offset, ctx.req.length, readAheadState, profiler)
 			}
 			profiler.AddEventNow("after fs.Read()")
 
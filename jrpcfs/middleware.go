@@ -7,6 +7,7 @@ import (
 	"github.com/swiftstack/ProxyFS/fs"
 	"github.com/swiftstack/ProxyFS/inode"
 	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/qos"
 	"github.com/swiftstack/ProxyFS/utils"
 )
 
@@ -173,6 +174,8 @@ func (s *Server) RpcHead(in *HeadReq, reply *HeadReply) (err error) {
 	reply.AttrChangeTime = resp.AttrChangeTime
 	reply.InodeNumber = int64(uint64(resp.InodeNumber))
 	reply.NumWrites = resp.NumWrites
+	reply.ETag = resp.ETag
+	reply.ExpireAt = resp.ExpireAt
 
 	reply.IsDir = resp.IsDir
 
@@ -216,12 +219,19 @@ func (s *Server) RpcGetObject(in *GetObjectReq, reply *GetObjectReply) (err erro
 	mountRelativePath := vContainerName + "/" + objectName
 
 	var ino uint64
-	reply.FileSize, reply.ModificationTime, reply.AttrChangeTime, ino, reply.NumWrites, reply.Metadata, err = mountHandle.MiddlewareGetObject(mountRelativePath, in.ReadEntsIn, &reply.ReadEntsOut)
+	reply.FileSize, reply.ModificationTime, reply.AttrChangeTime, ino, reply.NumWrites, reply.Metadata, reply.ETag, reply.ExpireAt, err = mountHandle.MiddlewareGetObject(mountRelativePath, in.ReadEntsIn, &reply.ReadEntsOut)
 	if err != nil {
 		return err
 	}
 	reply.InodeNumber = int64(ino)
 
+	// The middleware reads the object per the read plan once we reply... so charge it now
+	var readLength uint64
+	for _, readPlanStep := range reply.ReadEntsOut {
+		readLength += readPlanStep.Length
+	}
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Read, readLength)
+
 	return err
 }
 
@@ -286,9 +296,18 @@ func (s *Server) RpcPutComplete(in *PutCompleteReq, reply *PutCompleteReply) (er
 
 	_, containerName, objectName, _, mountHandle, err := mountIfNotMounted(in.VirtPath)
 
+	// The middleware has already written the object's data... so charge it before completing
+	if err == nil {
+		var writeLength uint64
+		for _, physLength := range in.PhysLengths {
+			writeLength += physLength
+		}
+		qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, writeLength)
+	}
+
 	// Call fs to complete the creation of the inode for the file and
 	// the directories.
-	mtime, ctime, ino, numWrites, err := mountHandle.MiddlewarePutComplete(containerName, objectName, in.PhysPaths, in.PhysLengths, in.Metadata)
+	mtime, ctime, ino, numWrites, err := mountHandle.MiddlewarePutComplete(containerName, objectName, in.PhysPaths, in.PhysLengths, in.PhysBlockCRC32s, in.Metadata)
 	reply.ModificationTime = mtime
 	reply.AttrChangeTime = ctime
 	reply.InodeNumber = int64(uint64(ino))
@@ -360,6 +379,13 @@ func (s *Server) RpcCoalesce(in *CoalesceReq, reply *CoalesceReply) (err error)
 	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
 
 	_, destContainer, destObject, _, mountHandle, err := mountIfNotMounted(in.VirtPath)
+	if nil != err {
+		return
+	}
+
+	// Coalescing (like copying) references the elements' log segments rather than moving their data
+
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
 
 	var ino uint64
 	ino, reply.NumWrites, reply.ModificationTime, err = mountHandle.MiddlewareCoalesce(destContainer+"/"+destObject, in.ElementAccountRelativePaths)
@@ -367,6 +393,129 @@ func (s *Server) RpcCoalesce(in *CoalesceReq, reply *CoalesceReply) (err error)
 	return
 }
 
+// Copy an object within an account. It's like "cp --reflink old new": the new object shares the old one's log
+// segments rather than getting its own copy of the data. The new object's metadata is replaced by in.Metadata.
+func (s *Server) RpcCopy(in *CopyReq, reply *CopyReply) (err error) {
+	enterGate()
+	defer leaveGate()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	_, dstContainer, dstObject, _, mountHandle, err := mountIfNotMounted(in.VirtPath)
+	if nil != err {
+		return
+	}
+
+	// Require a reference to an object; you can't copy to a container with this method.
+	if dstObject == "" {
+		err = blunder.NewError(blunder.NotAnObjectError, "%s: VirtPath must reference an object, not container or account (%s)", utils.GetFnName(), in.VirtPath)
+		return
+	}
+
+	qos.Wait(mountHandle, qos.UnknownUserID, qos.Write, 0)
+
+	var ino inode.InodeNumber
+	reply.ModificationTime, reply.AttrChangeTime, ino, reply.NumWrites, err = mountHandle.MiddlewareCopy(in.SrcAccountRelativePath, dstContainer+"/"+dstObject, in.Metadata)
+	reply.InodeNumber = int64(uint64(ino))
+	return
+}
+
+// RpcSetVersioning configures (or disables) the retention of prior versions of the objects in a container.
+func (s *Server) RpcSetVersioning(in *SetVersioningReq, reply *SetVersioningReply) (err error) {
+	enterGate()
+	defer leaveGate()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	_, containerName, objectName, _, mountHandle, err := mountIfNotMounted(in.VirtPath)
+	if nil != err {
+		return
+	}
+
+	if ("" == containerName) || ("" != objectName) {
+		err = blunder.NewError(blunder.InvalidArgError, "%s: VirtPath must reference a container (%s)", utils.GetFnName(), in.VirtPath)
+		return
+	}
+
+	err = mountHandle.MiddlewareSetVersioning(containerName, in.Mode, in.MaxVersions)
+	return
+}
+
+// RpcListVersions returns the prior versions retained for an object in a versioned container.
+func (s *Server) RpcListVersions(in *ListVersionsReq, reply *ListVersionsReply) (err error) {
+	enterGate()
+	defer leaveGate()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	_, containerName, objectName, _, mountHandle, err := mountIfNotMounted(in.VirtPath)
+	if nil != err {
+		return
+	}
+
+	if "" == objectName {
+		err = blunder.NewError(blunder.NotAnObjectError, "%s: VirtPath must reference an object, not container or account (%s)", utils.GetFnName(), in.VirtPath)
+		return
+	}
+
+	reply.Versions, err = mountHandle.MiddlewareListVersions(containerName, objectName)
+	return
+}
+
+// RpcRestoreVersion makes a prior version of an object current again. The version it replaces is itself
+// retained if the container is (still) versioned.
+func (s *Server) RpcRestoreVersion(in *RestoreVersionReq, reply *RestoreVersionReply) (err error) {
+	enterGate()
+	defer leaveGate()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	_, containerName, objectName, _, mountHandle, err := mountIfNotMounted(in.VirtPath)
+	if nil != err {
+		return
+	}
+
+	if "" == objectName {
+		err = blunder.NewError(blunder.NotAnObjectError, "%s: VirtPath must reference an object, not container or account (%s)", utils.GetFnName(), in.VirtPath)
+		return
+	}
+
+	err = mountHandle.MiddlewareRestoreVersion(containerName, objectName, in.VersionID)
+	return
+}
+
+// RpcSetExpiration sets (or, if in.ExpireAt is zero, clears) the time at which an object expires.
+// Once expired, the object is reported as missing and, eventually, unlinked.
+func (s *Server) RpcSetExpiration(in *SetExpirationReq, reply *SetExpirationReply) (err error) {
+	enterGate()
+	defer leaveGate()
+
+	flog := logger.TraceEnter("in.", in)
+	defer func() { flog.TraceExitErr("reply.", err, reply) }()
+	defer func() { rpcEncodeError(&err) }() // Encode error for return by RPC
+
+	_, containerName, objectName, _, mountHandle, err := mountIfNotMounted(in.VirtPath)
+	if nil != err {
+		return
+	}
+
+	if "" == objectName {
+		err = blunder.NewError(blunder.NotAnObjectError, "%s: VirtPath must reference an object, not container or account (%s)", utils.GetFnName(), in.VirtPath)
+		return
+	}
+
+	err = mountHandle.MiddlewareSetExpiration(containerName, objectName, in.ExpireAt)
+	return
+}
+
 // Renew a lease, ensuring that the related file's log segments won't get deleted. This ensures that an HTTP client is
 // able to complete an object GET request regardless of concurrent FS writes or HTTP PUTs to that file.
 //
@@ -1,6 +1,8 @@
 package jrpcfs
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -104,6 +106,7 @@ func testSetup() []func() {
 		"Volume:SomeVolume.MaxDirFileNodesPerMetadataNode=16",
 		"Volume:SomeVolume.MaxBytesInodeCache=100000",
 		"Volume:SomeVolume.InodeCacheEvictInterval=1s",
+		"Volume:SomeVolume.MaintainETag=true",
 		"Volume:SomeVolume2.FSID=2",
 		"Volume:SomeVolume2.AccountName=" + testAccountName2,
 		"Volume:SomeVolume2.AutoFormat=true",
@@ -2412,3 +2415,349 @@ func TestRpcCoalesceSubdirOfAFile(t *testing.T) {
 	assert.NotNil(err)
 	assert.Equal(fmt.Sprintf("errno: %d", blunder.PermDeniedError), err.Error())
 }
+
+func TestRpcCopy(t *testing.T) {
+	server := &Server{}
+	assert := assert.New(t)
+	mountHandle, err := fs.MountByVolumeName("SomeVolume", fs.MountOptions(0))
+	if nil != err {
+		panic(fmt.Sprintf("failed to mount SomeVolume: %v", err))
+	}
+
+	containerName := "rpc-copy-unspiteful-haversine"
+	containerPath := testVerAccountName + "/" + containerName
+	containerInode := fsMkDir(mountHandle, inode.RootDirInodeNumber, containerName)
+
+	srcInode := fsCreateFile(mountHandle, containerInode, "src")
+	_, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeGroupID(0), nil, srcInode, 0, []byte("red orange yellow"), nil)
+	if err != nil {
+		panic(err)
+	}
+
+	// Copy into a not yet existing subdirectory
+	copyRequest := CopyReq{
+		VirtPath:               containerPath + "/sub/dst",
+		SrcAccountRelativePath: "/" + containerName + "/src",
+		Metadata:               []byte("metadata for dst"),
+	}
+	copyReply := CopyReply{}
+	err = server.RpcCopy(&copyRequest, &copyReply)
+	assert.Nil(err)
+
+	dstInode, err := mountHandle.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerName+"/sub/dst")
+	assert.Nil(err)
+	assert.Equal(uint64(dstInode), uint64(copyReply.InodeNumber))
+	assert.NotEqual(uint64(srcInode), uint64(copyReply.InodeNumber))
+
+	dstContents, err := mountHandle.Read(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dstInode, 0, 99999, nil)
+	assert.Nil(err)
+	assert.Equal([]byte("red orange yellow"), dstContents)
+
+	headRequest := HeadReq{
+		VirtPath: containerPath + "/sub/dst",
+	}
+	headReply := HeadReply{}
+	err = server.RpcHead(&headRequest, &headReply)
+	assert.Nil(err)
+	assert.Equal([]byte("metadata for dst"), headReply.Metadata)
+
+	// Overwriting the source must not affect the copy
+	_, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeGroupID(0), nil, srcInode, 0, []byte("green"), nil)
+	if err != nil {
+		panic(err)
+	}
+	err = mountHandle.Flush(inode.InodeRootUserID, inode.InodeGroupID(0), nil, srcInode)
+	assert.Nil(err)
+
+	dstContents, err = mountHandle.Read(inode.InodeRootUserID, inode.InodeGroupID(0), nil, dstInode, 0, 99999, nil)
+	assert.Nil(err)
+	assert.Equal([]byte("red orange yellow"), dstContents)
+
+	// Copying from a missing source fails
+	copyRequest = CopyReq{
+		VirtPath:               containerPath + "/dst2",
+		SrcAccountRelativePath: "/" + containerName + "/no-such-src",
+	}
+	copyReply = CopyReply{}
+	err = server.RpcCopy(&copyRequest, &copyReply)
+	assert.NotNil(err)
+	assert.Equal(fmt.Sprintf("errno: %d", blunder.NotFoundError), err.Error())
+}
+
+func TestRpcETag(t *testing.T) {
+	server := &Server{}
+	assert := assert.New(t)
+	mountHandle, err := fs.MountByVolumeName("SomeVolume", fs.MountOptions(0))
+	if nil != err {
+		panic(fmt.Sprintf("failed to mount SomeVolume: %v", err))
+	}
+
+	containerName := "rpc-etag-unwritten-palimpsest"
+	containerPath := testVerAccountName + "/" + containerName
+	containerInode := fsMkDir(mountHandle, inode.RootDirInodeNumber, containerName)
+
+	fileInode := fsCreateFile(mountHandle, containerInode, "obj")
+	_, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInode, 0, []byte("cyan magenta"), nil)
+	if err != nil {
+		panic(err)
+	}
+
+	headRequest := HeadReq{
+		VirtPath: containerPath + "/obj",
+	}
+
+	// No ETag until the file is flushed after its writes
+	headReply := HeadReply{}
+	err = server.RpcHead(&headRequest, &headReply)
+	assert.Nil(err)
+	assert.Equal("", headReply.ETag)
+
+	err = mountHandle.Flush(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInode)
+	assert.Nil(err)
+
+	expectedMD5 := md5.Sum([]byte("cyan magenta"))
+	expectedETag := hex.EncodeToString(expectedMD5[:])
+
+	// The ETag is computed in the background following the Flush()
+	for i := 0; i < 100; i++ {
+		headReply = HeadReply{}
+		err = server.RpcHead(&headRequest, &headReply)
+		assert.Nil(err)
+		if expectedETag == headReply.ETag {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(expectedETag, headReply.ETag)
+
+	getObjectRequest := GetObjectReq{VirtPath: containerPath + "/obj"}
+	getObjectReply := GetObjectReply{}
+	err = server.RpcGetObject(&getObjectRequest, &getObjectReply)
+	assert.Nil(err)
+	assert.Equal(expectedETag, getObjectReply.ETag)
+
+	getContainerRequest := GetContainerReq{
+		VirtPath:   containerPath,
+		MaxEntries: 1,
+	}
+	getContainerReply := GetContainerReply{}
+	err = server.RpcGetContainer(&getContainerRequest, &getContainerReply)
+	assert.Nil(err)
+	assert.Equal(1, len(getContainerReply.ContainerEntries))
+	assert.Equal(expectedETag, getContainerReply.ContainerEntries[0].ETag)
+
+	// The next write invalidates the ETag
+	_, err = mountHandle.Write(inode.InodeRootUserID, inode.InodeGroupID(0), nil, fileInode, 0, []byte("yellow"), nil)
+	if err != nil {
+		panic(err)
+	}
+
+	headReply = HeadReply{}
+	err = server.RpcHead(&headRequest, &headReply)
+	assert.Nil(err)
+	assert.Equal("", headReply.ETag)
+}
+
+func TestRpcVersioning(t *testing.T) {
+	server := &Server{}
+	assert := assert.New(t)
+	mountHandle, err := fs.MountByVolumeName("SomeVolume", fs.MountOptions(0))
+	if nil != err {
+		panic(fmt.Sprintf("failed to mount SomeVolume: %v", err))
+	}
+
+	containerName := "rpc-versioning-palimpsestic-quire"
+	containerPath := testVerAccountName + "/" + containerName
+	objPath := containerPath + "/sub/obj"
+	fsMkDir(mountHandle, inode.RootDirInodeNumber, containerName)
+
+	setVersioning := func(mode string, maxVersions uint64) {
+		setVersioningRequest := SetVersioningReq{
+			VirtPath:    containerPath,
+			Mode:        mode,
+			MaxVersions: maxVersions,
+		}
+		setVersioningReply := SetVersioningReply{}
+		err := server.RpcSetVersioning(&setVersioningRequest, &setVersioningReply)
+		assert.Nil(err)
+	}
+
+	readContents := func(inodeNumber inode.InodeNumber) string {
+		contents, err := mountHandle.Read(inode.InodeRootUserID, inode.InodeGroupID(0), nil, inodeNumber, 0, 99999, nil)
+		assert.Nil(err)
+		return string(contents)
+	}
+
+	currentContents := func() string {
+		objInode, err := mountHandle.LookupPath(inode.InodeRootUserID, inode.InodeGroupID(0), nil, containerName+"/sub/obj")
+		assert.Nil(err)
+		return readContents(objInode)
+	}
+
+	listVersions := func() (versionIDs []string, versionContents []string) {
+		listVersionsRequest := ListVersionsReq{VirtPath: objPath}
+		listVersionsReply := ListVersionsReply{}
+		err := server.RpcListVersions(&listVersionsRequest, &listVersionsReply)
+		assert.Nil(err)
+		for _, version := range listVersionsReply.Versions {
+			versionIDs = append(versionIDs, version.VersionID)
+			versionContents = append(versionContents, readContents(inode.InodeNumber(version.InodeNumber)))
+			assert.Equal(uint64(len(versionContents[len(versionContents)-1])), version.FileSize)
+			assert.Equal([]byte("metadata"), version.Metadata)
+		}
+		return
+	}
+
+	put := func(contents string) {
+		err := putFileInSwift(server, objPath, []byte(contents), []byte("metadata"))
+		assert.Nil(err)
+	}
+
+	deleteObj := func() {
+		deleteRequest := DeleteReq{VirtPath: objPath}
+		deleteReply := DeleteReply{}
+		err := server.RpcDelete(&deleteRequest, &deleteReply)
+		assert.Nil(err)
+	}
+
+	// Versioning is configured per container (and only with known modes)
+	setVersioningRequest := SetVersioningReq{VirtPath: objPath, Mode: fs.VersioningModeHistory}
+	setVersioningReply := SetVersioningReply{}
+	err = server.RpcSetVersioning(&setVersioningRequest, &setVersioningReply)
+	assert.Equal(fmt.Sprintf("errno: %d", blunder.InvalidArgError), err.Error())
+
+	setVersioningRequest = SetVersioningReq{VirtPath: containerPath, Mode: "sideways"}
+	err = server.RpcSetVersioning(&setVersioningRequest, &setVersioningReply)
+	assert.Equal(fmt.Sprintf("errno: %d", blunder.InvalidArgError), err.Error())
+
+	// Without versioning, nothing is retained
+	put("zero")
+	put("one")
+	versionIDs, _ := listVersions()
+	assert.Equal(0, len(versionIDs))
+
+	// History mode retains replaced (and deleted) versions... up to MaxVersions
+	setVersioning(fs.VersioningModeHistory, 2)
+
+	put("two")
+	put("three")
+	_, versionContents := listVersions()
+	assert.Equal([]string{"one", "two"}, versionContents)
+
+	put("four")
+	versionIDs, versionContents = listVersions()
+	assert.Equal([]string{"two", "three"}, versionContents)
+	assert.Equal("four", currentContents())
+
+	restoreVersionRequest := RestoreVersionReq{VirtPath: objPath, VersionID: versionIDs[0]}
+	restoreVersionReply := RestoreVersionReply{}
+	err = server.RpcRestoreVersion(&restoreVersionRequest, &restoreVersionReply)
+	assert.Nil(err)
+	assert.Equal("two", currentContents())
+	_, versionContents = listVersions()
+	assert.Equal([]string{"three", "four"}, versionContents)
+
+	err = server.RpcRestoreVersion(&restoreVersionRequest, &restoreVersionReply)
+	assert.Equal(fmt.Sprintf("errno: %d", blunder.NotFoundError), err.Error())
+
+	deleteObj()
+	headRequest := HeadReq{VirtPath: objPath}
+	headReply := HeadReply{}
+	err = server.RpcHead(&headRequest, &headReply)
+	assert.Equal(fmt.Sprintf("errno: %d", blunder.NotFoundError), err.Error())
+	_, versionContents = listVersions()
+	assert.Equal([]string{"four", "two"}, versionContents)
+
+	// Stack mode restores the most recent prior version upon delete
+	setVersioning(fs.VersioningModeStack, 0)
+
+	put("five")
+	_, versionContents = listVersions()
+	assert.Equal([]string{"four", "two"}, versionContents)
+
+	deleteObj()
+	assert.Equal("two", currentContents())
+	_, versionContents = listVersions()
+	assert.Equal([]string{"four"}, versionContents)
+
+	// Disabling versioning leaves existing versions alone
+	setVersioning(fs.VersioningModeNone, 0)
+
+	put("six")
+	assert.Equal("six", currentContents())
+	_, versionContents = listVersions()
+	assert.Equal([]string{"four"}, versionContents)
+
+	// The hidden versions directory is not a container
+	getAccountRequest := GetAccountReq{VirtPath: testVerAccountName}
+	getAccountReply := GetAccountReply{}
+	err = server.RpcGetAccount(&getAccountRequest, &getAccountReply)
+	assert.Nil(err)
+	for _, accountEntry := range getAccountReply.AccountEntries {
+		assert.NotEqual(fs.VersionsDirName, accountEntry.Basename)
+	}
+
+	putContainerRequest := PutContainerReq{VirtPath: testVerAccountName + "/" + fs.VersionsDirName}
+	putContainerReply := PutContainerReply{}
+	err = server.RpcPutContainer(&putContainerRequest, &putContainerReply)
+	assert.Equal(fmt.Sprintf("errno: %d", blunder.PermDeniedError), err.Error())
+}
+
+func TestRpcSetExpiration(t *testing.T) {
+	server := &Server{}
+	assert := assert.New(t)
+	mountHandle, err := fs.MountByVolumeName("SomeVolume", fs.MountOptions(0))
+	if nil != err {
+		panic(fmt.Sprintf("failed to mount SomeVolume: %v", err))
+	}
+
+	containerName := "rpc-expiration-evanescent-ephemeris"
+	containerPath := testVerAccountName + "/" + containerName
+	objPath := containerPath + "/obj"
+	fsMkDir(mountHandle, inode.RootDirInodeNumber, containerName)
+
+	err = putFileInSwift(server, objPath, []byte("fleeting"), []byte("metadata"))
+	assert.Nil(err)
+
+	setExpiration := func(virtPath string, expireAt uint64) error {
+		setExpirationRequest := SetExpirationReq{VirtPath: virtPath, ExpireAt: expireAt}
+		setExpirationReply := SetExpirationReply{}
+		return server.RpcSetExpiration(&setExpirationRequest, &setExpirationReply)
+	}
+
+	head := func() (headReply HeadReply, err error) {
+		headRequest := HeadReq{VirtPath: objPath}
+		err = server.RpcHead(&headRequest, &headReply)
+		return
+	}
+
+	// Expiration only applies to objects
+	err = setExpiration(containerPath, uint64(time.Now().Unix())+3600)
+	assert.NotNil(err)
+
+	// An unexpired object reports when it will expire
+	expireAt := uint64(time.Now().Unix()) + 3600
+	err = setExpiration(objPath, expireAt)
+	assert.Nil(err)
+	headReply, err := head()
+	assert.Nil(err)
+	assert.Equal(expireAt, headReply.ExpireAt)
+
+	getObjectRequest := GetObjectReq{VirtPath: objPath}
+	getObjectReply := GetObjectReply{}
+	err = server.RpcGetObject(&getObjectRequest, &getObjectReply)
+	assert.Nil(err)
+	assert.Equal(expireAt, getObjectReply.ExpireAt)
+
+	// An expired object is missing
+	err = setExpiration(objPath, uint64(time.Now().Unix())-1)
+	assert.Nil(err)
+	_, err = head()
+	assert.NotNil(err)
+	assert.Equal(fmt.Sprintf("errno: %d", blunder.NotFoundError), err.Error())
+
+	getObjectReply = GetObjectReply{}
+	err = server.RpcGetObject(&getObjectRequest, &getObjectReply)
+	assert.NotNil(err)
+	assert.Equal(fmt.Sprintf("errno: %d", blunder.NotFoundError), err.Error())
+}
@@ -0,0 +1,217 @@
+// Package jrpcfsclient provides a typed Go client for the JSON RPC interface
+// exported by package jrpcfs.
+//
+// A Client maintains a pool of connections to a ProxyFS JSONRPCServer.TCPPort
+// (over which the Server.Rpc* methods are invoked) and, optionally, a pool of
+// connections to its JSONRPCServer.FastTCPPort (over which file data is read
+// and written). Connections are established lazily and are transparently
+// re-established should the underlying TCP connection fail.
+//
+// The jrpcfs JSON RPC conveys InodeNumbers as int64's (see the discussion in
+// jrpcfs/api.go). This package performs that conversion internally such that
+// callers deal exclusively in inode.InodeNumber's (i.e. uint64's).
+//
+// Errors returned by the server (which jrpcfs encodes as "errno: <n>") are
+// converted back into blunder-annotated errors such that blunder.Is() and
+// blunder.Errno() may be applied to them.
+package jrpcfsclient
+
+import (
+	"time"
+
+	"github.com/swiftstack/ProxyFS/fs"
+	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/jrpcfs"
+)
+
+// ConfigStruct is used to specify the ProxyFS JSON RPC Server a Client will connect to.
+type ConfigStruct struct {
+	IPAddr                 string        // Peer:<WhoAmI>.PrivateIPAddr (or VolumeGroup:<name>.VirtualIPAddr)
+	TCPPort                uint16        // JSONRPCServer.TCPPort
+	FastTCPPort            uint16        // JSONRPCServer.FastTCPPort; if zero, Read() & Write() are unavailable
+	ConnectionPoolSize     uint16        // Number of TCPPort connections multiplexing RPCs; if zero, 1 is used
+	FastConnectionPoolSize uint16        // Maximum number of idle FastTCPPort connections retained for reuse
+	Timeout                time.Duration // Per-request limit; if zero, requests will not time out
+	RetryLimit             uint16        // Number of times to retry a request failing due to a connection error (see call())
+	RetryDelay             time.Duration // Delay between retries
+}
+
+// Client is used to issue requests to a ProxyFS JSON RPC Server. It is safe
+// for concurrent use by multiple goroutines.
+type Client struct {
+	clientStruct
+}
+
+// Mount is returned by MountByAccountName() and MountByVolumeName() and is used
+// to issue requests against the mounted volume.
+type Mount struct {
+	client             *Client
+	mountID            jrpcfs.MountIDAsString
+	mountIDAsByteArray jrpcfs.MountIDAsByteArray
+	rootDirInodeNumber inode.InodeNumber
+}
+
+// StatStruct mirrors jrpcfs.StatStruct with StatInodeNumber converted to an inode.InodeNumber.
+//
+// Note that times are conveyed as nanoseconds since epoch.
+//
+type StatStruct struct {
+	CTimeNs     uint64
+	CRTimeNs    uint64
+	MTimeNs     uint64
+	ATimeNs     uint64
+	Size        uint64
+	NumLinks    uint64
+	InodeNumber inode.InodeNumber
+	FileMode    uint32
+	UserID      uint32
+	GroupID     uint32
+}
+
+// DirEntryStruct mirrors jrpcfs.DirEntry with InodeNumber converted to an inode.InodeNumber.
+//
+// FileType here will be a uint16 containing DT_DIR|DT_REG|DT_LNK.
+//
+type DirEntryStruct struct {
+	InodeNumber     inode.InodeNumber
+	FileType        uint16
+	Basename        string
+	NextDirLocation int64
+}
+
+// NotifyEventStruct mirrors jrpcfs.NotifyEvent with InodeNumbers converted to inode.InodeNumber's.
+type NotifyEventStruct struct {
+	Sequence          uint64
+	Type              fs.NotifyEventType
+	DirInodeNumber    inode.InodeNumber
+	Basename          string
+	InodeNumber       inode.InodeNumber
+	NewDirInodeNumber inode.InodeNumber // Only set for fs.NotifyRename
+	NewBasename       string            // Only set for fs.NotifyRename
+}
+
+// TrashEntryStruct mirrors jrpcfs.TrashEntry with InodeNumbers converted to inode.InodeNumber's.
+//
+// FileType here will be a uint16 containing DT_DIR|DT_REG|DT_LNK.
+//
+type TrashEntryStruct struct {
+	TrashID        string
+	TrashUserID    int32
+	DirInodeNumber inode.InodeNumber
+	Basename       string
+	Path           string
+	DeleteTime     uint64 // nanoseconds since epoch
+	InodeNumber    inode.InodeNumber
+	FileType       uint16
+	FileSize       uint64
+}
+
+// NewClient returns a Client for the JSON RPC Server described by config. No
+// connections are established until the first request is issued.
+func NewClient(config *ConfigStruct) (client *Client, err error) {
+	client, err = newClient(config)
+	return
+}
+
+// Close shuts down all connections held by the Client. Requests issued after
+// Close() has been called will fail.
+func (client *Client) Close() (err error) {
+	err = client.close()
+	return
+}
+
+// Ping invokes RpcPing, returning the server's reply message.
+func (client *Client) Ping(message string) (replyMessage string, err error) {
+	var (
+		reply *jrpcfs.PingReply
+	)
+
+	reply = &jrpcfs.PingReply{}
+
+	err = client.call("RpcPing", &jrpcfs.PingReq{Message: message}, reply)
+	if nil == err {
+		replyMessage = reply.Message
+	}
+
+	return
+}
+
+// Log invokes RpcLog, asking the server to log message.
+func (client *Client) Log(message string) (err error) {
+	err = client.call("RpcLog", &jrpcfs.LogRequest{Message: message}, &jrpcfs.Reply{})
+	return
+}
+
+// MountByAccountName invokes RpcMountByAccountName.
+func (client *Client) MountByAccountName(accountName string, mountOptions uint64, authUserID uint64, authGroupID uint64) (mount *Mount, err error) {
+	var (
+		reply   *jrpcfs.MountByAccountNameReply
+		request *jrpcfs.MountByAccountNameRequest
+	)
+
+	request = &jrpcfs.MountByAccountNameRequest{
+		AccountName:  accountName,
+		MountOptions: mountOptions,
+		AuthUserID:   authUserID,
+		AuthGroupID:  authGroupID,
+	}
+	reply = &jrpcfs.MountByAccountNameReply{}
+
+	err = client.call("RpcMountByAccountName", request, reply)
+	if nil != err {
+		return
+	}
+
+	mount, err = client.newMount(reply.MountID, reply.RootDirInodeNumber)
+
+	return
+}
+
+// MountByVolumeName invokes RpcMountByVolumeName.
+func (client *Client) MountByVolumeName(volumeName string, mountOptions uint64, authUserID uint64, authGroupID uint64) (mount *Mount, err error) {
+	var (
+		reply   *jrpcfs.MountByVolumeNameReply
+		request *jrpcfs.MountByVolumeNameRequest
+	)
+
+	request = &jrpcfs.MountByVolumeNameRequest{
+		VolumeName:   volumeName,
+		MountOptions: mountOptions,
+		AuthUserID:   authUserID,
+		AuthGroupID:  authGroupID,
+	}
+	reply = &jrpcfs.MountByVolumeNameReply{}
+
+	err = client.call("RpcMountByVolumeName", request, reply)
+	if nil != err {
+		return
+	}
+
+	mount, err = client.newMount(reply.MountID, reply.RootDirInodeNumber)
+
+	return
+}
+
+// MountID returns the MountID assigned by the server to this Mount.
+func (mount *Mount) MountID() (mountID jrpcfs.MountIDAsString) {
+	mountID = mount.mountID
+	return
+}
+
+// RootDirInodeNumber returns the InodeNumber of the root directory of the mounted volume.
+func (mount *Mount) RootDirInodeNumber() (rootDirInodeNumber inode.InodeNumber) {
+	rootDirInodeNumber = mount.rootDirInodeNumber
+	return
+}
+
+// InodeNumberToInt64 converts an inode.InodeNumber to the int64 form used by the jrpcfs JSON RPC.
+func InodeNumberToInt64(inodeNumber inode.InodeNumber) (inodeNumberAsInt64 int64) {
+	inodeNumberAsInt64 = int64(uint64(inodeNumber))
+	return
+}
+
+// Int64ToInodeNumber converts the int64 form of an InodeNumber used by the jrpcfs JSON RPC to an inode.InodeNumber.
+func Int64ToInodeNumber(inodeNumberAsInt64 int64) (inodeNumber inode.InodeNumber) {
+	inodeNumber = inode.InodeNumber(uint64(inodeNumberAsInt64))
+	return
+}
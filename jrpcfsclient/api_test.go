@@ -0,0 +1,610 @@
+package jrpcfsclient
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/fs"
+	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/jrpcfs"
+)
+
+func TestAPI(t *testing.T) {
+	var (
+		client          *Client
+		dirEnts         []DirEntryStruct
+		dirInodeNumber  inode.InodeNumber
+		err             error
+		events          []NotifyEventStruct
+		fileInodeNumber inode.InodeNumber
+		lookupNumber    inode.InodeNumber
+		mount           *Mount
+		readBuf         []byte
+		replyMessage    string
+		stat            *StatStruct
+		stats           []StatStruct
+		subscriptionID  fs.NotifySubscriptionID
+		writeBuf        []byte
+		writeSize       uint64
+	)
+
+	testSetup(t)
+
+	client = testNewClient(t)
+
+	replyMessage, err = client.Ping("hello")
+	if nil != err {
+		t.Fatalf("Ping() failed: %v", err)
+	}
+	if "pong 5 bytes" != replyMessage {
+		t.Fatalf("Ping() returned unexpected replyMessage: \"%s\"", replyMessage)
+	}
+
+	mount, err = client.MountByVolumeName(testVolumeName, 0, 0, 0)
+	if nil != err {
+		t.Fatalf("MountByVolumeName() failed: %v", err)
+	}
+	if inode.RootDirInodeNumber != mount.RootDirInodeNumber() {
+		t.Fatalf("MountByVolumeName() returned unexpected RootDirInodeNumber: %v", mount.RootDirInodeNumber())
+	}
+
+	dirInodeNumber, err = mount.Mkdir(mount.RootDirInodeNumber(), "TestDir", 0, 0, uint32(inode.PosixModePerm))
+	if nil != err {
+		t.Fatalf("Mkdir() failed: %v", err)
+	}
+
+	subscriptionID, err = mount.NotifySubscribe(dirInodeNumber, false, fs.NotifyCreate|fs.NotifyUnlink)
+	if nil != err {
+		t.Fatalf("NotifySubscribe() failed: %v", err)
+	}
+
+	fileInodeNumber, err = mount.Create(dirInodeNumber, "TestFile", 0, 0, uint32(inode.PosixModePerm))
+	if nil != err {
+		t.Fatalf("Create() failed: %v", err)
+	}
+
+	lookupNumber, err = mount.LookupPath("/TestDir/TestFile")
+	if nil != err {
+		t.Fatalf("LookupPath() failed: %v", err)
+	}
+	if fileInodeNumber != lookupNumber {
+		t.Fatalf("LookupPath() returned unexpected InodeNumber")
+	}
+
+	_, err = mount.Lookup(dirInodeNumber, "NoSuchFile")
+	if nil == err {
+		t.Fatalf("Lookup() of missing file should have failed")
+	}
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("Lookup() of missing file returned unexpected error: %v", err)
+	}
+
+	writeBuf = []byte("Some file data")
+
+	writeSize, err = mount.Write(fileInodeNumber, 0, writeBuf)
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+	if uint64(len(writeBuf)) != writeSize {
+		t.Fatalf("Write() returned unexpected size: %v", writeSize)
+	}
+
+	err = mount.Flush(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("Flush() failed: %v", err)
+	}
+
+	readBuf, err = mount.Read(fileInodeNumber, 5, 4)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if !bytes.Equal(writeBuf[5:9], readBuf) {
+		t.Fatalf("Read() returned unexpected data: \"%s\"", string(readBuf))
+	}
+
+	stat, err = mount.GetStat(fileInodeNumber)
+	if nil != err {
+		t.Fatalf("GetStat() failed: %v", err)
+	}
+	if (fileInodeNumber != stat.InodeNumber) || (uint64(len(writeBuf)) != stat.Size) {
+		t.Fatalf("GetStat() returned unexpected stat: %+v", stat)
+	}
+
+	dirEnts, stats, err = mount.ReaddirPlus(dirInodeNumber, 10, "")
+	if nil != err {
+		t.Fatalf("ReaddirPlus() failed: %v", err)
+	}
+	if (3 != len(dirEnts)) || (3 != len(stats)) {
+		t.Fatalf("ReaddirPlus() returned unexpected number of entries: %d", len(dirEnts))
+	}
+	if ("TestFile" != dirEnts[2].Basename) || (fileInodeNumber != dirEnts[2].InodeNumber) || (fileInodeNumber != stats[2].InodeNumber) {
+		t.Fatalf("ReaddirPlus() returned unexpected entry: %+v", dirEnts[2])
+	}
+
+	err = mount.SetXAttr(fileInodeNumber, "TestXAttr", []byte("TestXAttrValue"), 0)
+	if nil != err {
+		t.Fatalf("SetXAttr() failed: %v", err)
+	}
+	readBuf, err = mount.GetXAttrPath("/TestDir/TestFile", "TestXAttr")
+	if nil != err {
+		t.Fatalf("GetXAttrPath() failed: %v", err)
+	}
+	if "TestXAttrValue" != string(readBuf) {
+		t.Fatalf("GetXAttrPath() returned unexpected value: \"%s\"", string(readBuf))
+	}
+
+	err = mount.Unlink(dirInodeNumber, "TestFile")
+	if nil != err {
+		t.Fatalf("Unlink() failed: %v", err)
+	}
+
+	events, _, err = mount.NotifyFetch(subscriptionID, 0, time.Second)
+	if nil != err {
+		t.Fatalf("NotifyFetch() failed: %v", err)
+	}
+	if (2 != len(events)) || (fs.NotifyCreate != events[0].Type) || (fs.NotifyUnlink != events[1].Type) {
+		t.Fatalf("NotifyFetch() returned unexpected events: %+v", events)
+	}
+	if (dirInodeNumber != events[0].DirInodeNumber) || ("TestFile" != events[0].Basename) || (fileInodeNumber != events[0].InodeNumber) {
+		t.Fatalf("NotifyFetch() returned unexpected event: %+v", events[0])
+	}
+
+	err = mount.NotifyUnsubscribe(subscriptionID)
+	if nil != err {
+		t.Fatalf("NotifyUnsubscribe() failed: %v", err)
+	}
+	err = mount.RmdirPath("/TestDir")
+	if nil != err {
+		t.Fatalf("RmdirPath() failed: %v", err)
+	}
+
+	err = client.Close()
+	if nil != err {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	_, err = client.Ping("closed")
+	if nil == err {
+		t.Fatalf("Ping() after Close() should have failed")
+	}
+
+	testTeardown(t)
+}
+
+func TestAPIFileRanges(t *testing.T) {
+	var (
+		client             *Client
+		copySize           uint64
+		dstFileInodeNumber inode.InodeNumber
+		err                error
+		fileInodeNumber    inode.InodeNumber
+		lookupNumber       inode.InodeNumber
+		mount              *Mount
+		readBuf            []byte
+		seekOffset         uint64
+		trashEntries       []TrashEntryStruct
+	)
+
+	testSetup(t)
+
+	client = testNewClient(t)
+
+	mount, err = client.MountByVolumeName(testVolumeName, 0, 0, 0)
+	if nil != err {
+		t.Fatalf("MountByVolumeName() failed: %v", err)
+	}
+
+	fileInodeNumber, err = mount.Create(mount.RootDirInodeNumber(), "SrcFile", 0, 0, uint32(inode.PosixModePerm))
+	if nil != err {
+		t.Fatalf("Create(\"SrcFile\") failed: %v", err)
+	}
+	_, err = mount.Write(fileInodeNumber, 0, []byte("ABCDEFGH"))
+	if nil != err {
+		t.Fatalf("Write() failed: %v", err)
+	}
+
+	// Punch a hole in the middle of SrcFile leaving its size unchanged
+
+	err = mount.Fallocate(fileInodeNumber, uint32(inode.FallocatePunchHole|inode.FallocateKeepSize), 2, 4)
+	if nil != err {
+		t.Fatalf("Fallocate() failed: %v", err)
+	}
+	readBuf, err = mount.Read(fileInodeNumber, 0, 8)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if !bytes.Equal([]byte("AB\x00\x00\x00\x00GH"), readBuf) {
+		t.Fatalf("Read() after Fallocate() returned unexpected data: %v", readBuf)
+	}
+
+	seekOffset, err = mount.Seek(fileInodeNumber, 0, jrpcfs.SeekHole)
+	if nil != err {
+		t.Fatalf("Seek(,,SeekHole) failed: %v", err)
+	}
+	if 2 != seekOffset {
+		t.Fatalf("Seek(,,SeekHole) returned %v... expected 2", seekOffset)
+	}
+	seekOffset, err = mount.Seek(fileInodeNumber, 2, jrpcfs.SeekData)
+	if nil != err {
+		t.Fatalf("Seek(,,SeekData) failed: %v", err)
+	}
+	if 6 != seekOffset {
+		t.Fatalf("Seek(,,SeekData) returned %v... expected 6", seekOffset)
+	}
+	_, err = mount.Seek(fileInodeNumber, 0, 0)
+	if !blunder.Is(err, blunder.InvalidArgError) {
+		t.Fatalf("Seek() with invalid whence returned unexpected error: %v", err)
+	}
+
+	// Copy the tail of SrcFile to DstFile
+
+	dstFileInodeNumber, err = mount.Create(mount.RootDirInodeNumber(), "DstFile", 0, 0, uint32(inode.PosixModePerm))
+	if nil != err {
+		t.Fatalf("Create(\"DstFile\") failed: %v", err)
+	}
+	copySize, err = mount.CopyFileRange(fileInodeNumber, 6, dstFileInodeNumber, 0, 2)
+	if nil != err {
+		t.Fatalf("CopyFileRange() failed: %v", err)
+	}
+	if 2 != copySize {
+		t.Fatalf("CopyFileRange() returned %v... expected 2", copySize)
+	}
+	readBuf, err = mount.Read(dstFileInodeNumber, 0, 2)
+	if nil != err {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if "GH" != string(readBuf) {
+		t.Fatalf("Read() after CopyFileRange() returned unexpected data: \"%s\"", string(readBuf))
+	}
+
+	// Trash DstFile (TestVolume has TrashEnabled) and restore it to where it was deleted from
+
+	err = mount.Unlink(mount.RootDirInodeNumber(), "DstFile")
+	if nil != err {
+		t.Fatalf("Unlink() failed: %v", err)
+	}
+	_, err = mount.LookupPath("/DstFile")
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("LookupPath() of trashed file returned unexpected error: %v", err)
+	}
+
+	trashEntries, err = mount.TrashList(0, 0)
+	if nil != err {
+		t.Fatalf("TrashList() failed: %v", err)
+	}
+	if 1 != len(trashEntries) {
+		t.Fatalf("TrashList() returned %v entries... expected 1", len(trashEntries))
+	}
+	if ("DstFile" != trashEntries[0].Basename) || (mount.RootDirInodeNumber() != trashEntries[0].DirInodeNumber) || (dstFileInodeNumber != trashEntries[0].InodeNumber) || (2 != trashEntries[0].FileSize) {
+		t.Fatalf("TrashList() returned unexpected entry: %+v", trashEntries[0])
+	}
+
+	err = mount.TrashRestore(0, 0, trashEntries[0].TrashUserID, trashEntries[0].TrashID, "")
+	if nil != err {
+		t.Fatalf("TrashRestore() failed: %v", err)
+	}
+	lookupNumber, err = mount.LookupPath("/DstFile")
+	if nil != err {
+		t.Fatalf("LookupPath() of restored file failed: %v", err)
+	}
+	if dstFileInodeNumber != lookupNumber {
+		t.Fatalf("LookupPath() of restored file returned unexpected InodeNumber")
+	}
+
+	trashEntries, err = mount.TrashList(0, 0)
+	if nil != err {
+		t.Fatalf("TrashList() failed: %v", err)
+	}
+	if 0 != len(trashEntries) {
+		t.Fatalf("TrashList() after TrashRestore() returned %v entries... expected 0", len(trashEntries))
+	}
+
+	err = client.Close()
+	if nil != err {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	testTeardown(t)
+}
+
+func TestAPIMiddleware(t *testing.T) {
+	var (
+		client          *Client
+		containerPath   string = "/v1/" + testAccountName + "/TestContainer"
+		copyReply       *jrpcfs.CopyReply
+		err             error
+		fileInodeNumber inode.InodeNumber
+		mount           *Mount
+		versions        []fs.VersionEntry
+	)
+
+	testSetup(t)
+
+	client = testNewClient(t)
+
+	mount, err = client.MountByVolumeName(testVolumeName, 0, 0, 0)
+	if nil != err {
+		t.Fatalf("MountByVolumeName() failed: %v", err)
+	}
+
+	err = client.CreateContainer(containerPath)
+	if nil != err {
+		t.Fatalf("CreateContainer() failed: %v", err)
+	}
+
+	createObject := func(basename string, contents string) {
+		fileInodeNumber, err := mount.CreatePath("/TestContainer/"+basename, 0, 0, uint32(inode.PosixModePerm))
+		if nil != err {
+			t.Fatalf("CreatePath(\"%s\") failed: %v", basename, err)
+		}
+		_, err = mount.Write(fileInodeNumber, 0, []byte(contents))
+		if nil != err {
+			t.Fatalf("Write(\"%s\") failed: %v", basename, err)
+		}
+	}
+
+	verifyObject := func(basename string, expectedContents string) {
+		fileInodeNumber, err := mount.LookupPath("/TestContainer/" + basename)
+		if nil != err {
+			t.Fatalf("LookupPath(\"%s\") failed: %v", basename, err)
+		}
+		readBuf, err := mount.Read(fileInodeNumber, 0, 1024)
+		if nil != err {
+			t.Fatalf("Read(\"%s\") failed: %v", basename, err)
+		}
+		if expectedContents != string(readBuf) {
+			t.Fatalf("Read(\"%s\") returned \"%s\"... expected \"%s\"", basename, string(readBuf), expectedContents)
+		}
+	}
+
+	// Copy an object within the account
+
+	createObject("Src", "copied")
+
+	copyReply, err = client.Copy(containerPath+"/Dst", "TestContainer/Src", []byte("TestMetadata"))
+	if nil != err {
+		t.Fatalf("Copy() failed: %v", err)
+	}
+	fileInodeNumber, err = mount.LookupPath("/TestContainer/Dst")
+	if nil != err {
+		t.Fatalf("LookupPath() of copied object failed: %v", err)
+	}
+	if fileInodeNumber != Int64ToInodeNumber(copyReply.InodeNumber) {
+		t.Fatalf("Copy() returned unexpected InodeNumber")
+	}
+	verifyObject("Dst", "copied")
+
+	// Archive an object by deleting it from a versioned container, then restore it
+
+	err = client.SetVersioning(containerPath, fs.VersioningModeHistory, 0)
+	if nil != err {
+		t.Fatalf("SetVersioning() failed: %v", err)
+	}
+	err = client.SetVersioning(containerPath+"/Src", fs.VersioningModeHistory, 0)
+	if !blunder.Is(err, blunder.InvalidArgError) {
+		t.Fatalf("SetVersioning() of an object returned unexpected error: %v", err)
+	}
+
+	createObject("Obj", "one")
+
+	err = client.Delete(containerPath + "/Obj")
+	if nil != err {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+
+	versions, err = client.ListVersions(containerPath + "/Obj")
+	if nil != err {
+		t.Fatalf("ListVersions() failed: %v", err)
+	}
+	if (1 != len(versions)) || (3 != versions[0].FileSize) {
+		t.Fatalf("ListVersions() returned unexpected versions: %+v", versions)
+	}
+
+	createObject("Obj", "two")
+
+	err = client.RestoreVersion(containerPath+"/Obj", versions[0].VersionID)
+	if nil != err {
+		t.Fatalf("RestoreVersion() failed: %v", err)
+	}
+	verifyObject("Obj", "one")
+
+	err = client.RestoreVersion(containerPath+"/Obj", "0000000000000001")
+	if nil == err {
+		t.Fatalf("RestoreVersion() of a missing version should have failed")
+	}
+
+	// An object that has expired is reported as missing until its expiration is cleared
+
+	err = client.SetExpiration(containerPath+"/Src", 1)
+	if nil != err {
+		t.Fatalf("SetExpiration() failed: %v", err)
+	}
+	_, err = client.Head(containerPath + "/Src")
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("Head() of expired object returned unexpected error: %v", err)
+	}
+
+	err = client.SetExpiration(containerPath+"/Src", 0)
+	if nil != err {
+		t.Fatalf("SetExpiration() failed: %v", err)
+	}
+	_, err = client.Head(containerPath + "/Src")
+	if nil != err {
+		t.Fatalf("Head() of unexpired object failed: %v", err)
+	}
+
+	err = client.SetExpiration(containerPath, 1)
+	if !blunder.Is(err, blunder.NotAnObjectError) {
+		t.Fatalf("SetExpiration() of a container returned unexpected error: %v", err)
+	}
+
+	err = client.Close()
+	if nil != err {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	testTeardown(t)
+}
+
+func TestInodeNumberConversion(t *testing.T) {
+	var (
+		inodeNumber inode.InodeNumber
+	)
+
+	inodeNumber = inode.InodeNumber(0xFEDCBA9876543210)
+
+	if -81985529216486896 != InodeNumberToInt64(inodeNumber) {
+		t.Fatalf("InodeNumberToInt64() returned unexpected value")
+	}
+	if inodeNumber != Int64ToInodeNumber(InodeNumberToInt64(inodeNumber)) {
+		t.Fatalf("Int64ToInodeNumber(InodeNumberToInt64()) did not round-trip")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var (
+		client       *Client
+		err          error
+		listener     net.Listener
+		numRequests  uint64
+		portAsString string
+		portAsUint64 uint64
+		retryLimit   uint16 = 2
+	)
+
+	// Each connection is dropped once a request has been received (but not replied to)
+
+	listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatalf("net.Listen() failed: %v", err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if nil != err {
+				return
+			}
+			_, err = bufio.NewReader(conn).ReadBytes('\n')
+			if nil == err {
+				atomic.AddUint64(&numRequests, 1)
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	_, portAsString, err = net.SplitHostPort(listener.Addr().String())
+	if nil != err {
+		t.Fatalf("net.SplitHostPort() failed: %v", err)
+	}
+	portAsUint64, err = strconv.ParseUint(portAsString, 10, 16)
+	if nil != err {
+		t.Fatalf("strconv.ParseUint() failed: %v", err)
+	}
+
+	client, err = NewClient(&ConfigStruct{
+		IPAddr:     testIPAddr,
+		TCPPort:    uint16(portAsUint64),
+		Timeout:    10 * time.Second,
+		RetryLimit: retryLimit,
+		RetryDelay: 10 * time.Millisecond,
+	})
+	if nil != err {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+
+	// An idempotent request is retried
+
+	_, err = client.Ping("retried")
+	if nil == err {
+		t.Fatalf("Ping() should have failed")
+	}
+	if uint64(1+retryLimit) != atomic.LoadUint64(&numRequests) {
+		t.Fatalf("Ping() sent %v requests... expected %v", atomic.LoadUint64(&numRequests), 1+retryLimit)
+	}
+
+	// A request that may have been performed by the server is not
+
+	atomic.StoreUint64(&numRequests, 0)
+
+	err = client.CreateContainer("/v1/" + testAccountName + "/NotRetried")
+	if nil == err {
+		t.Fatalf("CreateContainer() should have failed")
+	}
+	if 1 != atomic.LoadUint64(&numRequests) {
+		t.Fatalf("CreateContainer() sent %v requests... expected 1", atomic.LoadUint64(&numRequests))
+	}
+
+	err = client.Close()
+	if nil != err {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	_ = listener.Close()
+}
+
+func TestMarshaling(t *testing.T) {
+	var (
+		pingReplyOutput *jrpcfs.PingReply
+		pingReqBuf      []byte
+		pingReqOutput   *jrpcfs.PingReq
+		pingReplyBuf    []byte
+		requestID       uint64
+		requestMethod   string
+		responseErr     error
+		err             error
+	)
+
+	pingReqBuf, err = MarshalRequest(17, "Server.RpcPing", &jrpcfs.PingReq{Message: "TestMessage"})
+	if nil != err {
+		t.Fatalf("MarshalRequest() failed: %v", err)
+	}
+
+	requestMethod, requestID, err = UnmarshalRequestForMethodAndID(pingReqBuf)
+	if nil != err {
+		t.Fatalf("UnmarshalRequestForMethodAndID() failed: %v", err)
+	}
+	if ("Server.RpcPing" != requestMethod) || (17 != requestID) {
+		t.Fatalf("UnmarshalRequestForMethodAndID() returned unexpected method/ID")
+	}
+
+	pingReqOutput = &jrpcfs.PingReq{}
+
+	err = UnmarshalRequest(requestID, pingReqBuf, pingReqOutput)
+	if nil != err {
+		t.Fatalf("UnmarshalRequest() failed: %v", err)
+	}
+	if "TestMessage" != pingReqOutput.Message {
+		t.Fatalf("UnmarshalRequest() returned unexpected Message")
+	}
+
+	pingReplyBuf, err = MarshalResponse(requestID, fmt.Errorf("errno: 2"), &jrpcfs.PingReply{Message: "TestReply"})
+	if nil != err {
+		t.Fatalf("MarshalResponse() failed: %v", err)
+	}
+
+	requestID, responseErr, err = UnmarshalResponseForIDAndError(pingReplyBuf)
+	if nil != err {
+		t.Fatalf("UnmarshalResponseForIDAndError() failed: %v", err)
+	}
+	if (17 != requestID) || (nil == responseErr) || ("errno: 2" != responseErr.Error()) {
+		t.Fatalf("UnmarshalResponseForIDAndError() returned unexpected ID/error")
+	}
+
+	pingReplyOutput = &jrpcfs.PingReply{}
+
+	err = UnmarshalResponse(requestID, pingReplyBuf, pingReplyOutput)
+	if nil != err {
+		t.Fatalf("UnmarshalResponse() failed: %v", err)
+	}
+	if "TestReply" != pingReplyOutput.Message {
+		t.Fatalf("UnmarshalResponse() returned unexpected Message")
+	}
+}
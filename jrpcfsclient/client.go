@@ -0,0 +1,320 @@
+package jrpcfsclient
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/jrpcfs"
+)
+
+const rpcServiceName = "Server."
+
+type connectionStruct struct {
+	sync.Mutex
+	rpcClient *rpc.Client // nil if not currently connected
+}
+
+type clientStruct struct {
+	sync.Mutex
+	config              ConfigStruct
+	tcpAddr             string
+	fastTCPAddr         string
+	closed              bool
+	connections         []*connectionStruct
+	nextConnectionIndex uint64           // accessed atomically
+	fastConnectionPool  chan net.Conn    // idle FastTCPPort connections
+	fastConnectionSet   map[net.Conn]int // all open FastTCPPort connections (value ignored)
+}
+
+func newClient(config *ConfigStruct) (client *Client, err error) {
+	var (
+		connectionIndex    int
+		connectionPoolSize uint16
+	)
+
+	if nil == config {
+		err = fmt.Errorf("jrpcfsclient.NewClient() requires a non-nil config")
+		return
+	}
+	if "" == config.IPAddr {
+		err = fmt.Errorf("jrpcfsclient.NewClient() requires a non-empty config.IPAddr")
+		return
+	}
+	if 0 == config.TCPPort {
+		err = fmt.Errorf("jrpcfsclient.NewClient() requires a non-zero config.TCPPort")
+		return
+	}
+
+	client = &Client{}
+
+	client.config = *config
+	client.tcpAddr = net.JoinHostPort(config.IPAddr, strconv.FormatUint(uint64(config.TCPPort), 10))
+	if 0 != config.FastTCPPort {
+		client.fastTCPAddr = net.JoinHostPort(config.IPAddr, strconv.FormatUint(uint64(config.FastTCPPort), 10))
+	}
+
+	connectionPoolSize = config.ConnectionPoolSize
+	if 0 == connectionPoolSize {
+		connectionPoolSize = 1
+	}
+
+	client.connections = make([]*connectionStruct, connectionPoolSize)
+	for connectionIndex = range client.connections {
+		client.connections[connectionIndex] = &connectionStruct{}
+	}
+
+	client.fastConnectionPool = make(chan net.Conn, config.FastConnectionPoolSize)
+	client.fastConnectionSet = make(map[net.Conn]int)
+
+	err = nil
+	return
+}
+
+func (client *Client) close() (err error) {
+	var (
+		connection     *connectionStruct
+		fastConnection net.Conn
+	)
+
+	client.Lock()
+
+	if client.closed {
+		client.Unlock()
+		err = fmt.Errorf("jrpcfsclient.Client already closed")
+		return
+	}
+
+	client.closed = true
+
+	for fastConnection = range client.fastConnectionSet {
+		_ = fastConnection.Close()
+	}
+
+	client.fastConnectionSet = make(map[net.Conn]int)
+
+	client.Unlock()
+
+	for _, connection = range client.connections {
+		connection.Lock()
+		if nil != connection.rpcClient {
+			_ = connection.rpcClient.Close()
+			connection.rpcClient = nil
+		}
+		connection.Unlock()
+	}
+
+	err = nil
+	return
+}
+
+func (client *Client) isClosed() (closed bool) {
+	client.Lock()
+	closed = client.closed
+	client.Unlock()
+	return
+}
+
+func (client *Client) newMount(mountID jrpcfs.MountIDAsString, rootDirInodeNumber int64) (mount *Mount, err error) {
+	var (
+		mountIDAsByteSlice []byte
+	)
+
+	mountIDAsByteSlice, err = base64.StdEncoding.DecodeString(string(mountID))
+	if nil != err {
+		err = fmt.Errorf("unable to decode MountID \"%s\": %v", mountID, err)
+		return
+	}
+	if len(mountIDAsByteSlice) != len(jrpcfs.MountIDAsByteArray{}) {
+		err = fmt.Errorf("decoded MountID \"%s\" has unexpected length %d", mountID, len(mountIDAsByteSlice))
+		return
+	}
+
+	mount = &Mount{
+		client:             client,
+		mountID:            mountID,
+		rootDirInodeNumber: Int64ToInodeNumber(rootDirInodeNumber),
+	}
+
+	copy(mount.mountIDAsByteArray[:], mountIDAsByteSlice)
+
+	return
+}
+
+// fetchRPCClient returns the connected rpc.Client for this connection, dialing the server if necessary.
+func (connection *connectionStruct) fetchRPCClient(client *Client) (rpcClient *rpc.Client, err error) {
+	var (
+		conn net.Conn
+	)
+
+	connection.Lock()
+	defer connection.Unlock()
+
+	if nil != connection.rpcClient {
+		rpcClient = connection.rpcClient
+		err = nil
+		return
+	}
+
+	if 0 == client.config.Timeout {
+		conn, err = net.Dial("tcp", client.tcpAddr)
+	} else {
+		conn, err = net.DialTimeout("tcp", client.tcpAddr, client.config.Timeout)
+	}
+	if nil != err {
+		return
+	}
+
+	connection.rpcClient = jsonrpc.NewClient(conn)
+	rpcClient = connection.rpcClient
+
+	return
+}
+
+// resetRPCClient discards rpcClient (if it is still the current one for this connection)
+// such that the next request will establish a new connection.
+func (connection *connectionStruct) resetRPCClient(rpcClient *rpc.Client) {
+	connection.Lock()
+	if connection.rpcClient == rpcClient {
+		_ = connection.rpcClient.Close()
+		connection.rpcClient = nil
+	}
+	connection.Unlock()
+}
+
+// idempotentMethods lists the Server.Rpc* methods that may safely be repeated should the
+// connection fail after the request was sent. Any other method (e.g. RpcCreate) may have
+// been performed by the server before the connection failed, so repeating it could fail
+// spuriously (e.g. with EEXIST) or, worse, perform it twice.
+var idempotentMethods = map[string]bool{
+	"RpcGetAccount":       true,
+	"RpcGetContainer":     true,
+	"RpcGetStat":          true,
+	"RpcGetStatPath":      true,
+	"RpcGetXAttr":         true,
+	"RpcGetXAttrPath":     true,
+	"RpcHead":             true,
+	"RpcIsAccountBimodal": true,
+	"RpcListVersions":     true,
+	"RpcListXAttr":        true,
+	"RpcListXAttrPath":    true,
+	"RpcLookup":           true,
+	"RpcLookupPath":       true,
+	"RpcPing":             true,
+	"RpcReadSymlink":      true,
+	"RpcReadSymlinkPath":  true,
+	"RpcReaddir":          true,
+	"RpcReaddirByLoc":     true,
+	"RpcReaddirPlus":      true,
+	"RpcReaddirPlusByLoc": true,
+	"RpcSeek":             true,
+	"RpcStatVFS":          true,
+	"RpcTrashList":        true,
+	"RpcType":             true,
+}
+
+// call invokes Server.<method> on one of the pooled connections. Should the connection
+// fail to be established, it is retried up to config.RetryLimit times. Should the connection
+// fail once the request has been sent (as opposed to the server returning an error), the
+// connection is re-established but the request is only retried if it is listed in
+// idempotentMethods.
+//
+// Note that a request timing out is not retried as it may still be executing on the server.
+func (client *Client) call(method string, request interface{}, reply interface{}) (err error) {
+	err = client.callWithTimeout(method, request, reply, client.config.Timeout)
+	return
+}
+
+// callWithTimeout is identical to call() except that timeout overrides config.Timeout.
+func (client *Client) callWithTimeout(method string, request interface{}, reply interface{}, timeout time.Duration) (err error) {
+	var (
+		connection  *connectionStruct
+		retryIndex  uint16
+		rpcCall     *rpc.Call
+		rpcClient   *rpc.Client
+		serverError rpc.ServerError
+		ok          bool
+	)
+
+	for retryIndex = 0; ; retryIndex++ {
+		if client.isClosed() {
+			err = fmt.Errorf("jrpcfsclient.Client closed")
+			return
+		}
+
+		connection = client.connections[atomic.AddUint64(&client.nextConnectionIndex, 1)%uint64(len(client.connections))]
+
+		rpcClient, err = connection.fetchRPCClient(client)
+		if nil == err {
+			rpcCall = rpcClient.Go(rpcServiceName+method, request, reply, make(chan *rpc.Call, 1))
+
+			if 0 == timeout {
+				<-rpcCall.Done
+			} else {
+				select {
+				case <-rpcCall.Done:
+				case <-time.After(timeout):
+					connection.resetRPCClient(rpcClient)
+					err = blunder.NewError(blunder.TimedOut, "%s timed out after %v", method, timeout)
+					return
+				}
+			}
+
+			err = rpcCall.Error
+			if nil == err {
+				return
+			}
+
+			serverError, ok = err.(rpc.ServerError)
+			if ok {
+				err = decodeServerError(method, serverError)
+				return
+			}
+
+			connection.resetRPCClient(rpcClient)
+
+			if !idempotentMethods[method] {
+				err = fmt.Errorf("%s failed: %v", method, err)
+				return
+			}
+		}
+
+		if retryIndex >= client.config.RetryLimit {
+			err = fmt.Errorf("%s failed: %v", method, err)
+			return
+		}
+
+		time.Sleep(client.config.RetryDelay)
+	}
+}
+
+// decodeServerError converts the "errno: <n>" form used by jrpcfs to convey errors back into a blunder error.
+func decodeServerError(method string, serverError rpc.ServerError) (err error) {
+	var (
+		errno      int64
+		parseErr   error
+		serverText string
+	)
+
+	serverText = string(serverError)
+
+	if strings.HasPrefix(serverText, "errno: ") {
+		errno, parseErr = strconv.ParseInt(strings.TrimPrefix(serverText, "errno: "), 10, 64)
+		if nil == parseErr {
+			err = blunder.NewError(blunder.FsError(errno), "%s failed: %s", method, serverText)
+			return
+		}
+	}
+
+	err = fmt.Errorf("%s failed: %s", method, serverText)
+
+	return
+}
@@ -0,0 +1,819 @@
+package jrpcfsclient
+
+import (
+	"time"
+
+	"github.com/swiftstack/ProxyFS/fs"
+	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/jrpcfs"
+)
+
+// The methods in this file map one-to-one onto the Server.Rpc* methods found in jrpcfs/filesystem.go.
+
+func (mount *Mount) inodeHandle(inodeNumber inode.InodeNumber) (inodeHandle jrpcfs.InodeHandle) {
+	inodeHandle = jrpcfs.InodeHandle{
+		MountID:     mount.mountID,
+		InodeNumber: InodeNumberToInt64(inodeNumber),
+	}
+	return
+}
+
+func (mount *Mount) pathHandle(fullpath string) (pathHandle jrpcfs.PathHandle) {
+	pathHandle = jrpcfs.PathHandle{
+		MountID:  mount.mountID,
+		Fullpath: fullpath,
+	}
+	return
+}
+
+func (stat *StatStruct) fromJrpcfsStatStruct(jrpcfsStat *jrpcfs.StatStruct) {
+	stat.CTimeNs = jrpcfsStat.CTimeNs
+	stat.CRTimeNs = jrpcfsStat.CRTimeNs
+	stat.MTimeNs = jrpcfsStat.MTimeNs
+	stat.ATimeNs = jrpcfsStat.ATimeNs
+	stat.Size = jrpcfsStat.Size
+	stat.NumLinks = jrpcfsStat.NumLinks
+	stat.InodeNumber = Int64ToInodeNumber(jrpcfsStat.StatInodeNumber)
+	stat.FileMode = jrpcfsStat.FileMode
+	stat.UserID = jrpcfsStat.UserID
+	stat.GroupID = jrpcfsStat.GroupID
+}
+
+func (stat *StatStruct) toJrpcfsStatStruct() (jrpcfsStat jrpcfs.StatStruct) {
+	jrpcfsStat = jrpcfs.StatStruct{
+		CTimeNs:         stat.CTimeNs,
+		CRTimeNs:        stat.CRTimeNs,
+		MTimeNs:         stat.MTimeNs,
+		ATimeNs:         stat.ATimeNs,
+		Size:            stat.Size,
+		NumLinks:        stat.NumLinks,
+		StatInodeNumber: InodeNumberToInt64(stat.InodeNumber),
+		FileMode:        stat.FileMode,
+		UserID:          stat.UserID,
+		GroupID:         stat.GroupID,
+	}
+	return
+}
+
+func dirEntriesFromJrpcfsDirEntries(jrpcfsDirEnts []jrpcfs.DirEntry) (dirEnts []DirEntryStruct) {
+	var (
+		dirEntIndex int
+	)
+
+	dirEnts = make([]DirEntryStruct, len(jrpcfsDirEnts))
+
+	for dirEntIndex = range jrpcfsDirEnts {
+		dirEnts[dirEntIndex] = DirEntryStruct{
+			InodeNumber:     Int64ToInodeNumber(jrpcfsDirEnts[dirEntIndex].InodeNumber),
+			FileType:        jrpcfsDirEnts[dirEntIndex].FileType,
+			Basename:        jrpcfsDirEnts[dirEntIndex].Basename,
+			NextDirLocation: jrpcfsDirEnts[dirEntIndex].NextDirLocation,
+		}
+	}
+
+	return
+}
+
+func statsFromJrpcfsStatStructs(jrpcfsStats []jrpcfs.StatStruct) (stats []StatStruct) {
+	var (
+		statIndex int
+	)
+
+	stats = make([]StatStruct, len(jrpcfsStats))
+
+	for statIndex = range jrpcfsStats {
+		stats[statIndex].fromJrpcfsStatStruct(&jrpcfsStats[statIndex])
+	}
+
+	return
+}
+
+// Chmod invokes RpcChmod.
+func (mount *Mount) Chmod(inodeNumber inode.InodeNumber, fileMode uint32) (err error) {
+	request := &jrpcfs.ChmodRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+		FileMode:    fileMode,
+	}
+	err = mount.client.call("RpcChmod", request, &jrpcfs.Reply{})
+	return
+}
+
+// ChmodPath invokes RpcChmodPath.
+func (mount *Mount) ChmodPath(fullpath string, fileMode uint32) (err error) {
+	request := &jrpcfs.ChmodPathRequest{
+		PathHandle: mount.pathHandle(fullpath),
+		FileMode:   fileMode,
+	}
+	err = mount.client.call("RpcChmodPath", request, &jrpcfs.Reply{})
+	return
+}
+
+// Chown invokes RpcChown.
+func (mount *Mount) Chown(inodeNumber inode.InodeNumber, userID int32, groupID int32) (err error) {
+	request := &jrpcfs.ChownRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+		UserID:      userID,
+		GroupID:     groupID,
+	}
+	err = mount.client.call("RpcChown", request, &jrpcfs.Reply{})
+	return
+}
+
+// ChownPath invokes RpcChownPath.
+func (mount *Mount) ChownPath(fullpath string, userID int32, groupID int32) (err error) {
+	request := &jrpcfs.ChownPathRequest{
+		PathHandle: mount.pathHandle(fullpath),
+		UserID:     userID,
+		GroupID:    groupID,
+	}
+	err = mount.client.call("RpcChownPath", request, &jrpcfs.Reply{})
+	return
+}
+
+// CopyFileRange invokes RpcCopyFileRange. The returned size may be less than length.
+func (mount *Mount) CopyFileRange(srcInodeNumber inode.InodeNumber, srcOffset uint64, dstInodeNumber inode.InodeNumber, dstOffset uint64, length uint64) (size uint64, err error) {
+	request := &jrpcfs.CopyFileRangeRequest{
+		InodeHandle:    mount.inodeHandle(srcInodeNumber),
+		SrcOffset:      srcOffset,
+		DstInodeNumber: InodeNumberToInt64(dstInodeNumber),
+		DstOffset:      dstOffset,
+		Length:         length,
+	}
+	reply := &jrpcfs.CopyFileRangeReply{}
+	err = mount.client.call("RpcCopyFileRange", request, reply)
+	if nil == err {
+		size = reply.Size
+	}
+	return
+}
+
+// Create invokes RpcCreate.
+func (mount *Mount) Create(dirInodeNumber inode.InodeNumber, basename string, userID int32, groupID int32, fileMode uint32) (fileInodeNumber inode.InodeNumber, err error) {
+	request := &jrpcfs.CreateRequest{
+		InodeHandle: mount.inodeHandle(dirInodeNumber),
+		Basename:    basename,
+		UserID:      userID,
+		GroupID:     groupID,
+		FileMode:    fileMode,
+	}
+	reply := &jrpcfs.InodeReply{}
+	err = mount.client.call("RpcCreate", request, reply)
+	if nil == err {
+		fileInodeNumber = Int64ToInodeNumber(reply.InodeNumber)
+	}
+	return
+}
+
+// CreatePath invokes RpcCreatePath.
+func (mount *Mount) CreatePath(fullpath string, userID int32, groupID int32, fileMode uint32) (fileInodeNumber inode.InodeNumber, err error) {
+	request := &jrpcfs.CreatePathRequest{
+		PathHandle: mount.pathHandle(fullpath),
+		UserID:     userID,
+		GroupID:    groupID,
+		FileMode:   fileMode,
+	}
+	reply := &jrpcfs.InodeReply{}
+	err = mount.client.call("RpcCreatePath", request, reply)
+	if nil == err {
+		fileInodeNumber = Int64ToInodeNumber(reply.InodeNumber)
+	}
+	return
+}
+
+// Fallocate invokes RpcFallocate. See jrpcfs.FallocateRequest for the supported mode bits.
+func (mount *Mount) Fallocate(inodeNumber inode.InodeNumber, mode uint32, offset uint64, length uint64) (err error) {
+	request := &jrpcfs.FallocateRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+		Mode:        mode,
+		Offset:      offset,
+		Length:      length,
+	}
+	err = mount.client.call("RpcFallocate", request, &jrpcfs.Reply{})
+	return
+}
+
+// Flock invokes RpcFlock.
+func (mount *Mount) Flock(inodeNumber inode.InodeNumber, flockCmd int32, flock *fs.FlockStruct) (flockReply *fs.FlockStruct, err error) {
+	request := &jrpcfs.FlockRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+		FlockCmd:    flockCmd,
+		FlockType:   flock.Type,
+		FlockWhence: flock.Whence,
+		FlockStart:  flock.Start,
+		FlockLen:    flock.Len,
+		FlockPid:    flock.Pid,
+	}
+	reply := &jrpcfs.FlockReply{}
+	err = mount.client.call("RpcFlock", request, reply)
+	if nil == err {
+		flockReply = &fs.FlockStruct{
+			Type:   reply.FlockType,
+			Whence: reply.FlockWhence,
+			Start:  reply.FlockStart,
+			Len:    reply.FlockLen,
+			Pid:    reply.FlockPid,
+		}
+	}
+	return
+}
+
+// Flush invokes RpcFlush.
+func (mount *Mount) Flush(inodeNumber inode.InodeNumber) (err error) {
+	sendTime := time.Now()
+	request := &jrpcfs.FlushRequest{
+		InodeHandle:  mount.inodeHandle(inodeNumber),
+		SendTimeSec:  jrpcfs.UnixSec(sendTime),
+		SendTimeNsec: jrpcfs.UnixNanosec(sendTime),
+	}
+	err = mount.client.call("RpcFlush", request, &jrpcfs.Reply{})
+	return
+}
+
+// GetStat invokes RpcGetStat.
+func (mount *Mount) GetStat(inodeNumber inode.InodeNumber) (stat *StatStruct, err error) {
+	request := &jrpcfs.GetStatRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+	}
+	reply := &jrpcfs.StatStruct{}
+	err = mount.client.call("RpcGetStat", request, reply)
+	if nil == err {
+		stat = &StatStruct{}
+		stat.fromJrpcfsStatStruct(reply)
+	}
+	return
+}
+
+// GetStatPath invokes RpcGetStatPath.
+func (mount *Mount) GetStatPath(fullpath string) (stat *StatStruct, err error) {
+	request := &jrpcfs.GetStatPathRequest{
+		PathHandle: mount.pathHandle(fullpath),
+	}
+	reply := &jrpcfs.StatStruct{}
+	err = mount.client.call("RpcGetStatPath", request, reply)
+	if nil == err {
+		stat = &StatStruct{}
+		stat.fromJrpcfsStatStruct(reply)
+	}
+	return
+}
+
+// GetXAttr invokes RpcGetXAttr.
+func (mount *Mount) GetXAttr(inodeNumber inode.InodeNumber, attrName string) (attrValue []byte, err error) {
+	request := &jrpcfs.GetXAttrRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+		AttrName:    attrName,
+	}
+	reply := &jrpcfs.GetXAttrReply{}
+	err = mount.client.call("RpcGetXAttr", request, reply)
+	if nil == err {
+		attrValue = reply.AttrValue
+	}
+	return
+}
+
+// GetXAttrPath invokes RpcGetXAttrPath.
+func (mount *Mount) GetXAttrPath(fullpath string, attrName string) (attrValue []byte, err error) {
+	request := &jrpcfs.GetXAttrPathRequest{
+		PathHandle: mount.pathHandle(fullpath),
+		AttrName:   attrName,
+	}
+	reply := &jrpcfs.GetXAttrReply{}
+	err = mount.client.call("RpcGetXAttrPath", request, reply)
+	if nil == err {
+		attrValue = reply.AttrValue
+	}
+	return
+}
+
+// Link invokes RpcLink.
+func (mount *Mount) Link(dirInodeNumber inode.InodeNumber, basename string, targetInodeNumber inode.InodeNumber) (err error) {
+	request := &jrpcfs.LinkRequest{
+		InodeHandle:       mount.inodeHandle(dirInodeNumber),
+		Basename:          basename,
+		TargetInodeNumber: InodeNumberToInt64(targetInodeNumber),
+	}
+	err = mount.client.call("RpcLink", request, &jrpcfs.Reply{})
+	return
+}
+
+// LinkPath invokes RpcLinkPath.
+func (mount *Mount) LinkPath(fullpath string, targetFullpath string) (err error) {
+	request := &jrpcfs.LinkPathRequest{
+		PathHandle:     mount.pathHandle(fullpath),
+		TargetFullpath: targetFullpath,
+	}
+	err = mount.client.call("RpcLinkPath", request, &jrpcfs.Reply{})
+	return
+}
+
+// ListXAttr invokes RpcListXAttr.
+func (mount *Mount) ListXAttr(inodeNumber inode.InodeNumber) (attrNames []string, err error) {
+	request := &jrpcfs.ListXAttrRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+	}
+	reply := &jrpcfs.ListXAttrReply{}
+	err = mount.client.call("RpcListXAttr", request, reply)
+	if nil == err {
+		attrNames = reply.AttrNames
+	}
+	return
+}
+
+// ListXAttrPath invokes RpcListXAttrPath.
+func (mount *Mount) ListXAttrPath(fullpath string) (attrNames []string, err error) {
+	request := &jrpcfs.ListXAttrPathRequest{
+		PathHandle: mount.pathHandle(fullpath),
+	}
+	reply := &jrpcfs.ListXAttrReply{}
+	err = mount.client.call("RpcListXAttrPath", request, reply)
+	if nil == err {
+		attrNames = reply.AttrNames
+	}
+	return
+}
+
+// Lookup invokes RpcLookup.
+func (mount *Mount) Lookup(dirInodeNumber inode.InodeNumber, basename string) (inodeNumber inode.InodeNumber, err error) {
+	request := &jrpcfs.LookupRequest{
+		InodeHandle: mount.inodeHandle(dirInodeNumber),
+		Basename:    basename,
+	}
+	reply := &jrpcfs.InodeReply{}
+	err = mount.client.call("RpcLookup", request, reply)
+	if nil == err {
+		inodeNumber = Int64ToInodeNumber(reply.InodeNumber)
+	}
+	return
+}
+
+// LookupPath invokes RpcLookupPath.
+func (mount *Mount) LookupPath(fullpath string) (inodeNumber inode.InodeNumber, err error) {
+	request := &jrpcfs.LookupPathRequest{
+		MountID:  mount.mountID,
+		Fullpath: fullpath,
+	}
+	reply := &jrpcfs.InodeReply{}
+	err = mount.client.call("RpcLookupPath", request, reply)
+	if nil == err {
+		inodeNumber = Int64ToInodeNumber(reply.InodeNumber)
+	}
+	return
+}
+
+// Mkdir invokes RpcMkdir.
+func (mount *Mount) Mkdir(dirInodeNumber inode.InodeNumber, basename string, userID int32, groupID int32, fileMode uint32) (newDirInodeNumber inode.InodeNumber, err error) {
+	request := &jrpcfs.MkdirRequest{
+		InodeHandle: mount.inodeHandle(dirInodeNumber),
+		Basename:    basename,
+		UserID:      userID,
+		GroupID:     groupID,
+		FileMode:    fileMode,
+	}
+	reply := &jrpcfs.InodeReply{}
+	err = mount.client.call("RpcMkdir", request, reply)
+	if nil == err {
+		newDirInodeNumber = Int64ToInodeNumber(reply.InodeNumber)
+	}
+	return
+}
+
+// MkdirPath invokes RpcMkdirPath.
+func (mount *Mount) MkdirPath(fullpath string, userID int32, groupID int32, fileMode uint32) (err error) {
+	request := &jrpcfs.MkdirPathRequest{
+		PathHandle: mount.pathHandle(fullpath),
+		UserID:     userID,
+		GroupID:    groupID,
+		FileMode:   fileMode,
+	}
+	err = mount.client.call("RpcMkdirPath", request, &jrpcfs.Reply{})
+	return
+}
+
+// NotifyFetch invokes RpcNotifyFetch, waiting up to timeout (capped by the server at
+// jrpcfs.NotifyFetchTimeoutMax) for events to arrive. The wait is in addition to config.Timeout.
+func (mount *Mount) NotifyFetch(subscriptionID fs.NotifySubscriptionID, maxEvents uint64, timeout time.Duration) (events []NotifyEventStruct, overflowed bool, err error) {
+	var (
+		callTimeout time.Duration
+		eventIndex  int
+	)
+
+	request := &jrpcfs.NotifyFetchRequest{
+		MountID:        mount.mountID,
+		SubscriptionID: uint64(subscriptionID),
+		MaxEvents:      maxEvents,
+		TimeoutMs:      uint64(timeout / time.Millisecond),
+	}
+	reply := &jrpcfs.NotifyFetchReply{}
+
+	if timeout > jrpcfs.NotifyFetchTimeoutMax {
+		timeout = jrpcfs.NotifyFetchTimeoutMax
+	}
+	if 0 != mount.client.config.Timeout {
+		callTimeout = mount.client.config.Timeout + timeout
+	}
+
+	err = mount.client.callWithTimeout("RpcNotifyFetch", request, reply, callTimeout)
+	if nil != err {
+		return
+	}
+
+	events = make([]NotifyEventStruct, len(reply.Events))
+
+	for eventIndex = range reply.Events {
+		events[eventIndex] = NotifyEventStruct{
+			Sequence:          reply.Events[eventIndex].Sequence,
+			Type:              fs.NotifyEventType(reply.Events[eventIndex].EventType),
+			DirInodeNumber:    Int64ToInodeNumber(reply.Events[eventIndex].DirInodeNumber),
+			Basename:          reply.Events[eventIndex].Basename,
+			InodeNumber:       Int64ToInodeNumber(reply.Events[eventIndex].InodeNumber),
+			NewDirInodeNumber: Int64ToInodeNumber(reply.Events[eventIndex].NewDirInodeNumber),
+			NewBasename:       reply.Events[eventIndex].NewBasename,
+		}
+	}
+
+	overflowed = reply.Overflowed
+
+	return
+}
+
+// NotifySubscribe invokes RpcNotifySubscribe.
+func (mount *Mount) NotifySubscribe(dirInodeNumber inode.InodeNumber, subtree bool, eventMask fs.NotifyEventType) (subscriptionID fs.NotifySubscriptionID, err error) {
+	request := &jrpcfs.NotifySubscribeRequest{
+		InodeHandle: mount.inodeHandle(dirInodeNumber),
+		Subtree:     subtree,
+		EventMask:   uint32(eventMask),
+	}
+	reply := &jrpcfs.NotifySubscribeReply{}
+	err = mount.client.call("RpcNotifySubscribe", request, reply)
+	if nil == err {
+		subscriptionID = fs.NotifySubscriptionID(reply.SubscriptionID)
+	}
+	return
+}
+
+// NotifyUnsubscribe invokes RpcNotifyUnsubscribe.
+func (mount *Mount) NotifyUnsubscribe(subscriptionID fs.NotifySubscriptionID) (err error) {
+	request := &jrpcfs.NotifyUnsubscribeRequest{
+		MountID:        mount.mountID,
+		SubscriptionID: uint64(subscriptionID),
+	}
+	err = mount.client.call("RpcNotifyUnsubscribe", request, &jrpcfs.Reply{})
+	return
+}
+
+// Readdir invokes RpcReaddir.
+func (mount *Mount) Readdir(dirInodeNumber inode.InodeNumber, maxEntries uint64, prevDirEntName string) (dirEnts []DirEntryStruct, err error) {
+	request := &jrpcfs.ReaddirRequest{
+		InodeHandle:    mount.inodeHandle(dirInodeNumber),
+		MaxEntries:     maxEntries,
+		PrevDirEntName: prevDirEntName,
+	}
+	reply := &jrpcfs.ReaddirReply{}
+	err = mount.client.call("RpcReaddir", request, reply)
+	if nil == err {
+		dirEnts = dirEntriesFromJrpcfsDirEntries(reply.DirEnts)
+	}
+	return
+}
+
+// ReaddirByLoc invokes RpcReaddirByLoc.
+func (mount *Mount) ReaddirByLoc(dirInodeNumber inode.InodeNumber, maxEntries uint64, prevDirEntLocation int64) (dirEnts []DirEntryStruct, err error) {
+	request := &jrpcfs.ReaddirByLocRequest{
+		InodeHandle:        mount.inodeHandle(dirInodeNumber),
+		MaxEntries:         maxEntries,
+		PrevDirEntLocation: prevDirEntLocation,
+	}
+	reply := &jrpcfs.ReaddirReply{}
+	err = mount.client.call("RpcReaddirByLoc", request, reply)
+	if nil == err {
+		dirEnts = dirEntriesFromJrpcfsDirEntries(reply.DirEnts)
+	}
+	return
+}
+
+// ReaddirPlus invokes RpcReaddirPlus.
+func (mount *Mount) ReaddirPlus(dirInodeNumber inode.InodeNumber, maxEntries uint64, prevDirEntName string) (dirEnts []DirEntryStruct, stats []StatStruct, err error) {
+	request := &jrpcfs.ReaddirPlusRequest{
+		InodeHandle:    mount.inodeHandle(dirInodeNumber),
+		MaxEntries:     maxEntries,
+		PrevDirEntName: prevDirEntName,
+	}
+	reply := &jrpcfs.ReaddirPlusReply{}
+	err = mount.client.call("RpcReaddirPlus", request, reply)
+	if nil == err {
+		dirEnts = dirEntriesFromJrpcfsDirEntries(reply.DirEnts)
+		stats = statsFromJrpcfsStatStructs(reply.StatEnts)
+	}
+	return
+}
+
+// ReaddirPlusByLoc invokes RpcReaddirPlusByLoc.
+func (mount *Mount) ReaddirPlusByLoc(dirInodeNumber inode.InodeNumber, maxEntries uint64, prevDirEntLocation int64) (dirEnts []DirEntryStruct, stats []StatStruct, err error) {
+	request := &jrpcfs.ReaddirPlusByLocRequest{
+		InodeHandle:        mount.inodeHandle(dirInodeNumber),
+		MaxEntries:         maxEntries,
+		PrevDirEntLocation: prevDirEntLocation,
+	}
+	reply := &jrpcfs.ReaddirPlusReply{}
+	err = mount.client.call("RpcReaddirPlusByLoc", request, reply)
+	if nil == err {
+		dirEnts = dirEntriesFromJrpcfsDirEntries(reply.DirEnts)
+		stats = statsFromJrpcfsStatStructs(reply.StatEnts)
+	}
+	return
+}
+
+// ReadSymlink invokes RpcReadSymlink.
+func (mount *Mount) ReadSymlink(inodeNumber inode.InodeNumber) (target string, err error) {
+	request := &jrpcfs.ReadSymlinkRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+	}
+	reply := &jrpcfs.ReadSymlinkReply{}
+	err = mount.client.call("RpcReadSymlink", request, reply)
+	if nil == err {
+		target = reply.Target
+	}
+	return
+}
+
+// ReadSymlinkPath invokes RpcReadSymlinkPath.
+func (mount *Mount) ReadSymlinkPath(fullpath string) (target string, err error) {
+	request := &jrpcfs.ReadSymlinkPathRequest{
+		PathHandle: mount.pathHandle(fullpath),
+	}
+	reply := &jrpcfs.ReadSymlinkReply{}
+	err = mount.client.call("RpcReadSymlinkPath", request, reply)
+	if nil == err {
+		target = reply.Target
+	}
+	return
+}
+
+// RemoveXAttr invokes RpcRemovetXAttr.
+func (mount *Mount) RemoveXAttr(inodeNumber inode.InodeNumber, attrName string) (err error) {
+	request := &jrpcfs.RemoveXAttrRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+		AttrName:    attrName,
+	}
+	err = mount.client.call("RpcRemovetXAttr", request, &jrpcfs.Reply{})
+	return
+}
+
+// RemoveXAttrPath invokes RpcRemoveAttrPath.
+func (mount *Mount) RemoveXAttrPath(fullpath string, attrName string) (err error) {
+	request := &jrpcfs.RemoveXAttrPathRequest{
+		PathHandle: mount.pathHandle(fullpath),
+		AttrName:   attrName,
+	}
+	err = mount.client.call("RpcRemoveAttrPath", request, &jrpcfs.Reply{})
+	return
+}
+
+// Rename invokes RpcRename.
+func (mount *Mount) Rename(srcDirInodeNumber inode.InodeNumber, srcBasename string, dstDirInodeNumber inode.InodeNumber, dstBasename string, flags inode.RenameFlags) (err error) {
+	request := &jrpcfs.RenameRequest{
+		MountID:           mount.mountID,
+		SrcDirInodeNumber: InodeNumberToInt64(srcDirInodeNumber),
+		SrcBasename:       srcBasename,
+		DstDirInodeNumber: InodeNumberToInt64(dstDirInodeNumber),
+		DstBasename:       dstBasename,
+		Flags:             uint32(flags),
+	}
+	err = mount.client.call("RpcRename", request, &jrpcfs.Reply{})
+	return
+}
+
+// RenamePath invokes RpcRenamePath.
+func (mount *Mount) RenamePath(fullpath string, dstFullpath string, flags inode.RenameFlags) (err error) {
+	request := &jrpcfs.RenamePathRequest{
+		PathHandle:  mount.pathHandle(fullpath),
+		DstFullpath: dstFullpath,
+		Flags:       uint32(flags),
+	}
+	err = mount.client.call("RpcRenamePath", request, &jrpcfs.Reply{})
+	return
+}
+
+// Resize invokes RpcResize.
+func (mount *Mount) Resize(inodeNumber inode.InodeNumber, newSize uint64) (err error) {
+	request := &jrpcfs.ResizeRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+		NewSize:     newSize,
+	}
+	err = mount.client.call("RpcResize", request, &jrpcfs.Reply{})
+	return
+}
+
+// Rmdir invokes RpcRmdir.
+func (mount *Mount) Rmdir(dirInodeNumber inode.InodeNumber, basename string) (err error) {
+	request := &jrpcfs.UnlinkRequest{
+		InodeHandle: mount.inodeHandle(dirInodeNumber),
+		Basename:    basename,
+	}
+	err = mount.client.call("RpcRmdir", request, &jrpcfs.Reply{})
+	return
+}
+
+// RmdirPath invokes RpcRmdirPath.
+func (mount *Mount) RmdirPath(fullpath string) (err error) {
+	request := &jrpcfs.UnlinkPathRequest{
+		PathHandle: mount.pathHandle(fullpath),
+	}
+	err = mount.client.call("RpcRmdirPath", request, &jrpcfs.Reply{})
+	return
+}
+
+// Seek invokes RpcSeek. Whence must be either jrpcfs.SeekData or jrpcfs.SeekHole.
+func (mount *Mount) Seek(inodeNumber inode.InodeNumber, offset uint64, whence int) (newOffset uint64, err error) {
+	request := &jrpcfs.SeekRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+		Offset:      offset,
+		Whence:      whence,
+	}
+	reply := &jrpcfs.SeekReply{}
+	err = mount.client.call("RpcSeek", request, reply)
+	if nil == err {
+		newOffset = reply.Offset
+	}
+	return
+}
+
+// Setstat invokes RpcSetstat. Note that only the times, Size, and NumLinks are applied by the server.
+func (mount *Mount) Setstat(inodeNumber inode.InodeNumber, stat *StatStruct) (err error) {
+	request := &jrpcfs.SetstatRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+		StatStruct:  stat.toJrpcfsStatStruct(),
+	}
+	err = mount.client.call("RpcSetstat", request, &jrpcfs.Reply{})
+	return
+}
+
+// SetTime invokes RpcSetTime.
+func (mount *Mount) SetTime(inodeNumber inode.InodeNumber, mTimeNs uint64, aTimeNs uint64) (err error) {
+	request := &jrpcfs.SetTimeRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+		StatStruct: jrpcfs.StatStruct{
+			MTimeNs: mTimeNs,
+			ATimeNs: aTimeNs,
+		},
+	}
+	err = mount.client.call("RpcSetTime", request, &jrpcfs.Reply{})
+	return
+}
+
+// SetTimePath invokes RpcSetTimePath.
+func (mount *Mount) SetTimePath(fullpath string, mTimeNs uint64, aTimeNs uint64) (err error) {
+	request := &jrpcfs.SetTimePathRequest{
+		PathHandle: mount.pathHandle(fullpath),
+		StatStruct: jrpcfs.StatStruct{
+			MTimeNs: mTimeNs,
+			ATimeNs: aTimeNs,
+		},
+	}
+	err = mount.client.call("RpcSetTimePath", request, &jrpcfs.Reply{})
+	return
+}
+
+// SetXAttr invokes RpcSetXAttr.
+func (mount *Mount) SetXAttr(inodeNumber inode.InodeNumber, attrName string, attrValue []byte, attrFlags int) (err error) {
+	request := &jrpcfs.SetXAttrRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+		AttrName:    attrName,
+		AttrValue:   attrValue,
+		AttrFlags:   attrFlags,
+	}
+	err = mount.client.call("RpcSetXAttr", request, &jrpcfs.Reply{})
+	return
+}
+
+// SetXAttrPath invokes RpcSetXAttrPath.
+func (mount *Mount) SetXAttrPath(fullpath string, attrName string, attrValue []byte, attrFlags int) (err error) {
+	request := &jrpcfs.SetXAttrPathRequest{
+		PathHandle: mount.pathHandle(fullpath),
+		AttrName:   attrName,
+		AttrValue:  attrValue,
+		AttrFlags:  attrFlags,
+	}
+	err = mount.client.call("RpcSetXAttrPath", request, &jrpcfs.Reply{})
+	return
+}
+
+// StatVFS invokes RpcStatVFS.
+func (mount *Mount) StatVFS() (statVFS *jrpcfs.StatVFS, err error) {
+	request := &jrpcfs.StatVFSRequest{
+		MountID: mount.mountID,
+	}
+	reply := &jrpcfs.StatVFS{}
+	err = mount.client.call("RpcStatVFS", request, reply)
+	if nil == err {
+		statVFS = reply
+	}
+	return
+}
+
+// Symlink invokes RpcSymlink.
+func (mount *Mount) Symlink(dirInodeNumber inode.InodeNumber, basename string, target string, userID int32, groupID int32) (err error) {
+	request := &jrpcfs.SymlinkRequest{
+		InodeHandle: mount.inodeHandle(dirInodeNumber),
+		Basename:    basename,
+		Target:      target,
+		UserID:      userID,
+		GroupID:     groupID,
+	}
+	err = mount.client.call("RpcSymlink", request, &jrpcfs.Reply{})
+	return
+}
+
+// SymlinkPath invokes RpcSymlinkPath.
+func (mount *Mount) SymlinkPath(fullpath string, targetFullpath string, userID int32, groupID int32) (err error) {
+	request := &jrpcfs.SymlinkPathRequest{
+		PathHandle:     mount.pathHandle(fullpath),
+		TargetFullpath: targetFullpath,
+		UserID:         userID,
+		GroupID:        groupID,
+	}
+	err = mount.client.call("RpcSymlinkPath", request, &jrpcfs.Reply{})
+	return
+}
+
+// TrashList invokes RpcTrashList. The root user (userID 0) is returned the trashed inodes of all users.
+func (mount *Mount) TrashList(userID int32, groupID int32) (entries []TrashEntryStruct, err error) {
+	var (
+		entryIndex int
+	)
+
+	request := &jrpcfs.TrashListRequest{
+		MountID: mount.mountID,
+		UserID:  userID,
+		GroupID: groupID,
+	}
+	reply := &jrpcfs.TrashListReply{}
+	err = mount.client.call("RpcTrashList", request, reply)
+	if nil != err {
+		return
+	}
+
+	entries = make([]TrashEntryStruct, len(reply.Entries))
+
+	for entryIndex = range reply.Entries {
+		entries[entryIndex] = TrashEntryStruct{
+			TrashID:        reply.Entries[entryIndex].TrashID,
+			TrashUserID:    reply.Entries[entryIndex].TrashUserID,
+			DirInodeNumber: Int64ToInodeNumber(reply.Entries[entryIndex].DirInodeNumber),
+			Basename:       reply.Entries[entryIndex].Basename,
+			Path:           reply.Entries[entryIndex].Path,
+			DeleteTime:     reply.Entries[entryIndex].DeleteTime,
+			InodeNumber:    Int64ToInodeNumber(reply.Entries[entryIndex].InodeNumber),
+			FileType:       reply.Entries[entryIndex].FileType,
+			FileSize:       reply.Entries[entryIndex].FileSize,
+		}
+	}
+
+	return
+}
+
+// TrashRestore invokes RpcTrashRestore. An empty restorePath restores the inode to where it was deleted from.
+func (mount *Mount) TrashRestore(userID int32, groupID int32, trashUserID int32, trashID string, restorePath string) (err error) {
+	request := &jrpcfs.TrashRestoreRequest{
+		MountID:     mount.mountID,
+		UserID:      userID,
+		GroupID:     groupID,
+		TrashUserID: trashUserID,
+		TrashID:     trashID,
+		RestorePath: restorePath,
+	}
+	err = mount.client.call("RpcTrashRestore", request, &jrpcfs.Reply{})
+	return
+}
+
+// Type invokes RpcType.
+func (mount *Mount) Type(inodeNumber inode.InodeNumber) (fileType uint16, err error) {
+	request := &jrpcfs.TypeRequest{
+		InodeHandle: mount.inodeHandle(inodeNumber),
+	}
+	reply := &jrpcfs.TypeReply{}
+	err = mount.client.call("RpcType", request, reply)
+	if nil == err {
+		fileType = reply.FileType
+	}
+	return
+}
+
+// Unlink invokes RpcUnlink.
+func (mount *Mount) Unlink(dirInodeNumber inode.InodeNumber, basename string) (err error) {
+	request := &jrpcfs.UnlinkRequest{
+		InodeHandle: mount.inodeHandle(dirInodeNumber),
+		Basename:    basename,
+	}
+	err = mount.client.call("RpcUnlink", request, &jrpcfs.Reply{})
+	return
+}
+
+// UnlinkPath invokes RpcUnlinkPath.
+func (mount *Mount) UnlinkPath(fullpath string) (err error) {
+	request := &jrpcfs.UnlinkPathRequest{
+		PathHandle: mount.pathHandle(fullpath),
+	}
+	err = mount.client.call("RpcUnlinkPath", request, &jrpcfs.Reply{})
+	return
+}
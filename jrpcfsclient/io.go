@@ -0,0 +1,182 @@
+package jrpcfsclient
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/inode"
+)
+
+// The FastTCPPort protocol (see jrpcfs/io.go) exchanges fixed-size binary headers in the
+// server's native (little-endian) byte order:
+//
+//   request:  opType(8) mountID(16) inodeNumber(8) offset(8) length(8) [write payload]
+//   response: errno(8) ioSize(8) [read payload]
+
+const (
+	ioWriteOpType = uint64(1001)
+	ioReadOpType  = uint64(1002)
+
+	ioRequestSize  = 8 + 16 + 8 + 8 + 8
+	ioResponseSize = 8 + 8
+)
+
+// Read reads up to length bytes at offset from the file via the FastTCPPort.
+func (mount *Mount) Read(inodeNumber inode.InodeNumber, offset uint64, length uint64) (buf []byte, err error) {
+	buf, _, err = mount.client.doIO(mount, ioReadOpType, inodeNumber, offset, length, nil)
+	return
+}
+
+// Write writes buf at offset to the file via the FastTCPPort.
+func (mount *Mount) Write(inodeNumber inode.InodeNumber, offset uint64, buf []byte) (size uint64, err error) {
+	_, size, err = mount.client.doIO(mount, ioWriteOpType, inodeNumber, offset, uint64(len(buf)), buf)
+	return
+}
+
+func (client *Client) fetchFastConnection() (fastConnection net.Conn, err error) {
+	select {
+	case fastConnection = <-client.fastConnectionPool:
+		err = nil
+		return
+	default:
+	}
+
+	if 0 == client.config.Timeout {
+		fastConnection, err = net.Dial("tcp", client.fastTCPAddr)
+	} else {
+		fastConnection, err = net.DialTimeout("tcp", client.fastTCPAddr, client.config.Timeout)
+	}
+	if nil != err {
+		return
+	}
+
+	client.Lock()
+	if client.closed {
+		client.Unlock()
+		_ = fastConnection.Close()
+		err = fmt.Errorf("jrpcfsclient.Client closed")
+		return
+	}
+	client.fastConnectionSet[fastConnection] = 0
+	client.Unlock()
+
+	return
+}
+
+func (client *Client) releaseFastConnection(fastConnection net.Conn, healthy bool) {
+	if healthy {
+		select {
+		case client.fastConnectionPool <- fastConnection:
+			return
+		default:
+		}
+	}
+
+	client.Lock()
+	delete(client.fastConnectionSet, fastConnection)
+	client.Unlock()
+
+	_ = fastConnection.Close()
+}
+
+func (client *Client) doIO(mount *Mount, opType uint64, inodeNumber inode.InodeNumber, offset uint64, length uint64, writeBuf []byte) (readBuf []byte, ioSize uint64, err error) {
+	var (
+		errno          uint64
+		fastConnection net.Conn
+		request        []byte
+		response       []byte
+		retryIndex     uint16
+	)
+
+	if "" == client.fastTCPAddr {
+		err = fmt.Errorf("jrpcfsclient.Client not configured with a FastTCPPort")
+		return
+	}
+
+	request = make([]byte, ioRequestSize)
+	binary.LittleEndian.PutUint64(request[0:8], opType)
+	copy(request[8:24], mount.mountIDAsByteArray[:])
+	binary.LittleEndian.PutUint64(request[24:32], uint64(inodeNumber))
+	binary.LittleEndian.PutUint64(request[32:40], offset)
+	binary.LittleEndian.PutUint64(request[40:48], length)
+
+	response = make([]byte, ioResponseSize)
+
+	// Both reads and writes at a given offset are idempotent, so any failure of the connection is retried
+
+	for retryIndex = 0; ; retryIndex++ {
+		if client.isClosed() {
+			err = fmt.Errorf("jrpcfsclient.Client closed")
+			return
+		}
+
+		fastConnection, err = client.fetchFastConnection()
+		if nil == err {
+			readBuf, ioSize, errno, err = doIOOnConnection(fastConnection, client, opType, request, writeBuf, response)
+			client.releaseFastConnection(fastConnection, (nil == err))
+			if nil == err {
+				if 0 != errno {
+					readBuf = nil
+					err = blunder.NewError(blunder.FsError(errno), "FastTCPPort opType %d failed: errno: %d", opType, errno)
+				}
+				return
+			}
+		}
+
+		if retryIndex >= client.config.RetryLimit {
+			err = fmt.Errorf("FastTCPPort opType %d failed: %v", opType, err)
+			return
+		}
+
+		time.Sleep(client.config.RetryDelay)
+	}
+}
+
+func doIOOnConnection(fastConnection net.Conn, client *Client, opType uint64, request []byte, writeBuf []byte, response []byte) (readBuf []byte, ioSize uint64, errno uint64, err error) {
+	if 0 != client.config.Timeout {
+		err = fastConnection.SetDeadline(time.Now().Add(client.config.Timeout))
+		if nil != err {
+			return
+		}
+	}
+
+	_, err = fastConnection.Write(request)
+	if nil != err {
+		return
+	}
+
+	if ioWriteOpType == opType {
+		_, err = fastConnection.Write(writeBuf)
+		if nil != err {
+			return
+		}
+	}
+
+	_, err = io.ReadFull(fastConnection, response)
+	if nil != err {
+		return
+	}
+
+	errno = binary.LittleEndian.Uint64(response[0:8])
+	ioSize = binary.LittleEndian.Uint64(response[8:16])
+
+	if (ioReadOpType == opType) && (0 < ioSize) {
+		readBuf = make([]byte, ioSize)
+		_, err = io.ReadFull(fastConnection, readBuf)
+		if nil != err {
+			return
+		}
+	} else if ioReadOpType == opType {
+		readBuf = make([]byte, 0)
+	}
+
+	if 0 != client.config.Timeout {
+		err = fastConnection.SetDeadline(time.Time{})
+	}
+
+	return
+}
@@ -0,0 +1,175 @@
+package jrpcfsclient
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The functions in this file marshal and unmarshal individual JSON RPC messages. They are
+// used by clients (e.g. pfsagentd) that tunnel jrpcfs requests over some other transport
+// (such as the PROXYFS HTTP method of pfs_middleware) rather than using a Client.
+
+type jrpcRequestMethodAndIDStruct struct {
+	Method string `json:"method"`
+	ID     uint64 `json:"id"`
+}
+
+type jrpcRequestStruct struct {
+	JSONrpc string         `json:"jsonrpc"`
+	Method  string         `json:"method"`
+	ID      uint64         `json:"id"`
+	Params  [1]interface{} `json:"params"`
+}
+
+type jrpcResponseIDStruct struct {
+	ID uint64 `json:"id"`
+}
+
+type jrpcResponseIDAndErrorStruct struct {
+	ID    uint64 `json:"id"`
+	Error string `json:"error"`
+}
+
+type jrpcResponseNoErrorStruct struct {
+	ID     uint64      `json:"id"`
+	Result interface{} `json:"result"`
+}
+
+type jrpcResponseWithErrorStruct struct {
+	ID     uint64      `json:"id"`
+	Error  string      `json:"error"`
+	Result interface{} `json:"result"`
+}
+
+// MarshalRequest encodes a JSON RPC request for requestMethod (e.g. "Server.RpcPing").
+func MarshalRequest(requestID uint64, requestMethod string, request interface{}) (requestBuf []byte, marshalErr error) {
+	var (
+		jrpcRequest *jrpcRequestStruct
+	)
+
+	jrpcRequest = &jrpcRequestStruct{
+		JSONrpc: "2.0",
+		Method:  requestMethod,
+		ID:      requestID,
+		Params:  [1]interface{}{request},
+	}
+
+	requestBuf, marshalErr = json.Marshal(jrpcRequest)
+
+	return
+}
+
+// MarshalResponse encodes a JSON RPC response to the request identified by requestID.
+func MarshalResponse(requestID uint64, responseError error, response interface{}) (responseBuf []byte, marshalErr error) {
+	var (
+		jrpcResponse interface{}
+	)
+
+	if nil == responseError {
+		if nil == response {
+			jrpcResponse = &jrpcResponseIDStruct{
+				ID: requestID,
+			}
+		} else {
+			jrpcResponse = &jrpcResponseNoErrorStruct{
+				ID:     requestID,
+				Result: response,
+			}
+		}
+	} else {
+		if nil == response {
+			jrpcResponse = &jrpcResponseIDAndErrorStruct{
+				ID:    requestID,
+				Error: responseError.Error(),
+			}
+		} else {
+			jrpcResponse = &jrpcResponseWithErrorStruct{
+				ID:     requestID,
+				Error:  responseError.Error(),
+				Result: response,
+			}
+		}
+	}
+
+	responseBuf, marshalErr = json.Marshal(jrpcResponse)
+
+	return
+}
+
+// UnmarshalRequestForMethodAndID decodes just the method and ID of a JSON RPC request.
+func UnmarshalRequestForMethodAndID(requestBuf []byte) (requestMethod string, requestID uint64, unmarshalErr error) {
+	var (
+		jrpcRequest *jrpcRequestMethodAndIDStruct
+	)
+
+	jrpcRequest = &jrpcRequestMethodAndIDStruct{}
+
+	unmarshalErr = json.Unmarshal(requestBuf, jrpcRequest)
+
+	if nil == unmarshalErr {
+		requestMethod = jrpcRequest.Method
+		requestID = jrpcRequest.ID
+	}
+
+	return
+}
+
+// UnmarshalRequest decodes the params of a JSON RPC request into request.
+func UnmarshalRequest(requestID uint64, requestBuf []byte, request interface{}) (unmarshalErr error) {
+	var (
+		jrpcRequest *jrpcRequestStruct
+	)
+
+	jrpcRequest = &jrpcRequestStruct{
+		Params: [1]interface{}{request},
+	}
+
+	unmarshalErr = json.Unmarshal(requestBuf, jrpcRequest)
+
+	if (nil == unmarshalErr) && (requestID != jrpcRequest.ID) {
+		unmarshalErr = fmt.Errorf("requestID mismatch")
+	}
+
+	return
+}
+
+// UnmarshalResponseForIDAndError decodes just the ID and error of a JSON RPC response.
+func UnmarshalResponseForIDAndError(responseBuf []byte) (requestID uint64, responseErr error, unmarshalErr error) {
+	var (
+		jrpcResponse *jrpcResponseIDAndErrorStruct
+	)
+
+	jrpcResponse = &jrpcResponseIDAndErrorStruct{}
+
+	unmarshalErr = json.Unmarshal(responseBuf, jrpcResponse)
+
+	if nil == unmarshalErr {
+		requestID = jrpcResponse.ID
+		if "" == jrpcResponse.Error {
+			responseErr = nil
+		} else {
+			responseErr = fmt.Errorf("%s", jrpcResponse.Error)
+		}
+	}
+
+	return
+}
+
+// UnmarshalResponse decodes the result of a JSON RPC response into response.
+func UnmarshalResponse(requestID uint64, responseBuf []byte, response interface{}) (unmarshalErr error) {
+	var (
+		jrpcResponse *jrpcResponseWithErrorStruct
+	)
+
+	jrpcResponse = &jrpcResponseWithErrorStruct{
+		Result: response,
+	}
+
+	unmarshalErr = json.Unmarshal(responseBuf, jrpcResponse)
+
+	if (nil == unmarshalErr) && (requestID != jrpcResponse.ID) {
+		unmarshalErr = fmt.Errorf("requestID mismatch")
+	}
+
+	return
+}
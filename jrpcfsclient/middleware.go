@@ -0,0 +1,228 @@
+package jrpcfsclient
+
+import (
+	"github.com/swiftstack/ProxyFS/fs"
+	"github.com/swiftstack/ProxyFS/jrpcfs"
+)
+
+// The methods in this file map one-to-one onto the Server.Rpc* methods found in jrpcfs/middleware.go
+// used by Swift middleware for bimodal support. As InodeNumbers in these replies are opaque to the
+// middleware, the jrpcfs reply structs are returned as-is (see Int64ToInodeNumber()).
+
+// Coalesce invokes RpcCoalesce.
+func (client *Client) Coalesce(virtPath string, elementAccountRelativePaths []string) (reply *jrpcfs.CoalesceReply, err error) {
+	request := &jrpcfs.CoalesceReq{
+		VirtPath:                    virtPath,
+		ElementAccountRelativePaths: elementAccountRelativePaths,
+	}
+	reply = &jrpcfs.CoalesceReply{}
+	err = client.call("RpcCoalesce", request, reply)
+	return
+}
+
+// Copy invokes RpcCopy. The new object at virtPath shares the log segments of the object at
+// srcAccountRelativePath (e.g. "some-container/some-object") and has its metadata replaced by metadata.
+func (client *Client) Copy(virtPath string, srcAccountRelativePath string, metadata []byte) (reply *jrpcfs.CopyReply, err error) {
+	request := &jrpcfs.CopyReq{
+		VirtPath:               virtPath,
+		SrcAccountRelativePath: srcAccountRelativePath,
+		Metadata:               metadata,
+	}
+	reply = &jrpcfs.CopyReply{}
+	err = client.call("RpcCopy", request, reply)
+	return
+}
+
+// CreateContainer invokes RpcCreateContainer.
+func (client *Client) CreateContainer(virtPath string) (err error) {
+	request := &jrpcfs.CreateContainerRequest{
+		VirtPath: virtPath,
+	}
+	err = client.call("RpcCreateContainer", request, &jrpcfs.CreateContainerReply{})
+	return
+}
+
+// Delete invokes RpcDelete.
+func (client *Client) Delete(virtPath string) (err error) {
+	request := &jrpcfs.DeleteReq{
+		VirtPath: virtPath,
+	}
+	err = client.call("RpcDelete", request, &jrpcfs.DeleteReply{})
+	return
+}
+
+// GetAccount invokes RpcGetAccount.
+func (client *Client) GetAccount(virtPath string, marker string, endMarker string, maxEntries uint64) (reply *jrpcfs.GetAccountReply, err error) {
+	request := &jrpcfs.GetAccountReq{
+		VirtPath:   virtPath,
+		Marker:     marker,
+		EndMarker:  endMarker,
+		MaxEntries: maxEntries,
+	}
+	reply = &jrpcfs.GetAccountReply{}
+	err = client.call("RpcGetAccount", request, reply)
+	return
+}
+
+// GetContainer invokes RpcGetContainer.
+func (client *Client) GetContainer(request *jrpcfs.GetContainerReq) (reply *jrpcfs.GetContainerReply, err error) {
+	reply = &jrpcfs.GetContainerReply{}
+	err = client.call("RpcGetContainer", request, reply)
+	return
+}
+
+// GetObject invokes RpcGetObject. To obtain a read plan for the entire object, pass an empty readEntsIn.
+func (client *Client) GetObject(virtPath string, readEntsIn []fs.ReadRangeIn) (reply *jrpcfs.GetObjectReply, err error) {
+	request := &jrpcfs.GetObjectReq{
+		VirtPath:   virtPath,
+		ReadEntsIn: readEntsIn,
+	}
+	reply = &jrpcfs.GetObjectReply{}
+	err = client.call("RpcGetObject", request, reply)
+	return
+}
+
+// Head invokes RpcHead.
+func (client *Client) Head(virtPath string) (reply *jrpcfs.HeadReply, err error) {
+	request := &jrpcfs.HeadReq{
+		VirtPath: virtPath,
+	}
+	reply = &jrpcfs.HeadReply{}
+	err = client.call("RpcHead", request, reply)
+	return
+}
+
+// IsAccountBimodal invokes RpcIsAccountBimodal.
+func (client *Client) IsAccountBimodal(accountName string) (isBimodal bool, activePeerPrivateIPAddr string, err error) {
+	request := &jrpcfs.IsAccountBimodalReq{
+		AccountName: accountName,
+	}
+	reply := &jrpcfs.IsAccountBimodalReply{}
+	err = client.call("RpcIsAccountBimodal", request, reply)
+	if nil == err {
+		isBimodal = reply.IsBimodal
+		activePeerPrivateIPAddr = reply.ActivePeerPrivateIPAddr
+	}
+	return
+}
+
+// ListVersions invokes RpcListVersions. The versions are returned oldest first.
+func (client *Client) ListVersions(virtPath string) (versions []fs.VersionEntry, err error) {
+	request := &jrpcfs.ListVersionsReq{
+		VirtPath: virtPath,
+	}
+	reply := &jrpcfs.ListVersionsReply{}
+	err = client.call("RpcListVersions", request, reply)
+	if nil == err {
+		versions = reply.Versions
+	}
+	return
+}
+
+// MiddlewareMkdir invokes RpcMiddlewareMkdir.
+func (client *Client) MiddlewareMkdir(virtPath string, metadata []byte) (reply *jrpcfs.MiddlewareMkdirReply, err error) {
+	request := &jrpcfs.MiddlewareMkdirReq{
+		VirtPath: virtPath,
+		Metadata: metadata,
+	}
+	reply = &jrpcfs.MiddlewareMkdirReply{}
+	err = client.call("RpcMiddlewareMkdir", request, reply)
+	return
+}
+
+// Post invokes RpcPost.
+func (client *Client) Post(virtPath string, newMetaData []byte, oldMetaData []byte) (err error) {
+	request := &jrpcfs.MiddlewarePostReq{
+		VirtPath:    virtPath,
+		NewMetaData: newMetaData,
+		OldMetaData: oldMetaData,
+	}
+	err = client.call("RpcPost", request, &jrpcfs.MiddlewarePostReply{})
+	return
+}
+
+// PutComplete invokes RpcPutComplete.
+func (client *Client) PutComplete(virtPath string, physPaths []string, physLengths []uint64, metadata []byte) (reply *jrpcfs.PutCompleteReply, err error) {
+	request := &jrpcfs.PutCompleteReq{
+		VirtPath:    virtPath,
+		PhysPaths:   physPaths,
+		PhysLengths: physLengths,
+		Metadata:    metadata,
+	}
+	reply = &jrpcfs.PutCompleteReply{}
+	err = client.call("RpcPutComplete", request, reply)
+	return
+}
+
+// PutContainer invokes RpcPutContainer.
+func (client *Client) PutContainer(virtPath string, newMetadata []byte, oldMetadata []byte) (err error) {
+	request := &jrpcfs.PutContainerReq{
+		VirtPath:    virtPath,
+		NewMetadata: newMetadata,
+		OldMetadata: oldMetadata,
+	}
+	err = client.call("RpcPutContainer", request, &jrpcfs.PutContainerReply{})
+	return
+}
+
+// PutLocation invokes RpcPutLocation.
+func (client *Client) PutLocation(virtPath string) (physPath string, err error) {
+	request := &jrpcfs.PutLocationReq{
+		VirtPath: virtPath,
+	}
+	reply := &jrpcfs.PutLocationReply{}
+	err = client.call("RpcPutLocation", request, reply)
+	if nil == err {
+		physPath = reply.PhysPath
+	}
+	return
+}
+
+// ReleaseLease invokes RpcReleaseLease.
+func (client *Client) ReleaseLease(leaseID string) (err error) {
+	request := &jrpcfs.ReleaseLeaseReq{
+		LeaseId: leaseID,
+	}
+	err = client.call("RpcReleaseLease", request, &jrpcfs.ReleaseLeaseReply{})
+	return
+}
+
+// RenewLease invokes RpcRenewLease.
+func (client *Client) RenewLease(leaseID string) (err error) {
+	request := &jrpcfs.RenewLeaseReq{
+		LeaseId: leaseID,
+	}
+	err = client.call("RpcRenewLease", request, &jrpcfs.RenewLeaseReply{})
+	return
+}
+
+// RestoreVersion invokes RpcRestoreVersion.
+func (client *Client) RestoreVersion(virtPath string, versionID string) (err error) {
+	request := &jrpcfs.RestoreVersionReq{
+		VirtPath:  virtPath,
+		VersionID: versionID,
+	}
+	err = client.call("RpcRestoreVersion", request, &jrpcfs.RestoreVersionReply{})
+	return
+}
+
+// SetExpiration invokes RpcSetExpiration. An expireAt (seconds since epoch) of zero clears any expiration.
+func (client *Client) SetExpiration(virtPath string, expireAt uint64) (err error) {
+	request := &jrpcfs.SetExpirationReq{
+		VirtPath: virtPath,
+		ExpireAt: expireAt,
+	}
+	err = client.call("RpcSetExpiration", request, &jrpcfs.SetExpirationReply{})
+	return
+}
+
+// SetVersioning invokes RpcSetVersioning. Mode is one of the fs.VersioningMode* values.
+func (client *Client) SetVersioning(virtPath string, mode string, maxVersions uint64) (err error) {
+	request := &jrpcfs.SetVersioningReq{
+		VirtPath:    virtPath,
+		Mode:        mode,
+		MaxVersions: maxVersions,
+	}
+	err = client.call("RpcSetVersioning", request, &jrpcfs.SetVersioningReply{})
+	return
+}
@@ -0,0 +1,164 @@
+package jrpcfsclient
+
+import (
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/ramswift"
+	"github.com/swiftstack/ProxyFS/transitions"
+)
+
+const (
+	testIPAddr      = "127.0.0.1"
+	testTCPPort     = uint16(12347) // 12347 instead of 12345 so that test can run if proxyfsd is already running
+	testFastTCPPort = uint16(32347) // ...and similarly here...
+	testVolumeName  = "TestVolume"
+	testAccountName = "AUTH_test"
+)
+
+var (
+	testConfMap          conf.ConfMap
+	testRamswiftDoneChan chan bool // our test chan used during testTeardown() to know ramswift is, indeed, down
+)
+
+func testSetup(t *testing.T) {
+	var (
+		err                    error
+		signalHandlerIsArmedWG sync.WaitGroup
+		testConfMapStrings     []string
+	)
+
+	testConfMapStrings = []string{
+		"Stats.IPAddr=localhost",
+		"Stats.UDPPort=52184",
+		"Stats.BufferLength=100",
+		"Stats.MaxLatency=1s",
+		"Logging.LogFilePath=/dev/null",
+		"Logging.LogToConsole=false",
+		"SwiftClient.NoAuthIPAddr=127.0.0.1",
+		"SwiftClient.NoAuthTCPPort=35263",
+		"SwiftClient.Timeout=10s",
+		"SwiftClient.RetryLimit=3",
+		"SwiftClient.RetryLimitObject=3",
+		"SwiftClient.RetryDelay=10ms",
+		"SwiftClient.RetryDelayObject=10ms",
+		"SwiftClient.RetryExpBackoff=1.2",
+		"SwiftClient.RetryExpBackoffObject=2.0",
+		"SwiftClient.ChunkedConnectionPoolSize=64",
+		"SwiftClient.NonChunkedConnectionPoolSize=32",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainerStoragePolicy=silver",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainerNamePrefix=Replicated3Way_",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.ContainersPerPeer=10",
+		"PhysicalContainerLayout:PhysicalContainerLayoutReplicated3Way.MaxObjectsPerContainer=1000000",
+		"Peer:Peer0.PrivateIPAddr=" + testIPAddr,
+		"Peer:Peer0.ReadCacheQuotaFraction=0.20",
+		"Cluster.Peers=Peer0",
+		"Cluster.WhoAmI=Peer0",
+		"Volume:TestVolume.FSID=1",
+		"Volume:TestVolume.AccountName=" + testAccountName,
+		"Volume:TestVolume.AutoFormat=true",
+		"Volume:TestVolume.CheckpointContainerName=.__checkpoint__",
+		"Volume:TestVolume.CheckpointContainerStoragePolicy=gold",
+		"Volume:TestVolume.CheckpointInterval=10s",
+		"Volume:TestVolume.DefaultPhysicalContainerLayout=PhysicalContainerLayoutReplicated3Way",
+		"Volume:TestVolume.MaxFlushSize=10000000",
+		"Volume:TestVolume.MaxFlushTime=10s",
+		"Volume:TestVolume.NonceValuesToReserve=100",
+		"Volume:TestVolume.MaxEntriesPerDirNode=32",
+		"Volume:TestVolume.MaxExtentsPerFileNode=32",
+		"Volume:TestVolume.MaxInodesPerMetadataNode=32",
+		"Volume:TestVolume.MaxLogSegmentsPerMetadataNode=64",
+		"Volume:TestVolume.MaxDirFileNodesPerMetadataNode=16",
+		"Volume:TestVolume.MaxBytesInodeCache=100000",
+		"Volume:TestVolume.TrashEnabled=true",
+		"Volume:TestVolume.InodeCacheEvictInterval=1s",
+		"VolumeGroup:TestVolumeGroup.VolumeList=TestVolume",
+		"VolumeGroup:TestVolumeGroup.VirtualIPAddr=",
+		"VolumeGroup:TestVolumeGroup.PrimaryPeer=Peer0",
+		"VolumeGroup:TestVolumeGroup.ReadCacheLineSize=1000000",
+		"VolumeGroup:TestVolumeGroup.ReadCacheWeight=100",
+		"FSGlobals.VolumeGroupList=TestVolumeGroup",
+		"FSGlobals.TryLockBackoffMin=100us",
+		"FSGlobals.TryLockBackoffMax=300us",
+		"FSGlobals.SymlinkMax=32",
+		"FSGlobals.InodeRecCacheEvictLowLimit=10000",
+		"FSGlobals.InodeRecCacheEvictHighLimit=10010",
+		"FSGlobals.LogSegmentRecCacheEvictLowLimit=10000",
+		"FSGlobals.LogSegmentRecCacheEvictHighLimit=10010",
+		"FSGlobals.BPlusTreeObjectCacheEvictLowLimit=10000",
+		"FSGlobals.BPlusTreeObjectCacheEvictHighLimit=10010",
+		"FSGlobals.DirEntryCacheEvictLowLimit=10000",
+		"FSGlobals.DirEntryCacheEvictHighLimit=10010",
+		"FSGlobals.FileExtentMapEvictLowLimit=10000",
+		"FSGlobals.FileExtentMapEvictHighLimit=10010",
+		"RamSwiftInfo.MaxAccountNameLength=256",
+		"RamSwiftInfo.MaxContainerNameLength=256",
+		"RamSwiftInfo.MaxObjectNameLength=1024",
+		"RamSwiftInfo.AccountListingLimit=10000",
+		"RamSwiftInfo.ContainerListingLimit=10000",
+		"JSONRPCServer.TCPPort=12347",
+		"JSONRPCServer.FastTCPPort=32347",
+		"JSONRPCServer.DataPathLogging=false",
+	}
+
+	testConfMap, err = conf.MakeConfMapFromStrings(testConfMapStrings)
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings() failed: %v", err)
+	}
+
+	signalHandlerIsArmedWG.Add(1)
+	testRamswiftDoneChan = make(chan bool, 1)
+	go ramswift.Daemon("/dev/null", testConfMapStrings, &signalHandlerIsArmedWG, testRamswiftDoneChan, unix.SIGTERM)
+
+	signalHandlerIsArmedWG.Wait()
+
+	err = transitions.Up(testConfMap)
+	if nil != err {
+		t.Fatalf("transitions.Up() failed: %v", err)
+	}
+}
+
+func testTeardown(t *testing.T) {
+	var (
+		err error
+	)
+
+	err = transitions.Down(testConfMap)
+	if nil != err {
+		t.Fatalf("transitions.Down() failed: %v", err)
+	}
+
+	_ = syscall.Kill(syscall.Getpid(), unix.SIGTERM)
+	_ = <-testRamswiftDoneChan
+
+	// Run GC to reclaim memory before we proceed to next test
+	runtime.GC()
+}
+
+func testNewClient(t *testing.T) (client *Client) {
+	var (
+		err error
+	)
+
+	client, err = NewClient(&ConfigStruct{
+		IPAddr:                 testIPAddr,
+		TCPPort:                testTCPPort,
+		FastTCPPort:            testFastTCPPort,
+		ConnectionPoolSize:     2,
+		FastConnectionPoolSize: 2,
+		Timeout:                10 * time.Second,
+		RetryLimit:             3,
+		RetryDelay:             10 * time.Millisecond,
+	})
+	if nil != err {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+
+	return
+}
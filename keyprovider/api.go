@@ -0,0 +1,80 @@
+// Package keyprovider supplies the keys used to encrypt a volume's data and metadata at rest.
+//
+// Encryption follows an envelope scheme: each encrypted volume has its own randomly generated
+// DataKey that is used (via AES-256-GCM) to seal LogSegment data and checkpoint B+Tree nodes.
+// That DataKey is itself only ever persisted in wrapped (i.e. encrypted) form by a KeyProvider
+// holding the key encryption keys. The KeyProvider used for a volume is selected by the
+// Volume:<name>.EncryptionKeyProvider option naming a KeyProvider:<name> section whose Type
+// selects the implementation.
+package keyprovider
+
+import (
+	"fmt"
+
+	"github.com/swiftstack/ProxyFS/conf"
+)
+
+// KeyProvider is implemented by each supported source of key encryption keys.
+type KeyProvider interface {
+	// CurrentKeyID returns the ID of the key encryption key to be used to wrap new DataKeys.
+	// Key IDs never contain whitespace.
+	CurrentKeyID() (keyID string, err error)
+	// WrapKey encrypts dataKey with the identified key encryption key.
+	WrapKey(keyID string, dataKey []byte) (wrappedKey []byte, err error)
+	// UnwrapKey recovers a dataKey previously returned by WrapKey() for the same keyID.
+	UnwrapKey(keyID string, wrappedKey []byte) (dataKey []byte, err error)
+}
+
+// DataKey seals and opens data with a volume's (unwrapped) data key. Sealed data is prefixed
+// with a random nonce and followed by an authentication tag, adding SealOverhead bytes. The
+// additionalData supplied to Open() must match that supplied to Seal().
+type DataKey interface {
+	Seal(plaintext []byte, additionalData []byte) (ciphertext []byte)
+	Open(ciphertext []byte, additionalData []byte) (plaintext []byte, err error)
+}
+
+// FetchKeyProvider returns the KeyProvider configured for the named Volume:<name> section.
+// A nil keyProvider (and nil err) is returned if the volume does not specify one.
+func FetchKeyProvider(confMap conf.ConfMap, volumeSectionName string) (keyProvider KeyProvider, err error) {
+	var (
+		keyProviderName        string
+		keyProviderSectionName string
+		keyProviderType        string
+		newKeyProvider         newKeyProviderFunc
+		ok                     bool
+	)
+
+	keyProviderName, err = confMap.FetchOptionValueString(volumeSectionName, "EncryptionKeyProvider")
+	if (nil != err) || ("" == keyProviderName) {
+		keyProvider = nil
+		err = nil
+		return
+	}
+
+	keyProviderSectionName = "KeyProvider:" + keyProviderName
+
+	keyProviderType, err = confMap.FetchOptionValueString(keyProviderSectionName, "Type")
+	if nil != err {
+		return
+	}
+
+	newKeyProvider, ok = keyProviderTypeMap[keyProviderType]
+	if !ok {
+		err = fmt.Errorf("%s.Type (%s) not supported", keyProviderSectionName, keyProviderType)
+		return
+	}
+
+	keyProvider, err = newKeyProvider(confMap, keyProviderSectionName)
+
+	return
+}
+
+// GenerateDataKey returns a fresh (random) data key suitable for passing to NewDataKey().
+func GenerateDataKey() (dataKeyBytes []byte, err error) {
+	return generateDataKey()
+}
+
+// NewDataKey returns a DataKey that seals and opens data with the supplied (unwrapped) data key.
+func NewDataKey(dataKeyBytes []byte) (dataKey DataKey, err error) {
+	return newDataKey(dataKeyBytes)
+}
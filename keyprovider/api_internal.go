@@ -0,0 +1,16 @@
+package keyprovider
+
+import (
+	"github.com/swiftstack/ProxyFS/conf"
+)
+
+// newKeyProviderFunc is the signature of the func registered for each KeyProvider type that will
+// construct an instance of that KeyProvider as described by the named confMap section.
+type newKeyProviderFunc func(confMap conf.ConfMap, keyProviderSectionName string) (keyProvider KeyProvider, err error)
+
+var keyProviderTypeMap = make(map[string]newKeyProviderFunc) // Key: KeyProvider:<name>.Type
+
+// registerKeyProviderType should be called from the init() func of each file implementing a KeyProvider.
+func registerKeyProviderType(keyProviderType string, newKeyProvider newKeyProviderFunc) {
+	keyProviderTypeMap[keyProviderType] = newKeyProvider
+}
@@ -0,0 +1,133 @@
+package keyprovider
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/conf"
+)
+
+func TestDataKey(t *testing.T) {
+	dataKeyBytes, err := GenerateDataKey()
+	if nil != err {
+		t.Fatalf("GenerateDataKey() failed: %v", err)
+	}
+
+	dataKey, err := NewDataKey(dataKeyBytes)
+	if nil != err {
+		t.Fatalf("NewDataKey() failed: %v", err)
+	}
+
+	plaintext := []byte("some file data")
+
+	ciphertext := dataKey.Seal(plaintext, []byte("where"))
+	if len(ciphertext) != (len(plaintext) + SealOverhead) {
+		t.Fatalf("Seal() returned %d bytes... expected %d", len(ciphertext), len(plaintext)+SealOverhead)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Fatalf("Seal() returned plaintext")
+	}
+
+	openedPlaintext, err := dataKey.Open(ciphertext, []byte("where"))
+	if nil != err {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if !bytes.Equal(plaintext, openedPlaintext) {
+		t.Fatalf("Open() returned unexpected plaintext")
+	}
+
+	_, err = dataKey.Open(ciphertext, []byte("elsewhere"))
+	if nil == err {
+		t.Fatalf("Open() with mismatched additionalData should have failed")
+	}
+
+	ciphertext[SealOverhead/2]++
+	_, err = dataKey.Open(ciphertext, []byte("where"))
+	if nil == err {
+		t.Fatalf("Open() of tampered ciphertext should have failed")
+	}
+
+	_, err = NewDataKey(dataKeyBytes[1:])
+	if nil == err {
+		t.Fatalf("NewDataKey() of short key should have failed")
+	}
+}
+
+func TestKeyFileKeyProvider(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "keyprovider")
+	if nil != err {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(testDir)
+
+	keyFile := filepath.Join(testDir, "volume.keys")
+
+	err = ioutil.WriteFile(keyFile, []byte(
+		"# keyID key\n"+
+			"old 000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F\n"+
+			"\n"+
+			"new 202122232425262728292A2B2C2D2E2F303132333435363738393A3B3C3D3E3F\n"), 0600)
+	if nil != err {
+		t.Fatalf("ioutil.WriteFile() failed: %v", err)
+	}
+
+	confMap, err := conf.MakeConfMapFromStrings([]string{
+		"Volume:TestVolume.EncryptionKeyProvider=TestKeyProvider",
+		"Volume:PlainVolume.AccountName=AUTH_plain",
+		"KeyProvider:TestKeyProvider.Type=KeyFile",
+		"KeyProvider:TestKeyProvider.KeyFile=" + keyFile,
+	})
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings() failed: %v", err)
+	}
+
+	keyProvider, err := FetchKeyProvider(confMap, "Volume:PlainVolume")
+	if (nil != err) || (nil != keyProvider) {
+		t.Fatalf("FetchKeyProvider() of volume without EncryptionKeyProvider should have returned nil, nil")
+	}
+
+	keyProvider, err = FetchKeyProvider(confMap, "Volume:TestVolume")
+	if nil != err {
+		t.Fatalf("FetchKeyProvider() failed: %v", err)
+	}
+
+	keyID, err := keyProvider.CurrentKeyID()
+	if (nil != err) || ("new" != keyID) {
+		t.Fatalf("CurrentKeyID() returned %v, %v... expected \"new\"", keyID, err)
+	}
+
+	dataKeyBytes, err := GenerateDataKey()
+	if nil != err {
+		t.Fatalf("GenerateDataKey() failed: %v", err)
+	}
+
+	for _, keyID = range []string{"old", "new"} {
+		wrappedKey, err := keyProvider.WrapKey(keyID, dataKeyBytes)
+		if nil != err {
+			t.Fatalf("WrapKey(%s,) failed: %v", keyID, err)
+		}
+		unwrappedKey, err := keyProvider.UnwrapKey(keyID, wrappedKey)
+		if nil != err {
+			t.Fatalf("UnwrapKey(%s,) failed: %v", keyID, err)
+		}
+		if !bytes.Equal(dataKeyBytes, unwrappedKey) {
+			t.Fatalf("UnwrapKey(%s,) returned unexpected key", keyID)
+		}
+	}
+
+	wrappedKey, err := keyProvider.WrapKey("old", dataKeyBytes)
+	if nil != err {
+		t.Fatalf("WrapKey() failed: %v", err)
+	}
+	_, err = keyProvider.UnwrapKey("new", wrappedKey)
+	if nil == err {
+		t.Fatalf("UnwrapKey() with the wrong keyID should have failed")
+	}
+	_, err = keyProvider.WrapKey("missing", dataKeyBytes)
+	if nil == err {
+		t.Fatalf("WrapKey() with unknown keyID should have failed")
+	}
+}
@@ -0,0 +1,100 @@
+package keyprovider
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+)
+
+const (
+	// DataKeySize is the length of an (unwrapped) data key selecting AES-256.
+	DataKeySize = 32
+
+	dataKeyNonceSize = 12
+	dataKeyTagSize   = 16
+
+	// SealOverhead is the number of bytes DataKey.Seal() adds to its plaintext.
+	SealOverhead = dataKeyNonceSize + dataKeyTagSize
+)
+
+type dataKeyStruct struct {
+	aead cipher.AEAD
+}
+
+func generateDataKey() (dataKeyBytes []byte, err error) {
+	dataKeyBytes = make([]byte, DataKeySize)
+
+	_, err = rand.Read(dataKeyBytes)
+
+	return
+}
+
+func newAESGCM(key []byte) (aead cipher.AEAD, err error) {
+	var (
+		block cipher.Block
+	)
+
+	if DataKeySize != len(key) {
+		err = fmt.Errorf("key length (%d) must be %d", len(key), DataKeySize)
+		return
+	}
+
+	block, err = aes.NewCipher(key)
+	if nil != err {
+		return
+	}
+
+	aead, err = cipher.NewGCMWithNonceSize(block, dataKeyNonceSize)
+
+	return
+}
+
+func newDataKey(dataKeyBytes []byte) (dataKey DataKey, err error) {
+	var (
+		aead cipher.AEAD
+	)
+
+	aead, err = newAESGCM(dataKeyBytes)
+	if nil != err {
+		return
+	}
+
+	dataKey = &dataKeyStruct{aead: aead}
+
+	return
+}
+
+func sealAESGCM(aead cipher.AEAD, plaintext []byte, additionalData []byte) (ciphertext []byte) {
+	ciphertext = make([]byte, dataKeyNonceSize, dataKeyNonceSize+len(plaintext)+dataKeyTagSize)
+
+	_, err := rand.Read(ciphertext)
+	if nil != err {
+		panic(fmt.Errorf("crypto/rand.Read() failed: %v", err))
+	}
+
+	ciphertext = aead.Seal(ciphertext, ciphertext[:dataKeyNonceSize], plaintext, additionalData)
+
+	return
+}
+
+func openAESGCM(aead cipher.AEAD, ciphertext []byte, additionalData []byte) (plaintext []byte, err error) {
+	if SealOverhead > len(ciphertext) {
+		err = fmt.Errorf("sealed data too short (%d bytes)", len(ciphertext))
+		return
+	}
+
+	plaintext, err = aead.Open(nil, ciphertext[:dataKeyNonceSize], ciphertext[dataKeyNonceSize:], additionalData)
+
+	return
+}
+
+func (dataKey *dataKeyStruct) Seal(plaintext []byte, additionalData []byte) (ciphertext []byte) {
+	ciphertext = sealAESGCM(dataKey.aead, plaintext, additionalData)
+	return
+}
+
+func (dataKey *dataKeyStruct) Open(ciphertext []byte, additionalData []byte) (plaintext []byte, err error) {
+	plaintext, err = openAESGCM(dataKey.aead, ciphertext, additionalData)
+	return
+}
@@ -0,0 +1,139 @@
+// Local key file KeyProvider
+
+package keyprovider
+
+import (
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/swiftstack/ProxyFS/conf"
+)
+
+// KeyFileKeyProviderType is the KeyProvider:<name>.Type for a KeyProvider whose key encryption
+// keys are read from the local file named by KeyProvider:<name>.KeyFile. Each non-empty line
+// not beginning with '#' holds a key ID followed by whitespace and a hex-encoded 32 byte key:
+//
+//   # keyID  key
+//   2019-01  00112233445566778899AABBCCDDEEFF00112233445566778899AABBCCDDEEFF
+//
+// The last key listed is used to wrap new DataKeys. Older keys should be retained for as long
+// as any volume's checkpoint header references them.
+const KeyFileKeyProviderType = "KeyFile"
+
+type keyFileKeyProviderStruct struct {
+	keyFile      string
+	currentKeyID string
+	aeadMap      map[string]cipher.AEAD // Key: keyID
+}
+
+func init() {
+	registerKeyProviderType(KeyFileKeyProviderType, newKeyFileKeyProvider)
+}
+
+func newKeyFileKeyProvider(confMap conf.ConfMap, keyProviderSectionName string) (keyProvider KeyProvider, err error) {
+	var (
+		aead               cipher.AEAD
+		fields             []string
+		key                []byte
+		keyFileBuf         []byte
+		keyFileKeyProvider *keyFileKeyProviderStruct
+		line               string
+		lineIndex          int
+		ok                 bool
+	)
+
+	keyFileKeyProvider = &keyFileKeyProviderStruct{
+		aeadMap: make(map[string]cipher.AEAD),
+	}
+
+	keyFileKeyProvider.keyFile, err = confMap.FetchOptionValueString(keyProviderSectionName, "KeyFile")
+	if nil != err {
+		return
+	}
+
+	keyFileBuf, err = ioutil.ReadFile(keyFileKeyProvider.keyFile)
+	if nil != err {
+		return
+	}
+
+	for lineIndex, line = range strings.Split(string(keyFileBuf), "\n") {
+		line = strings.TrimSpace(line)
+		if ("" == line) || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields = strings.Fields(line)
+		if 2 != len(fields) {
+			err = fmt.Errorf("%s line %d: expected \"<keyID> <key>\"", keyFileKeyProvider.keyFile, lineIndex+1)
+			return
+		}
+
+		_, ok = keyFileKeyProvider.aeadMap[fields[0]]
+		if ok {
+			err = fmt.Errorf("%s line %d: duplicate keyID %s", keyFileKeyProvider.keyFile, lineIndex+1, fields[0])
+			return
+		}
+
+		key, err = hex.DecodeString(fields[1])
+		if nil != err {
+			err = fmt.Errorf("%s line %d: bad key: %v", keyFileKeyProvider.keyFile, lineIndex+1, err)
+			return
+		}
+
+		aead, err = newAESGCM(key)
+		if nil != err {
+			err = fmt.Errorf("%s line %d: bad key: %v", keyFileKeyProvider.keyFile, lineIndex+1, err)
+			return
+		}
+
+		keyFileKeyProvider.aeadMap[fields[0]] = aead
+		keyFileKeyProvider.currentKeyID = fields[0]
+	}
+
+	if "" == keyFileKeyProvider.currentKeyID {
+		err = fmt.Errorf("%s contains no keys", keyFileKeyProvider.keyFile)
+		return
+	}
+
+	keyProvider = keyFileKeyProvider
+	err = nil
+	return
+}
+
+func (keyFileKeyProvider *keyFileKeyProviderStruct) CurrentKeyID() (keyID string, err error) {
+	keyID = keyFileKeyProvider.currentKeyID
+	err = nil
+	return
+}
+
+func (keyFileKeyProvider *keyFileKeyProviderStruct) WrapKey(keyID string, dataKey []byte) (wrappedKey []byte, err error) {
+	aead, ok := keyFileKeyProvider.aeadMap[keyID]
+	if !ok {
+		err = fmt.Errorf("%s has no keyID %s", keyFileKeyProvider.keyFile, keyID)
+		return
+	}
+
+	wrappedKey = sealAESGCM(aead, dataKey, []byte(keyID))
+	err = nil
+	return
+}
+
+func (keyFileKeyProvider *keyFileKeyProviderStruct) UnwrapKey(keyID string, wrappedKey []byte) (dataKey []byte, err error) {
+	aead, ok := keyFileKeyProvider.aeadMap[keyID]
+	if !ok {
+		err = fmt.Errorf("%s has no keyID %s", keyFileKeyProvider.keyFile, keyID)
+		return
+	}
+
+	dataKey, err = openAESGCM(aead, wrappedKey, []byte(keyID))
+	if nil != err {
+		err = fmt.Errorf("%s keyID %s failed to unwrap key: %v", keyFileKeyProvider.keyFile, keyID, err)
+		return
+	}
+
+	err = nil
+	return
+}
@@ -2,32 +2,15 @@ package liveness
 
 import (
 	"container/list"
-	"encoding/json"
 	"fmt"
-	"net"
 	"reflect"
 	"time"
 
-	"github.com/swiftstack/ProxyFS/jrpcfs"
+	"github.com/swiftstack/ProxyFS/jrpcfsclient"
 	"github.com/swiftstack/ProxyFS/logger"
 	"github.com/swiftstack/ProxyFS/utils"
 )
 
-type pingReqStruct struct {
-	JSONrpc string            `json:"jsonrpc"`
-	Method  string            `json:"method"`
-	Params  [1]jrpcfs.PingReq `json:"params"`
-	ID      uint64            `json:"id"`
-}
-
-type pingReplyStruct struct {
-	ID     uint64           `json:"id"`
-	Result jrpcfs.PingReply `json:"result"`
-	Error  string           `json:"error"`
-}
-
-const maxRPCReplySize = 4096
-
 func livenessChecker() {
 	var (
 		checkEntityList                 *list.List
@@ -150,17 +133,10 @@ func livenessChecker() {
 
 func livenessCheckServingPeer(servingPeer *internalServingPeerReportStruct) {
 	var (
+		client           *jrpcfsclient.Client
+		clientConfig     *jrpcfsclient.ConfigStruct
 		err              error
-		nextID           uint64
-		pingReply        pingReplyStruct
-		pingReplyBuf     []byte
-		pingReplyLen     int
-		pingReq          pingReqStruct
-		pingReqBuf       []byte
 		servingPeerState string
-		tcpAddr          *net.TCPAddr
-		tcpAddrToResolve string
-		tcpConn          *net.TCPConn
 		timeNow          time.Time
 	)
 
@@ -176,32 +152,22 @@ func livenessCheckServingPeer(servingPeer *internalServingPeerReportStruct) {
 		globals.Unlock()
 	}()
 
-	// Form RpcPing message to poll servingPeer's JSONRPCServer.TCPPort with
-
-	pingReq.JSONrpc = "2.0"
-	pingReq.Method = "Server.RpcPing"
-	pingReq.Params[0].Message = "Ping at " + timeNow.Format(time.RFC3339)
-	pingReq.ID = nextID
+	// Form jrpcfsclient.Client to poll servingPeer's JSONRPCServer.TCPPort with
 
-	pingReqBuf, err = json.Marshal(pingReq)
-	if nil != err {
-		err = fmt.Errorf("json.Marshal(pingReq) failed: %v", err)
-		logger.Error(err)
-		return
+	clientConfig = &jrpcfsclient.ConfigStruct{
+		TCPPort:    globals.jsonRPCServerPort,
+		RetryLimit: 0,
 	}
 
-	// Compute tcpAddr of servingPeer's JSONRPCServer.TCPPort
-
 	if servingPeer.name == globals.whoAmI {
-		tcpAddrToResolve = net.JoinHostPort(globals.myPrivateIPAddr.String(), fmt.Sprintf("%d", globals.jsonRPCServerPort))
+		clientConfig.IPAddr = globals.myPrivateIPAddr.String()
 	} else {
-		tcpAddrToResolve = net.JoinHostPort(globals.peersByName[servingPeer.name].privateIPAddr.String(), fmt.Sprintf("%d", globals.jsonRPCServerPort))
+		clientConfig.IPAddr = globals.peersByName[servingPeer.name].privateIPAddr.String()
 	}
 
-	tcpAddr, err = net.ResolveTCPAddr("tcp", tcpAddrToResolve)
-
+	client, err = jrpcfsclient.NewClient(clientConfig)
 	if nil != err {
-		err = fmt.Errorf("net.ResolveTCPAddr(\"tcp\", \"%v\") failed: %v", tcpAddrToResolve, err)
+		err = fmt.Errorf("jrpcfsclient.NewClient() failed: %v", err)
 		logger.Error(err)
 		return
 	}
@@ -210,31 +176,10 @@ func livenessCheckServingPeer(servingPeer *internalServingPeerReportStruct) {
 
 	servingPeerState = StateDead
 
-	tcpConn, err = net.DialTCP("tcp", nil, tcpAddr)
-	if nil != err {
-		return
-	}
-
-	_, err = tcpConn.Write(pingReqBuf)
-	if nil != err {
-		return
-	}
-
-	pingReplyBuf = make([]byte, maxRPCReplySize)
-
-	pingReplyLen, err = tcpConn.Read(pingReplyBuf)
-	if nil != err {
-		return
-	}
-
-	err = tcpConn.Close()
-	if nil != err {
-		return
-	}
+	_, err = client.Ping("Ping at " + timeNow.Format(time.RFC3339))
 
-	pingReplyBuf = pingReplyBuf[:pingReplyLen]
+	_ = client.Close()
 
-	err = json.Unmarshal(pingReplyBuf, &pingReply)
 	if nil != err {
 		return
 	}
@@ -0,0 +1,115 @@
+// The pfs-inode-upgrade program rewrites every inode of a volume not already in the volume's
+// configured OnDiskInodeVersion (e.g. V1 JSON inodes to the compact binary V2 format). It is
+// intended to be run while the volume is not being served.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/inode"
+	"github.com/swiftstack/ProxyFS/transitions"
+)
+
+func usage(file *os.File) {
+	fmt.Fprintf(file, "Usage:\n")
+	fmt.Fprintf(file, "    %v volume-name conf-file [section.option=value]*\n", os.Args[0])
+	fmt.Fprintf(file, "  where:\n")
+	fmt.Fprintf(file, "    volume-name             name of the volume whose inodes are to be upgraded\n")
+	fmt.Fprintf(file, "    conf-file               input to conf.MakeConfMapFromFile()\n")
+	fmt.Fprintf(file, "    [section.option=value]* optional input to conf.UpdateFromStrings()\n")
+	fmt.Fprintf(file, "\n")
+	fmt.Fprintf(file, "Note: Inodes are rewritten in the volume's OnDiskInodeVersion (2 by default)\n")
+}
+
+func main() {
+	var (
+		confMap        conf.ConfMap
+		err            error
+		upgradedInodes uint64
+		volumeHandle   inode.VolumeHandle
+		volumeName     string
+		whoAmI         string
+	)
+
+	// Parse arguments
+
+	if 3 > len(os.Args) {
+		usage(os.Stderr)
+		os.Exit(1)
+	}
+
+	volumeName = os.Args[1]
+
+	confMap, err = conf.MakeConfMapFromFile(os.Args[2])
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "conf.MakeConfMapFromFile(\"%v\") failed: %v\n", os.Args[2], err)
+		os.Exit(1)
+	}
+
+	if 3 < len(os.Args) {
+		err = confMap.UpdateFromStrings(os.Args[3:])
+		if nil != err {
+			fmt.Fprintf(os.Stderr, "confMap.UpdateFromStrings(%#v) failed: %v\n", os.Args[3:], err)
+			os.Exit(1)
+		}
+	}
+
+	// Upgrade confMap if necessary
+	err = transitions.UpgradeConfMapIfNeeded(confMap)
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "Failed to upgrade config: %v", err)
+		os.Exit(1)
+	}
+
+	// Update confMap to specify only volumeName (served locally)
+
+	whoAmI, err = confMap.FetchOptionValueString("Cluster", "WhoAmI")
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "confMap.FetchOptionValueString(\"Cluster\", \"WhoAmI\") failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = confMap.UpdateFromStrings([]string{
+		"FSGlobals.VolumeGroupList=PFSINODEUPGRADE",
+		"VolumeGroup:PFSINODEUPGRADE.VolumeList=" + volumeName,
+		"VolumeGroup:PFSINODEUPGRADE.VirtualIPAddr=",
+		"VolumeGroup:PFSINODEUPGRADE.PrimaryPeer=" + whoAmI})
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "failed to retarget config at only %s: %v\n", volumeName, err)
+		os.Exit(1)
+	}
+
+	// Start up needed ProxyFS components
+
+	err = transitions.Up(confMap)
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "transitions.Up() failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	volumeHandle, err = inode.FetchVolumeHandle(volumeName)
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "inode.FetchVolumeHandle(\"%v\") failed: %v\n", volumeName, err)
+		_ = transitions.Down(confMap)
+		os.Exit(1)
+	}
+
+	upgradedInodes, err = volumeHandle.UpgradeOnDiskInodes()
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "volumeHandle.UpgradeOnDiskInodes() failed after upgrading %v inodes: %v\n", upgradedInodes, err)
+		_ = transitions.Down(confMap)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Upgraded %v inodes of volume %v\n", upgradedInodes, volumeName)
+
+	// Shutdown ProxyFS components
+
+	err = transitions.Down(confMap)
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "transitions.Down() failed: %v\n", err)
+		os.Exit(1)
+	}
+}
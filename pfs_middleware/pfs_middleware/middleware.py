@@ -65,7 +65,14 @@ differences that must be called out.
   This will combine the files /c/red, ..., /c/violet into a single file
   /c/rainbow. The files /c/red et cetera will be deleted as a result of this
   request.
+
+* Server-side object copies (a COPY request, or a PUT request with an
+  X-Copy-From header) within an account do not copy any data. Instead, the
+  new object shares the log segments of the source object, much like
+  "cp --reflink" on a filesystem. The new object's ETag is not the MD5
+  checksum of its contents.
 """
+import base64
 import contextlib
 import datetime
 import eventlet
@@ -80,6 +87,7 @@ import six
 import socket
 import time
 import xml.etree.ElementTree as ET
+import zlib
 from six.moves.urllib import parse as urllib_parse
 from io import BytesIO
 
@@ -117,6 +125,19 @@ DIRECTORY_CONTENT_TYPE = "application/directory"
 
 ZERO_FILL_PATH = "/0"
 
+# Followed by the index of the read plan entry whose "InlineData" is wanted
+INLINE_DATA_PATH_PREFIX = "/1/"
+
+# Followed by the index of the read plan entry whose compressed unit is wanted
+COMPRESSED_UNIT_PATH_PREFIX = "/2/"
+
+# The read plan "Compression" value for raw DEFLATE (RFC 1951) units
+COMPRESSION_DEFLATE = "Deflate"
+
+# Size of the blocks of each log segment we compute CRC32s for; must match
+# ProxyFS's logSegmentChecksumBlockSize
+CHECKSUM_BLOCK_SIZE = 256 * 1024
+
 LEASE_RENEWAL_INTERVAL = 5  # seconds
 
 ORIGINAL_MD5_HEADER = "X-Object-Sysmeta-ProxyFS-Initial-MD5"
@@ -136,12 +157,27 @@ STICKY_OBJECT_METADATA_HEADERS = {
     "X-Static-Large-Object",
     ORIGINAL_MD5_HEADER}
 
+# Object versioning is done by proxyfsd itself: prior versions are kept in
+# a hidden top-level directory (not a container) instead of in the
+# container named by either location header.
+VERSIONS_LOCATION_HEADER = "X-Versions-Location"
+HISTORY_LOCATION_HEADER = "X-History-Location"
+MAX_VERSIONS_HEADER = "X-Container-Meta-Max-Versions"
+RESTORE_VERSION_HEADER = "X-Restore-Version"
+VERSIONS_DIR_NAME = ".versions"
+
+# Object expiration is also done by proxyfsd itself: Swift's object expirer
+# never sees objects in a ProxyFS volume.
+DELETE_AT_HEADER = "X-Delete-At"
+DELETE_AFTER_HEADER = "X-Delete-After"
+
 SPECIAL_CONTAINER_METADATA_HEADERS = {
     "X-Container-Read",
     "X-Container-Write",
     "X-Container-Sync-Key",
     "X-Container-Sync-To",
-    "X-Versions-Location"}
+    VERSIONS_LOCATION_HEADER,
+    HISTORY_LOCATION_HEADER}
 
 # ProxyFS directories don't know how many objects are under them, nor how
 # many bytes each one uses. (Yes, a directory knows how many files and
@@ -208,6 +244,15 @@ def listing_iter_from_read_plan(read_plan):
         ("/v1/AUTH_test/Replicated3Way_1/0000000000000078", None, None, 0, 18),
         ("/v1/AUTH_test/Replicated3Way_1/000000000000007A", None, None, 0, 88),
     ]
+
+    Entries carrying "InlineData" (small files whose data is kept in their
+    inode) are turned into requests for INLINE_DATA_PATH_PREFIX followed by
+    the entry's index; see InlineDataFiller.
+
+    Entries carrying "Compression" (whose "Offset" and "Length" select from
+    a unit that must first be fetched and decompressed) are likewise turned
+    into requests for COMPRESSED_UNIT_PATH_PREFIX followed by the entry's
+    index; see CompressedUnitFiller.
     """
     if read_plan is None:
         # ProxyFS likes to send null values instead of empty lists.
@@ -217,12 +262,23 @@ def listing_iter_from_read_plan(read_plan):
     # RPC-response parser all the way to here, but it's inefficient, in both
     # CPU cycles and programmer brainpower, to create some intermediate
     # representation just to avoid GoCase.
-    return [(rpe["ObjectPath"] or ZERO_FILL_PATH,
-             None,  # we don't know the segment's ETag
-             None,  # we don't know the segment's length
-             rpe["Offset"],
-             rpe["Offset"] + rpe["Length"] - 1)
-            for rpe in read_plan]
+    listing = []
+    for i, rpe in enumerate(read_plan):
+        if rpe.get("InlineData"):
+            listing.append((INLINE_DATA_PATH_PREFIX + str(i),
+                            None, None, 0, rpe["Length"] - 1))
+        elif rpe.get("Compression"):
+            listing.append((COMPRESSED_UNIT_PATH_PREFIX + str(i),
+                            None, None,
+                            rpe["Offset"],
+                            rpe["Offset"] + rpe["Length"] - 1))
+        else:
+            listing.append((rpe["ObjectPath"] or ZERO_FILL_PATH,
+                            None,  # we don't know the segment's ETag
+                            None,  # we don't know the segment's length
+                            rpe["Offset"],
+                            rpe["Offset"] + rpe["Length"] - 1))
+    return listing
 
 
 def x_timestamp_from_epoch_ns(epoch_ns):
@@ -436,8 +492,79 @@ def extract_container_metadata_from_headers(req):
     return meta_headers
 
 
+def versioning_from_container_metadata(metadata):
+    """
+    Determine how proxyfsd should version a container's objects.
+
+    As with Swift, X-History-Location makes deleted objects versions too
+    while X-Versions-Location restores an object's prior version when it
+    is deleted. The value of either header is otherwise ignored.
+
+    :param metadata: container metadata (a dictionary)
+
+    :returns: 2-tuple (mode, max versions) as taken by
+        rpc.set_versioning_request()
+
+    :raises ValueError: if both location headers are set or if the max
+        versions header isn't a non-negative integer
+    """
+    if metadata.get(HISTORY_LOCATION_HEADER):
+        if metadata.get(VERSIONS_LOCATION_HEADER):
+            raise ValueError("Only one of %s or %s may be specified" % (
+                VERSIONS_LOCATION_HEADER, HISTORY_LOCATION_HEADER))
+        mode = "history"
+    elif metadata.get(VERSIONS_LOCATION_HEADER):
+        mode = "stack"
+    else:
+        mode = ""
+
+    max_versions = int(metadata.get(MAX_VERSIONS_HEADER) or 0)
+    if max_versions < 0:
+        raise ValueError("%s must not be negative" % MAX_VERSIONS_HEADER)
+
+    return mode, max_versions
+
+
+def expire_at_from_headers(headers):
+    """
+    Determine when an object should expire.
+
+    As with Swift, X-Delete-After (a number of seconds from now) takes
+    precedence over X-Delete-At (seconds since the epoch).
+
+    :param headers: request headers (a dictionary)
+
+    :returns: expiration time in seconds since the epoch (as taken by
+        rpc.set_expiration_request()), or 0 if neither header is present
+
+    :raises ValueError: if the header isn't an integer or names a time in
+        the past
+    """
+    now = int(time.time())
+
+    if headers.get(DELETE_AFTER_HEADER):
+        try:
+            delete_after = int(headers[DELETE_AFTER_HEADER])
+        except ValueError:
+            raise ValueError("Non-integer %s" % DELETE_AFTER_HEADER)
+        if delete_after < 0:
+            raise ValueError("%s in past" % DELETE_AFTER_HEADER)
+        return now + delete_after
+
+    if headers.get(DELETE_AT_HEADER):
+        try:
+            delete_at = int(float(headers[DELETE_AT_HEADER]))
+        except ValueError:
+            raise ValueError("Non-integer %s" % DELETE_AT_HEADER)
+        if delete_at <= now:
+            raise ValueError("%s in past" % DELETE_AT_HEADER)
+        return delete_at
+
+    return 0
+
+
 def best_possible_etag(obj_metadata, account_name, ino, num_writes,
-                       is_dir=False, container_listing=False):
+                       is_dir=False, container_listing=False, fs_etag=""):
     if is_dir:
         return EMPTY_OBJECT_ETAG
     if container_listing and LISTING_ETAG_OVERRIDE_HEADER in obj_metadata:
@@ -456,6 +583,11 @@ def best_possible_etag(obj_metadata, account_name, ino, num_writes,
                 return md5sum
         except ValueError:
             pass
+    # For files written via the filesystem (FUSE, SMB, ...), proxyfsd may
+    # maintain the content MD5 itself; it's discarded on any modification,
+    # so if present it's trustworthy.
+    if fs_etag:
+        return fs_etag
     return construct_etag(account_name, ino, num_writes)
 
 
@@ -522,6 +654,93 @@ class ZeroFiller(object):
             yield self.ZEROES[:n]
 
 
+class InlineDataFiller(object):
+    """
+    Internal middleware to serve the portions of an object GET response
+    whose data was returned inline in the read plan (i.e. small files whose
+    data ProxyFS keeps in their inode rather than in log segments).
+
+    One of these is made per GET request since the data comes from that
+    request's read plan.
+    """
+    def __init__(self, app, read_plan):
+        self.app = app
+        self.inline_data = {}
+        for i, rpe in enumerate(read_plan):
+            if rpe.get("InlineData"):
+                # Go's encoding/json sends []byte as base64
+                self.inline_data[INLINE_DATA_PATH_PREFIX + str(i)] = \
+                    base64.b64decode(rpe["InlineData"])
+
+    @swob.wsgify
+    def __call__(self, req):
+        data = self.inline_data.get(req.path)
+        if data is None:
+            return self.app
+        start, end = req.range.ranges[0]
+        return swob.Response(
+            request=req, status=206,
+            headers={"Content-Length": end - start + 1,
+                     "Content-Range": "%d-%d/%d" % (start, end, len(data))},
+            body=data[start:end + 1])
+
+
+class CompressedUnitFiller(object):
+    """
+    Internal middleware to serve the portions of an object GET response
+    whose data lives in compressed log segment units.
+
+    Each such read plan entry names the range of log segment bytes
+    ("CompressedObjectOffset" and "CompressedObjectLength") holding the
+    unit. That range is fetched and decompressed, and then the requested
+    range of the decompressed unit is returned.
+
+    One of these is made per GET request since the units come from that
+    request's read plan.
+    """
+    def __init__(self, app, read_plan):
+        self.app = app
+        self.units = {}
+        for i, rpe in enumerate(read_plan):
+            if rpe.get("Compression"):
+                self.units[COMPRESSED_UNIT_PATH_PREFIX + str(i)] = rpe
+
+    @swob.wsgify
+    def __call__(self, req):
+        rpe = self.units.get(req.path)
+        if rpe is None:
+            return self.app
+
+        unit_start = rpe["CompressedObjectOffset"]
+        unit_end = unit_start + rpe["CompressedObjectLength"] - 1
+        unit_req = swift_code.make_subrequest(
+            req.environ, path=rpe["ObjectPath"], method='GET',
+            headers={'x-auth-token': req.headers.get('x-auth-token'),
+                     'Range': "bytes=%d-%d" % (unit_start, unit_end)},
+            agent='%(orig)s PFS', swift_source='PFS')
+        unit_resp = unit_req.get_response(self.app)
+        if not swift_code.is_success(unit_resp.status_int):
+            return unit_resp
+
+        if rpe["Compression"] != COMPRESSION_DEFLATE:
+            return swob.HTTPNotImplemented(request=req)
+
+        try:
+            # Go's compress/flate produces raw DEFLATE (i.e. no zlib header)
+            decompressor = zlib.decompressobj(-zlib.MAX_WBITS)
+            data = decompressor.decompress(unit_resp.body)
+            data += decompressor.flush()
+        except zlib.error:
+            return swob.HTTPInternalServerError(request=req)
+
+        start, end = req.range.ranges[0]
+        return swob.Response(
+            request=req, status=206,
+            headers={"Content-Length": end - start + 1,
+                     "Content-Range": "%d-%d/%d" % (start, end, len(data))},
+            body=data[start:end + 1])
+
+
 class SnoopingInput(object):
     """
     Wrap WSGI input and call a provided callback every time data is read.
@@ -541,6 +760,39 @@ class SnoopingInput(object):
         return line
 
 
+class BlockChecksummer(object):
+    """
+    Compute the CRC32 of each CHECKSUM_BLOCK_SIZE block of the data passed
+    to update(). This lets ProxyFS (if DataChecksums is enabled) verify log
+    segments we PUT without having to GET them back.
+    """
+    def __init__(self):
+        self.block_crc32s = []
+        self._block_crc32 = 0
+        self._block_bytes_seen = 0
+
+    def update(self, chunk):
+        while chunk:
+            to_take = min(len(chunk),
+                          CHECKSUM_BLOCK_SIZE - self._block_bytes_seen)
+            self._block_crc32 = zlib.crc32(chunk[:to_take],
+                                           self._block_crc32)
+            self._block_bytes_seen += to_take
+            chunk = chunk[to_take:]
+            if self._block_bytes_seen == CHECKSUM_BLOCK_SIZE:
+                self._finish_block()
+
+    def finish(self):
+        if self._block_bytes_seen > 0:
+            self._finish_block()
+        return self.block_crc32s
+
+    def _finish_block(self):
+        self.block_crc32s.append(self._block_crc32 & 0xffffffff)
+        self._block_crc32 = 0
+        self._block_bytes_seen = 0
+
+
 class LimitedInput(object):
     """
     Wrap WSGI input and limit the consumer to taking at most N bytes.
@@ -693,12 +945,14 @@ class PfsMiddleware(object):
                 # here is return an error to the client.
                 return swob.HTTPServiceUnavailable(request=req)
 
-            if con in ('.', '..') or con and len(con) > NAME_MAX:
+            if con in ('.', '..', VERSIONS_DIR_NAME) or \
+                    con and len(con) > NAME_MAX:
                 if req.method == 'PUT' and not obj:
                     return swob.HTTPBadRequest(
                         request=req,
-                        body='Container name cannot be "." or "..", '
-                             'or be more than 255 bytes long')
+                        body='Container name cannot be ".", "..", or '
+                             '"%s", or be more than 255 bytes long' %
+                             VERSIONS_DIR_NAME)
                 else:
                     return swob.HTTPNotFound(request=req)
             elif obj and any(p in ('', '.', '..') or len(p) > NAME_MAX
@@ -754,18 +1008,28 @@ class PfsMiddleware(object):
                     return self.app
 
                 # Otherwise, dispatch to a helper method
-                if method == 'GET' and obj:
+                if method == 'GET' and obj and 'versions' in req.params:
+                    resp = self.get_object_versions(ctx)
+                elif method == 'GET' and obj:
                     resp = self.get_object(ctx)
                 elif method == 'HEAD' and obj:
                     resp = self.head_object(ctx)
+                elif method == 'PUT' and obj and \
+                        'X-Copy-From' in req.headers:
+                    resp = self.copy_object(ctx, auth_cb)
                 elif method == 'PUT' and obj:
                     resp = self.put_object(ctx)
+                elif method == 'POST' and obj and \
+                        RESTORE_VERSION_HEADER in req.headers:
+                    resp = self.restore_object_version(ctx)
                 elif method == 'POST' and obj:
                     resp = self.post_object(ctx)
                 elif method == 'DELETE' and obj:
                     resp = self.delete_object(ctx)
                 elif method == 'COALESCE' and obj:
                     resp = self.coalesce_object(ctx, auth_cb)
+                elif method == 'COPY' and obj:
+                    resp = self.copy_object(ctx, auth_cb)
 
                 elif method == 'GET' and con:
                     resp = self.get_container(ctx)
@@ -831,6 +1095,12 @@ class PfsMiddleware(object):
           all the "segments" against both read *and* write ACLs
           (see coalesce_object).
 
+        * for object COPY, it's the (source) container's read ACL
+          (X-Container-Read). Separately, we *also* need to authorize
+          the destination against its container's write ACL, or for an
+          object PUT with X-Copy-From, the source against its
+          container's read ACL (see copy_object).
+
         * for all other requests, it's None
 
         Some authentication systems, of course, also have account-level
@@ -841,7 +1111,8 @@ class PfsMiddleware(object):
 
         bimodal_checker = ctx.req.environ[utils.ENV_BIMODAL_CHECKER]
 
-        if ctx.req.method in ('GET', 'HEAD') and ctx.container_name:
+        if ctx.req.method in ('GET', 'HEAD') and ctx.container_name or \
+                ctx.object_name and ctx.req.method == 'COPY':
             container_info = get_container_info(
                 ctx.req.environ, bimodal_checker,
                 swift_source="PFS")
@@ -998,7 +1269,7 @@ class PfsMiddleware(object):
             else:
                 raise
 
-        raw_metadata, mtime_ns, _, _, _, _ = rpc.parse_head_response(
+        raw_metadata, mtime_ns, _, _, _, _, _ = rpc.parse_head_response(
             head_response)
         metadata = deserialize_metadata(raw_metadata)
         resp = swob.HTTPNoContent(request=ctx.req, headers=metadata)
@@ -1035,10 +1306,15 @@ class PfsMiddleware(object):
         try:
             head_response = self.rpc_call(
                 ctx, rpc.head_request(container_path))
-            raw_old_metadata, _, _, _, _, _ = rpc.parse_head_response(
+            raw_old_metadata, _, _, _, _, _, _ = rpc.parse_head_response(
                 head_response)
         except utils.RpcError as err:
             if err.errno == pfs_errno.NotFoundError:
+                try:
+                    versioning = versioning_from_container_metadata(
+                        new_metadata)
+                except ValueError as err:
+                    return swob.HTTPBadRequest(request=req, body=str(err))
                 clear_info_cache(None, ctx.req.environ, ctx.account_name,
                                  container=ctx.container_name)
                 self.rpc_call(ctx, rpc.put_container_request(
@@ -1046,6 +1322,7 @@ class PfsMiddleware(object):
                     "",
                     serialize_metadata({
                         k: v for k, v in new_metadata.items() if v})))
+                self._set_versioning(ctx, container_path, {}, versioning)
                 return swob.HTTPCreated(request=req)
             else:
                 raise
@@ -1055,10 +1332,16 @@ class PfsMiddleware(object):
             old_metadata, new_metadata)
         raw_merged_metadata = serialize_metadata(merged_metadata)
 
+        try:
+            versioning = versioning_from_container_metadata(merged_metadata)
+        except ValueError as err:
+            return swob.HTTPBadRequest(request=req, body=str(err))
+
         clear_info_cache(None, ctx.req.environ, ctx.account_name,
                          container=ctx.container_name)
         self.rpc_call(ctx, rpc.put_container_request(
             container_path, raw_old_metadata, raw_merged_metadata))
+        self._set_versioning(ctx, container_path, old_metadata, versioning)
 
         return swob.HTTPAccepted(request=req)
 
@@ -1076,7 +1359,7 @@ class PfsMiddleware(object):
         try:
             head_response = self.rpc_call(
                 ctx, rpc.head_request(container_path))
-            raw_old_metadata, _, _, _, _, _ = rpc.parse_head_response(
+            raw_old_metadata, _, _, _, _, _, _ = rpc.parse_head_response(
                 head_response)
         except utils.RpcError as err:
             if err.errno == pfs_errno.NotFoundError:
@@ -1099,13 +1382,33 @@ class PfsMiddleware(object):
         req.headers.clear()
         req.headers.update(new_metadata)
 
+        try:
+            versioning = versioning_from_container_metadata(merged_metadata)
+        except ValueError as err:
+            return swob.HTTPBadRequest(request=req, body=str(err))
+
         clear_info_cache(None, req.environ, ctx.account_name,
                          container=ctx.container_name)
         self.rpc_call(ctx, rpc.post_request(
             container_path, raw_old_metadata, raw_merged_metadata))
+        self._set_versioning(ctx, container_path, old_metadata, versioning)
 
         return swob.HTTPNoContent(request=req)
 
+    def _set_versioning(self, ctx, container_path, old_metadata, versioning):
+        """
+        Tell proxyfsd about a change to a container's versioning (if the
+        new metadata changed it).
+        """
+        try:
+            old_versioning = versioning_from_container_metadata(old_metadata)
+        except ValueError:
+            old_versioning = None
+        if versioning != old_versioning:
+            mode, max_versions = versioning
+            self.rpc_call(ctx, rpc.set_versioning_request(
+                container_path, mode, max_versions))
+
     def delete_container(self, ctx):
         # Turns out these are the same RPC with the same error handling, so
         # why not?
@@ -1248,7 +1551,7 @@ class PfsMiddleware(object):
             etag = best_possible_etag(
                 obj_metadata, account_name,
                 ent["InodeNumber"], ent["NumWrites"], is_dir=ent["IsDir"],
-                container_listing=True)
+                container_listing=True, fs_etag=ent.get("ETag", ""))
             json_entry = {
                 "name": name,
                 "bytes": int(swift_bytes or size),
@@ -1284,7 +1587,8 @@ class PfsMiddleware(object):
                 obj_metadata, account_name,
                 container_entry["InodeNumber"],
                 container_entry["NumWrites"],
-                is_dir=container_entry["IsDir"])
+                is_dir=container_entry["IsDir"],
+                fs_etag=container_entry.get("ETag", ""))
             container_node = ET.Element('object')
 
             name_node = ET.Element('name')
@@ -1402,6 +1706,12 @@ class PfsMiddleware(object):
         """
         req = ctx.req
 
+        try:
+            expire_at = expire_at_from_headers(req.headers)
+        except ValueError as err:
+            return swob.HTTPBadRequest(
+                request=req, content_type='text/plain', body=str(err))
+
         virtual_path = urllib_parse.unquote(req.path)
         put_location_req = rpc.put_location_request(virtual_path)
 
@@ -1431,6 +1741,7 @@ class PfsMiddleware(object):
         # Since this upload can be arbitrarily large, we split it across
         # multiple log segments.
         log_segments = []
+        log_segment_block_crc32s = []
         i = 0
         while True:
             # First, make sure there's more data to read from the client. No
@@ -1452,7 +1763,9 @@ class PfsMiddleware(object):
             # real gain (user metadata).
             subreq = swob.Request.blank(phys_path)
             subreq.method = 'PUT'
-            subreq.environ['wsgi.input'] = subinput
+            checksummer = BlockChecksummer()
+            subreq.environ['wsgi.input'] = SnoopingInput(
+                subinput, checksummer.update)
             subreq.headers["Transfer-Encoding"] = "chunked"
 
             # This ensures that (a) every subrequest has its own unique
@@ -1469,6 +1782,7 @@ class PfsMiddleware(object):
                 return subresp
 
             log_segments.append((phys_path, subinput.bytes_read))
+            log_segment_block_crc32s.append(checksummer.finish())
             i += 1
 
         if should_validate_etag(request_etag) and \
@@ -1490,7 +1804,8 @@ class PfsMiddleware(object):
                 len(log_segments), obj_metadata[LISTING_ETAG_OVERRIDE_HEADER])
 
         put_complete_req = rpc.put_complete_request(
-            virtual_path, log_segments, serialize_metadata(obj_metadata))
+            virtual_path, log_segments, serialize_metadata(obj_metadata),
+            log_segment_block_crc32s)
         try:
             mtime_ns, inode, num_writes = rpc.parse_put_complete_response(
                 self.rpc_call(ctx, put_complete_req))
@@ -1512,6 +1827,10 @@ class PfsMiddleware(object):
                 # punt to top-level error handler
                 raise
 
+        if expire_at:
+            self.rpc_call(ctx, rpc.set_expiration_request(
+                virtual_path, expire_at))
+
         # For reference, an object PUT response to plain Swift looks like:
         # HTTP/1.1 201 Created
         # Last-Modified: Thu, 08 Dec 2016 22:51:13 GMT
@@ -1537,10 +1856,16 @@ class PfsMiddleware(object):
         path = urllib_parse.unquote(req.path)
         new_metadata = extract_object_metadata_from_headers(req.headers)
 
+        try:
+            expire_at = expire_at_from_headers(req.headers)
+        except ValueError as err:
+            return swob.HTTPBadRequest(
+                request=req, content_type='text/plain', body=str(err))
+
         try:
             head_response = self.rpc_call(ctx, rpc.head_request(path))
-            raw_old_metadata, mtime, _, _, inode_number, num_writes = \
-                rpc.parse_head_response(head_response)
+            raw_old_metadata, mtime, _, is_dir, inode_number, num_writes, \
+                _ = rpc.parse_head_response(head_response)
         except utils.RpcError as err:
             if err.errno in (pfs_errno.NotFoundError, pfs_errno.NotDirError):
                 return swob.HTTPNotFound(request=req)
@@ -1554,6 +1879,11 @@ class PfsMiddleware(object):
         self.rpc_call(ctx, rpc.post_request(
             path, raw_old_metadata, raw_merged_metadata))
 
+        # Like the Swift object server, a POST without X-Delete-At (or
+        # X-Delete-After) removes any expiration the object had.
+        if not is_dir and expire_at != rpc.parse_expire_at(head_response):
+            self.rpc_call(ctx, rpc.set_expiration_request(path, expire_at))
+
         resp = swob.HTTPAccepted(request=req, body="")
         return resp
 
@@ -1577,8 +1907,8 @@ class PfsMiddleware(object):
                 # punt to top-level exception handler
                 raise
 
-        read_plan, raw_metadata, size, mtime_ns, ino, num_writes, lease_id = \
-            rpc.parse_get_object_response(object_response)
+        (read_plan, raw_metadata, size, mtime_ns, ino, num_writes, lease_id,
+         fs_etag) = rpc.parse_get_object_response(object_response)
         headers = swob.HeaderKeyDict(deserialize_metadata(raw_metadata))
 
         if "Content-Type" not in headers:
@@ -1594,7 +1924,10 @@ class PfsMiddleware(object):
         headers["X-Timestamp"] = x_timestamp_from_epoch_ns(
             mtime_ns)
         headers["Etag"] = best_possible_etag(headers, ctx.account_name,
-                                             ino, num_writes)
+                                             ino, num_writes, fs_etag=fs_etag)
+        expire_at = rpc.parse_expire_at(object_response)
+        if expire_at:
+            headers[DELETE_AT_HEADER] = str(expire_at)
 
         get_read_plan = req.params.get("get-read-plan", "no")
         if get_read_plan == "":
@@ -1620,6 +1953,11 @@ class PfsMiddleware(object):
             return swob.HTTPRequestedRangeNotSatisfiable(
                 request=req, headers=headers)
 
+        if any(rpe.get("Encryption") for rpe in read_plan or ()):
+            # We hold no keys, so encrypted volumes are simply not served
+            # bimodally.
+            return swob.HTTPNotImplemented(request=req)
+
         # NB: this is a size-0 queue, so it acts as a channel: a put()
         # blocks until another greenthread does a get(). This lets us use it
         # for (very limited) bidirectional communication.
@@ -1627,6 +1965,11 @@ class PfsMiddleware(object):
         eventlet.spawn_n(self._keep_lease_alive, ctx, channel, lease_id)
 
         listing_iter = listing_iter_from_read_plan(read_plan)
+        segment_app = self.zero_filler_app
+        if any(rpe.get("InlineData") for rpe in read_plan or ()):
+            segment_app = InlineDataFiller(segment_app, read_plan)
+        if any(rpe.get("Compression") for rpe in read_plan or ()):
+            segment_app = CompressedUnitFiller(segment_app, read_plan)
         # Make sure that nobody (like our __call__ method) messes with this
         # environment once we've started. Otherwise, the auth callback may
         # reappear, causing log-segment GET requests to fail. This may be
@@ -1659,7 +2002,7 @@ class PfsMiddleware(object):
             listing_iter, done_with_object_get)
 
         seg_iter = swift_code.SegmentedIterable(
-            copied_req, self.zero_filler_app, wrapped_listing_iter,
+            copied_req, segment_app, wrapped_listing_iter,
             self.max_get_time,
             self.logger, 'PFS', 'PFS',
             name=req.path)
@@ -1713,6 +2056,60 @@ class PfsMiddleware(object):
 
         channel.put("alright, it's done")
 
+    def get_object_versions(self, ctx):
+        req = ctx.req
+        try:
+            list_versions_response = self.rpc_call(
+                ctx, rpc.list_versions_request(
+                    urllib_parse.unquote(req.path)))
+        except utils.RpcError as err:
+            if err.errno in (pfs_errno.NotFoundError, pfs_errno.NotDirError):
+                return swob.HTTPNotFound(request=req)
+            else:
+                raise
+        versions = rpc.parse_list_versions_response(list_versions_response)
+
+        # Most recent first, as with Swift's version listings
+        json_entries = []
+        for ver in reversed(versions):
+            obj_metadata = deserialize_metadata(ver["Metadata"])
+            content_type = swift_code.wsgi_to_str(
+                obj_metadata.get("Content-Type"))
+            if content_type is None:
+                content_type = guess_content_type(ctx.object_name, False)
+            content_type, swift_bytes = content_type.partition(
+                ';swift_bytes=')[::2]
+            json_entries.append({
+                "version_id": ver["VersionID"],
+                "bytes": int(swift_bytes or ver["FileSize"]),
+                "content_type": content_type,
+                "hash": best_possible_etag(
+                    obj_metadata, ctx.account_name, ver["InodeNumber"],
+                    ver["NumWrites"], fs_etag=ver.get("ETag", "")),
+                "last_modified": iso_timestamp_from_epoch_ns(
+                    ver["AttrChangeTime"]),
+                "archived": iso_timestamp_from_epoch_ns(
+                    ver["ArchiveTime"])})
+
+        return swob.HTTPOk(
+            request=req, body=json.dumps(json_entries).encode('ascii'),
+            headers={"Content-Type": "application/json; charset=utf-8"})
+
+    def restore_object_version(self, ctx):
+        req = ctx.req
+        try:
+            self.rpc_call(ctx, rpc.restore_version_request(
+                urllib_parse.unquote(req.path),
+                req.headers[RESTORE_VERSION_HEADER]))
+        except utils.RpcError as err:
+            if err.errno in (pfs_errno.NotFoundError, pfs_errno.NotDirError):
+                return swob.HTTPNotFound(request=req)
+            elif err.errno == pfs_errno.InvalidArgError:
+                return swob.HTTPBadRequest(request=req)
+            else:
+                raise
+        return swob.HTTPAccepted(request=req, body="")
+
     def delete_object(self, ctx):
         try:
             self.rpc_call(ctx, rpc.delete_request(
@@ -1737,8 +2134,8 @@ class PfsMiddleware(object):
             else:
                 raise
 
-        raw_md, last_modified_ns, file_size, is_dir, ino, num_writes = \
-            rpc.parse_head_response(head_response)
+        raw_md, last_modified_ns, file_size, is_dir, ino, num_writes, \
+            fs_etag = rpc.parse_head_response(head_response)
 
         headers = swob.HeaderKeyDict(deserialize_metadata(raw_md))
 
@@ -1750,11 +2147,15 @@ class PfsMiddleware(object):
 
         headers["Content-Length"] = file_size
         headers["ETag"] = best_possible_etag(
-            headers, ctx.account_name, ino, num_writes, is_dir=is_dir)
+            headers, ctx.account_name, ino, num_writes, is_dir=is_dir,
+            fs_etag=fs_etag)
         headers["Last-Modified"] = last_modified_from_epoch_ns(
             last_modified_ns)
         headers["X-Timestamp"] = x_timestamp_from_epoch_ns(
             last_modified_ns)
+        expire_at = rpc.parse_expire_at(head_response)
+        if expire_at:
+            headers[DELETE_AT_HEADER] = str(expire_at)
 
         resp = swob.HTTPOk(request=req, headers=headers,
                            conditional_response=True)
@@ -1853,6 +2254,134 @@ class PfsMiddleware(object):
 
         return swob.HTTPCreated(request=req, headers=headers)
 
+    def copy_object(self, ctx, auth_cb):
+        """
+        Handle a server-side copy, expressed either as a COPY request to
+        the source object (with a Destination header) or as a PUT request
+        to the destination object (with an X-Copy-From header).
+
+        Rather than reading and rewriting the data, we ask proxyfsd to
+        make the destination share the source's extents. Copies between
+        accounts live in different volumes, so those are left to Swift.
+        """
+        req = ctx.req
+
+        if req.method == 'COPY':
+            other_path = req.headers.get('Destination')
+            other_account = req.headers.get('Destination-Account',
+                                            ctx.account_name)
+        else:
+            if req.content_length:
+                return swob.HTTPBadRequest(
+                    request=req,
+                    body='Copy requests require a zero byte body')
+            other_path = req.headers.get('X-Copy-From')
+            other_account = req.headers.get('X-Copy-From-Account',
+                                            ctx.account_name)
+
+        if not other_path:
+            return swob.HTTPPreconditionFailed(
+                request=req,
+                body='Destination header required')
+        if other_account != ctx.account_name:
+            return self.app
+
+        other_path = urllib_parse.unquote(other_path).lstrip('/')
+        if '/' not in other_path or any(
+                p in ('', '.', '..') or len(p) > NAME_MAX
+                for p in other_path.split('/')):
+            return swob.HTTPPreconditionFailed(
+                request=req,
+                body='Header must be of the form <container name>/<object '
+                     'name>')
+        other_container = other_path.split('/', 1)[0]
+
+        this_path = '%s/%s' % (ctx.container_name, ctx.object_name)
+        if req.method == 'COPY':
+            src_path, dst_path = this_path, other_path
+            other_acl = 'write_acl'
+        else:
+            src_path, dst_path = other_path, this_path
+            other_acl = 'read_acl'
+
+        # The request itself was authorized against this_path's container;
+        # the other container needs checking too.
+        bimodal_checker = req.environ[utils.ENV_BIMODAL_CHECKER]
+        acl_env = req.environ.copy()
+        acl_env['PATH_INFO'] = '/v1/%s/%s' % (ctx.account_name,
+                                              other_container)
+        container_info = get_container_info(
+            acl_env, bimodal_checker, swift_source="PFS")
+        if not 200 <= container_info["status"] < 300:
+            return swob.HTTPNotFound(request=req)
+        if auth_cb:
+            req.acl = container_info[other_acl]
+            denial_response = auth_cb(req)
+            if denial_response:
+                return denial_response
+
+        src_virtual_path = '/v1/%s/%s' % (ctx.account_name, src_path)
+        dst_virtual_path = '/v1/%s/%s' % (ctx.account_name, dst_path)
+
+        try:
+            head_response = self.rpc_call(
+                ctx, rpc.head_request(src_virtual_path))
+        except utils.RpcError as err:
+            if err.errno in (pfs_errno.NotFoundError, pfs_errno.NotDirError):
+                return swob.HTTPNotFound(request=req)
+            else:
+                raise
+        raw_src_metadata, src_mtime_ns, _, src_is_dir, _, _, _ = \
+            rpc.parse_head_response(head_response)
+        if src_is_dir:
+            return swob.HTTPConflict(
+                request=req,
+                headers={"Content-Type": "text/plain"},
+                body="Source must be a plain file, not a directory")
+
+        # The copy starts out with the source's metadata, less anything
+        # tied to the source's particular sequence of writes.
+        obj_metadata = deserialize_metadata(raw_src_metadata)
+        obj_metadata.pop(ORIGINAL_MD5_HEADER, None)
+        obj_metadata.pop(LISTING_ETAG_OVERRIDE_HEADER, None)
+        if config_true_value(req.headers.get('X-Fresh-Metadata')):
+            obj_metadata = {k: v for k, v in obj_metadata.items()
+                            if not k.startswith("X-Object-Meta-")}
+        obj_metadata.update(extract_object_metadata_from_headers(
+            req.headers))
+        obj_metadata = {k: v for k, v in obj_metadata.items() if v}
+
+        try:
+            copy_response = self.rpc_call(ctx, rpc.copy_object_request(
+                dst_virtual_path, '/' + src_path,
+                serialize_metadata(obj_metadata)))
+        except utils.RpcError as err:
+            if err.errno in (pfs_errno.NotFoundError, pfs_errno.NotDirError):
+                return swob.HTTPNotFound(request=req)
+            elif err.errno == pfs_errno.IsDirError:
+                return swob.HTTPConflict(
+                    request=req,
+                    headers={"Content-Type": "text/plain"},
+                    body="This is a directory")
+            else:
+                raise
+
+        mtime_ns, ino, num_writes = rpc.parse_copy_object_response(
+            copy_response)
+
+        headers = {}
+        headers["Etag"] = construct_etag(ctx.account_name, ino, num_writes)
+        headers["Content-Type"] = obj_metadata.get(
+            "Content-Type",
+            guess_content_type(dst_path, False)).split(';swift_bytes=')[0]
+        headers["Last-Modified"] = last_modified_from_epoch_ns(mtime_ns)
+        headers["X-Timestamp"] = x_timestamp_from_epoch_ns(mtime_ns)
+        headers["X-Copied-From"] = urllib_parse.quote(src_path)
+        headers["X-Copied-From-Last-Modified"] = \
+            last_modified_from_epoch_ns(src_mtime_ns)
+
+        return swob.HTTPCreated(request=req, headers=headers)
+
     def _unpack_owning_proxyfs(self, req):
         """
         Checks to see if an account is bimodal or not, and if so, which proxyfs
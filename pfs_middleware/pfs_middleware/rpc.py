@@ -57,6 +57,7 @@ allow_read_write = {
     "Server.ChownPathRequest",
     "Server.ChownRequest",
     "Server.CoalesceReq",
+    "Server.CopyReq",
     "Server.CreateContainerRequest",
     "Server.CreatePathRequest",
     "Server.CreateRequest",
@@ -141,6 +142,17 @@ def _ctime_or_mtime(res):
     return res.get("AttrChangeTime", res["ModificationTime"])
 
 
+def _etag(res):
+    '''
+    Get the content MD5 proxyfsd maintains for a file, if any, from a response.
+
+    This is empty unless the volume is configured with MaintainETag (and the
+    file has been flushed since it was last modified). Older versions of
+    proxyfsd don't return it at all.
+    '''
+    return res.get("ETag", "")
+
+
 def jsonrpc_request(method, params, call_id=None):
     """
     Marshal an RPC request in JSON-RPC 2.0 format.
@@ -232,7 +244,7 @@ def parse_get_object_response(read_plan_response):
     Parse a response from RpcGetObject.
 
     Returns (read_plan, metadata, file_size, inode number, number of writes,
-    lease ID).
+    lease ID, ETag).
 
     The read plan is a list of dictionaries with keys "Length", "Offset",
     and "ObjectPath". One gets the object data by reading <Length> bytes
@@ -244,7 +256,8 @@ def parse_get_object_response(read_plan_response):
             _ctime_or_mtime(read_plan_response),
             read_plan_response["InodeNumber"],
             read_plan_response["NumWrites"],
-            read_plan_response["LeaseId"])
+            read_plan_response["LeaseId"],
+            _etag(read_plan_response))
 
 
 def coalesce_object_request(destination, elements):
@@ -273,6 +286,33 @@ def parse_coalesce_object_response(coalesce_object_response):
             coalesce_object_response["NumWrites"])
 
 
+def copy_object_request(destination, source, obj_metadata):
+    """
+    Return a JSON-RPC request to make a server-side copy of an object.
+
+    :param destination: full path for the destination object, e.g. /v1/a/c/o
+
+    :param source: account-relative path for the source object, e.g. "/c/o"
+
+    :param obj_metadata: serialized object metadata for the destination
+    """
+    return jsonrpc_request("Server.RpcCopy",
+                           [{"VirtPath": destination,
+                             "SrcAccountRelativePath": source,
+                             "Metadata": _encode_binary(obj_metadata)}])
+
+
+def parse_copy_object_response(copy_object_response):
+    """
+    Parse a response from RpcCopy.
+
+    Returns (modification time, inode no., no. writes).
+    """
+    return (_ctime_or_mtime(copy_object_response),
+            copy_object_response["InodeNumber"],
+            copy_object_response["NumWrites"])
+
+
 def put_location_request(path):
     """
     Return a JSON-RPC request to get a segment path for an incoming object.
@@ -295,7 +335,8 @@ def parse_put_location_response(put_location_response):
     return put_location_response["PhysPath"]
 
 
-def put_complete_request(virtual_path, log_segments, obj_metadata):
+def put_complete_request(virtual_path, log_segments, obj_metadata,
+                         log_segment_block_crc32s=None):
     """
     Return a JSON-RPC request to notify proxyfsd that an object PUT has
     completed.
@@ -312,12 +353,19 @@ def put_complete_request(virtual_path, log_segments, obj_metadata):
         sizes. Comes as a list of 2-tuples (segment-name, segment-size).
 
     :param obj_metadata: serialized object metadata
+
+    :param log_segment_block_crc32s: optional list (parallel to
+        log_segments) of lists of the CRC32s of each 256 KiB block of the
+        corresponding log segment.
     """
-    return jsonrpc_request("Server.RpcPutComplete", [{
+    args = {
         "VirtPath": virtual_path,
         "PhysPaths": [ls[0] for ls in log_segments],
         "PhysLengths": [ls[1] for ls in log_segments],
-        "Metadata": _encode_binary(obj_metadata)}])
+        "Metadata": _encode_binary(obj_metadata)}
+    if log_segment_block_crc32s is not None:
+        args["PhysBlockCRC32s"] = log_segment_block_crc32s
+    return jsonrpc_request("Server.RpcPutComplete", [args])
 
 
 def parse_put_complete_response(put_complete_response):
@@ -490,8 +538,8 @@ def parse_head_response(head_response):
     """
     Parse a response from RpcHead.
 
-    Returns: 6-tuple (serialized metadata, modification time in nanoseconds,
-        file size, is-directory, inode number, number of writes)
+    Returns: 7-tuple (serialized metadata, modification time in nanoseconds,
+        file size, is-directory, inode number, number of writes, ETag)
 
     The metadata is whatever was sent to proxyfsd. Presumably it's
     serialized somehow, but it's not guaranteed by proxyfsd. This will be
@@ -501,7 +549,8 @@ def parse_head_response(head_response):
     return (_decode_binary(head_response["Metadata"]),
             _ctime_or_mtime(head_response),
             head_response["FileSize"], head_response["IsDir"],
-            head_response["InodeNumber"], head_response["NumWrites"])
+            head_response["InodeNumber"], head_response["NumWrites"],
+            _etag(head_response))
 
 
 def delete_request(path):
@@ -531,6 +580,104 @@ def put_container_request(path, old_metadata, new_metadata):
         "NewMetadata": _encode_binary(new_metadata)}])
 
 
+def set_versioning_request(path, mode, max_versions):
+    """
+    Return a JSON-RPC request to configure object versioning for a
+    container.
+
+    :param path: URL path component for the container, e.g. "/v1/acc/con"
+
+    :param mode: "" (no versioning), "stack" (X-Versions-Location) or
+        "history" (X-History-Location)
+
+    :param max_versions: number of prior versions retained per object; 0
+        means no limit
+    """
+    return jsonrpc_request("Server.RpcSetVersioning", [{
+        "VirtPath": path,
+        "Mode": mode,
+        "MaxVersions": max_versions}])
+
+
+# NB: there is no parse_set_versioning_response since a successful
+# response to RpcSetVersioning contains no useful information.
+
+
+def list_versions_request(path):
+    """
+    Return a JSON-RPC request to list the prior versions of an object.
+
+    :param path: URL path component for the object, e.g. "/v1/acc/con/obj"
+    """
+    return jsonrpc_request("Server.RpcListVersions", [{"VirtPath": path}])
+
+
+def parse_list_versions_response(list_versions_response):
+    """
+    Parse a response from RpcListVersions.
+
+    Returns: a list of dictionaries (oldest version first) with keys:
+
+        VersionID: identifies the version (e.g. to restore_version_request)
+
+        ArchiveTime: when the version was replaced or deleted, in
+            nanoseconds since the epoch
+
+        FileSize, ModificationTime, AttrChangeTime, InodeNumber, NumWrites,
+        Metadata and ETag: as for container entries
+    """
+    versions = []
+    for ver in list_versions_response["Versions"] or []:
+        ver = ver.copy()
+        ver["Metadata"] = _decode_binary(ver["Metadata"])
+        versions.append(ver)
+    return versions
+
+
+def restore_version_request(path, version_id):
+    """
+    Return a JSON-RPC request to make a prior version of an object current
+    again.
+
+    :param path: URL path component for the object, e.g. "/v1/acc/con/obj"
+
+    :param version_id: a VersionID from parse_list_versions_response()
+    """
+    return jsonrpc_request("Server.RpcRestoreVersion", [{
+        "VirtPath": path,
+        "VersionID": version_id}])
+
+
+def set_expiration_request(path, expire_at):
+    """
+    Return a JSON-RPC request to set (or clear) the time at which an object
+    expires and is deleted.
+
+    :param path: URL path component for the object, e.g. "/v1/acc/con/obj"
+
+    :param expire_at: expiration time in seconds since the epoch; 0 means
+        the object never expires
+    """
+    return jsonrpc_request("Server.RpcSetExpiration", [{
+        "VirtPath": path,
+        "ExpireAt": expire_at}])
+
+
+# NB: there is no parse_set_expiration_response since a successful
+# response to RpcSetExpiration contains no useful information.
+
+
+def parse_expire_at(response):
+    """
+    Get the expiration time of an object from a response to RpcHead or
+    RpcGetObject.
+
+    Returns: seconds since the epoch, or 0 if the object never expires (or
+        proxyfsd is too old to know about expiration)
+    """
+    return response.get("ExpireAt", 0)
+
+
 def renew_lease_request(lease_id):
     """
     Return a JSON-RPC request to renew a lease. We do this while serving an
@@ -19,6 +19,7 @@ import hashlib
 import json
 import mock
 import unittest
+import zlib
 from io import BytesIO
 from swift.common import swob
 from xml.etree import ElementTree
@@ -62,6 +63,19 @@ class TestHelpers(unittest.TestCase):
             'unicode-\xe1\x88\xb4': 'meta \xf0\x9f\x8c\xb4',
         })
 
+    def test_block_checksummer(self):
+        block_size = mware.CHECKSUM_BLOCK_SIZE
+        data = b'A' * block_size + b'B' * 10
+
+        checksummer = mware.BlockChecksummer()
+        for offset in range(0, len(data), 1000):
+            checksummer.update(data[offset:offset + 1000])
+        self.assertEqual(checksummer.finish(),
+                         [zlib.crc32(data[:block_size]) & 0xffffffff,
+                          zlib.crc32(data[block_size:]) & 0xffffffff])
+
+        self.assertEqual(mware.BlockChecksummer().finish(), [])
+
     def test_serialize_metadata(self):
         self.assertEqual(mware.serialize_metadata({}), '{}')
         self.assertEqual(
@@ -686,6 +700,29 @@ class TestObjectGet(BaseMiddlewareTest):
             },
         ])
 
+    def test_GET_expiration(self):
+        def mock_RpcGetObject(get_object_req):
+            return {
+                "error": None,
+                "result": {
+                    "FileSize": 0,
+                    "Metadata": "",
+                    "InodeNumber": 3571,
+                    "NumWrites": 1,
+                    "ModificationTime": 1554000000000000000,
+                    "LeaseId": "unfeline-catchweed",
+                    "ExpireAt": 1554003600,
+                    "ReadEntsOut": []}}
+
+        self.fake_rpc.register_handler(
+            "Server.RpcGetObject", mock_RpcGetObject)
+
+        req = swob.Request.blank('/v1/AUTH_test/notes/lunch')
+        status, headers, body = self.call_pfs(req)
+
+        self.assertEqual(status, '200 OK')
+        self.assertEqual(headers["X-Delete-At"], "1554003600")
+
     def test_GET_slo_manifest(self):
         self.app.register(
             'GET', '/v1/AUTH_test/InternalContainerName/0000000000c11fbd',
@@ -858,6 +895,38 @@ class TestObjectGet(BaseMiddlewareTest):
         self.assertEqual(status, "200 OK")
         self.assertEqual(body, b"sparse" + (b"\x00" * 10000) + b"file")
 
+    def test_GET_inline(self):
+        # Small files may have their data kept in their inode, in which case
+        # the read plan carries the data itself (base64 encoded by Go).
+        def mock_RpcGetObject(get_object_req):
+            self.assertEqual(get_object_req['VirtPath'],
+                             "/v1/AUTH_test/c/tiny-file")
+
+            return {
+                "error": None,
+                "result": {
+                    "FileSize": 8,
+                    "Metadata": "",
+                    "InodeNumber": 1245,
+                    "NumWrites": 2424,
+                    "ModificationTime": 1481152134331862558,
+                    "LeaseId": "6840595b3370f109dc8ed388b41800a4",
+                    "ReadEntsOut": [{
+                        "ObjectPath": "",
+                        "Offset": 0,
+                        "Length": 8,
+                        "InlineData": base64.b64encode(
+                            b"burritos").decode('ascii')}]}}
+
+        req = swob.Request.blank('/v1/AUTH_test/c/tiny-file')
+
+        self.fake_rpc.register_handler(
+            "Server.RpcGetObject", mock_RpcGetObject)
+        status, headers, body = self.call_pfs(req)
+
+        self.assertEqual(status, "200 OK")
+        self.assertEqual(body, b"burritos")
+
     def test_GET_multiple_segments(self):
         # Typically, a GET request will include data from multiple log
         # segments. Small files written all at once might fit in a single
@@ -1042,6 +1111,92 @@ class TestObjectGet(BaseMiddlewareTest):
         self.assertEqual(
             body, b'Cheshire, Duddleswell, Dunlop, Coquetdale, Derby')
 
+    def test_GET_range_compressed(self):
+        # On volumes with compression enabled, log segments hold units of
+        # raw DEFLATE data; the read plan says where each unit lives and
+        # which part of the decompressed unit is wanted.
+        compressor = zlib.compressobj(9, zlib.DEFLATED, -zlib.MAX_WBITS)
+        unit = compressor.compress(b"Caerphilly, Cheddar, Cheshire, ")
+        unit += compressor.flush()
+
+        self.app.register(
+            'GET', '/v1/AUTH_test/InternalContainerName/0000000000000001',
+            200, {},
+            b"prior unit" + unit + b"\xff\xea\x00junk")
+        self.app.register(
+            'GET', '/v1/AUTH_test/InternalContainerName/0000000000000002',
+            200, {},
+            ("Derby, Gloucester, Wensleydale\xff\xea\x00junk"))
+
+        def mock_RpcGetObject(get_object_req):
+            self.assertEqual(get_object_req['VirtPath'],
+                             "/v1/AUTH_test/cheeses/UK")
+            self.assertEqual(get_object_req['ReadEntsIn'],
+                             [{"Offset": 12, "Len": 24}])
+
+            return {
+                "error": None,
+                "result": {
+                    "FileSize": 61,
+                    "Metadata": "",
+                    "InodeNumber": 1245,
+                    "NumWrites": 2424,
+                    "ModificationTime": 1481152134331862558,
+                    "LeaseId": "982938",
+                    "ReadEntsOut": [{
+                        "ObjectPath": ("/v1/AUTH_test/InternalContainer"
+                                       "Name/0000000000000001"),
+                        "Offset": 12,
+                        "Length": 19,
+                        "Compression": "Deflate",
+                        "CompressedObjectOffset": 10,
+                        "CompressedObjectLength": len(unit),
+                    }, {
+                        "ObjectPath": ("/v1/AUTH_test/InternalContainer"
+                                       "Name/0000000000000002"),
+                        "Offset": 0,
+                        "Length": 5}]}}
+
+        req = swob.Request.blank('/v1/AUTH_test/cheeses/UK',
+                                 headers={"Range": "bytes=12-35"})
+
+        self.fake_rpc.register_handler(
+            "Server.RpcGetObject", mock_RpcGetObject)
+        status, headers, body = self.call_pfs(req)
+
+        self.assertEqual(status, '206 Partial Content')
+        self.assertEqual(headers.get('Content-Range'), "bytes 12-35/61")
+        self.assertEqual(body, b'Cheddar, Cheshire, Derby')
+
+    def test_GET_encrypted(self):
+        # Encrypted units can't be decoded here since we hold no keys
+        def mock_RpcGetObject(get_object_req):
+            return {
+                "error": None,
+                "result": {
+                    "FileSize": 8,
+                    "Metadata": "",
+                    "InodeNumber": 1245,
+                    "NumWrites": 2424,
+                    "ModificationTime": 1481152134331862558,
+                    "LeaseId": "982938",
+                    "ReadEntsOut": [{
+                        "ObjectPath": ("/v1/AUTH_test/InternalContainer"
+                                       "Name/0000000000000001"),
+                        "Offset": 0,
+                        "Length": 8,
+                        "Encryption": "AES-GCM",
+                        "CompressedObjectOffset": 0,
+                        "CompressedObjectLength": 36}]}}
+
+        req = swob.Request.blank('/v1/AUTH_test/c/secret')
+
+        self.fake_rpc.register_handler(
+            "Server.RpcGetObject", mock_RpcGetObject)
+        status, headers, body = self.call_pfs(req)
+
+        self.assertEqual(status, '501 Not Implemented')
+
     def test_GET_range_suffix(self):
         self.app.register(
             'GET', '/v1/AUTH_test/InternalContainerName/0000000000000001',
@@ -2513,6 +2668,10 @@ class TestContainerPut(BaseMiddlewareTest):
         self.fake_rpc.register_handler(
             "Server.RpcPutContainer", mock_RpcPutContainer)
 
+        self.fake_rpc.register_handler(
+            "Server.RpcSetVersioning",
+            lambda *a: {"error": None, "result": {}})
+
     def test_new_container(self):
         def mock_RpcHead(_):
             return {"error": "errno: 2", "result": None}
@@ -2748,7 +2907,7 @@ class TestContainerPut(BaseMiddlewareTest):
         self.assertEqual("202 Accepted", status)
 
         rpc_calls = self.fake_rpc.calls
-        method, args = rpc_calls[-1]
+        method, args = rpc_calls[-2]
         self.assertEqual(method, "Server.RpcPutContainer")
         self.assertEqual(args[0]["VirtPath"], "/v1/AUTH_test/new-con")
         self.assertEqual(
@@ -2756,6 +2915,13 @@ class TestContainerPut(BaseMiddlewareTest):
         new_meta = json.loads(base64.b64decode(args[0]["NewMetadata"]))
         self.assertEqual(expected_meta, new_meta)
 
+        # X-Versions-Location is gone, so versioning is turned off
+        method, args = rpc_calls[-1]
+        self.assertEqual(method, "Server.RpcSetVersioning")
+        self.assertEqual(args[0]["VirtPath"], "/v1/AUTH_test/new-con")
+        self.assertEqual(args[0]["Mode"], "")
+        self.assertEqual(args[0]["MaxVersions"], 0)
+
     def test_metadata_removal_swift_owner(self):
         self._check_metadata_removal(
             {"X-Container-Read": "",
@@ -2802,6 +2968,83 @@ class TestContainerPut(BaseMiddlewareTest):
              "X-Container-Sync-Key": "sync-key",
              "X-Container-Sync-To": "sync-to"})
 
+    def test_versioning_new_container(self):
+        self.fake_rpc.register_handler(
+            "Server.RpcHead", lambda *a: {"error": "errno: 2", "result": None})
+
+        req = swob.Request.blank(
+            "/v1/AUTH_test/new-con",
+            environ={"REQUEST_METHOD": "PUT"},
+            headers={"X-History-Location": "old-stuff",
+                     "X-Container-Meta-Max-Versions": "3"})
+        status, _, _ = self.call_pfs(req)
+        self.assertEqual("201 Created", status)
+
+        rpc_calls = self.fake_rpc.calls
+        self.assertEqual(4, len(rpc_calls))
+        self.assertEqual(rpc_calls[2][0], "Server.RpcPutContainer")
+
+        method, args = rpc_calls[3]
+        self.assertEqual(method, "Server.RpcSetVersioning")
+        self.assertEqual(args[0]["VirtPath"], "/v1/AUTH_test/new-con")
+        self.assertEqual(args[0]["Mode"], "history")
+        self.assertEqual(args[0]["MaxVersions"], 3)
+
+    def test_versioning_unchanged(self):
+        old_meta = json.dumps({"X-Versions-Location": "loc"})
+
+        def mock_RpcHead(_):
+            return {
+                "error": None,
+                "result": {
+                    "Metadata": base64.b64encode(
+                        old_meta.encode('ascii')).decode('ascii'),
+                    "ModificationTime": 1511224700123739000,
+                    "FileSize": 0,
+                    "IsDir": True,
+                    "InodeNumber": 8017342,
+                    "NumWrites": 0}}
+
+        self.fake_rpc.register_handler("Server.RpcHead", mock_RpcHead)
+
+        req = swob.Request.blank(
+            "/v1/AUTH_test/con",
+            environ={"REQUEST_METHOD": "PUT"},
+            headers={"X-Versions-Location": "other-loc"})
+        status, _, _ = self.call_pfs(req)
+        self.assertEqual("202 Accepted", status)
+        self.assertEqual(
+            ["Server.RpcIsAccountBimodal", "Server.RpcHead",
+             "Server.RpcPutContainer"],
+            [method for method, _ in self.fake_rpc.calls])
+
+    def test_versioning_bad_headers(self):
+        self.fake_rpc.register_handler(
+            "Server.RpcHead", lambda *a: {"error": "errno: 2", "result": None})
+
+        for headers in (
+                {"X-History-Location": "a", "X-Versions-Location": "b"},
+                {"X-Versions-Location": "a",
+                 "X-Container-Meta-Max-Versions": "-1"},
+                {"X-Versions-Location": "a",
+                 "X-Container-Meta-Max-Versions": "lots"}):
+            req = swob.Request.blank(
+                "/v1/AUTH_test/new-con",
+                environ={"REQUEST_METHOD": "PUT"},
+                headers=headers)
+            status, _, _ = self.call_pfs(req)
+            self.assertEqual("400 Bad Request", status)
+
+        self.assertNotIn("Server.RpcPutContainer",
+                         [method for method, _ in self.fake_rpc.calls])
+
+    def test_versions_dir_name(self):
+        req = swob.Request.blank(
+            "/v1/AUTH_test/.versions",
+            environ={"REQUEST_METHOD": "PUT"})
+        status, _, _ = self.call_pfs(req)
+        self.assertEqual("400 Bad Request", status)
+
 
 class TestContainerDelete(BaseMiddlewareTest):
     def test_success(self):
@@ -3164,6 +3407,10 @@ class TestObjectPut(BaseMiddlewareTest):
              pre + "1550057D8B0039185EB6184C599C940E51953403-01",
              pre + "1550057D8B0039185EB6184C599C940E51953403-02"])
         self.assertEqual(args[0]["PhysLengths"], [100, 100, 75])
+        self.assertEqual(args[0]["PhysBlockCRC32s"],
+                         [[zlib.crc32(b'A' * 100) & 0xffffffff],
+                          [zlib.crc32(b'B' * 100) & 0xffffffff],
+                          [zlib.crc32(b'C' * 75) & 0xffffffff]])
 
         # check the txids as well
         put_calls = [c for c in self.app.calls if c[0] == 'PUT']
@@ -3336,10 +3583,9 @@ class TestObjectPut(BaseMiddlewareTest):
         self.assertEqual(status, '409 Conflict')
 
     def test_stripping_bad_headers(self):
-        # Someday, we'll have to figure out how to expire objects in
-        # proxyfs. For now, though, we remove X-Delete-At and X-Delete-After
-        # because having a log segment expire will do bad things to our
-        # file's integrity.
+        # We remove X-Delete-At and X-Delete-After because having a log
+        # segment expire will do bad things to our file's integrity;
+        # proxyfsd expires the object itself instead.
         #
         # We strip ETag because Swift will treat that as the expected MD5 of
         # the log segment, and if we split across multiple log segments,
@@ -3352,14 +3598,26 @@ class TestObjectPut(BaseMiddlewareTest):
         headers_in = {"X-Delete-After": 86400,
                       "ETag": hashlib.md5(wsgi_input.getvalue()).hexdigest()}
 
+        self.fake_rpc.register_handler(
+            "Server.RpcSetExpiration",
+            lambda *a: {"error": None, "result": {}})
+
         req = swob.Request.blank("/v1/AUTH_test/a-container/an-object",
                                  headers=headers_in,
                                  environ={"REQUEST_METHOD": "PUT",
                                           "wsgi.input": wsgi_input,
                                           "CONTENT_LENGTH": cl})
-        status, headers, body = self.call_pfs(req)
+        with mock.patch('time.time', return_value=1554000000.5):
+            status, headers, body = self.call_pfs(req)
         self.assertEqual(status, '201 Created')
 
+        # proxyfsd was told when to expire the object instead
+        method, args = self.fake_rpc.calls[-1]
+        self.assertEqual(method, "Server.RpcSetExpiration")
+        self.assertEqual(args[0]["VirtPath"],
+                         "/v1/AUTH_test/a-container/an-object")
+        self.assertEqual(args[0]["ExpireAt"], 1554000000 + 86400)
+
         serialized_metadata = self.fake_rpc.calls[3][1][0]["Metadata"]
         metadata = json.loads(base64.b64decode(serialized_metadata))
         # it didn't get saved in metadata (not that it matters too much)
@@ -3374,6 +3632,33 @@ class TestObjectPut(BaseMiddlewareTest):
         self.assertNotIn("X-Delete-After", put_headers)
         self.assertNotIn("ETag", put_headers)
 
+    def test_bad_expiration(self):
+        def put_with(headers):
+            req = swob.Request.blank(
+                "/v1/AUTH_test/a-container/an-object",
+                headers=headers,
+                environ={"REQUEST_METHOD": "PUT",
+                         "wsgi.input": BytesIO(b"uncharred-scratchback"),
+                         "CONTENT_LENGTH": "21"})
+            with mock.patch('time.time', return_value=1554000000.5):
+                return self.call_pfs(req)
+
+        status, _, body = put_with({"X-Delete-At": "1553999999"})
+        self.assertEqual(status, '400 Bad Request')
+        self.assertEqual(body, b"X-Delete-At in past")
+
+        status, _, body = put_with({"X-Delete-After": "soon"})
+        self.assertEqual(status, '400 Bad Request')
+        self.assertEqual(body, b"Non-integer X-Delete-After")
+
+        status, _, body = put_with({"X-Delete-After": "-1"})
+        self.assertEqual(status, '400 Bad Request')
+        self.assertEqual(body, b"X-Delete-After in past")
+
+        # nothing was uploaded
+        self.assertNotIn("Server.RpcPutLocation",
+                         [method for method, _ in self.fake_rpc.calls])
+
     def test_etag_checking(self):
         wsgi_input = BytesIO(b"unsplashed-comprest")
         right_etag = hashlib.md5(wsgi_input.getvalue()).hexdigest()
@@ -3667,6 +3952,76 @@ class TestObjectPost(BaseMiddlewareTest):
         new_meta = json.loads(base64.b64decode(args[0]["NewMetaData"]))
         self.assertNotIn("X-Object-Meta-Color", new_meta)
 
+    def test_expiration(self):
+        self.expire_at = 0
+
+        def mock_RpcHead(_):
+            return {
+                "error": None,
+                "result": {
+                    "Metadata": "",
+                    "ModificationTime": 1554000000000000000,
+                    "FileSize": 20781,
+                    "IsDir": False,
+                    "InodeNumber": 2738215,
+                    "NumWrites": 1,
+                    "ExpireAt": self.expire_at}}
+
+        self.fake_rpc.register_handler(
+            "Server.RpcHead", mock_RpcHead)
+
+        self.fake_rpc.register_handler(
+            "Server.RpcPost", lambda *a: {"error": None, "result": {}})
+
+        self.fake_rpc.register_handler(
+            "Server.RpcSetExpiration",
+            lambda *a: {"error": None, "result": {}})
+
+        def post_with(headers):
+            ncalls = len(self.fake_rpc.calls)
+            req = swob.Request.blank(
+                "/v1/AUTH_test/con/obj",
+                environ={"REQUEST_METHOD": "POST"},
+                headers=headers)
+            with mock.patch('time.time', return_value=1554000000.5):
+                status, _, _ = self.call_pfs(req)
+            self.assertEqual("202 Accepted", status)
+            return [(method, args[0])
+                    for method, args in self.fake_rpc.calls[ncalls:]
+                    if method == "Server.RpcSetExpiration"]
+
+        # setting an expiration
+        self.assertEqual(
+            post_with({"X-Delete-After": "3600"}),
+            [("Server.RpcSetExpiration", {
+                "VirtPath": "/v1/AUTH_test/con/obj",
+                "ExpireAt": 1554003600})])
+
+        # leaving it alone
+        self.expire_at = 1554003600
+        self.assertEqual(
+            post_with({"X-Delete-At": "1554003600"}), [])
+
+        # removing it, just like Swift, by not sending it
+        self.assertEqual(
+            post_with({"X-Object-Meta-Fish": "cod"}),
+            [("Server.RpcSetExpiration", {
+                "VirtPath": "/v1/AUTH_test/con/obj",
+                "ExpireAt": 0})])
+
+        # a bad one doesn't change anything
+        ncalls = len(self.fake_rpc.calls)
+        req = swob.Request.blank(
+            "/v1/AUTH_test/con/obj",
+            environ={"REQUEST_METHOD": "POST"},
+            headers={"X-Delete-At": "tomorrow"})
+        status, _, body = self.call_pfs(req)
+        self.assertEqual("400 Bad Request", status)
+        self.assertEqual(b"Non-integer X-Delete-At", body)
+        self.assertNotIn(
+            "Server.RpcPost",
+            [method for method, _ in self.fake_rpc.calls[ncalls:]])
+
 
 class TestObjectDelete(BaseMiddlewareTest):
     def test_success(self):
@@ -3739,11 +4094,109 @@ class TestObjectDelete(BaseMiddlewareTest):
         self.assertEqual(status, "500 Internal Error")
 
 
+class TestObjectVersions(BaseMiddlewareTest):
+    def test_list(self):
+        def mock_RpcListVersions(_):
+            return {
+                "error": None,
+                "result": {"Versions": [{
+                    "VersionID": "15E9F1C2A4B60000",
+                    "ArchiveTime": 1580000000000000000,
+                    "FileSize": 5,
+                    "ModificationTime": 1570000000000000000,
+                    "AttrChangeTime": 1570000000000000000,
+                    "NumWrites": 1,
+                    "InodeNumber": 1234,
+                    "Metadata": base64.b64encode(json.dumps({
+                        "Content-Type": "text/plain"}).encode(
+                            'ascii')).decode('ascii'),
+                    "ETag": ""}, {
+                    "VersionID": "15E9F1C2A4B70000",
+                    "ArchiveTime": 1590000000000000000,
+                    "FileSize": 8,
+                    "ModificationTime": 1580000000000000000,
+                    "AttrChangeTime": 1580000000000000000,
+                    "NumWrites": 2,
+                    "InodeNumber": 1235,
+                    "Metadata": None,
+                    "ETag": "0123456789abcdef0123456789abcdef"}]}}
+
+        self.fake_rpc.register_handler(
+            "Server.RpcListVersions", mock_RpcListVersions)
+
+        req = swob.Request.blank("/v1/AUTH_test/con/obj.txt?versions")
+        status, headers, body = self.call_pfs(req)
+        self.assertEqual("200 OK", status)
+        self.assertEqual(headers["Content-Type"],
+                         "application/json; charset=utf-8")
+
+        method, args = self.fake_rpc.calls[-1]
+        self.assertEqual(method, "Server.RpcListVersions")
+        self.assertEqual(args[0]["VirtPath"], "/v1/AUTH_test/con/obj.txt")
+
+        versions = json.loads(body)
+        self.assertEqual(
+            ["15E9F1C2A4B70000", "15E9F1C2A4B60000"],
+            [v["version_id"] for v in versions])
+        self.assertEqual(versions[0]["bytes"], 8)
+        self.assertEqual(versions[0]["hash"],
+                         "0123456789abcdef0123456789abcdef")
+        self.assertEqual(versions[0]["content_type"], "text/plain")
+        self.assertEqual(versions[0]["last_modified"],
+                         "2020-01-26T00:53:20.000000")
+        self.assertEqual(versions[0]["archived"],
+                         "2020-05-20T18:40:00.000000")
+        self.assertEqual(versions[1]["bytes"], 5)
+        self.assertEqual(versions[1]["content_type"], "text/plain")
+
+    def test_list_not_found(self):
+        self.fake_rpc.register_handler(
+            "Server.RpcListVersions",
+            lambda *a: {"error": "errno: 2", "result": None})
+
+        req = swob.Request.blank("/v1/AUTH_test/con/obj?versions")
+        status, _, _ = self.call_pfs(req)
+        self.assertEqual("404 Not Found", status)
+
+    def test_restore(self):
+        self.fake_rpc.register_handler(
+            "Server.RpcRestoreVersion",
+            lambda *a: {"error": None, "result": {}})
+
+        req = swob.Request.blank(
+            "/v1/AUTH_test/con/obj",
+            environ={"REQUEST_METHOD": "POST"},
+            headers={"X-Restore-Version": "15E9F1C2A4B60000"})
+        status, _, _ = self.call_pfs(req)
+        self.assertEqual("202 Accepted", status)
+
+        method, args = self.fake_rpc.calls[-1]
+        self.assertEqual(method, "Server.RpcRestoreVersion")
+        self.assertEqual(args[0]["VirtPath"], "/v1/AUTH_test/con/obj")
+        self.assertEqual(args[0]["VersionID"], "15E9F1C2A4B60000")
+
+    def test_restore_errors(self):
+        for errno, expected_status in (
+                (2, "404 Not Found"),
+                (22, "400 Bad Request")):
+            self.fake_rpc.register_handler(
+                "Server.RpcRestoreVersion",
+                lambda *a: {"error": "errno: %d" % errno, "result": None})
+
+            req = swob.Request.blank(
+                "/v1/AUTH_test/con/obj",
+                environ={"REQUEST_METHOD": "POST"},
+                headers={"X-Restore-Version": "bogus"})
+            status, _, _ = self.call_pfs(req)
+            self.assertEqual(expected_status, status)
+
+
 class TestObjectHead(BaseMiddlewareTest):
     def setUp(self):
         super(TestObjectHead, self).setUp()
 
         self.serialized_object_metadata = ""
+        self.expire_at = 0
 
         # All these tests run against the same object.
         def mock_RpcHead(head_object_req):
@@ -3766,6 +4219,7 @@ class TestObjectHead(BaseMiddlewareTest):
                     "IsDir": False,
                     "InodeNumber": 4591,
                     "NumWrites": 874,
+                    "ExpireAt": self.expire_at,
                 }}
 
         self.fake_rpc.register_handler(
@@ -3934,6 +4388,18 @@ class TestObjectHead(BaseMiddlewareTest):
         self.assertEqual(status, '200 OK')
         self.assertEqual(headers["Etag"], "b61d068208b52f4acbd618860d30faae")
 
+    def test_expiration(self):
+        req = swob.Request.blank("/v1/AUTH_test/c/an-object.png",
+                                 environ={"REQUEST_METHOD": "HEAD"})
+        status, headers, _ = self.call_pfs(req)
+        self.assertEqual(status, '200 OK')
+        self.assertNotIn("X-Delete-At", headers)
+
+        self.expire_at = 1554003600
+        status, headers, _ = self.call_pfs(req)
+        self.assertEqual(status, '200 OK')
+        self.assertEqual(headers["X-Delete-At"], "1554003600")
+
     def test_conditional_if_match(self):
         obj_etag = "c2185d19ada5f5c3aa47d6b55dc912df"
 
@@ -4337,6 +4803,159 @@ class TestObjectCoalesce(BaseMiddlewareTest):
         self.assertEqual(status, '500 Internal Error')
 
 
+class TestObjectCopy(BaseMiddlewareTest):
+    def setUp(self):
+        super(TestObjectCopy, self).setUp()
+
+        self.src_metadata = {
+            "Content-Type": "image/png",
+            "X-Object-Meta-Color": "red",
+            mware.ORIGINAL_MD5_HEADER: "2:6f5902ac237024bdd0c176cb93063dc4",
+        }
+
+        def mock_RpcHead(head_req):
+            path = head_req['VirtPath']
+            if path == "/v1/AUTH_test/c/src.png":
+                return {
+                    "error": None,
+                    "result": {
+                        "Metadata": base64.b64encode(json.dumps(
+                            self.src_metadata).encode('ascii')).decode(
+                                'ascii'),
+                        "ModificationTime": 1479173168018879490,
+                        "FileSize": 2641863,
+                        "IsDir": False,
+                        "InodeNumber": 4591,
+                        "NumWrites": 2,
+                    }}
+            elif path in ("/v1/AUTH_test/c", "/v1/AUTH_test/c2"):
+                return {
+                    "error": None,
+                    "result": {
+                        "Metadata": "",
+                        "ModificationTime": 1485814697697650000,
+                        "FileSize": 0,
+                        "IsDir": True,
+                        "InodeNumber": 1828,
+                        "NumWrites": 893,
+                    }}
+            else:
+                return {
+                    "error": "errno: 2",
+                    "result": None,
+                }
+
+        self.fake_rpc.register_handler(
+            "Server.RpcHead", mock_RpcHead)
+
+        def mock_RpcCopy(copy_req):
+            return {
+                "error": None,
+                "result": {
+                    "ModificationTime": 1488323796002909000,
+                    "InodeNumber": 283253,
+                    "NumWrites": 1,
+                }}
+
+        self.fake_rpc.register_handler(
+            "Server.RpcCopy", mock_RpcCopy)
+
+    def _copy_args(self):
+        method, args = self.fake_rpc.calls[-1]
+        self.assertEqual(method, "Server.RpcCopy")
+        return (args[0]["VirtPath"], args[0]["SrcAccountRelativePath"],
+                json.loads(base64.b64decode(args[0]["Metadata"])))
+
+    def test_copy_verb(self):
+        req = swob.Request.blank(
+            "/v1/AUTH_test/c/src.png",
+            environ={"REQUEST_METHOD": "COPY"},
+            headers={"Destination": "c2/dst.png",
+                     "X-Object-Meta-Shape": "round"})
+        status, headers, body = self.call_pfs(req)
+        self.assertEqual(status, '201 Created')
+        self.assertEqual(headers["Etag"],
+                         '"pfsv2/AUTH_test/00045275/00000001-32"')
+        self.assertEqual(headers["Content-Type"], "image/png")
+        self.assertEqual(headers["X-Copied-From"], "c/src.png")
+
+        virt_path, src_path, metadata = self._copy_args()
+        self.assertEqual(virt_path, "/v1/AUTH_test/c2/dst.png")
+        self.assertEqual(src_path, "/c/src.png")
+        # The source's MD5 doesn't describe the copy's sequence of writes
+        self.assertEqual(metadata, {
+            "Content-Type": "image/png",
+            "X-Object-Meta-Color": "red",
+            "X-Object-Meta-Shape": "round",
+        })
+
+    def test_put_with_copy_from(self):
+        req = swob.Request.blank(
+            "/v1/AUTH_test/c2/dst.png",
+            environ={"REQUEST_METHOD": "PUT"},
+            headers={"X-Copy-From": "/c/src.png",
+                     "Content-Length": "0",
+                     "X-Fresh-Metadata": "true"})
+        status, headers, body = self.call_pfs(req)
+        self.assertEqual(status, '201 Created')
+
+        virt_path, src_path, metadata = self._copy_args()
+        self.assertEqual(virt_path, "/v1/AUTH_test/c2/dst.png")
+        self.assertEqual(src_path, "/c/src.png")
+        self.assertEqual(metadata, {"Content-Type": "image/png"})
+
+    def test_put_with_copy_from_and_body(self):
+        req = swob.Request.blank(
+            "/v1/AUTH_test/c2/dst.png",
+            environ={"REQUEST_METHOD": "PUT",
+                     "wsgi.input": BytesIO(b"data")},
+            headers={"X-Copy-From": "c/src.png",
+                     "Content-Length": "4"})
+        status, headers, body = self.call_pfs(req)
+        self.assertEqual(status, '400 Bad Request')
+
+    def test_missing_destination(self):
+        req = swob.Request.blank(
+            "/v1/AUTH_test/c/src.png",
+            environ={"REQUEST_METHOD": "COPY"})
+        status, headers, body = self.call_pfs(req)
+        self.assertEqual(status, '412 Precondition Failed')
+
+    def test_bad_destination(self):
+        req = swob.Request.blank(
+            "/v1/AUTH_test/c/src.png",
+            environ={"REQUEST_METHOD": "COPY"},
+            headers={"Destination": "just-a-container"})
+        status, headers, body = self.call_pfs(req)
+        self.assertEqual(status, '412 Precondition Failed')
+
+    def test_source_not_found(self):
+        req = swob.Request.blank(
+            "/v1/AUTH_test/c/no-such-src.png",
+            environ={"REQUEST_METHOD": "COPY"},
+            headers={"Destination": "c2/dst.png"})
+        status, headers, body = self.call_pfs(req)
+        self.assertEqual(status, '404 Not Found')
+        self.assertNotIn("Server.RpcCopy",
+                         [method for method, args in self.fake_rpc.calls])
+
+    def test_cross_account(self):
+        # Different accounts are different volumes; let Swift copy the data
+        self.app.register('COPY', '/v1/AUTH_test/c/src.png', 201, {}, '')
+
+        req = swob.Request.blank(
+            "/v1/AUTH_test/c/src.png",
+            environ={"REQUEST_METHOD": "COPY"},
+            headers={"Destination": "c2/dst.png",
+                     "Destination-Account": "AUTH_other"})
+        status, headers, body = self.call_pfs(req)
+        self.assertEqual(status, '201 Created')
+        self.assertEqual(self.app.calls[-1][:2],
+                         ('COPY', '/v1/AUTH_test/c/src.png'))
+        self.assertNotIn("Server.RpcCopy",
+                         [method for method, args in self.fake_rpc.calls])
+
+
 class TestAuth(BaseMiddlewareTest):
     def setUp(self):
         super(TestAuth, self).setUp()
@@ -4456,6 +5075,29 @@ class TestBestPossibleEtag(unittest.TestCase):
                 {}, "AUTH_test", 0x01740209, 1),
             '"pfsv2/AUTH_test/01740209/00000001-32"')
 
+    def test_fs_etag(self):
+        # proxyfsd-maintained MD5 beats a constructed ETag...
+        self.assertEqual(
+            mware.best_possible_etag(
+                {}, "AUTH_test", 0x01740209, 3,
+                fs_etag="0b5e1ad4c8f8fb81dbd1bb7d0d5f4ad4"),
+            '0b5e1ad4c8f8fb81dbd1bb7d0d5f4ad4')
+
+        # ... and a stale Initial-MD5
+        self.assertEqual(
+            mware.best_possible_etag(
+                {self.HEADER: "1:5484c2634aa61c69fc02ef5400a61c94"},
+                "AUTH_test", 0x01740209, 3,
+                fs_etag="0b5e1ad4c8f8fb81dbd1bb7d0d5f4ad4"),
+            '0b5e1ad4c8f8fb81dbd1bb7d0d5f4ad4')
+
+        # ... but directories are still directories
+        self.assertEqual(
+            mware.best_possible_etag(
+                {}, "AUTH_test", 0x01740209, 3, is_dir=True,
+                fs_etag="0b5e1ad4c8f8fb81dbd1bb7d0d5f4ad4"),
+            mware.EMPTY_OBJECT_ETAG)
+
     def test_bogus(self):
         self.assertEqual(
             mware.best_possible_etag(
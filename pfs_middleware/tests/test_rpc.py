@@ -55,14 +55,19 @@ class TestResponseParsing(unittest.TestCase):
             "InodeNumber": 'f',
             "NumWrites": 'g',
             "LeaseId": 'h',
+            "ETag": 'i',
         }
         self.assertEqual(rpc.parse_get_object_response(resp), (
-            'a', 'b', 'c', 'e', 'f', 'g', 'h'))
+            'a', 'b', 'c', 'e', 'f', 'g', 'h', 'i'))
         # Old proxyfsd didn't send AttrChangeTime, but we've always had
         # ModificationTime available, which is the next-best option
         del resp["AttrChangeTime"]
         self.assertEqual(rpc.parse_get_object_response(resp), (
-            'a', 'b', 'c', 'd', 'f', 'g', 'h'))
+            'a', 'b', 'c', 'd', 'f', 'g', 'h', 'i'))
+        # Old proxyfsd didn't send ETag either
+        del resp["ETag"]
+        self.assertEqual(rpc.parse_get_object_response(resp), (
+            'a', 'b', 'c', 'd', 'f', 'g', 'h', ''))
 
     def test_coalesce_object(self):
         resp = {
@@ -137,11 +142,16 @@ class TestResponseParsing(unittest.TestCase):
             "IsDir": 'e',
             "InodeNumber": 'f',
             "NumWrites": 'g',
+            "ETag": 'h',
         }
         self.assertEqual(rpc.parse_head_response(resp), (
-            'a', 'c', 'd', 'e', 'f', 'g'))
+            'a', 'c', 'd', 'e', 'f', 'g', 'h'))
         # Old proxyfsd didn't send AttrChangeTime, but we've always had
         # ModificationTime available, which is the next-best option
         del resp["AttrChangeTime"]
         self.assertEqual(rpc.parse_head_response(resp), (
-            'a', 'b', 'd', 'e', 'f', 'g'))
+            'a', 'b', 'd', 'e', 'f', 'g', 'h'))
+        # Old proxyfsd didn't send ETag either
+        del resp["ETag"]
+        self.assertEqual(rpc.parse_head_response(resp), (
+            'a', 'b', 'd', 'e', 'f', 'g', ''))
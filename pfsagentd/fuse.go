@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
@@ -11,6 +12,7 @@ import (
 
 	"bazil.org/fuse"
 
+	"github.com/swiftstack/ProxyFS/jrpcfs"
 	"github.com/swiftstack/ProxyFS/utils"
 )
 
@@ -57,6 +59,8 @@ func performMount() {
 		}
 	}
 
+	performProxyFSMount()
+
 	globals.fuseConn, err = fuse.Mount(
 		globals.config.FUSEMountPointPath,
 		fuse.AllowOther(),
@@ -84,6 +88,49 @@ func performMount() {
 	logInfof("Now serving %s on %s", globals.config.FUSEVolumeName, globals.config.FUSEMountPointPath)
 }
 
+// performProxyFSMount mounts the volume backing [Agent]SwiftAccountName in order to obtain the MountID
+// passed in subsequent RPCs.
+func performProxyFSMount() {
+	var (
+		err          error
+		mountReply   *jrpcfs.MountByAccountNameReply
+		mountRequest *jrpcfs.MountByAccountNameRequest
+	)
+
+	mountRequest = &jrpcfs.MountByAccountNameRequest{
+		AccountName:  globals.config.SwiftAccountName,
+		MountOptions: 0,
+		AuthUserID:   0,
+		AuthGroupID:  0,
+	}
+
+	mountReply = &jrpcfs.MountByAccountNameReply{}
+
+	err = doJRPCRequest("Server.RpcMountByAccountName", mountRequest, mountReply)
+	if nil != err {
+		logFatalf("unable to mount %s: %v", globals.config.SwiftAccountName, err)
+	}
+
+	globals.mountID = mountReply.MountID
+}
+
+// fuseErrorOf converts an error returned by doJRPCRequest() to the fuse.Errno to respond with.
+func fuseErrorOf(err error) (fuseErr fuse.Errno) {
+	var (
+		errno int
+	)
+
+	_, scanErr := fmt.Sscanf(err.Error(), "errno: %d", &errno)
+	if nil != scanErr {
+		logWarnf("unexpected error (responding with fuse.EIO): %v", err)
+		fuseErr = fuse.EIO
+		return
+	}
+
+	fuseErr = fuse.Errno(syscall.Errno(errno))
+	return
+}
+
 func fetchInodeDevice(pathTitle string, path string) (inodeDevice int64) {
 	var (
 		err  error
@@ -148,12 +195,16 @@ func serveFuse() {
 		switch request.(type) {
 		case *fuse.AccessRequest:
 			handleAccessRequest(request.(*fuse.AccessRequest))
+		case *fuse.CopyFileRangeRequest:
+			handleCopyFileRangeRequest(request.(*fuse.CopyFileRangeRequest))
 		case *fuse.CreateRequest:
 			handleCreateRequest(request.(*fuse.CreateRequest))
 		case *fuse.DestroyRequest:
 			handleDestroyRequest(request.(*fuse.DestroyRequest))
 		case *fuse.ExchangeDataRequest:
 			handleExchangeDataRequest(request.(*fuse.ExchangeDataRequest))
+		case *fuse.FallocateRequest:
+			handleFallocateRequest(request.(*fuse.FallocateRequest))
 		case *fuse.FlushRequest:
 			handleFlushRequest(request.(*fuse.FlushRequest))
 		case *fuse.ForgetRequest:
@@ -174,6 +225,8 @@ func serveFuse() {
 			handleListxattrRequest(request.(*fuse.ListxattrRequest))
 		case *fuse.LookupRequest:
 			handleLookupRequest(request.(*fuse.LookupRequest))
+		case *fuse.LseekRequest:
+			handleLseekRequest(request.(*fuse.LseekRequest))
 		case *fuse.MkdirRequest:
 			handleMkdirRequest(request.(*fuse.MkdirRequest))
 		case *fuse.MknodRequest:
@@ -213,6 +266,15 @@ func handleAccessRequest(request *fuse.AccessRequest) {
 	logFatalf("handleAccessRequest() should not have been called due to DefaultPermissions() passed to fuse.Mount()")
 }
 
+func handleCopyFileRangeRequest(request *fuse.CopyFileRangeRequest) {
+	logInfof("TODO: handleCopyFileRangeRequest() via Server.RpcCopyFileRange")
+	logInfof("Header:\n%s", utils.JSONify(request.Header, true))
+	logInfof("Payload\n%s", utils.JSONify(request, true))
+	// fuse.ENOSYS (rather than fuse.ENOTSUP) tells the kernel to fall back to copying the data itself
+	logInfof("Responding with fuse.ENOSYS")
+	request.RespondError(fuse.ENOSYS)
+}
+
 func handleCreateRequest(request *fuse.CreateRequest) {
 	logInfof("TODO: handleCreateRequest()")
 	logInfof("Header:\n%s", utils.JSONify(request.Header, true))
@@ -228,8 +290,37 @@ func handleDestroyRequest(request *fuse.DestroyRequest) {
 	request.RespondError(fuse.ENOTSUP)
 }
 
+// handleExchangeDataRequest swaps the two (file) inodes' directory entries... such that each name
+// subsequently refers to the other's data (and attributes).
 func handleExchangeDataRequest(request *fuse.ExchangeDataRequest) {
-	logInfof("TODO: handleExchangeDataRequest()")
+	var (
+		err           error
+		renameReply   *jrpcfs.Reply
+		renameRequest *jrpcfs.RenameRequest
+	)
+
+	renameRequest = &jrpcfs.RenameRequest{
+		MountID:           globals.mountID,
+		SrcDirInodeNumber: int64(request.OldDir),
+		SrcBasename:       request.OldName,
+		DstDirInodeNumber: int64(request.NewDir),
+		DstBasename:       request.NewName,
+		Flags:             uint32(fuse.RenameExchange),
+	}
+
+	renameReply = &jrpcfs.Reply{}
+
+	err = doJRPCRequest("Server.RpcRename", renameRequest, renameReply)
+	if nil != err {
+		request.RespondError(fuseErrorOf(err))
+		return
+	}
+
+	request.Respond()
+}
+
+func handleFallocateRequest(request *fuse.FallocateRequest) {
+	logInfof("TODO: handleFallocateRequest() via Server.RpcFallocate")
 	logInfof("Header:\n%s", utils.JSONify(request.Header, true))
 	logInfof("Payload\n%s", utils.JSONify(request, true))
 	logInfof("Responding with fuse.ENOTSUP")
@@ -340,6 +431,15 @@ func handleLookupRequest(request *fuse.LookupRequest) {
 	request.RespondError(fuse.ENOTSUP)
 }
 
+func handleLseekRequest(request *fuse.LseekRequest) {
+	logInfof("TODO: handleLseekRequest() via Server.RpcSeek")
+	logInfof("Header:\n%s", utils.JSONify(request.Header, true))
+	logInfof("Payload\n%s", utils.JSONify(request, true))
+	// fuse.ENOSYS (rather than fuse.ENOTSUP) tells the kernel to treat the entire file as data
+	logInfof("Responding with fuse.ENOSYS")
+	request.RespondError(fuse.ENOSYS)
+}
+
 func handleMkdirRequest(request *fuse.MkdirRequest) {
 	logInfof("TODO: handleMkdirRequest()")
 	logInfof("Header:\n%s", utils.JSONify(request.Header, true))
@@ -405,11 +505,30 @@ func handleRemovexattrRequest(request *fuse.RemovexattrRequest) {
 }
 
 func handleRenameRequest(request *fuse.RenameRequest) {
-	logInfof("TODO: handleRenameRequest()")
-	logInfof("Header:\n%s", utils.JSONify(request.Header, true))
-	logInfof("Payload\n%s", utils.JSONify(request, true))
-	logInfof("Responding with fuse.ENOTSUP")
-	request.RespondError(fuse.ENOTSUP)
+	var (
+		err           error
+		renameReply   *jrpcfs.Reply
+		renameRequest *jrpcfs.RenameRequest
+	)
+
+	renameRequest = &jrpcfs.RenameRequest{
+		MountID:           globals.mountID,
+		SrcDirInodeNumber: int64(request.Header.Node),
+		SrcBasename:       request.OldName,
+		DstDirInodeNumber: int64(request.NewDir),
+		DstBasename:       request.NewName,
+		Flags:             uint32(request.Flags),
+	}
+
+	renameReply = &jrpcfs.Reply{}
+
+	err = doJRPCRequest("Server.RpcRename", renameRequest, renameReply)
+	if nil != err {
+		request.RespondError(fuseErrorOf(err))
+		return
+	}
+
+	request.Respond()
 }
 
 func handleSetattrRequest(request *fuse.SetattrRequest) {
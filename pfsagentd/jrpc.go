@@ -1,170 +1,103 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
+	"net/http"
+
+	"github.com/swiftstack/ProxyFS/jrpcfsclient"
 )
 
-type jrpcRequestMethodAndIDStruct struct {
-	Method string `json:"method"`
-	ID     uint64 `json:"id"`
-}
+// doJRPCRequest invokes jrpcMethod (e.g. "Server.RpcRename") via a PROXYFS request to the Swift
+// Proxy decoding the reply into jrpcResult. Errors returned by ProxyFS are of the form "errno: <n>".
+func doJRPCRequest(jrpcMethod string, jrpcParam interface{}, jrpcResult interface{}) (err error) {
+	var (
+		httpRequest     *http.Request
+		ok              bool
+		requestBuf      []byte
+		requestID       uint64
+		responseBuf     []byte
+		responseErr     error
+		responseID      uint64
+		swiftAccountURL string
+	)
 
-type jrpcRequestStruct struct {
-	JSONrpc string         `json:"jsonrpc"`
-	Method  string         `json:"method"`
-	ID      uint64         `json:"id"`
-	Params  [1]interface{} `json:"params"`
-}
+	requestID, requestBuf, err = jrpcMarshalRequest(jrpcMethod, jrpcParam)
+	if nil != err {
+		err = fmt.Errorf("unable to marshal %s request: %v", jrpcMethod, err)
+		return
+	}
 
-type jrpcResponseIDStruct struct {
-	ID uint64 `json:"id"`
-}
+	_, swiftAccountURL = fetchAuthTokenAndAccountURL()
 
-type jrpcResponseIDAndErrorStruct struct {
-	ID    uint64 `json:"id"`
-	Error string `json:"error"`
-}
+	httpRequest, err = http.NewRequest("PROXYFS", swiftAccountURL, bytes.NewReader(requestBuf))
+	if nil != err {
+		err = fmt.Errorf("unable to create PROXYFS %s request: %v", jrpcMethod, err)
+		return
+	}
 
-type jrpcResponseNoErrorStruct struct {
-	ID     uint64      `json:"id"`
-	Result interface{} `json:"result"`
-}
+	httpRequest.Header.Add("Content-Type", "application/json")
+
+	_, responseBuf, ok = doHTTPRequest(httpRequest, http.StatusOK)
+	if !ok {
+		err = fmt.Errorf("PROXYFS %s request failed", jrpcMethod)
+		return
+	}
+
+	responseID, responseErr, err = jrpcUnmarshalResponseForIDAndError(responseBuf)
+	if nil != err {
+		err = fmt.Errorf("unable to unmarshal %s response: %v", jrpcMethod, err)
+		return
+	}
+	if requestID != responseID {
+		err = fmt.Errorf("%s response ID (%v) did not match request ID (%v)", jrpcMethod, responseID, requestID)
+		return
+	}
+	if nil != responseErr {
+		err = responseErr
+		return
+	}
+
+	err = jrpcUnmarshalResponse(requestID, responseBuf, jrpcResult)
+	if nil != err {
+		err = fmt.Errorf("unable to unmarshal %s response: %v", jrpcMethod, err)
+	}
 
-type jrpcResponseWithErrorStruct struct {
-	ID     uint64      `json:"id"`
-	Error  string      `json:"error"`
-	Result interface{} `json:"result"`
+	return
 }
 
 func jrpcMarshalRequest(requestMethod string, request interface{}) (requestID uint64, requestBuf []byte, marshalErr error) {
-	var (
-		jrpcRequest *jrpcRequestStruct
-	)
-
 	globals.Lock()
 	requestID = globals.jrpcLastID + 1
 	globals.jrpcLastID = requestID
 	globals.Unlock()
 
-	jrpcRequest = &jrpcRequestStruct{
-		JSONrpc: "2.0",
-		Method:  requestMethod,
-		ID:      requestID,
-		Params:  [1]interface{}{request},
-	}
-
-	requestBuf, marshalErr = json.Marshal(jrpcRequest)
+	requestBuf, marshalErr = jrpcfsclient.MarshalRequest(requestID, requestMethod, request)
 
 	return
 }
 
 func jrpcMarshalResponse(requestID uint64, responseError error, response interface{}) (responseBuf []byte, marshalErr error) {
-	var (
-		jrpcResponse interface{}
-	)
-
-	if nil == responseError {
-		if nil == response {
-			jrpcResponse = &jrpcResponseIDStruct{
-				ID: requestID,
-			}
-		} else {
-			jrpcResponse = &jrpcResponseNoErrorStruct{
-				ID:     requestID,
-				Result: response,
-			}
-		}
-	} else {
-		if nil == response {
-			jrpcResponse = &jrpcResponseIDAndErrorStruct{
-				ID:    requestID,
-				Error: responseError.Error(),
-			}
-		} else {
-			jrpcResponse = &jrpcResponseWithErrorStruct{
-				ID:     requestID,
-				Error:  responseError.Error(),
-				Result: response,
-			}
-		}
-	}
-
-	responseBuf, marshalErr = json.Marshal(jrpcResponse)
-
+	responseBuf, marshalErr = jrpcfsclient.MarshalResponse(requestID, responseError, response)
 	return
 }
 
 func jrpcUnmarshalRequestForMethodAndID(requestBuf []byte) (requestMethod string, requestID uint64, unmarshalErr error) {
-	var (
-		jrpcRequest *jrpcRequestMethodAndIDStruct
-	)
-
-	jrpcRequest = &jrpcRequestMethodAndIDStruct{}
-
-	unmarshalErr = json.Unmarshal(requestBuf, jrpcRequest)
-
-	if nil == unmarshalErr {
-		requestMethod = jrpcRequest.Method
-		requestID = jrpcRequest.ID
-	}
-
+	requestMethod, requestID, unmarshalErr = jrpcfsclient.UnmarshalRequestForMethodAndID(requestBuf)
 	return
 }
 
 func jrpcUnmarshalRequest(requestID uint64, requestBuf []byte, request interface{}) (unmarshalErr error) {
-	var (
-		jrpcRequest *jrpcRequestStruct
-	)
-
-	jrpcRequest = &jrpcRequestStruct{
-		Params: [1]interface{}{request},
-	}
-
-	unmarshalErr = json.Unmarshal(requestBuf, jrpcRequest)
-
-	if (nil == unmarshalErr) && (requestID != jrpcRequest.ID) {
-		unmarshalErr = fmt.Errorf("requestID mismatch")
-	}
-
+	unmarshalErr = jrpcfsclient.UnmarshalRequest(requestID, requestBuf, request)
 	return
 }
 
 func jrpcUnmarshalResponseForIDAndError(responseBuf []byte) (requestID uint64, responseErr error, unmarshalErr error) {
-	var (
-		jrpcResponse *jrpcResponseIDAndErrorStruct
-	)
-
-	jrpcResponse = &jrpcResponseIDAndErrorStruct{}
-
-	unmarshalErr = json.Unmarshal(responseBuf, jrpcResponse)
-
-	if nil == unmarshalErr {
-		requestID = jrpcResponse.ID
-		if "" == jrpcResponse.Error {
-			responseErr = nil
-		} else {
-			responseErr = fmt.Errorf("%s", jrpcResponse.Error)
-		}
-	}
-
+	requestID, responseErr, unmarshalErr = jrpcfsclient.UnmarshalResponseForIDAndError(responseBuf)
 	return
 }
 
 func jrpcUnmarshalResponse(requestID uint64, responseBuf []byte, response interface{}) (unmarshalErr error) {
-	var (
-		jrpcResponse *jrpcResponseWithErrorStruct
-	)
-
-	jrpcResponse = &jrpcResponseWithErrorStruct{
-		Result: response,
-	}
-
-	unmarshalErr = json.Unmarshal(responseBuf, jrpcResponse)
-
-	if (nil == unmarshalErr) && (requestID != jrpcResponse.ID) {
-		unmarshalErr = fmt.Errorf("requestID mismatch")
-	}
-
+	unmarshalErr = jrpcfsclient.UnmarshalResponse(requestID, responseBuf, response)
 	return
 }
@@ -13,6 +13,7 @@ import (
 	"bazil.org/fuse"
 
 	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/jrpcfs"
 	"github.com/swiftstack/ProxyFS/utils"
 )
 
@@ -50,6 +51,7 @@ type globalsStruct struct {
 	swiftAccountURL    string // swiftStorageURL with AccountName forced to config.SwiftAccountName
 	fuseConn           *fuse.Conn
 	jrpcLastID         uint64
+	mountID            jrpcfs.MountIDAsString // Of config.SwiftAccountName's volume (whose InodeNumbers are the FUSE NodeIDs)
 }
 
 var globals globalsStruct
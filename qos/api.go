@@ -0,0 +1,69 @@
+// Package qos rate limits the I/O served on behalf of each mount, volume, and user.
+//
+// Limits are token buckets (refilled at the configured rate and holding at most one second's worth)
+// on the number of operations (IOPS) and bytes (Bandwidth) per second... for reads and writes
+// separately. Each volume may limit:
+//
+//   - the I/O of the volume as a whole      (QoSReadIOPS, QoSWriteIOPS, QoSReadBandwidth, QoSWriteBandwidth)
+//   - the I/O via each mount of the volume  (QoSMountReadIOPS, QoSMountWriteIOPS, ...)
+//   - the I/O on behalf of each user        (QoSUserReadIOPS, QoSUserWriteIOPS, ...)
+//
+// Each volume group may additionally limit (using the same QoSReadIOPS, etc. option names) the
+// aggregate I/O of its volumes. That aggregate is shared amongst those volumes in proportion to each
+// volume's QoSWeight... though only amongst those recently active (such that the share of an idle
+// volume is not wasted). All limits default to zero meaning unlimited.
+//
+// Metadata operations (e.g. lookups, creates, and renames) are charged as zero-length reads or writes
+// such that they consume IOPS but not Bandwidth.
+//
+// Rather than failing an operation exceeding a limit, Wait() delays it. An operation larger than a
+// bucket holds is admitted by borrowing against future refills (delaying subsequent operations).
+//
+// Limits are (re)read as volumes are served and when ProxyFS is signaled (e.g. via SIGHUP).
+package qos
+
+import (
+	"math"
+)
+
+// Direction distinguishes reads from writes (each limited separately).
+type Direction int
+
+const (
+	Read Direction = iota
+	Write
+)
+
+// UnknownUserID is passed to Wait() by callers not knowing on whose behalf an operation is performed
+// (e.g. the fast-port of jrpcfs). Per-user limits don't apply to such operations.
+const UnknownUserID = uint32(math.MaxUint32)
+
+// Mount identifies the mount via which an operation is performed (e.g. an fs.MountHandle).
+// It must be usable as a map key.
+type Mount interface {
+	VolumeName() (volumeName string)
+}
+
+// VolumeStats reports the I/O (and throttling thereof) of a volume.
+type VolumeStats struct {
+	ReadOps           uint64
+	ReadBytes         uint64
+	WriteOps          uint64
+	WriteBytes        uint64
+	ThrottledReadOps  uint64 // Operations delayed by one or more limits
+	ThrottledWriteOps uint64
+	ThrottledUsec     uint64 // Total delay imposed
+}
+
+// Wait blocks until the limits applicable to an operation of length bytes in direction dir via mount
+// on behalf of userID permit it to proceed. Operations upon volumes not served locally (or having no
+// limits) proceed immediately.
+func Wait(mount Mount, userID uint32, dir Direction, length uint64) {
+	wait(mount, userID, dir, length)
+}
+
+// Dump returns the current VolumeStats of every served volume (keyed by volume name).
+func Dump() (volumeStatsMap map[string]VolumeStats) {
+	volumeStatsMap = dump()
+	return
+}
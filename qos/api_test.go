@@ -0,0 +1,197 @@
+package qos
+
+import (
+	"testing"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/transitions"
+)
+
+type testMountStruct struct {
+	volumeName string
+}
+
+func (testMount *testMountStruct) VolumeName() (volumeName string) {
+	volumeName = testMount.volumeName
+	return
+}
+
+func TestAPI(t *testing.T) {
+	testConfMapStrings := []string{
+		"Logging.LogFilePath=/dev/null",
+		"Cluster.Peers=Peer0",
+		"Cluster.WhoAmI=Peer0",
+		"FSGlobals.VolumeGroupList=TestVolumeGroup",
+		"FSGlobals.TryLockBackoffMin=100us",
+		"FSGlobals.TryLockBackoffMax=300us",
+		"FSGlobals.SymlinkMax=32",
+		"VolumeGroup:TestVolumeGroup.VolumeList=VolumeA,VolumeB",
+		"VolumeGroup:TestVolumeGroup.VirtualIPAddr=",
+		"VolumeGroup:TestVolumeGroup.PrimaryPeer=Peer0",
+		"VolumeGroup:TestVolumeGroup.QoSWriteIOPS=100",
+		"Volume:VolumeA.QoSWeight=3",
+		"Volume:VolumeA.QoSMountReadIOPS=10",
+		"Volume:VolumeA.QoSUserReadBandwidth=1000",
+		"Volume:VolumeB.QoSReadIOPS=20",
+	}
+
+	testConfMap, err := conf.MakeConfMapFromStrings(testConfMapStrings)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	err = transitions.Up(testConfMap)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	// Rebalancing is driven explicitly below
+
+	stopRebalancer()
+
+	mountA1 := &testMountStruct{volumeName: "VolumeA"}
+	mountA2 := &testMountStruct{volumeName: "VolumeA"}
+	mountB := &testMountStruct{volumeName: "VolumeB"}
+
+	// Operations upon unknown volumes are never delayed
+
+	if 0 != reserve(&testMountStruct{volumeName: "VolumeX"}, UnknownUserID, Read, 1) {
+		t.Fatalf("reserve() upon unknown volume should not have been delayed")
+	}
+
+	// A mount may burst up to one second's worth... after which it (but not other mounts) is delayed
+
+	for i := 0; i < 10; i++ {
+		if 0 != reserve(mountA1, UnknownUserID, Read, 0) {
+			t.Fatalf("reserve() %v within QoSMountReadIOPS should not have been delayed", i)
+		}
+	}
+	delay := reserve(mountA1, UnknownUserID, Read, 0)
+	if (0 == delay) || (100*time.Millisecond < delay) {
+		t.Fatalf("reserve() beyond QoSMountReadIOPS should have been delayed up to 100ms... got %v", delay)
+	}
+	if 0 != reserve(mountA2, UnknownUserID, Read, 0) {
+		t.Fatalf("reserve() via another mount should not have been delayed")
+	}
+
+	// A user is limited across mounts... but only if known
+
+	if 0 != reserve(mountA2, 1000, Read, 1000) {
+		t.Fatalf("reserve() within QoSUserReadBandwidth should not have been delayed")
+	}
+	delay = reserve(mountA2, 1000, Read, 500)
+	if (400*time.Millisecond > delay) || (500*time.Millisecond < delay) {
+		t.Fatalf("reserve() beyond QoSUserReadBandwidth should have been delayed ~500ms... got %v", delay)
+	}
+	if 0 != reserve(mountA2, 1001, Read, 1000) {
+		t.Fatalf("reserve() on behalf of another user should not have been delayed")
+	}
+	if 0 != reserve(mountA2, UnknownUserID, Read, 1000) {
+		t.Fatalf("reserve() on behalf of an unknown user should not have been delayed")
+	}
+	if 0 != reserve(mountA2, 1000, Write, 1000) {
+		t.Fatalf("reserve() of writes should not have been delayed by QoSUserReadBandwidth")
+	}
+
+	// A volume is limited across mounts
+
+	for i := 0; i < 20; i++ {
+		if 0 != reserve(mountB, UnknownUserID, Read, 0) {
+			t.Fatalf("reserve() %v within QoSReadIOPS should not have been delayed", i)
+		}
+	}
+	if 0 == reserve(&testMountStruct{volumeName: "VolumeB"}, UnknownUserID, Read, 0) {
+		t.Fatalf("reserve() beyond QoSReadIOPS should have been delayed")
+	}
+
+	// The volume group's limits are shared by weight amongst its active volumes
+
+	shareRate := func(volumeName string) float64 {
+		return globals.volumeMap[volumeName].shareBuckets.ops[Write].rate
+	}
+
+	rebalance()
+
+	if (75 != shareRate("VolumeA")) || (25 != shareRate("VolumeB")) {
+		t.Fatalf("Active volumes should share QoSWriteIOPS 3:1... got %v & %v", shareRate("VolumeA"), shareRate("VolumeB"))
+	}
+
+	_ = reserve(mountA1, UnknownUserID, Write, 0)
+
+	rebalance()
+
+	if (100 != shareRate("VolumeA")) || (25 != shareRate("VolumeB")) {
+		t.Fatalf("Sole active volume should receive all of QoSWriteIOPS... got %v & %v", shareRate("VolumeA"), shareRate("VolumeB"))
+	}
+
+	// Throttling is reported
+
+	volumeStatsMap := Dump()
+
+	if (16 != volumeStatsMap["VolumeA"].ReadOps) || (3500 != volumeStatsMap["VolumeA"].ReadBytes) || (2 != volumeStatsMap["VolumeA"].WriteOps) {
+		t.Fatalf("Dump() reported unexpected VolumeA stats: %+v", volumeStatsMap["VolumeA"])
+	}
+	if (2 != volumeStatsMap["VolumeA"].ThrottledReadOps) || (0 != volumeStatsMap["VolumeA"].ThrottledWriteOps) || (0 == volumeStatsMap["VolumeA"].ThrottledUsec) {
+		t.Fatalf("Dump() reported unexpected VolumeA throttling: %+v", volumeStatsMap["VolumeA"])
+	}
+	if (21 != volumeStatsMap["VolumeB"].ReadOps) || (1 != volumeStatsMap["VolumeB"].ThrottledReadOps) {
+		t.Fatalf("Dump() reported unexpected VolumeB stats: %+v", volumeStatsMap["VolumeB"])
+	}
+
+	// Idle mount and user buckets are discarded
+
+	globals.Lock()
+	volumeA := globals.volumeMap["VolumeA"]
+	globals.Unlock()
+
+	volumeA.Lock()
+	if (2 != len(volumeA.mountBucketsMap)) || (2 != len(volumeA.userBucketsMap)) {
+		t.Fatalf("Expected 2 mount and 2 user buckets... got %v & %v", len(volumeA.mountBucketsMap), len(volumeA.userBucketsMap))
+	}
+	volumeA.reapWhileLocked(time.Now().Add(2 * time.Second))
+	if (0 != len(volumeA.mountBucketsMap)) || (0 != len(volumeA.userBucketsMap)) {
+		t.Fatalf("Full mount and user buckets should have been discarded")
+	}
+	volumeA.Unlock()
+
+	// Limits may be changed (or removed) when signaled
+
+	err = testConfMap.UpdateFromStrings([]string{"Volume:VolumeB.QoSReadIOPS=0", "VolumeGroup:TestVolumeGroup.QoSWriteIOPS=0"})
+	if nil != err {
+		t.Fatal(err)
+	}
+	err = transitions.Signaled(testConfMap)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if 0 != reserve(mountB, UnknownUserID, Read, 0) {
+		t.Fatalf("reserve() should not have been delayed once QoSReadIOPS was removed")
+	}
+	if (0 != shareRate("VolumeA")) || (0 != shareRate("VolumeB")) {
+		t.Fatalf("Volume group QoSWriteIOPS should have been removed")
+	}
+
+	err = testConfMap.UpdateFromStrings([]string{"Volume:VolumeA.QoSWeight=0"})
+	if nil != err {
+		t.Fatal(err)
+	}
+	err = transitions.Signaled(testConfMap)
+	if nil == err {
+		t.Fatalf("transitions.Signaled() with QoSWeight=0 should have failed")
+	}
+	err = testConfMap.UpdateFromStrings([]string{"Volume:VolumeA.QoSWeight=3"})
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	err = transitions.Down(testConfMap)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	if 0 != len(Dump()) {
+		t.Fatalf("Dump() should be empty once volumes are no longer served")
+	}
+}
@@ -0,0 +1,213 @@
+package qos
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/trackedlock"
+	"github.com/swiftstack/ProxyFS/transitions"
+)
+
+const (
+	rebalanceInterval = time.Second // How often volume group limits are re-divided (and idle buckets discarded)
+)
+
+type limitsStruct struct {
+	iops      [2]float64 // Indexed by Direction (0 == unlimited)
+	bandwidth [2]float64 // Indexed by Direction (0 == unlimited)
+}
+
+type volumeSettingsStruct struct {
+	weight      uint64
+	limits      limitsStruct // Of the volume as a whole
+	mountLimits limitsStruct // Of each mount
+	userLimits  limitsStruct // Of each user
+}
+
+type globalsStruct struct {
+	trackedlock.Mutex
+	volumeGroupNameMap map[string]string             // Key == volumeName (of every volume); Value == volumeGroupName
+	volumeGroupMap     map[string]*volumeGroupStruct // Key == volumeGroupName (of volume groups with served volumes)
+	volumeMap          map[string]*volumeStruct      // Key == volumeName (of served volumes)
+	rebalanceStopChan  chan struct{}
+	rebalanceDoneChan  chan struct{}
+}
+
+var globals globalsStruct
+
+func init() {
+	transitions.Register("qos", &globals)
+}
+
+// fetchLimits fetches the optional <prefix>{Read|Write}{IOPS|Bandwidth} options of sectionName.
+func fetchLimits(confMap conf.ConfMap, sectionName string, prefix string) (limits limitsStruct) {
+	var (
+		dir           int
+		dirName       string
+		err           error
+		valueAsUint64 uint64
+	)
+
+	for dir, dirName = range []string{"Read", "Write"} {
+		valueAsUint64, err = confMap.FetchOptionValueUint64(sectionName, prefix+dirName+"IOPS")
+		if nil != err {
+			valueAsUint64 = 0 // TODO: Eventually, just return
+		}
+		limits.iops[dir] = float64(valueAsUint64)
+
+		valueAsUint64, err = confMap.FetchOptionValueUint64(sectionName, prefix+dirName+"Bandwidth")
+		if nil != err {
+			valueAsUint64 = 0 // TODO: Eventually, just return
+		}
+		limits.bandwidth[dir] = float64(valueAsUint64)
+	}
+
+	return
+}
+
+func fetchVolumeSettings(confMap conf.ConfMap, volumeName string) (settings volumeSettingsStruct, err error) {
+	var (
+		volumeSectionName string
+	)
+
+	volumeSectionName = "Volume:" + volumeName
+
+	settings.weight, err = confMap.FetchOptionValueUint64(volumeSectionName, "QoSWeight")
+	if nil != err {
+		settings.weight = 1 // TODO: Eventually, just return
+	}
+	if 0 == settings.weight {
+		err = fmt.Errorf("%s.QoSWeight must be non-zero", volumeSectionName)
+		return
+	}
+
+	settings.limits = fetchLimits(confMap, volumeSectionName, "QoS")
+	settings.mountLimits = fetchLimits(confMap, volumeSectionName, "QoSMount")
+	settings.userLimits = fetchLimits(confMap, volumeSectionName, "QoSUser")
+
+	err = nil
+	return
+}
+
+func fetchVolumeGroupLimits(confMap conf.ConfMap, volumeGroupName string) (limits limitsStruct) {
+	limits = fetchLimits(confMap, "VolumeGroup:"+volumeGroupName, "QoS")
+	return
+}
+
+func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
+	globals.volumeGroupNameMap = make(map[string]string)
+	globals.volumeGroupMap = make(map[string]*volumeGroupStruct)
+	globals.volumeMap = make(map[string]*volumeStruct)
+
+	startRebalancer()
+
+	err = nil
+	return
+}
+
+func (dummy *globalsStruct) VolumeGroupCreated(confMap conf.ConfMap, volumeGroupName string, activePeer string, virtualIPAddr string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) VolumeGroupMoved(confMap conf.ConfMap, volumeGroupName string, activePeer string, virtualIPAddr string) (err error) {
+	return nil
+}
+func (dummy *globalsStruct) VolumeGroupDestroyed(confMap conf.ConfMap, volumeGroupName string) (err error) {
+	return nil
+}
+
+func (dummy *globalsStruct) VolumeCreated(confMap conf.ConfMap, volumeName string, volumeGroupName string) (err error) {
+	globals.Lock()
+	globals.volumeGroupNameMap[volumeName] = volumeGroupName
+	globals.Unlock()
+
+	err = nil
+	return
+}
+
+func (dummy *globalsStruct) VolumeMoved(confMap conf.ConfMap, volumeName string, volumeGroupName string) (err error) {
+	globals.Lock()
+	globals.volumeGroupNameMap[volumeName] = volumeGroupName
+	globals.Unlock()
+
+	err = nil
+	return
+}
+
+func (dummy *globalsStruct) VolumeDestroyed(confMap conf.ConfMap, volumeName string) (err error) {
+	globals.Lock()
+	delete(globals.volumeGroupNameMap, volumeName)
+	globals.Unlock()
+
+	err = nil
+	return
+}
+
+func (dummy *globalsStruct) ServeVolume(confMap conf.ConfMap, volumeName string) (err error) {
+	settings, err := fetchVolumeSettings(confMap, volumeName)
+	if nil != err {
+		return
+	}
+
+	globals.Lock()
+	volumeGroup := serveVolumeWhileLocked(volumeName, settings)
+	if nil != volumeGroup {
+		volumeGroup.limits = fetchVolumeGroupLimits(confMap, volumeGroup.name)
+		volumeGroup.rebalanceWhileLocked(false)
+	}
+	globals.Unlock()
+
+	return
+}
+
+func (dummy *globalsStruct) UnserveVolume(confMap conf.ConfMap, volumeName string) (err error) {
+	globals.Lock()
+	unserveVolumeWhileLocked(volumeName)
+	globals.Unlock()
+
+	err = nil
+	return
+}
+
+func (dummy *globalsStruct) SignaledStart(confMap conf.ConfMap) (err error) {
+	return nil
+}
+
+func (dummy *globalsStruct) SignaledFinish(confMap conf.ConfMap) (err error) {
+	var (
+		settings   volumeSettingsStruct
+		volume     *volumeStruct
+		volumeName string
+	)
+
+	globals.Lock()
+	defer globals.Unlock()
+
+	for volumeName, volume = range globals.volumeMap {
+		settings, err = fetchVolumeSettings(confMap, volumeName)
+		if nil != err {
+			return
+		}
+		volume.setSettings(settings)
+	}
+
+	for _, volumeGroup := range globals.volumeGroupMap {
+		volumeGroup.limits = fetchVolumeGroupLimits(confMap, volumeGroup.name)
+		volumeGroup.rebalanceWhileLocked(false)
+	}
+
+	err = nil
+	return
+}
+
+func (dummy *globalsStruct) Down(confMap conf.ConfMap) (err error) {
+	stopRebalancer()
+
+	if 0 != len(globals.volumeMap) {
+		err = fmt.Errorf("qos.Down() called with 0 != len(globals.volumeMap)")
+		return
+	}
+
+	err = nil
+	return
+}
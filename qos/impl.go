@@ -0,0 +1,428 @@
+package qos
+
+import (
+	"time"
+
+	"github.com/swiftstack/ProxyFS/trackedlock"
+)
+
+// bucketStruct is a token bucket refilled at rate tokens per second and holding at most rate tokens.
+// A reservation exceeding the tokens available drives tokens negative (i.e. borrows against future
+// refills)... the delay returned being the time until the debt is repaid.
+type bucketStruct struct {
+	rate       float64 // 0 == unlimited
+	tokens     float64
+	lastRefill time.Time
+}
+
+type bucketSetStruct struct {
+	ops   [2]bucketStruct // Indexed by Direction
+	bytes [2]bucketStruct // Indexed by Direction
+}
+
+type volumeStruct struct {
+	trackedlock.Mutex
+	name            string
+	volumeGroup     *volumeGroupStruct
+	settings        volumeSettingsStruct
+	active          bool            // Performed I/O since the last rebalance
+	volumeBuckets   bucketSetStruct // Limits the volume as a whole
+	shareBuckets    bucketSetStruct // Limits the volume to its share of its volume group's limits
+	mountBucketsMap map[Mount]*bucketSetStruct
+	userBucketsMap  map[uint32]*bucketSetStruct
+	stats           VolumeStats
+}
+
+type volumeGroupStruct struct {
+	name      string
+	limits    limitsStruct
+	volumeMap map[string]*volumeStruct // Key == volumeName (of served volumes)
+}
+
+func (bucket *bucketStruct) refill(now time.Time) {
+	bucket.tokens += now.Sub(bucket.lastRefill).Seconds() * bucket.rate
+	if bucket.tokens > bucket.rate {
+		bucket.tokens = bucket.rate
+	}
+	bucket.lastRefill = now
+}
+
+// setRate changes the rate of bucket (retaining any debt)... a newly limited bucket starts full.
+func (bucket *bucketStruct) setRate(now time.Time, rate float64) {
+	if 0 == bucket.rate {
+		bucket.tokens = rate
+	} else {
+		bucket.refill(now)
+		if bucket.tokens > rate {
+			bucket.tokens = rate
+		}
+	}
+	bucket.rate = rate
+	bucket.lastRefill = now
+}
+
+func (bucket *bucketStruct) reserve(now time.Time, tokens float64) (delay time.Duration) {
+	if 0 == bucket.rate {
+		delay = 0
+		return
+	}
+
+	bucket.refill(now)
+	bucket.tokens -= tokens
+
+	if 0 > bucket.tokens {
+		delay = time.Duration(-bucket.tokens / bucket.rate * float64(time.Second))
+	} else {
+		delay = 0
+	}
+
+	return
+}
+
+func (bucket *bucketStruct) isFull(now time.Time) (full bool) {
+	if 0 == bucket.rate {
+		full = true
+		return
+	}
+
+	bucket.refill(now)
+	full = (bucket.tokens >= bucket.rate)
+
+	return
+}
+
+func newBucketSet(now time.Time, limits limitsStruct) (bucketSet *bucketSetStruct) {
+	bucketSet = &bucketSetStruct{}
+	bucketSet.setLimits(now, limits)
+	return
+}
+
+func (bucketSet *bucketSetStruct) setLimits(now time.Time, limits limitsStruct) {
+	for dir := Read; dir <= Write; dir++ {
+		bucketSet.ops[dir].setRate(now, limits.iops[dir])
+		bucketSet.bytes[dir].setRate(now, limits.bandwidth[dir])
+	}
+}
+
+func (bucketSet *bucketSetStruct) reserve(now time.Time, dir Direction, length uint64) (delay time.Duration) {
+	delay = bucketSet.ops[dir].reserve(now, 1)
+
+	bytesDelay := bucketSet.bytes[dir].reserve(now, float64(length))
+	if bytesDelay > delay {
+		delay = bytesDelay
+	}
+
+	return
+}
+
+func (bucketSet *bucketSetStruct) isFull(now time.Time) (full bool) {
+	for dir := Read; dir <= Write; dir++ {
+		if !bucketSet.ops[dir].isFull(now) || !bucketSet.bytes[dir].isFull(now) {
+			full = false
+			return
+		}
+	}
+
+	full = true
+	return
+}
+
+func (limits *limitsStruct) isUnlimited() (unlimited bool) {
+	unlimited = (limitsStruct{} == *limits)
+	return
+}
+
+// scaled returns limits reduced to the fraction numerator/denominator.
+func (limits *limitsStruct) scaled(numerator uint64, denominator uint64) (scaledLimits limitsStruct) {
+	fraction := float64(numerator) / float64(denominator)
+
+	for dir := Read; dir <= Write; dir++ {
+		scaledLimits.iops[dir] = limits.iops[dir] * fraction
+		scaledLimits.bandwidth[dir] = limits.bandwidth[dir] * fraction
+	}
+
+	return
+}
+
+// serveVolumeWhileLocked adds volumeName... returning the volume group (if any) it joined.
+func serveVolumeWhileLocked(volumeName string, settings volumeSettingsStruct) (volumeGroup *volumeGroupStruct) {
+	var (
+		now    = time.Now()
+		ok     bool
+		volume *volumeStruct
+	)
+
+	volume = &volumeStruct{
+		name:            volumeName,
+		mountBucketsMap: make(map[Mount]*bucketSetStruct),
+		userBucketsMap:  make(map[uint32]*bucketSetStruct),
+	}
+
+	volume.settings = settings
+	volume.volumeBuckets.setLimits(now, settings.limits)
+
+	globals.volumeMap[volumeName] = volume
+
+	volumeGroupName, ok := globals.volumeGroupNameMap[volumeName]
+	if !ok {
+		// Not part of any (known) volume group... so only the volume's own limits apply
+		volumeGroup = nil
+		return
+	}
+
+	volumeGroup, ok = globals.volumeGroupMap[volumeGroupName]
+	if !ok {
+		volumeGroup = &volumeGroupStruct{
+			name:      volumeGroupName,
+			volumeMap: make(map[string]*volumeStruct),
+		}
+		globals.volumeGroupMap[volumeGroupName] = volumeGroup
+	}
+
+	volume.volumeGroup = volumeGroup
+	volumeGroup.volumeMap[volumeName] = volume
+
+	return
+}
+
+func unserveVolumeWhileLocked(volumeName string) {
+	volume, ok := globals.volumeMap[volumeName]
+	if !ok {
+		return
+	}
+
+	delete(globals.volumeMap, volumeName)
+
+	volumeGroup := volume.volumeGroup
+	if nil == volumeGroup {
+		return
+	}
+
+	delete(volumeGroup.volumeMap, volumeName)
+
+	if 0 == len(volumeGroup.volumeMap) {
+		delete(globals.volumeGroupMap, volumeGroup.name)
+	} else {
+		volumeGroup.rebalanceWhileLocked(false)
+	}
+}
+
+// setSettings applies newly fetched settings to volume (and any mount or user buckets it has).
+func (volume *volumeStruct) setSettings(settings volumeSettingsStruct) {
+	now := time.Now()
+
+	volume.Lock()
+
+	volume.settings = settings
+	volume.volumeBuckets.setLimits(now, settings.limits)
+
+	for _, bucketSet := range volume.mountBucketsMap {
+		bucketSet.setLimits(now, settings.mountLimits)
+	}
+	for _, bucketSet := range volume.userBucketsMap {
+		bucketSet.setLimits(now, settings.userLimits)
+	}
+
+	volume.Unlock()
+}
+
+// rebalanceWhileLocked divides the limits of volumeGroup amongst its active volumes in proportion to
+// their weights. An idle volume is granted the share it would receive were it to become active. If
+// resetActive is true, the volumes are marked idle until they next perform I/O.
+func (volumeGroup *volumeGroupStruct) rebalanceWhileLocked(resetActive bool) {
+	var (
+		activeWeightSum uint64
+		denominator     uint64
+		now             = time.Now()
+		volume          *volumeStruct
+	)
+
+	for _, volume = range volumeGroup.volumeMap {
+		volume.Lock()
+		if volume.active {
+			activeWeightSum += volume.settings.weight
+		}
+	}
+
+	for _, volume = range volumeGroup.volumeMap {
+		if volumeGroup.limits.isUnlimited() {
+			volume.shareBuckets.setLimits(now, volumeGroup.limits)
+		} else {
+			denominator = activeWeightSum
+			if !volume.active {
+				denominator += volume.settings.weight
+			}
+			volume.shareBuckets.setLimits(now, volumeGroup.limits.scaled(volume.settings.weight, denominator))
+		}
+		if resetActive {
+			volume.active = false
+		}
+		volume.Unlock()
+	}
+}
+
+// reapWhileLocked discards the mount and user buckets of volume that are full (and hence, being
+// recreated full on next use, can be discarded without effect).
+func (volume *volumeStruct) reapWhileLocked(now time.Time) {
+	for mount, bucketSet := range volume.mountBucketsMap {
+		if bucketSet.isFull(now) {
+			delete(volume.mountBucketsMap, mount)
+		}
+	}
+	for userID, bucketSet := range volume.userBucketsMap {
+		if bucketSet.isFull(now) {
+			delete(volume.userBucketsMap, userID)
+		}
+	}
+}
+
+func startRebalancer() {
+	globals.rebalanceStopChan = make(chan struct{})
+	globals.rebalanceDoneChan = make(chan struct{})
+
+	go rebalancer(globals.rebalanceStopChan, globals.rebalanceDoneChan)
+}
+
+func stopRebalancer() {
+	if nil == globals.rebalanceStopChan {
+		return
+	}
+
+	close(globals.rebalanceStopChan)
+	<-globals.rebalanceDoneChan
+
+	globals.rebalanceStopChan = nil
+	globals.rebalanceDoneChan = nil
+}
+
+func rebalancer(stopChan chan struct{}, doneChan chan struct{}) {
+	ticker := time.NewTicker(rebalanceInterval)
+
+	for {
+		select {
+		case <-ticker.C:
+			rebalance()
+		case <-stopChan:
+			ticker.Stop()
+			close(doneChan)
+			return
+		}
+	}
+}
+
+func rebalance() {
+	now := time.Now()
+
+	globals.Lock()
+
+	for _, volume := range globals.volumeMap {
+		volume.Lock()
+		volume.reapWhileLocked(now)
+		volume.Unlock()
+	}
+
+	for _, volumeGroup := range globals.volumeGroupMap {
+		volumeGroup.rebalanceWhileLocked(true)
+	}
+
+	globals.Unlock()
+}
+
+// reserve accounts for an operation against every limit applicable to it... returning the delay
+// before the most constraining of them permits it to proceed.
+func reserve(mount Mount, userID uint32, dir Direction, length uint64) (delay time.Duration) {
+	var (
+		bucketDelay time.Duration
+		bucketSet   *bucketSetStruct
+		now         time.Time
+		ok          bool
+	)
+
+	globals.Lock()
+	volume, ok := globals.volumeMap[mount.VolumeName()]
+	globals.Unlock()
+
+	if !ok {
+		delay = 0
+		return
+	}
+
+	now = time.Now()
+
+	volume.Lock()
+
+	volume.active = true
+
+	delay = volume.volumeBuckets.reserve(now, dir, length)
+
+	bucketDelay = volume.shareBuckets.reserve(now, dir, length)
+	if bucketDelay > delay {
+		delay = bucketDelay
+	}
+
+	if !volume.settings.mountLimits.isUnlimited() {
+		bucketSet, ok = volume.mountBucketsMap[mount]
+		if !ok {
+			bucketSet = newBucketSet(now, volume.settings.mountLimits)
+			volume.mountBucketsMap[mount] = bucketSet
+		}
+		bucketDelay = bucketSet.reserve(now, dir, length)
+		if bucketDelay > delay {
+			delay = bucketDelay
+		}
+	}
+
+	if (UnknownUserID != userID) && !volume.settings.userLimits.isUnlimited() {
+		bucketSet, ok = volume.userBucketsMap[userID]
+		if !ok {
+			bucketSet = newBucketSet(now, volume.settings.userLimits)
+			volume.userBucketsMap[userID] = bucketSet
+		}
+		bucketDelay = bucketSet.reserve(now, dir, length)
+		if bucketDelay > delay {
+			delay = bucketDelay
+		}
+	}
+
+	if Read == dir {
+		volume.stats.ReadOps++
+		volume.stats.ReadBytes += length
+		if 0 < delay {
+			volume.stats.ThrottledReadOps++
+		}
+	} else {
+		volume.stats.WriteOps++
+		volume.stats.WriteBytes += length
+		if 0 < delay {
+			volume.stats.ThrottledWriteOps++
+		}
+	}
+	volume.stats.ThrottledUsec += uint64(delay / time.Microsecond)
+
+	volume.Unlock()
+
+	return
+}
+
+func wait(mount Mount, userID uint32, dir Direction, length uint64) {
+	delay := reserve(mount, userID, dir, length)
+	if 0 < delay {
+		time.Sleep(delay)
+	}
+}
+
+func dump() (volumeStatsMap map[string]VolumeStats) {
+	globals.Lock()
+
+	volumeStatsMap = make(map[string]VolumeStats, len(globals.volumeMap))
+
+	for volumeName, volume := range globals.volumeMap {
+		volume.Lock()
+		volumeStatsMap[volumeName] = volume.stats
+		volume.Unlock()
+	}
+
+	globals.Unlock()
+
+	return
+}
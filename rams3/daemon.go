@@ -0,0 +1,854 @@
+// Package rams3 provides an in-memory emulation of the subset of the S3 object storage API
+// used by the swiftclient S3 Backend, which can be run as a goroutine from another package,
+// or as a standalone binary.
+//
+// Only path-style requests (i.e. http://<IPAddr>:<TCPPort>/<bucket>/<key>) are supported.
+// Request signatures are not verified.
+package rams3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/swiftstack/ProxyFS/conf"
+)
+
+const (
+	defaultIPAddr                = "127.0.0.1"
+	defaultMinMultipartPartSize  = uint64(5 * 1024 * 1024)
+	defaultListObjectsMaxKeys    = 1000
+	metadataHeaderPrefix         = "X-Amz-Meta-"
+	s3XMLNamespace               = "http://s3.amazonaws.com/doc/2006-03-01/"
+	bucketAlreadyOwnedByYouError = "BucketAlreadyOwnedByYou"
+)
+
+type objectStruct struct {
+	contents []byte
+	etag     string
+	metadata http.Header // only X-Amz-Meta-* headers
+}
+
+type partStruct struct {
+	contents []byte
+	etag     string
+}
+
+type multipartUploadStruct struct {
+	key      string
+	metadata http.Header
+	partMap  map[int]*partStruct // key is partNumber
+}
+
+type bucketStruct struct {
+	objectMap          map[string]*objectStruct          // key is object key
+	multipartUploadMap map[string]*multipartUploadStruct // key is uploadId
+}
+
+type globalsStruct struct {
+	sync.Mutex             // protects everything below
+	addr                   string
+	minMultipartPartSize   uint64
+	bucketMap              map[string]*bucketStruct // key is bucket name
+	lastUploadID           uint64
+	httpServer             *http.Server
+	httpServerExitedWG     sync.WaitGroup
+	httpServerListener     net.Listener
+	methodCount            map[string]uint64 // key is http.Method*
+	chaosFailureRate       uint64            // if non-zero, every chaosFailureRate'th request fails
+	chaosFailureHTTPStatus int
+}
+
+var globals *globalsStruct
+
+type errorResponseStruct struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource"`
+}
+
+type listBucketResultContentsStruct struct {
+	Key          string `xml:"Key"`
+	Size         int    `xml:"Size"`
+	ETag         string `xml:"ETag"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type listBucketResultCommonPrefixesStruct struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type listBucketResultStruct struct {
+	XMLName               xml.Name                               `xml:"ListBucketResult"`
+	Xmlns                 string                                 `xml:"xmlns,attr"`
+	Name                  string                                 `xml:"Name"`
+	Prefix                string                                 `xml:"Prefix"`
+	Delimiter             string                                 `xml:"Delimiter,omitempty"`
+	MaxKeys               int                                    `xml:"MaxKeys"`
+	KeyCount              int                                    `xml:"KeyCount"`
+	IsTruncated           bool                                   `xml:"IsTruncated"`
+	ContinuationToken     string                                 `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string                                 `xml:"NextContinuationToken,omitempty"`
+	Contents              []listBucketResultContentsStruct       `xml:"Contents"`
+	CommonPrefixes        []listBucketResultCommonPrefixesStruct `xml:"CommonPrefixes"`
+}
+
+type initiateMultipartUploadResultStruct struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type completeMultipartUploadPartStruct struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUploadStruct struct {
+	XMLName xml.Name                            `xml:"CompleteMultipartUpload"`
+	Parts   []completeMultipartUploadPartStruct `xml:"Part"`
+}
+
+type completeMultipartUploadResultStruct struct {
+	XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Bucket  string   `xml:"Bucket"`
+	Key     string   `xml:"Key"`
+	ETag    string   `xml:"ETag"`
+}
+
+type httpRequestHandler struct{}
+
+func computeETag(contents []byte) (etag string) {
+	md5Sum := md5.Sum(contents)
+	etag = "\"" + hex.EncodeToString(md5Sum[:]) + "\""
+	return
+}
+
+// parsePath splits the request URL path into its bucket and key components.
+func parsePath(request *http.Request) (bucketName string, key string) {
+	var (
+		path      string
+		pathSplit []string
+	)
+
+	path = strings.TrimPrefix(request.URL.Path, "/")
+	pathSplit = strings.SplitN(path, "/", 2)
+
+	bucketName = pathSplit[0]
+	if 2 == len(pathSplit) {
+		key = pathSplit[1]
+	}
+
+	return
+}
+
+// parseRangeHeader parses a single-range HTTP Range header returning the range of
+// objectLen bytes selected. If no Range header was supplied, ok == true and the
+// entire object is selected. If the range is not satisfiable, ok == false.
+func parseRangeHeader(request *http.Request, objectLen int) (startOffset int, stopOffset int, isRange bool, ok bool) {
+	var (
+		err             error
+		rangeHeader     string
+		rangeSplit      []string
+		suffixLen       int
+		startOffsetU64  uint64
+		stopOffsetU64   uint64
+		stopOffsetGiven bool
+	)
+
+	rangeHeader = request.Header.Get("Range")
+	if "" == rangeHeader {
+		startOffset = 0
+		stopOffset = objectLen - 1
+		isRange = false
+		ok = true
+		return
+	}
+
+	isRange = true
+
+	if !strings.HasPrefix(rangeHeader, "bytes=") || strings.Contains(rangeHeader, ",") {
+		ok = false
+		return
+	}
+
+	rangeSplit = strings.SplitN(strings.TrimPrefix(rangeHeader, "bytes="), "-", 2)
+	if 2 != len(rangeSplit) {
+		ok = false
+		return
+	}
+
+	if "" == rangeSplit[0] {
+		suffixLen, err = strconv.Atoi(rangeSplit[1])
+		if (nil != err) || (0 == suffixLen) || (0 == objectLen) {
+			ok = false
+			return
+		}
+		if suffixLen > objectLen {
+			suffixLen = objectLen
+		}
+		startOffset = objectLen - suffixLen
+		stopOffset = objectLen - 1
+		ok = true
+		return
+	}
+
+	startOffsetU64, err = strconv.ParseUint(rangeSplit[0], 10, 64)
+	if nil != err {
+		ok = false
+		return
+	}
+
+	if "" != rangeSplit[1] {
+		stopOffsetU64, err = strconv.ParseUint(rangeSplit[1], 10, 64)
+		if (nil != err) || (stopOffsetU64 < startOffsetU64) {
+			ok = false
+			return
+		}
+		stopOffsetGiven = true
+	}
+
+	if startOffsetU64 >= uint64(objectLen) {
+		ok = false
+		return
+	}
+
+	startOffset = int(startOffsetU64)
+	if !stopOffsetGiven || (stopOffsetU64 >= uint64(objectLen)) {
+		stopOffset = objectLen - 1
+	} else {
+		stopOffset = int(stopOffsetU64)
+	}
+
+	ok = true
+	return
+}
+
+func extractMetadata(header http.Header) (metadata http.Header) {
+	metadata = make(http.Header)
+	for headerName, headerValueSlice := range header {
+		if strings.HasPrefix(headerName, metadataHeaderPrefix) {
+			metadata[headerName] = headerValueSlice
+		}
+	}
+	return
+}
+
+func writeXMLResponse(responseWriter http.ResponseWriter, httpStatus int, response interface{}) {
+	buf, err := xml.Marshal(response)
+	if nil != err {
+		err = fmt.Errorf("xml.Marshal(%#v) failed: %v", response, err)
+		panic(err)
+	}
+
+	responseWriter.Header().Set("Content-Type", "application/xml")
+	responseWriter.Header().Set("Content-Length", strconv.Itoa(len(xml.Header)+len(buf)))
+	responseWriter.WriteHeader(httpStatus)
+	_, _ = responseWriter.Write([]byte(xml.Header))
+	_, _ = responseWriter.Write(buf)
+}
+
+func writeErrorResponse(responseWriter http.ResponseWriter, request *http.Request, httpStatus int, code string) {
+	if http.MethodHead == request.Method {
+		responseWriter.WriteHeader(httpStatus)
+		return
+	}
+
+	writeXMLResponse(responseWriter, httpStatus, &errorResponseStruct{
+		Code:     code,
+		Message:  code,
+		Resource: request.URL.Path,
+	})
+}
+
+func writeObjectHeaders(responseWriter http.ResponseWriter, object *objectStruct) {
+	for headerName, headerValueSlice := range object.metadata {
+		for _, headerValue := range headerValueSlice {
+			responseWriter.Header().Add(headerName, headerValue)
+		}
+	}
+	responseWriter.Header().Set("ETag", object.etag)
+	responseWriter.Header().Set("Content-Type", "application/octet-stream")
+}
+
+// locateBucket returns the named bucket (or writes a NoSuchBucket error response).
+// Must be called with globals locked.
+func locateBucket(responseWriter http.ResponseWriter, request *http.Request, bucketName string) (bucket *bucketStruct, ok bool) {
+	bucket, ok = globals.bucketMap[bucketName]
+	if !ok {
+		writeErrorResponse(responseWriter, request, http.StatusNotFound, "NoSuchBucket")
+	}
+	return
+}
+
+func doDelete(responseWriter http.ResponseWriter, request *http.Request) {
+	bucketName, key := parsePath(request)
+
+	globals.Lock()
+	defer globals.Unlock()
+
+	bucket, ok := locateBucket(responseWriter, request, bucketName)
+	if !ok {
+		return
+	}
+
+	if "" == key {
+		// DELETE Bucket
+		if 0 != len(bucket.objectMap) {
+			writeErrorResponse(responseWriter, request, http.StatusConflict, "BucketNotEmpty")
+			return
+		}
+		delete(globals.bucketMap, bucketName)
+		responseWriter.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	uploadID := request.URL.Query().Get("uploadId")
+	if "" != uploadID {
+		// Abort Multipart Upload
+		multipartUpload, ok := bucket.multipartUploadMap[uploadID]
+		if !ok || (key != multipartUpload.key) {
+			writeErrorResponse(responseWriter, request, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		delete(bucket.multipartUploadMap, uploadID)
+		responseWriter.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// DELETE Object (succeeds even if the object does not exist)
+	delete(bucket.objectMap, key)
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+func doGetOrHead(responseWriter http.ResponseWriter, request *http.Request) {
+	bucketName, key := parsePath(request)
+
+	if "" == bucketName {
+		writeErrorResponse(responseWriter, request, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	globals.Lock()
+	defer globals.Unlock()
+
+	bucket, ok := locateBucket(responseWriter, request, bucketName)
+	if !ok {
+		return
+	}
+
+	if "" == key {
+		if http.MethodHead == request.Method {
+			// HEAD Bucket
+			responseWriter.WriteHeader(http.StatusOK)
+		} else {
+			// GET Bucket (List Objects V2)
+			doListObjects(responseWriter, request, bucketName, bucket)
+		}
+		return
+	}
+
+	object, ok := bucket.objectMap[key]
+	if !ok {
+		writeErrorResponse(responseWriter, request, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	startOffset, stopOffset, isRange, ok := parseRangeHeader(request, len(object.contents))
+	if !ok {
+		responseWriter.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", len(object.contents)))
+		writeErrorResponse(responseWriter, request, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+		return
+	}
+
+	writeObjectHeaders(responseWriter, object)
+
+	if http.MethodHead == request.Method {
+		responseWriter.Header().Set("Content-Length", strconv.Itoa(len(object.contents)))
+		responseWriter.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if isRange {
+		responseWriter.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", startOffset, stopOffset, len(object.contents)))
+		responseWriter.Header().Set("Content-Length", strconv.Itoa(stopOffset-startOffset+1))
+		responseWriter.WriteHeader(http.StatusPartialContent)
+		_, _ = responseWriter.Write(object.contents[startOffset : stopOffset+1])
+	} else {
+		responseWriter.Header().Set("Content-Length", strconv.Itoa(len(object.contents)))
+		responseWriter.WriteHeader(http.StatusOK)
+		_, _ = responseWriter.Write(object.contents)
+	}
+}
+
+// doListObjects implements List Objects V2. Must be called with globals locked.
+func doListObjects(responseWriter http.ResponseWriter, request *http.Request, bucketName string, bucket *bucketStruct) {
+	var (
+		commonPrefix      string
+		commonPrefixSet   map[string]bool
+		continuationToken string
+		delimiter         string
+		err               error
+		key               string
+		keyList           []string
+		maxKeys           int
+		prefix            string
+		query             = request.URL.Query()
+		result            *listBucketResultStruct
+		startAfter        string
+	)
+
+	if "2" != query.Get("list-type") {
+		writeErrorResponse(responseWriter, request, http.StatusNotImplemented, "NotImplemented")
+		return
+	}
+
+	prefix = query.Get("prefix")
+	delimiter = query.Get("delimiter")
+	continuationToken = query.Get("continuation-token")
+	startAfter = query.Get("start-after")
+	if "" != continuationToken {
+		startAfter = continuationToken
+	}
+
+	maxKeys = defaultListObjectsMaxKeys
+	if "" != query.Get("max-keys") {
+		maxKeys, err = strconv.Atoi(query.Get("max-keys"))
+		if (nil != err) || (0 > maxKeys) {
+			writeErrorResponse(responseWriter, request, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+	}
+
+	result = &listBucketResultStruct{
+		Xmlns:             s3XMLNamespace,
+		Name:              bucketName,
+		Prefix:            prefix,
+		Delimiter:         delimiter,
+		MaxKeys:           maxKeys,
+		ContinuationToken: continuationToken,
+	}
+
+	keyList = make([]string, 0, len(bucket.objectMap))
+	for key = range bucket.objectMap {
+		if strings.HasPrefix(key, prefix) && (key > startAfter) {
+			keyList = append(keyList, key)
+		}
+	}
+	sort.Strings(keyList)
+
+	commonPrefixSet = make(map[string]bool)
+
+	for _, key = range keyList {
+		if "" != delimiter {
+			delimiterIndex := strings.Index(key[len(prefix):], delimiter)
+			if 0 <= delimiterIndex {
+				commonPrefix = key[:len(prefix)+delimiterIndex+len(delimiter)]
+				if commonPrefixSet[commonPrefix] {
+					continue
+				}
+				if result.KeyCount == maxKeys {
+					result.IsTruncated = true
+					break
+				}
+				commonPrefixSet[commonPrefix] = true
+				result.CommonPrefixes = append(result.CommonPrefixes, listBucketResultCommonPrefixesStruct{Prefix: commonPrefix})
+				result.KeyCount++
+				result.NextContinuationToken = key
+				continue
+			}
+		}
+		if result.KeyCount == maxKeys {
+			result.IsTruncated = true
+			break
+		}
+		object := bucket.objectMap[key]
+		result.Contents = append(result.Contents, listBucketResultContentsStruct{
+			Key:          key,
+			Size:         len(object.contents),
+			ETag:         object.etag,
+			StorageClass: "STANDARD",
+		})
+		result.KeyCount++
+		result.NextContinuationToken = key
+	}
+
+	if result.IsTruncated {
+		if "" != delimiter && 0 < len(result.CommonPrefixes) && strings.HasPrefix(result.NextContinuationToken, result.CommonPrefixes[len(result.CommonPrefixes)-1].Prefix) {
+			// Skip past all keys sharing the last returned CommonPrefix
+			result.NextContinuationToken = result.CommonPrefixes[len(result.CommonPrefixes)-1].Prefix + "\U0010FFFF"
+		}
+	} else {
+		result.NextContinuationToken = ""
+	}
+
+	writeXMLResponse(responseWriter, http.StatusOK, result)
+}
+
+func doPost(responseWriter http.ResponseWriter, request *http.Request) {
+	bucketName, key := parsePath(request)
+	query := request.URL.Query()
+
+	body, err := ioutil.ReadAll(request.Body)
+	if nil != err {
+		writeErrorResponse(responseWriter, request, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+
+	globals.Lock()
+	defer globals.Unlock()
+
+	bucket, ok := locateBucket(responseWriter, request, bucketName)
+	if !ok {
+		return
+	}
+
+	if "" == key {
+		writeErrorResponse(responseWriter, request, http.StatusNotImplemented, "NotImplemented")
+		return
+	}
+
+	if _, ok = query["uploads"]; ok {
+		// Initiate Multipart Upload
+		globals.lastUploadID++
+		uploadID := fmt.Sprintf("%016X", globals.lastUploadID)
+		bucket.multipartUploadMap[uploadID] = &multipartUploadStruct{
+			key:      key,
+			metadata: extractMetadata(request.Header),
+			partMap:  make(map[int]*partStruct),
+		}
+		writeXMLResponse(responseWriter, http.StatusOK, &initiateMultipartUploadResultStruct{
+			Xmlns:    s3XMLNamespace,
+			Bucket:   bucketName,
+			Key:      key,
+			UploadID: uploadID,
+		})
+		return
+	}
+
+	uploadID := query.Get("uploadId")
+	if "" == uploadID {
+		writeErrorResponse(responseWriter, request, http.StatusNotImplemented, "NotImplemented")
+		return
+	}
+
+	// Complete Multipart Upload
+
+	multipartUpload, ok := bucket.multipartUploadMap[uploadID]
+	if !ok || (key != multipartUpload.key) {
+		writeErrorResponse(responseWriter, request, http.StatusNotFound, "NoSuchUpload")
+		return
+	}
+
+	completeMultipartUpload := &completeMultipartUploadStruct{}
+	err = xml.Unmarshal(body, completeMultipartUpload)
+	if (nil != err) || (0 == len(completeMultipartUpload.Parts)) {
+		writeErrorResponse(responseWriter, request, http.StatusBadRequest, "MalformedXML")
+		return
+	}
+
+	contents := make([]byte, 0)
+	etagMD5s := make([]byte, 0, md5.Size*len(completeMultipartUpload.Parts))
+	prevPartNumber := 0
+
+	for partIndex, completePart := range completeMultipartUpload.Parts {
+		if completePart.PartNumber <= prevPartNumber {
+			writeErrorResponse(responseWriter, request, http.StatusBadRequest, "InvalidPartOrder")
+			return
+		}
+		prevPartNumber = completePart.PartNumber
+		part, ok := multipartUpload.partMap[completePart.PartNumber]
+		if !ok || (part.etag != completePart.ETag) {
+			writeErrorResponse(responseWriter, request, http.StatusBadRequest, "InvalidPart")
+			return
+		}
+		if (partIndex < len(completeMultipartUpload.Parts)-1) && (uint64(len(part.contents)) < globals.minMultipartPartSize) {
+			writeErrorResponse(responseWriter, request, http.StatusBadRequest, "EntityTooSmall")
+			return
+		}
+		contents = append(contents, part.contents...)
+		partMD5, _ := hex.DecodeString(strings.Trim(part.etag, "\""))
+		etagMD5s = append(etagMD5s, partMD5...)
+	}
+
+	etagMD5Sum := md5.Sum(etagMD5s)
+	etag := fmt.Sprintf("\"%s-%d\"", hex.EncodeToString(etagMD5Sum[:]), len(completeMultipartUpload.Parts))
+
+	bucket.objectMap[key] = &objectStruct{
+		contents: contents,
+		etag:     etag,
+		metadata: multipartUpload.metadata,
+	}
+
+	delete(bucket.multipartUploadMap, uploadID)
+
+	writeXMLResponse(responseWriter, http.StatusOK, &completeMultipartUploadResultStruct{
+		Xmlns:  s3XMLNamespace,
+		Bucket: bucketName,
+		Key:    key,
+		ETag:   etag,
+	})
+}
+
+func doPut(responseWriter http.ResponseWriter, request *http.Request) {
+	bucketName, key := parsePath(request)
+	query := request.URL.Query()
+
+	if "" == bucketName {
+		writeErrorResponse(responseWriter, request, http.StatusBadRequest, "InvalidBucketName")
+		return
+	}
+
+	body, err := ioutil.ReadAll(request.Body)
+	if nil != err {
+		writeErrorResponse(responseWriter, request, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+
+	globals.Lock()
+	defer globals.Unlock()
+
+	if "" == key {
+		// PUT Bucket
+		_, ok := globals.bucketMap[bucketName]
+		if ok {
+			writeErrorResponse(responseWriter, request, http.StatusConflict, bucketAlreadyOwnedByYouError)
+			return
+		}
+		globals.bucketMap[bucketName] = &bucketStruct{
+			objectMap:          make(map[string]*objectStruct),
+			multipartUploadMap: make(map[string]*multipartUploadStruct),
+		}
+		responseWriter.WriteHeader(http.StatusOK)
+		return
+	}
+
+	bucket, ok := locateBucket(responseWriter, request, bucketName)
+	if !ok {
+		return
+	}
+
+	uploadID := query.Get("uploadId")
+	if "" != uploadID {
+		// Upload Part
+		multipartUpload, ok := bucket.multipartUploadMap[uploadID]
+		if !ok || (key != multipartUpload.key) {
+			writeErrorResponse(responseWriter, request, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		partNumber, err := strconv.Atoi(query.Get("partNumber"))
+		if (nil != err) || (1 > partNumber) || (10000 < partNumber) {
+			writeErrorResponse(responseWriter, request, http.StatusBadRequest, "InvalidArgument")
+			return
+		}
+		part := &partStruct{
+			contents: body,
+			etag:     computeETag(body),
+		}
+		multipartUpload.partMap[partNumber] = part
+		responseWriter.Header().Set("ETag", part.etag)
+		responseWriter.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// PUT Object
+	object := &objectStruct{
+		contents: body,
+		etag:     computeETag(body),
+		metadata: extractMetadata(request.Header),
+	}
+	bucket.objectMap[key] = object
+	responseWriter.Header().Set("ETag", object.etag)
+	responseWriter.WriteHeader(http.StatusOK)
+}
+
+func (h httpRequestHandler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	globals.Lock()
+	globals.methodCount[request.Method]++
+	chaosFailure := (0 != globals.chaosFailureRate) && (0 == globals.methodCount[request.Method]%globals.chaosFailureRate)
+	globals.Unlock()
+
+	if chaosFailure {
+		writeErrorResponse(responseWriter, request, globals.chaosFailureHTTPStatus, "InternalError")
+		return
+	}
+
+	switch request.Method {
+	case http.MethodDelete:
+		doDelete(responseWriter, request)
+	case http.MethodGet:
+		doGetOrHead(responseWriter, request)
+	case http.MethodHead:
+		doGetOrHead(responseWriter, request)
+	case http.MethodPost:
+		doPost(responseWriter, request)
+	case http.MethodPut:
+		doPut(responseWriter, request)
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// fetchSettings applies the [RamS3] section of confMap. Missing options take their defaults.
+func fetchSettings(confMap conf.ConfMap) (err error) {
+	var (
+		chaosFailureHTTPStatus uint16
+		ipAddr                 string
+		tcpPort                uint16
+	)
+
+	ipAddr, err = confMap.FetchOptionValueString("RamS3", "IPAddr")
+	if nil != err {
+		ipAddr = defaultIPAddr
+	}
+
+	tcpPort, err = confMap.FetchOptionValueUint16("RamS3", "TCPPort")
+	if nil != err {
+		return
+	}
+
+	globals.addr = net.JoinHostPort(ipAddr, strconv.Itoa(int(tcpPort)))
+
+	globals.minMultipartPartSize, err = confMap.FetchOptionValueUint64("RamS3", "MinMultipartPartSize")
+	if nil != err {
+		globals.minMultipartPartSize = defaultMinMultipartPartSize
+	}
+
+	globals.chaosFailureRate, err = confMap.FetchOptionValueUint64("RamS3", "ChaosFailureRate")
+	if nil != err {
+		globals.chaosFailureRate = 0
+	}
+
+	chaosFailureHTTPStatus, err = confMap.FetchOptionValueUint16("RamS3", "ChaosFailureHTTPStatus")
+	if nil != err {
+		chaosFailureHTTPStatus = http.StatusInternalServerError
+	}
+	globals.chaosFailureHTTPStatus = int(chaosFailureHTTPStatus)
+
+	err = nil
+	return
+}
+
+// Daemon serves the emulated S3 API on RamS3.IPAddr:RamS3.TCPPort until one of signals is
+// received (other than SIGHUP, which merely re-applies the confFile & confStrings).
+func Daemon(confFile string, confStrings []string, signalHandlerIsArmedWG *sync.WaitGroup, doneChan chan bool, signals ...os.Signal) {
+	var (
+		confMap        conf.ConfMap
+		err            error
+		signalChan     chan os.Signal
+		signalReceived os.Signal
+	)
+
+	// Initialization
+
+	globals = &globalsStruct{
+		bucketMap:   make(map[string]*bucketStruct),
+		methodCount: make(map[string]uint64),
+	}
+
+	// Compute confMap
+
+	confMap, err = conf.MakeConfMapFromFile(confFile)
+	if nil != err {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	err = confMap.UpdateFromStrings(confStrings)
+	if nil != err {
+		log.Fatalf("failed to apply config overrides: %v", err)
+	}
+
+	err = fetchSettings(confMap)
+	if nil != err {
+		log.Fatalf("failed to fetch [RamS3] settings: %v", err)
+	}
+
+	// Kick off S3 Emulator (binding before arming the signal handler so that callers may
+	// issue requests as soon as signalHandlerIsArmedWG is signaled)
+
+	globals.httpServerListener, err = net.Listen("tcp", globals.addr)
+	if nil != err {
+		log.Fatalf("net.Listen(\"tcp\", \"%s\") failed: %v", globals.addr, err)
+	}
+
+	globals.httpServer = &http.Server{Handler: httpRequestHandler{}}
+
+	globals.httpServerExitedWG.Add(1)
+
+	go func() {
+		_ = globals.httpServer.Serve(globals.httpServerListener)
+		globals.httpServerExitedWG.Done()
+	}()
+
+	// Arm signal handler used to indicate termination and wait on it
+	//
+	// Note: signalled chan must be buffered to avoid race with window between
+	// arming handler and blocking on the chan read
+
+	signalChan = make(chan os.Signal, 1)
+
+	signal.Notify(signalChan, signals...)
+
+	if nil != signalHandlerIsArmedWG {
+		signalHandlerIsArmedWG.Done()
+	}
+
+	// Await a signal - reloading confFile each SIGHUP - exiting otherwise
+
+	for {
+		signalReceived = <-signalChan
+
+		if unix.SIGHUP == signalReceived {
+			// recompute confMap and re-apply (note that RamS3.IPAddr & RamS3.TCPPort changes are ignored)
+
+			confMap, err = conf.MakeConfMapFromFile(confFile)
+			if nil != err {
+				log.Fatalf("failed to load updated config: %v", err)
+			}
+
+			err = confMap.UpdateFromStrings(confStrings)
+			if nil != err {
+				log.Fatalf("failed to reapply config overrides: %v", err)
+			}
+
+			globals.Lock()
+			addr := globals.addr
+			err = fetchSettings(confMap)
+			globals.addr = addr
+			globals.Unlock()
+			if nil != err {
+				log.Fatalf("failed to fetch [RamS3] settings: %v", err)
+			}
+		} else {
+			// signalReceived either SIGINT or SIGTERM... so just exit
+
+			signal.Stop(signalChan)
+
+			_ = globals.httpServer.Close()
+			globals.httpServerExitedWG.Wait()
+
+			globals = nil
+
+			doneChan <- true
+
+			return
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package rams3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+const testURLPrefix = "http://127.0.0.1:9996/"
+
+func testRequest(t *testing.T, method string, path string, headers map[string]string, body string, expectedStatus int) (responseHeaders http.Header, responseBody []byte) {
+	request, err := http.NewRequest(method, testURLPrefix+path, strings.NewReader(body))
+	if nil != err {
+		t.Fatalf("http.NewRequest(\"%s\", \"%s\") failed: %v", method, path, err)
+	}
+	for headerName, headerValue := range headers {
+		request.Header.Set(headerName, headerValue)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if nil != err {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	responseBody, err = ioutil.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if nil != err {
+		t.Fatalf("%s %s body read failed: %v", method, path, err)
+	}
+	if expectedStatus != response.StatusCode {
+		t.Fatalf("%s %s returned Status %d... expected %d", method, path, response.StatusCode, expectedStatus)
+	}
+
+	responseHeaders = response.Header
+
+	return
+}
+
+func TestViaHTTP(t *testing.T) {
+	var (
+		initiateResult         initiateMultipartUploadResultStruct
+		listResult             listBucketResultStruct
+		signalHandlerIsArmedWG sync.WaitGroup
+	)
+
+	signalHandlerIsArmedWG.Add(1)
+	doneChan := make(chan bool, 1) // Must be buffered to avoid race
+
+	go Daemon("/dev/null", []string{"RamS3.TCPPort=9996", "RamS3.MinMultipartPartSize=2"}, &signalHandlerIsArmedWG, doneChan, unix.SIGTERM)
+
+	signalHandlerIsArmedWG.Wait()
+
+	// Buckets
+
+	testRequest(t, http.MethodHead, "bucket", nil, "", http.StatusNotFound)
+	testRequest(t, http.MethodPut, "bucket", nil, "", http.StatusOK)
+	testRequest(t, http.MethodPut, "bucket", nil, "", http.StatusConflict)
+	testRequest(t, http.MethodHead, "bucket", nil, "", http.StatusOK)
+
+	// Objects (including ranged GETs & metadata)
+
+	testRequest(t, http.MethodPut, "bucket/a/1", map[string]string{"X-Amz-Meta-Color": "blue"}, "0123456789", http.StatusOK)
+	testRequest(t, http.MethodPut, "bucket/a/2", nil, "", http.StatusOK)
+	testRequest(t, http.MethodPut, "bucket/b/1", nil, "", http.StatusOK)
+	testRequest(t, http.MethodPut, "bucket/c", nil, "", http.StatusOK)
+
+	headers, _ := testRequest(t, http.MethodHead, "bucket/a/1", nil, "", http.StatusOK)
+	if ("10" != headers.Get("Content-Length")) || ("blue" != headers.Get("X-Amz-Meta-Color")) {
+		t.Fatalf("HEAD bucket/a/1 returned unexpected headers: %v", headers)
+	}
+	_, body := testRequest(t, http.MethodGet, "bucket/a/1", map[string]string{"Range": "bytes=2-4"}, "", http.StatusPartialContent)
+	if "234" != string(body) {
+		t.Fatalf("GET bucket/a/1 bytes=2-4 returned \"%s\"", body)
+	}
+	_, body = testRequest(t, http.MethodGet, "bucket/a/1", map[string]string{"Range": "bytes=-3"}, "", http.StatusPartialContent)
+	if "789" != string(body) {
+		t.Fatalf("GET bucket/a/1 bytes=-3 returned \"%s\"", body)
+	}
+	testRequest(t, http.MethodGet, "bucket/a/1", map[string]string{"Range": "bytes=10-"}, "", http.StatusRequestedRangeNotSatisfiable)
+	testRequest(t, http.MethodGet, "bucket/a/3", nil, "", http.StatusNotFound)
+
+	// List Objects V2 (with delimiter & pagination)
+
+	_, body = testRequest(t, http.MethodGet, "bucket?list-type=2&delimiter=/&max-keys=2", nil, "", http.StatusOK)
+	err := xml.Unmarshal(body, &listResult)
+	if (nil != err) || !listResult.IsTruncated || (2 != len(listResult.CommonPrefixes)) || ("a/" != listResult.CommonPrefixes[0].Prefix) || ("b/" != listResult.CommonPrefixes[1].Prefix) {
+		t.Fatalf("GET bucket?list-type=2 returned unexpected result (%s) or error: %v", body, err)
+	}
+	_, body = testRequest(t, http.MethodGet, "bucket?list-type=2&delimiter=/&continuation-token="+listResult.NextContinuationToken, nil, "", http.StatusOK)
+	listResult = listBucketResultStruct{}
+	err = xml.Unmarshal(body, &listResult)
+	if (nil != err) || listResult.IsTruncated || (0 != len(listResult.CommonPrefixes)) || (1 != len(listResult.Contents)) || ("c" != listResult.Contents[0].Key) {
+		t.Fatalf("GET bucket?list-type=2 (continued) returned unexpected result (%s) or error: %v", body, err)
+	}
+
+	// Multipart Upload
+
+	_, body = testRequest(t, http.MethodPost, "bucket/mpu?uploads", map[string]string{"X-Amz-Meta-Kind": "mpu"}, "", http.StatusOK)
+	err = xml.Unmarshal(body, &initiateResult)
+	if (nil != err) || ("" == initiateResult.UploadID) {
+		t.Fatalf("POST bucket/mpu?uploads returned unexpected result (%s) or error: %v", body, err)
+	}
+	headers1, _ := testRequest(t, http.MethodPut, "bucket/mpu?partNumber=1&uploadId="+initiateResult.UploadID, nil, "ab", http.StatusOK)
+	headers2, _ := testRequest(t, http.MethodPut, "bucket/mpu?partNumber=2&uploadId="+initiateResult.UploadID, nil, "c", http.StatusOK)
+	completeBody := "<CompleteMultipartUpload>" +
+		"<Part><PartNumber>1</PartNumber><ETag>" + headers1.Get("ETag") + "</ETag></Part>" +
+		"<Part><PartNumber>2</PartNumber><ETag>" + headers2.Get("ETag") + "</ETag></Part>" +
+		"</CompleteMultipartUpload>"
+	testRequest(t, http.MethodPost, "bucket/mpu?uploadId="+initiateResult.UploadID, nil, completeBody, http.StatusOK)
+	headers, body = testRequest(t, http.MethodGet, "bucket/mpu", nil, "", http.StatusOK)
+	if !bytes.Equal([]byte("abc"), body) || ("mpu" != headers.Get("X-Amz-Meta-Kind")) || !strings.HasSuffix(headers.Get("ETag"), "-2\"") {
+		t.Fatalf("GET bucket/mpu returned unexpected body (%s) or headers: %v", body, headers)
+	}
+
+	// Deletes
+
+	testRequest(t, http.MethodDelete, "bucket", nil, "", http.StatusConflict)
+	for _, key := range []string{"a/1", "a/2", "b/1", "c", "mpu", "missing"} {
+		testRequest(t, http.MethodDelete, "bucket/"+key, nil, "", http.StatusNoContent)
+	}
+	testRequest(t, http.MethodDelete, "bucket", nil, "", http.StatusNoContent)
+
+	// Send ourself a SIGTERM to terminate Daemon()
+
+	unix.Kill(unix.Getpid(), unix.SIGTERM)
+
+	_ = <-doneChan
+}
@@ -20,7 +20,9 @@ import tempfile
 import time
 
 
-PACKAGES = ["blunder",
+PACKAGES = ["audit",
+            "blunder",
+            "cachebudget",
             "cleanproxyfs",
             "conf",
             "dlm",
@@ -32,13 +34,18 @@ PACKAGES = ["blunder",
             "inode",
             "inodeworkout",
             "jrpcfs",
+            "jrpcfsclient",
+            "keyprovider",
             "logger",
             "mkproxyfs", "mkproxyfs/mkproxyfs",
+            "pfs-inode-upgrade",
             "pfs-stress",
             "pfsconfjson", "pfsconfjsonpacked",
             "pfsworkout",
             "platform",
             "proxyfsd", "proxyfsd/proxyfsd",
+            "qos",
+            "rams3", "rams3/rams3",
             "ramswift", "ramswift/ramswift",
             "stats",
             "statslogger",
@@ -27,8 +27,17 @@ var (
 	FileCreateSuccessOps         = "proxyfs.inode.file.create.success.operations"
 	FileWritebackHitOps          = "proxyfs.inode.file.writeback.hit.operations"
 	FileWritebackMissOps         = "proxyfs.inode.file.writeback.miss.operations"
+	FileFallocateOps             = "proxyfs.inode.file.fallocate.operations"
+	FileCopyRangeOps             = "proxyfs.inode.file.copy-range.operations"
+	FileCopyRangeBytes           = "proxyfs.inode.file.copy-range.bytes"
+	FileETagComputeOps           = "proxyfs.inode.file.etag-compute.operations"
+	FileETagComputeBytes         = "proxyfs.inode.file.etag-compute.bytes"
 	FileReadcacheHitOps          = "proxyfs.inode.file.readcache.hit.operations"
 	FileReadcacheMissOps         = "proxyfs.inode.file.readcache.miss.operations"
+	FileReadaheadOps             = "proxyfs.inode.file.readahead.operations"
+	FileReadaheadBytes           = "proxyfs.inode.file.readahead.bytes"
+	FileReadaheadHitOps          = "proxyfs.inode.file.readahead.hit.operations"
+	FileReadaheadWastedBytes     = "proxyfs.inode.file.readahead.wasted.bytes"
 	FileReadOps                  = "proxyfs.inode.file.read.operations"
 	FileReadOps4K                = "proxyfs.inode.file.read.operations.size-up-to-4KB"
 	FileReadOps8K                = "proxyfs.inode.file.read.operations.size-4KB-to-8KB"
@@ -50,6 +59,7 @@ var (
 	FileReadplanOpsEntriesTo16   = "proxyfs.inode.file.readplan.operations.entries-5-to-16"
 	FileReadplanOpsEntriesTo64   = "proxyfs.inode.file.readplan.operations.entries-17-to-64"
 	FileReadplanOpsEntriesOver64 = "proxyfs.inode.file.readplan.operations.entries-over-64"
+	FileSeekOps                  = "proxyfs.inode.file.seek.operations"
 	FileWriteOps                 = "proxyfs.inode.file.write.operations"
 	FileWriteOps4K               = "proxyfs.inode.file.write.operations.size-up-to-4KB"
 	FileWriteOps8K               = "proxyfs.inode.file.write.operations.size-4KB-to-8KB"
@@ -70,9 +80,14 @@ var (
 	FileWroteBytes               = "proxyfs.inode.file.wrote.bytes"
 	DirSetsizeOps                = "proxyfs.inode.directory.setsize.operations"
 	FileFlushOps                 = "proxyfs.inode.file.flush.operations"
+	FileInlineWriteOps           = "proxyfs.inode.file.inline.write.operations"
+	FileInlinePromoteOps         = "proxyfs.inode.file.inline.promote.operations"
 	LogSegCreateOps              = "proxyfs.inode.file.log-segment.create.operations"
 	GcLogSegDeleteOps            = "proxyfs.inode.garbage-collection.log-segment.delete.operations"
 	GcLogSegOps                  = "proxyfs.inode.garbage-collection.log-segment.operations"
+	DedupChunkHits               = "proxyfs.inode.dedup.chunk.hit.operations"
+	DedupChunkMisses             = "proxyfs.inode.dedup.chunk.miss.operations"
+	DedupBytesSaved              = "proxyfs.inode.dedup.bytes-saved"
 	DirDestroyOps                = "proxyfs.inode.directory.destroy.operations"
 	FileDestroyOps               = "proxyfs.inode.file.destroy.operations"
 	SymlinkDestroyOps            = "proxyfs.inode.symlink.destroy.operations"
@@ -55,7 +55,7 @@ func accountDelete(accountName string) (err error) {
 		return
 	}
 
-	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "DELETE", "/"+swiftVersion+"/"+pathEscape(accountName), nil)
+	err = writeHTTPRequestLineAndHeaders(connection, "DELETE", "/"+swiftVersion+"/"+pathEscape(accountName), nil)
 	if nil != err {
 		releaseNonChunkedConnection(connection, false)
 		err = blunder.AddError(err, blunder.BadHTTPDeleteError)
@@ -163,7 +163,7 @@ func accountGet(connection *connectionStruct, accountName string, marker string)
 		isError    bool
 	)
 
-	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "GET", "/"+swiftVersion+"/"+pathEscape(accountName)+"?marker="+url.QueryEscape(marker), nil)
+	err = writeHTTPRequestLineAndHeaders(connection, "GET", "/"+swiftVersion+"/"+pathEscape(accountName)+"?marker="+url.QueryEscape(marker), nil)
 	if nil != err {
 		err = blunder.AddError(err, blunder.BadHTTPGetError)
 		logger.WarnfWithError(err, "swiftclient.accountGet(,\"%v\",\"%v\") got writeHTTPRequestLineAndHeaders() error", accountName, marker)
@@ -240,7 +240,7 @@ func accountHead(accountName string) (headers map[string][]string, err error) {
 		return
 	}
 
-	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "HEAD", "/"+swiftVersion+"/"+pathEscape(accountName), nil)
+	err = writeHTTPRequestLineAndHeaders(connection, "HEAD", "/"+swiftVersion+"/"+pathEscape(accountName), nil)
 	if nil != err {
 		releaseNonChunkedConnection(connection, false)
 		err = blunder.AddError(err, blunder.BadHTTPHeadError)
@@ -318,7 +318,7 @@ func accountPost(accountName string, requestHeaders map[string][]string) (err er
 
 	requestHeaders["Content-Length"] = []string{"0"}
 
-	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "POST", "/"+swiftVersion+"/"+pathEscape(accountName), requestHeaders)
+	err = writeHTTPRequestLineAndHeaders(connection, "POST", "/"+swiftVersion+"/"+pathEscape(accountName), requestHeaders)
 	if nil != err {
 		releaseNonChunkedConnection(connection, false)
 		err = blunder.AddError(err, blunder.BadHTTPPutError)
@@ -412,7 +412,7 @@ func accountPut(accountName string, requestHeaders map[string][]string) (err err
 
 	requestHeaders["Content-Length"] = []string{"0"}
 
-	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "PUT", "/"+swiftVersion+"/"+pathEscape(accountName), requestHeaders)
+	err = writeHTTPRequestLineAndHeaders(connection, "PUT", "/"+swiftVersion+"/"+pathEscape(accountName), requestHeaders)
 	if nil != err {
 		releaseNonChunkedConnection(connection, false)
 		err = blunder.AddError(err, blunder.BadHTTPPutError)
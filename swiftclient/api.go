@@ -1,4 +1,5 @@
-// Package swiftclient provides API access to the local Swift NoAuth Pipeline.
+// Package swiftclient provides API access to the local Swift NoAuth Pipeline
+// (or an alternative object storage Backend configured for a given Account).
 package swiftclient
 
 type OperationOptions uint64
@@ -29,10 +30,23 @@ type ChunkedPutContext interface {
 	SendChunk(buf []byte) (err error)                          // Send the supplied "chunk" via this ChunkedPutContext
 }
 
+// EndpointStarvationParameters is used to report the current state of one of the
+// Swift NoAuth Pipeline endpoints (see SwiftClient.NoAuthEndpoints) as part of
+// StarvationParameters. Healthy is false if the endpoint has been ejected.
+type EndpointStarvationParameters struct {
+	Addr                      string
+	Healthy                   bool
+	ConsecutiveFailures       uint32
+	ConnectionsInUse          uint64
+	ChunkedConnectionsIdle    uint16
+	NonChunkedConnectionsIdle uint16
+}
+
 // StarvationParameters is used to report the current details pertaining to both
-// the chunkedConnectionPool and nonChunkedConnectionPool. As this is a snapshot of
-// a typically rapidly evolving state, it should only be use in well controlled test
-// and debugging situations.
+// the chunkedConnectionPool and nonChunkedConnectionPool as well as each of the
+// endpoints they are spread over. As this is a snapshot of a typically rapidly
+// evolving state, it should only be use in well controlled test and debugging
+// situations.
 type StarvationParameters struct {
 	ChunkedConnectionPoolCapacity      uint16
 	ChunkedConnectionPoolInUse         uint16
@@ -40,6 +54,7 @@ type StarvationParameters struct {
 	NonChunkedConnectionPoolCapacity   uint16
 	NonChunkedConnectionPoolInUse      uint16
 	NonChunkedConnectionPoolNumWaiters uint64
+	EndpointList                       []EndpointStarvationParameters
 }
 
 // GetStarvationParameters returns the the current details pertaining to both
@@ -61,59 +76,88 @@ func SetStarvationCallbackFunc(starvationCallback StarvationCallbackFunc) {
 	globals.starvationCallback = starvationCallback
 }
 
+// Backend is implemented by each supported object storage service. The Backend used for
+// a particular Account is selected by the ObjectStorageBackend option of the Volume (or,
+// failing that, of the VolumeGroup containing it) whose AccountName matches. Absent that
+// option, the Swift NoAuth Pipeline described by the [SwiftClient] section is used.
+//
+// The package-level Account*, Container*, and Object* funcs dispatch to the Backend
+// selected for the supplied accountName. Errors returned by a Backend should be annotated
+// with both a blunder.FsError and the equivalent Swift HTTP status (see blunder.AddHTTPCode()).
+type Backend interface {
+	AccountDelete(accountName string) (err error)
+	AccountGet(accountName string) (headers map[string][]string, containerList []string, err error)
+	AccountHead(accountName string) (headers map[string][]string, err error)
+	AccountPost(accountName string, headers map[string][]string) (err error)
+	AccountPut(accountName string, headers map[string][]string) (err error)
+	ContainerDelete(accountName string, containerName string) (err error)
+	ContainerGet(accountName string, containerName string) (headers map[string][]string, objectList []string, err error)
+	ContainerHead(accountName string, containerName string) (headers map[string][]string, err error)
+	ContainerPost(accountName string, containerName string, headers map[string][]string) (err error)
+	ContainerPut(accountName string, containerName string, headers map[string][]string) (err error)
+	ObjectContentLength(accountName string, containerName string, objectName string) (length uint64, err error)
+	ObjectDelete(accountName string, containerName string, objectName string, operationOptions OperationOptions) (err error)
+	ObjectFetchChunkedPutContext(accountName string, containerName string, objectName string, useReserveForVolumeName string) (chunkedPutContext ChunkedPutContext, err error)
+	ObjectGet(accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error)
+	ObjectHead(accountName string, containerName string, objectName string) (headers map[string][]string, err error)
+	ObjectLoad(accountName string, containerName string, objectName string) (buf []byte, err error)
+	ObjectRead(accountName string, containerName string, objectName string, offset uint64, buf []byte) (len uint64, err error)
+	ObjectTail(accountName string, containerName string, objectName string, length uint64) (buf []byte, err error)
+}
+
 // AccountDelete invokes HTTP DELETE on the named Swift Account.
 func AccountDelete(accountName string) (err error) {
-	return accountDeleteWithRetry(accountName)
+	return backendForAccount(accountName).AccountDelete(accountName)
 }
 
 // AccountGet invokes HTTP GET on the named Swift Account.
 func AccountGet(accountName string) (headers map[string][]string, containerList []string, err error) {
-	return accountGetWithRetry(accountName)
+	return backendForAccount(accountName).AccountGet(accountName)
 }
 
 // AccountHead invokes HTTP HEAD on the named Swift Account.
 func AccountHead(accountName string) (headers map[string][]string, err error) {
-	return accountHeadWithRetry(accountName)
+	return backendForAccount(accountName).AccountHead(accountName)
 }
 
 // AccountPost invokes HTTP PUT on the named Swift Account.
 func AccountPost(accountName string, headers map[string][]string) (err error) {
-	return accountPostWithRetry(accountName, headers)
+	return backendForAccount(accountName).AccountPost(accountName, headers)
 }
 
 // AccountPut invokes HTTP PUT on the named Swift Account.
 func AccountPut(accountName string, headers map[string][]string) (err error) {
-	return accountPutWithRetry(accountName, headers)
+	return backendForAccount(accountName).AccountPut(accountName, headers)
 }
 
 // ContainerDelete invokes HTTP DELETE on the named Swift Container.
 func ContainerDelete(accountName string, containerName string) (err error) {
-	return containerDeleteWithRetry(accountName, containerName)
+	return backendForAccount(accountName).ContainerDelete(accountName, containerName)
 }
 
 // ContainerGet invokes HTTP GET on the named Swift Container.
 func ContainerGet(accountName string, containerName string) (headers map[string][]string, objectList []string, err error) {
-	return containerGetWithRetry(accountName, containerName)
+	return backendForAccount(accountName).ContainerGet(accountName, containerName)
 }
 
 // ContainerHead invokes HTTP HEAD on the named Swift Container.
 func ContainerHead(accountName string, containerName string) (headers map[string][]string, err error) {
-	return containerHeadWithRetry(accountName, containerName)
+	return backendForAccount(accountName).ContainerHead(accountName, containerName)
 }
 
 // ContainerPost invokes HTTP PUT on the named Swift Container.
 func ContainerPost(accountName string, containerName string, headers map[string][]string) (err error) {
-	return containerPostWithRetry(accountName, containerName, headers)
+	return backendForAccount(accountName).ContainerPost(accountName, containerName, headers)
 }
 
 // ContainerPut invokes HTTP PUT on the named Swift Container.
 func ContainerPut(accountName string, containerName string, headers map[string][]string) (err error) {
-	return containerPutWithRetry(accountName, containerName, headers)
+	return backendForAccount(accountName).ContainerPut(accountName, containerName, headers)
 }
 
 // ObjectContentLength invokes HTTP HEAD on the named Swift Object and returns value of Content-Length Header.
 func ObjectContentLength(accountName string, containerName string, objectName string) (length uint64, err error) {
-	return objectContentLengthWithRetry(accountName, containerName, objectName)
+	return backendForAccount(accountName).ObjectContentLength(accountName, containerName, objectName)
 }
 
 // ObjectCopy asynchronously creates a copy of the named Swift Object Source called the named Swift Object Destination.
@@ -123,40 +167,40 @@ func ObjectCopy(srcAccountName string, srcContainerName string, srcObjectName st
 
 // ObjectDelete invokes HTTP DELETE on the named Swift Object.
 func ObjectDelete(accountName string, containerName string, objectName string, operationOptions OperationOptions) (err error) {
-	return objectDelete(accountName, containerName, objectName, operationOptions)
+	return backendForAccount(accountName).ObjectDelete(accountName, containerName, objectName, operationOptions)
 }
 
 // ObjectFetchChunkedPutContext provisions a context to use for an HTTP PUT using "chunked" Transfer-Encoding on the named Swift Object.
 // The useReserveForVolumeName string argument should be set to "" if normal chunked connection pool is to be used.
 // If a reserved connection is to be used, note that there is only one per useReserveForVolumeName allowed at a time.
 func ObjectFetchChunkedPutContext(accountName string, containerName string, objectName string, useReserveForVolumeName string) (chunkedPutContext ChunkedPutContext, err error) {
-	return objectFetchChunkedPutContextWithRetry(accountName, containerName, objectName, useReserveForVolumeName)
+	return backendForAccount(accountName).ObjectFetchChunkedPutContext(accountName, containerName, objectName, useReserveForVolumeName)
 }
 
 // ObjectGet invokes HTTP GET on the named Swift Object for the specified byte range.
 func ObjectGet(accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error) {
-	return objectGetWithRetry(accountName, containerName, objectName, offset, length)
+	return backendForAccount(accountName).ObjectGet(accountName, containerName, objectName, offset, length)
 }
 
 // ObjectHead invokes HTTP HEAD on the named Swift Object.
 func ObjectHead(accountName string, containerName string, objectName string) (headers map[string][]string, err error) {
-	return objectHeadWithRetry(accountName, containerName, objectName)
+	return backendForAccount(accountName).ObjectHead(accountName, containerName, objectName)
 }
 
 // ObjectLoad invokes HTTP GET on the named Swift Object for the entire object.
 func ObjectLoad(accountName string, containerName string, objectName string) (buf []byte, err error) {
-	return objectLoadWithRetry(accountName, containerName, objectName)
+	return backendForAccount(accountName).ObjectLoad(accountName, containerName, objectName)
 }
 
 // ObjectRead invokes HTTP GET on the named Swift Object at the specified offset filling in the specified byte slice.
 // Note that the byte slice must already have the desired length even though those bytes will be overwritten.
 func ObjectRead(accountName string, containerName string, objectName string, offset uint64, buf []byte) (len uint64, err error) {
-	return objectReadWithRetry(accountName, containerName, objectName, offset, buf)
+	return backendForAccount(accountName).ObjectRead(accountName, containerName, objectName, offset, buf)
 }
 
 // ObjectTail invokes HTTP GET on the named Swift Object with a byte range selecting the specified length of trailing bytes.
 func ObjectTail(accountName string, containerName string, objectName string, length uint64) (buf []byte, err error) {
-	return objectTailWithRetry(accountName, containerName, objectName, length)
+	return backendForAccount(accountName).ObjectTail(accountName, containerName, objectName, length)
 }
 
 // Number of chunked connections that are idle
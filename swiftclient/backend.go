@@ -0,0 +1,252 @@
+// Object storage backend selection
+
+package swiftclient
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/trackedlock"
+)
+
+// SwiftBackendType is the ObjectStorageBackend:<name>.Type for the Swift NoAuth Pipeline
+// described by the [SwiftClient] section. It is used for any Account whose Volume (or
+// VolumeGroup) does not specify an ObjectStorageBackend.
+const SwiftBackendType = "Swift"
+
+// newBackendFunc is the signature of the func registered for each Backend type that will
+// construct an instance of that Backend as described by the named confMap section.
+type newBackendFunc func(confMap conf.ConfMap, backendSectionName string) (backend Backend, err error)
+
+type backendsStruct struct {
+	trackedlock.RWMutex
+	typeMap    map[string]newBackendFunc // Key: ObjectStorageBackend:<name>.Type
+	accountMap map[string]Backend        // Key: Volume:<name>.AccountName
+}
+
+var backends = backendsStruct{
+	typeMap: make(map[string]newBackendFunc),
+}
+
+// registerBackendType should be called from the init() func of each file implementing a Backend.
+func registerBackendType(backendType string, newBackend newBackendFunc) {
+	backends.typeMap[backendType] = newBackend
+}
+
+func init() {
+	registerBackendType(SwiftBackendType, newSwiftBackend)
+}
+
+// swiftBackendStruct implements Backend by way of the Swift NoAuth Pipeline described by the
+// [SwiftClient] section (and the connection pools maintained for it).
+type swiftBackendStruct struct{}
+
+var swiftBackend = &swiftBackendStruct{}
+
+func newSwiftBackend(confMap conf.ConfMap, backendSectionName string) (backend Backend, err error) {
+	backend = swiftBackend
+	err = nil
+	return
+}
+
+// fetchBackends computes the Backend to be used for each Account named in confMap. Note that
+// every Volume:<name> section is consulted, not just those of served volumes, as tools like
+// mkproxyfs access a Volume's Account without serving it.
+func fetchBackends(confMap conf.ConfMap) (accountMap map[string]Backend, err error) {
+	var (
+		accountName        string
+		backend            Backend
+		backendName        string
+		backendNameMap     map[string]Backend // Key: ObjectStorageBackend:<name>
+		backendSectionName string
+		backendType        string
+		newBackend         newBackendFunc
+		ok                 bool
+		sectionName        string
+		volumeGroupName    string
+		volumeName         string
+		volumeNameList     []string
+		volumeToGroupMap   map[string]string // Key: Volume:<name>; Value: VolumeGroup:<name>
+	)
+
+	accountMap = make(map[string]Backend)
+	backendNameMap = make(map[string]Backend)
+	volumeToGroupMap = make(map[string]string)
+
+	for sectionName = range confMap {
+		if strings.HasPrefix(sectionName, "VolumeGroup:") {
+			volumeNameList, err = confMap.FetchOptionValueStringSlice(sectionName, "VolumeList")
+			if nil != err {
+				volumeNameList = []string{}
+			}
+			for _, volumeName = range volumeNameList {
+				volumeToGroupMap["Volume:"+volumeName] = sectionName
+			}
+		}
+	}
+
+	for sectionName = range confMap {
+		if !strings.HasPrefix(sectionName, "Volume:") {
+			continue
+		}
+
+		accountName, err = confMap.FetchOptionValueString(sectionName, "AccountName")
+		if nil != err {
+			continue // Not every Volume:<name> section need be complete (e.g. for tools)
+		}
+
+		backendName, err = confMap.FetchOptionValueString(sectionName, "ObjectStorageBackend")
+		if nil != err {
+			volumeGroupName, ok = volumeToGroupMap[sectionName]
+			if !ok {
+				continue
+			}
+			backendName, err = confMap.FetchOptionValueString(volumeGroupName, "ObjectStorageBackend")
+			if nil != err {
+				continue
+			}
+		}
+
+		if "" == backendName {
+			continue
+		}
+
+		backend, ok = backendNameMap[backendName]
+		if !ok {
+			backendSectionName = "ObjectStorageBackend:" + backendName
+
+			backendType, err = confMap.FetchOptionValueString(backendSectionName, "Type")
+			if nil != err {
+				return
+			}
+
+			newBackend, ok = backends.typeMap[backendType]
+			if !ok {
+				err = fmt.Errorf("%s.Type (\"%s\") not supported", backendSectionName, backendType)
+				return
+			}
+
+			backend, err = newBackend(confMap, backendSectionName)
+			if nil != err {
+				err = fmt.Errorf("%s could not be initialized: %v", backendSectionName, err)
+				return
+			}
+
+			backendNameMap[backendName] = backend
+		}
+
+		if swiftBackend != backend {
+			accountMap[accountName] = backend
+		}
+	}
+
+	err = nil
+	return
+}
+
+func updateBackends(confMap conf.ConfMap) (err error) {
+	var (
+		accountMap map[string]Backend
+	)
+
+	accountMap, err = fetchBackends(confMap)
+	if nil != err {
+		return
+	}
+
+	backends.Lock()
+	backends.accountMap = accountMap
+	backends.Unlock()
+
+	return
+}
+
+// backendForAccount returns the Backend to be used for accountName.
+func backendForAccount(accountName string) (backend Backend) {
+	var (
+		ok bool
+	)
+
+	backends.RLock()
+	backend, ok = backends.accountMap[accountName]
+	backends.RUnlock()
+
+	if !ok {
+		backend = swiftBackend
+	}
+
+	return
+}
+
+func (backend *swiftBackendStruct) AccountDelete(accountName string) (err error) {
+	return accountDeleteWithRetry(accountName)
+}
+
+func (backend *swiftBackendStruct) AccountGet(accountName string) (headers map[string][]string, containerList []string, err error) {
+	return accountGetWithRetry(accountName)
+}
+
+func (backend *swiftBackendStruct) AccountHead(accountName string) (headers map[string][]string, err error) {
+	return accountHeadWithRetry(accountName)
+}
+
+func (backend *swiftBackendStruct) AccountPost(accountName string, headers map[string][]string) (err error) {
+	return accountPostWithRetry(accountName, headers)
+}
+
+func (backend *swiftBackendStruct) AccountPut(accountName string, headers map[string][]string) (err error) {
+	return accountPutWithRetry(accountName, headers)
+}
+
+func (backend *swiftBackendStruct) ContainerDelete(accountName string, containerName string) (err error) {
+	return containerDeleteWithRetry(accountName, containerName)
+}
+
+func (backend *swiftBackendStruct) ContainerGet(accountName string, containerName string) (headers map[string][]string, objectList []string, err error) {
+	return containerGetWithRetry(accountName, containerName)
+}
+
+func (backend *swiftBackendStruct) ContainerHead(accountName string, containerName string) (headers map[string][]string, err error) {
+	return containerHeadWithRetry(accountName, containerName)
+}
+
+func (backend *swiftBackendStruct) ContainerPost(accountName string, containerName string, headers map[string][]string) (err error) {
+	return containerPostWithRetry(accountName, containerName, headers)
+}
+
+func (backend *swiftBackendStruct) ContainerPut(accountName string, containerName string, headers map[string][]string) (err error) {
+	return containerPutWithRetry(accountName, containerName, headers)
+}
+
+func (backend *swiftBackendStruct) ObjectContentLength(accountName string, containerName string, objectName string) (length uint64, err error) {
+	return objectContentLengthWithRetry(accountName, containerName, objectName)
+}
+
+func (backend *swiftBackendStruct) ObjectDelete(accountName string, containerName string, objectName string, operationOptions OperationOptions) (err error) {
+	return objectDelete(accountName, containerName, objectName, operationOptions)
+}
+
+func (backend *swiftBackendStruct) ObjectFetchChunkedPutContext(accountName string, containerName string, objectName string, useReserveForVolumeName string) (chunkedPutContext ChunkedPutContext, err error) {
+	return objectFetchChunkedPutContextWithRetry(accountName, containerName, objectName, useReserveForVolumeName)
+}
+
+func (backend *swiftBackendStruct) ObjectGet(accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error) {
+	return objectGetWithRetry(accountName, containerName, objectName, offset, length)
+}
+
+func (backend *swiftBackendStruct) ObjectHead(accountName string, containerName string, objectName string) (headers map[string][]string, err error) {
+	return objectHeadWithRetry(accountName, containerName, objectName)
+}
+
+func (backend *swiftBackendStruct) ObjectLoad(accountName string, containerName string, objectName string) (buf []byte, err error) {
+	return objectLoadWithRetry(accountName, containerName, objectName)
+}
+
+func (backend *swiftBackendStruct) ObjectRead(accountName string, containerName string, objectName string, offset uint64, buf []byte) (len uint64, err error) {
+	return objectReadWithRetry(accountName, containerName, objectName, offset, buf)
+}
+
+func (backend *swiftBackendStruct) ObjectTail(accountName string, containerName string, objectName string, length uint64) (buf []byte, err error) {
+	return objectTailWithRetry(accountName, containerName, objectName, length)
+}
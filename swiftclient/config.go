@@ -4,7 +4,7 @@ import (
 	"container/list"
 	"fmt"
 	"net"
-	"strconv"
+	"sync"
 	"time"
 
 	"github.com/swiftstack/ProxyFS/bucketstats"
@@ -16,29 +16,29 @@ import (
 
 type connectionStruct struct {
 	connectionNonce      uint64 // globals.connectionNonce at time connection was established
+	endpoint             *endpointStruct
 	tcpConn              *net.TCPConn
 	reserveForVolumeName string
 }
 
 type connectionPoolStruct struct {
 	trackedlock.Mutex
-	poolCapacity            uint16              // Set to SwiftClient.{|Non}ChunkedConnectionPoolSize
-	poolInUse               uint16              // Active (i.e. not in LIFO) *connectionStruct's
-	lifoIndex               uint16              // Indicates where next released *connectionStruct will go
-	lifoOfActiveConnections []*connectionStruct // LIFO of available active connections
-	numWaiters              uint64              // Count of the number of blocked acquirers
-	waiters                 *list.List          // Contains sync.Cond's of waiters
-	//                                             At time of connection release:
-	//                                               If poolInUse < poolCapacity,
-	//                                                 If keepAlive: connectionStruct pushed to lifoOfActiveConnections
-	//                                               If poolInUse == poolCapacity,
-	//                                                 If keepAlive: connectionStruct pushed to lifoOfActiveConnections
-	//                                                 sync.Cond at front of waitors is awakened
-	//                                               If poolInUse > poolCapacity,
-	//                                                 poolInUse is decremented and connection is discarded
-	//                                             Note: waiters list is not used for when in starvation mode
-	//                                                   for the chunkedConnectionPool if a starvationCallback
-	//                                                   has been provided
+	poolCapacity uint16     // Set to SwiftClient.{|Non}ChunkedConnectionPoolSize
+	poolInUse    uint16     // Active (i.e. not idle) *connectionStruct's
+	numIdle      uint16     // Idle *connectionStruct's across all endpointStruct LIFOs
+	numWaiters   uint64     // Count of the number of blocked acquirers
+	waiters      *list.List // Contains sync.Cond's of waiters
+	//                         At time of connection release:
+	//                           If poolInUse < poolCapacity,
+	//                             If keepAlive: connectionStruct pushed to its endpointStruct's LIFO
+	//                           If poolInUse == poolCapacity,
+	//                             If keepAlive: connectionStruct pushed to its endpointStruct's LIFO
+	//                             sync.Cond at front of waitors is awakened
+	//                           If poolInUse > poolCapacity,
+	//                             poolInUse is decremented and connection is discarded
+	//                         Note: waiters list is not used for when in starvation mode
+	//                               for the chunkedConnectionPool if a starvationCallback
+	//                               has been provided
 }
 
 // Used to track client request times (client's of swiftclient) and Swift server
@@ -62,8 +62,15 @@ type requestFailureStatistic struct {
 }
 
 type globalsStruct struct {
-	noAuthStringAddr                string
-	noAuthTCPAddr                   *net.TCPAddr
+	endpoints                       []*endpointStruct // From SwiftClient.NoAuthEndpoints (or NoAuthIPAddr:NoAuthTCPPort)
+	endpointsMutex                  trackedlock.Mutex
+	endpointNextIndex               int    // where selectEndpoint() starts its scan
+	endpointSelection               string // one of EndpointSelection{RoundRobin|LeastLoaded}
+	healthCheckInterval             time.Duration
+	healthCheckTimeout              time.Duration
+	healthCheckFailureThreshold     uint32 // consecutive failures before an endpoint is ejected
+	healthCheckerStopChan           chan bool
+	healthCheckerWG                 sync.WaitGroup
 	timeout                         time.Duration // TODO: Currently not enforced
 	retryLimit                      uint16        // maximum retries
 	retryLimitObject                uint16        // maximum retries for object ops
@@ -193,32 +200,13 @@ func init() {
 func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
 	var (
 		chunkedConnectionPoolSize    uint16
-		freeConnectionIndex          uint16
-		noAuthIPAddr                 string
-		noAuthTCPPort                uint16
 		nonChunkedConnectionPoolSize uint16
 	)
 
 	// register the bucketstats statistics tracked here
 	bucketstats.Register("proxyfs.swiftclient", "", &globals)
 
-	noAuthIPAddr, err = confMap.FetchOptionValueString("SwiftClient", "NoAuthIPAddr")
-	if nil != err {
-		noAuthIPAddr = "127.0.0.1" // TODO: Eventually just return
-	}
-
-	noAuthTCPPort, err = confMap.FetchOptionValueUint16("SwiftClient", "NoAuthTCPPort")
-	if nil != err {
-		return
-	}
-	if uint16(0) == noAuthTCPPort {
-		err = fmt.Errorf("SwiftClient.NoAuthTCPPort must be a non-zero uint16")
-		return
-	}
-
-	globals.noAuthStringAddr = noAuthIPAddr + ":" + strconv.Itoa(int(noAuthTCPPort))
-
-	globals.noAuthTCPAddr, err = net.ResolveTCPAddr("tcp4", globals.noAuthStringAddr)
+	err = fetchEndpoints(confMap)
 	if nil != err {
 		return
 	}
@@ -284,15 +272,10 @@ func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
 
 	globals.chunkedConnectionPool.poolCapacity = chunkedConnectionPoolSize
 	globals.chunkedConnectionPool.poolInUse = 0
-	globals.chunkedConnectionPool.lifoIndex = 0
-	globals.chunkedConnectionPool.lifoOfActiveConnections = make([]*connectionStruct, chunkedConnectionPoolSize)
+	globals.chunkedConnectionPool.numIdle = 0
 	globals.chunkedConnectionPool.numWaiters = 0
 	globals.chunkedConnectionPool.waiters = list.New()
 
-	for freeConnectionIndex = uint16(0); freeConnectionIndex < chunkedConnectionPoolSize; freeConnectionIndex++ {
-		globals.chunkedConnectionPool.lifoOfActiveConnections[freeConnectionIndex] = nil
-	}
-
 	nonChunkedConnectionPoolSize, err = confMap.FetchOptionValueUint16("SwiftClient", "NonChunkedConnectionPoolSize")
 	if nil != err {
 		return
@@ -304,21 +287,23 @@ func (dummy *globalsStruct) Up(confMap conf.ConfMap) (err error) {
 
 	globals.nonChunkedConnectionPool.poolCapacity = nonChunkedConnectionPoolSize
 	globals.nonChunkedConnectionPool.poolInUse = 0
-	globals.nonChunkedConnectionPool.lifoIndex = 0
-	globals.nonChunkedConnectionPool.lifoOfActiveConnections = make([]*connectionStruct, nonChunkedConnectionPoolSize)
+	globals.nonChunkedConnectionPool.numIdle = 0
 	globals.nonChunkedConnectionPool.numWaiters = 0
 	globals.nonChunkedConnectionPool.waiters = list.New()
 
-	for freeConnectionIndex = uint16(0); freeConnectionIndex < nonChunkedConnectionPoolSize; freeConnectionIndex++ {
-		globals.nonChunkedConnectionPool.lifoOfActiveConnections[freeConnectionIndex] = nil
-	}
-
 	globals.starvationCallback = nil
 
 	globals.reservedChunkedConnection = make(map[string]*connectionStruct)
 
 	globals.maxIntAsUint64 = uint64(^uint(0) >> 1)
 
+	err = updateBackends(confMap)
+	if nil != err {
+		return
+	}
+
+	startHealthChecker()
+
 	return
 }
 
@@ -355,14 +340,20 @@ func (dummy *globalsStruct) SignaledStart(confMap conf.ConfMap) (err error) {
 }
 
 func (dummy *globalsStruct) SignaledFinish(confMap conf.ConfMap) (err error) {
-	return nil
+	err = updateBackends(confMap)
+	return
 }
 
 func (dummy *globalsStruct) Down(confMap conf.ConfMap) (err error) {
+	stopHealthChecker()
 	drainConnections()
 	globals.connectionNonce++
 	bucketstats.UnRegister("proxyfs.swiftclient", "")
 
+	backends.Lock()
+	backends.accountMap = nil
+	backends.Unlock()
+
 	err = nil
 	return
 }
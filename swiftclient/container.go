@@ -55,7 +55,7 @@ func containerDelete(accountName string, containerName string) (err error) {
 		return
 	}
 
-	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "DELETE", "/"+swiftVersion+"/"+pathEscape(accountName, containerName), nil)
+	err = writeHTTPRequestLineAndHeaders(connection, "DELETE", "/"+swiftVersion+"/"+pathEscape(accountName, containerName), nil)
 	if nil != err {
 		releaseNonChunkedConnection(connection, false)
 		err = blunder.AddError(err, blunder.BadHTTPDeleteError)
@@ -163,7 +163,7 @@ func containerGet(connection *connectionStruct, accountName string, containerNam
 		isError    bool
 	)
 
-	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "GET", "/"+swiftVersion+"/"+pathEscape(accountName, containerName)+"?marker="+url.QueryEscape(marker), nil)
+	err = writeHTTPRequestLineAndHeaders(connection, "GET", "/"+swiftVersion+"/"+pathEscape(accountName, containerName)+"?marker="+url.QueryEscape(marker), nil)
 	if nil != err {
 		err = blunder.AddError(err, blunder.BadHTTPGetError)
 		logger.WarnfWithError(err, "swiftclient.containerGet(,\"%v\",\"%v\",\"%v\") got writeHTTPRequestLineAndHeaders() error", accountName, containerName, marker)
@@ -241,7 +241,7 @@ func containerHead(accountName string, containerName string) (headers map[string
 		return
 	}
 
-	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "HEAD", "/"+swiftVersion+"/"+pathEscape(accountName, containerName), nil)
+	err = writeHTTPRequestLineAndHeaders(connection, "HEAD", "/"+swiftVersion+"/"+pathEscape(accountName, containerName), nil)
 	if nil != err {
 		releaseNonChunkedConnection(connection, false)
 		err = blunder.AddError(err, blunder.BadHTTPHeadError)
@@ -319,7 +319,7 @@ func containerPost(accountName string, containerName string, requestHeaders map[
 
 	requestHeaders["Content-Length"] = []string{"0"}
 
-	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "POST", "/"+swiftVersion+"/"+pathEscape(accountName, containerName), requestHeaders)
+	err = writeHTTPRequestLineAndHeaders(connection, "POST", "/"+swiftVersion+"/"+pathEscape(accountName, containerName), requestHeaders)
 	if nil != err {
 		releaseNonChunkedConnection(connection, false)
 		err = blunder.AddError(err, blunder.BadHTTPPutError)
@@ -413,7 +413,7 @@ func containerPut(accountName string, containerName string, requestHeaders map[s
 
 	requestHeaders["Content-Length"] = []string{"0"}
 
-	err = writeHTTPRequestLineAndHeaders(connection.tcpConn, "PUT", "/"+swiftVersion+"/"+pathEscape(accountName, containerName), requestHeaders)
+	err = writeHTTPRequestLineAndHeaders(connection, "PUT", "/"+swiftVersion+"/"+pathEscape(accountName, containerName), requestHeaders)
 	if nil != err {
 		releaseNonChunkedConnection(connection, false)
 		err = blunder.AddError(err, blunder.BadHTTPPutError)
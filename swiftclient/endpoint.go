@@ -0,0 +1,452 @@
+package swiftclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+)
+
+// Selection policies for choosing among the healthy Swift NoAuth Pipeline endpoints
+const (
+	EndpointSelectionRoundRobin  = "RoundRobin"
+	EndpointSelectionLeastLoaded = "LeastLoaded"
+)
+
+const (
+	healthCheckIntervalDefault         = 10 * time.Second
+	healthCheckTimeoutDefault          = time.Second
+	healthCheckFailureThresholdDefault = uint32(2)
+)
+
+// endpointStruct tracks one Swift NoAuth Pipeline endpoint. Connections to each
+// endpoint are pooled separately (idle connections are kept in chunkedIdle and
+// nonChunkedIdle) but share the capacity of the chunkedConnectionPool and
+// nonChunkedConnectionPool. An endpoint is ejected once consecutiveFailures
+// reaches globals.healthCheckFailureThreshold and re-admitted the next time a
+// health check or connection attempt to it succeeds.
+type endpointStruct struct {
+	stringAddr          string
+	tcpAddr             *net.TCPAddr
+	healthy             bool                // Protected by globals.endpointsMutex
+	consecutiveFailures uint32              // Protected by globals.endpointsMutex
+	inUse               uint64              // Protected by globals.endpointsMutex (includes reserved connections)
+	chunkedIdle         []*connectionStruct // Protected by globals.chunkedConnectionPool
+	nonChunkedIdle      []*connectionStruct // Protected by globals.nonChunkedConnectionPool
+}
+
+func fetchEndpoints(confMap conf.ConfMap) (err error) {
+	var (
+		endpoint         *endpointStruct
+		endpointAddr     string
+		endpointAddrs    []string
+		noAuthIPAddr     string
+		noAuthTCPPort    uint16
+		failureThreshold uint32
+	)
+
+	endpointAddrs, err = confMap.FetchOptionValueStringSlice("SwiftClient", "NoAuthEndpoints")
+	if (nil != err) || (0 == len(endpointAddrs)) {
+		noAuthIPAddr, err = confMap.FetchOptionValueString("SwiftClient", "NoAuthIPAddr")
+		if nil != err {
+			noAuthIPAddr = "127.0.0.1" // TODO: Eventually just return
+		}
+
+		noAuthTCPPort, err = confMap.FetchOptionValueUint16("SwiftClient", "NoAuthTCPPort")
+		if nil != err {
+			return
+		}
+		if uint16(0) == noAuthTCPPort {
+			err = fmt.Errorf("SwiftClient.NoAuthTCPPort must be a non-zero uint16")
+			return
+		}
+
+		endpointAddrs = []string{noAuthIPAddr + ":" + strconv.Itoa(int(noAuthTCPPort))}
+	}
+
+	globals.endpoints = make([]*endpointStruct, 0, len(endpointAddrs))
+
+	for _, endpointAddr = range endpointAddrs {
+		endpoint = &endpointStruct{
+			stringAddr:          endpointAddr,
+			healthy:             true,
+			consecutiveFailures: 0,
+			inUse:               0,
+			chunkedIdle:         make([]*connectionStruct, 0),
+			nonChunkedIdle:      make([]*connectionStruct, 0),
+		}
+
+		endpoint.tcpAddr, err = net.ResolveTCPAddr("tcp4", endpointAddr)
+		if nil != err {
+			err = fmt.Errorf("SwiftClient.NoAuthEndpoints entry \"%s\" invalid: %v", endpointAddr, err)
+			return
+		}
+
+		globals.endpoints = append(globals.endpoints, endpoint)
+	}
+
+	globals.endpointNextIndex = 0
+
+	globals.endpointSelection, err = confMap.FetchOptionValueString("SwiftClient", "EndpointSelection")
+	if nil != err {
+		globals.endpointSelection = EndpointSelectionLeastLoaded
+	}
+	switch globals.endpointSelection {
+	case EndpointSelectionRoundRobin:
+	case EndpointSelectionLeastLoaded:
+	default:
+		err = fmt.Errorf("SwiftClient.EndpointSelection must be either %s or %s", EndpointSelectionRoundRobin, EndpointSelectionLeastLoaded)
+		return
+	}
+
+	globals.healthCheckInterval, err = confMap.FetchOptionValueDuration("SwiftClient", "HealthCheckInterval")
+	if nil != err {
+		globals.healthCheckInterval = healthCheckIntervalDefault
+	}
+
+	globals.healthCheckTimeout, err = confMap.FetchOptionValueDuration("SwiftClient", "HealthCheckTimeout")
+	if nil != err {
+		globals.healthCheckTimeout = healthCheckTimeoutDefault
+	}
+
+	failureThreshold, err = confMap.FetchOptionValueUint32("SwiftClient", "HealthCheckFailureThreshold")
+	if nil != err {
+		failureThreshold = healthCheckFailureThresholdDefault
+	}
+	if 0 == failureThreshold {
+		err = fmt.Errorf("SwiftClient.HealthCheckFailureThreshold must be a non-zero uint32")
+		return
+	}
+	globals.healthCheckFailureThreshold = failureThreshold
+
+	logger.Infof("SwiftClient.NoAuthEndpoints %v, SwiftClient.EndpointSelection %s, SwiftClient.HealthCheckInterval %v",
+		endpointAddrs, globals.endpointSelection, globals.healthCheckInterval)
+
+	err = nil
+	return
+}
+
+// selectEndpoint picks the endpoint for a new (or reused) connection among those
+// currently healthy. Should all endpoints have been ejected, the selection is made
+// among all of them so that a recovered endpoint will still be found.
+func selectEndpoint() (endpoint *endpointStruct) {
+	var (
+		candidate      *endpointStruct
+		candidateIndex int
+		healthyOnly    bool
+		offset         int
+	)
+
+	globals.endpointsMutex.Lock()
+
+	healthyOnly = false
+	for _, candidate = range globals.endpoints {
+		if candidate.healthy {
+			healthyOnly = true
+			break
+		}
+	}
+
+	endpoint = nil
+
+	for offset = 0; offset < len(globals.endpoints); offset++ {
+		candidateIndex = (globals.endpointNextIndex + offset) % len(globals.endpoints)
+		candidate = globals.endpoints[candidateIndex]
+		if healthyOnly && !candidate.healthy {
+			continue
+		}
+		if (nil == endpoint) || ((EndpointSelectionLeastLoaded == globals.endpointSelection) && (candidate.inUse < endpoint.inUse)) {
+			endpoint = candidate
+			if EndpointSelectionRoundRobin == globals.endpointSelection {
+				break
+			}
+		}
+	}
+
+	globals.endpointNextIndex = (globals.endpointNextIndex + 1) % len(globals.endpoints)
+
+	endpoint.inUse++
+
+	globals.endpointsMutex.Unlock()
+
+	return
+}
+
+// reselectEndpoint is used when a connection is about to be (re)opened to an endpoint
+// that has since been ejected. The returned endpoint may be the same one if no healthy
+// alternative exists.
+func reselectEndpoint(oldEndpoint *endpointStruct) (newEndpoint *endpointStruct) {
+	if oldEndpoint.isHealthy() {
+		newEndpoint = oldEndpoint
+		return
+	}
+
+	newEndpoint = selectEndpoint()
+
+	globals.endpointsMutex.Lock()
+	oldEndpoint.inUse--
+	globals.endpointsMutex.Unlock()
+
+	return
+}
+
+func (endpoint *endpointStruct) isHealthy() (healthy bool) {
+	globals.endpointsMutex.Lock()
+	healthy = endpoint.healthy
+	globals.endpointsMutex.Unlock()
+	return
+}
+
+func (endpoint *endpointStruct) acquired() {
+	globals.endpointsMutex.Lock()
+	endpoint.inUse++
+	globals.endpointsMutex.Unlock()
+}
+
+func (endpoint *endpointStruct) released() {
+	globals.endpointsMutex.Lock()
+	endpoint.inUse--
+	globals.endpointsMutex.Unlock()
+}
+
+// succeeded re-admits an ejected endpoint
+func (endpoint *endpointStruct) succeeded() {
+	globals.endpointsMutex.Lock()
+	endpoint.consecutiveFailures = 0
+	if !endpoint.healthy {
+		endpoint.healthy = true
+		logger.Infof("swiftclient re-admitted Swift NoAuth Pipeline endpoint %s", endpoint.stringAddr)
+	}
+	globals.endpointsMutex.Unlock()
+}
+
+// failed counts a failed health check or connection attempt, ejecting the endpoint
+// (and closing its idle connections) upon reaching globals.healthCheckFailureThreshold
+func (endpoint *endpointStruct) failed(err error) {
+	var (
+		ejected bool
+	)
+
+	globals.endpointsMutex.Lock()
+	endpoint.consecutiveFailures++
+	// Only eject if a health checker is running to eventually re-admit the endpoint
+	ejected = endpoint.healthy && (0 != globals.healthCheckInterval) && (endpoint.consecutiveFailures >= globals.healthCheckFailureThreshold)
+	if ejected {
+		endpoint.healthy = false
+	}
+	globals.endpointsMutex.Unlock()
+
+	if ejected {
+		logger.WarnfWithError(err, "swiftclient ejected Swift NoAuth Pipeline endpoint %s", endpoint.stringAddr)
+		drainEndpointConnections(endpoint)
+	}
+}
+
+// checkHealth issues a GET of /info to the endpoint
+func (endpoint *endpointStruct) checkHealth(httpClient *http.Client) (err error) {
+	var (
+		httpResponse *http.Response
+	)
+
+	httpResponse, err = httpClient.Get("http://" + endpoint.stringAddr + "/info")
+	if nil != err {
+		return
+	}
+	_ = httpResponse.Body.Close()
+
+	if http.StatusOK != httpResponse.StatusCode {
+		err = fmt.Errorf("GET /info returned Status %s", httpResponse.Status)
+		return
+	}
+
+	err = nil
+	return
+}
+
+func checkEndpoints() {
+	var (
+		endpoint   *endpointStruct
+		err        error
+		httpClient *http.Client
+	)
+
+	httpClient = &http.Client{
+		Transport: &http.Transport{DisableKeepAlives: true},
+		Timeout:   globals.healthCheckTimeout,
+	}
+
+	for _, endpoint = range globals.endpoints {
+		err = endpoint.checkHealth(httpClient)
+		if nil == err {
+			endpoint.succeeded()
+		} else {
+			endpoint.failed(err)
+		}
+	}
+}
+
+func startHealthChecker() {
+	if 0 == globals.healthCheckInterval {
+		globals.healthCheckerStopChan = nil
+		return
+	}
+
+	globals.healthCheckerStopChan = make(chan bool, 1)
+	globals.healthCheckerWG.Add(1)
+
+	go healthChecker(globals.healthCheckerStopChan)
+}
+
+func stopHealthChecker() {
+	if nil == globals.healthCheckerStopChan {
+		return
+	}
+
+	globals.healthCheckerStopChan <- true
+	globals.healthCheckerWG.Wait()
+
+	globals.healthCheckerStopChan = nil
+}
+
+func healthChecker(stopChan chan bool) {
+	var (
+		ticker *time.Ticker
+	)
+
+	ticker = time.NewTicker(globals.healthCheckInterval)
+
+	for {
+		select {
+		case <-stopChan:
+			ticker.Stop()
+			globals.healthCheckerWG.Done()
+			return
+		case <-ticker.C:
+			checkEndpoints()
+		}
+	}
+}
+
+// idleConnections returns the LIFO of idle connections to endpoint for this pool
+//
+// Must be called with pool locked.
+func (pool *connectionPoolStruct) idleConnections(endpoint *endpointStruct) (idle *[]*connectionStruct) {
+	if pool == &globals.chunkedConnectionPool {
+		idle = &endpoint.chunkedIdle
+	} else {
+		idle = &endpoint.nonChunkedIdle
+	}
+	return
+}
+
+// popIdleConnection returns an idle connection to endpoint or, if endpoint is nil,
+// to any endpoint (nil if none are idle)
+//
+// Must be called with pool locked.
+func (pool *connectionPoolStruct) popIdleConnection(endpoint *endpointStruct) (connection *connectionStruct) {
+	var (
+		idle *[]*connectionStruct
+	)
+
+	connection = nil
+
+	if nil == endpoint {
+		for _, endpoint = range globals.endpoints {
+			connection = pool.popIdleConnection(endpoint)
+			if nil != connection {
+				return
+			}
+		}
+		return
+	}
+
+	idle = pool.idleConnections(endpoint)
+	if 0 < len(*idle) {
+		connection = (*idle)[len(*idle)-1]
+		(*idle)[len(*idle)-1] = nil
+		*idle = (*idle)[:len(*idle)-1]
+		pool.numIdle--
+	}
+
+	return
+}
+
+// pushIdleConnection returns connection to its endpoint's LIFO of idle connections
+//
+// Must be called with pool locked.
+func (pool *connectionPoolStruct) pushIdleConnection(connection *connectionStruct) {
+	var (
+		idle *[]*connectionStruct
+	)
+
+	idle = pool.idleConnections(connection.endpoint)
+	*idle = append(*idle, connection)
+	pool.numIdle++
+}
+
+// drainEndpointConnections closes all idle & reserved connections to endpoint or,
+// if endpoint is nil, to all endpoints
+func drainEndpointConnections(endpoint *endpointStruct) {
+	var (
+		connection *connectionStruct
+		pool       *connectionPoolStruct
+		volumeName string
+	)
+
+	globals.reservedChunkedConnectionMutex.Lock()
+	for volumeName, connection = range globals.reservedChunkedConnection {
+		if (nil == endpoint) || (endpoint == connection.endpoint) {
+			_ = connection.tcpConn.Close()
+			delete(globals.reservedChunkedConnection, volumeName)
+		}
+	}
+	globals.reservedChunkedConnectionMutex.Unlock()
+
+	for _, pool = range []*connectionPoolStruct{&globals.chunkedConnectionPool, &globals.nonChunkedConnectionPool} {
+		pool.Lock()
+		for {
+			connection = pool.popIdleConnection(endpoint)
+			if nil == connection {
+				break
+			}
+			_ = connection.tcpConn.Close()
+		}
+		pool.Unlock()
+	}
+}
+
+func getEndpointStarvationParameters() (endpointList []EndpointStarvationParameters) {
+	var (
+		endpoint      *endpointStruct
+		endpointIndex int
+	)
+
+	endpointList = make([]EndpointStarvationParameters, len(globals.endpoints))
+
+	globals.chunkedConnectionPool.Lock()
+	for endpointIndex, endpoint = range globals.endpoints {
+		endpointList[endpointIndex].ChunkedConnectionsIdle = uint16(len(endpoint.chunkedIdle))
+	}
+	globals.chunkedConnectionPool.Unlock()
+
+	globals.nonChunkedConnectionPool.Lock()
+	for endpointIndex, endpoint = range globals.endpoints {
+		endpointList[endpointIndex].NonChunkedConnectionsIdle = uint16(len(endpoint.nonChunkedIdle))
+	}
+	globals.nonChunkedConnectionPool.Unlock()
+
+	globals.endpointsMutex.Lock()
+	for endpointIndex, endpoint = range globals.endpoints {
+		endpointList[endpointIndex].Addr = endpoint.stringAddr
+		endpointList[endpointIndex].Healthy = endpoint.healthy
+		endpointList[endpointIndex].ConsecutiveFailures = endpoint.consecutiveFailures
+		endpointList[endpointIndex].ConnectionsInUse = endpoint.inUse
+	}
+	globals.endpointsMutex.Unlock()
+
+	return
+}
@@ -0,0 +1,130 @@
+package swiftclient
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/conf"
+)
+
+func testStartInfoServer(t *testing.T, addr string) (listener net.Listener) {
+	listener, err := net.Listen("tcp4", addr)
+	if nil != err {
+		t.Fatalf("net.Listen(\"%s\") failed: %v", addr, err)
+	}
+
+	go http.Serve(listener, http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if "/info" != request.URL.Path {
+			responseWriter.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return
+}
+
+func TestEndpoints(t *testing.T) {
+	savedEndpoints := globals.endpoints
+	savedEndpointSelection := globals.endpointSelection
+	savedHealthCheckInterval := globals.healthCheckInterval
+	savedHealthCheckTimeout := globals.healthCheckTimeout
+	savedHealthCheckFailureThreshold := globals.healthCheckFailureThreshold
+	defer func() {
+		globals.endpoints = savedEndpoints
+		globals.endpointSelection = savedEndpointSelection
+		globals.healthCheckInterval = savedHealthCheckInterval
+		globals.healthCheckTimeout = savedHealthCheckTimeout
+		globals.healthCheckFailureThreshold = savedHealthCheckFailureThreshold
+	}()
+
+	liveListener := testStartInfoServer(t, "127.0.0.1:0")
+	defer liveListener.Close()
+	deadListener := testStartInfoServer(t, "127.0.0.1:0")
+	liveAddr := liveListener.Addr().String()
+	deadAddr := deadListener.Addr().String()
+	_ = deadListener.Close()
+
+	confMap, err := conf.MakeConfMapFromStrings([]string{
+		"SwiftClient.NoAuthEndpoints=" + liveAddr + "," + deadAddr,
+		"SwiftClient.EndpointSelection=" + EndpointSelectionRoundRobin,
+		"SwiftClient.HealthCheckInterval=1h",
+		"SwiftClient.HealthCheckFailureThreshold=1",
+	})
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings() failed: %v", err)
+	}
+	err = fetchEndpoints(confMap)
+	if nil != err {
+		t.Fatalf("fetchEndpoints() failed: %v", err)
+	}
+	if 2 != len(globals.endpoints) {
+		t.Fatalf("fetchEndpoints() returned %d endpoints... expected 2", len(globals.endpoints))
+	}
+	liveEndpoint := globals.endpoints[0]
+	deadEndpoint := globals.endpoints[1]
+
+	// RoundRobin alternates between the (presumed healthy) endpoints
+
+	if (liveEndpoint != selectEndpoint()) || (deadEndpoint != selectEndpoint()) || (liveEndpoint != selectEndpoint()) {
+		t.Fatalf("selectEndpoint() should have alternated between endpoints")
+	}
+
+	// Failed health checks eject the dead endpoint
+
+	checkEndpoints()
+
+	endpointList := getEndpointStarvationParameters()
+	if (2 != len(endpointList)) || (liveAddr != endpointList[0].Addr) || !endpointList[0].Healthy || (2 != endpointList[0].ConnectionsInUse) {
+		t.Fatalf("getEndpointStarvationParameters() returned unexpected live endpoint state: %+v", endpointList)
+	}
+	if (deadAddr != endpointList[1].Addr) || endpointList[1].Healthy || (1 != endpointList[1].ConsecutiveFailures) {
+		t.Fatalf("getEndpointStarvationParameters() returned unexpected dead endpoint state: %+v", endpointList)
+	}
+
+	for i := 0; i < 4; i++ {
+		if liveEndpoint != selectEndpoint() {
+			t.Fatalf("selectEndpoint() should not have returned an ejected endpoint")
+		}
+	}
+
+	// A failed connection attempt is also counted against an endpoint
+
+	deadEndpoint.healthy = true
+	deadEndpoint.consecutiveFailures = 0
+	deadEndpoint.acquired()
+	connection := &connectionStruct{endpoint: deadEndpoint}
+	err = openConnection("TestEndpoints", connection)
+	if (nil == err) || deadEndpoint.isHealthy() {
+		t.Fatalf("openConnection() to dead endpoint should have failed and ejected it")
+	}
+	err = openConnection("TestEndpoints", connection)
+	if (nil != err) || (liveEndpoint != connection.endpoint) {
+		t.Fatalf("openConnection() should have moved to the remaining healthy endpoint: %v", err)
+	}
+	_ = connection.tcpConn.Close()
+	connection.endpoint.released()
+
+	// Passing health checks re-admit the (now revived) dead endpoint
+
+	deadListener = testStartInfoServer(t, deadAddr)
+	defer deadListener.Close()
+
+	checkEndpoints()
+
+	if !deadEndpoint.isHealthy() || (0 != deadEndpoint.consecutiveFailures) {
+		t.Fatalf("checkEndpoints() should have re-admitted the revived endpoint")
+	}
+
+	// LeastLoaded prefers the endpoint with the fewest connections in use
+
+	globals.endpointSelection = EndpointSelectionLeastLoaded
+
+	for deadEndpoint.inUse < liveEndpoint.inUse {
+		if deadEndpoint != selectEndpoint() {
+			t.Fatalf("selectEndpoint() should have returned the least loaded endpoint")
+		}
+	}
+	if liveEndpoint.inUse != deadEndpoint.inUse {
+		t.Fatalf("selectEndpoint() should have balanced the load (%d vs %d)", liveEndpoint.inUse, deadEndpoint.inUse)
+	}
+}
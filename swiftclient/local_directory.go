@@ -0,0 +1,765 @@
+// Local directory object storage backend
+
+package swiftclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/trackedlock"
+)
+
+// LocalDirectoryBackendType is the ObjectStorageBackend:<name>.Type for a Backend that stores
+// Accounts, Containers, and Objects beneath the local directory ObjectStorageBackend:<name>.Path.
+// It is intended for development and single node deployments not backed by a Swift cluster.
+//
+// The layout beneath Path is:
+//
+//   <Account>/headers.json
+//   <Account>/containers/<Container>/headers.json
+//   <Account>/containers/<Container>/objects/<Object>
+//   <Account>/containers/<Container>/tmp/                 (Objects being PUT)
+//
+// where each name has been path escaped (see localDirectoryEscape()). Header semantics mirror
+// those of Swift (and ramswift): PUT and POST merge the supplied headers with those already
+// present, and a header supplied with an empty value is removed.
+const LocalDirectoryBackendType = "LocalDirectory"
+
+const (
+	localDirectoryHeadersFileName  = "headers.json"
+	localDirectoryContainersDir    = "containers"
+	localDirectoryObjectsDir       = "objects"
+	localDirectoryTmpDir           = "tmp"
+	localDirectoryDirPerm          = os.FileMode(0700)
+	localDirectoryFilePerm         = os.FileMode(0600)
+	localDirectoryTmpObjectPattern = "put-"
+)
+
+type localDirectoryBackendStruct struct {
+	trackedlock.Mutex //      serializes Account & Container (but not Object) operations
+	path              string
+}
+
+type localDirectoryChunkedPutContextStruct struct {
+	trackedlock.Mutex
+	backend       *localDirectoryBackendStruct
+	accountName   string
+	containerName string
+	objectName    string
+	objectPath    string
+	file          *os.File // nil once Close() has been called
+	active        bool
+	bytesPut      uint64
+}
+
+func init() {
+	registerBackendType(LocalDirectoryBackendType, newLocalDirectoryBackend)
+}
+
+func newLocalDirectoryBackend(confMap conf.ConfMap, backendSectionName string) (backend Backend, err error) {
+	var (
+		localDirectoryBackend *localDirectoryBackendStruct
+		path                  string
+	)
+
+	path, err = confMap.FetchOptionValueString(backendSectionName, "Path")
+	if nil != err {
+		return
+	}
+	if "" == path {
+		err = fmt.Errorf("%s.Path must not be empty", backendSectionName)
+		return
+	}
+
+	err = os.MkdirAll(path, localDirectoryDirPerm)
+	if nil != err {
+		return
+	}
+
+	localDirectoryBackend = &localDirectoryBackendStruct{path: path}
+
+	backend = localDirectoryBackend
+	err = nil
+	return
+}
+
+func backendHTTPStatusError(httpStatus int, format string, args ...interface{}) (err error) {
+	_, fsErr := httpStatusIsError(httpStatus)
+	err = blunder.NewError(fsErr, format, args...)
+	err = blunder.AddHTTPCode(err, httpStatus)
+	return
+}
+
+// localDirectoryEscape path escapes name (see url.PathEscape()) such that it names a single entry
+// of its parent directory. As url.PathEscape() leaves "." and ".." as is, they are escaped
+// explicitly so that a name can not refer to (or beyond) its parent.
+func localDirectoryEscape(name string) string {
+	switch name {
+	case ".":
+		return "%2E"
+	case "..":
+		return "%2E%2E"
+	default:
+		return url.PathEscape(name)
+	}
+}
+
+func (backend *localDirectoryBackendStruct) accountPath(accountName string) string {
+	return filepath.Join(backend.path, localDirectoryEscape(accountName))
+}
+
+func (backend *localDirectoryBackendStruct) containerPath(accountName string, containerName string) string {
+	return filepath.Join(backend.accountPath(accountName), localDirectoryContainersDir, localDirectoryEscape(containerName))
+}
+
+func (backend *localDirectoryBackendStruct) objectPath(accountName string, containerName string, objectName string) string {
+	return filepath.Join(backend.containerPath(accountName, containerName), localDirectoryObjectsDir, localDirectoryEscape(objectName))
+}
+
+func dirExists(path string) (exists bool) {
+	fileInfo, err := os.Stat(path)
+	exists = (nil == err) && fileInfo.IsDir()
+	return
+}
+
+// listDir returns the sorted, unescaped names of the entries in the directory at path.
+func listDir(path string) (nameList []string, err error) {
+	var (
+		fileInfo     os.FileInfo
+		fileInfoList []os.FileInfo
+		name         string
+	)
+
+	fileInfoList, err = ioutil.ReadDir(path)
+	if nil != err {
+		return
+	}
+
+	nameList = make([]string, 0, len(fileInfoList))
+
+	for _, fileInfo = range fileInfoList {
+		name, err = url.PathUnescape(fileInfo.Name())
+		if nil != err {
+			return
+		}
+		nameList = append(nameList, name)
+	}
+
+	sort.Strings(nameList)
+
+	err = nil
+	return
+}
+
+func loadHeaders(dirPath string) (headers map[string][]string, err error) {
+	var (
+		buf []byte
+	)
+
+	buf, err = ioutil.ReadFile(filepath.Join(dirPath, localDirectoryHeadersFileName))
+	if nil != err {
+		return
+	}
+
+	headers = make(map[string][]string)
+
+	err = json.Unmarshal(buf, &headers)
+
+	return
+}
+
+// mergeHeaders applies newHeaders to the headers stored in dirPath following Swift semantics.
+func mergeHeaders(dirPath string, newHeaders map[string][]string) (err error) {
+	var (
+		buf         []byte
+		headerName  string
+		headerValue string
+		headers     map[string][]string
+		tmpPath     string
+		valueSlice  []string
+	)
+
+	headers, err = loadHeaders(dirPath)
+	if nil != err {
+		return
+	}
+
+	for headerName, valueSlice = range newHeaders {
+		headerName = http.CanonicalHeaderKey(headerName)
+		headers[headerName] = make([]string, 0, len(valueSlice))
+		for _, headerValue = range valueSlice {
+			if "" != headerValue {
+				headers[headerName] = append(headers[headerName], headerValue)
+			}
+		}
+		if 0 == len(headers[headerName]) {
+			delete(headers, headerName)
+		}
+	}
+
+	buf, err = json.Marshal(headers)
+	if nil != err {
+		return
+	}
+
+	tmpPath = filepath.Join(dirPath, localDirectoryHeadersFileName+".tmp")
+
+	err = ioutil.WriteFile(tmpPath, buf, localDirectoryFilePerm)
+	if nil != err {
+		return
+	}
+
+	err = os.Rename(tmpPath, filepath.Join(dirPath, localDirectoryHeadersFileName))
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) AccountDelete(accountName string) (err error) {
+	var (
+		accountPath   string
+		containerList []string
+	)
+
+	backend.Lock()
+	defer backend.Unlock()
+
+	accountPath = backend.accountPath(accountName)
+
+	if !dirExists(accountPath) {
+		err = backendHTTPStatusError(http.StatusNotFound, "DELETE %s returned HTTP StatusCode %d", accountName, http.StatusNotFound)
+		return
+	}
+
+	containerList, err = listDir(filepath.Join(accountPath, localDirectoryContainersDir))
+	if nil != err {
+		return
+	}
+	if 0 != len(containerList) {
+		err = backendHTTPStatusError(http.StatusConflict, "DELETE %s returned HTTP StatusCode %d", accountName, http.StatusConflict)
+		return
+	}
+
+	err = os.RemoveAll(accountPath)
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) AccountGet(accountName string) (headers map[string][]string, containerList []string, err error) {
+	var (
+		accountPath string
+	)
+
+	backend.Lock()
+	defer backend.Unlock()
+
+	accountPath = backend.accountPath(accountName)
+
+	if !dirExists(accountPath) {
+		err = backendHTTPStatusError(http.StatusNotFound, "GET %s returned HTTP StatusCode %d", accountName, http.StatusNotFound)
+		return
+	}
+
+	headers, err = loadHeaders(accountPath)
+	if nil != err {
+		return
+	}
+
+	containerList, err = listDir(filepath.Join(accountPath, localDirectoryContainersDir))
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) AccountHead(accountName string) (headers map[string][]string, err error) {
+	var (
+		accountPath string
+	)
+
+	backend.Lock()
+	defer backend.Unlock()
+
+	accountPath = backend.accountPath(accountName)
+
+	if !dirExists(accountPath) {
+		err = backendHTTPStatusError(http.StatusNotFound, "HEAD %s returned HTTP StatusCode %d", accountName, http.StatusNotFound)
+		return
+	}
+
+	headers, err = loadHeaders(accountPath)
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) AccountPost(accountName string, headers map[string][]string) (err error) {
+	var (
+		accountPath string
+	)
+
+	backend.Lock()
+	defer backend.Unlock()
+
+	accountPath = backend.accountPath(accountName)
+
+	if !dirExists(accountPath) {
+		err = backendHTTPStatusError(http.StatusNotFound, "POST %s returned HTTP StatusCode %d", accountName, http.StatusNotFound)
+		return
+	}
+
+	err = mergeHeaders(accountPath, headers)
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) AccountPut(accountName string, headers map[string][]string) (err error) {
+	var (
+		accountPath string
+	)
+
+	backend.Lock()
+	defer backend.Unlock()
+
+	accountPath = backend.accountPath(accountName)
+
+	if !dirExists(accountPath) {
+		err = os.MkdirAll(filepath.Join(accountPath, localDirectoryContainersDir), localDirectoryDirPerm)
+		if nil != err {
+			return
+		}
+		err = ioutil.WriteFile(filepath.Join(accountPath, localDirectoryHeadersFileName), []byte("{}"), localDirectoryFilePerm)
+		if nil != err {
+			return
+		}
+	}
+
+	err = mergeHeaders(accountPath, headers)
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) ContainerDelete(accountName string, containerName string) (err error) {
+	var (
+		containerPath string
+		objectList    []string
+	)
+
+	backend.Lock()
+	defer backend.Unlock()
+
+	containerPath = backend.containerPath(accountName, containerName)
+
+	if !dirExists(containerPath) {
+		err = backendHTTPStatusError(http.StatusNotFound, "DELETE %s/%s returned HTTP StatusCode %d", accountName, containerName, http.StatusNotFound)
+		return
+	}
+
+	objectList, err = listDir(filepath.Join(containerPath, localDirectoryObjectsDir))
+	if nil != err {
+		return
+	}
+	if 0 != len(objectList) {
+		err = backendHTTPStatusError(http.StatusConflict, "DELETE %s/%s returned HTTP StatusCode %d", accountName, containerName, http.StatusConflict)
+		return
+	}
+
+	err = os.RemoveAll(containerPath)
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) ContainerGet(accountName string, containerName string) (headers map[string][]string, objectList []string, err error) {
+	var (
+		containerPath string
+	)
+
+	backend.Lock()
+	defer backend.Unlock()
+
+	containerPath = backend.containerPath(accountName, containerName)
+
+	if !dirExists(containerPath) {
+		err = backendHTTPStatusError(http.StatusNotFound, "GET %s/%s returned HTTP StatusCode %d", accountName, containerName, http.StatusNotFound)
+		return
+	}
+
+	headers, err = loadHeaders(containerPath)
+	if nil != err {
+		return
+	}
+
+	objectList, err = listDir(filepath.Join(containerPath, localDirectoryObjectsDir))
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) ContainerHead(accountName string, containerName string) (headers map[string][]string, err error) {
+	var (
+		containerPath string
+	)
+
+	backend.Lock()
+	defer backend.Unlock()
+
+	containerPath = backend.containerPath(accountName, containerName)
+
+	if !dirExists(containerPath) {
+		err = backendHTTPStatusError(http.StatusNotFound, "HEAD %s/%s returned HTTP StatusCode %d", accountName, containerName, http.StatusNotFound)
+		return
+	}
+
+	headers, err = loadHeaders(containerPath)
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) ContainerPost(accountName string, containerName string, headers map[string][]string) (err error) {
+	var (
+		containerPath string
+	)
+
+	backend.Lock()
+	defer backend.Unlock()
+
+	containerPath = backend.containerPath(accountName, containerName)
+
+	if !dirExists(containerPath) {
+		err = backendHTTPStatusError(http.StatusNotFound, "POST %s/%s returned HTTP StatusCode %d", accountName, containerName, http.StatusNotFound)
+		return
+	}
+
+	err = mergeHeaders(containerPath, headers)
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) ContainerPut(accountName string, containerName string, headers map[string][]string) (err error) {
+	var (
+		containerPath string
+	)
+
+	backend.Lock()
+	defer backend.Unlock()
+
+	if !dirExists(backend.accountPath(accountName)) {
+		err = backendHTTPStatusError(http.StatusNotFound, "PUT %s/%s returned HTTP StatusCode %d", accountName, containerName, http.StatusNotFound)
+		return
+	}
+
+	containerPath = backend.containerPath(accountName, containerName)
+
+	if !dirExists(containerPath) {
+		err = os.MkdirAll(filepath.Join(containerPath, localDirectoryObjectsDir), localDirectoryDirPerm)
+		if nil != err {
+			return
+		}
+		err = os.MkdirAll(filepath.Join(containerPath, localDirectoryTmpDir), localDirectoryDirPerm)
+		if nil != err {
+			return
+		}
+		err = ioutil.WriteFile(filepath.Join(containerPath, localDirectoryHeadersFileName), []byte("{}"), localDirectoryFilePerm)
+		if nil != err {
+			return
+		}
+	}
+
+	err = mergeHeaders(containerPath, headers)
+
+	return
+}
+
+// openObject opens the named Object returning its size. A missing Object (or Container or
+// Account) is reported as an HTTP StatusNotFound error.
+func (backend *localDirectoryBackendStruct) openObject(method string, accountName string, containerName string, objectName string) (file *os.File, size uint64, err error) {
+	var (
+		fileInfo os.FileInfo
+	)
+
+	file, err = os.Open(backend.objectPath(accountName, containerName, objectName))
+	if nil != err {
+		if os.IsNotExist(err) {
+			err = backendHTTPStatusError(http.StatusNotFound, "%s %s/%s/%s returned HTTP StatusCode %d", method, accountName, containerName, objectName, http.StatusNotFound)
+		}
+		return
+	}
+
+	fileInfo, err = file.Stat()
+	if nil != err {
+		_ = file.Close()
+		return
+	}
+
+	size = uint64(fileInfo.Size())
+
+	return
+}
+
+// readObject returns up to length bytes of the named Object starting at offset. As with
+// Swift, an offset at or beyond the end of the Object is reported as an HTTP
+// StatusRequestedRangeNotSatisfiable error.
+func (backend *localDirectoryBackendStruct) readObject(accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error) {
+	var (
+		file *os.File
+		n    int
+		size uint64
+	)
+
+	file, size, err = backend.openObject("GET", accountName, containerName, objectName)
+	if nil != err {
+		return
+	}
+	defer file.Close()
+
+	if offset >= size {
+		err = backendHTTPStatusError(http.StatusRequestedRangeNotSatisfiable, "GET %s/%s/%s returned HTTP StatusCode %d", accountName, containerName, objectName, http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	if length > (size - offset) {
+		length = size - offset
+	}
+
+	buf = make([]byte, length)
+
+	n, err = file.ReadAt(buf, int64(offset))
+	if (nil != err) && (io.EOF != err) {
+		return
+	}
+
+	buf = buf[:n]
+	err = nil
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) ObjectContentLength(accountName string, containerName string, objectName string) (length uint64, err error) {
+	var (
+		file *os.File
+	)
+
+	file, length, err = backend.openObject("HEAD", accountName, containerName, objectName)
+	if nil != err {
+		return
+	}
+
+	err = file.Close()
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) ObjectDelete(accountName string, containerName string, objectName string, operationOptions OperationOptions) (err error) {
+	err = os.Remove(backend.objectPath(accountName, containerName, objectName))
+	if (nil != err) && os.IsNotExist(err) {
+		err = backendHTTPStatusError(http.StatusNotFound, "DELETE %s/%s/%s returned HTTP StatusCode %d", accountName, containerName, objectName, http.StatusNotFound)
+	}
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) ObjectFetchChunkedPutContext(accountName string, containerName string, objectName string, useReserveForVolumeName string) (chunkedPutContext ChunkedPutContext, err error) {
+	var (
+		containerPath string
+		file          *os.File
+	)
+
+	containerPath = backend.containerPath(accountName, containerName)
+
+	if !dirExists(containerPath) {
+		err = backendHTTPStatusError(http.StatusNotFound, "PUT %s/%s/%s returned HTTP StatusCode %d", accountName, containerName, objectName, http.StatusNotFound)
+		return
+	}
+
+	file, err = ioutil.TempFile(filepath.Join(containerPath, localDirectoryTmpDir), localDirectoryTmpObjectPattern)
+	if nil != err {
+		return
+	}
+
+	chunkedPutContext = &localDirectoryChunkedPutContextStruct{
+		backend:       backend,
+		accountName:   accountName,
+		containerName: containerName,
+		objectName:    objectName,
+		objectPath:    backend.objectPath(accountName, containerName, objectName),
+		file:          file,
+		active:        true,
+		bytesPut:      0,
+	}
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) ObjectGet(accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error) {
+	buf, err = backend.readObject(accountName, containerName, objectName, offset, length)
+	return
+}
+
+func (backend *localDirectoryBackendStruct) ObjectHead(accountName string, containerName string, objectName string) (headers map[string][]string, err error) {
+	var (
+		length uint64
+	)
+
+	length, err = backend.ObjectContentLength(accountName, containerName, objectName)
+	if nil != err {
+		return
+	}
+
+	headers = map[string][]string{"Content-Length": {strconv.FormatUint(length, 10)}}
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) ObjectLoad(accountName string, containerName string, objectName string) (buf []byte, err error) {
+	buf, err = ioutil.ReadFile(backend.objectPath(accountName, containerName, objectName))
+	if (nil != err) && os.IsNotExist(err) {
+		err = backendHTTPStatusError(http.StatusNotFound, "GET %s/%s/%s returned HTTP StatusCode %d", accountName, containerName, objectName, http.StatusNotFound)
+	}
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) ObjectRead(accountName string, containerName string, objectName string, offset uint64, buf []byte) (length uint64, err error) {
+	var (
+		readBuf []byte
+	)
+
+	readBuf, err = backend.readObject(accountName, containerName, objectName, offset, uint64(len(buf)))
+	if nil != err {
+		return
+	}
+
+	length = uint64(copy(buf, readBuf))
+
+	return
+}
+
+func (backend *localDirectoryBackendStruct) ObjectTail(accountName string, containerName string, objectName string, length uint64) (buf []byte, err error) {
+	var (
+		file *os.File
+		size uint64
+	)
+
+	file, size, err = backend.openObject("GET", accountName, containerName, objectName)
+	if nil != err {
+		return
+	}
+	defer file.Close()
+
+	if length > size {
+		length = size
+	}
+
+	buf = make([]byte, length)
+
+	_, err = file.ReadAt(buf, int64(size-length))
+	if io.EOF == err {
+		err = nil
+	}
+
+	return
+}
+
+func (chunkedPutContext *localDirectoryChunkedPutContextStruct) Active() (active bool) {
+	chunkedPutContext.Lock()
+	active = chunkedPutContext.active
+	chunkedPutContext.Unlock()
+	return
+}
+
+func (chunkedPutContext *localDirectoryChunkedPutContextStruct) BytesPut() (bytesPut uint64, err error) {
+	chunkedPutContext.Lock()
+	bytesPut = chunkedPutContext.bytesPut
+	chunkedPutContext.Unlock()
+	err = nil
+	return
+}
+
+func (chunkedPutContext *localDirectoryChunkedPutContextStruct) Close() (err error) {
+	var (
+		file *os.File
+	)
+
+	chunkedPutContext.Lock()
+	defer chunkedPutContext.Unlock()
+
+	file = chunkedPutContext.file
+	if nil == file {
+		err = fmt.Errorf("swiftclient.localDirectoryChunkedPutContextStruct.Close() called for %s/%s/%s more than once",
+			chunkedPutContext.accountName, chunkedPutContext.containerName, chunkedPutContext.objectName)
+		return
+	}
+
+	chunkedPutContext.file = nil
+	chunkedPutContext.active = false
+
+	err = file.Close()
+	if nil != err {
+		_ = os.Remove(file.Name())
+		return
+	}
+
+	err = os.Rename(file.Name(), chunkedPutContext.objectPath)
+	if nil != err {
+		_ = os.Remove(file.Name())
+	}
+
+	return
+}
+
+func (chunkedPutContext *localDirectoryChunkedPutContextStruct) Read(offset uint64, length uint64) (buf []byte, err error) {
+	var (
+		n int
+	)
+
+	chunkedPutContext.Lock()
+	defer chunkedPutContext.Unlock()
+
+	if nil == chunkedPutContext.file {
+		err = fmt.Errorf("swiftclient.localDirectoryChunkedPutContextStruct.Read() called for %s/%s/%s after Close()",
+			chunkedPutContext.accountName, chunkedPutContext.containerName, chunkedPutContext.objectName)
+		return
+	}
+	if (offset + length) > chunkedPutContext.bytesPut {
+		err = blunder.NewError(blunder.BadHTTPGetError, "swiftclient.localDirectoryChunkedPutContextStruct.Read() called for %s/%s/%s with offset (0x%X) + length (0x%X) > bytesPut (0x%X)",
+			chunkedPutContext.accountName, chunkedPutContext.containerName, chunkedPutContext.objectName, offset, length, chunkedPutContext.bytesPut)
+		return
+	}
+
+	buf = make([]byte, length)
+
+	n, err = chunkedPutContext.file.ReadAt(buf, int64(offset))
+	if (nil != err) && (io.EOF != err) {
+		return
+	}
+
+	buf = buf[:n]
+	err = nil
+
+	return
+}
+
+func (chunkedPutContext *localDirectoryChunkedPutContextStruct) SendChunk(buf []byte) (err error) {
+	var (
+		n int
+	)
+
+	chunkedPutContext.Lock()
+	defer chunkedPutContext.Unlock()
+
+	if nil == chunkedPutContext.file {
+		err = fmt.Errorf("swiftclient.localDirectoryChunkedPutContextStruct.SendChunk() called for %s/%s/%s after Close()",
+			chunkedPutContext.accountName, chunkedPutContext.containerName, chunkedPutContext.objectName)
+		return
+	}
+
+	n, err = chunkedPutContext.file.WriteAt(buf, int64(chunkedPutContext.bytesPut))
+	chunkedPutContext.bytesPut += uint64(n)
+
+	return
+}
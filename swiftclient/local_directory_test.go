@@ -0,0 +1,225 @@
+package swiftclient
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+)
+
+func TestLocalDirectoryBackend(t *testing.T) {
+	var (
+		accountName   = "AUTH_local"
+		containerName = "container"
+		objectName    = "dir/object"
+		copyName      = "copy"
+	)
+
+	testDir, err := ioutil.TempDir("", "swiftclient_local_directory_test")
+	if nil != err {
+		t.Fatalf("ioutil.TempDir() failed: %v", err)
+	}
+	defer os.RemoveAll(testDir)
+
+	confMap, err := conf.MakeConfMapFromStrings([]string{
+		"ObjectStorageBackend:Local.Type=" + LocalDirectoryBackendType,
+		"ObjectStorageBackend:Local.Path=" + testDir,
+		"Volume:LocalVolume.AccountName=" + accountName,
+		"Volume:SwiftVolume.AccountName=AUTH_swift",
+		"VolumeGroup:LocalVolumeGroup.VolumeList=LocalVolume",
+		"VolumeGroup:LocalVolumeGroup.ObjectStorageBackend=Local",
+	})
+	if nil != err {
+		t.Fatalf("conf.MakeConfMapFromStrings() failed: %v", err)
+	}
+
+	err = updateBackends(confMap)
+	if nil != err {
+		t.Fatalf("updateBackends() failed: %v", err)
+	}
+	defer func() {
+		backends.Lock()
+		backends.accountMap = nil
+		backends.Unlock()
+	}()
+
+	if swiftBackend != backendForAccount("AUTH_swift") {
+		t.Fatalf("backendForAccount(\"AUTH_swift\") should have returned the Swift Backend")
+	}
+	if _, ok := backendForAccount(accountName).(*localDirectoryBackendStruct); !ok {
+		t.Fatalf("backendForAccount(\"%s\") should have returned a LocalDirectory Backend", accountName)
+	}
+
+	// Account & Container operations (via the package-level funcs)
+
+	_, err = AccountHead(accountName)
+	if !blunder.Is(err, blunder.NotFoundError) || (404 != blunder.HTTPCode(err)) {
+		t.Fatalf("AccountHead() of missing Account should have failed with 404: %v", err)
+	}
+	err = AccountPut(accountName, map[string][]string{"X-Account-Meta-Test": {"a"}, "X-Account-Meta-Gone": {"b"}})
+	if nil != err {
+		t.Fatalf("AccountPut() failed: %v", err)
+	}
+	err = AccountPost(accountName, map[string][]string{"X-Account-Meta-Gone": {""}})
+	if nil != err {
+		t.Fatalf("AccountPost() failed: %v", err)
+	}
+	headers, err := AccountHead(accountName)
+	if nil != err {
+		t.Fatalf("AccountHead() failed: %v", err)
+	}
+	if (1 != len(headers)) || (1 != len(headers["X-Account-Meta-Test"])) || ("a" != headers["X-Account-Meta-Test"][0]) {
+		t.Fatalf("AccountHead() returned unexpected headers: %v", headers)
+	}
+
+	err = ContainerPost(accountName, containerName, map[string][]string{})
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("ContainerPost() of missing Container should have failed with 404: %v", err)
+	}
+	err = ContainerPut(accountName, containerName, map[string][]string{"x-container-meta-checkpoint": {"1 2 3 4"}})
+	if nil != err {
+		t.Fatalf("ContainerPut() failed: %v", err)
+	}
+	headers, err = ContainerHead(accountName, containerName)
+	if (nil != err) || ("1 2 3 4" != headers["X-Container-Meta-Checkpoint"][0]) {
+		t.Fatalf("ContainerHead() returned unexpected headers (%v) or error: %v", headers, err)
+	}
+	_, containerList, err := AccountGet(accountName)
+	if (nil != err) || (1 != len(containerList)) || (containerName != containerList[0]) {
+		t.Fatalf("AccountGet() returned unexpected containerList (%v) or error: %v", containerList, err)
+	}
+
+	// Object operations
+
+	chunkedPutContext, err := ObjectFetchChunkedPutContext(accountName, containerName, objectName, "")
+	if nil != err {
+		t.Fatalf("ObjectFetchChunkedPutContext() failed: %v", err)
+	}
+	err = chunkedPutContext.SendChunk([]byte("0123"))
+	if nil != err {
+		t.Fatalf("SendChunk() failed: %v", err)
+	}
+	err = chunkedPutContext.SendChunk([]byte("456789"))
+	if nil != err {
+		t.Fatalf("SendChunk() failed: %v", err)
+	}
+	buf, err := chunkedPutContext.Read(2, 4)
+	if (nil != err) || !bytes.Equal([]byte("2345"), buf) {
+		t.Fatalf("chunkedPutContext.Read() returned unexpected buf (%s) or error: %v", buf, err)
+	}
+	_, err = ObjectHead(accountName, containerName, objectName)
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("ObjectHead() of Object not yet Close()'d should have failed with 404: %v", err)
+	}
+	err = chunkedPutContext.Close()
+	if nil != err {
+		t.Fatalf("chunkedPutContext.Close() failed: %v", err)
+	}
+
+	length, err := ObjectContentLength(accountName, containerName, objectName)
+	if (nil != err) || (10 != length) {
+		t.Fatalf("ObjectContentLength() returned unexpected length (%v) or error: %v", length, err)
+	}
+	buf, err = ObjectGet(accountName, containerName, objectName, 8, 5)
+	if (nil != err) || !bytes.Equal([]byte("89"), buf) {
+		t.Fatalf("ObjectGet() returned unexpected buf (%s) or error: %v", buf, err)
+	}
+	_, err = ObjectGet(accountName, containerName, objectName, 10, 1)
+	if 416 != blunder.HTTPCode(err) {
+		t.Fatalf("ObjectGet() beyond end of Object should have failed with 416: %v", err)
+	}
+	buf = make([]byte, 3)
+	length, err = ObjectRead(accountName, containerName, objectName, 1, buf)
+	if (nil != err) || (3 != length) || !bytes.Equal([]byte("123"), buf) {
+		t.Fatalf("ObjectRead() returned unexpected buf (%s) or error: %v", buf, err)
+	}
+	buf, err = ObjectTail(accountName, containerName, objectName, 3)
+	if (nil != err) || !bytes.Equal([]byte("789"), buf) {
+		t.Fatalf("ObjectTail() returned unexpected buf (%s) or error: %v", buf, err)
+	}
+
+	err = ObjectCopy(accountName, containerName, objectName, accountName, containerName, copyName, &testObjectCopyCallbackStruct{chunkSize: 4})
+	if nil != err {
+		t.Fatalf("ObjectCopy() failed: %v", err)
+	}
+	buf, err = ObjectLoad(accountName, containerName, copyName)
+	if (nil != err) || !bytes.Equal([]byte("0123456789"), buf) {
+		t.Fatalf("ObjectLoad() returned unexpected buf (%s) or error: %v", buf, err)
+	}
+
+	_, objectList, err := ContainerGet(accountName, containerName)
+	if (nil != err) || (2 != len(objectList)) || (copyName != objectList[0]) || (objectName != objectList[1]) {
+		t.Fatalf("ContainerGet() returned unexpected objectList (%v) or error: %v", objectList, err)
+	}
+
+	// Names "." and ".." must name Objects (not their parent or grandparent directories)
+
+	for _, dotName := range []string{".", ".."} {
+		chunkedPutContext, err = ObjectFetchChunkedPutContext(accountName, containerName, dotName, "")
+		if nil != err {
+			t.Fatalf("ObjectFetchChunkedPutContext(\"%s\") failed: %v", dotName, err)
+		}
+		err = chunkedPutContext.SendChunk([]byte(dotName))
+		if nil != err {
+			t.Fatalf("SendChunk() to \"%s\" failed: %v", dotName, err)
+		}
+		err = chunkedPutContext.Close()
+		if nil != err {
+			t.Fatalf("chunkedPutContext.Close() of \"%s\" failed: %v", dotName, err)
+		}
+		buf, err = ObjectLoad(accountName, containerName, dotName)
+		if (nil != err) || !bytes.Equal([]byte(dotName), buf) {
+			t.Fatalf("ObjectLoad(\"%s\") returned unexpected buf (%s) or error: %v", dotName, buf, err)
+		}
+	}
+	_, dotObjectList, err := ContainerGet(accountName, containerName)
+	if (nil != err) || (4 != len(dotObjectList)) || ("." != dotObjectList[0]) || (".." != dotObjectList[1]) {
+		t.Fatalf("ContainerGet() returned unexpected objectList (%v) or error: %v", dotObjectList, err)
+	}
+	for _, dotName := range []string{".", ".."} {
+		err = ObjectDelete(accountName, containerName, dotName, 0)
+		if nil != err {
+			t.Fatalf("ObjectDelete(\"%s\") failed: %v", dotName, err)
+		}
+	}
+	err = ContainerPut(accountName, "..", map[string][]string{})
+	if nil != err {
+		t.Fatalf("ContainerPut(\"..\") failed: %v", err)
+	}
+	_, containerList, err = AccountGet(accountName)
+	if (nil != err) || (2 != len(containerList)) || (".." != containerList[0]) || (containerName != containerList[1]) {
+		t.Fatalf("AccountGet() returned unexpected containerList (%v) or error: %v", containerList, err)
+	}
+	err = ContainerDelete(accountName, "..")
+	if nil != err {
+		t.Fatalf("ContainerDelete(\"..\") failed: %v", err)
+	}
+
+	// Cleanup
+
+	err = ContainerDelete(accountName, containerName)
+	if !blunder.Is(err, blunder.NotEmptyError) {
+		t.Fatalf("ContainerDelete() of non-empty Container should have failed with 409: %v", err)
+	}
+	for _, name := range objectList {
+		err = ObjectDelete(accountName, containerName, name, 0)
+		if nil != err {
+			t.Fatalf("ObjectDelete() failed: %v", err)
+		}
+	}
+	err = ObjectDelete(accountName, containerName, objectName, 0)
+	if !blunder.Is(err, blunder.NotFoundError) {
+		t.Fatalf("ObjectDelete() of missing Object should have failed with 404: %v", err)
+	}
+	err = ContainerDelete(accountName, containerName)
+	if nil != err {
+		t.Fatalf("ContainerDelete() failed: %v", err)
+	}
+	err = AccountDelete(accountName)
+	if nil != err {
+		t.Fatalf("AccountDelete() failed: %v", err)
+	}
+}
@@ -0,0 +1,1010 @@
+// S3-compatible object storage backend
+
+package swiftclient
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/swiftstack/ProxyFS/blunder"
+	"github.com/swiftstack/ProxyFS/conf"
+	"github.com/swiftstack/ProxyFS/logger"
+	"github.com/swiftstack/ProxyFS/trackedlock"
+)
+
+// S3BackendType is the ObjectStorageBackend:<name>.Type for a Backend that stores Accounts,
+// Containers, and Objects in an S3-compatible object store at ObjectStorageBackend:<name>.Endpoint.
+//
+// Each Account maps to a Bucket named BucketPrefix + <Account> (lower-cased and with any '_'
+// replaced by '-' to conform to S3 Bucket naming rules). Within that Bucket:
+//
+//   .proxyfs-account             holds the Account's headers (as S3 user metadata)
+//   <Container>/                 holds the Container's headers (e.g. the headhunter checkpoint)
+//   <Container>/<Object>         holds an Object's contents
+//
+// Chunked PUTs are performed via S3 Multipart Upload once more than MultipartPartSize bytes
+// have been sent (smaller Objects are simply PUT on Close()). Requests are signed with AWS
+// Signature Version 4 if AccessKeyID is specified.
+const S3BackendType = "S3"
+
+const (
+	s3AccountMarkerKey          = ".proxyfs-account"
+	s3ContainerDelimiter        = "/"
+	s3MetadataHeaderPrefix      = "X-Amz-Meta-"
+	s3DefaultRegion             = "us-east-1"
+	s3DefaultMultipartPartSize  = uint64(5 * 1024 * 1024)
+	s3DefaultTimeout            = 10 * time.Second
+	s3EmptyPayloadHash          = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3SignatureAlgorithm        = "AWS4-HMAC-SHA256"
+	s3AmzDateFormat             = "20060102T150405Z"
+	s3AmzShortDateFormat        = "20060102"
+	s3BucketAlreadyOwnedByYou   = "BucketAlreadyOwnedByYou"
+	s3CompleteMultipartXMLStart = "<CompleteMultipartUpload>"
+	s3CompleteMultipartXMLStop  = "</CompleteMultipartUpload>"
+)
+
+type s3BackendStruct struct {
+	endpoint          string // e.g. "http://127.0.0.1:9000" (no trailing '/')
+	region            string
+	accessKeyID       string // if "", requests are not signed
+	secretAccessKey   string
+	bucketPrefix      string
+	multipartPartSize uint64
+	retryLimit        uint16
+	retryDelay        time.Duration
+	retryExpBackoff   float64
+	httpClient        *http.Client
+}
+
+type s3ChunkedPutContextStruct struct {
+	trackedlock.Mutex
+	backend       *s3BackendStruct
+	bucketName    string
+	key           string
+	path          string   // "<accountName>/<containerName>/<objectName>" for error messages
+	buf           []byte   // all bytes sent via SendChunk() (retained to satisfy Read())
+	bytesUploaded uint64   // prefix of buf already sent as Multipart Upload parts
+	uploadID      string   // "" until first part is uploaded
+	partETagList  []string // ETag of each part uploaded so far
+	active        bool
+}
+
+type s3ErrorStruct struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+type s3ListBucketResultStruct struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	IsTruncated           bool     `xml:"IsTruncated"`
+	NextContinuationToken string   `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+type s3InitiateMultipartUploadResultStruct struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	UploadID string   `xml:"UploadId"`
+}
+
+func init() {
+	registerBackendType(S3BackendType, newS3Backend)
+}
+
+func newS3Backend(confMap conf.ConfMap, backendSectionName string) (backend Backend, err error) {
+	var (
+		s3Backend *s3BackendStruct
+		timeout   time.Duration
+	)
+
+	s3Backend = &s3BackendStruct{}
+
+	s3Backend.endpoint, err = confMap.FetchOptionValueString(backendSectionName, "Endpoint")
+	if nil != err {
+		return
+	}
+	s3Backend.endpoint = strings.TrimSuffix(s3Backend.endpoint, "/")
+	if !strings.HasPrefix(s3Backend.endpoint, "http://") && !strings.HasPrefix(s3Backend.endpoint, "https://") {
+		err = fmt.Errorf("%s.Endpoint (\"%s\") must begin with http:// or https://", backendSectionName, s3Backend.endpoint)
+		return
+	}
+
+	s3Backend.region, err = confMap.FetchOptionValueString(backendSectionName, "Region")
+	if (nil != err) || ("" == s3Backend.region) {
+		s3Backend.region = s3DefaultRegion
+	}
+
+	s3Backend.accessKeyID, err = confMap.FetchOptionValueString(backendSectionName, "AccessKeyID")
+	if nil != err {
+		s3Backend.accessKeyID = ""
+	}
+	if "" != s3Backend.accessKeyID {
+		s3Backend.secretAccessKey, err = confMap.FetchOptionValueString(backendSectionName, "SecretAccessKey")
+		if nil != err {
+			return
+		}
+	}
+
+	s3Backend.bucketPrefix, err = confMap.FetchOptionValueString(backendSectionName, "BucketPrefix")
+	if nil != err {
+		s3Backend.bucketPrefix = ""
+	}
+
+	s3Backend.multipartPartSize, err = confMap.FetchOptionValueUint64(backendSectionName, "MultipartPartSize")
+	if nil != err {
+		s3Backend.multipartPartSize = s3DefaultMultipartPartSize
+	}
+	if 0 == s3Backend.multipartPartSize {
+		err = fmt.Errorf("%s.MultipartPartSize must be non-zero", backendSectionName)
+		return
+	}
+
+	timeout, err = confMap.FetchOptionValueDuration(backendSectionName, "Timeout")
+	if nil != err {
+		timeout = s3DefaultTimeout
+	}
+
+	// Absent overrides, retry as for Swift Object requests
+
+	s3Backend.retryLimit, err = confMap.FetchOptionValueUint16(backendSectionName, "RetryLimit")
+	if nil != err {
+		s3Backend.retryLimit = globals.retryLimitObject
+	}
+	s3Backend.retryDelay, err = confMap.FetchOptionValueDuration(backendSectionName, "RetryDelay")
+	if nil != err {
+		s3Backend.retryDelay = globals.retryDelayObject
+	}
+	s3Backend.retryExpBackoff, err = confMap.FetchOptionValueFloat64(backendSectionName, "RetryExpBackoff")
+	if nil != err {
+		s3Backend.retryExpBackoff = globals.retryExpBackoffObject
+	}
+
+	s3Backend.httpClient = &http.Client{Timeout: timeout}
+
+	backend = s3Backend
+	err = nil
+	return
+}
+
+// s3URIEncode encodes s as required for AWS Signature Version 4 canonical requests (which
+// is also a valid encoding for the request itself).
+func s3URIEncode(s string, encodeSlash bool) string {
+	var (
+		b         strings.Builder
+		c         byte
+		i         int
+		hexDigits = "0123456789ABCDEF"
+	)
+
+	for i = 0; i < len(s); i++ {
+		c = s[i]
+		if (('A' <= c) && (c <= 'Z')) || (('a' <= c) && (c <= 'z')) || (('0' <= c) && (c <= '9')) || ('-' == c) || ('.' == c) || ('_' == c) || ('~' == c) || (('/' == c) && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteByte(hexDigits[c>>4])
+			b.WriteByte(hexDigits[c&0xF])
+		}
+	}
+
+	return b.String()
+}
+
+// s3CanonicalQueryString returns query encoded with sorted keys as required for both
+// AWS Signature Version 4 canonical requests and the request itself.
+func s3CanonicalQueryString(query url.Values) string {
+	var (
+		key       string
+		keyList   []string
+		valueList []string
+		pairList  []string
+		value     string
+	)
+
+	keyList = make([]string, 0, len(query))
+	for key = range query {
+		keyList = append(keyList, key)
+	}
+	sort.Strings(keyList)
+
+	pairList = make([]string, 0, len(query))
+	for _, key = range keyList {
+		valueList = append([]string{}, query[key]...)
+		sort.Strings(valueList)
+		for _, value = range valueList {
+			pairList = append(pairList, s3URIEncode(key, true)+"="+s3URIEncode(value, true))
+		}
+	}
+
+	return strings.Join(pairList, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// s3SignRequest adds AWS Signature Version 4 headers to request. The signed headers are
+// Host, Range (if present), and all X-Amz-* headers. The request's URL must have been
+// constructed from canonicalURI & canonicalQuery.
+func s3SignRequest(request *http.Request, canonicalURI string, canonicalQuery string, payloadHash string, region string, accessKeyID string, secretAccessKey string, now time.Time) {
+	var (
+		amzDate             string
+		canonicalHeaderList []string
+		canonicalRequest    string
+		headerName          string
+		headerNameList      []string
+		scope               string
+		shortDate           string
+		signature           string
+		signingKey          []byte
+		stringToSign        string
+	)
+
+	amzDate = now.UTC().Format(s3AmzDateFormat)
+	shortDate = now.UTC().Format(s3AmzShortDateFormat)
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headerNameList = []string{"host"}
+	for headerName = range request.Header {
+		headerName = strings.ToLower(headerName)
+		if strings.HasPrefix(headerName, "x-amz-") || ("range" == headerName) {
+			headerNameList = append(headerNameList, headerName)
+		}
+	}
+	sort.Strings(headerNameList)
+
+	canonicalHeaderList = make([]string, 0, len(headerNameList))
+	for _, headerName = range headerNameList {
+		if "host" == headerName {
+			canonicalHeaderList = append(canonicalHeaderList, "host:"+request.URL.Host)
+		} else {
+			canonicalHeaderList = append(canonicalHeaderList, headerName+":"+strings.TrimSpace(strings.Join(request.Header[http.CanonicalHeaderKey(headerName)], ",")))
+		}
+	}
+
+	canonicalRequest = strings.Join([]string{
+		request.Method,
+		canonicalURI,
+		canonicalQuery,
+		strings.Join(canonicalHeaderList, "\n") + "\n",
+		strings.Join(headerNameList, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope = shortDate + "/" + region + "/s3/aws4_request"
+
+	stringToSign = s3SignatureAlgorithm + "\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey = hmacSHA256([]byte("AWS4"+secretAccessKey), shortDate)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+
+	signature = hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", s3SignatureAlgorithm+" Credential="+accessKeyID+"/"+scope+", SignedHeaders="+strings.Join(headerNameList, ";")+", Signature="+signature)
+}
+
+func (backend *s3BackendStruct) bucketName(accountName string) string {
+	return strings.Replace(strings.ToLower(backend.bucketPrefix+accountName), "_", "-", -1)
+}
+
+// s3Error converts a non-2xx S3 response into a blunder error annotated with the HTTP status.
+func s3Error(method string, path string, httpStatus int, responseBody []byte) (err error) {
+	var (
+		fsErr   blunder.FsError
+		s3Err   s3ErrorStruct
+		s3Code  string
+		xmlErr  error
+		isError bool
+	)
+
+	xmlErr = xml.Unmarshal(responseBody, &s3Err)
+	if nil == xmlErr {
+		s3Code = s3Err.Code
+	}
+
+	isError, fsErr = httpStatusIsError(httpStatus)
+	if !isError {
+		// e.g. a CompleteMultipartUpload that returned 200 OK but with an <Error> body
+		fsErr = blunder.IOError
+	}
+
+	err = blunder.NewError(fsErr, "%s %s returned HTTP StatusCode %d (%s)", method, path, httpStatus, s3Code)
+	err = blunder.AddHTTPCode(err, httpStatus)
+
+	return
+}
+
+// request issues an S3 request (with retries for connection failures and 5xx responses)
+// returning the response headers and body of a 2xx response or an error otherwise.
+func (backend *s3BackendStruct) request(method string, bucketName string, key string, query url.Values, headers http.Header, body []byte, path string) (responseHeaders http.Header, responseBody []byte, err error) {
+	var (
+		canonicalQuery string
+		canonicalURI   string
+		httpRequest    *http.Request
+		httpResponse   *http.Response
+		httpStatus     int
+		headerName     string
+		payloadHash    string
+		requestURL     string
+		retryCtrl      *RetryCtrl
+		retriable      bool
+	)
+
+	canonicalURI = "/" + s3URIEncode(bucketName, true)
+	if "" != key {
+		canonicalURI += "/" + s3URIEncode(key, false)
+	}
+	canonicalQuery = s3CanonicalQueryString(query)
+
+	requestURL = backend.endpoint + canonicalURI
+	if "" != canonicalQuery {
+		requestURL += "?" + canonicalQuery
+	}
+
+	if 0 == len(body) {
+		payloadHash = s3EmptyPayloadHash
+	} else {
+		payloadHash = sha256Hex(body)
+	}
+
+	retryCtrl = NewRetryCtrl(backend.retryLimit, backend.retryDelay, backend.retryExpBackoff)
+
+	for {
+		httpRequest, err = http.NewRequest(method, requestURL, bytes.NewReader(body))
+		if nil != err {
+			return
+		}
+		for headerName = range headers {
+			httpRequest.Header[headerName] = headers[headerName]
+		}
+		httpRequest.ContentLength = int64(len(body))
+
+		if "" != backend.accessKeyID {
+			s3SignRequest(httpRequest, canonicalURI, canonicalQuery, payloadHash, backend.region, backend.accessKeyID, backend.secretAccessKey, time.Now())
+		}
+
+		httpResponse, err = backend.httpClient.Do(httpRequest)
+		if nil == err {
+			responseBody, err = ioutil.ReadAll(httpResponse.Body)
+			_ = httpResponse.Body.Close()
+		}
+		if nil != err {
+			err = blunder.NewError(blunder.IOError, "%s %s failed: %v", method, path, err)
+			retriable = true
+		} else {
+			httpStatus = httpResponse.StatusCode
+			if httpStatusIsSuccess(httpStatus) {
+				responseHeaders = httpResponse.Header
+				return
+			}
+			err = s3Error(method, path, httpStatus, responseBody)
+			retriable = (500 <= httpStatus)
+		}
+
+		if !retriable || (retryCtrl.attemptCnt >= retryCtrl.attemptMax) {
+			return
+		}
+
+		retryCtrl.attemptCnt++
+		logger.WarnfWithError(err, "swiftclient.s3BackendStruct.request() retrying %s %s (attempt %d)", method, path, retryCtrl.attemptCnt)
+		retryCtrl.RetryWait()
+	}
+}
+
+// swiftHeadersToS3Metadata converts Swift headers to the S3 user metadata representing them.
+func swiftHeadersToS3Metadata(headers map[string][]string) (metadata http.Header) {
+	metadata = make(http.Header)
+	for headerName, headerValueSlice := range headers {
+		for _, headerValue := range headerValueSlice {
+			metadata.Add(s3MetadataHeaderPrefix+http.CanonicalHeaderKey(headerName), headerValue)
+		}
+	}
+	return
+}
+
+// s3MetadataToSwiftHeaders extracts the Swift headers represented by S3 user metadata.
+func s3MetadataToSwiftHeaders(responseHeaders http.Header) (headers map[string][]string) {
+	headers = make(map[string][]string)
+	for headerName, headerValueSlice := range responseHeaders {
+		if strings.HasPrefix(headerName, s3MetadataHeaderPrefix) {
+			headers[http.CanonicalHeaderKey(strings.TrimPrefix(headerName, s3MetadataHeaderPrefix))] = headerValueSlice
+		}
+	}
+	return
+}
+
+// fetchMarkerHeaders returns the Swift headers recorded on the marker object at key.
+func (backend *s3BackendStruct) fetchMarkerHeaders(bucketName string, key string, path string) (headers map[string][]string, err error) {
+	var (
+		responseHeaders http.Header
+	)
+
+	responseHeaders, _, err = backend.request(http.MethodHead, bucketName, key, nil, nil, nil, path)
+	if nil != err {
+		return
+	}
+
+	headers = s3MetadataToSwiftHeaders(responseHeaders)
+
+	return
+}
+
+// mergeMarkerHeaders applies newHeaders (following Swift semantics) to the marker object at
+// key. If mustExist is false, a missing marker object is created.
+func (backend *s3BackendStruct) mergeMarkerHeaders(bucketName string, key string, newHeaders map[string][]string, mustExist bool, path string) (err error) {
+	var (
+		headerName  string
+		headerValue string
+		headers     map[string][]string
+		valueSlice  []string
+	)
+
+	headers, err = backend.fetchMarkerHeaders(bucketName, key, path)
+	if nil != err {
+		if mustExist || !blunder.Is(err, blunder.NotFoundError) {
+			return
+		}
+		headers = make(map[string][]string)
+	}
+
+	for headerName, valueSlice = range newHeaders {
+		headerName = http.CanonicalHeaderKey(headerName)
+		headers[headerName] = make([]string, 0, len(valueSlice))
+		for _, headerValue = range valueSlice {
+			if "" != headerValue {
+				headers[headerName] = append(headers[headerName], headerValue)
+			}
+		}
+		if 0 == len(headers[headerName]) {
+			delete(headers, headerName)
+		}
+	}
+
+	_, _, err = backend.request(http.MethodPut, bucketName, key, nil, swiftHeadersToS3Metadata(headers), nil, path)
+
+	return
+}
+
+// list returns the keys (and, if delimiter is non-empty, common prefixes) beginning with prefix.
+func (backend *s3BackendStruct) list(bucketName string, prefix string, delimiter string, path string) (keyList []string, commonPrefixList []string, err error) {
+	var (
+		query        url.Values
+		responseBody []byte
+		result       s3ListBucketResultStruct
+	)
+
+	keyList = make([]string, 0)
+	commonPrefixList = make([]string, 0)
+
+	query = url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	if "" != delimiter {
+		query.Set("delimiter", delimiter)
+	}
+
+	for {
+		_, responseBody, err = backend.request(http.MethodGet, bucketName, "", query, nil, nil, path)
+		if nil != err {
+			return
+		}
+
+		result = s3ListBucketResultStruct{}
+
+		err = xml.Unmarshal(responseBody, &result)
+		if nil != err {
+			err = blunder.NewError(blunder.BadHTTPGetError, "GET %s returned unparseable ListBucketResult: %v", path, err)
+			return
+		}
+
+		for _, content := range result.Contents {
+			keyList = append(keyList, content.Key)
+		}
+		for _, commonPrefix := range result.CommonPrefixes {
+			commonPrefixList = append(commonPrefixList, commonPrefix.Prefix)
+		}
+
+		if !result.IsTruncated {
+			break
+		}
+
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+
+	sort.Strings(keyList)
+	sort.Strings(commonPrefixList)
+
+	return
+}
+
+func (backend *s3BackendStruct) AccountDelete(accountName string) (err error) {
+	var (
+		bucketName    string
+		containerList []string
+	)
+
+	bucketName = backend.bucketName(accountName)
+
+	_, containerList, err = backend.AccountGet(accountName)
+	if nil != err {
+		return
+	}
+	if 0 != len(containerList) {
+		err = backendHTTPStatusError(http.StatusConflict, "DELETE %s returned HTTP StatusCode %d", accountName, http.StatusConflict)
+		return
+	}
+
+	_, _, err = backend.request(http.MethodDelete, bucketName, s3AccountMarkerKey, nil, nil, nil, accountName)
+	if nil != err {
+		return
+	}
+
+	_, _, err = backend.request(http.MethodDelete, bucketName, "", nil, nil, nil, accountName)
+
+	return
+}
+
+func (backend *s3BackendStruct) AccountGet(accountName string) (headers map[string][]string, containerList []string, err error) {
+	var (
+		bucketName       string
+		commonPrefix     string
+		commonPrefixList []string
+	)
+
+	bucketName = backend.bucketName(accountName)
+
+	headers, err = backend.fetchMarkerHeaders(bucketName, s3AccountMarkerKey, accountName)
+	if nil != err {
+		return
+	}
+
+	_, commonPrefixList, err = backend.list(bucketName, "", s3ContainerDelimiter, accountName)
+	if nil != err {
+		return
+	}
+
+	containerList = make([]string, 0, len(commonPrefixList))
+	for _, commonPrefix = range commonPrefixList {
+		containerList = append(containerList, strings.TrimSuffix(commonPrefix, s3ContainerDelimiter))
+	}
+
+	return
+}
+
+func (backend *s3BackendStruct) AccountHead(accountName string) (headers map[string][]string, err error) {
+	headers, err = backend.fetchMarkerHeaders(backend.bucketName(accountName), s3AccountMarkerKey, accountName)
+	return
+}
+
+func (backend *s3BackendStruct) AccountPost(accountName string, headers map[string][]string) (err error) {
+	err = backend.mergeMarkerHeaders(backend.bucketName(accountName), s3AccountMarkerKey, headers, true, accountName)
+	return
+}
+
+func (backend *s3BackendStruct) AccountPut(accountName string, headers map[string][]string) (err error) {
+	var (
+		bucketName string
+	)
+
+	bucketName = backend.bucketName(accountName)
+
+	_, _, err = backend.request(http.MethodPut, bucketName, "", nil, nil, nil, accountName)
+	if (nil != err) && (http.StatusConflict == blunder.HTTPCode(err)) && strings.Contains(err.Error(), s3BucketAlreadyOwnedByYou) {
+		err = nil
+	}
+	if nil != err {
+		return
+	}
+
+	err = backend.mergeMarkerHeaders(bucketName, s3AccountMarkerKey, headers, false, accountName)
+
+	return
+}
+
+func (backend *s3BackendStruct) ContainerDelete(accountName string, containerName string) (err error) {
+	var (
+		bucketName string
+		keyList    []string
+		path       string
+	)
+
+	bucketName = backend.bucketName(accountName)
+	path = accountName + "/" + containerName
+
+	_, err = backend.fetchMarkerHeaders(bucketName, containerName+s3ContainerDelimiter, path)
+	if nil != err {
+		return
+	}
+
+	keyList, _, err = backend.list(bucketName, containerName+s3ContainerDelimiter, "", path)
+	if nil != err {
+		return
+	}
+	if 1 < len(keyList) {
+		err = backendHTTPStatusError(http.StatusConflict, "DELETE %s returned HTTP StatusCode %d", path, http.StatusConflict)
+		return
+	}
+
+	_, _, err = backend.request(http.MethodDelete, bucketName, containerName+s3ContainerDelimiter, nil, nil, nil, path)
+
+	return
+}
+
+func (backend *s3BackendStruct) ContainerGet(accountName string, containerName string) (headers map[string][]string, objectList []string, err error) {
+	var (
+		bucketName string
+		key        string
+		keyList    []string
+		path       string
+		prefix     string
+	)
+
+	bucketName = backend.bucketName(accountName)
+	path = accountName + "/" + containerName
+	prefix = containerName + s3ContainerDelimiter
+
+	headers, err = backend.fetchMarkerHeaders(bucketName, prefix, path)
+	if nil != err {
+		return
+	}
+
+	keyList, _, err = backend.list(bucketName, prefix, "", path)
+	if nil != err {
+		return
+	}
+
+	objectList = make([]string, 0, len(keyList))
+	for _, key = range keyList {
+		if prefix != key {
+			objectList = append(objectList, strings.TrimPrefix(key, prefix))
+		}
+	}
+
+	return
+}
+
+func (backend *s3BackendStruct) ContainerHead(accountName string, containerName string) (headers map[string][]string, err error) {
+	headers, err = backend.fetchMarkerHeaders(backend.bucketName(accountName), containerName+s3ContainerDelimiter, accountName+"/"+containerName)
+	return
+}
+
+func (backend *s3BackendStruct) ContainerPost(accountName string, containerName string, headers map[string][]string) (err error) {
+	err = backend.mergeMarkerHeaders(backend.bucketName(accountName), containerName+s3ContainerDelimiter, headers, true, accountName+"/"+containerName)
+	return
+}
+
+func (backend *s3BackendStruct) ContainerPut(accountName string, containerName string, headers map[string][]string) (err error) {
+	var (
+		bucketName string
+	)
+
+	bucketName = backend.bucketName(accountName)
+
+	_, err = backend.fetchMarkerHeaders(bucketName, s3AccountMarkerKey, accountName)
+	if nil != err {
+		return
+	}
+
+	err = backend.mergeMarkerHeaders(bucketName, containerName+s3ContainerDelimiter, headers, false, accountName+"/"+containerName)
+
+	return
+}
+
+func (backend *s3BackendStruct) objectKey(containerName string, objectName string) string {
+	return containerName + s3ContainerDelimiter + objectName
+}
+
+func (backend *s3BackendStruct) ObjectContentLength(accountName string, containerName string, objectName string) (length uint64, err error) {
+	var (
+		path            string
+		responseHeaders http.Header
+	)
+
+	path = accountName + "/" + containerName + "/" + objectName
+
+	responseHeaders, _, err = backend.request(http.MethodHead, backend.bucketName(accountName), backend.objectKey(containerName, objectName), nil, nil, nil, path)
+	if nil != err {
+		return
+	}
+
+	length, err = strconv.ParseUint(responseHeaders.Get("Content-Length"), 10, 64)
+	if nil != err {
+		err = blunder.NewError(blunder.BadHTTPHeadError, "HEAD %s returned bad Content-Length: %v", path, err)
+	}
+
+	return
+}
+
+func (backend *s3BackendStruct) ObjectDelete(accountName string, containerName string, objectName string, operationOptions OperationOptions) (err error) {
+	var (
+		bucketName string
+		key        string
+		path       string
+	)
+
+	bucketName = backend.bucketName(accountName)
+	key = backend.objectKey(containerName, objectName)
+	path = accountName + "/" + containerName + "/" + objectName
+
+	// S3 DELETE succeeds for a missing Object... so first HEAD it to preserve Swift semantics
+
+	_, _, err = backend.request(http.MethodHead, bucketName, key, nil, nil, nil, path)
+	if nil != err {
+		return
+	}
+
+	_, _, err = backend.request(http.MethodDelete, bucketName, key, nil, nil, nil, path)
+
+	return
+}
+
+func (backend *s3BackendStruct) ObjectFetchChunkedPutContext(accountName string, containerName string, objectName string, useReserveForVolumeName string) (chunkedPutContext ChunkedPutContext, err error) {
+	chunkedPutContext = &s3ChunkedPutContextStruct{
+		backend:       backend,
+		bucketName:    backend.bucketName(accountName),
+		key:           backend.objectKey(containerName, objectName),
+		path:          accountName + "/" + containerName + "/" + objectName,
+		buf:           make([]byte, 0),
+		bytesUploaded: 0,
+		uploadID:      "",
+		partETagList:  make([]string, 0),
+		active:        true,
+	}
+
+	err = nil
+	return
+}
+
+// getRange issues a GET with the supplied Range header value.
+func (backend *s3BackendStruct) getRange(accountName string, containerName string, objectName string, rangeValue string) (buf []byte, err error) {
+	var (
+		headers http.Header
+	)
+
+	if "" != rangeValue {
+		headers = http.Header{"Range": {rangeValue}}
+	}
+
+	_, buf, err = backend.request(http.MethodGet, backend.bucketName(accountName), backend.objectKey(containerName, objectName), nil, headers, nil, accountName+"/"+containerName+"/"+objectName)
+
+	return
+}
+
+func (backend *s3BackendStruct) ObjectGet(accountName string, containerName string, objectName string, offset uint64, length uint64) (buf []byte, err error) {
+	if 0 == length {
+		buf = make([]byte, 0)
+		return
+	}
+
+	buf, err = backend.getRange(accountName, containerName, objectName, fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	return
+}
+
+func (backend *s3BackendStruct) ObjectHead(accountName string, containerName string, objectName string) (headers map[string][]string, err error) {
+	var (
+		responseHeaders http.Header
+	)
+
+	responseHeaders, _, err = backend.request(http.MethodHead, backend.bucketName(accountName), backend.objectKey(containerName, objectName), nil, nil, nil, accountName+"/"+containerName+"/"+objectName)
+	if nil != err {
+		return
+	}
+
+	headers = s3MetadataToSwiftHeaders(responseHeaders)
+	headers["Content-Length"] = []string{responseHeaders.Get("Content-Length")}
+	if "" != responseHeaders.Get("ETag") {
+		headers["Etag"] = []string{strings.Trim(responseHeaders.Get("ETag"), "\"")}
+	}
+
+	return
+}
+
+func (backend *s3BackendStruct) ObjectLoad(accountName string, containerName string, objectName string) (buf []byte, err error) {
+	buf, err = backend.getRange(accountName, containerName, objectName, "")
+	return
+}
+
+func (backend *s3BackendStruct) ObjectRead(accountName string, containerName string, objectName string, offset uint64, buf []byte) (length uint64, err error) {
+	var (
+		readBuf []byte
+	)
+
+	readBuf, err = backend.ObjectGet(accountName, containerName, objectName, offset, uint64(len(buf)))
+	if nil != err {
+		return
+	}
+
+	length = uint64(copy(buf, readBuf))
+
+	return
+}
+
+func (backend *s3BackendStruct) ObjectTail(accountName string, containerName string, objectName string, length uint64) (buf []byte, err error) {
+	if 0 == length {
+		buf = make([]byte, 0)
+		return
+	}
+
+	buf, err = backend.getRange(accountName, containerName, objectName, fmt.Sprintf("bytes=-%d", length))
+
+	return
+}
+
+func (chunkedPutContext *s3ChunkedPutContextStruct) Active() (active bool) {
+	chunkedPutContext.Lock()
+	active = chunkedPutContext.active
+	chunkedPutContext.Unlock()
+	return
+}
+
+func (chunkedPutContext *s3ChunkedPutContextStruct) BytesPut() (bytesPut uint64, err error) {
+	chunkedPutContext.Lock()
+	bytesPut = uint64(len(chunkedPutContext.buf))
+	chunkedPutContext.Unlock()
+	err = nil
+	return
+}
+
+// uploadPart sends buf[bytesUploaded:stop] as the next Multipart Upload part (initiating the
+// Multipart Upload if necessary). Must be called with chunkedPutContext locked.
+func (chunkedPutContext *s3ChunkedPutContextStruct) uploadPart(stop uint64) (err error) {
+	var (
+		backend         = chunkedPutContext.backend
+		responseBody    []byte
+		responseHeaders http.Header
+		result          s3InitiateMultipartUploadResultStruct
+	)
+
+	if "" == chunkedPutContext.uploadID {
+		_, responseBody, err = backend.request(http.MethodPost, chunkedPutContext.bucketName, chunkedPutContext.key, url.Values{"uploads": {""}}, nil, nil, chunkedPutContext.path)
+		if nil != err {
+			return
+		}
+		err = xml.Unmarshal(responseBody, &result)
+		if (nil != err) || ("" == result.UploadID) {
+			err = blunder.NewError(blunder.BadHTTPPutError, "POST %s?uploads returned unparseable InitiateMultipartUploadResult: %v", chunkedPutContext.path, err)
+			return
+		}
+		chunkedPutContext.uploadID = result.UploadID
+	}
+
+	responseHeaders, _, err = backend.request(http.MethodPut, chunkedPutContext.bucketName, chunkedPutContext.key,
+		url.Values{"partNumber": {strconv.Itoa(len(chunkedPutContext.partETagList) + 1)}, "uploadId": {chunkedPutContext.uploadID}},
+		nil, chunkedPutContext.buf[chunkedPutContext.bytesUploaded:stop], chunkedPutContext.path)
+	if nil != err {
+		return
+	}
+
+	chunkedPutContext.partETagList = append(chunkedPutContext.partETagList, responseHeaders.Get("ETag"))
+	chunkedPutContext.bytesUploaded = stop
+
+	return
+}
+
+// abort discards any Multipart Upload in progress. Must be called with chunkedPutContext locked.
+func (chunkedPutContext *s3ChunkedPutContextStruct) abort() {
+	if "" != chunkedPutContext.uploadID {
+		_, _, _ = chunkedPutContext.backend.request(http.MethodDelete, chunkedPutContext.bucketName, chunkedPutContext.key,
+			url.Values{"uploadId": {chunkedPutContext.uploadID}}, nil, nil, chunkedPutContext.path)
+		chunkedPutContext.uploadID = ""
+	}
+}
+
+func (chunkedPutContext *s3ChunkedPutContextStruct) Close() (err error) {
+	var (
+		backend      = chunkedPutContext.backend
+		partIndex    int
+		partETag     string
+		responseBody []byte
+		s3Err        s3ErrorStruct
+		xmlBuf       bytes.Buffer
+	)
+
+	chunkedPutContext.Lock()
+	defer chunkedPutContext.Unlock()
+
+	if !chunkedPutContext.active {
+		err = fmt.Errorf("swiftclient.s3ChunkedPutContextStruct.Close() called for %s more than once", chunkedPutContext.path)
+		return
+	}
+
+	chunkedPutContext.active = false
+
+	if "" == chunkedPutContext.uploadID {
+		_, _, err = backend.request(http.MethodPut, chunkedPutContext.bucketName, chunkedPutContext.key, nil, nil, chunkedPutContext.buf, chunkedPutContext.path)
+		return
+	}
+
+	if chunkedPutContext.bytesUploaded < uint64(len(chunkedPutContext.buf)) {
+		err = chunkedPutContext.uploadPart(uint64(len(chunkedPutContext.buf)))
+		if nil != err {
+			chunkedPutContext.abort()
+			return
+		}
+	}
+
+	xmlBuf.WriteString(s3CompleteMultipartXMLStart)
+	for partIndex, partETag = range chunkedPutContext.partETagList {
+		fmt.Fprintf(&xmlBuf, "<Part><PartNumber>%d</PartNumber><ETag>", partIndex+1)
+		_ = xml.EscapeText(&xmlBuf, []byte(partETag))
+		xmlBuf.WriteString("</ETag></Part>")
+	}
+	xmlBuf.WriteString(s3CompleteMultipartXMLStop)
+
+	_, responseBody, err = backend.request(http.MethodPost, chunkedPutContext.bucketName, chunkedPutContext.key,
+		url.Values{"uploadId": {chunkedPutContext.uploadID}}, nil, xmlBuf.Bytes(), chunkedPutContext.path)
+	if nil == err {
+		// CompleteMultipartUpload may fail after having returned 200 OK
+		if (nil == xml.Unmarshal(responseBody, &s3Err)) && ("" != s3Err.Code) {
+			err = s3Error(http.MethodPost, chunkedPutContext.path, http.StatusOK, responseBody)
+		}
+	}
+	if nil != err {
+		chunkedPutContext.abort()
+	}
+
+	return
+}
+
+func (chunkedPutContext *s3ChunkedPutContextStruct) Read(offset uint64, length uint64) (buf []byte, err error) {
+	chunkedPutContext.Lock()
+	defer chunkedPutContext.Unlock()
+
+	if (offset + length) > uint64(len(chunkedPutContext.buf)) {
+		err = blunder.NewError(blunder.BadHTTPGetError, "swiftclient.s3ChunkedPutContextStruct.Read() called for %s with offset (0x%X) + length (0x%X) > bytesPut (0x%X)",
+			chunkedPutContext.path, offset, length, len(chunkedPutContext.buf))
+		return
+	}
+
+	buf = make([]byte, length)
+	copy(buf, chunkedPutContext.buf[offset:offset+length])
+
+	return
+}
+
+func (chunkedPutContext *s3ChunkedPutContextStruct) SendChunk(buf []byte) (err error) {
+	chunkedPutContext.Lock()
+	defer chunkedPutContext.Unlock()
+
+	if !chunkedPutContext.active {
+		err = fmt.Errorf("swiftclient.s3ChunkedPutContextStruct.SendChunk() called for %s after Close()", chunkedPutContext.path)
+		return
+	}
+
+	chunkedPutContext.buf = append(chunkedPutContext.buf, buf...)
+
+	for (uint64(len(chunkedPutContext.buf)) - chunkedPutContext.bytesUploaded) > chunkedPutContext.backend.multipartPartSize {
+		err = chunkedPutContext.uploadPart(chunkedPutContext.bytesUploaded + chunkedPutContext.backend.multipartPartSize)
+		if nil != err {
+			return
+		}
+	}
+
+	return
+}
@@ -18,17 +18,6 @@ import (
 const swiftVersion = "v1"
 
 func drainConnections() {
-	var (
-		connection *connectionStruct
-		volumeName string
-	)
-
-	for volumeName, connection = range globals.reservedChunkedConnection {
-		_ = connection.tcpConn.Close()
-		delete(globals.reservedChunkedConnection, volumeName)
-	}
-
-	globals.chunkedConnectionPool.Lock()
 	// The following should not be necessary so, as such, will remain commented out
 	/*
 		for 0 < globals.chunkedConnectionPool.poolInUse {
@@ -37,30 +26,7 @@ func drainConnections() {
 			globals.chunkedConnectionPool.Lock()
 		}
 	*/
-	for 0 < globals.chunkedConnectionPool.lifoIndex {
-		globals.chunkedConnectionPool.lifoIndex--
-		connection = globals.chunkedConnectionPool.lifoOfActiveConnections[globals.chunkedConnectionPool.lifoIndex]
-		globals.chunkedConnectionPool.lifoOfActiveConnections[globals.chunkedConnectionPool.lifoIndex] = nil
-		_ = connection.tcpConn.Close()
-	}
-	globals.chunkedConnectionPool.Unlock()
-
-	globals.nonChunkedConnectionPool.Lock()
-	// The following should not be necessary so, as such, will remain commented out
-	/*
-		for 0 < globals.nonChunkedConnectionPool.poolInUse {
-			globals.nonChunkedConnectionPool.Unlock()
-			time.Sleep(100 * time.Millisecond)
-			globals.nonChunkedConnectionPool.Lock()
-		}
-	*/
-	for 0 < globals.nonChunkedConnectionPool.lifoIndex {
-		globals.nonChunkedConnectionPool.lifoIndex--
-		connection = globals.nonChunkedConnectionPool.lifoOfActiveConnections[globals.nonChunkedConnectionPool.lifoIndex]
-		globals.nonChunkedConnectionPool.lifoOfActiveConnections[globals.nonChunkedConnectionPool.lifoIndex] = nil
-		_ = connection.tcpConn.Close()
-	}
-	globals.nonChunkedConnectionPool.Unlock()
+	drainEndpointConnections(nil)
 }
 
 func getStarvationParameters() (starvationParameters *StarvationParameters) {
@@ -79,13 +45,17 @@ func getStarvationParameters() (starvationParameters *StarvationParameters) {
 	starvationParameters.NonChunkedConnectionPoolNumWaiters = globals.nonChunkedConnectionPool.numWaiters
 	globals.nonChunkedConnectionPool.Unlock()
 
+	starvationParameters.EndpointList = getEndpointStarvationParameters()
+
 	return
 }
 
 func acquireChunkedConnection(useReserveForVolumeName string) (connection *connectionStruct, err error) {
 	var (
+		connectionToBeClosed            *connectionStruct
 		connectionToBeCreated           bool
 		cv                              *sync.Cond
+		endpoint                        *endpointStruct
 		ok                              bool
 		swiftChunkedStarvationCallbacks uint64
 		wasStalled                      bool
@@ -106,22 +76,23 @@ func acquireChunkedConnection(useReserveForVolumeName string) (connection *conne
 
 			globals.reservedChunkedConnectionMutex.Unlock()
 
+			connection.endpoint.acquired()
+
 			stats.IncrementOperations(&stats.SwiftChunkedConnsReuseOps)
 		} else {
 			// No connection available...create a new one
 
 			globals.reservedChunkedConnectionMutex.Unlock()
 
-			connection = &connectionStruct{connectionNonce: globals.connectionNonce, reserveForVolumeName: useReserveForVolumeName}
+			endpoint = selectEndpoint()
+
+			connection = &connectionStruct{connectionNonce: globals.connectionNonce, endpoint: endpoint, reserveForVolumeName: useReserveForVolumeName}
 
 			err = openConnection(fmt.Sprintf("swiftclient.acquireChunkedConnection(\"%v\")",
 				useReserveForVolumeName), connection)
 			if err != nil {
+				connection.endpoint.released()
 				connection = nil
-
-				// if openConnection() failed then the cached connections are
-				// likely broken and using them will create further mischief
-				drainConnections()
 			}
 
 			stats.IncrementOperations(&stats.SwiftChunkedConnsCreateOps)
@@ -130,6 +101,7 @@ func acquireChunkedConnection(useReserveForVolumeName string) (connection *conne
 		return
 	}
 
+	connectionToBeClosed = nil
 	connectionToBeCreated = false
 	wasStalled = false
 
@@ -174,28 +146,34 @@ func acquireChunkedConnection(useReserveForVolumeName string) (connection *conne
 
 	globals.chunkedConnectionPool.poolInUse++
 
-	if 0 == globals.chunkedConnectionPool.lifoIndex {
+	endpoint = selectEndpoint()
+
+	connection = globals.chunkedConnectionPool.popIdleConnection(endpoint)
+	if nil == connection {
 		connectionToBeCreated = true
-	} else {
-		globals.chunkedConnectionPool.lifoIndex--
-		connection = globals.chunkedConnectionPool.lifoOfActiveConnections[globals.chunkedConnectionPool.lifoIndex]
-		globals.chunkedConnectionPool.lifoOfActiveConnections[globals.chunkedConnectionPool.lifoIndex] = nil
+
+		// Keep the total number of connections within poolCapacity by discarding
+		// an idle connection to some other endpoint if necessary
+		if globals.chunkedConnectionPool.poolInUse+globals.chunkedConnectionPool.numIdle > globals.chunkedConnectionPool.poolCapacity {
+			connectionToBeClosed = globals.chunkedConnectionPool.popIdleConnection(nil)
+		}
 	}
 
 	globals.chunkedConnectionPool.Unlock()
 
+	if nil != connectionToBeClosed {
+		_ = connectionToBeClosed.tcpConn.Close()
+	}
+
 	if connectionToBeCreated {
-		connection = &connectionStruct{connectionNonce: globals.connectionNonce, reserveForVolumeName: ""}
+		connection = &connectionStruct{connectionNonce: globals.connectionNonce, endpoint: endpoint, reserveForVolumeName: ""}
 		err = openConnection("swiftclient.acquireChunkedConnection()", connection)
 		if err != nil {
+			connection.endpoint.released()
 			connection = nil
 			globals.chunkedConnectionPool.Lock()
 			globals.chunkedConnectionPool.poolInUse--
 			globals.chunkedConnectionPool.Unlock()
-
-			// if openConnection() failed then the cached connections are
-			// likely broken and using them will create further mischief
-			drainConnections()
 		}
 		stats.IncrementOperations(&stats.SwiftChunkedConnsCreateOps)
 	} else {
@@ -218,9 +196,12 @@ func releaseChunkedConnection(connection *connectionStruct, keepAlive bool) {
 		waiter               *list.Element
 	)
 
+	connection.endpoint.released()
+
 	if "" != connection.reserveForVolumeName {
 		if keepAlive &&
-			(connection.connectionNonce == globals.connectionNonce) {
+			(connection.connectionNonce == globals.connectionNonce) &&
+			connection.endpoint.isHealthy() {
 			// Re-insert connection in globals.reservedChunkedConnection map
 
 			globals.reservedChunkedConnectionMutex.Lock()
@@ -241,9 +222,11 @@ func releaseChunkedConnection(connection *connectionStruct, keepAlive bool) {
 
 	globals.chunkedConnectionPool.poolInUse--
 
-	if keepAlive && (connection.connectionNonce == globals.connectionNonce) {
-		globals.chunkedConnectionPool.lifoOfActiveConnections[globals.chunkedConnectionPool.lifoIndex] = connection
-		globals.chunkedConnectionPool.lifoIndex++
+	if keepAlive &&
+		(connection.connectionNonce == globals.connectionNonce) &&
+		(globals.chunkedConnectionPool.poolInUse+globals.chunkedConnectionPool.numIdle < globals.chunkedConnectionPool.poolCapacity) &&
+		connection.endpoint.isHealthy() {
+		globals.chunkedConnectionPool.pushIdleConnection(connection)
 	} else {
 		connectionToBeClosed = true
 	}
@@ -276,26 +259,10 @@ func resizeChunkedConnectionPool(newPoolSize uint) {
 	defer globals.chunkedConnectionPool.Unlock()
 
 	// if shrinking the pool close any connections that are no longer usable
-	if newSize < globals.chunkedConnectionPool.poolCapacity {
-		for globals.chunkedConnectionPool.lifoIndex > newSize {
-			globals.chunkedConnectionPool.lifoIndex--
-			index := globals.chunkedConnectionPool.lifoIndex
-			globals.chunkedConnectionPool.lifoOfActiveConnections[index].tcpConn.Close()
-			globals.chunkedConnectionPool.lifoOfActiveConnections[index] = nil
-		}
-		globals.chunkedConnectionPool.poolCapacity = newSize
-		return
+	for globals.chunkedConnectionPool.numIdle > newSize {
+		globals.chunkedConnectionPool.popIdleConnection(nil).tcpConn.Close()
 	}
 
-	// growing -- must allocate a new pool and populate it
-	newActiveConnections := make([]*connectionStruct, newPoolSize)
-	for i := uint16(0); i < globals.chunkedConnectionPool.poolCapacity; i++ {
-		newActiveConnections[i] = globals.chunkedConnectionPool.lifoOfActiveConnections[i]
-	}
-	for i := globals.chunkedConnectionPool.poolCapacity; i < newSize; i++ {
-		newActiveConnections[globals.chunkedConnectionPool.poolCapacity] = nil
-	}
-	globals.chunkedConnectionPool.lifoOfActiveConnections = newActiveConnections
 	globals.chunkedConnectionPool.poolCapacity = newSize
 	return
 }
@@ -306,11 +273,14 @@ func resizeChunkedConnectionPool(newPoolSize uint) {
 //
 func acquireNonChunkedConnection() (connection *connectionStruct, err error) {
 	var (
+		connectionToBeClosed  *connectionStruct
 		connectionToBeCreated bool
 		cv                    *sync.Cond
+		endpoint              *endpointStruct
 		wasStalled            bool
 	)
 
+	connectionToBeClosed = nil
 	connectionToBeCreated = false
 	wasStalled = false
 
@@ -334,28 +304,34 @@ func acquireNonChunkedConnection() (connection *connectionStruct, err error) {
 
 	globals.nonChunkedConnectionPool.poolInUse++
 
-	if 0 == globals.nonChunkedConnectionPool.lifoIndex {
+	endpoint = selectEndpoint()
+
+	connection = globals.nonChunkedConnectionPool.popIdleConnection(endpoint)
+	if nil == connection {
 		connectionToBeCreated = true
-	} else {
-		globals.nonChunkedConnectionPool.lifoIndex--
-		connection = globals.nonChunkedConnectionPool.lifoOfActiveConnections[globals.nonChunkedConnectionPool.lifoIndex]
-		globals.nonChunkedConnectionPool.lifoOfActiveConnections[globals.nonChunkedConnectionPool.lifoIndex] = nil
+
+		// Keep the total number of connections within poolCapacity by discarding
+		// an idle connection to some other endpoint if necessary
+		if globals.nonChunkedConnectionPool.poolInUse+globals.nonChunkedConnectionPool.numIdle > globals.nonChunkedConnectionPool.poolCapacity {
+			connectionToBeClosed = globals.nonChunkedConnectionPool.popIdleConnection(nil)
+		}
 	}
 
 	globals.nonChunkedConnectionPool.Unlock()
 
+	if nil != connectionToBeClosed {
+		_ = connectionToBeClosed.tcpConn.Close()
+	}
+
 	if connectionToBeCreated {
-		connection = &connectionStruct{connectionNonce: globals.connectionNonce, reserveForVolumeName: ""}
+		connection = &connectionStruct{connectionNonce: globals.connectionNonce, endpoint: endpoint, reserveForVolumeName: ""}
 		err = openConnection("swiftclient.acquireNonChunkedConnection()", connection)
 		if err != nil {
+			connection.endpoint.released()
 			connection = nil
 			globals.nonChunkedConnectionPool.Lock()
 			globals.nonChunkedConnectionPool.poolInUse--
 			globals.nonChunkedConnectionPool.Unlock()
-
-			// if openConnection() failed then the cached connections are
-			// likely broken and using them will create further mischief
-			drainConnections()
 		}
 		stats.IncrementOperations(&stats.SwiftNonChunkedConnsCreateOps)
 	} else {
@@ -378,13 +354,17 @@ func releaseNonChunkedConnection(connection *connectionStruct, keepAlive bool) {
 	)
 	connectionToBeClosed = false
 
+	connection.endpoint.released()
+
 	globals.nonChunkedConnectionPool.Lock()
 
 	globals.nonChunkedConnectionPool.poolInUse--
 
-	if keepAlive && (connection.connectionNonce == globals.connectionNonce) {
-		globals.nonChunkedConnectionPool.lifoOfActiveConnections[globals.nonChunkedConnectionPool.lifoIndex] = connection
-		globals.nonChunkedConnectionPool.lifoIndex++
+	if keepAlive &&
+		(connection.connectionNonce == globals.connectionNonce) &&
+		(globals.nonChunkedConnectionPool.poolInUse+globals.nonChunkedConnectionPool.numIdle < globals.nonChunkedConnectionPool.poolCapacity) &&
+		connection.endpoint.isHealthy() {
+		globals.nonChunkedConnectionPool.pushIdleConnection(connection)
 	} else {
 		connectionToBeClosed = true
 	}
@@ -416,26 +396,10 @@ func resizeNonChunkedConnectionPool(newPoolSize uint) {
 	defer globals.nonChunkedConnectionPool.Unlock()
 
 	// if shrinking the pool close any connections that are no longer usable
-	if newSize < globals.nonChunkedConnectionPool.poolCapacity {
-		for globals.nonChunkedConnectionPool.lifoIndex > newSize {
-			globals.nonChunkedConnectionPool.lifoIndex--
-			index := globals.nonChunkedConnectionPool.lifoIndex
-			globals.nonChunkedConnectionPool.lifoOfActiveConnections[index].tcpConn.Close()
-			globals.nonChunkedConnectionPool.lifoOfActiveConnections[index] = nil
-		}
-		globals.nonChunkedConnectionPool.poolCapacity = newSize
-		return
+	for globals.nonChunkedConnectionPool.numIdle > newSize {
+		globals.nonChunkedConnectionPool.popIdleConnection(nil).tcpConn.Close()
 	}
 
-	// growing -- must allocate a new pool and populate it
-	newActiveConnections := make([]*connectionStruct, newPoolSize)
-	for i := uint16(0); i < globals.nonChunkedConnectionPool.poolCapacity; i++ {
-		newActiveConnections[i] = globals.nonChunkedConnectionPool.lifoOfActiveConnections[i]
-	}
-	for i := globals.nonChunkedConnectionPool.poolCapacity; i < newSize; i++ {
-		newActiveConnections[globals.nonChunkedConnectionPool.poolCapacity] = nil
-	}
-	globals.nonChunkedConnectionPool.lifoOfActiveConnections = newActiveConnections
 	globals.nonChunkedConnectionPool.poolCapacity = newSize
 	return
 }
@@ -459,7 +423,12 @@ var openConnectionCallCnt uint32
 
 // (Re)open a connection to the Swift NoAuth Proxy.
 //
-// The connection is closed first, just in case it was already open.
+// The connection is closed first, just in case it was already open. Should its
+// endpoint have been ejected, the connection is moved to a healthy endpoint.
+//
+// On failure, the cached connections to the endpoint are likely broken and using
+// them will create further mischief, so they are drained as the failure is counted
+// against the endpoint.
 //
 func openConnection(caller string, connection *connectionStruct) (err error) {
 
@@ -467,6 +436,8 @@ func openConnection(caller string, connection *connectionStruct) (err error) {
 		_ = connection.tcpConn.Close()
 	}
 
+	connection.endpoint = reselectEndpoint(connection.endpoint)
+
 	if globals.chaosOpenConnectionFailureRate > 0 {
 		// atomic add only used when testing
 		if atomic.AddUint32(&openConnectionCallCnt, 1)%globals.chaosOpenConnectionFailureRate == 0 {
@@ -475,11 +446,15 @@ func openConnection(caller string, connection *connectionStruct) (err error) {
 	}
 
 	if err == nil {
-		connection.tcpConn, err = net.DialTCP("tcp4", nil, globals.noAuthTCPAddr)
+		connection.tcpConn, err = net.DialTCP("tcp4", nil, connection.endpoint.tcpAddr)
 	}
 	if err != nil {
 		logger.WarnfWithError(err, "%s cannot connect to Swift NoAuth Pipeline at %s",
-			caller, globals.noAuthStringAddr)
+			caller, connection.endpoint.stringAddr)
+		drainEndpointConnections(connection.endpoint)
+		connection.endpoint.failed(err)
+	} else {
+		connection.endpoint.succeeded()
 	}
 	return
 }
@@ -515,7 +490,7 @@ func writeBytesToTCPConn(tcpConn *net.TCPConn, buf []byte) (err error) {
 	return
 }
 
-func writeHTTPRequestLineAndHeaders(tcpConn *net.TCPConn, method string, path string, headers map[string][]string) (err error) {
+func writeHTTPRequestLineAndHeaders(connection *connectionStruct, method string, path string, headers map[string][]string) (err error) {
 	var (
 		bytesBuffer      bytes.Buffer
 		headerName       string
@@ -526,7 +501,7 @@ func writeHTTPRequestLineAndHeaders(tcpConn *net.TCPConn, method string, path st
 
 	_, _ = bytesBuffer.WriteString(method + " " + path + " HTTP/1.1\r\n")
 
-	_, _ = bytesBuffer.WriteString("Host: " + globals.noAuthStringAddr + "\r\n")
+	_, _ = bytesBuffer.WriteString("Host: " + connection.endpoint.stringAddr + "\r\n")
 	_, _ = bytesBuffer.WriteString("User-Agent: ProxyFS\r\n")
 
 	for headerName, headerValues = range headers {
@@ -543,7 +518,7 @@ func writeHTTPRequestLineAndHeaders(tcpConn *net.TCPConn, method string, path st
 
 	_, _ = bytesBuffer.WriteString("\r\n")
 
-	err = writeBytesToTCPConn(tcpConn, bytesBuffer.Bytes())
+	err = writeBytesToTCPConn(connection.tcpConn, bytesBuffer.Bytes())
 
 	return
 }